@@ -5,7 +5,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -19,6 +19,7 @@ import (
 
 	"github.com/google/wire"
 	"github.com/redis/go-redis/v9"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gorm.io/gorm"
 )
 
@@ -65,11 +66,17 @@ func provideCleanup(
 	db *gorm.DB,
 	rdb *redis.Client,
 	tokenRefresh *service.TokenRefreshService,
+	apiKeyExpiry *service.ApiKeyExpiryService,
+	capture *service.CaptureService,
+	liveEvents *service.LiveEventService,
+	webhooks *service.WebhookService,
+	userNotify *service.UserNotificationService,
 	pricing *service.PricingService,
 	emailQueue *service.EmailQueueService,
 	oauth *service.OAuthService,
 	openaiOAuth *service.OpenAIOAuthService,
 	geminiOAuth *service.GeminiOAuthService,
+	tracerProvider *sdktrace.TracerProvider,
 ) func() {
 	return func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -84,6 +91,26 @@ func provideCleanup(
 				tokenRefresh.Stop()
 				return nil
 			}},
+			{"ApiKeyExpiryService", func() error {
+				apiKeyExpiry.Stop()
+				return nil
+			}},
+			{"CaptureService", func() error {
+				capture.Stop()
+				return nil
+			}},
+			{"LiveEventService", func() error {
+				liveEvents.Stop()
+				return nil
+			}},
+			{"WebhookService", func() error {
+				webhooks.Stop()
+				return nil
+			}},
+			{"UserNotificationService", func() error {
+				userNotify.Stop()
+				return nil
+			}},
 			{"PricingService", func() error {
 				pricing.Stop()
 				return nil
@@ -104,6 +131,12 @@ func provideCleanup(
 				geminiOAuth.Stop()
 				return nil
 			}},
+			{"TracerProvider", func() error {
+				if tracerProvider == nil {
+					return nil
+				}
+				return tracerProvider.Shutdown(ctx)
+			}},
 			{"Redis", func() error {
 				return rdb.Close()
 			}},
@@ -118,19 +151,19 @@ func provideCleanup(
 
 		for _, step := range cleanupSteps {
 			if err := step.fn(); err != nil {
-				log.Printf("[Cleanup] %s failed: %v", step.name, err)
+				slog.Error("cleanup step failed", "step", step.name, "error", err)
 				// Continue with remaining cleanup steps even if one fails
 			} else {
-				log.Printf("[Cleanup] %s succeeded", step.name)
+				slog.Debug("cleanup step succeeded", "step", step.name)
 			}
 		}
 
 		// Check if context timed out
 		select {
 		case <-ctx.Done():
-			log.Printf("[Cleanup] Warning: cleanup timed out after 10 seconds")
+			slog.Warn("cleanup timed out after 10 seconds")
 		default:
-			log.Printf("[Cleanup] All cleanup steps completed")
+			slog.Info("all cleanup steps completed")
 		}
 	}
 }
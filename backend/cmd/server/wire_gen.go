@@ -17,8 +17,9 @@ import (
 	"github.com/Wei-Shaw/sub2api/internal/server/middleware"
 	"github.com/Wei-Shaw/sub2api/internal/service"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/sdk/trace"
 	"gorm.io/gorm"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -34,7 +35,11 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	if err != nil {
 		return nil, err
 	}
-	db, err := infrastructure.ProvideDB(configConfig)
+	logger, err := infrastructure.ProvideLogger(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	db, err := infrastructure.ProvideDB(configConfig, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -47,33 +52,64 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	turnstileVerifier := repository.NewTurnstileVerifier()
 	turnstileService := service.NewTurnstileService(settingService, turnstileVerifier)
 	emailQueueService := service.ProvideEmailQueueService(emailService)
-	authService := service.NewAuthService(userRepository, configConfig, settingService, emailService, turnstileService, emailQueueService)
+	userTOTPRepository := repository.NewUserTOTPRepository(db)
+	webAuthnCredentialRepository := repository.NewWebAuthnCredentialRepository(db)
+	twoFactorCache := repository.NewTwoFactorCache(client)
+	twoFactorService := service.NewTwoFactorService(userTOTPRepository, webAuthnCredentialRepository, twoFactorCache, settingService, configConfig)
+	oidcClient := repository.NewOIDCClient()
+	oidcStateCache := repository.NewOIDCStateCache(client)
+	userIdentityRepository := repository.NewUserIdentityRepository(db)
+	oidcService := service.NewOIDCService(settingService, oidcClient, oidcStateCache, userIdentityRepository)
+	authService := service.NewAuthService(userRepository, configConfig, settingService, emailService, turnstileService, emailQueueService, twoFactorService, oidcService)
 	userService := service.NewUserService(userRepository)
 	authHandler := handler.NewAuthHandler(authService, userService)
-	userHandler := handler.NewUserHandler(userService)
+	userNotificationPrefsRepository := repository.NewUserNotificationPrefsRepository(db)
+	notificationDedupCache := repository.NewNotificationDedupCache(client)
+	userSubscriptionRepository := repository.NewUserSubscriptionRepository(db)
+	userNotificationService := service.ProvideUserNotificationService(userNotificationPrefsRepository, notificationDedupCache, userRepository, userSubscriptionRepository, settingService, emailQueueService)
+	userHandler := handler.NewUserHandler(userService, userNotificationService)
+	twoFactorHandler := handler.NewTwoFactorHandler(userService, twoFactorService)
 	apiKeyRepository := repository.NewApiKeyRepository(db)
 	groupRepository := repository.NewGroupRepository(db)
-	userSubscriptionRepository := repository.NewUserSubscriptionRepository(db)
 	apiKeyCache := repository.NewApiKeyCache(client)
 	apiKeyService := service.NewApiKeyService(apiKeyRepository, userRepository, groupRepository, userSubscriptionRepository, apiKeyCache, configConfig)
 	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
 	usageLogRepository := repository.NewUsageLogRepository(db)
-	usageService := service.NewUsageService(usageLogRepository, userRepository)
+	balanceTransactionRepository := repository.NewBalanceTransactionRepository(db)
+	usageService := service.NewUsageService(usageLogRepository, userRepository, balanceTransactionRepository)
 	usageHandler := handler.NewUsageHandler(usageService, apiKeyService)
-	redeemCodeRepository := repository.NewRedeemCodeRepository(db)
 	billingCache := repository.NewBillingCache(client)
-	billingCacheService := service.NewBillingCacheService(billingCache, userRepository, userSubscriptionRepository)
-	subscriptionService := service.NewSubscriptionService(groupRepository, userSubscriptionRepository, billingCacheService)
+	organizationRepository := repository.NewOrganizationRepository(db)
+	pricingRemoteClient := repository.NewPricingRemoteClient()
+	pricingService, err := service.ProvidePricingService(configConfig, pricingRemoteClient)
+	if err != nil {
+		return nil, err
+	}
+	billingService := service.NewBillingService(configConfig, pricingService)
+	billingCacheService := service.NewBillingCacheService(billingCache, userRepository, userSubscriptionRepository, organizationRepository, billingService)
+	balanceService := service.NewBalanceService(balanceTransactionRepository, usageLogRepository, billingCacheService)
+	balanceHandler := handler.NewBalanceHandler(balanceService)
+	redeemCodeRepository := repository.NewRedeemCodeRepository(db)
+	subscriptionService := service.NewSubscriptionService(groupRepository, userSubscriptionRepository, billingCacheService, userNotificationService)
 	redeemCache := repository.NewRedeemCache(client)
-	redeemService := service.NewRedeemService(redeemCodeRepository, userRepository, subscriptionService, redeemCache, billingCacheService)
+	redeemService := service.NewRedeemService(redeemCodeRepository, userRepository, subscriptionService, redeemCache, billingCacheService, balanceTransactionRepository)
 	redeemHandler := handler.NewRedeemHandler(redeemService)
 	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService)
+	auditLogRepository := repository.NewAuditLogRepository(db)
+	auditService := service.NewAuditService(auditLogRepository)
+	organizationService := service.NewOrganizationService(organizationRepository, userRepository, balanceTransactionRepository, apiKeyService, subscriptionService, emailQueueService, settingService, auditService)
+	organizationHandler := handler.NewOrganizationHandler(organizationService)
 	dashboardService := service.NewDashboardService(usageLogRepository)
-	dashboardHandler := admin.NewDashboardHandler(dashboardService)
+	liveEventPubSub := repository.NewLiveEventPubSub(client)
+	liveEventService := service.ProvideLiveEventService(liveEventPubSub)
+	dashboardHandler := admin.NewDashboardHandler(dashboardService, liveEventService)
 	accountRepository := repository.NewAccountRepository(db)
 	proxyRepository := repository.NewProxyRepository(db)
 	proxyExitInfoProber := repository.NewProxyExitInfoProber()
-	adminService := service.NewAdminService(userRepository, groupRepository, accountRepository, proxyRepository, apiKeyRepository, redeemCodeRepository, billingCacheService, proxyExitInfoProber)
+	httpUpstream := repository.NewHTTPUpstream(configConfig)
+	webhookDeliveryRepository := repository.NewWebhookDeliveryRepository(db)
+	webhookService := service.ProvideWebhookService(webhookDeliveryRepository, userSubscriptionRepository, settingService)
+	adminService := service.NewAdminService(userRepository, groupRepository, accountRepository, proxyRepository, apiKeyRepository, redeemCodeRepository, billingCacheService, proxyExitInfoProber, httpUpstream, balanceTransactionRepository, webhookService, auditService)
 	adminUserHandler := admin.NewUserHandler(adminService)
 	groupHandler := admin.NewGroupHandler(adminService)
 	claudeOAuthClient := repository.NewClaudeOAuthClient()
@@ -83,54 +119,71 @@ func initializeApplication(buildInfo handler.BuildInfo) (*Application, error) {
 	geminiOAuthClient := repository.NewGeminiOAuthClient(configConfig)
 	geminiCliCodeAssistClient := repository.NewGeminiCliCodeAssistClient()
 	geminiOAuthService := service.NewGeminiOAuthService(proxyRepository, geminiOAuthClient, geminiCliCodeAssistClient, configConfig)
-	rateLimitService := service.NewRateLimitService(accountRepository, configConfig)
+	rateLimitService := service.NewRateLimitService(accountRepository, configConfig, webhookService)
 	claudeUsageFetcher := repository.NewClaudeUsageFetcher()
 	accountUsageService := service.NewAccountUsageService(accountRepository, usageLogRepository, claudeUsageFetcher)
 	geminiTokenCache := repository.NewGeminiTokenCache(client)
 	geminiTokenProvider := service.NewGeminiTokenProvider(accountRepository, geminiTokenCache, geminiOAuthService)
-	httpUpstream := repository.NewHTTPUpstream(configConfig)
 	accountTestService := service.NewAccountTestService(accountRepository, oAuthService, openAIOAuthService, geminiTokenProvider, httpUpstream)
 	concurrencyCache := repository.NewConcurrencyCache(client)
 	concurrencyService := service.NewConcurrencyService(concurrencyCache)
 	crsSyncService := service.NewCRSSyncService(accountRepository, proxyRepository, oAuthService, openAIOAuthService, geminiOAuthService)
-	accountHandler := admin.NewAccountHandler(adminService, oAuthService, openAIOAuthService, geminiOAuthService, rateLimitService, accountUsageService, accountTestService, concurrencyService, crsSyncService)
+	gatewayCache := repository.NewGatewayCache(client)
+	stickySessionService := service.NewStickySessionService(gatewayCache, accountRepository)
+	accountHandler := admin.NewAccountHandler(adminService, oAuthService, openAIOAuthService, geminiOAuthService, rateLimitService, accountUsageService, accountTestService, concurrencyService, crsSyncService, stickySessionService)
 	oAuthHandler := admin.NewOAuthHandler(oAuthService)
 	openAIOAuthHandler := admin.NewOpenAIOAuthHandler(openAIOAuthService, adminService)
 	geminiOAuthHandler := admin.NewGeminiOAuthHandler(geminiOAuthService)
 	proxyHandler := admin.NewProxyHandler(adminService)
 	adminRedeemHandler := admin.NewRedeemHandler(adminService)
-	settingHandler := admin.NewSettingHandler(settingService, emailService)
+	settingHandler := admin.NewSettingHandler(settingService, emailService, webhookService, auditService)
 	updateCache := repository.NewUpdateCache(client)
 	gitHubReleaseClient := repository.NewGitHubReleaseClient()
 	serviceBuildInfo := provideServiceBuildInfo(buildInfo)
 	updateService := service.ProvideUpdateService(updateCache, gitHubReleaseClient, serviceBuildInfo)
 	systemHandler := handler.ProvideSystemHandler(updateService)
-	adminSubscriptionHandler := admin.NewSubscriptionHandler(subscriptionService)
+	adminSubscriptionHandler := admin.NewSubscriptionHandler(subscriptionService, auditService)
 	adminUsageHandler := admin.NewUsageHandler(usageService, apiKeyService, adminService)
-	adminHandlers := handler.ProvideAdminHandlers(dashboardHandler, adminUserHandler, groupHandler, accountHandler, oAuthHandler, openAIOAuthHandler, geminiOAuthHandler, proxyHandler, adminRedeemHandler, settingHandler, systemHandler, adminSubscriptionHandler, adminUsageHandler)
-	gatewayCache := repository.NewGatewayCache(client)
-	pricingRemoteClient := repository.NewPricingRemoteClient()
-	pricingService, err := service.ProvidePricingService(configConfig, pricingRemoteClient)
-	if err != nil {
-		return nil, err
-	}
-	billingService := service.NewBillingService(configConfig, pricingService)
+	adminBalanceHandler := admin.NewBalanceHandler(balanceService, auditService)
+	requestCaptureRepository := repository.NewRequestCaptureRepository(db)
 	identityCache := repository.NewIdentityCache(client)
 	identityService := service.NewIdentityService(identityCache)
-	gatewayService := service.NewGatewayService(accountRepository, usageLogRepository, userRepository, userSubscriptionRepository, gatewayCache, configConfig, billingService, rateLimitService, billingCacheService, identityService, httpUpstream)
-	geminiMessagesCompatService := service.NewGeminiMessagesCompatService(accountRepository, gatewayCache, geminiTokenProvider, rateLimitService, httpUpstream)
-	gatewayHandler := handler.NewGatewayHandler(gatewayService, geminiMessagesCompatService, userService, concurrencyService, billingCacheService)
-	openAIGatewayService := service.NewOpenAIGatewayService(accountRepository, usageLogRepository, userRepository, userSubscriptionRepository, gatewayCache, configConfig, billingService, rateLimitService, billingCacheService, httpUpstream)
-	openAIGatewayHandler := handler.NewOpenAIGatewayHandler(openAIGatewayService, concurrencyService, billingCacheService)
+	accountScheduler := service.NewAccountScheduler(groupRepository, concurrencyService)
+	apiKeyLimitCache := repository.NewApiKeyLimitCache(client)
+	apiKeyLimitService := service.NewApiKeyLimitService(apiKeyLimitCache, usageLogRepository)
+	gatewayService := service.NewGatewayService(accountRepository, usageLogRepository, userRepository, userSubscriptionRepository, gatewayCache, configConfig, billingService, rateLimitService, billingCacheService, identityService, httpUpstream, accountScheduler, apiKeyLimitService, balanceTransactionRepository, webhookService, userNotificationService)
+	captureService := service.ProvideCaptureService(requestCaptureRepository, accountRepository, apiKeyRepository, gatewayService, configConfig)
+	captureHandler := admin.NewCaptureHandler(captureService)
+	auditHandler := admin.NewAuditHandler(auditService)
+	adminApiKeyRepository := repository.NewAdminApiKeyRepository(db)
+	adminApiKeyService := service.NewAdminApiKeyService(adminApiKeyRepository, settingService)
+	adminApiKeyHandler := admin.NewAdminApiKeyHandler(adminApiKeyService, settingService, auditService)
+	adminOrganizationHandler := admin.NewOrganizationHandler(organizationService)
+	adminHandlers := handler.ProvideAdminHandlers(dashboardHandler, adminUserHandler, groupHandler, accountHandler, oAuthHandler, openAIOAuthHandler, geminiOAuthHandler, proxyHandler, adminRedeemHandler, settingHandler, systemHandler, adminSubscriptionHandler, adminUsageHandler, adminBalanceHandler, captureHandler, auditHandler, adminApiKeyHandler, adminOrganizationHandler)
+	geminiMessagesCompatService := service.NewGeminiMessagesCompatService(accountRepository, gatewayCache, geminiTokenProvider, rateLimitService, httpUpstream, accountScheduler)
+	openAIGatewayService := service.NewOpenAIGatewayService(accountRepository, usageLogRepository, userRepository, userSubscriptionRepository, gatewayCache, configConfig, billingService, rateLimitService, billingCacheService, httpUpstream, accountScheduler, apiKeyLimitService, balanceTransactionRepository, webhookService, userNotificationService)
+	openAIMessagesCompatService := service.NewOpenAIMessagesCompatService(openAIGatewayService, rateLimitService, httpUpstream)
+	responseCache := repository.NewResponseCache(client)
+	responseCacheService := service.NewResponseCacheService(responseCache)
+	gatewayHandler := handler.NewGatewayHandler(gatewayService, geminiMessagesCompatService, openAIMessagesCompatService, openAIGatewayService, userService, concurrencyService, billingCacheService, captureService, responseCacheService, liveEventService)
+	openAIGatewayHandler := handler.NewOpenAIGatewayHandler(openAIGatewayService, concurrencyService, billingCacheService, captureService, responseCacheService, liveEventService)
 	handlerSettingHandler := handler.ProvideSettingHandler(settingService, buildInfo)
-	handlers := handler.ProvideHandlers(authHandler, userHandler, apiKeyHandler, usageHandler, redeemHandler, subscriptionHandler, adminHandlers, gatewayHandler, openAIGatewayHandler, handlerSettingHandler)
-	jwtAuthMiddleware := middleware.NewJWTAuthMiddleware(authService, userService)
-	adminAuthMiddleware := middleware.NewAdminAuthMiddleware(authService, userService, settingService)
-	apiKeyAuthMiddleware := middleware.NewApiKeyAuthMiddleware(apiKeyService, subscriptionService)
-	engine := server.ProvideRouter(configConfig, handlers, jwtAuthMiddleware, adminAuthMiddleware, apiKeyAuthMiddleware, apiKeyService, subscriptionService)
+	accountMetricsCollector := service.NewAccountMetricsCollector(accountRepository, concurrencyService)
+	metricsHandler := handler.NewMetricsHandler(accountMetricsCollector)
+	handlers := handler.ProvideHandlers(authHandler, userHandler, twoFactorHandler, apiKeyHandler, usageHandler, balanceHandler, redeemHandler, subscriptionHandler, organizationHandler, adminHandlers, gatewayHandler, openAIGatewayHandler, handlerSettingHandler, metricsHandler)
+	jwtAuthMiddleware := middleware.NewJWTAuthMiddleware(authService)
+	adminAuthMiddleware := middleware.NewAdminAuthMiddleware(authService, userService, adminApiKeyService)
+	apiKeyAuthMiddleware := middleware.NewApiKeyAuthMiddleware(apiKeyService, subscriptionService, apiKeyLimitService, organizationService)
+	metricsAuthMiddleware := middleware.NewMetricsAuthMiddleware(configConfig, adminApiKeyService)
+	engine := server.ProvideRouter(configConfig, logger, handlers, jwtAuthMiddleware, adminAuthMiddleware, apiKeyAuthMiddleware, apiKeyService, subscriptionService, apiKeyLimitService, organizationService, metricsAuthMiddleware)
 	httpServer := server.ProvideHTTPServer(configConfig, engine)
-	tokenRefreshService := service.ProvideTokenRefreshService(accountRepository, oAuthService, openAIOAuthService, geminiOAuthService, configConfig)
-	v := provideCleanup(db, client, tokenRefreshService, pricingService, emailQueueService, oAuthService, openAIOAuthService, geminiOAuthService)
+	tokenRefreshService := service.ProvideTokenRefreshService(accountRepository, oAuthService, openAIOAuthService, geminiOAuthService, configConfig, webhookService)
+	apiKeyExpiryService := service.ProvideApiKeyExpiryService(apiKeyRepository)
+	tracerProvider, err := infrastructure.ProvideTracerProvider(configConfig, logger)
+	if err != nil {
+		return nil, err
+	}
+	v := provideCleanup(db, client, tokenRefreshService, apiKeyExpiryService, captureService, liveEventService, webhookService, userNotificationService, pricingService, emailQueueService, oAuthService, openAIOAuthService, geminiOAuthService, tracerProvider)
 	application := &Application{
 		Server:  httpServer,
 		Cleanup: v,
@@ -156,11 +209,17 @@ func provideCleanup(
 	db *gorm.DB,
 	rdb *redis.Client,
 	tokenRefresh *service.TokenRefreshService,
+	apiKeyExpiry *service.ApiKeyExpiryService,
+	capture *service.CaptureService,
+	liveEvents *service.LiveEventService,
+	webhooks *service.WebhookService,
+	userNotify *service.UserNotificationService,
 	pricing *service.PricingService,
 	emailQueue *service.EmailQueueService,
 	oauth *service.OAuthService,
 	openaiOAuth *service.OpenAIOAuthService,
 	geminiOAuth *service.GeminiOAuthService,
+	tracerProvider *trace.TracerProvider,
 ) func() {
 	return func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -174,6 +233,26 @@ func provideCleanup(
 				tokenRefresh.Stop()
 				return nil
 			}},
+			{"ApiKeyExpiryService", func() error {
+				apiKeyExpiry.Stop()
+				return nil
+			}},
+			{"CaptureService", func() error {
+				capture.Stop()
+				return nil
+			}},
+			{"LiveEventService", func() error {
+				liveEvents.Stop()
+				return nil
+			}},
+			{"WebhookService", func() error {
+				webhooks.Stop()
+				return nil
+			}},
+			{"UserNotificationService", func() error {
+				userNotify.Stop()
+				return nil
+			}},
 			{"PricingService", func() error {
 				pricing.Stop()
 				return nil
@@ -194,6 +273,12 @@ func provideCleanup(
 				geminiOAuth.Stop()
 				return nil
 			}},
+			{"TracerProvider", func() error {
+				if tracerProvider == nil {
+					return nil
+				}
+				return tracerProvider.Shutdown(ctx)
+			}},
 			{"Redis", func() error {
 				return rdb.Close()
 			}},
@@ -208,18 +293,18 @@ func provideCleanup(
 
 		for _, step := range cleanupSteps {
 			if err := step.fn(); err != nil {
-				log.Printf("[Cleanup] %s failed: %v", step.name, err)
+				slog.Error("cleanup step failed", "step", step.name, "error", err)
 
 			} else {
-				log.Printf("[Cleanup] %s succeeded", step.name)
+				slog.Debug("cleanup step succeeded", "step", step.name)
 			}
 		}
 
 		select {
 		case <-ctx.Done():
-			log.Printf("[Cleanup] Warning: cleanup timed out after 10 seconds")
+			slog.Warn("cleanup timed out after 10 seconds")
 		default:
-			log.Printf("[Cleanup] All cleanup steps completed")
+			slog.Info("all cleanup steps completed")
 		}
 	}
 }
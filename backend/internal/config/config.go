@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -19,6 +20,60 @@ type Config struct {
 	TokenRefresh TokenRefreshConfig `mapstructure:"token_refresh"`
 	Timezone     string             `mapstructure:"timezone"` // e.g. "Asia/Shanghai", "UTC"
 	Gemini       GeminiConfig       `mapstructure:"gemini"`
+	Metrics      MetricsConfig      `mapstructure:"metrics"`
+	Tracing      TracingConfig      `mapstructure:"tracing"`
+	Log          LogConfig          `mapstructure:"log"`
+	Capture      CaptureConfig      `mapstructure:"capture"`
+	WebAuthn     WebAuthnConfig     `mapstructure:"webauthn"`
+}
+
+// WebAuthnConfig WebAuthn/Passkey 二次验证配置，RPID 为空时不启用
+type WebAuthnConfig struct {
+	// 依赖方 ID，一般为站点域名（不含协议和端口），如 example.com
+	RPID string `mapstructure:"rp_id"`
+	// 浏览器弹窗中展示的站点名称
+	RPDisplayName string `mapstructure:"rp_display_name"`
+	// 允许的前端来源，如 https://example.com
+	RPOrigins []string `mapstructure:"rp_origins"`
+}
+
+// CaptureConfig 请求/响应抓取配置（用于排查上游问题）
+type CaptureConfig struct {
+	// 总开关；开启后仍需在 API Key 或账号上单独启用抓取
+	Enabled bool `mapstructure:"enabled"`
+	// 单个请求体/响应体最多保存的字节数，超出部分截断
+	MaxBodyBytes int `mapstructure:"max_body_bytes"`
+	// 抓取记录保留天数
+	RetentionDays int `mapstructure:"retention_days"`
+}
+
+// LogConfig 结构化日志配置
+type LogConfig struct {
+	// 日志级别: debug/info/warn/error
+	Level string `mapstructure:"level"`
+	// 输出格式: json/text
+	Format string `mapstructure:"format"`
+}
+
+// MetricsConfig Prometheus 指标配置
+type MetricsConfig struct {
+	// 是否启用 /metrics
+	Enabled bool `mapstructure:"enabled"`
+	// 是否要求管理员 API Key（x-api-key 或 Authorization: Bearer）
+	RequireAdminKey bool `mapstructure:"require_admin_key"`
+}
+
+// TracingConfig OpenTelemetry 链路追踪配置
+type TracingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// 导出方式: "otlp"（OTLP/HTTP）或 "stdout"（本地调试）
+	Exporter string `mapstructure:"exporter"`
+	// OTLP/HTTP 地址，如 localhost:4318
+	Endpoint string `mapstructure:"endpoint"`
+	// 是否使用 HTTP 而非 HTTPS 连接 collector
+	Insecure    bool    `mapstructure:"insecure"`
+	ServiceName string  `mapstructure:"service_name"`
+	SampleRatio float64 `mapstructure:"sample_ratio"` // 0-1
 }
 
 type GeminiConfig struct {
@@ -66,6 +121,10 @@ type ServerConfig struct {
 	Mode              string `mapstructure:"mode"`                // debug/release
 	ReadHeaderTimeout int    `mapstructure:"read_header_timeout"` // 读取请求头超时（秒）
 	IdleTimeout       int    `mapstructure:"idle_timeout"`        // 空闲连接超时（秒）
+
+	// TrustedProxies 受信任的反向代理 IP/CIDR，只有来自这些地址的请求才会读取 X-Forwarded-For/X-Real-IP。
+	// 为空时不信任任何代理，客户端 IP 取 TCP 连接的来源地址（部署在反向代理后面时需配置）
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // GatewayConfig API网关相关配置
@@ -73,6 +132,16 @@ type GatewayConfig struct {
 	// 等待上游响应头的超时时间（秒），0表示无超时
 	// 注意：这不影响流式数据传输，只控制等待响应头的时间
 	ResponseHeaderTimeout int `mapstructure:"response_header_timeout"`
+
+	// 上游连接池配置（直连与每个代理各自一个连接池）
+	MaxIdleConns        int `mapstructure:"max_idle_conns"`          // 每个连接池的最大空闲连接数
+	MaxIdleConnsPerHost int `mapstructure:"max_idle_conns_per_host"` // 每个目标主机的最大空闲连接数
+	MaxConnsPerHost     int `mapstructure:"max_conns_per_host"`      // 每个目标主机的最大连接数，0表示不限制
+	IdleConnTimeout     int `mapstructure:"idle_conn_timeout"`       // 空闲连接超时（秒）
+
+	// 代理连接池缓存
+	MaxProxyTransports    int `mapstructure:"max_proxy_transports"`     // 最多缓存的代理连接池数量（LRU淘汰）
+	ProxyTransportIdleTTL int `mapstructure:"proxy_transport_idle_ttl"` // 代理连接池闲置多久后关闭（秒）
 }
 
 func (s *ServerConfig) Address() string {
@@ -129,6 +198,8 @@ type DefaultConfig struct {
 	UserBalance     float64 `mapstructure:"user_balance"`
 	ApiKeyPrefix    string  `mapstructure:"api_key_prefix"`
 	RateMultiplier  float64 `mapstructure:"rate_multiplier"`
+	// API Key 轮换后旧 Key 的默认宽限期（分钟）
+	ApiKeyRotationGraceMinutes int `mapstructure:"api_key_rotation_grace_minutes"`
 }
 
 type RateLimitConfig struct {
@@ -201,6 +272,7 @@ func setDefaults() {
 	viper.SetDefault("default.user_balance", 0)
 	viper.SetDefault("default.api_key_prefix", "sk-")
 	viper.SetDefault("default.rate_multiplier", 1.0)
+	viper.SetDefault("default.api_key_rotation_grace_minutes", 60)
 
 	// RateLimit
 	viper.SetDefault("rate_limit.overload_cooldown_minutes", 10)
@@ -218,6 +290,12 @@ func setDefaults() {
 
 	// Gateway
 	viper.SetDefault("gateway.response_header_timeout", 300) // 300秒(5分钟)等待上游响应头，LLM高负载时可能排队较久
+	viper.SetDefault("gateway.max_idle_conns", 100)
+	viper.SetDefault("gateway.max_idle_conns_per_host", 10)
+	viper.SetDefault("gateway.max_conns_per_host", 0)
+	viper.SetDefault("gateway.idle_conn_timeout", 90)
+	viper.SetDefault("gateway.max_proxy_transports", 100)
+	viper.SetDefault("gateway.proxy_transport_idle_ttl", 600) // 10分钟未使用的代理连接池自动关闭
 
 	// TokenRefresh
 	viper.SetDefault("token_refresh.enabled", true)
@@ -226,6 +304,32 @@ func setDefaults() {
 	viper.SetDefault("token_refresh.max_retries", 3)                   // 最多重试3次
 	viper.SetDefault("token_refresh.retry_backoff_seconds", 2)         // 重试退避基础2秒
 
+	// Metrics
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.require_admin_key", true)
+
+	// Log
+	viper.SetDefault("log.level", "info")
+	viper.SetDefault("log.format", "json")
+
+	// Tracing
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.exporter", "otlp")
+	viper.SetDefault("tracing.endpoint", "localhost:4318")
+	viper.SetDefault("tracing.insecure", true)
+	viper.SetDefault("tracing.service_name", "sub2api")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+
+	// Capture
+	viper.SetDefault("capture.enabled", false)
+	viper.SetDefault("capture.max_body_bytes", 1<<20) // 1MB
+	viper.SetDefault("capture.retention_days", 7)
+
+	// WebAuthn
+	viper.SetDefault("webauthn.rp_id", "")
+	viper.SetDefault("webauthn.rp_display_name", "Sub2API")
+	viper.SetDefault("webauthn.rp_origins", []string{})
+
 	// Gemini OAuth - configure via environment variables or config file
 	// GEMINI_OAUTH_CLIENT_ID and GEMINI_OAUTH_CLIENT_SECRET
 	// Default: uses Gemini CLI public credentials (set via environment)
@@ -241,9 +345,43 @@ func (c *Config) Validate() error {
 	if c.JWT.Secret == "change-me-in-production" && c.Server.Mode == "release" {
 		return fmt.Errorf("jwt.secret must be changed in production")
 	}
+	if c.Tracing.Enabled {
+		switch c.Tracing.Exporter {
+		case "otlp", "stdout":
+		default:
+			return fmt.Errorf("tracing.exporter must be one of: otlp, stdout")
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			return fmt.Errorf("tracing.sample_ratio must be between 0 and 1")
+		}
+	}
+	if c.Capture.Enabled {
+		if c.Capture.MaxBodyBytes <= 0 {
+			return fmt.Errorf("capture.max_body_bytes must be positive")
+		}
+		if c.Capture.RetentionDays <= 0 {
+			return fmt.Errorf("capture.retention_days must be positive")
+		}
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if !isValidIPOrCIDR(proxy) {
+			return fmt.Errorf("server.trusted_proxies contains an invalid IP or CIDR: %q", proxy)
+		}
+	}
+	if c.WebAuthn.RPID != "" && len(c.WebAuthn.RPOrigins) == 0 {
+		return fmt.Errorf("webauthn.rp_origins is required when webauthn.rp_id is set")
+	}
 	return nil
 }
 
+func isValidIPOrCIDR(entry string) bool {
+	if strings.Contains(entry, "/") {
+		_, _, err := net.ParseCIDR(entry)
+		return err == nil
+	}
+	return net.ParseIP(entry) != nil
+}
+
 // GetServerAddress returns the server address (host:port) from config file or environment variable.
 // This is a lightweight function that can be used before full config validation,
 // such as during setup wizard startup.
@@ -29,15 +29,16 @@ func NewOAuthHandler(oauthService *service.OAuthService) *OAuthHandler {
 
 // AccountHandler handles admin account management
 type AccountHandler struct {
-	adminService        service.AdminService
-	oauthService        *service.OAuthService
-	openaiOAuthService  *service.OpenAIOAuthService
-	geminiOAuthService  *service.GeminiOAuthService
-	rateLimitService    *service.RateLimitService
-	accountUsageService *service.AccountUsageService
-	accountTestService  *service.AccountTestService
-	concurrencyService  *service.ConcurrencyService
-	crsSyncService      *service.CRSSyncService
+	adminService         service.AdminService
+	oauthService         *service.OAuthService
+	openaiOAuthService   *service.OpenAIOAuthService
+	geminiOAuthService   *service.GeminiOAuthService
+	rateLimitService     *service.RateLimitService
+	accountUsageService  *service.AccountUsageService
+	accountTestService   *service.AccountTestService
+	concurrencyService   *service.ConcurrencyService
+	crsSyncService       *service.CRSSyncService
+	stickySessionService *service.StickySessionService
 }
 
 // NewAccountHandler creates a new admin account handler
@@ -51,17 +52,19 @@ func NewAccountHandler(
 	accountTestService *service.AccountTestService,
 	concurrencyService *service.ConcurrencyService,
 	crsSyncService *service.CRSSyncService,
+	stickySessionService *service.StickySessionService,
 ) *AccountHandler {
 	return &AccountHandler{
-		adminService:        adminService,
-		oauthService:        oauthService,
-		openaiOAuthService:  openaiOAuthService,
-		geminiOAuthService:  geminiOAuthService,
-		rateLimitService:    rateLimitService,
-		accountUsageService: accountUsageService,
-		accountTestService:  accountTestService,
-		concurrencyService:  concurrencyService,
-		crsSyncService:      crsSyncService,
+		adminService:         adminService,
+		oauthService:         oauthService,
+		openaiOAuthService:   openaiOAuthService,
+		geminiOAuthService:   geminiOAuthService,
+		rateLimitService:     rateLimitService,
+		accountUsageService:  accountUsageService,
+		accountTestService:   accountTestService,
+		concurrencyService:   concurrencyService,
+		crsSyncService:       crsSyncService,
+		stickySessionService: stickySessionService,
 	}
 }
 
@@ -75,6 +78,7 @@ type CreateAccountRequest struct {
 	ProxyID     *int64         `json:"proxy_id"`
 	Concurrency int            `json:"concurrency"`
 	Priority    int            `json:"priority"`
+	Weight      int            `json:"weight" binding:"omitempty,min=1"`
 	GroupIDs    []int64        `json:"group_ids"`
 }
 
@@ -88,6 +92,7 @@ type UpdateAccountRequest struct {
 	ProxyID     *int64         `json:"proxy_id"`
 	Concurrency *int           `json:"concurrency"`
 	Priority    *int           `json:"priority"`
+	Weight      *int           `json:"weight" binding:"omitempty,min=1"`
 	Status      string         `json:"status" binding:"omitempty,oneof=active inactive"`
 	GroupIDs    *[]int64       `json:"group_ids"`
 }
@@ -99,6 +104,7 @@ type BulkUpdateAccountsRequest struct {
 	ProxyID     *int64         `json:"proxy_id"`
 	Concurrency *int           `json:"concurrency"`
 	Priority    *int           `json:"priority"`
+	Weight      *int           `json:"weight" binding:"omitempty,min=1"`
 	Status      string         `json:"status" binding:"omitempty,oneof=active inactive error"`
 	GroupIDs    *[]int64       `json:"group_ids"`
 	Credentials map[string]any `json:"credentials"`
@@ -186,6 +192,7 @@ func (h *AccountHandler) Create(c *gin.Context) {
 		ProxyID:     req.ProxyID,
 		Concurrency: req.Concurrency,
 		Priority:    req.Priority,
+		Weight:      req.Weight,
 		GroupIDs:    req.GroupIDs,
 	})
 	if err != nil {
@@ -219,6 +226,7 @@ func (h *AccountHandler) Update(c *gin.Context) {
 		ProxyID:     req.ProxyID,
 		Concurrency: req.Concurrency, // 指针类型，nil 表示未提供
 		Priority:    req.Priority,    // 指针类型，nil 表示未提供
+		Weight:      req.Weight,
 		Status:      req.Status,
 		GroupIDs:    req.GroupIDs,
 	})
@@ -570,6 +578,7 @@ func (h *AccountHandler) BulkUpdate(c *gin.Context) {
 		req.ProxyID != nil ||
 		req.Concurrency != nil ||
 		req.Priority != nil ||
+		req.Weight != nil ||
 		req.Status != "" ||
 		req.GroupIDs != nil ||
 		len(req.Credentials) > 0 ||
@@ -586,6 +595,7 @@ func (h *AccountHandler) BulkUpdate(c *gin.Context) {
 		ProxyID:     req.ProxyID,
 		Concurrency: req.Concurrency,
 		Priority:    req.Priority,
+		Weight:      req.Weight,
 		Status:      req.Status,
 		GroupIDs:    req.GroupIDs,
 		Credentials: req.Credentials,
@@ -826,6 +836,46 @@ func (h *AccountHandler) SetSchedulable(c *gin.Context) {
 	response.Success(c, dto.AccountFromService(account))
 }
 
+// ListStickySessions handles listing sticky sessions bound to an account
+// GET /api/v1/admin/accounts/:id/sticky-sessions
+func (h *AccountHandler) ListStickySessions(c *gin.Context) {
+	accountID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid account ID")
+		return
+	}
+
+	sessions, err := h.stickySessionService.ListByAccount(c.Request.Context(), accountID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.StickySession, 0, len(sessions))
+	for i := range sessions {
+		out = append(out, *dto.StickySessionFromService(&sessions[i]))
+	}
+	response.Success(c, out)
+}
+
+// ClearStickySessions handles clearing sticky sessions bound to an account
+// DELETE /api/v1/admin/accounts/:id/sticky-sessions
+func (h *AccountHandler) ClearStickySessions(c *gin.Context) {
+	accountID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid account ID")
+		return
+	}
+
+	cleared, err := h.stickySessionService.ClearByAccount(c.Request.Context(), accountID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"cleared": cleared})
+}
+
 // GetAvailableModels handles getting available models for an account
 // GET /api/v1/admin/accounts/:id/models
 func (h *AccountHandler) GetAvailableModels(c *gin.Context) {
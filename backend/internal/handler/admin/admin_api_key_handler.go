@@ -0,0 +1,206 @@
+package admin
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/handler/dto"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminApiKeyHandler 管理员 API Key 处理器
+type AdminApiKeyHandler struct {
+	adminApiKeyService *service.AdminApiKeyService
+	settingService     *service.SettingService
+	auditService       *service.AuditService
+}
+
+// NewAdminApiKeyHandler 创建管理员 API Key 处理器
+func NewAdminApiKeyHandler(adminApiKeyService *service.AdminApiKeyService, settingService *service.SettingService, auditService *service.AuditService) *AdminApiKeyHandler {
+	return &AdminApiKeyHandler{
+		adminApiKeyService: adminApiKeyService,
+		settingService:     settingService,
+		auditService:       auditService,
+	}
+}
+
+// CreateAdminApiKeyRequest 创建管理员 API Key 请求
+type CreateAdminApiKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scope     string     `json:"scope" binding:"required,oneof=read_only accounts billing full"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// List 获取全部管理员 API Key
+// GET /api/v1/admin/admin-api-keys
+func (h *AdminApiKeyHandler) List(c *gin.Context) {
+	keys, err := h.adminApiKeyService.List(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.AdminApiKey, 0, len(keys))
+	for i := range keys {
+		out = append(out, *dto.AdminApiKeyFromService(&keys[i]))
+	}
+	response.Success(c, out)
+}
+
+// Create 创建管理员 API Key
+// POST /api/v1/admin/admin-api-keys
+func (h *AdminApiKeyHandler) Create(c *gin.Context) {
+	var req CreateAdminApiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	key, raw, err := h.adminApiKeyService.Create(c.Request.Context(), &service.CreateAdminApiKeyInput{
+		Name:      req.Name,
+		Scope:     req.Scope,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: getAdminIDFromContext(c),
+	})
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	h.auditService.Record(c.Request.Context(), service.AuditActionCreate, service.AuditResourceAdminApiKey, key.ID, nil, key)
+
+	response.Success(c, gin.H{
+		"key":     raw, // 完整 key 只在生成时返回一次
+		"api_key": dto.AdminApiKeyFromService(key),
+	})
+}
+
+// Revoke 吊销管理员 API Key
+// DELETE /api/v1/admin/admin-api-keys/:id
+func (h *AdminApiKeyHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid admin api key ID")
+		return
+	}
+
+	key, err := h.adminApiKeyService.Revoke(c.Request.Context(), id)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	if key.RevokedAt == nil {
+		h.auditService.Record(c.Request.Context(), service.AuditActionDelete, service.AuditResourceAdminApiKey, id, key, nil)
+	}
+
+	response.Success(c, gin.H{"message": "Admin API key revoked"})
+}
+
+// 以下为旧版单一 Key 接口，操作名称为 default 的 Key
+
+// GetDefault 获取默认管理员 API Key 状态
+// GET /api/v1/admin/settings/admin-api-key
+func (h *AdminApiKeyHandler) GetDefault(c *gin.Context) {
+	active, err := h.adminApiKeyService.ActiveByName(c.Request.Context(), service.AdminApiKeyDefaultName)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	if len(active) > 0 {
+		response.Success(c, gin.H{
+			"exists":     true,
+			"masked_key": active[0].KeyHint,
+			"scope":      active[0].Scope,
+		})
+		return
+	}
+
+	// 尚未迁移的旧版 Key
+	maskedKey, exists, err := h.settingService.GetAdminApiKeyStatus(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	scope := ""
+	if exists {
+		scope = service.AdminApiKeyScopeFromRole(h.settingService.GetAdminApiKeyRole(c.Request.Context()))
+	}
+	response.Success(c, gin.H{
+		"exists":     exists,
+		"masked_key": maskedKey,
+		"scope":      scope,
+	})
+}
+
+// RegenerateDefaultRequest 重新生成默认管理员 API Key 请求
+type RegenerateDefaultRequest struct {
+	// Scope 为空时为 full
+	Scope string `json:"scope" binding:"omitempty,oneof=read_only accounts billing full"`
+}
+
+// RegenerateDefault 生成/重新生成默认管理员 API Key，旧的默认 Key 立即失效
+// POST /api/v1/admin/settings/admin-api-key/regenerate
+func (h *AdminApiKeyHandler) RegenerateDefault(c *gin.Context) {
+	var req RegenerateDefaultRequest
+	// 兼容不带请求体的旧调用方式
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "Invalid request: "+err.Error())
+			return
+		}
+	}
+	if req.Scope == "" {
+		req.Scope = service.AdminApiKeyScopeFull
+	}
+
+	if !h.revokeDefault(c) {
+		return
+	}
+	key, raw, err := h.adminApiKeyService.Create(c.Request.Context(), &service.CreateAdminApiKeyInput{
+		Name:      service.AdminApiKeyDefaultName,
+		Scope:     req.Scope,
+		CreatedBy: getAdminIDFromContext(c),
+	})
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	h.auditService.Record(c.Request.Context(), service.AuditActionCreate, service.AuditResourceAdminApiKey, key.ID, nil, key)
+
+	response.Success(c, gin.H{
+		"key": raw, // 完整 key 只在生成时返回一次
+	})
+}
+
+// DeleteDefault 吊销默认管理员 API Key
+// DELETE /api/v1/admin/settings/admin-api-key
+func (h *AdminApiKeyHandler) DeleteDefault(c *gin.Context) {
+	if !h.revokeDefault(c) {
+		return
+	}
+	response.Success(c, gin.H{"message": "Admin API key deleted"})
+}
+
+// revokeDefault 吊销默认 Key 并删除尚未迁移的旧版 Key
+func (h *AdminApiKeyHandler) revokeDefault(c *gin.Context) bool {
+	ctx := c.Request.Context()
+	active, err := h.adminApiKeyService.ActiveByName(ctx, service.AdminApiKeyDefaultName)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return false
+	}
+	for i := range active {
+		if _, err := h.adminApiKeyService.Revoke(ctx, active[i].ID); err != nil {
+			response.ErrorFrom(c, err)
+			return false
+		}
+		h.auditService.Record(ctx, service.AuditActionDelete, service.AuditResourceAdminApiKey, active[i].ID, &active[i], nil)
+	}
+	if err := h.settingService.DeleteAdminApiKey(ctx); err != nil {
+		response.ErrorFrom(c, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/handler/dto"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/timezone"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler handles admin audit log requests
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+// NewAuditHandler creates a new admin audit handler
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// List handles searching audit logs with filters
+// GET /api/v1/admin/audit-logs
+func (h *AuditHandler) List(c *gin.Context) {
+	page, pageSize := response.ParsePagination(c)
+
+	filters := service.AuditLogFilters{
+		Action:       c.Query("action"),
+		ResourceType: c.Query("resource_type"),
+		ResourceID:   c.Query("resource_id"),
+	}
+	if actorIDStr := c.Query("actor_id"); actorIDStr != "" {
+		id, err := strconv.ParseInt(actorIDStr, 10, 64)
+		if err != nil {
+			response.BadRequest(c, "Invalid actor_id")
+			return
+		}
+		filters.ActorID = id
+	}
+	if keyIDStr := c.Query("admin_api_key_id"); keyIDStr != "" {
+		id, err := strconv.ParseInt(keyIDStr, 10, 64)
+		if err != nil {
+			response.BadRequest(c, "Invalid admin_api_key_id")
+			return
+		}
+		filters.AdminApiKeyID = id
+	}
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		t, err := timezone.ParseInLocation("2006-01-02", startDateStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid start_date format, use YYYY-MM-DD")
+			return
+		}
+		filters.StartTime = &t
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		t, err := timezone.ParseInLocation("2006-01-02", endDateStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid end_date format, use YYYY-MM-DD")
+			return
+		}
+		t = t.Add(24*time.Hour - time.Nanosecond)
+		filters.EndTime = &t
+	}
+
+	params := pagination.PaginationParams{Page: page, PageSize: pageSize}
+	logs, result, err := h.auditService.List(c.Request.Context(), params, filters)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.AuditLog, 0, len(logs))
+	for i := range logs {
+		out = append(out, *dto.AuditLogFromService(&logs[i]))
+	}
+	response.Paginated(c, out, result.Total, page, pageSize)
+}
@@ -0,0 +1,123 @@
+package admin
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/handler/dto"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/timezone"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BalanceHandler handles admin balance ledger requests
+type BalanceHandler struct {
+	balanceService *service.BalanceService
+	auditService   *service.AuditService
+}
+
+// NewBalanceHandler creates a new admin balance handler
+func NewBalanceHandler(balanceService *service.BalanceService, auditService *service.AuditService) *BalanceHandler {
+	return &BalanceHandler{
+		balanceService: balanceService,
+		auditService:   auditService,
+	}
+}
+
+// RefundUsageRequest represents the usage refund request payload
+type RefundUsageRequest struct {
+	Notes string `json:"notes"`
+}
+
+// ListTransactions handles listing balance transactions with filters
+// GET /api/v1/admin/balance-transactions
+func (h *BalanceHandler) ListTransactions(c *gin.Context) {
+	page, pageSize := response.ParsePagination(c)
+
+	filters := service.BalanceTransactionFilters{Type: c.Query("type")}
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		id, err := strconv.ParseInt(userIDStr, 10, 64)
+		if err != nil {
+			response.BadRequest(c, "Invalid user_id")
+			return
+		}
+		filters.UserID = id
+	}
+	if orgIDStr := c.Query("organization_id"); orgIDStr != "" {
+		id, err := strconv.ParseInt(orgIDStr, 10, 64)
+		if err != nil {
+			response.BadRequest(c, "Invalid organization_id")
+			return
+		}
+		filters.OrganizationID = id
+	}
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		t, err := timezone.ParseInLocation("2006-01-02", startDateStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid start_date format, use YYYY-MM-DD")
+			return
+		}
+		filters.StartTime = &t
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		t, err := timezone.ParseInLocation("2006-01-02", endDateStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid end_date format, use YYYY-MM-DD")
+			return
+		}
+		t = t.Add(24*time.Hour - time.Nanosecond)
+		filters.EndTime = &t
+	}
+
+	params := pagination.PaginationParams{Page: page, PageSize: pageSize}
+	txs, result, err := h.balanceService.List(c.Request.Context(), params, filters)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.BalanceTransaction, 0, len(txs))
+	for i := range txs {
+		out = append(out, *dto.BalanceTransactionFromService(&txs[i]))
+	}
+	response.Paginated(c, out, result.Total, page, pageSize)
+}
+
+// RefundUsage handles refunding the cost of a usage record
+// POST /api/v1/admin/usage/:id/refund
+func (h *BalanceHandler) RefundUsage(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	usageID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid usage ID")
+		return
+	}
+
+	var req RefundUsageRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	tx, err := h.balanceService.RefundUsage(c.Request.Context(), usageID, subject.UserID, req.Notes)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+	h.auditService.Record(c.Request.Context(), service.AuditActionUpdate, service.AuditResourceBalance, tx.UserID,
+		map[string]any{"balance": tx.BalanceAfter - tx.Amount},
+		map[string]any{"balance": tx.BalanceAfter, "operation": service.BalanceTxTypeRefund, "usage_log_id": usageID, "notes": req.Notes})
+
+	response.Success(c, dto.BalanceTransactionFromService(tx))
+}
@@ -0,0 +1,205 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/Wei-Shaw/sub2api/internal/handler/dto"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CaptureHandler handles admin request capture requests
+type CaptureHandler struct {
+	captureService *service.CaptureService
+}
+
+// NewCaptureHandler creates a new admin capture handler
+func NewCaptureHandler(captureService *service.CaptureService) *CaptureHandler {
+	return &CaptureHandler{
+		captureService: captureService,
+	}
+}
+
+// SetCaptureRequest represents the request body for toggling capture
+type SetCaptureRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// ReplayCaptureRequest represents the request body for replaying a capture
+type ReplayCaptureRequest struct {
+	AccountID int64 `json:"account_id" binding:"required"`
+}
+
+// List handles listing request captures with filters
+// GET /api/v1/admin/captures
+func (h *CaptureHandler) List(c *gin.Context) {
+	page, pageSize := response.ParsePagination(c)
+
+	filters := service.RequestCaptureFilters{Platform: c.Query("platform")}
+	if apiKeyIDStr := c.Query("api_key_id"); apiKeyIDStr != "" {
+		id, err := strconv.ParseInt(apiKeyIDStr, 10, 64)
+		if err != nil {
+			response.BadRequest(c, "Invalid api_key_id")
+			return
+		}
+		filters.ApiKeyID = id
+	}
+	if accountIDStr := c.Query("account_id"); accountIDStr != "" {
+		id, err := strconv.ParseInt(accountIDStr, 10, 64)
+		if err != nil {
+			response.BadRequest(c, "Invalid account_id")
+			return
+		}
+		filters.AccountID = id
+	}
+
+	params := pagination.PaginationParams{Page: page, PageSize: pageSize}
+	captures, result, err := h.captureService.List(c.Request.Context(), params, filters)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.RequestCapture, 0, len(captures))
+	for i := range captures {
+		out = append(out, *dto.RequestCaptureFromService(&captures[i]))
+	}
+	response.Paginated(c, out, result.Total, page, pageSize)
+}
+
+// GetByID handles getting a request capture with bodies
+// GET /api/v1/admin/captures/:id
+func (h *CaptureHandler) GetByID(c *gin.Context) {
+	captureID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid capture ID")
+		return
+	}
+
+	capture, err := h.captureService.GetByID(c.Request.Context(), captureID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.RequestCaptureFromService(capture))
+}
+
+// Download handles downloading a request capture as a JSON file
+// GET /api/v1/admin/captures/:id/download
+func (h *CaptureHandler) Download(c *gin.Context) {
+	captureID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid capture ID")
+		return
+	}
+
+	capture, err := h.captureService.GetByID(c.Request.Context(), captureID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	data, err := json.MarshalIndent(dto.RequestCaptureFromService(capture), "", "  ")
+	if err != nil {
+		response.InternalError(c, "Failed to export capture: "+err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=capture_%d.json", capture.ID))
+	c.Data(200, "application/json", data)
+}
+
+// Delete handles deleting a request capture
+// DELETE /api/v1/admin/captures/:id
+func (h *CaptureHandler) Delete(c *gin.Context) {
+	captureID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid capture ID")
+		return
+	}
+
+	if err := h.captureService.Delete(c.Request.Context(), captureID); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Capture deleted successfully"})
+}
+
+// Replay handles replaying a captured request against another account
+// POST /api/v1/admin/captures/:id/replay
+func (h *CaptureHandler) Replay(c *gin.Context) {
+	captureID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid capture ID")
+		return
+	}
+
+	var req ReplayCaptureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	result, err := h.captureService.Replay(c.Request.Context(), captureID, req.AccountID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.CaptureReplayResultFromService(result))
+}
+
+// SetAccountCapture handles toggling request capture for an account
+// POST /api/v1/admin/accounts/:id/capture
+func (h *CaptureHandler) SetAccountCapture(c *gin.Context) {
+	accountID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid account ID")
+		return
+	}
+
+	var req SetCaptureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	account, err := h.captureService.SetAccountCapture(c.Request.Context(), accountID, req.Enabled)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.AccountFromService(account))
+}
+
+// SetApiKeyCapture handles toggling request capture for an API key
+// POST /api/v1/admin/api-keys/:id/capture
+func (h *CaptureHandler) SetApiKeyCapture(c *gin.Context) {
+	apiKeyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid API key ID")
+		return
+	}
+
+	var req SetCaptureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	apiKey, err := h.captureService.SetApiKeyCapture(c.Request.Context(), apiKeyID, req.Enabled)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.ApiKeyFromService(apiKey))
+}
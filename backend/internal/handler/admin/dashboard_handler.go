@@ -1,9 +1,13 @@
 package admin
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/Wei-Shaw/sub2api/internal/handler/dto"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/timezone"
 	"github.com/Wei-Shaw/sub2api/internal/service"
@@ -14,13 +18,18 @@ import (
 // DashboardHandler handles admin dashboard statistics
 type DashboardHandler struct {
 	dashboardService *service.DashboardService
+	liveEventService *service.LiveEventService
 	startTime        time.Time // Server start time for uptime calculation
 }
 
+// liveEventKeepAlive is the interval for SSE keep-alive comments on the live event stream
+const liveEventKeepAlive = 15 * time.Second
+
 // NewDashboardHandler creates a new admin dashboard handler
-func NewDashboardHandler(dashboardService *service.DashboardService) *DashboardHandler {
+func NewDashboardHandler(dashboardService *service.DashboardService, liveEventService *service.LiveEventService) *DashboardHandler {
 	return &DashboardHandler{
 		dashboardService: dashboardService,
+		liveEventService: liveEventService,
 		startTime:        time.Now(),
 	}
 }
@@ -127,6 +136,71 @@ func (h *DashboardHandler) GetRealtimeMetrics(c *gin.Context) {
 	})
 }
 
+// StreamLiveEvents pushes live gateway request events over SSE
+// GET /api/v1/admin/dashboard/live
+// Query params: platform, account_id
+func (h *DashboardHandler) StreamLiveEvents(c *gin.Context) {
+	platform := c.Query("platform")
+	var accountID int64
+	if accountIDStr := c.Query("account_id"); accountIDStr != "" {
+		id, err := strconv.ParseInt(accountIDStr, 10, 64)
+		if err != nil {
+			response.BadRequest(c, "Invalid account_id")
+			return
+		}
+		accountID = id
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		response.InternalError(c, "Streaming not supported")
+		return
+	}
+
+	events, unsubscribe := h.liveEventService.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(liveEventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if platform != "" && event.Platform != platform {
+				continue
+			}
+			if accountID > 0 && event.AccountID != accountID {
+				continue
+			}
+			data, err := json.Marshal(dto.LiveEventFromService(event))
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
 // GetUsageTrend handles getting usage trend data
 // GET /api/v1/admin/dashboard/trend
 // Query params: start_date, end_date (YYYY-MM-DD), granularity (day/hour), user_id, api_key_id
@@ -33,20 +33,31 @@ type CreateGroupRequest struct {
 	DailyLimitUSD    *float64 `json:"daily_limit_usd"`
 	WeeklyLimitUSD   *float64 `json:"weekly_limit_usd"`
 	MonthlyLimitUSD  *float64 `json:"monthly_limit_usd"`
+	// 账号调度策略：priority/weighted/least_concurrency/round_robin
+	SchedulingStrategy string `json:"scheduling_strategy" binding:"omitempty,oneof=priority weighted least_concurrency round_robin"`
+	// 响应缓存：TTL 为 0 时使用默认值，倍率未指定时为 1
+	ResponseCacheEnabled    bool     `json:"response_cache_enabled"`
+	ResponseCacheTTLSeconds int      `json:"response_cache_ttl_seconds" binding:"min=0"`
+	ResponseCacheMultiplier *float64 `json:"response_cache_multiplier" binding:"omitempty,min=0"`
 }
 
 // UpdateGroupRequest represents update group request
 type UpdateGroupRequest struct {
-	Name             string   `json:"name"`
-	Description      string   `json:"description"`
-	Platform         string   `json:"platform" binding:"omitempty,oneof=anthropic openai gemini"`
-	RateMultiplier   *float64 `json:"rate_multiplier"`
-	IsExclusive      *bool    `json:"is_exclusive"`
-	Status           string   `json:"status" binding:"omitempty,oneof=active inactive"`
-	SubscriptionType string   `json:"subscription_type" binding:"omitempty,oneof=standard subscription"`
-	DailyLimitUSD    *float64 `json:"daily_limit_usd"`
-	WeeklyLimitUSD   *float64 `json:"weekly_limit_usd"`
-	MonthlyLimitUSD  *float64 `json:"monthly_limit_usd"`
+	Name               string   `json:"name"`
+	Description        string   `json:"description"`
+	Platform           string   `json:"platform" binding:"omitempty,oneof=anthropic openai gemini"`
+	RateMultiplier     *float64 `json:"rate_multiplier"`
+	IsExclusive        *bool    `json:"is_exclusive"`
+	Status             string   `json:"status" binding:"omitempty,oneof=active inactive"`
+	SubscriptionType   string   `json:"subscription_type" binding:"omitempty,oneof=standard subscription"`
+	DailyLimitUSD      *float64 `json:"daily_limit_usd"`
+	WeeklyLimitUSD     *float64 `json:"weekly_limit_usd"`
+	MonthlyLimitUSD    *float64 `json:"monthly_limit_usd"`
+	SchedulingStrategy string   `json:"scheduling_strategy" binding:"omitempty,oneof=priority weighted least_concurrency round_robin"`
+
+	ResponseCacheEnabled    *bool    `json:"response_cache_enabled"`
+	ResponseCacheTTLSeconds *int     `json:"response_cache_ttl_seconds" binding:"omitempty,min=0"`
+	ResponseCacheMultiplier *float64 `json:"response_cache_multiplier" binding:"omitempty,min=0"`
 }
 
 // List handles listing all groups with pagination
@@ -130,15 +141,20 @@ func (h *GroupHandler) Create(c *gin.Context) {
 	}
 
 	group, err := h.adminService.CreateGroup(c.Request.Context(), &service.CreateGroupInput{
-		Name:             req.Name,
-		Description:      req.Description,
-		Platform:         req.Platform,
-		RateMultiplier:   req.RateMultiplier,
-		IsExclusive:      req.IsExclusive,
-		SubscriptionType: req.SubscriptionType,
-		DailyLimitUSD:    req.DailyLimitUSD,
-		WeeklyLimitUSD:   req.WeeklyLimitUSD,
-		MonthlyLimitUSD:  req.MonthlyLimitUSD,
+		Name:               req.Name,
+		Description:        req.Description,
+		Platform:           req.Platform,
+		RateMultiplier:     req.RateMultiplier,
+		IsExclusive:        req.IsExclusive,
+		SubscriptionType:   req.SubscriptionType,
+		DailyLimitUSD:      req.DailyLimitUSD,
+		WeeklyLimitUSD:     req.WeeklyLimitUSD,
+		MonthlyLimitUSD:    req.MonthlyLimitUSD,
+		SchedulingStrategy: req.SchedulingStrategy,
+
+		ResponseCacheEnabled:    req.ResponseCacheEnabled,
+		ResponseCacheTTLSeconds: req.ResponseCacheTTLSeconds,
+		ResponseCacheMultiplier: req.ResponseCacheMultiplier,
 	})
 	if err != nil {
 		response.ErrorFrom(c, err)
@@ -164,16 +180,21 @@ func (h *GroupHandler) Update(c *gin.Context) {
 	}
 
 	group, err := h.adminService.UpdateGroup(c.Request.Context(), groupID, &service.UpdateGroupInput{
-		Name:             req.Name,
-		Description:      req.Description,
-		Platform:         req.Platform,
-		RateMultiplier:   req.RateMultiplier,
-		IsExclusive:      req.IsExclusive,
-		Status:           req.Status,
-		SubscriptionType: req.SubscriptionType,
-		DailyLimitUSD:    req.DailyLimitUSD,
-		WeeklyLimitUSD:   req.WeeklyLimitUSD,
-		MonthlyLimitUSD:  req.MonthlyLimitUSD,
+		Name:               req.Name,
+		Description:        req.Description,
+		Platform:           req.Platform,
+		RateMultiplier:     req.RateMultiplier,
+		IsExclusive:        req.IsExclusive,
+		Status:             req.Status,
+		SubscriptionType:   req.SubscriptionType,
+		DailyLimitUSD:      req.DailyLimitUSD,
+		WeeklyLimitUSD:     req.WeeklyLimitUSD,
+		MonthlyLimitUSD:    req.MonthlyLimitUSD,
+		SchedulingStrategy: req.SchedulingStrategy,
+
+		ResponseCacheEnabled:    req.ResponseCacheEnabled,
+		ResponseCacheTTLSeconds: req.ResponseCacheTTLSeconds,
+		ResponseCacheMultiplier: req.ResponseCacheMultiplier,
 	})
 	if err != nil {
 		response.ErrorFrom(c, err)
@@ -1,6 +1,9 @@
 package admin
 
 import (
+	"net/url"
+	"slices"
+
 	"github.com/Wei-Shaw/sub2api/internal/handler/dto"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
 	"github.com/Wei-Shaw/sub2api/internal/service"
@@ -12,13 +15,17 @@ import (
 type SettingHandler struct {
 	settingService *service.SettingService
 	emailService   *service.EmailService
+	webhookService *service.WebhookService
+	auditService   *service.AuditService
 }
 
 // NewSettingHandler 创建系统设置处理器
-func NewSettingHandler(settingService *service.SettingService, emailService *service.EmailService) *SettingHandler {
+func NewSettingHandler(settingService *service.SettingService, emailService *service.EmailService, webhookService *service.WebhookService, auditService *service.AuditService) *SettingHandler {
 	return &SettingHandler{
 		settingService: settingService,
 		emailService:   emailService,
+		webhookService: webhookService,
+		auditService:   auditService,
 	}
 }
 
@@ -52,6 +59,28 @@ func (h *SettingHandler) GetSettings(c *gin.Context) {
 		DocUrl:              settings.DocUrl,
 		DefaultConcurrency:  settings.DefaultConcurrency,
 		DefaultBalance:      settings.DefaultBalance,
+
+		WebhookEnabled:                settings.WebhookEnabled,
+		WebhookUrl:                    settings.WebhookUrl,
+		WebhookSecret:                 settings.WebhookSecret,
+		WebhookFormat:                 settings.WebhookFormat,
+		WebhookEvents:                 settings.WebhookEvents,
+		WebhookBalanceThreshold:       settings.WebhookBalanceThreshold,
+		WebhookSubscriptionExpiryDays: settings.WebhookSubscriptionExpiryDays,
+
+		Admin2FARequired: settings.Admin2FARequired,
+
+		OidcEnabled:       settings.OidcEnabled,
+		OidcProviderName:  settings.OidcProviderName,
+		OidcIssuer:        settings.OidcIssuer,
+		OidcClientId:      settings.OidcClientId,
+		OidcClientSecret:  settings.OidcClientSecret,
+		OidcRedirectUrl:   settings.OidcRedirectUrl,
+		OidcScopes:        settings.OidcScopes,
+		OidcAutoProvision: settings.OidcAutoProvision,
+		OidcGroupsClaim:   settings.OidcGroupsClaim,
+		OidcAdminGroups:   settings.OidcAdminGroups,
+		OidcGroupMapping:  settings.OidcGroupMapping,
 	})
 }
 
@@ -86,6 +115,31 @@ type UpdateSettingsRequest struct {
 	// 默认配置
 	DefaultConcurrency int     `json:"default_concurrency"`
 	DefaultBalance     float64 `json:"default_balance"`
+
+	// Webhook 通知设置
+	WebhookEnabled                bool     `json:"webhook_enabled"`
+	WebhookUrl                    string   `json:"webhook_url"`
+	WebhookSecret                 string   `json:"webhook_secret"`
+	WebhookFormat                 string   `json:"webhook_format"`
+	WebhookEvents                 []string `json:"webhook_events"`
+	WebhookBalanceThreshold       float64  `json:"webhook_balance_threshold"`
+	WebhookSubscriptionExpiryDays int      `json:"webhook_subscription_expiry_days"`
+
+	// 管理员是否必须启用二次验证，未传时保持不变
+	Admin2FARequired *bool `json:"admin_2fa_required"`
+
+	// OIDC 单点登录
+	OidcEnabled       bool               `json:"oidc_enabled"`
+	OidcProviderName  string             `json:"oidc_provider_name"`
+	OidcIssuer        string             `json:"oidc_issuer"`
+	OidcClientId      string             `json:"oidc_client_id"`
+	OidcClientSecret  string             `json:"oidc_client_secret"`
+	OidcRedirectUrl   string             `json:"oidc_redirect_url"`
+	OidcScopes        string             `json:"oidc_scopes"`
+	OidcAutoProvision bool               `json:"oidc_auto_provision"`
+	OidcGroupsClaim   string             `json:"oidc_groups_claim"`
+	OidcAdminGroups   []string           `json:"oidc_admin_groups"`
+	OidcGroupMapping  map[string][]int64 `json:"oidc_group_mapping"`
 }
 
 // UpdateSettings 更新系统设置
@@ -107,6 +161,54 @@ func (h *SettingHandler) UpdateSettings(c *gin.Context) {
 	if req.SmtpPort <= 0 {
 		req.SmtpPort = 587
 	}
+	if req.WebhookFormat == "" {
+		req.WebhookFormat = service.WebhookFormatGeneric
+	}
+	if !service.IsValidWebhookFormat(req.WebhookFormat) {
+		response.BadRequest(c, "Invalid webhook_format")
+		return
+	}
+	if req.WebhookEnabled && req.WebhookUrl == "" {
+		response.BadRequest(c, "webhook_url is required when webhook is enabled")
+		return
+	}
+	for _, e := range req.WebhookEvents {
+		if !slices.Contains(service.WebhookEventTypes, e) {
+			response.BadRequest(c, "Invalid webhook event: "+e)
+			return
+		}
+	}
+	if req.WebhookBalanceThreshold < 0 {
+		req.WebhookBalanceThreshold = 0
+	}
+	if req.WebhookSubscriptionExpiryDays < 0 {
+		req.WebhookSubscriptionExpiryDays = 0
+	}
+	if req.WebhookSubscriptionExpiryDays > service.WebhookDedupeWindowDays {
+		req.WebhookSubscriptionExpiryDays = service.WebhookDedupeWindowDays
+	}
+	if req.OidcEnabled {
+		if req.OidcIssuer == "" || req.OidcClientId == "" || req.OidcRedirectUrl == "" {
+			response.BadRequest(c, "oidc_issuer, oidc_client_id and oidc_redirect_url are required when OIDC is enabled")
+			return
+		}
+		if !isHTTPURL(req.OidcIssuer) || !isHTTPURL(req.OidcRedirectUrl) {
+			response.BadRequest(c, "oidc_issuer and oidc_redirect_url must be http(s) URLs")
+			return
+		}
+	}
+	for group, ids := range req.OidcGroupMapping {
+		for _, id := range ids {
+			if id <= 0 {
+				response.BadRequest(c, "Invalid group ID in oidc_group_mapping: "+group)
+				return
+			}
+		}
+	}
+	admin2FARequired := h.settingService.IsAdmin2FARequired(c.Request.Context())
+	if req.Admin2FARequired != nil {
+		admin2FARequired = *req.Admin2FARequired
+	}
 
 	settings := &service.SystemSettings{
 		RegistrationEnabled: req.RegistrationEnabled,
@@ -129,6 +231,34 @@ func (h *SettingHandler) UpdateSettings(c *gin.Context) {
 		DocUrl:              req.DocUrl,
 		DefaultConcurrency:  req.DefaultConcurrency,
 		DefaultBalance:      req.DefaultBalance,
+
+		WebhookEnabled:                req.WebhookEnabled,
+		WebhookUrl:                    req.WebhookUrl,
+		WebhookSecret:                 req.WebhookSecret,
+		WebhookFormat:                 req.WebhookFormat,
+		WebhookEvents:                 req.WebhookEvents,
+		WebhookBalanceThreshold:       req.WebhookBalanceThreshold,
+		WebhookSubscriptionExpiryDays: req.WebhookSubscriptionExpiryDays,
+
+		Admin2FARequired: admin2FARequired,
+
+		OidcEnabled:       req.OidcEnabled,
+		OidcProviderName:  req.OidcProviderName,
+		OidcIssuer:        req.OidcIssuer,
+		OidcClientId:      req.OidcClientId,
+		OidcClientSecret:  req.OidcClientSecret,
+		OidcRedirectUrl:   req.OidcRedirectUrl,
+		OidcScopes:        req.OidcScopes,
+		OidcAutoProvision: req.OidcAutoProvision,
+		OidcGroupsClaim:   req.OidcGroupsClaim,
+		OidcAdminGroups:   req.OidcAdminGroups,
+		OidcGroupMapping:  req.OidcGroupMapping,
+	}
+
+	previousSettings, err := h.settingService.GetAllSettings(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
 	}
 
 	if err := h.settingService.UpdateSettings(c.Request.Context(), settings); err != nil {
@@ -142,6 +272,7 @@ func (h *SettingHandler) UpdateSettings(c *gin.Context) {
 		response.ErrorFrom(c, err)
 		return
 	}
+	h.auditService.Record(c.Request.Context(), service.AuditActionUpdate, service.AuditResourceSetting, "system", previousSettings, updatedSettings)
 
 	response.Success(c, dto.SystemSettings{
 		RegistrationEnabled: updatedSettings.RegistrationEnabled,
@@ -164,9 +295,37 @@ func (h *SettingHandler) UpdateSettings(c *gin.Context) {
 		DocUrl:              updatedSettings.DocUrl,
 		DefaultConcurrency:  updatedSettings.DefaultConcurrency,
 		DefaultBalance:      updatedSettings.DefaultBalance,
+
+		WebhookEnabled:                updatedSettings.WebhookEnabled,
+		WebhookUrl:                    updatedSettings.WebhookUrl,
+		WebhookSecret:                 updatedSettings.WebhookSecret,
+		WebhookFormat:                 updatedSettings.WebhookFormat,
+		WebhookEvents:                 updatedSettings.WebhookEvents,
+		WebhookBalanceThreshold:       updatedSettings.WebhookBalanceThreshold,
+		WebhookSubscriptionExpiryDays: updatedSettings.WebhookSubscriptionExpiryDays,
+
+		Admin2FARequired: updatedSettings.Admin2FARequired,
+
+		OidcEnabled:       updatedSettings.OidcEnabled,
+		OidcProviderName:  updatedSettings.OidcProviderName,
+		OidcIssuer:        updatedSettings.OidcIssuer,
+		OidcClientId:      updatedSettings.OidcClientId,
+		OidcClientSecret:  updatedSettings.OidcClientSecret,
+		OidcRedirectUrl:   updatedSettings.OidcRedirectUrl,
+		OidcScopes:        updatedSettings.OidcScopes,
+		OidcAutoProvision: updatedSettings.OidcAutoProvision,
+		OidcGroupsClaim:   updatedSettings.OidcGroupsClaim,
+		OidcAdminGroups:   updatedSettings.OidcAdminGroups,
+		OidcGroupMapping:  updatedSettings.OidcGroupMapping,
 	})
 }
 
+// isHTTPURL 检查是否为 http(s) 绝对地址
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
 // TestSmtpRequest 测试SMTP连接请求
 type TestSmtpRequest struct {
 	SmtpHost     string `json:"smtp_host" binding:"required"`
@@ -301,42 +460,123 @@ func (h *SettingHandler) SendTestEmail(c *gin.Context) {
 	response.Success(c, gin.H{"message": "Test email sent successfully"})
 }
 
-// GetAdminApiKey 获取管理员 API Key 状态
-// GET /api/v1/admin/settings/admin-api-key
-func (h *SettingHandler) GetAdminApiKey(c *gin.Context) {
-	maskedKey, exists, err := h.settingService.GetAdminApiKeyStatus(c.Request.Context())
+// TestWebhookRequest 测试 Webhook 请求
+type TestWebhookRequest struct {
+	WebhookUrl    string `json:"webhook_url" binding:"required"`
+	WebhookFormat string `json:"webhook_format"`
+	WebhookSecret string `json:"webhook_secret"`
+}
+
+// TestWebhook 发送测试 Webhook 通知
+// POST /api/v1/admin/settings/test-webhook
+func (h *SettingHandler) TestWebhook(c *gin.Context) {
+	var req TestWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	if req.WebhookFormat == "" {
+		req.WebhookFormat = service.WebhookFormatGeneric
+	}
+	if !service.IsValidWebhookFormat(req.WebhookFormat) {
+		response.BadRequest(c, "Invalid webhook_format")
+		return
+	}
+
+	// 如果未提供密钥，使用已保存的密钥
+	saved, err := h.settingService.GetWebhookConfig(c.Request.Context())
 	if err != nil {
 		response.ErrorFrom(c, err)
 		return
 	}
+	secret := req.WebhookSecret
+	if secret == "" {
+		secret = saved.Secret
+	}
 
-	response.Success(c, gin.H{
-		"exists":     exists,
-		"masked_key": maskedKey,
-	})
+	config := &service.WebhookConfig{
+		Enabled:  true,
+		Url:      req.WebhookUrl,
+		Secret:   secret,
+		Format:   req.WebhookFormat,
+		SiteName: saved.SiteName,
+	}
+	if err := h.webhookService.SendTest(c.Request.Context(), config); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Test webhook sent successfully"})
 }
 
-// RegenerateAdminApiKey 生成/重新生成管理员 API Key
-// POST /api/v1/admin/settings/admin-api-key/regenerate
-func (h *SettingHandler) RegenerateAdminApiKey(c *gin.Context) {
-	key, err := h.settingService.GenerateAdminApiKey(c.Request.Context())
+// GetNotificationSettings 获取用户通知设置与邮件模板
+// GET /api/v1/admin/settings/notifications
+func (h *SettingHandler) GetNotificationSettings(c *gin.Context) {
+	settings, err := h.settingService.GetNotificationSettings(c.Request.Context())
 	if err != nil {
 		response.ErrorFrom(c, err)
 		return
 	}
 
-	response.Success(c, gin.H{
-		"key": key, // 完整 key 只在生成时返回一次
-	})
+	response.Success(c, dto.NotificationSettingsFromService(settings))
 }
 
-// DeleteAdminApiKey 删除管理员 API Key
-// DELETE /api/v1/admin/settings/admin-api-key
-func (h *SettingHandler) DeleteAdminApiKey(c *gin.Context) {
-	if err := h.settingService.DeleteAdminApiKey(c.Request.Context()); err != nil {
+// NotificationTemplateRequest 通知模板
+type NotificationTemplateRequest struct {
+	Type    string `json:"type" binding:"required"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// UpdateNotificationSettingsRequest 更新用户通知设置请求
+type UpdateNotificationSettingsRequest struct {
+	Enabled                bool                          `json:"enabled"`
+	SubscriptionExpiryDays int                           `json:"subscription_expiry_days"`
+	Templates              []NotificationTemplateRequest `json:"templates"`
+}
+
+// UpdateNotificationSettings 更新用户通知设置与邮件模板（模板为空时恢复默认）
+// PUT /api/v1/admin/settings/notifications
+func (h *SettingHandler) UpdateNotificationSettings(c *gin.Context) {
+	var req UpdateNotificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	if req.SubscriptionExpiryDays <= 0 {
+		req.SubscriptionExpiryDays = 3
+	}
+	settings := &service.NotificationSettings{
+		Enabled:                req.Enabled,
+		SubscriptionExpiryDays: req.SubscriptionExpiryDays,
+	}
+	for _, t := range req.Templates {
+		if !service.IsValidNotificationType(t.Type) {
+			response.BadRequest(c, "Invalid notification type: "+t.Type)
+			return
+		}
+		settings.Templates = append(settings.Templates, service.NotificationTemplate{Type: t.Type, Subject: t.Subject, Body: t.Body})
+	}
+
+	previous, err := h.settingService.GetNotificationSettings(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	if err := h.settingService.UpdateNotificationSettings(c.Request.Context(), settings); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	updated, err := h.settingService.GetNotificationSettings(c.Request.Context())
+	if err != nil {
 		response.ErrorFrom(c, err)
 		return
 	}
+	h.auditService.Record(c.Request.Context(), service.AuditActionUpdate, service.AuditResourceSetting, "notifications", previous, updated)
 
-	response.Success(c, gin.H{"message": "Admin API key deleted"})
+	response.Success(c, dto.NotificationSettingsFromService(updated))
 }
@@ -28,12 +28,14 @@ func toResponsePagination(p *pagination.PaginationResult) *response.PaginationRe
 // SubscriptionHandler handles admin subscription management
 type SubscriptionHandler struct {
 	subscriptionService *service.SubscriptionService
+	auditService        *service.AuditService
 }
 
 // NewSubscriptionHandler creates a new admin subscription handler
-func NewSubscriptionHandler(subscriptionService *service.SubscriptionService) *SubscriptionHandler {
+func NewSubscriptionHandler(subscriptionService *service.SubscriptionService, auditService *service.AuditService) *SubscriptionHandler {
 	return &SubscriptionHandler{
 		subscriptionService: subscriptionService,
+		auditService:        auditService,
 	}
 }
 
@@ -149,6 +151,7 @@ func (h *SubscriptionHandler) Assign(c *gin.Context) {
 		response.ErrorFrom(c, err)
 		return
 	}
+	h.auditService.Record(c.Request.Context(), service.AuditActionCreate, service.AuditResourceSubscription, subscription.ID, nil, subscription)
 
 	response.Success(c, dto.UserSubscriptionFromService(subscription))
 }
@@ -176,6 +179,10 @@ func (h *SubscriptionHandler) BulkAssign(c *gin.Context) {
 		response.ErrorFrom(c, err)
 		return
 	}
+	for i := range result.Subscriptions {
+		sub := &result.Subscriptions[i]
+		h.auditService.Record(c.Request.Context(), service.AuditActionCreate, service.AuditResourceSubscription, sub.ID, nil, sub)
+	}
 
 	response.Success(c, dto.BulkAssignResultFromService(result))
 }
@@ -195,11 +202,18 @@ func (h *SubscriptionHandler) Extend(c *gin.Context) {
 		return
 	}
 
+	before, err := h.subscriptionService.GetByID(c.Request.Context(), subscriptionID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
 	subscription, err := h.subscriptionService.ExtendSubscription(c.Request.Context(), subscriptionID, req.Days)
 	if err != nil {
 		response.ErrorFrom(c, err)
 		return
 	}
+	h.auditService.Record(c.Request.Context(), service.AuditActionUpdate, service.AuditResourceSubscription, subscriptionID, before, subscription)
 
 	response.Success(c, dto.UserSubscriptionFromService(subscription))
 }
@@ -213,11 +227,18 @@ func (h *SubscriptionHandler) Revoke(c *gin.Context) {
 		return
 	}
 
+	before, err := h.subscriptionService.GetByID(c.Request.Context(), subscriptionID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
 	err = h.subscriptionService.RevokeSubscription(c.Request.Context(), subscriptionID)
 	if err != nil {
 		response.ErrorFrom(c, err)
 		return
 	}
+	h.auditService.Record(c.Request.Context(), service.AuditActionDelete, service.AuditResourceSubscription, subscriptionID, before, nil)
 
 	response.Success(c, gin.H{"message": "Subscription revoked successfully"})
 }
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/handler/dto"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
@@ -29,6 +30,34 @@ type CreateAPIKeyRequest struct {
 	Name      string  `json:"name" binding:"required"`
 	GroupID   *int64  `json:"group_id"`   // nullable
 	CustomKey *string `json:"custom_key"` // 可选的自定义key
+
+	RateLimitRPM    int      `json:"rate_limit_rpm" binding:"omitempty,min=0"`
+	RateLimitTPM    int      `json:"rate_limit_tpm" binding:"omitempty,min=0"`
+	DailyLimitUSD   *float64 `json:"daily_limit_usd" binding:"omitempty,min=0"`
+	MonthlyLimitUSD *float64 `json:"monthly_limit_usd" binding:"omitempty,min=0"`
+
+	AllowedModels []string `json:"allowed_models"`
+	AllowedIPs    []string `json:"allowed_ips"`
+
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (req *CreateAPIKeyRequest) toService() service.CreateApiKeyRequest {
+	return service.CreateApiKeyRequest{
+		Name:      req.Name,
+		GroupID:   req.GroupID,
+		CustomKey: req.CustomKey,
+
+		RateLimitRPM:    req.RateLimitRPM,
+		RateLimitTPM:    req.RateLimitTPM,
+		DailyLimitUSD:   req.DailyLimitUSD,
+		MonthlyLimitUSD: req.MonthlyLimitUSD,
+
+		AllowedModels: req.AllowedModels,
+		AllowedIPs:    req.AllowedIPs,
+
+		ExpiresAt: req.ExpiresAt,
+	}
 }
 
 // UpdateAPIKeyRequest represents the update API key request payload
@@ -36,6 +65,26 @@ type UpdateAPIKeyRequest struct {
 	Name    string `json:"name"`
 	GroupID *int64 `json:"group_id"`
 	Status  string `json:"status" binding:"omitempty,oneof=active inactive"`
+
+	// 限流与花费上限，传 0 表示取消限制
+	RateLimitRPM    *int     `json:"rate_limit_rpm" binding:"omitempty,min=0"`
+	RateLimitTPM    *int     `json:"rate_limit_tpm" binding:"omitempty,min=0"`
+	DailyLimitUSD   *float64 `json:"daily_limit_usd" binding:"omitempty,min=0"`
+	MonthlyLimitUSD *float64 `json:"monthly_limit_usd" binding:"omitempty,min=0"`
+
+	// 模型/IP 允许列表，传空数组表示取消限制
+	AllowedModels *[]string `json:"allowed_models"`
+	AllowedIPs    *[]string `json:"allowed_ips"`
+
+	// 设置新的过期时间，或通过 clear_expires_at 取消过期
+	ExpiresAt      *time.Time `json:"expires_at"`
+	ClearExpiresAt bool       `json:"clear_expires_at"`
+}
+
+// RotateAPIKeyRequest represents the rotate API key request payload
+type RotateAPIKeyRequest struct {
+	// 旧 Key 的宽限期（分钟），不传时使用系统默认值，0 表示立即失效
+	GracePeriodMinutes *int `json:"grace_period_minutes" binding:"omitempty,min=0,max=10080"`
 }
 
 // List handles listing user's API keys with pagination
@@ -108,12 +157,7 @@ func (h *APIKeyHandler) Create(c *gin.Context) {
 		return
 	}
 
-	svcReq := service.CreateApiKeyRequest{
-		Name:      req.Name,
-		GroupID:   req.GroupID,
-		CustomKey: req.CustomKey,
-	}
-	key, err := h.apiKeyService.Create(c.Request.Context(), subject.UserID, svcReq)
+	key, err := h.apiKeyService.Create(c.Request.Context(), subject.UserID, req.toService())
 	if err != nil {
 		response.ErrorFrom(c, err)
 		return
@@ -151,6 +195,14 @@ func (h *APIKeyHandler) Update(c *gin.Context) {
 	if req.Status != "" {
 		svcReq.Status = &req.Status
 	}
+	svcReq.RateLimitRPM = req.RateLimitRPM
+	svcReq.RateLimitTPM = req.RateLimitTPM
+	svcReq.DailyLimitUSD = req.DailyLimitUSD
+	svcReq.MonthlyLimitUSD = req.MonthlyLimitUSD
+	svcReq.AllowedModels = req.AllowedModels
+	svcReq.AllowedIPs = req.AllowedIPs
+	svcReq.ExpiresAt = req.ExpiresAt
+	svcReq.ClearExpiresAt = req.ClearExpiresAt
 
 	key, err := h.apiKeyService.Update(c.Request.Context(), keyID, subject.UserID, svcReq)
 	if err != nil {
@@ -161,6 +213,44 @@ func (h *APIKeyHandler) Update(c *gin.Context) {
 	response.Success(c, dto.ApiKeyFromService(key))
 }
 
+// Rotate issues a new secret for an API key, keeping the old one valid for a grace period
+// POST /api/v1/api-keys/:id/rotate
+func (h *APIKeyHandler) Rotate(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	keyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid key ID")
+		return
+	}
+
+	var req RotateAPIKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "Invalid request: "+err.Error())
+			return
+		}
+	}
+
+	var gracePeriod *time.Duration
+	if req.GracePeriodMinutes != nil {
+		d := time.Duration(*req.GracePeriodMinutes) * time.Minute
+		gracePeriod = &d
+	}
+
+	key, err := h.apiKeyService.Rotate(c.Request.Context(), keyID, subject.UserID, gracePeriod)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.ApiKeyFromService(key))
+}
+
 // Delete handles deleting an API key
 // DELETE /api/v1/api-keys/:id
 func (h *APIKeyHandler) Delete(c *gin.Context) {
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"encoding/json"
+
 	"github.com/Wei-Shaw/sub2api/internal/handler/dto"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
 	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
@@ -50,11 +52,81 @@ type LoginRequest struct {
 	TurnstileToken string `json:"turnstile_token"`
 }
 
+// ForgotPasswordRequest 找回密码请求
+type ForgotPasswordRequest struct {
+	Email          string `json:"email" binding:"required,email"`
+	TurnstileToken string `json:"turnstile_token"`
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Email       string `json:"email" binding:"required,email"`
+	VerifyCode  string `json:"verify_code" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// SendChangeEmailCodeRequest 发送修改邮箱验证码请求
+type SendChangeEmailCodeRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+}
+
+// ChangeEmailRequest 修改邮箱请求
+type ChangeEmailRequest struct {
+	NewEmail   string `json:"new_email" binding:"required,email"`
+	VerifyCode string `json:"verify_code" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+}
+
 // AuthResponse 认证响应格式（匹配前端期望）
 type AuthResponse struct {
 	AccessToken string    `json:"access_token"`
 	TokenType   string    `json:"token_type"`
 	User        *dto.User `json:"user"`
+	// 登录时首次绑定 TOTP 返回的恢复码，仅展示一次
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// TwoFactorChallengeResponse 需要二次验证时的登录响应
+type TwoFactorChallengeResponse struct {
+	RequiresTwoFactor  bool     `json:"requires_2fa"`
+	PreAuthToken       string   `json:"pre_auth_token"`
+	ExpiresIn          int      `json:"expires_in"` // 预认证 token 有效秒数
+	Methods            []string `json:"methods"`
+	EnrollmentRequired bool     `json:"enrollment_required"`
+}
+
+// OIDCAuthorizeResponse 单点登录跳转地址
+type OIDCAuthorizeResponse struct {
+	AuthURL string `json:"auth_url"`
+}
+
+// OIDCCallbackRequest IdP 回调参数，由前端回调页转交
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// PreAuthRequest 携带预认证 token 的请求
+type PreAuthRequest struct {
+	PreAuthToken string `json:"pre_auth_token" binding:"required"`
+}
+
+// TwoFactorVerifyRequest 提交 TOTP 验证码或恢复码
+type TwoFactorVerifyRequest struct {
+	PreAuthToken string `json:"pre_auth_token" binding:"required"`
+	Code         string `json:"code" binding:"required"`
+}
+
+// WebAuthnLoginFinishRequest 提交浏览器返回的安全密钥断言
+type WebAuthnLoginFinishRequest struct {
+	PreAuthToken string          `json:"pre_auth_token" binding:"required"`
+	Credential   json.RawMessage `json:"credential" binding:"required"`
+}
+
+// TOTPSetupResponse TOTP 密钥信息，otpauth_url 可渲染为二维码
+type TOTPSetupResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURL string `json:"otpauth_url"`
 }
 
 // Register handles user registration
@@ -129,19 +201,164 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, user, err := h.authService.Login(c.Request.Context(), req.Email, req.Password)
+	result, err := h.authService.Login(c.Request.Context(), req.Email, req.Password)
 	if err != nil {
 		response.ErrorFrom(c, err)
 		return
 	}
 
+	respondLogin(c, result)
+}
+
+// respondLogin 返回登录结果，需要二次验证时返回预认证 token
+func respondLogin(c *gin.Context, result *service.LoginResult) {
+	if result.RequiresTwoFactor() {
+		response.Success(c, TwoFactorChallengeResponse{
+			RequiresTwoFactor:  true,
+			PreAuthToken:       result.PreAuthToken,
+			ExpiresIn:          int(service.PreAuthTokenTTL.Seconds()),
+			Methods:            result.TwoFactorMethods,
+			EnrollmentRequired: result.EnrollmentRequired,
+		})
+		return
+	}
+
 	response.Success(c, AuthResponse{
-		AccessToken: token,
+		AccessToken: result.Token,
 		TokenType:   "Bearer",
-		User:        dto.UserFromService(user),
+		User:        dto.UserFromService(result.User),
 	})
 }
 
+// OIDCAuthorize 获取单点登录跳转地址
+// GET /api/v1/auth/oidc/authorize
+func (h *AuthHandler) OIDCAuthorize(c *gin.Context) {
+	authURL, err := h.authService.OIDCAuthorizationURL(c.Request.Context())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, OIDCAuthorizeResponse{AuthURL: authURL})
+}
+
+// OIDCCallback 使用 IdP 回调的 code 完成登录
+// POST /api/v1/auth/oidc/callback
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	var req OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	result, err := h.authService.LoginWithOIDC(c.Request.Context(), req.Code, req.State)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	respondLogin(c, result)
+}
+
+func twoFactorAuthResponse(result *service.TwoFactorLoginResult) AuthResponse {
+	return AuthResponse{
+		AccessToken:   result.Token,
+		TokenType:     "Bearer",
+		User:          dto.UserFromService(result.User),
+		RecoveryCodes: result.RecoveryCodes,
+	}
+}
+
+// VerifyTwoFactor 使用 TOTP 验证码或恢复码完成登录
+// POST /api/v1/auth/2fa/verify
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	result, err := h.authService.VerifyTwoFactor(c.Request.Context(), req.PreAuthToken, req.Code)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, twoFactorAuthResponse(result))
+}
+
+// BeginWebAuthnLogin 获取安全密钥验证参数
+// POST /api/v1/auth/2fa/webauthn/begin
+func (h *AuthHandler) BeginWebAuthnLogin(c *gin.Context) {
+	var req PreAuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	assertion, err := h.authService.BeginWebAuthnLogin(c.Request.Context(), req.PreAuthToken)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, assertion)
+}
+
+// FinishWebAuthnLogin 校验安全密钥并完成登录
+// POST /api/v1/auth/2fa/webauthn/finish
+func (h *AuthHandler) FinishWebAuthnLogin(c *gin.Context) {
+	var req WebAuthnLoginFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	result, err := h.authService.FinishWebAuthnLogin(c.Request.Context(), req.PreAuthToken, req.Credential)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, twoFactorAuthResponse(result))
+}
+
+// SetupTwoFactorEnrollment 必须启用二次验证但尚未设置的账号在登录阶段生成 TOTP 密钥
+// POST /api/v1/auth/2fa/enroll/setup
+func (h *AuthHandler) SetupTwoFactorEnrollment(c *gin.Context) {
+	var req PreAuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	setup, err := h.authService.BeginTwoFactorEnrollment(c.Request.Context(), req.PreAuthToken)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, TOTPSetupResponse{Secret: setup.Secret, OtpauthURL: setup.URI})
+}
+
+// CompleteTwoFactorEnrollment 确认 TOTP 绑定并完成登录，返回恢复码
+// POST /api/v1/auth/2fa/enroll/enable
+func (h *AuthHandler) CompleteTwoFactorEnrollment(c *gin.Context) {
+	var req TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	result, err := h.authService.CompleteTwoFactorEnrollment(c.Request.Context(), req.PreAuthToken, req.Code)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, twoFactorAuthResponse(result))
+}
+
 // GetCurrentUser handles getting current authenticated user
 // GET /api/v1/auth/me
 func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
@@ -159,3 +376,103 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 
 	response.Success(c, dto.UserFromService(user))
 }
+
+// ForgotPassword 发送重置密码验证码
+// POST /api/v1/auth/forgot-password
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	// Turnstile 验证
+	if err := h.authService.VerifyTurnstile(c.Request.Context(), req.TurnstileToken, c.ClientIP()); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	result, err := h.authService.RequestPasswordReset(c.Request.Context(), req.Email, c.ClientIP())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	// 无论邮箱是否已注册都返回相同结果
+	response.Success(c, SendVerifyCodeResponse{
+		Message:   "If the email is registered, a verification code has been sent",
+		Countdown: result.Countdown,
+	})
+}
+
+// ResetPassword 使用验证码重置密码
+// POST /api/v1/auth/reset-password
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	if err := h.authService.ResetPassword(c.Request.Context(), req.Email, req.VerifyCode, req.NewPassword); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Password reset successfully"})
+}
+
+// SendChangeEmailCode 向新邮箱发送验证码
+// POST /api/v1/auth/change-email/send-code
+func (h *AuthHandler) SendChangeEmailCode(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req SendChangeEmailCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	result, err := h.authService.SendChangeEmailCode(c.Request.Context(), subject.UserID, req.NewEmail, c.ClientIP())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, SendVerifyCodeResponse{
+		Message:   "Verification code sent successfully",
+		Countdown: result.Countdown,
+	})
+}
+
+// ChangeEmail 修改邮箱，成功后返回新的 token
+// POST /api/v1/auth/change-email
+func (h *AuthHandler) ChangeEmail(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	token, user, err := h.authService.ChangeEmail(c.Request.Context(), subject.UserID, req.NewEmail, req.VerifyCode, req.Password)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, AuthResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		User:        dto.UserFromService(user),
+	})
+}
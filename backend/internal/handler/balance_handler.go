@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/handler/dto"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/timezone"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BalanceHandler handles balance ledger requests
+type BalanceHandler struct {
+	balanceService *service.BalanceService
+}
+
+// NewBalanceHandler creates a new BalanceHandler
+func NewBalanceHandler(balanceService *service.BalanceService) *BalanceHandler {
+	return &BalanceHandler{
+		balanceService: balanceService,
+	}
+}
+
+// ListTransactions handles listing the current user's balance transactions
+// GET /api/v1/user/balance-transactions
+func (h *BalanceHandler) ListTransactions(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	page, pageSize := response.ParsePagination(c)
+
+	filters, ok := parseBalanceTransactionFilters(c)
+	if !ok {
+		return
+	}
+
+	params := pagination.PaginationParams{Page: page, PageSize: pageSize}
+	txs, result, err := h.balanceService.ListByUser(c.Request.Context(), subject.UserID, params, filters)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.BalanceTransaction, 0, len(txs))
+	for i := range txs {
+		item := dto.BalanceTransactionFromService(&txs[i])
+		// 普通用户不应看到操作人
+		item.OperatorID = nil
+		out = append(out, *item)
+	}
+	response.Paginated(c, out, result.Total, page, pageSize)
+}
+
+// parseBalanceTransactionFilters 解析流水查询的 type/start_date/end_date 参数，失败时已写入 400 响应
+func parseBalanceTransactionFilters(c *gin.Context) (service.BalanceTransactionFilters, bool) {
+	filters := service.BalanceTransactionFilters{Type: c.Query("type")}
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		t, err := timezone.ParseInLocation("2006-01-02", startDateStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid start_date format, use YYYY-MM-DD")
+			return filters, false
+		}
+		filters.StartTime = &t
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		t, err := timezone.ParseInLocation("2006-01-02", endDateStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid end_date format, use YYYY-MM-DD")
+			return filters, false
+		}
+		t = t.Add(24*time.Hour - time.Nanosecond)
+		filters.EndTime = &t
+	}
+	return filters, true
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/metrics"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tracing"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChatCompletions handles OpenAI Chat Completions compatible endpoint backed by Claude accounts
+// POST /v1/chat/completions
+func (h *GatewayHandler) ChatCompletions(c *gin.Context) {
+	apiKey, ok := middleware2.GetApiKeyFromContext(c)
+	if !ok {
+		chatCompletionsErrorResponse(c, http.StatusUnauthorized, "authentication_error", "Invalid API key")
+		return
+	}
+
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		chatCompletionsErrorResponse(c, http.StatusInternalServerError, "api_error", "User context not found")
+		return
+	}
+
+	if apiKey.Group != nil && apiKey.Group.Platform != service.PlatformAnthropic {
+		chatCompletionsErrorResponse(c, http.StatusBadRequest, "invalid_request_error", "API key group platform is not anthropic")
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		chatCompletionsErrorResponse(c, http.StatusBadRequest, "invalid_request_error", "Failed to read request body")
+		return
+	}
+	if len(body) == 0 {
+		chatCompletionsErrorResponse(c, http.StatusBadRequest, "invalid_request_error", "Request body is empty")
+		return
+	}
+
+	var req struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		chatCompletionsErrorResponse(c, http.StatusBadRequest, "invalid_request_error", "Failed to parse request body")
+		return
+	}
+
+	// 检查 API Key 的模型/IP 允许列表
+	if err := apiKey.CheckAccess(req.Model, c.ClientIP()); err != nil {
+		chatCompletionsErrorResponse(c, http.StatusForbidden, "permission_error", infraerrors.Message(err))
+		return
+	}
+
+	// 记录请求数与耗时指标
+	defer observeGatewayRequest(c, apiKey, service.PlatformAnthropic, req.Model, time.Now())
+
+	// 按需抓取请求/响应（API Key 或上游账号开启抓取时落库）
+	capture := h.captureService.Begin(c, apiKey, service.PlatformAnthropic, req.Model, body)
+	defer h.captureService.Finish(capture)
+
+	// 推送实时请求事件到管理后台
+	h.liveEventService.Begin(c, apiKey, service.PlatformAnthropic, req.Model, req.Stream)
+	defer h.liveEventService.Finish(c)
+
+	// 先转换一次用于校验请求和计算粘性会话hash
+	claudeBody, err := service.ConvertChatCompletionsToClaudeBody(body)
+	if err != nil {
+		chatCompletionsErrorResponse(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	// OpenAI 协议没有 ping 事件规范
+	concurrencyHelper := NewConcurrencyHelper(h.concurrencyHelper.concurrencyService, SSEPingFormatNone)
+	streamStarted := false
+
+	subscription, _ := middleware2.GetSubscriptionFromContext(c)
+
+	// 0. 检查wait队列是否已满
+	maxWait := service.CalculateMaxWait(subject.Concurrency)
+	canWait, err := concurrencyHelper.IncrementWaitCount(c.Request.Context(), subject.UserID, maxWait)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Warn("increment wait count failed", logger.Err(err))
+	} else if !canWait {
+		chatCompletionsErrorResponse(c, http.StatusTooManyRequests, "rate_limit_error", "Too many pending requests, please retry later")
+		return
+	}
+	defer concurrencyHelper.DecrementWaitCount(c.Request.Context(), subject.UserID)
+
+	// 1. 获取用户并发槽位
+	userReleaseFunc, err := concurrencyHelper.AcquireUserSlotWithWait(c, subject.UserID, subject.Concurrency, req.Stream, &streamStarted)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Warn("acquire user concurrency slot failed", logger.Err(err))
+		chatCompletionsStreamingAwareError(c, http.StatusTooManyRequests, "rate_limit_error", "Concurrency limit exceeded for user, please retry later", streamStarted)
+		return
+	}
+	if userReleaseFunc != nil {
+		defer userReleaseFunc()
+	}
+
+	// 2. Wait后二次检查余额/订阅
+	if err := h.billingCacheService.CheckBillingEligibility(c.Request.Context(), apiKey.User, apiKey, apiKey.Group, subscription); err != nil {
+		logger.FromContext(c.Request.Context()).Info("billing eligibility check failed after wait", logger.Err(err))
+		chatCompletionsStreamingAwareError(c, http.StatusForbidden, "billing_error", err.Error(), streamStarted)
+		return
+	}
+
+	// 余额模式：按最大可能费用预授权，由 RecordUsage 结算后释放
+	balanceHold, err := h.billingCacheService.ReserveBalance(c.Request.Context(), apiKey, subscription, req.Model, claudeBody, parseMaxOutputTokens(claudeBody))
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Info("reserve balance hold failed", logger.Err(err))
+		chatCompletionsStreamingAwareError(c, http.StatusForbidden, "billing_error", err.Error(), streamStarted)
+		return
+	}
+	defer balanceHold.Release()
+
+	sessionHash := h.gatewayService.GenerateSessionHash(apiKey.ID, claudeBody)
+
+	const maxAccountSwitches = 3
+	switchCount := 0
+	failedAccountIDs := make(map[int64]struct{})
+	lastFailoverStatus := 0
+
+	for {
+		account, err := h.gatewayService.SelectAccountForModelWithExclusions(c.Request.Context(), apiKey.GroupID, sessionHash, req.Model, failedAccountIDs)
+		if err != nil {
+			if len(failedAccountIDs) == 0 {
+				chatCompletionsStreamingAwareError(c, http.StatusServiceUnavailable, "api_error", "No available accounts: "+err.Error(), streamStarted)
+				return
+			}
+			status, errType, errMsg := h.mapUpstreamError(lastFailoverStatus)
+			chatCompletionsStreamingAwareError(c, status, errType, errMsg, streamStarted)
+			return
+		}
+		onAccountSelected(c, account)
+
+		// 3. 获取账号并发槽位
+		accountReleaseFunc, err := concurrencyHelper.AcquireAccountSlotWithWait(c, account.ID, account.Concurrency, req.Stream, &streamStarted)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Warn("acquire account concurrency slot failed", logger.Err(err))
+			chatCompletionsStreamingAwareError(c, http.StatusTooManyRequests, "rate_limit_error", "Concurrency limit exceeded for account, please retry later", streamStarted)
+			return
+		}
+
+		result, err := h.gatewayService.ForwardChatCompletions(c.Request.Context(), c, account, body)
+		if accountReleaseFunc != nil {
+			accountReleaseFunc()
+		}
+		if err != nil {
+			var failoverErr *service.UpstreamFailoverError
+			if errors.As(err, &failoverErr) {
+				metrics.IncFailover(account.Platform, failoverErr.StatusCode)
+				service.PublishLiveFailover(c.Request.Context(), account, failoverErr.StatusCode)
+				failedAccountIDs[account.ID] = struct{}{}
+				lastFailoverStatus = failoverErr.StatusCode
+				if switchCount >= maxAccountSwitches {
+					status, errType, errMsg := h.mapUpstreamError(lastFailoverStatus)
+					chatCompletionsStreamingAwareError(c, status, errType, errMsg, streamStarted)
+					return
+				}
+				switchCount++
+				metrics.IncAccountSwitch(account.Platform)
+				logger.FromContext(c.Request.Context()).Warn("upstream error, switching account", "status", failoverErr.StatusCode, "switch", switchCount, "max_switches", maxAccountSwitches)
+				continue
+			}
+			// 错误响应已在ForwardChatCompletions中处理，这里只记录日志
+			logger.FromContext(c.Request.Context()).Error("forward chat completions failed", logger.Err(err))
+			return
+		}
+
+		// 异步记录使用量
+		go func(parentCtx context.Context, result *service.ForwardResult, usedAccount *service.Account, hold *service.BalanceHold) {
+			ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+			defer cancel()
+			if err := h.gatewayService.RecordUsage(ctx, &service.RecordUsageInput{
+				Result:       result,
+				ApiKey:       apiKey,
+				User:         apiKey.User,
+				Account:      usedAccount,
+				Subscription: subscription,
+				BalanceHold:  hold,
+			}); err != nil {
+				logger.FromContext(ctx).Error("record usage failed", logger.Err(err))
+			}
+		}(tracing.Detach(c.Request.Context()), result, account, balanceHold.Transfer())
+		return
+	}
+}
+
+// chatCompletionsStreamingAwareError 流已开始时以 SSE data 形式发送 OpenAI 格式错误
+func chatCompletionsStreamingAwareError(c *gin.Context, status int, errType, message string, streamStarted bool) {
+	if streamStarted {
+		flusher, ok := c.Writer.(http.Flusher)
+		if ok {
+			payload, _ := json.Marshal(gin.H{"error": gin.H{"type": errType, "message": message}})
+			if _, err := fmt.Fprintf(c.Writer, "data: %s\n\n", payload); err != nil {
+				_ = c.Error(err)
+			}
+			flusher.Flush()
+		}
+		return
+	}
+	chatCompletionsErrorResponse(c, status, errType, message)
+}
+
+// chatCompletionsErrorResponse returns OpenAI API format error response
+func chatCompletionsErrorResponse(c *gin.Context, status int, errType, message string) {
+	c.JSON(status, gin.H{
+		"error": gin.H{
+			"type":    errType,
+			"message": message,
+		},
+	})
+}
@@ -1,6 +1,10 @@
 package dto
 
-import "github.com/Wei-Shaw/sub2api/internal/service"
+import (
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+)
 
 func UserFromServiceShallow(u *service.User) *User {
 	if u == nil {
@@ -49,12 +53,28 @@ func ApiKeyFromService(k *service.ApiKey) *ApiKey {
 		return nil
 	}
 	return &ApiKey{
-		ID:        k.ID,
-		UserID:    k.UserID,
-		Key:       k.Key,
-		Name:      k.Name,
-		GroupID:   k.GroupID,
-		Status:    k.Status,
+		ID:      k.ID,
+		UserID:  k.UserID,
+		Key:     k.Key,
+		Name:    k.Name,
+		GroupID: k.GroupID,
+		Status:  k.Status,
+
+		OrganizationID: k.OrganizationID,
+
+		RateLimitRPM:    k.RateLimitRPM,
+		RateLimitTPM:    k.RateLimitTPM,
+		DailyLimitUSD:   k.DailyLimitUSD,
+		MonthlyLimitUSD: k.MonthlyLimitUSD,
+		AllowedModels:   k.AllowedModels,
+		AllowedIPs:      k.AllowedIPs,
+
+		ExpiresAt:            k.ExpiresAt,
+		IsExpired:            k.IsExpired(time.Now()),
+		PreviousKeyExpiresAt: k.PreviousKeyExpiresAt,
+
+		CaptureEnabled: k.CaptureEnabled,
+
 		CreatedAt: k.CreatedAt,
 		UpdatedAt: k.UpdatedAt,
 		User:      UserFromServiceShallow(k.User),
@@ -67,20 +87,25 @@ func GroupFromServiceShallow(g *service.Group) *Group {
 		return nil
 	}
 	return &Group{
-		ID:               g.ID,
-		Name:             g.Name,
-		Description:      g.Description,
-		Platform:         g.Platform,
-		RateMultiplier:   g.RateMultiplier,
-		IsExclusive:      g.IsExclusive,
-		Status:           g.Status,
-		SubscriptionType: g.SubscriptionType,
-		DailyLimitUSD:    g.DailyLimitUSD,
-		WeeklyLimitUSD:   g.WeeklyLimitUSD,
-		MonthlyLimitUSD:  g.MonthlyLimitUSD,
-		CreatedAt:        g.CreatedAt,
-		UpdatedAt:        g.UpdatedAt,
-		AccountCount:     g.AccountCount,
+		ID:                 g.ID,
+		Name:               g.Name,
+		Description:        g.Description,
+		Platform:           g.Platform,
+		RateMultiplier:     g.RateMultiplier,
+		IsExclusive:        g.IsExclusive,
+		Status:             g.Status,
+		SubscriptionType:   g.SubscriptionType,
+		DailyLimitUSD:      g.DailyLimitUSD,
+		WeeklyLimitUSD:     g.WeeklyLimitUSD,
+		MonthlyLimitUSD:    g.MonthlyLimitUSD,
+		SchedulingStrategy: g.GetSchedulingStrategy(),
+
+		ResponseCacheEnabled:    g.ResponseCacheEnabled,
+		ResponseCacheTTLSeconds: g.ResponseCacheTTLSeconds,
+		ResponseCacheMultiplier: g.ResponseCacheMultiplier,
+		CreatedAt:               g.CreatedAt,
+		UpdatedAt:               g.UpdatedAt,
+		AccountCount:            g.AccountCount,
 	}
 }
 
@@ -113,12 +138,14 @@ func AccountFromServiceShallow(a *service.Account) *Account {
 		ProxyID:             a.ProxyID,
 		Concurrency:         a.Concurrency,
 		Priority:            a.Priority,
+		Weight:              a.GetWeight(),
 		Status:              a.Status,
 		ErrorMessage:        a.ErrorMessage,
 		LastUsedAt:          a.LastUsedAt,
 		CreatedAt:           a.CreatedAt,
 		UpdatedAt:           a.UpdatedAt,
 		Schedulable:         a.Schedulable,
+		CaptureEnabled:      a.CaptureEnabled,
 		RateLimitedAt:       a.RateLimitedAt,
 		RateLimitResetAt:    a.RateLimitResetAt,
 		OverloadUntil:       a.OverloadUntil,
@@ -214,6 +241,165 @@ func RedeemCodeFromService(rc *service.RedeemCode) *RedeemCode {
 	}
 }
 
+func BalanceTransactionFromService(t *service.BalanceTransaction) *BalanceTransaction {
+	if t == nil {
+		return nil
+	}
+	return &BalanceTransaction{
+		ID:             t.ID,
+		UserID:         t.UserID,
+		OrganizationID: t.OrganizationID,
+		Type:           t.Type,
+		Amount:         t.Amount,
+		BalanceAfter:   t.BalanceAfter,
+		UsageLogID:     t.UsageLogID,
+		RedeemCodeID:   t.RedeemCodeID,
+		OperatorID:     t.OperatorID,
+		Notes:          t.Notes,
+		CreatedAt:      t.CreatedAt,
+		User:           UserFromServiceShallow(t.User),
+	}
+}
+
+func AdminApiKeyFromService(k *service.AdminApiKey) *AdminApiKey {
+	if k == nil {
+		return nil
+	}
+	status := "active"
+	switch {
+	case k.RevokedAt != nil:
+		status = "revoked"
+	case !k.IsActive(time.Now()):
+		status = "expired"
+	}
+	return &AdminApiKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		KeyHint:    k.KeyHint,
+		Scope:      k.Scope,
+		Status:     status,
+		CreatedBy:  k.CreatedBy,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+func AuditLogFromService(l *service.AuditLog) *AuditLog {
+	if l == nil {
+		return nil
+	}
+	changes := make(map[string]AuditChange, len(l.Changes))
+	for k, v := range l.Changes {
+		changes[k] = AuditChange{Before: v.Before, After: v.After}
+	}
+	return &AuditLog{
+		ID:            l.ID,
+		ActorID:       l.ActorID,
+		ActorRole:     l.ActorRole,
+		AuthMethod:    l.AuthMethod,
+		AdminApiKeyID: l.AdminApiKeyID,
+		IP:            l.IP,
+		Action:        l.Action,
+		ResourceType:  l.ResourceType,
+		ResourceID:    l.ResourceID,
+		Changes:       changes,
+		CreatedAt:     l.CreatedAt,
+	}
+}
+
+func StickySessionFromService(s *service.StickySession) *StickySession {
+	if s == nil {
+		return nil
+	}
+	return &StickySession{
+		Platform:    s.Platform,
+		GroupID:     s.GroupID,
+		ApiKeyID:    s.ApiKeyID,
+		SessionHash: s.SessionHash,
+		AccountID:   s.AccountID,
+		ExpiresAt:   s.ExpiresAt,
+	}
+}
+
+func LiveEventFromService(e *service.LiveEvent) *LiveEvent {
+	if e == nil {
+		return nil
+	}
+	return &LiveEvent{
+		Type:         e.Type,
+		RequestID:    e.RequestID,
+		UserID:       e.UserID,
+		UserEmail:    e.UserEmail,
+		ApiKeyID:     e.ApiKeyID,
+		GroupID:      e.GroupID,
+		Platform:     e.Platform,
+		Model:        e.Model,
+		Stream:       e.Stream,
+		AccountID:    e.AccountID,
+		AccountName:  e.AccountName,
+		StatusCode:   e.StatusCode,
+		InputTokens:  e.InputTokens,
+		OutputTokens: e.OutputTokens,
+		CacheHit:     e.CacheHit,
+		LatencyMs:    e.LatencyMs,
+		Timestamp:    e.Timestamp,
+	}
+}
+
+func RequestCaptureFromService(c *service.RequestCapture) *RequestCapture {
+	if c == nil {
+		return nil
+	}
+	return &RequestCapture{
+		ID:                c.ID,
+		UserID:            c.UserID,
+		ApiKeyID:          c.ApiKeyID,
+		AccountID:         c.AccountID,
+		Platform:          c.Platform,
+		Model:             c.Model,
+		RequestID:         c.RequestID,
+		Method:            c.Method,
+		Path:              c.Path,
+		ClientHeaders:     c.ClientHeaders,
+		ClientBody:        c.ClientBody,
+		UpstreamURL:       c.UpstreamURL,
+		UpstreamHeaders:   c.UpstreamHeaders,
+		UpstreamBody:      c.UpstreamBody,
+		ResponseStatus:    c.ResponseStatus,
+		ResponseHeaders:   c.ResponseHeaders,
+		ResponseBody:      c.ResponseBody,
+		RequestTruncated:  c.RequestTruncated,
+		ResponseTruncated: c.ResponseTruncated,
+		DurationMs:        c.DurationMs,
+		CreatedAt:         c.CreatedAt,
+	}
+}
+
+func CaptureReplayResultFromService(r *service.CaptureReplayResult) *CaptureReplayResult {
+	if r == nil {
+		return nil
+	}
+	out := &CaptureReplayResult{
+		CaptureID:  r.CaptureID,
+		AccountID:  r.AccountID,
+		StatusCode: r.StatusCode,
+		Body:       r.Body,
+		DurationMs: r.DurationMs,
+		Error:      r.Error,
+	}
+	if r.Usage != nil {
+		out.Usage = &ClaudeUsage{
+			InputTokens:              r.Usage.InputTokens,
+			OutputTokens:             r.Usage.OutputTokens,
+			CacheCreationInputTokens: r.Usage.CacheCreationInputTokens,
+			CacheReadInputTokens:     r.Usage.CacheReadInputTokens,
+		}
+	}
+	return out
+}
+
 func UsageLogFromService(l *service.UsageLog) *UsageLog {
 	if l == nil {
 		return nil
@@ -224,9 +410,11 @@ func UsageLogFromService(l *service.UsageLog) *UsageLog {
 		ApiKeyID:              l.ApiKeyID,
 		AccountID:             l.AccountID,
 		RequestID:             l.RequestID,
+		TraceID:               l.TraceID,
 		Model:                 l.Model,
 		GroupID:               l.GroupID,
 		SubscriptionID:        l.SubscriptionID,
+		OrganizationID:        l.OrganizationID,
 		InputTokens:           l.InputTokens,
 		OutputTokens:          l.OutputTokens,
 		CacheCreationTokens:   l.CacheCreationTokens,
@@ -242,6 +430,7 @@ func UsageLogFromService(l *service.UsageLog) *UsageLog {
 		RateMultiplier:        l.RateMultiplier,
 		BillingType:           l.BillingType,
 		Stream:                l.Stream,
+		CacheHit:              l.CacheHit,
 		DurationMs:            l.DurationMs,
 		FirstTokenMs:          l.FirstTokenMs,
 		CreatedAt:             l.CreatedAt,
@@ -265,6 +454,38 @@ func SettingFromService(s *service.Setting) *Setting {
 	}
 }
 
+func UserNotificationPrefsFromService(p *service.UserNotificationPrefs) *UserNotificationPrefs {
+	if p == nil {
+		return nil
+	}
+	return &UserNotificationPrefs{
+		BalanceLowEnabled:         p.BalanceLowEnabled,
+		BalanceThreshold:          p.BalanceThreshold,
+		SubscriptionExpiryEnabled: p.SubscriptionExpiryEnabled,
+		QuotaReachedEnabled:       p.QuotaReachedEnabled,
+	}
+}
+
+func NotificationSettingsFromService(s *service.NotificationSettings) *NotificationSettings {
+	if s == nil {
+		return nil
+	}
+	out := &NotificationSettings{
+		Enabled:                s.Enabled,
+		SubscriptionExpiryDays: s.SubscriptionExpiryDays,
+		Templates:              make([]NotificationTemplate, 0, len(s.Templates)),
+	}
+	for _, t := range s.Templates {
+		out.Templates = append(out.Templates, NotificationTemplate{
+			Type:         t.Type,
+			Subject:      t.Subject,
+			Body:         t.Body,
+			Placeholders: service.NotificationPlaceholders[t.Type],
+		})
+	}
+	return out
+}
+
 func UserSubscriptionFromService(sub *service.UserSubscription) *UserSubscription {
 	if sub == nil {
 		return nil
@@ -273,6 +494,7 @@ func UserSubscriptionFromService(sub *service.UserSubscription) *UserSubscriptio
 		ID:                 sub.ID,
 		UserID:             sub.UserID,
 		GroupID:            sub.GroupID,
+		OrganizationID:     sub.OrganizationID,
 		StartsAt:           sub.StartsAt,
 		ExpiresAt:          sub.ExpiresAt,
 		Status:             sub.Status,
@@ -308,3 +530,94 @@ func BulkAssignResultFromService(r *service.BulkAssignResult) *BulkAssignResult
 		Errors:        r.Errors,
 	}
 }
+
+func TwoFactorStatusFromService(s *service.TwoFactorStatus) *TwoFactorStatus {
+	if s == nil {
+		return nil
+	}
+	out := &TwoFactorStatus{
+		Required:               s.Required,
+		TOTPEnabled:            s.TOTPEnabled,
+		RecoveryCodesRemaining: s.RecoveryCodesRemaining,
+		WebAuthnAvailable:      s.WebAuthnAvailable,
+		WebAuthnCredentials:    make([]WebAuthnCredential, 0, len(s.WebAuthnCredentials)),
+	}
+	for i := range s.WebAuthnCredentials {
+		out.WebAuthnCredentials = append(out.WebAuthnCredentials, *WebAuthnCredentialFromService(&s.WebAuthnCredentials[i]))
+	}
+	return out
+}
+
+func WebAuthnCredentialFromService(c *service.WebAuthnCredential) *WebAuthnCredential {
+	if c == nil {
+		return nil
+	}
+	return &WebAuthnCredential{
+		ID:         c.ID,
+		Name:       c.Name,
+		CreatedAt:  c.CreatedAt,
+		LastUsedAt: c.LastUsedAt,
+	}
+}
+
+func OrganizationFromService(o *service.Organization) *Organization {
+	if o == nil {
+		return nil
+	}
+	return &Organization{
+		ID:        o.ID,
+		Name:      o.Name,
+		Balance:   o.Balance,
+		Status:    o.Status,
+		OwnerID:   o.OwnerID,
+		CreatedAt: o.CreatedAt,
+		UpdatedAt: o.UpdatedAt,
+	}
+}
+
+func OrganizationMemberFromService(m *service.OrganizationMember) *OrganizationMember {
+	if m == nil {
+		return nil
+	}
+	return &OrganizationMember{
+		ID:              m.ID,
+		OrganizationID:  m.OrganizationID,
+		UserID:          m.UserID,
+		Role:            m.Role,
+		MonthlyLimitUSD: m.MonthlyLimitUSD,
+		MonthlyUsageUSD: m.CurrentMonthUsage(time.Now()),
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+		User:            UserFromServiceShallow(m.User),
+		Organization:    OrganizationFromService(m.Organization),
+	}
+}
+
+func OrganizationInvitationFromService(i *service.OrganizationInvitation) *OrganizationInvitation {
+	if i == nil {
+		return nil
+	}
+	return &OrganizationInvitation{
+		ID:             i.ID,
+		OrganizationID: i.OrganizationID,
+		Email:          i.Email,
+		Role:           i.Role,
+		InvitedBy:      i.InvitedBy,
+		ExpiresAt:      i.ExpiresAt,
+		CreatedAt:      i.CreatedAt,
+	}
+}
+
+func OrganizationMemberUsageFromService(u *service.OrganizationMemberUsage) *OrganizationMemberUsage {
+	if u == nil {
+		return nil
+	}
+	return &OrganizationMemberUsage{
+		UserID:       u.UserID,
+		Requests:     u.Requests,
+		InputTokens:  u.InputTokens,
+		OutputTokens: u.OutputTokens,
+		TotalCost:    u.TotalCost,
+		ActualCost:   u.ActualCost,
+	}
+}
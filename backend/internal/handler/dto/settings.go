@@ -26,6 +26,28 @@ type SystemSettings struct {
 
 	DefaultConcurrency int     `json:"default_concurrency"`
 	DefaultBalance     float64 `json:"default_balance"`
+
+	WebhookEnabled                bool     `json:"webhook_enabled"`
+	WebhookUrl                    string   `json:"webhook_url"`
+	WebhookSecret                 string   `json:"webhook_secret,omitempty"`
+	WebhookFormat                 string   `json:"webhook_format"`
+	WebhookEvents                 []string `json:"webhook_events"`
+	WebhookBalanceThreshold       float64  `json:"webhook_balance_threshold"`
+	WebhookSubscriptionExpiryDays int      `json:"webhook_subscription_expiry_days"`
+
+	Admin2FARequired bool `json:"admin_2fa_required"`
+
+	OidcEnabled       bool               `json:"oidc_enabled"`
+	OidcProviderName  string             `json:"oidc_provider_name"`
+	OidcIssuer        string             `json:"oidc_issuer"`
+	OidcClientId      string             `json:"oidc_client_id"`
+	OidcClientSecret  string             `json:"oidc_client_secret,omitempty"`
+	OidcRedirectUrl   string             `json:"oidc_redirect_url"`
+	OidcScopes        string             `json:"oidc_scopes"`
+	OidcAutoProvision bool               `json:"oidc_auto_provision"`
+	OidcGroupsClaim   string             `json:"oidc_groups_claim"`
+	OidcAdminGroups   []string           `json:"oidc_admin_groups"`
+	OidcGroupMapping  map[string][]int64 `json:"oidc_group_mapping"`
 }
 
 type PublicSettings struct {
@@ -39,5 +61,22 @@ type PublicSettings struct {
 	ApiBaseUrl          string `json:"api_base_url"`
 	ContactInfo         string `json:"contact_info"`
 	DocUrl              string `json:"doc_url"`
+	OidcEnabled         bool   `json:"oidc_enabled"`
+	OidcProviderName    string `json:"oidc_provider_name"`
 	Version             string `json:"version"`
 }
+
+// NotificationTemplate is an admin-editable user notification email template.
+type NotificationTemplate struct {
+	Type         string   `json:"type"`
+	Subject      string   `json:"subject"`
+	Body         string   `json:"body"`
+	Placeholders []string `json:"placeholders"`
+}
+
+// NotificationSettings is the admin user-notification settings payload.
+type NotificationSettings struct {
+	Enabled                bool                   `json:"enabled"`
+	SubscriptionExpiryDays int                    `json:"subscription_expiry_days"`
+	Templates              []NotificationTemplate `json:"templates"`
+}
@@ -21,12 +21,28 @@ type User struct {
 }
 
 type ApiKey struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	Key       string    `json:"key"`
-	Name      string    `json:"name"`
-	GroupID   *int64    `json:"group_id"`
-	Status    string    `json:"status"`
+	ID      int64  `json:"id"`
+	UserID  int64  `json:"user_id"`
+	Key     string `json:"key"`
+	Name    string `json:"name"`
+	GroupID *int64 `json:"group_id"`
+	Status  string `json:"status"`
+
+	OrganizationID *int64 `json:"organization_id,omitempty"`
+
+	RateLimitRPM    int      `json:"rate_limit_rpm"`
+	RateLimitTPM    int      `json:"rate_limit_tpm"`
+	DailyLimitUSD   *float64 `json:"daily_limit_usd"`
+	MonthlyLimitUSD *float64 `json:"monthly_limit_usd"`
+	AllowedModels   []string `json:"allowed_models"`
+	AllowedIPs      []string `json:"allowed_ips"`
+
+	ExpiresAt            *time.Time `json:"expires_at"`
+	IsExpired            bool       `json:"is_expired"`
+	PreviousKeyExpiresAt *time.Time `json:"previous_key_expires_at"`
+
+	CaptureEnabled bool `json:"capture_enabled"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
@@ -48,6 +64,12 @@ type Group struct {
 	WeeklyLimitUSD   *float64 `json:"weekly_limit_usd"`
 	MonthlyLimitUSD  *float64 `json:"monthly_limit_usd"`
 
+	SchedulingStrategy string `json:"scheduling_strategy"`
+
+	ResponseCacheEnabled    bool    `json:"response_cache_enabled"`
+	ResponseCacheTTLSeconds int     `json:"response_cache_ttl_seconds"`
+	ResponseCacheMultiplier float64 `json:"response_cache_multiplier"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
@@ -65,13 +87,15 @@ type Account struct {
 	ProxyID      *int64         `json:"proxy_id"`
 	Concurrency  int            `json:"concurrency"`
 	Priority     int            `json:"priority"`
+	Weight       int            `json:"weight"`
 	Status       string         `json:"status"`
 	ErrorMessage string         `json:"error_message"`
 	LastUsedAt   *time.Time     `json:"last_used_at"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 
-	Schedulable bool `json:"schedulable"`
+	Schedulable    bool `json:"schedulable"`
+	CaptureEnabled bool `json:"capture_enabled"`
 
 	RateLimitedAt    *time.Time `json:"rate_limited_at"`
 	RateLimitResetAt *time.Time `json:"rate_limit_reset_at"`
@@ -134,16 +158,143 @@ type RedeemCode struct {
 	Group *Group `json:"group,omitempty"`
 }
 
+type BalanceTransaction struct {
+	ID             int64     `json:"id"`
+	UserID         int64     `json:"user_id"`
+	OrganizationID *int64    `json:"organization_id,omitempty"`
+	Type           string    `json:"type"`
+	Amount         float64   `json:"amount"`
+	BalanceAfter   float64   `json:"balance_after"`
+	UsageLogID     *int64    `json:"usage_log_id"`
+	RedeemCodeID   *int64    `json:"redeem_code_id"`
+	OperatorID     *int64    `json:"operator_id"`
+	Notes          string    `json:"notes"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	User *User `json:"user,omitempty"`
+}
+
+type AdminApiKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	KeyHint    string     `json:"key_hint"`
+	Scope      string     `json:"scope"`
+	Status     string     `json:"status"` // active / expired / revoked
+	CreatedBy  int64      `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type AuditLog struct {
+	ID            int64                  `json:"id"`
+	ActorID       int64                  `json:"actor_id"`
+	ActorRole     string                 `json:"actor_role"`
+	AuthMethod    string                 `json:"auth_method"`
+	AdminApiKeyID *int64                 `json:"admin_api_key_id"`
+	IP            string                 `json:"ip"`
+	Action        string                 `json:"action"`
+	ResourceType  string                 `json:"resource_type"`
+	ResourceID    string                 `json:"resource_id"`
+	Changes       map[string]AuditChange `json:"changes"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+type StickySession struct {
+	Platform    string    `json:"platform"`
+	GroupID     int64     `json:"group_id"`
+	ApiKeyID    int64     `json:"api_key_id"`
+	SessionHash string    `json:"session_hash"`
+	AccountID   int64     `json:"account_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type LiveEvent struct {
+	Type      string `json:"type"`
+	RequestID string `json:"request_id"`
+
+	UserID    int64  `json:"user_id"`
+	UserEmail string `json:"user_email"`
+	ApiKeyID  int64  `json:"api_key_id"`
+	GroupID   *int64 `json:"group_id"`
+	Platform  string `json:"platform"`
+	Model     string `json:"model"`
+	Stream    bool   `json:"stream"`
+
+	AccountID   int64  `json:"account_id,omitempty"`
+	AccountName string `json:"account_name,omitempty"`
+	StatusCode  int    `json:"status_code,omitempty"`
+
+	InputTokens  int  `json:"input_tokens"`
+	OutputTokens int  `json:"output_tokens"`
+	CacheHit     bool `json:"cache_hit"`
+
+	LatencyMs int64     `json:"latency_ms"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type RequestCapture struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	ApiKeyID  int64  `json:"api_key_id"`
+	AccountID *int64 `json:"account_id"`
+	Platform  string `json:"platform"`
+	Model     string `json:"model"`
+	RequestID string `json:"request_id"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+
+	// 列表接口不返回请求/响应正文
+	ClientHeaders   map[string]string `json:"client_headers,omitempty"`
+	ClientBody      string            `json:"client_body,omitempty"`
+	UpstreamURL     string            `json:"upstream_url"`
+	UpstreamHeaders map[string]string `json:"upstream_headers,omitempty"`
+	UpstreamBody    string            `json:"upstream_body,omitempty"`
+	ResponseStatus  int               `json:"response_status"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+
+	RequestTruncated  bool      `json:"request_truncated"`
+	ResponseTruncated bool      `json:"response_truncated"`
+	DurationMs        int64     `json:"duration_ms"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type ClaudeUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+type CaptureReplayResult struct {
+	CaptureID  int64        `json:"capture_id"`
+	AccountID  int64        `json:"account_id"`
+	StatusCode int          `json:"status_code"`
+	Body       string       `json:"body"`
+	Usage      *ClaudeUsage `json:"usage"`
+	DurationMs int64        `json:"duration_ms"`
+	Error      string       `json:"error,omitempty"`
+}
+
 type UsageLog struct {
 	ID        int64  `json:"id"`
 	UserID    int64  `json:"user_id"`
 	ApiKeyID  int64  `json:"api_key_id"`
 	AccountID int64  `json:"account_id"`
 	RequestID string `json:"request_id"`
+	TraceID   string `json:"trace_id,omitempty"`
 	Model     string `json:"model"`
 
 	GroupID        *int64 `json:"group_id"`
 	SubscriptionID *int64 `json:"subscription_id"`
+	OrganizationID *int64 `json:"organization_id,omitempty"`
 
 	InputTokens         int `json:"input_tokens"`
 	OutputTokens        int `json:"output_tokens"`
@@ -163,6 +314,7 @@ type UsageLog struct {
 
 	BillingType  int8 `json:"billing_type"`
 	Stream       bool `json:"stream"`
+	CacheHit     bool `json:"cache_hit"`
 	DurationMs   *int `json:"duration_ms"`
 	FirstTokenMs *int `json:"first_token_ms"`
 
@@ -187,6 +339,8 @@ type UserSubscription struct {
 	UserID  int64 `json:"user_id"`
 	GroupID int64 `json:"group_id"`
 
+	OrganizationID *int64 `json:"organization_id,omitempty"`
+
 	StartsAt  time.Time `json:"starts_at"`
 	ExpiresAt time.Time `json:"expires_at"`
 	Status    string    `json:"status"`
@@ -217,3 +371,73 @@ type BulkAssignResult struct {
 	Subscriptions []UserSubscription `json:"subscriptions"`
 	Errors        []string           `json:"errors"`
 }
+
+// UserNotificationPrefs is a user's notification opt-in/opt-out settings.
+type UserNotificationPrefs struct {
+	BalanceLowEnabled         bool    `json:"balance_low_enabled"`
+	BalanceThreshold          float64 `json:"balance_threshold"`
+	SubscriptionExpiryEnabled bool    `json:"subscription_expiry_enabled"`
+	QuotaReachedEnabled       bool    `json:"quota_reached_enabled"`
+}
+
+// TwoFactorStatus is the current user's two-factor authentication status.
+type TwoFactorStatus struct {
+	Required               bool                 `json:"required"`
+	TOTPEnabled            bool                 `json:"totp_enabled"`
+	RecoveryCodesRemaining int                  `json:"recovery_codes_remaining"`
+	WebAuthnAvailable      bool                 `json:"webauthn_available"`
+	WebAuthnCredentials    []WebAuthnCredential `json:"webauthn_credentials"`
+}
+
+// WebAuthnCredential is a registered security key or passkey.
+type WebAuthnCredential struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type Organization struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Balance   float64   `json:"balance"`
+	Status    string    `json:"status"`
+	OwnerID   int64     `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrganizationMember is a member of an organization. MonthlyUsageUSD is the
+// member's spend from the organization balance in the current (UTC) month.
+type OrganizationMember struct {
+	ID              int64     `json:"id"`
+	OrganizationID  int64     `json:"organization_id"`
+	UserID          int64     `json:"user_id"`
+	Role            string    `json:"role"`
+	MonthlyLimitUSD *float64  `json:"monthly_limit_usd"`
+	MonthlyUsageUSD float64   `json:"monthly_usage_usd"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	User         *User         `json:"user,omitempty"`
+	Organization *Organization `json:"organization,omitempty"`
+}
+
+type OrganizationInvitation struct {
+	ID             int64     `json:"id"`
+	OrganizationID int64     `json:"organization_id"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	InvitedBy      int64     `json:"invited_by"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type OrganizationMemberUsage struct {
+	UserID       int64   `json:"user_id"`
+	Requests     int64   `json:"requests"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	TotalCost    float64 `json:"total_cost"`
+	ActualCost   float64 `json:"actual_cost"`
+}
@@ -6,13 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/claude"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/metrics"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/openai"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tracing"
 	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
 	"github.com/Wei-Shaw/sub2api/internal/service"
 
@@ -21,27 +24,42 @@ import (
 
 // GatewayHandler handles API gateway requests
 type GatewayHandler struct {
-	gatewayService      *service.GatewayService
-	geminiCompatService *service.GeminiMessagesCompatService
-	userService         *service.UserService
-	billingCacheService *service.BillingCacheService
-	concurrencyHelper   *ConcurrencyHelper
+	gatewayService       *service.GatewayService
+	geminiCompatService  *service.GeminiMessagesCompatService
+	openaiCompatService  *service.OpenAIMessagesCompatService
+	openaiGatewayService *service.OpenAIGatewayService
+	userService          *service.UserService
+	billingCacheService  *service.BillingCacheService
+	captureService       *service.CaptureService
+	responseCacheService *service.ResponseCacheService
+	liveEventService     *service.LiveEventService
+	concurrencyHelper    *ConcurrencyHelper
 }
 
 // NewGatewayHandler creates a new GatewayHandler
 func NewGatewayHandler(
 	gatewayService *service.GatewayService,
 	geminiCompatService *service.GeminiMessagesCompatService,
+	openaiCompatService *service.OpenAIMessagesCompatService,
+	openaiGatewayService *service.OpenAIGatewayService,
 	userService *service.UserService,
 	concurrencyService *service.ConcurrencyService,
 	billingCacheService *service.BillingCacheService,
+	captureService *service.CaptureService,
+	responseCacheService *service.ResponseCacheService,
+	liveEventService *service.LiveEventService,
 ) *GatewayHandler {
 	return &GatewayHandler{
-		gatewayService:      gatewayService,
-		geminiCompatService: geminiCompatService,
-		userService:         userService,
-		billingCacheService: billingCacheService,
-		concurrencyHelper:   NewConcurrencyHelper(concurrencyService, SSEPingFormatClaude),
+		gatewayService:       gatewayService,
+		geminiCompatService:  geminiCompatService,
+		openaiCompatService:  openaiCompatService,
+		openaiGatewayService: openaiGatewayService,
+		userService:          userService,
+		billingCacheService:  billingCacheService,
+		captureService:       captureService,
+		responseCacheService: responseCacheService,
+		liveEventService:     liveEventService,
+		concurrencyHelper:    NewConcurrencyHelper(concurrencyService, SSEPingFormatClaude),
 	}
 }
 
@@ -83,6 +101,23 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 		return
 	}
 
+	// 检查 API Key 的模型/IP 允许列表
+	if err := apiKey.CheckAccess(req.Model, c.ClientIP()); err != nil {
+		h.errorResponse(c, http.StatusForbidden, "permission_error", infraerrors.Message(err))
+		return
+	}
+
+	// 记录请求数与耗时指标
+	defer observeGatewayRequest(c, apiKey, service.PlatformAnthropic, req.Model, time.Now())
+
+	// 按需抓取请求/响应（API Key 或上游账号开启抓取时落库）
+	capture := h.captureService.Begin(c, apiKey, service.PlatformAnthropic, req.Model, body)
+	defer h.captureService.Finish(capture)
+
+	// 推送实时请求事件到管理后台
+	h.liveEventService.Begin(c, apiKey, service.PlatformAnthropic, req.Model, req.Stream)
+	defer h.liveEventService.Finish(c)
+
 	// Track if we've started streaming (for error handling)
 	streamStarted := false
 
@@ -93,7 +128,7 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 	maxWait := service.CalculateMaxWait(subject.Concurrency)
 	canWait, err := h.concurrencyHelper.IncrementWaitCount(c.Request.Context(), subject.UserID, maxWait)
 	if err != nil {
-		log.Printf("Increment wait count failed: %v", err)
+		logger.FromContext(c.Request.Context()).Warn("increment wait count failed", logger.Err(err))
 		// On error, allow request to proceed
 	} else if !canWait {
 		h.errorResponse(c, http.StatusTooManyRequests, "rate_limit_error", "Too many pending requests, please retry later")
@@ -105,7 +140,7 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 	// 1. 首先获取用户并发槽位
 	userReleaseFunc, err := h.concurrencyHelper.AcquireUserSlotWithWait(c, subject.UserID, subject.Concurrency, req.Stream, &streamStarted)
 	if err != nil {
-		log.Printf("User concurrency acquire failed: %v", err)
+		logger.FromContext(c.Request.Context()).Warn("acquire user concurrency slot failed", logger.Err(err))
 		h.handleConcurrencyError(c, err, "user", streamStarted)
 		return
 	}
@@ -115,13 +150,34 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 
 	// 2. 【新增】Wait后二次检查余额/订阅
 	if err := h.billingCacheService.CheckBillingEligibility(c.Request.Context(), apiKey.User, apiKey, apiKey.Group, subscription); err != nil {
-		log.Printf("Billing eligibility check failed after wait: %v", err)
+		logger.FromContext(c.Request.Context()).Info("billing eligibility check failed after wait", logger.Err(err))
+		h.handleStreamingAwareError(c, http.StatusForbidden, "billing_error", err.Error(), streamStarted)
+		return
+	}
+
+	// 余额模式：按最大可能费用预授权，由 RecordUsage 结算后释放
+	balanceHold, err := h.billingCacheService.ReserveBalance(c.Request.Context(), apiKey, subscription, req.Model, body, parseMaxOutputTokens(body))
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Info("reserve balance hold failed", logger.Err(err))
 		h.handleStreamingAwareError(c, http.StatusForbidden, "billing_error", err.Error(), streamStarted)
 		return
 	}
+	defer balanceHold.Release()
+
+	// 非流式请求：分组开启响应缓存时直接返回缓存结果，不占用上游账号
+	cacheKey := ""
+	if !req.Stream {
+		cacheKey = h.responseCacheService.CacheKey(apiKey.Group, c.Request.URL.Path, req.Model, body)
+	}
+	if cached := h.responseCacheService.Get(c.Request.Context(), cacheKey); cached != nil {
+		writeCachedResponse(c, cached)
+		h.recordCachedUsage(c, cached, apiKey, subscription, balanceHold.Transfer())
+		return
+	}
+	cacheWriter := wrapResponseCacheWriter(c, cacheKey)
 
 	// 计算粘性会话hash
-	sessionHash := h.gatewayService.GenerateSessionHash(body)
+	sessionHash := h.gatewayService.GenerateSessionHash(apiKey.ID, body)
 
 	platform := ""
 	if apiKey.Group != nil {
@@ -144,6 +200,7 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 				h.handleFailoverExhausted(c, lastFailoverStatus, streamStarted)
 				return
 			}
+			onAccountSelected(c, account)
 
 			// 检查预热请求拦截（在账号选择后、转发前检查）
 			if account.IsInterceptWarmupEnabled() && isWarmupRequest(body) {
@@ -158,7 +215,7 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 			// 3. 获取账号并发槽位
 			accountReleaseFunc, err := h.concurrencyHelper.AcquireAccountSlotWithWait(c, account.ID, account.Concurrency, req.Stream, &streamStarted)
 			if err != nil {
-				log.Printf("Account concurrency acquire failed: %v", err)
+				logger.FromContext(c.Request.Context()).Warn("acquire account concurrency slot failed", logger.Err(err))
 				h.handleConcurrencyError(c, err, "account", streamStarted)
 				return
 			}
@@ -171,6 +228,8 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 			if err != nil {
 				var failoverErr *service.UpstreamFailoverError
 				if errors.As(err, &failoverErr) {
+					metrics.IncFailover(account.Platform, failoverErr.StatusCode)
+					service.PublishLiveFailover(c.Request.Context(), account, failoverErr.StatusCode)
 					failedAccountIDs[account.ID] = struct{}{}
 					if switchCount >= maxAccountSwitches {
 						lastFailoverStatus = failoverErr.StatusCode
@@ -179,17 +238,20 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 					}
 					lastFailoverStatus = failoverErr.StatusCode
 					switchCount++
-					log.Printf("Account %d: upstream error %d, switching account %d/%d", account.ID, failoverErr.StatusCode, switchCount, maxAccountSwitches)
+					metrics.IncAccountSwitch(account.Platform)
+					logger.FromContext(c.Request.Context()).Warn("upstream error, switching account", "status", failoverErr.StatusCode, "switch", switchCount, "max_switches", maxAccountSwitches)
 					continue
 				}
 				// 错误响应已在Forward中处理，这里只记录日志
-				log.Printf("Forward request failed: %v", err)
+				logger.FromContext(c.Request.Context()).Error("forward request failed", logger.Err(err))
 				return
 			}
 
+			storeCachedResponse(c, h.responseCacheService, cacheWriter, cacheKey, apiKey.Group, account, result.Model, result.Usage)
+
 			// 异步记录使用量（subscription已在函数开头获取）
-			go func(result *service.ForwardResult, usedAccount *service.Account) {
-				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			go func(parentCtx context.Context, result *service.ForwardResult, usedAccount *service.Account, hold *service.BalanceHold) {
+				ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
 				defer cancel()
 				if err := h.gatewayService.RecordUsage(ctx, &service.RecordUsageInput{
 					Result:       result,
@@ -197,10 +259,85 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 					User:         apiKey.User,
 					Account:      usedAccount,
 					Subscription: subscription,
+					BalanceHold:  hold,
+				}); err != nil {
+					logger.FromContext(ctx).Error("record usage failed", logger.Err(err))
+				}
+			}(tracing.Detach(c.Request.Context()), result, account, balanceHold.Transfer())
+			return
+		}
+	}
+
+	if platform == service.PlatformOpenAI {
+		const maxAccountSwitches = 3
+		switchCount := 0
+		failedAccountIDs := make(map[int64]struct{})
+		lastFailoverStatus := 0
+
+		for {
+			account, err := h.openaiCompatService.SelectAccountForModelWithExclusions(c.Request.Context(), apiKey.GroupID, sessionHash, req.Model, failedAccountIDs)
+			if err != nil {
+				if len(failedAccountIDs) == 0 {
+					h.handleStreamingAwareError(c, http.StatusServiceUnavailable, "api_error", "No available accounts: "+err.Error(), streamStarted)
+					return
+				}
+				h.handleFailoverExhausted(c, lastFailoverStatus, streamStarted)
+				return
+			}
+			onAccountSelected(c, account)
+
+			// 3. 获取账号并发槽位
+			accountReleaseFunc, err := h.concurrencyHelper.AcquireAccountSlotWithWait(c, account.ID, account.Concurrency, req.Stream, &streamStarted)
+			if err != nil {
+				logger.FromContext(c.Request.Context()).Warn("acquire account concurrency slot failed", logger.Err(err))
+				h.handleConcurrencyError(c, err, "account", streamStarted)
+				return
+			}
+
+			// 转发请求（Claude Messages -> OpenAI Responses）
+			result, err := h.openaiCompatService.Forward(c.Request.Context(), c, account, body)
+			if accountReleaseFunc != nil {
+				accountReleaseFunc()
+			}
+			if err != nil {
+				var failoverErr *service.UpstreamFailoverError
+				if errors.As(err, &failoverErr) {
+					metrics.IncFailover(account.Platform, failoverErr.StatusCode)
+					service.PublishLiveFailover(c.Request.Context(), account, failoverErr.StatusCode)
+					failedAccountIDs[account.ID] = struct{}{}
+					if switchCount >= maxAccountSwitches {
+						lastFailoverStatus = failoverErr.StatusCode
+						h.handleFailoverExhausted(c, lastFailoverStatus, streamStarted)
+						return
+					}
+					lastFailoverStatus = failoverErr.StatusCode
+					switchCount++
+					metrics.IncAccountSwitch(account.Platform)
+					logger.FromContext(c.Request.Context()).Warn("upstream error, switching account", "status", failoverErr.StatusCode, "switch", switchCount, "max_switches", maxAccountSwitches)
+					continue
+				}
+				// 错误响应已在Forward中处理，这里只记录日志
+				logger.FromContext(c.Request.Context()).Error("forward request failed", logger.Err(err))
+				return
+			}
+
+			storeCachedResponse(c, h.responseCacheService, cacheWriter, cacheKey, apiKey.Group, account, result.Model, service.ClaudeUsage(result.Usage))
+
+			// 异步记录使用量（按OpenAI计费逻辑，模型名保留原始Claude模型）
+			go func(parentCtx context.Context, result *service.OpenAIForwardResult, usedAccount *service.Account, hold *service.BalanceHold) {
+				ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+				defer cancel()
+				if err := h.openaiGatewayService.RecordUsage(ctx, &service.OpenAIRecordUsageInput{
+					Result:       result,
+					ApiKey:       apiKey,
+					User:         apiKey.User,
+					Account:      usedAccount,
+					Subscription: subscription,
+					BalanceHold:  hold,
 				}); err != nil {
-					log.Printf("Record usage failed: %v", err)
+					logger.FromContext(ctx).Error("record usage failed", logger.Err(err))
 				}
-			}(result, account)
+			}(tracing.Detach(c.Request.Context()), result, account, balanceHold.Transfer())
 			return
 		}
 	}
@@ -221,6 +358,7 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 			h.handleFailoverExhausted(c, lastFailoverStatus, streamStarted)
 			return
 		}
+		onAccountSelected(c, account)
 
 		// 检查预热请求拦截（在账号选择后、转发前检查）
 		if account.IsInterceptWarmupEnabled() && isWarmupRequest(body) {
@@ -235,7 +373,7 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 		// 3. 获取账号并发槽位
 		accountReleaseFunc, err := h.concurrencyHelper.AcquireAccountSlotWithWait(c, account.ID, account.Concurrency, req.Stream, &streamStarted)
 		if err != nil {
-			log.Printf("Account concurrency acquire failed: %v", err)
+			logger.FromContext(c.Request.Context()).Warn("acquire account concurrency slot failed", logger.Err(err))
 			h.handleConcurrencyError(c, err, "account", streamStarted)
 			return
 		}
@@ -248,6 +386,8 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 		if err != nil {
 			var failoverErr *service.UpstreamFailoverError
 			if errors.As(err, &failoverErr) {
+				metrics.IncFailover(account.Platform, failoverErr.StatusCode)
+				service.PublishLiveFailover(c.Request.Context(), account, failoverErr.StatusCode)
 				failedAccountIDs[account.ID] = struct{}{}
 				if switchCount >= maxAccountSwitches {
 					lastFailoverStatus = failoverErr.StatusCode
@@ -256,17 +396,20 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 				}
 				lastFailoverStatus = failoverErr.StatusCode
 				switchCount++
-				log.Printf("Account %d: upstream error %d, switching account %d/%d", account.ID, failoverErr.StatusCode, switchCount, maxAccountSwitches)
+				metrics.IncAccountSwitch(account.Platform)
+				logger.FromContext(c.Request.Context()).Warn("upstream error, switching account", "status", failoverErr.StatusCode, "switch", switchCount, "max_switches", maxAccountSwitches)
 				continue
 			}
 			// 错误响应已在Forward中处理，这里只记录日志
-			log.Printf("Forward request failed: %v", err)
+			logger.FromContext(c.Request.Context()).Error("forward request failed", logger.Err(err))
 			return
 		}
 
+		storeCachedResponse(c, h.responseCacheService, cacheWriter, cacheKey, apiKey.Group, account, result.Model, result.Usage)
+
 		// 异步记录使用量（subscription已在函数开头获取）
-		go func(result *service.ForwardResult, usedAccount *service.Account) {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		go func(parentCtx context.Context, result *service.ForwardResult, usedAccount *service.Account, hold *service.BalanceHold) {
+			ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
 			defer cancel()
 			if err := h.gatewayService.RecordUsage(ctx, &service.RecordUsageInput{
 				Result:       result,
@@ -274,14 +417,47 @@ func (h *GatewayHandler) Messages(c *gin.Context) {
 				User:         apiKey.User,
 				Account:      usedAccount,
 				Subscription: subscription,
+				BalanceHold:  hold,
 			}); err != nil {
-				log.Printf("Record usage failed: %v", err)
+				logger.FromContext(ctx).Error("record usage failed", logger.Err(err))
 			}
-		}(result, account)
+		}(tracing.Detach(c.Request.Context()), result, account, balanceHold.Transfer())
 		return
 	}
 }
 
+// recordCachedUsage 异步记录命中响应缓存的使用量（按分组缓存倍率计费）
+// 按产生该响应的账号平台选择计费逻辑：OpenAI 账号的 input_tokens 包含缓存读取部分，需按 OpenAI 逻辑计费
+func (h *GatewayHandler) recordCachedUsage(c *gin.Context, cached *service.CachedResponse, apiKey *service.ApiKey, subscription *service.UserSubscription, hold *service.BalanceHold) {
+	go func(parentCtx context.Context) {
+		ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+		defer cancel()
+		var err error
+		if cached.Platform == service.PlatformOpenAI {
+			err = h.openaiGatewayService.RecordUsage(ctx, &service.OpenAIRecordUsageInput{
+				Result:       &service.OpenAIForwardResult{Usage: service.OpenAIUsage(cached.Usage), Model: cached.Model, CacheHit: true},
+				ApiKey:       apiKey,
+				User:         apiKey.User,
+				Account:      cached.SourceAccount(),
+				Subscription: subscription,
+				BalanceHold:  hold,
+			})
+		} else {
+			err = h.gatewayService.RecordUsage(ctx, &service.RecordUsageInput{
+				Result:       &service.ForwardResult{Usage: cached.Usage, Model: cached.Model, CacheHit: true},
+				ApiKey:       apiKey,
+				User:         apiKey.User,
+				Account:      cached.SourceAccount(),
+				Subscription: subscription,
+				BalanceHold:  hold,
+			})
+		}
+		if err != nil {
+			logger.FromContext(ctx).Error("record usage failed", logger.Err(err))
+		}
+	}(tracing.Detach(c.Request.Context()))
+}
+
 // Models handles listing available models
 // GET /v1/models
 // Returns different model lists based on the API key's group platform
@@ -498,6 +674,12 @@ func (h *GatewayHandler) CountTokens(c *gin.Context) {
 		return
 	}
 
+	// 检查 API Key 的模型/IP 允许列表
+	if err := apiKey.CheckAccess(req.Model, c.ClientIP()); err != nil {
+		h.errorResponse(c, http.StatusForbidden, "permission_error", infraerrors.Message(err))
+		return
+	}
+
 	// 获取订阅信息（可能为nil）
 	subscription, _ := middleware2.GetSubscriptionFromContext(c)
 
@@ -509,7 +691,7 @@ func (h *GatewayHandler) CountTokens(c *gin.Context) {
 	}
 
 	// 计算粘性会话 hash
-	sessionHash := h.gatewayService.GenerateSessionHash(body)
+	sessionHash := h.gatewayService.GenerateSessionHash(apiKey.ID, body)
 
 	// 选择支持该模型的账号
 	account, err := h.gatewayService.SelectAccountForModel(c.Request.Context(), apiKey.GroupID, sessionHash, req.Model)
@@ -517,10 +699,11 @@ func (h *GatewayHandler) CountTokens(c *gin.Context) {
 		h.errorResponse(c, http.StatusServiceUnavailable, "api_error", "No available accounts: "+err.Error())
 		return
 	}
+	onAccountSelected(c, account)
 
 	// 转发请求（不记录使用量）
 	if err := h.gatewayService.ForwardCountTokens(c.Request.Context(), c, account, body); err != nil {
-		log.Printf("Forward count_tokens request failed: %v", err)
+		logger.FromContext(c.Request.Context()).Error("forward count_tokens request failed", logger.Err(err))
 		// 错误响应已在 ForwardCountTokens 中处理
 		return
 	}
@@ -1,14 +1,21 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/metrics"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tracing"
 	"github.com/Wei-Shaw/sub2api/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -102,13 +109,21 @@ func (h *ConcurrencyHelper) AcquireAccountSlotWithWait(c *gin.Context, accountID
 	}
 
 	// Need to wait - handle streaming ping if needed
+	defer h.concurrencyService.TrackAccountWait(accountID)()
 	return h.waitForSlotWithPing(c, "account", accountID, maxConcurrency, isStream, streamStarted)
 }
 
 // waitForSlotWithPing waits for a concurrency slot, sending ping events for streaming requests.
 // streamStarted pointer is updated when streaming begins (for proper error handling by caller).
-func (h *ConcurrencyHelper) waitForSlotWithPing(c *gin.Context, slotType string, id int64, maxConcurrency int, isStream bool, streamStarted *bool) (func(), error) {
-	ctx, cancel := context.WithTimeout(c.Request.Context(), maxConcurrencyWait)
+func (h *ConcurrencyHelper) waitForSlotWithPing(c *gin.Context, slotType string, id int64, maxConcurrency int, isStream bool, streamStarted *bool) (release func(), err error) {
+	spanCtx, span := tracing.Start(c.Request.Context(), "ConcurrencyHelper.waitForSlotWithPing",
+		attribute.String("slot.type", slotType),
+		attribute.Int64("slot.id", id),
+		attribute.Int("slot.max_concurrency", maxConcurrency),
+	)
+	defer func() { tracing.EndWithError(span, err) }()
+
+	ctx, cancel := context.WithTimeout(spanCtx, maxConcurrencyWait)
 	defer cancel()
 
 	// Determine if ping is needed (streaming + ping format defined)
@@ -177,3 +192,110 @@ func (h *ConcurrencyHelper) waitForSlotWithPing(c *gin.Context, slotType string,
 		}
 	}
 }
+
+// observeGatewayRequest 记录网关请求的状态码与耗时（在 handler 返回时调用）
+// defaultPlatform 用于未绑定分组的 API Key
+func observeGatewayRequest(c *gin.Context, apiKey *service.ApiKey, defaultPlatform, model string, start time.Time) {
+	platform, group := defaultPlatform, ""
+	if apiKey.Group != nil {
+		if apiKey.Group.Platform != "" {
+			platform = apiKey.Group.Platform
+		}
+		group = apiKey.Group.Name
+	}
+	metrics.ObserveGatewayRequest(platform, model, group, c.Writer.Status(), time.Since(start))
+}
+
+// maxOutputTokensPaths 各协议请求体中的最大输出 token 字段
+var maxOutputTokensPaths = []string{
+	"max_tokens",
+	"max_completion_tokens",
+	"max_output_tokens",
+	"generationConfig.maxOutputTokens",
+}
+
+// parseMaxOutputTokens 从请求体读取最大输出 token，未指定时返回 0
+func parseMaxOutputTokens(body []byte) int {
+	for _, path := range maxOutputTokensPaths {
+		if v := gjson.GetBytes(body, path); v.Exists() {
+			return int(v.Int())
+		}
+	}
+	return 0
+}
+
+// onAccountSelected 选中账号（含故障转移后的重新选号）时调用，依次执行：
+//  1. 将账号写入请求级 logger（故障转移时覆盖为最新账号）
+//  2. 将账号模型映射中的模型加入指标标签白名单
+//  3. 推送选号实时事件
+func onAccountSelected(c *gin.Context, account *service.Account) {
+	logger.AddAttrs(c.Request.Context(),
+		slog.Int64("account_id", account.ID),
+		slog.String("platform", account.Platform),
+	)
+	for model := range account.GetModelMapping() {
+		metrics.RegisterKnownModels(model)
+	}
+	service.PublishLiveAccountSelected(c.Request.Context(), account)
+}
+
+// responseCacheWriter 写回客户端的同时记录响应体，用于写入响应缓存
+type responseCacheWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *responseCacheWriter) record(b []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(b) > service.MaxCachedResponseBytes {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(b)
+}
+
+func (w *responseCacheWriter) Write(b []byte) (int, error) {
+	w.record(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseCacheWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// wrapResponseCacheWriter 缓存 key 非空时替换 c.Writer 以记录响应体，否则返回 nil
+func wrapResponseCacheWriter(c *gin.Context, cacheKey string) *responseCacheWriter {
+	if cacheKey == "" {
+		return nil
+	}
+	w := &responseCacheWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	return w
+}
+
+// writeCachedResponse 将命中的缓存响应写回客户端
+func writeCachedResponse(c *gin.Context, cached *service.CachedResponse) {
+	c.Header("X-Response-Cache", "HIT")
+	c.Data(cached.StatusCode, cached.ContentType, cached.Body)
+}
+
+// storeCachedResponse 转发成功后写入响应缓存（w 为 nil 表示未开启缓存）
+func storeCachedResponse(c *gin.Context, cacheService *service.ResponseCacheService, w *responseCacheWriter, cacheKey string, group *service.Group, account *service.Account, model string, usage service.ClaudeUsage) {
+	if w == nil || w.overflow {
+		return
+	}
+	cacheService.Set(c.Request.Context(), cacheKey, group, &service.CachedResponse{
+		StatusCode:  w.Status(),
+		ContentType: w.Header().Get("Content-Type"),
+		Body:        bytes.Clone(w.body.Bytes()),
+		Model:       model,
+		AccountID:   account.ID,
+		Platform:    account.Platform,
+		Usage:       usage,
+	})
+}
@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"io"
-	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/gemini"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/googleapi"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/metrics"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tracing"
 	"github.com/Wei-Shaw/sub2api/internal/server/middleware"
 	"github.com/Wei-Shaw/sub2api/internal/service"
 
@@ -29,12 +32,17 @@ func (h *GatewayHandler) GeminiV1BetaListModels(c *gin.Context) {
 		googleError(c, http.StatusBadRequest, "API key group platform is not gemini")
 		return
 	}
+	if !apiKey.IsIPAllowed(c.ClientIP()) {
+		googleError(c, http.StatusForbidden, infraerrors.Message(service.ErrApiKeyIPNotAllowed))
+		return
+	}
 
 	account, err := h.geminiCompatService.SelectAccountForAIStudioEndpoints(c.Request.Context(), apiKey.GroupID)
 	if err != nil {
 		googleError(c, http.StatusServiceUnavailable, "No available Gemini accounts: "+err.Error())
 		return
 	}
+	onAccountSelected(c, account)
 
 	res, err := h.geminiCompatService.ForwardAIStudioGET(c.Request.Context(), account, "/v1beta/models")
 	if err != nil {
@@ -66,12 +74,17 @@ func (h *GatewayHandler) GeminiV1BetaGetModel(c *gin.Context) {
 		googleError(c, http.StatusBadRequest, "Missing model in URL")
 		return
 	}
+	if err := apiKey.CheckAccess(modelName, c.ClientIP()); err != nil {
+		googleError(c, http.StatusForbidden, infraerrors.Message(err))
+		return
+	}
 
 	account, err := h.geminiCompatService.SelectAccountForAIStudioEndpoints(c.Request.Context(), apiKey.GroupID)
 	if err != nil {
 		googleError(c, http.StatusServiceUnavailable, "No available Gemini accounts: "+err.Error())
 		return
 	}
+	onAccountSelected(c, account)
 
 	res, err := h.geminiCompatService.ForwardAIStudioGET(c.Request.Context(), account, "/v1beta/models/"+modelName)
 	if err != nil {
@@ -110,6 +123,13 @@ func (h *GatewayHandler) GeminiV1BetaModels(c *gin.Context) {
 		googleError(c, http.StatusNotFound, err.Error())
 		return
 	}
+	if err := apiKey.CheckAccess(modelName, c.ClientIP()); err != nil {
+		googleError(c, http.StatusForbidden, infraerrors.Message(err))
+		return
+	}
+
+	// 记录请求数与耗时指标
+	defer observeGatewayRequest(c, apiKey, service.PlatformGemini, modelName, time.Now())
 
 	stream := action == "streamGenerateContent"
 
@@ -123,6 +143,14 @@ func (h *GatewayHandler) GeminiV1BetaModels(c *gin.Context) {
 		return
 	}
 
+	// 按需抓取请求/响应（API Key 或上游账号开启抓取时落库）
+	capture := h.captureService.Begin(c, apiKey, service.PlatformGemini, modelName, body)
+	defer h.captureService.Finish(capture)
+
+	// 推送实时请求事件到管理后台
+	h.liveEventService.Begin(c, apiKey, service.PlatformGemini, modelName, stream)
+	defer h.liveEventService.Finish(c)
+
 	// Get subscription (may be nil)
 	subscription, _ := middleware.GetSubscriptionFromContext(c)
 
@@ -133,7 +161,7 @@ func (h *GatewayHandler) GeminiV1BetaModels(c *gin.Context) {
 	maxWait := service.CalculateMaxWait(authSubject.Concurrency)
 	canWait, err := geminiConcurrency.IncrementWaitCount(c.Request.Context(), authSubject.UserID, maxWait)
 	if err != nil {
-		log.Printf("Increment wait count failed: %v", err)
+		logger.FromContext(c.Request.Context()).Warn("increment wait count failed", logger.Err(err))
 	} else if !canWait {
 		googleError(c, http.StatusTooManyRequests, "Too many pending requests, please retry later")
 		return
@@ -157,8 +185,28 @@ func (h *GatewayHandler) GeminiV1BetaModels(c *gin.Context) {
 		return
 	}
 
+	// 余额模式：按最大可能费用预授权，由 RecordUsage 结算后释放
+	balanceHold, err := h.billingCacheService.ReserveBalance(c.Request.Context(), apiKey, subscription, modelName, body, parseMaxOutputTokens(body))
+	if err != nil {
+		googleError(c, http.StatusForbidden, err.Error())
+		return
+	}
+	defer balanceHold.Release()
+
+	// 非流式请求命中响应缓存时直接返回，不占用上游账号
+	cacheKey := ""
+	if !stream {
+		cacheKey = h.responseCacheService.CacheKey(apiKey.Group, c.Request.URL.Path, modelName, body)
+	}
+	if cached := h.responseCacheService.Get(c.Request.Context(), cacheKey); cached != nil {
+		writeCachedResponse(c, cached)
+		h.recordCachedUsage(c, cached, apiKey, subscription, balanceHold.Transfer())
+		return
+	}
+	cacheWriter := wrapResponseCacheWriter(c, cacheKey)
+
 	// 3) select account (sticky session based on request body)
-	sessionHash := h.gatewayService.GenerateSessionHash(body)
+	sessionHash := h.gatewayService.GenerateSessionHash(apiKey.ID, body)
 	const maxAccountSwitches = 3
 	switchCount := 0
 	failedAccountIDs := make(map[int64]struct{})
@@ -174,6 +222,7 @@ func (h *GatewayHandler) GeminiV1BetaModels(c *gin.Context) {
 			handleGeminiFailoverExhausted(c, lastFailoverStatus)
 			return
 		}
+		onAccountSelected(c, account)
 
 		// 4) account concurrency slot
 		accountReleaseFunc, err := geminiConcurrency.AcquireAccountSlotWithWait(c, account.ID, account.Concurrency, stream, &streamStarted)
@@ -190,6 +239,8 @@ func (h *GatewayHandler) GeminiV1BetaModels(c *gin.Context) {
 		if err != nil {
 			var failoverErr *service.UpstreamFailoverError
 			if errors.As(err, &failoverErr) {
+				metrics.IncFailover(account.Platform, failoverErr.StatusCode)
+				service.PublishLiveFailover(c.Request.Context(), account, failoverErr.StatusCode)
 				failedAccountIDs[account.ID] = struct{}{}
 				if switchCount >= maxAccountSwitches {
 					lastFailoverStatus = failoverErr.StatusCode
@@ -198,17 +249,20 @@ func (h *GatewayHandler) GeminiV1BetaModels(c *gin.Context) {
 				}
 				lastFailoverStatus = failoverErr.StatusCode
 				switchCount++
-				log.Printf("Gemini account %d: upstream error %d, switching account %d/%d", account.ID, failoverErr.StatusCode, switchCount, maxAccountSwitches)
+				metrics.IncAccountSwitch(account.Platform)
+				logger.FromContext(c.Request.Context()).Warn("upstream error, switching account", "status", failoverErr.StatusCode, "switch", switchCount, "max_switches", maxAccountSwitches)
 				continue
 			}
 			// ForwardNative already wrote the response
-			log.Printf("Gemini native forward failed: %v", err)
+			logger.FromContext(c.Request.Context()).Error("forward gemini native request failed", logger.Err(err))
 			return
 		}
 
+		storeCachedResponse(c, h.responseCacheService, cacheWriter, cacheKey, apiKey.Group, account, result.Model, result.Usage)
+
 		// 6) record usage async
-		go func(result *service.ForwardResult, usedAccount *service.Account) {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		go func(parentCtx context.Context, result *service.ForwardResult, usedAccount *service.Account, hold *service.BalanceHold) {
+			ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
 			defer cancel()
 			if err := h.gatewayService.RecordUsage(ctx, &service.RecordUsageInput{
 				Result:       result,
@@ -216,10 +270,11 @@ func (h *GatewayHandler) GeminiV1BetaModels(c *gin.Context) {
 				User:         apiKey.User,
 				Account:      usedAccount,
 				Subscription: subscription,
+				BalanceHold:  hold,
 			}); err != nil {
-				log.Printf("Record usage failed: %v", err)
+				logger.FromContext(ctx).Error("record usage failed", logger.Err(err))
 			}
-		}(result, account)
+		}(tracing.Detach(c.Request.Context()), result, account, balanceHold.Transfer())
 		return
 	}
 }
@@ -19,20 +19,29 @@ type AdminHandlers struct {
 	System       *admin.SystemHandler
 	Subscription *admin.SubscriptionHandler
 	Usage        *admin.UsageHandler
+	Balance      *admin.BalanceHandler
+	Capture      *admin.CaptureHandler
+	Audit        *admin.AuditHandler
+	AdminApiKey  *admin.AdminApiKeyHandler
+	Organization *admin.OrganizationHandler
 }
 
 // Handlers contains all HTTP handlers
 type Handlers struct {
 	Auth          *AuthHandler
 	User          *UserHandler
+	TwoFactor     *TwoFactorHandler
 	APIKey        *APIKeyHandler
 	Usage         *UsageHandler
+	Balance       *BalanceHandler
 	Redeem        *RedeemHandler
 	Subscription  *SubscriptionHandler
+	Organization  *OrganizationHandler
 	Admin         *AdminHandlers
 	Gateway       *GatewayHandler
 	OpenAIGateway *OpenAIGatewayHandler
 	Setting       *SettingHandler
+	Metrics       *MetricsHandler
 }
 
 // BuildInfo contains build-time information
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/metrics"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsHandler serves Prometheus metrics
+type MetricsHandler struct {
+	handler http.Handler
+}
+
+// NewMetricsHandler creates a new MetricsHandler and registers scrape-time collectors
+func NewMetricsHandler(accountCollector *service.AccountMetricsCollector) *MetricsHandler {
+	if err := metrics.Registry.Register(accountCollector); err != nil {
+		var already prometheus.AlreadyRegisteredError
+		if !errors.As(err, &already) {
+			slog.Error("register account metrics collector failed", logger.Err(err))
+		}
+	}
+	return &MetricsHandler{handler: metrics.Handler()}
+}
+
+// Metrics handles Prometheus scrapes
+// GET /metrics
+func (h *MetricsHandler) Metrics(c *gin.Context) {
+	h.handler.ServeHTTP(c.Writer, c.Request)
+}
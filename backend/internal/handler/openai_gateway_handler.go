@@ -6,11 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"time"
 
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/metrics"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/openai"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tracing"
 	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
 	"github.com/Wei-Shaw/sub2api/internal/service"
 
@@ -19,9 +22,12 @@ import (
 
 // OpenAIGatewayHandler handles OpenAI API gateway requests
 type OpenAIGatewayHandler struct {
-	gatewayService      *service.OpenAIGatewayService
-	billingCacheService *service.BillingCacheService
-	concurrencyHelper   *ConcurrencyHelper
+	gatewayService       *service.OpenAIGatewayService
+	billingCacheService  *service.BillingCacheService
+	captureService       *service.CaptureService
+	responseCacheService *service.ResponseCacheService
+	liveEventService     *service.LiveEventService
+	concurrencyHelper    *ConcurrencyHelper
 }
 
 // NewOpenAIGatewayHandler creates a new OpenAIGatewayHandler
@@ -29,11 +35,17 @@ func NewOpenAIGatewayHandler(
 	gatewayService *service.OpenAIGatewayService,
 	concurrencyService *service.ConcurrencyService,
 	billingCacheService *service.BillingCacheService,
+	captureService *service.CaptureService,
+	responseCacheService *service.ResponseCacheService,
+	liveEventService *service.LiveEventService,
 ) *OpenAIGatewayHandler {
 	return &OpenAIGatewayHandler{
-		gatewayService:      gatewayService,
-		billingCacheService: billingCacheService,
-		concurrencyHelper:   NewConcurrencyHelper(concurrencyService, SSEPingFormatNone),
+		gatewayService:       gatewayService,
+		billingCacheService:  billingCacheService,
+		captureService:       captureService,
+		responseCacheService: responseCacheService,
+		liveEventService:     liveEventService,
+		concurrencyHelper:    NewConcurrencyHelper(concurrencyService, SSEPingFormatNone),
 	}
 }
 
@@ -76,6 +88,23 @@ func (h *OpenAIGatewayHandler) Responses(c *gin.Context) {
 	reqModel, _ := reqBody["model"].(string)
 	reqStream, _ := reqBody["stream"].(bool)
 
+	// Check API key model/IP allow-lists
+	if err := apiKey.CheckAccess(reqModel, c.ClientIP()); err != nil {
+		h.errorResponse(c, http.StatusForbidden, "permission_error", infraerrors.Message(err))
+		return
+	}
+
+	// Record request count and latency when the handler returns
+	defer observeGatewayRequest(c, apiKey, service.PlatformOpenAI, reqModel, time.Now())
+
+	// 按需抓取请求/响应（API Key 或上游账号开启抓取时落库）
+	capture := h.captureService.Begin(c, apiKey, service.PlatformOpenAI, reqModel, body)
+	defer h.captureService.Finish(capture)
+
+	// Push live request events to the admin dashboard
+	h.liveEventService.Begin(c, apiKey, service.PlatformOpenAI, reqModel, reqStream)
+	defer h.liveEventService.Finish(c)
+
 	// For non-Codex CLI requests, set default instructions
 	userAgent := c.GetHeader("User-Agent")
 	if !openai.IsCodexCLIRequest(userAgent) {
@@ -98,7 +127,7 @@ func (h *OpenAIGatewayHandler) Responses(c *gin.Context) {
 	maxWait := service.CalculateMaxWait(subject.Concurrency)
 	canWait, err := h.concurrencyHelper.IncrementWaitCount(c.Request.Context(), subject.UserID, maxWait)
 	if err != nil {
-		log.Printf("Increment wait count failed: %v", err)
+		logger.FromContext(c.Request.Context()).Warn("increment wait count failed", logger.Err(err))
 		// On error, allow request to proceed
 	} else if !canWait {
 		h.errorResponse(c, http.StatusTooManyRequests, "rate_limit_error", "Too many pending requests, please retry later")
@@ -110,7 +139,7 @@ func (h *OpenAIGatewayHandler) Responses(c *gin.Context) {
 	// 1. First acquire user concurrency slot
 	userReleaseFunc, err := h.concurrencyHelper.AcquireUserSlotWithWait(c, subject.UserID, subject.Concurrency, reqStream, &streamStarted)
 	if err != nil {
-		log.Printf("User concurrency acquire failed: %v", err)
+		logger.FromContext(c.Request.Context()).Warn("acquire user concurrency slot failed", logger.Err(err))
 		h.handleConcurrencyError(c, err, "user", streamStarted)
 		return
 	}
@@ -120,13 +149,34 @@ func (h *OpenAIGatewayHandler) Responses(c *gin.Context) {
 
 	// 2. Re-check billing eligibility after wait
 	if err := h.billingCacheService.CheckBillingEligibility(c.Request.Context(), apiKey.User, apiKey, apiKey.Group, subscription); err != nil {
-		log.Printf("Billing eligibility check failed after wait: %v", err)
+		logger.FromContext(c.Request.Context()).Info("billing eligibility check failed after wait", logger.Err(err))
 		h.handleStreamingAwareError(c, http.StatusForbidden, "billing_error", err.Error(), streamStarted)
 		return
 	}
 
+	// Reserve the worst-case cost in balance mode; RecordUsage settles and releases it
+	balanceHold, err := h.billingCacheService.ReserveBalance(c.Request.Context(), apiKey, subscription, reqModel, body, parseMaxOutputTokens(body))
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Info("reserve balance hold failed", logger.Err(err))
+		h.handleStreamingAwareError(c, http.StatusForbidden, "billing_error", err.Error(), streamStarted)
+		return
+	}
+	defer balanceHold.Release()
+
+	// Serve identical non-streaming requests from the group's response cache without touching upstream accounts
+	cacheKey := ""
+	if !reqStream {
+		cacheKey = h.responseCacheService.CacheKey(apiKey.Group, c.Request.URL.Path, reqModel, body)
+	}
+	if cached := h.responseCacheService.Get(c.Request.Context(), cacheKey); cached != nil {
+		writeCachedResponse(c, cached)
+		h.recordCachedUsage(c, cached, apiKey, subscription, balanceHold.Transfer())
+		return
+	}
+	cacheWriter := wrapResponseCacheWriter(c, cacheKey)
+
 	// Generate session hash (from header for OpenAI)
-	sessionHash := h.gatewayService.GenerateSessionHash(c)
+	sessionHash := h.gatewayService.GenerateSessionHash(c, apiKey.ID)
 
 	const maxAccountSwitches = 3
 	switchCount := 0
@@ -135,10 +185,10 @@ func (h *OpenAIGatewayHandler) Responses(c *gin.Context) {
 
 	for {
 		// Select account supporting the requested model
-		log.Printf("[OpenAI Handler] Selecting account: groupID=%v model=%s", apiKey.GroupID, reqModel)
+		logger.FromContext(c.Request.Context()).Debug("selecting account", "group_id", apiKey.GroupID, "model", reqModel)
 		account, err := h.gatewayService.SelectAccountForModelWithExclusions(c.Request.Context(), apiKey.GroupID, sessionHash, reqModel, failedAccountIDs)
 		if err != nil {
-			log.Printf("[OpenAI Handler] SelectAccount failed: %v", err)
+			logger.FromContext(c.Request.Context()).Warn("select account failed", logger.Err(err))
 			if len(failedAccountIDs) == 0 {
 				h.handleStreamingAwareError(c, http.StatusServiceUnavailable, "api_error", "No available accounts: "+err.Error(), streamStarted)
 				return
@@ -146,12 +196,13 @@ func (h *OpenAIGatewayHandler) Responses(c *gin.Context) {
 			h.handleFailoverExhausted(c, lastFailoverStatus, streamStarted)
 			return
 		}
-		log.Printf("[OpenAI Handler] Selected account: id=%d name=%s", account.ID, account.Name)
+		onAccountSelected(c, account)
+		logger.FromContext(c.Request.Context()).Debug("selected account", "account_name", account.Name)
 
 		// 3. Acquire account concurrency slot
 		accountReleaseFunc, err := h.concurrencyHelper.AcquireAccountSlotWithWait(c, account.ID, account.Concurrency, reqStream, &streamStarted)
 		if err != nil {
-			log.Printf("Account concurrency acquire failed: %v", err)
+			logger.FromContext(c.Request.Context()).Warn("acquire account concurrency slot failed", logger.Err(err))
 			h.handleConcurrencyError(c, err, "account", streamStarted)
 			return
 		}
@@ -164,6 +215,8 @@ func (h *OpenAIGatewayHandler) Responses(c *gin.Context) {
 		if err != nil {
 			var failoverErr *service.UpstreamFailoverError
 			if errors.As(err, &failoverErr) {
+				metrics.IncFailover(account.Platform, failoverErr.StatusCode)
+				service.PublishLiveFailover(c.Request.Context(), account, failoverErr.StatusCode)
 				failedAccountIDs[account.ID] = struct{}{}
 				if switchCount >= maxAccountSwitches {
 					lastFailoverStatus = failoverErr.StatusCode
@@ -172,17 +225,20 @@ func (h *OpenAIGatewayHandler) Responses(c *gin.Context) {
 				}
 				lastFailoverStatus = failoverErr.StatusCode
 				switchCount++
-				log.Printf("Account %d: upstream error %d, switching account %d/%d", account.ID, failoverErr.StatusCode, switchCount, maxAccountSwitches)
+				metrics.IncAccountSwitch(account.Platform)
+				logger.FromContext(c.Request.Context()).Warn("upstream error, switching account", "status", failoverErr.StatusCode, "switch", switchCount, "max_switches", maxAccountSwitches)
 				continue
 			}
 			// Error response already handled in Forward, just log
-			log.Printf("Forward request failed: %v", err)
+			logger.FromContext(c.Request.Context()).Error("forward request failed", logger.Err(err))
 			return
 		}
 
+		storeCachedResponse(c, h.responseCacheService, cacheWriter, cacheKey, apiKey.Group, account, result.Model, service.ClaudeUsage(result.Usage))
+
 		// Async record usage
-		go func(result *service.OpenAIForwardResult, usedAccount *service.Account) {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		go func(parentCtx context.Context, result *service.OpenAIForwardResult, usedAccount *service.Account, hold *service.BalanceHold) {
+			ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
 			defer cancel()
 			if err := h.gatewayService.RecordUsage(ctx, &service.OpenAIRecordUsageInput{
 				Result:       result,
@@ -190,14 +246,34 @@ func (h *OpenAIGatewayHandler) Responses(c *gin.Context) {
 				User:         apiKey.User,
 				Account:      usedAccount,
 				Subscription: subscription,
+				BalanceHold:  hold,
 			}); err != nil {
-				log.Printf("Record usage failed: %v", err)
+				logger.FromContext(ctx).Error("record usage failed", logger.Err(err))
 			}
-		}(result, account)
+		}(tracing.Detach(c.Request.Context()), result, account, balanceHold.Transfer())
 		return
 	}
 }
 
+// recordCachedUsage asynchronously records usage for a response served from the response cache
+func (h *OpenAIGatewayHandler) recordCachedUsage(c *gin.Context, cached *service.CachedResponse, apiKey *service.ApiKey, subscription *service.UserSubscription, hold *service.BalanceHold) {
+	result := &service.OpenAIForwardResult{Usage: service.OpenAIUsage(cached.Usage), Model: cached.Model, CacheHit: true}
+	go func(parentCtx context.Context) {
+		ctx, cancel := context.WithTimeout(parentCtx, 10*time.Second)
+		defer cancel()
+		if err := h.gatewayService.RecordUsage(ctx, &service.OpenAIRecordUsageInput{
+			Result:       result,
+			ApiKey:       apiKey,
+			User:         apiKey.User,
+			Account:      cached.SourceAccount(),
+			Subscription: subscription,
+			BalanceHold:  hold,
+		}); err != nil {
+			logger.FromContext(ctx).Error("record usage failed", logger.Err(err))
+		}
+	}(tracing.Detach(c.Request.Context()))
+}
+
 // handleConcurrencyError handles concurrency-related errors with proper 429 response
 func (h *OpenAIGatewayHandler) handleConcurrencyError(c *gin.Context, err error, slotType string, streamStarted bool) {
 	h.handleStreamingAwareError(c, http.StatusTooManyRequests, "rate_limit_error",
@@ -0,0 +1,489 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/handler/dto"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/timezone"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrganizationHandler handles organization (team) requests for members
+type OrganizationHandler struct {
+	organizationService *service.OrganizationService
+}
+
+// NewOrganizationHandler creates a new OrganizationHandler
+func NewOrganizationHandler(organizationService *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: organizationService,
+	}
+}
+
+// OrganizationRequest represents the create/rename organization request payload
+type OrganizationRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// UpdateOrganizationMemberRequest represents the update member request payload
+type UpdateOrganizationMemberRequest struct {
+	Role *string `json:"role" binding:"omitempty,oneof=admin member"`
+	// 每月花费上限，传 0 表示取消限制
+	MonthlyLimitUSD *float64 `json:"monthly_limit_usd" binding:"omitempty,min=0"`
+}
+
+// InviteOrganizationMemberRequest represents the invite member request payload
+type InviteOrganizationMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"omitempty,oneof=admin member"`
+}
+
+// AcceptOrganizationInvitationRequest represents the accept invitation request payload
+type AcceptOrganizationInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// List handles listing the organizations the current user belongs to
+// GET /api/v1/organizations
+func (h *OrganizationHandler) List(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	memberships, err := h.organizationService.ListForUser(c.Request.Context(), subject.UserID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.OrganizationMember, 0, len(memberships))
+	for i := range memberships {
+		out = append(out, *dto.OrganizationMemberFromService(&memberships[i]))
+	}
+	response.Success(c, out)
+}
+
+// Create handles creating an organization owned by the current user
+// POST /api/v1/organizations
+func (h *OrganizationHandler) Create(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	org, err := h.organizationService.Create(c.Request.Context(), subject.UserID, req.Name)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.OrganizationFromService(org))
+}
+
+// GetByID handles getting an organization together with the caller's membership
+// GET /api/v1/organizations/:id
+func (h *OrganizationHandler) GetByID(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+
+	org, member, err := h.organizationService.Get(c.Request.Context(), subject.UserID, orgID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"organization": dto.OrganizationFromService(org),
+		"membership":   dto.OrganizationMemberFromService(member),
+	})
+}
+
+// Update handles renaming an organization
+// PUT /api/v1/organizations/:id
+func (h *OrganizationHandler) Update(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	org, err := h.organizationService.Rename(c.Request.Context(), subject.UserID, orgID, req.Name)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.OrganizationFromService(org))
+}
+
+// ListMembers handles listing organization members
+// GET /api/v1/organizations/:id/members
+func (h *OrganizationHandler) ListMembers(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.organizationService.ListMembers(c.Request.Context(), subject.UserID, orgID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.OrganizationMember, 0, len(members))
+	for i := range members {
+		out = append(out, *dto.OrganizationMemberFromService(&members[i]))
+	}
+	response.Success(c, out)
+}
+
+// UpdateMember handles changing a member's role or monthly spending cap
+// PUT /api/v1/organizations/:id/members/:user_id
+func (h *OrganizationHandler) UpdateMember(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+	targetUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	var req UpdateOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	member, err := h.organizationService.UpdateMember(c.Request.Context(), subject.UserID, orgID, targetUserID, &service.UpdateOrgMemberInput{
+		Role:            req.Role,
+		MonthlyLimitUSD: req.MonthlyLimitUSD,
+	})
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.OrganizationMemberFromService(member))
+}
+
+// RemoveMember handles removing a member (or leaving the organization)
+// DELETE /api/v1/organizations/:id/members/:user_id
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+	targetUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	if err := h.organizationService.RemoveMember(c.Request.Context(), subject.UserID, orgID, targetUserID); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Member removed successfully"})
+}
+
+// Invite handles inviting a user by email
+// POST /api/v1/organizations/:id/invitations
+func (h *OrganizationHandler) Invite(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+
+	var req InviteOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	if req.Role == "" {
+		req.Role = service.OrgRoleMember
+	}
+
+	inv, err := h.organizationService.Invite(c.Request.Context(), subject.UserID, orgID, req.Email, req.Role)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.OrganizationInvitationFromService(inv))
+}
+
+// ListInvitations handles listing pending invitations
+// GET /api/v1/organizations/:id/invitations
+func (h *OrganizationHandler) ListInvitations(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.organizationService.ListInvitations(c.Request.Context(), subject.UserID, orgID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.OrganizationInvitation, 0, len(invitations))
+	for i := range invitations {
+		out = append(out, *dto.OrganizationInvitationFromService(&invitations[i]))
+	}
+	response.Success(c, out)
+}
+
+// CancelInvitation handles revoking a pending invitation
+// DELETE /api/v1/organizations/:id/invitations/:invitation_id
+func (h *OrganizationHandler) CancelInvitation(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+	invitationID, err := strconv.ParseInt(c.Param("invitation_id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid invitation ID")
+		return
+	}
+
+	if err := h.organizationService.CancelInvitation(c.Request.Context(), subject.UserID, orgID, invitationID); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Invitation cancelled successfully"})
+}
+
+// AcceptInvitation handles joining an organization with an invitation token
+// POST /api/v1/organizations/invitations/accept
+func (h *OrganizationHandler) AcceptInvitation(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req AcceptOrganizationInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	member, err := h.organizationService.AcceptInvitation(c.Request.Context(), subject.UserID, req.Token)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.OrganizationMemberFromService(member))
+}
+
+// MemberUsage handles per-member usage within an organization
+// GET /api/v1/organizations/:id/usage
+// 默认统计当月（UTC，与成员花费上限的计算口径一致）
+func (h *OrganizationHandler) MemberUsage(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	startTime := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	endTime := now
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		t, err := timezone.ParseInLocation("2006-01-02", startDateStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid start_date format, use YYYY-MM-DD")
+			return
+		}
+		startTime = t
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		t, err := timezone.ParseInLocation("2006-01-02", endDateStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid end_date format, use YYYY-MM-DD")
+			return
+		}
+		endTime = t.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	usage, err := h.organizationService.MemberUsage(c.Request.Context(), subject.UserID, orgID, startTime, endTime)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.OrganizationMemberUsage, 0, len(usage))
+	for i := range usage {
+		out = append(out, *dto.OrganizationMemberUsageFromService(&usage[i]))
+	}
+	response.Success(c, gin.H{
+		"start_time": startTime,
+		"end_time":   endTime,
+		"members":    out,
+	})
+}
+
+// ListApiKeys handles listing organization-owned API keys
+// GET /api/v1/organizations/:id/keys
+func (h *OrganizationHandler) ListApiKeys(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize := response.ParsePagination(c)
+	params := pagination.PaginationParams{Page: page, PageSize: pageSize}
+
+	keys, result, err := h.organizationService.ListApiKeys(c.Request.Context(), subject.UserID, orgID, params)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.ApiKey, 0, len(keys))
+	for i := range keys {
+		item := dto.ApiKeyFromService(&keys[i])
+		// 只有创建者可以看到完整 Key
+		if keys[i].UserID != subject.UserID {
+			item.Key = ""
+		}
+		out = append(out, *item)
+	}
+	response.Paginated(c, out, result.Total, page, pageSize)
+}
+
+// CreateApiKey handles creating an API key billed to the organization
+// POST /api/v1/organizations/:id/keys
+func (h *OrganizationHandler) CreateApiKey(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	key, err := h.organizationService.CreateApiKey(c.Request.Context(), subject.UserID, orgID, req.toService())
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.ApiKeyFromService(key))
+}
+
+// DeleteApiKey handles deleting an organization-owned API key
+// DELETE /api/v1/organizations/:id/keys/:key_id
+func (h *OrganizationHandler) DeleteApiKey(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+	keyID, err := strconv.ParseInt(c.Param("key_id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid key ID")
+		return
+	}
+
+	if err := h.organizationService.DeleteApiKey(c.Request.Context(), subject.UserID, orgID, keyID); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "API key deleted successfully"})
+}
+
+// ListTransactions handles listing the organization balance ledger
+// GET /api/v1/organizations/:id/balance-transactions
+func (h *OrganizationHandler) ListTransactions(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+
+	page, pageSize := response.ParsePagination(c)
+	filters, ok := parseBalanceTransactionFilters(c)
+	if !ok {
+		return
+	}
+
+	params := pagination.PaginationParams{Page: page, PageSize: pageSize}
+	txs, result, err := h.organizationService.ListTransactions(c.Request.Context(), subject.UserID, orgID, params, filters)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.BalanceTransaction, 0, len(txs))
+	for i := range txs {
+		item := dto.BalanceTransactionFromService(&txs[i])
+		item.OperatorID = nil
+		out = append(out, *item)
+	}
+	response.Paginated(c, out, result.Total, page, pageSize)
+}
+
+// ListSubscriptions handles listing the organization's shared subscriptions
+// GET /api/v1/organizations/:id/subscriptions
+func (h *OrganizationHandler) ListSubscriptions(c *gin.Context) {
+	subject, orgID, ok := h.parseOrgRequest(c)
+	if !ok {
+		return
+	}
+
+	subs, err := h.organizationService.ListSubscriptions(c.Request.Context(), subject.UserID, orgID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	out := make([]dto.UserSubscription, 0, len(subs))
+	for i := range subs {
+		out = append(out, *dto.UserSubscriptionFromService(&subs[i]))
+	}
+	response.Success(c, out)
+}
+
+// parseOrgRequest 读取当前用户与路径中的组织 ID，失败时已写入错误响应
+func (h *OrganizationHandler) parseOrgRequest(c *gin.Context) (middleware2.AuthSubject, int64, bool) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return subject, 0, false
+	}
+	orgID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid organization ID")
+		return subject, 0, false
+	}
+	return subject, orgID, true
+}
@@ -42,6 +42,8 @@ func (h *SettingHandler) GetPublicSettings(c *gin.Context) {
 		ApiBaseUrl:          settings.ApiBaseUrl,
 		ContactInfo:         settings.ContactInfo,
 		DocUrl:              settings.DocUrl,
+		OidcEnabled:         settings.OidcEnabled,
+		OidcProviderName:    settings.OidcProviderName,
 		Version:             h.version,
 	})
 }
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/Wei-Shaw/sub2api/internal/handler/dto"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/response"
+	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorHandler handles two-factor authentication management for the current user
+type TwoFactorHandler struct {
+	userService      *service.UserService
+	twoFactorService *service.TwoFactorService
+}
+
+// NewTwoFactorHandler creates a new TwoFactorHandler
+func NewTwoFactorHandler(userService *service.UserService, twoFactorService *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		userService:      userService,
+		twoFactorService: twoFactorService,
+	}
+}
+
+// TwoFactorCodeRequest represents a request carrying a TOTP or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTOTPRequest represents the disable TOTP request payload
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// WebAuthnRegisterFinishRequest represents the browser attestation response
+type WebAuthnRegisterFinishRequest struct {
+	Name       string          `json:"name" binding:"max=100"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// RecoveryCodesResponse contains recovery codes that are shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// currentUser loads the authenticated user
+func (h *TwoFactorHandler) currentUser(c *gin.Context) (*service.User, bool) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return nil, false
+	}
+
+	user, err := h.userService.GetByID(c.Request.Context(), subject.UserID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return nil, false
+	}
+	return user, true
+}
+
+// GetStatus handles getting the current user's two-factor status
+// GET /api/v1/user/2fa
+func (h *TwoFactorHandler) GetStatus(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.twoFactorService.Status(c.Request.Context(), user)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.TwoFactorStatusFromService(status))
+}
+
+// SetupTOTP generates a new TOTP secret pending confirmation
+// POST /api/v1/user/2fa/totp/setup
+func (h *TwoFactorHandler) SetupTOTP(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	setup, err := h.twoFactorService.SetupTOTP(c.Request.Context(), user)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, TOTPSetupResponse{Secret: setup.Secret, OtpauthURL: setup.URI})
+}
+
+// EnableTOTP confirms the pending TOTP secret and returns recovery codes
+// POST /api/v1/user/2fa/totp/enable
+func (h *TwoFactorHandler) EnableTOTP(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	codes, err := h.twoFactorService.EnableTOTP(c.Request.Context(), user, req.Code)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP turns off TOTP
+// POST /api/v1/user/2fa/totp/disable
+func (h *TwoFactorHandler) DisableTOTP(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	if err := h.twoFactorService.DisableTOTP(c.Request.Context(), user, req.Password, req.Code); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes
+// POST /api/v1/user/2fa/recovery-codes
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(c.Request.Context(), user, req.Code)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// BeginWebAuthnRegistration returns options for navigator.credentials.create
+// POST /api/v1/user/2fa/webauthn/register/begin
+func (h *TwoFactorHandler) BeginWebAuthnRegistration(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	creation, err := h.twoFactorService.BeginWebAuthnRegistration(c.Request.Context(), user)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, creation)
+}
+
+// FinishWebAuthnRegistration verifies and stores a new security key
+// POST /api/v1/user/2fa/webauthn/register/finish
+func (h *TwoFactorHandler) FinishWebAuthnRegistration(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req WebAuthnRegisterFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	credential, err := h.twoFactorService.FinishWebAuthnRegistration(c.Request.Context(), user, req.Name, req.Credential)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.WebAuthnCredentialFromService(credential))
+}
+
+// DeleteWebAuthnCredential removes a security key
+// DELETE /api/v1/user/2fa/webauthn/:id
+func (h *TwoFactorHandler) DeleteWebAuthnCredential(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid credential ID")
+		return
+	}
+
+	if err := h.twoFactorService.DeleteWebAuthnCredential(c.Request.Context(), user, id); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Security key deleted successfully"})
+}
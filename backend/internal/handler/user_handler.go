@@ -11,13 +11,15 @@ import (
 
 // UserHandler handles user-related requests
 type UserHandler struct {
-	userService *service.UserService
+	userService         *service.UserService
+	notificationService *service.UserNotificationService
 }
 
 // NewUserHandler creates a new UserHandler
-func NewUserHandler(userService *service.UserService) *UserHandler {
+func NewUserHandler(userService *service.UserService, notificationService *service.UserNotificationService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:         userService,
+		notificationService: notificationService,
 	}
 }
 
@@ -112,3 +114,59 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 
 	response.Success(c, dto.UserFromService(updatedUser))
 }
+
+// GetNotificationPrefs handles getting the current user's notification preferences
+// GET /api/v1/user/notification-prefs
+func (h *UserHandler) GetNotificationPrefs(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	prefs, err := h.notificationService.GetPrefs(c.Request.Context(), subject.UserID)
+	if err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.UserNotificationPrefsFromService(prefs))
+}
+
+// UpdateNotificationPrefsRequest represents the update notification preferences request payload
+type UpdateNotificationPrefsRequest struct {
+	BalanceLowEnabled         bool    `json:"balance_low_enabled"`
+	BalanceThreshold          float64 `json:"balance_threshold" binding:"gte=0"`
+	SubscriptionExpiryEnabled bool    `json:"subscription_expiry_enabled"`
+	QuotaReachedEnabled       bool    `json:"quota_reached_enabled"`
+}
+
+// UpdateNotificationPrefs handles updating the current user's notification preferences
+// PUT /api/v1/user/notification-prefs
+func (h *UserHandler) UpdateNotificationPrefs(c *gin.Context) {
+	subject, ok := middleware2.GetAuthSubjectFromContext(c)
+	if !ok {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req UpdateNotificationPrefsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	prefs := &service.UserNotificationPrefs{
+		UserID:                    subject.UserID,
+		BalanceLowEnabled:         req.BalanceLowEnabled,
+		BalanceThreshold:          req.BalanceThreshold,
+		SubscriptionExpiryEnabled: req.SubscriptionExpiryEnabled,
+		QuotaReachedEnabled:       req.QuotaReachedEnabled,
+	}
+	if err := h.notificationService.UpdatePrefs(c.Request.Context(), prefs); err != nil {
+		response.ErrorFrom(c, err)
+		return
+	}
+
+	response.Success(c, dto.UserNotificationPrefsFromService(prefs))
+}
@@ -22,6 +22,11 @@ func ProvideAdminHandlers(
 	systemHandler *admin.SystemHandler,
 	subscriptionHandler *admin.SubscriptionHandler,
 	usageHandler *admin.UsageHandler,
+	balanceHandler *admin.BalanceHandler,
+	captureHandler *admin.CaptureHandler,
+	auditHandler *admin.AuditHandler,
+	adminApiKeyHandler *admin.AdminApiKeyHandler,
+	organizationHandler *admin.OrganizationHandler,
 ) *AdminHandlers {
 	return &AdminHandlers{
 		Dashboard:    dashboardHandler,
@@ -37,6 +42,11 @@ func ProvideAdminHandlers(
 		System:       systemHandler,
 		Subscription: subscriptionHandler,
 		Usage:        usageHandler,
+		Balance:      balanceHandler,
+		Capture:      captureHandler,
+		Audit:        auditHandler,
+		AdminApiKey:  adminApiKeyHandler,
+		Organization: organizationHandler,
 	}
 }
 
@@ -54,26 +64,34 @@ func ProvideSettingHandler(settingService *service.SettingService, buildInfo Bui
 func ProvideHandlers(
 	authHandler *AuthHandler,
 	userHandler *UserHandler,
+	twoFactorHandler *TwoFactorHandler,
 	apiKeyHandler *APIKeyHandler,
 	usageHandler *UsageHandler,
+	balanceHandler *BalanceHandler,
 	redeemHandler *RedeemHandler,
 	subscriptionHandler *SubscriptionHandler,
+	organizationHandler *OrganizationHandler,
 	adminHandlers *AdminHandlers,
 	gatewayHandler *GatewayHandler,
 	openaiGatewayHandler *OpenAIGatewayHandler,
 	settingHandler *SettingHandler,
+	metricsHandler *MetricsHandler,
 ) *Handlers {
 	return &Handlers{
 		Auth:          authHandler,
 		User:          userHandler,
+		TwoFactor:     twoFactorHandler,
 		APIKey:        apiKeyHandler,
 		Usage:         usageHandler,
+		Balance:       balanceHandler,
 		Redeem:        redeemHandler,
 		Subscription:  subscriptionHandler,
+		Organization:  organizationHandler,
 		Admin:         adminHandlers,
 		Gateway:       gatewayHandler,
 		OpenAIGateway: openaiGatewayHandler,
 		Setting:       settingHandler,
+		Metrics:       metricsHandler,
 	}
 }
 
@@ -82,13 +100,17 @@ var ProviderSet = wire.NewSet(
 	// Top-level handlers
 	NewAuthHandler,
 	NewUserHandler,
+	NewTwoFactorHandler,
 	NewAPIKeyHandler,
 	NewUsageHandler,
+	NewBalanceHandler,
 	NewRedeemHandler,
 	NewSubscriptionHandler,
+	NewOrganizationHandler,
 	NewGatewayHandler,
 	NewOpenAIGatewayHandler,
 	ProvideSettingHandler,
+	NewMetricsHandler,
 
 	// Admin handlers
 	admin.NewDashboardHandler,
@@ -104,6 +126,11 @@ var ProviderSet = wire.NewSet(
 	ProvideSystemHandler,
 	admin.NewSubscriptionHandler,
 	admin.NewUsageHandler,
+	admin.NewBalanceHandler,
+	admin.NewCaptureHandler,
+	admin.NewAuditHandler,
+	admin.NewAdminApiKeyHandler,
+	admin.NewOrganizationHandler,
 
 	// AdminHandlers and Handlers constructors
 	ProvideAdminHandlers,
@@ -1,25 +1,39 @@
 package infrastructure
 
 import (
+	"log/slog"
+	"time"
+
 	"github.com/Wei-Shaw/sub2api/internal/config"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/timezone"
 	"github.com/Wei-Shaw/sub2api/internal/repository"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 // InitDB 初始化数据库连接
-func InitDB(cfg *config.Config) (*gorm.DB, error) {
+func InitDB(cfg *config.Config, l *slog.Logger) (*gorm.DB, error) {
 	// 初始化时区（在数据库连接之前，确保时区设置正确）
 	if err := timezone.Init(cfg.Timezone); err != nil {
 		return nil, err
 	}
 
-	gormConfig := &gorm.Config{}
+	// GORM 日志经由 slog 输出（慢查询、错误为 warn，debug 模式下的 SQL 为 debug）
+	gormLogLevel := gormlogger.Warn
 	if cfg.Server.Mode == "debug" {
-		gormConfig.Logger = logger.Default.LogMode(logger.Info)
+		gormLogLevel = gormlogger.Info
+	}
+	gormConfig := &gorm.Config{
+		Logger: gormlogger.New(
+			slog.NewLogLogger(l.With("component", "gorm").Handler(), slog.LevelWarn),
+			gormlogger.Config{
+				SlowThreshold:             200 * time.Millisecond,
+				LogLevel:                  gormLogLevel,
+				IgnoreRecordNotFoundError: true,
+			},
+		),
 	}
 
 	// 使用带时区的 DSN 连接数据库
@@ -0,0 +1,20 @@
+package infrastructure
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+)
+
+// InitLogger 初始化结构化 logger 并设为全局默认。
+// slog.SetDefault 同时接管标准库 log 的输出，尚未迁移的 log.Printf 也会按统一格式（info 级别）输出并脱敏。
+func InitLogger(cfg *config.Config) (*slog.Logger, error) {
+	l, err := logger.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(l)
+	return l, nil
+}
@@ -1,25 +1,40 @@
 package infrastructure
 
 import (
+	"log/slog"
+
 	"github.com/Wei-Shaw/sub2api/internal/config"
 
 	"github.com/google/wire"
 	"github.com/redis/go-redis/v9"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gorm.io/gorm"
 )
 
 // ProviderSet 提供基础设施层的依赖
 var ProviderSet = wire.NewSet(
+	ProvideLogger,
 	ProvideDB,
 	ProvideRedis,
+	ProvideTracerProvider,
 )
 
+// ProvideLogger 提供结构化 logger，并设为 slog 及标准库 log 的默认输出
+func ProvideLogger(cfg *config.Config) (*slog.Logger, error) {
+	return InitLogger(cfg)
+}
+
 // ProvideDB 提供数据库连接
-func ProvideDB(cfg *config.Config) (*gorm.DB, error) {
-	return InitDB(cfg)
+func ProvideDB(cfg *config.Config, l *slog.Logger) (*gorm.DB, error) {
+	return InitDB(cfg, l)
 }
 
 // ProvideRedis 提供 Redis 客户端
 func ProvideRedis(cfg *config.Config) *redis.Client {
 	return InitRedis(cfg)
 }
+
+// ProvideTracerProvider 提供链路追踪 TracerProvider（未启用时为 nil）
+func ProvideTracerProvider(cfg *config.Config, l *slog.Logger) (*sdktrace.TracerProvider, error) {
+	return InitTracing(cfg, l)
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+type contextKey struct{}
+
+// scope 请求级 logger。字段在请求处理过程中逐步补充（如认证后的 user_id、选中账号后的 account_id），
+// 同名字段以最后一次为准，避免故障转移时重复输出 account_id。
+type scope struct {
+	mu     sync.RWMutex
+	base   *slog.Logger
+	attrs  []slog.Attr
+	logger *slog.Logger
+}
+
+// NewContext 返回携带请求级 logger 的 context
+func NewContext(ctx context.Context, l *slog.Logger, attrs ...slog.Attr) context.Context {
+	s := &scope{base: l}
+	s.set(attrs)
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext 返回 context 中的请求级 logger，没有时返回 slog.Default()
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if s, ok := ctx.Value(contextKey{}).(*scope); ok {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+			return s.logger
+		}
+	}
+	return slog.Default()
+}
+
+// AddAttrs 为 context 中的请求级 logger 补充字段（对同一请求后续的所有日志生效）；
+// context 中没有请求级 logger 时不做任何处理
+func AddAttrs(ctx context.Context, attrs ...slog.Attr) {
+	if ctx == nil {
+		return
+	}
+	if s, ok := ctx.Value(contextKey{}).(*scope); ok {
+		s.set(attrs)
+	}
+}
+
+func (s *scope) set(attrs []slog.Attr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range attrs {
+		replaced := false
+		for i := range s.attrs {
+			if s.attrs[i].Key == a.Key {
+				s.attrs[i] = a
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.attrs = append(s.attrs, a)
+		}
+	}
+
+	args := make([]any, len(s.attrs))
+	for i, a := range s.attrs {
+		args[i] = a
+	}
+	s.logger = s.base.With(args...)
+}
+
+// Component 返回带 component 字段的默认 logger，用于后台任务等无请求 context 的场景
+func Component(name string) *slog.Logger {
+	return slog.Default().With("component", name)
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted 脱敏后的占位值
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys 字段名（小写）命中即整体脱敏
+var sensitiveKeys = map[string]struct{}{
+	"password":      {},
+	"secret":        {},
+	"token":         {},
+	"access_token":  {},
+	"refresh_token": {},
+	"id_token":      {},
+	"authorization": {},
+	"cookie":        {},
+	"api_key":       {},
+	"apikey":        {},
+	"key":           {},
+	"session_key":   {},
+	"client_secret": {},
+	"credentials":   {},
+	"code_verifier": {},
+}
+
+var sensitiveKeySuffixes = []string{"_token", "_secret", "_password"}
+
+// sensitivePatterns 对字符串内容（包括日志消息）中的凭证做替换
+var sensitivePatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`(?i)\b(Bearer|Basic)\s+[A-Za-z0-9._~+/=-]+`), "$1 " + Redacted},
+	{regexp.MustCompile(`\b(sk|admin)-[A-Za-z0-9_-]{8,}`), "$1-" + Redacted},
+	{regexp.MustCompile(`\bya29\.[A-Za-z0-9._-]+`), "ya29." + Redacted},
+	{regexp.MustCompile(`\b1//[A-Za-z0-9._-]{20,}`), "1//" + Redacted},
+	{regexp.MustCompile(`"(access_token|refresh_token|id_token|code|code_verifier|client_secret|password|session_key|sessionKey)"\s*:\s*"[^"]*"`), `"$1":"` + Redacted + `"`},
+	{regexp.MustCompile(`(?i)([?&](?:key|api_key|access_token|token)=)[^&\s"]+`), "${1}" + Redacted},
+	{regexp.MustCompile(`(sessionKey=)[^;\s"]+`), "${1}" + Redacted},
+}
+
+// RedactString 替换字符串中的 API Key、OAuth token 等凭证
+func RedactString(s string) string {
+	for _, p := range sensitivePatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if _, ok := sensitiveKeys[key]; ok {
+		return true
+	}
+	for _, suffix := range sensitiveKeySuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr slog.HandlerOptions.ReplaceAttr 实现
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(v.String()))
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, RedactString(err.Error()))
+		}
+	}
+	return a
+}
@@ -0,0 +1,184 @@
+// Package metrics 提供 Prometheus 指标定义与记录辅助函数
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "sub2api"
+	// maxModelLabelLen 模型名来自客户端请求，限制长度以控制标签基数
+	maxModelLabelLen = 64
+	// otherModelLabel 未知模型统一使用的标签值
+	otherModelLabel = "other"
+)
+
+// knownModels 可作为指标标签的模型名（价格表与账号模型映射中的模型）
+// 客户端可以发送任意模型名，只有白名单内的模型单独计数，避免标签基数无限增长
+var knownModels sync.Map
+
+// Registry 独立的指标注册表（不使用全局默认注册表，避免第三方库指标混入）
+var Registry = prometheus.NewRegistry()
+
+var (
+	gatewayRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "gateway",
+		Name:      "requests_total",
+		Help:      "Total gateway requests by platform, model, group and HTTP status.",
+	}, []string{"platform", "model", "group", "status"})
+
+	gatewayRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "gateway",
+		Name:      "request_duration_seconds",
+		Help:      "Gateway request latency by platform, model, group and HTTP status.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"platform", "model", "group", "status"})
+
+	gatewayTimeToFirstToken = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "gateway",
+		Name:      "time_to_first_token_seconds",
+		Help:      "Time to first token for streaming responses by platform and model.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 20, 60},
+	}, []string{"platform", "model"})
+
+	upstreamRetriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "gateway",
+		Name:      "upstream_retries_total",
+		Help:      "Upstream request retries against the same account.",
+	}, []string{"platform"})
+
+	failoversTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "gateway",
+		Name:      "failovers_total",
+		Help:      "Upstream errors that triggered account failover, by upstream status.",
+	}, []string{"platform", "status"})
+
+	accountSwitchesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "gateway",
+		Name:      "account_switches_total",
+		Help:      "Account switches performed after a failover.",
+	}, []string{"platform"})
+
+	usageTokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "billing",
+		Name:      "tokens_total",
+		Help:      "Billed tokens by platform, model and token type.",
+	}, []string{"platform", "model", "type"})
+
+	usageCostTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "billing",
+		Name:      "cost_usd_total",
+		Help:      "Billed cost in USD (after rate multiplier) by platform, model and billing type.",
+	}, []string{"platform", "model", "billing_type"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		gatewayRequestsTotal,
+		gatewayRequestDuration,
+		gatewayTimeToFirstToken,
+		upstreamRetriesTotal,
+		failoversTotal,
+		accountSwitchesTotal,
+		usageTokensTotal,
+		usageCostTotal,
+	)
+}
+
+// Handler 返回 /metrics 的 HTTP 处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveGatewayRequest 记录一次网关请求的结果与耗时
+func ObserveGatewayRequest(platform, model, group string, status int, duration time.Duration) {
+	model = modelLabel(model)
+	statusLabel := strconv.Itoa(status)
+	gatewayRequestsTotal.WithLabelValues(platform, model, group, statusLabel).Inc()
+	gatewayRequestDuration.WithLabelValues(platform, model, group, statusLabel).Observe(duration.Seconds())
+}
+
+// ObserveFirstToken 记录首 token 延迟，firstTokenMs 为 nil 时忽略（非流式请求）
+func ObserveFirstToken(platform, model string, firstTokenMs *int) {
+	if firstTokenMs == nil {
+		return
+	}
+	gatewayTimeToFirstToken.WithLabelValues(platform, modelLabel(model)).Observe(float64(*firstTokenMs) / 1000)
+}
+
+// IncUpstreamRetry 记录一次同账号上游重试
+func IncUpstreamRetry(platform string) {
+	upstreamRetriesTotal.WithLabelValues(platform).Inc()
+}
+
+// IncFailover 记录一次触发故障转移的上游错误
+func IncFailover(platform string, status int) {
+	failoversTotal.WithLabelValues(platform, strconv.Itoa(status)).Inc()
+}
+
+// IncAccountSwitch 记录一次故障转移后的账号切换
+func IncAccountSwitch(platform string) {
+	accountSwitchesTotal.WithLabelValues(platform).Inc()
+}
+
+// Usage 一次请求的计费用量
+type Usage struct {
+	Platform            string
+	Model               string
+	BillingType         string
+	InputTokens         int
+	OutputTokens        int
+	CacheCreationTokens int
+	CacheReadTokens     int
+	CostUSD             float64
+}
+
+// AddUsage 累加 token 与费用计数
+func AddUsage(u Usage) {
+	u.Model = modelLabel(u.Model)
+	usageTokensTotal.WithLabelValues(u.Platform, u.Model, "input").Add(float64(u.InputTokens))
+	usageTokensTotal.WithLabelValues(u.Platform, u.Model, "output").Add(float64(u.OutputTokens))
+	usageTokensTotal.WithLabelValues(u.Platform, u.Model, "cache_creation").Add(float64(u.CacheCreationTokens))
+	usageTokensTotal.WithLabelValues(u.Platform, u.Model, "cache_read").Add(float64(u.CacheReadTokens))
+	usageCostTotal.WithLabelValues(u.Platform, u.Model, u.BillingType).Add(u.CostUSD)
+}
+
+// RegisterKnownModels 将模型名加入标签白名单，超过 maxModelLabelLen 的名称忽略
+func RegisterKnownModels(models ...string) {
+	for _, model := range models {
+		model = strings.ToLower(strings.TrimSpace(model))
+		if model == "" || len(model) > maxModelLabelLen {
+			continue
+		}
+		knownModels.Store(model, struct{}{})
+	}
+}
+
+func modelLabel(model string) string {
+	if model == "" {
+		return "unknown"
+	}
+	model = strings.ToLower(model)
+	if _, ok := knownModels.Load(model); ok {
+		return model
+	}
+	return otherModelLabel
+}
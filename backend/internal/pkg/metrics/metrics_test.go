@@ -0,0 +1,68 @@
+//go:build unit
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveGatewayRequest(t *testing.T) {
+	RegisterKnownModels("claude-sonnet-4")
+	ObserveGatewayRequest("anthropic", "claude-sonnet-4", "default", 200, 2*time.Second)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(gatewayRequestsTotal.WithLabelValues("anthropic", "claude-sonnet-4", "default", "200")))
+}
+
+func TestObserveGatewayRequest_UnknownModel(t *testing.T) {
+	before := testutil.ToFloat64(gatewayRequestsTotal.WithLabelValues("openai", "other", "default", "200"))
+	ObserveGatewayRequest("openai", "made-up-model-1234", "default", 200, time.Second)
+	ObserveGatewayRequest("openai", "made-up-model-5678", "default", 200, time.Second)
+
+	require.Equal(t, before+2, testutil.ToFloat64(gatewayRequestsTotal.WithLabelValues("openai", "other", "default", "200")))
+
+	families, err := Registry.Gather()
+	require.NoError(t, err)
+	for _, mf := range families {
+		if mf.GetName() != "sub2api_gateway_requests_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "model" {
+					require.NotContains(t, label.GetValue(), "made-up-model")
+				}
+			}
+		}
+	}
+}
+
+func TestAddUsage(t *testing.T) {
+	RegisterKnownModels("gpt-4o")
+	AddUsage(Usage{
+		Platform:     "openai",
+		Model:        "gpt-4o",
+		BillingType:  "balance",
+		InputTokens:  100,
+		OutputTokens: 20,
+		CostUSD:      0.5,
+	})
+
+	require.Equal(t, 100.0, testutil.ToFloat64(usageTokensTotal.WithLabelValues("openai", "gpt-4o", "input")))
+	require.Equal(t, 20.0, testutil.ToFloat64(usageTokensTotal.WithLabelValues("openai", "gpt-4o", "output")))
+	require.Equal(t, 0.5, testutil.ToFloat64(usageCostTotal.WithLabelValues("openai", "gpt-4o", "balance")))
+}
+
+func TestModelLabel(t *testing.T) {
+	RegisterKnownModels("Claude-Opus-4", strings.Repeat("x", 200))
+
+	require.Equal(t, "unknown", modelLabel(""))
+	require.Equal(t, "claude-opus-4", modelLabel("claude-opus-4"))
+	require.Equal(t, "claude-opus-4", modelLabel("CLAUDE-OPUS-4"))
+	require.Equal(t, otherModelLabel, modelLabel("not-a-real-model"))
+	require.Equal(t, otherModelLabel, modelLabel(strings.Repeat("x", 200)))
+}
@@ -0,0 +1,144 @@
+//go:build unit
+
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func rsaJWK(t *testing.T, kid string) (*rsa.PrivateKey, JSONWebKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key, JSONWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	raw, err := token.SignedString(key)
+	require.NoError(t, err)
+	return raw
+}
+
+func baseClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   "https://idp.example.com",
+		"aud":   "client-1",
+		"sub":   "user-1",
+		"nonce": "n-1",
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+var testOpts = VerifyOptions{Issuer: "https://idp.example.com", ClientID: "client-1", Nonce: "n-1"}
+
+func TestVerifyIDToken_RSA(t *testing.T) {
+	key, jwk := rsaJWK(t, "k1")
+	_, other := rsaJWK(t, "k2")
+	keys := &KeySet{Keys: []JSONWebKey{other, jwk}}
+
+	claims, err := VerifyIDToken(sign(t, jwt.SigningMethodRS256, key, "k1", baseClaims()), keys, testOpts)
+	require.NoError(t, err)
+	require.Equal(t, "user-1", StringClaim(claims, "sub"))
+
+	// 未知 kid
+	_, err = VerifyIDToken(sign(t, jwt.SigningMethodRS256, key, "k3", baseClaims()), keys, testOpts)
+	require.ErrorIs(t, err, ErrUnknownKey)
+
+	// 签名密钥不匹配
+	_, err = VerifyIDToken(sign(t, jwt.SigningMethodRS256, key, "k2", baseClaims()), keys, testOpts)
+	require.Error(t, err)
+}
+
+func TestVerifyIDToken_EC(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keys := &KeySet{Keys: []JSONWebKey{{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}}}
+
+	// 只有一个密钥时允许省略 kid
+	_, err = VerifyIDToken(sign(t, jwt.SigningMethodES256, key, "", baseClaims()), keys, testOpts)
+	require.NoError(t, err)
+}
+
+func TestVerifyIDToken_RejectsInvalidClaims(t *testing.T) {
+	key, jwk := rsaJWK(t, "k1")
+	keys := &KeySet{Keys: []JSONWebKey{jwk}}
+
+	cases := map[string]func(jwt.MapClaims){
+		"issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"audience": func(c jwt.MapClaims) { c["aud"] = "client-2" },
+		"nonce":    func(c jwt.MapClaims) { c["nonce"] = "n-2" },
+		"expired":  func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"no exp":   func(c jwt.MapClaims) { delete(c, "exp") },
+		"subject":  func(c jwt.MapClaims) { delete(c, "sub") },
+		"azp": func(c jwt.MapClaims) {
+			c["aud"] = []string{"client-1", "client-2"}
+			c["azp"] = "client-2"
+		},
+	}
+	for name, mutate := range cases {
+		claims := baseClaims()
+		mutate(claims)
+		_, err := VerifyIDToken(sign(t, jwt.SigningMethodRS256, key, "k1", claims), keys, testOpts)
+		require.Error(t, err, name)
+	}
+
+	// 不接受对称签名
+	hs := sign(t, jwt.SigningMethodHS256, []byte("secret"), "k1", baseClaims())
+	_, err := VerifyIDToken(hs, keys, testOpts)
+	require.Error(t, err)
+}
+
+func TestBuildAuthorizationURL(t *testing.T) {
+	raw, err := BuildAuthorizationURL("https://idp.example.com/authorize?tenant=a", AuthorizationRequest{
+		ClientID:      "client-1",
+		RedirectURI:   "https://app.example.com/auth/oidc/callback",
+		State:         "s",
+		Nonce:         "n",
+		CodeChallenge: "c",
+	})
+	require.NoError(t, err)
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	q := u.Query()
+	require.Equal(t, "a", q.Get("tenant"))
+	require.Equal(t, "code", q.Get("response_type"))
+	require.Equal(t, DefaultScopes, q.Get("scope"))
+	require.Equal(t, "S256", q.Get("code_challenge_method"))
+	require.Equal(t, "n", q.Get("nonce"))
+}
+
+func TestStringsClaim(t *testing.T) {
+	claims := jwt.MapClaims{"groups": []any{"a", 1, "b"}, "role": "admin"}
+	require.Equal(t, []string{"a", "b"}, StringsClaim(claims, "groups"))
+	require.Equal(t, []string{"admin"}, StringsClaim(claims, "role"))
+	require.Nil(t, StringsClaim(claims, "missing"))
+}
@@ -1,7 +1,7 @@
 package sysutil
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"runtime"
 	"time"
@@ -21,12 +21,11 @@ import (
 //   - Service configured with Restart=always in systemd unit file
 func RestartService() error {
 	if runtime.GOOS != "linux" {
-		log.Println("Service restart via exit only works on Linux with systemd")
+		slog.Warn("service restart via exit only works on Linux with systemd")
 		return nil
 	}
 
-	log.Println("Initiating service restart by graceful exit...")
-	log.Println("systemd will automatically restart the service (Restart=always)")
+	slog.Info("initiating service restart by graceful exit; systemd will restart the service (Restart=always)")
 
 	// Give a moment for logs to flush and response to be sent
 	go func() {
@@ -41,7 +40,6 @@ func RestartService() error {
 // It logs errors instead of returning them, suitable for goroutine usage.
 func RestartServiceAsync() {
 	if err := RestartService(); err != nil {
-		log.Printf("Service restart failed: %v", err)
-		log.Println("Please restart the service manually: sudo systemctl restart sub2api")
+		slog.Error("service restart failed, please restart manually: sudo systemctl restart sub2api", "error", err)
 	}
 }
@@ -5,7 +5,7 @@ package timezone
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 )
 
@@ -35,7 +35,7 @@ func Init(tz string) error {
 	location = loc
 	tzName = tz
 
-	log.Printf("Timezone initialized: %s (UTC offset: %s)", tz, getUTCOffset(loc))
+	slog.Info("timezone initialized", "timezone", tz, "utc_offset", getUTCOffset(loc))
 	return nil
 }
 
@@ -0,0 +1,55 @@
+//go:build unit
+
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 Appendix B, SHA1 test vectors (last 6 digits)
+func TestCodeAt_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range cases {
+		got, err := CodeAt(secret, Counter(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, want, got, "t=%d", unix)
+	}
+}
+
+func TestValidate_Skew(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	prev, err := CodeAt(secret, Counter(now)-1)
+	require.NoError(t, err)
+
+	step, ok := Validate(secret, prev, now, 1)
+	require.True(t, ok)
+	require.Equal(t, Counter(now)-1, step)
+
+	_, ok = Validate(secret, prev, now, 0)
+	require.False(t, ok)
+	_, ok = Validate(secret, "12345", now, 1)
+	require.False(t, ok)
+}
+
+func TestKeyURI(t *testing.T) {
+	uri := KeyURI("Sub2API", "a@example.com", "ABC")
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/Sub2API:a@example.com?"))
+	require.Contains(t, uri, "secret=ABC")
+	require.Contains(t, uri, "issuer=Sub2API")
+}
@@ -0,0 +1,47 @@
+// Package tracing 提供 OpenTelemetry 链路追踪辅助函数
+// 未启用追踪时全局 TracerProvider 为 noop，所有函数均可安全调用。
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName tracer 名称
+const InstrumentationName = "github.com/Wei-Shaw/sub2api"
+
+// TraceIDHeader 返回给客户端的 trace id 响应头
+const TraceIDHeader = "X-Trace-Id"
+
+// Start 创建子 span
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(InstrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// TraceID 返回 ctx 中的 trace id，无有效 span 时返回空字符串
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// Detach 返回不随原请求取消、但保留 span 上下文及其他请求级数据（如请求 logger）的 context，
+// 用于请求结束后仍需继续的异步操作（如 RecordUsage）
+func Detach(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
+
+// EndWithError 记录错误（如有）并结束 span
+func EndWithError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -357,6 +357,11 @@ func (r *accountRepository) SetSchedulable(ctx context.Context, id int64, schedu
 		Update("schedulable", schedulable).Error
 }
 
+func (r *accountRepository) SetCaptureEnabled(ctx context.Context, id int64, enabled bool) error {
+	return r.db.WithContext(ctx).Model(&accountModel{}).Where("id = ?", id).
+		Update("capture_enabled", enabled).Error
+}
+
 func (r *accountRepository) UpdateExtra(ctx context.Context, id int64, updates map[string]any) error {
 	if len(updates) == 0 {
 		return nil
@@ -397,6 +402,9 @@ func (r *accountRepository) BulkUpdate(ctx context.Context, ids []int64, updates
 	if updates.Priority != nil {
 		updateMap["priority"] = *updates.Priority
 	}
+	if updates.Weight != nil {
+		updateMap["weight"] = *updates.Weight
+	}
 	if updates.Status != nil {
 		updateMap["status"] = *updates.Status
 	}
@@ -430,6 +438,7 @@ type accountModel struct {
 	ProxyID      *int64            `gorm:"index"`
 	Concurrency  int               `gorm:"default:3;not null"`
 	Priority     int               `gorm:"default:50;not null"`
+	Weight       int               `gorm:"default:1;not null"`
 	Status       string            `gorm:"size:20;default:active;not null"`
 	ErrorMessage string            `gorm:"type:text"`
 	LastUsedAt   *time.Time        `gorm:"index"`
@@ -437,7 +446,8 @@ type accountModel struct {
 	UpdatedAt    time.Time         `gorm:"not null"`
 	DeletedAt    gorm.DeletedAt    `gorm:"index"`
 
-	Schedulable bool `gorm:"default:true;not null"`
+	Schedulable    bool `gorm:"default:true;not null"`
+	CaptureEnabled bool `gorm:"default:false;not null"`
 
 	RateLimitedAt    *time.Time `gorm:"index"`
 	RateLimitResetAt *time.Time `gorm:"index"`
@@ -504,12 +514,14 @@ func accountModelToService(m *accountModel) *service.Account {
 		ProxyID:             m.ProxyID,
 		Concurrency:         m.Concurrency,
 		Priority:            m.Priority,
+		Weight:              m.Weight,
 		Status:              m.Status,
 		ErrorMessage:        m.ErrorMessage,
 		LastUsedAt:          m.LastUsedAt,
 		CreatedAt:           m.CreatedAt,
 		UpdatedAt:           m.UpdatedAt,
 		Schedulable:         m.Schedulable,
+		CaptureEnabled:      m.CaptureEnabled,
 		RateLimitedAt:       m.RateLimitedAt,
 		RateLimitResetAt:    m.RateLimitResetAt,
 		OverloadUntil:       m.OverloadUntil,
@@ -564,12 +576,14 @@ func accountModelFromService(a *service.Account) *accountModel {
 		ProxyID:             a.ProxyID,
 		Concurrency:         a.Concurrency,
 		Priority:            a.Priority,
+		Weight:              a.Weight,
 		Status:              a.Status,
 		ErrorMessage:        a.ErrorMessage,
 		LastUsedAt:          a.LastUsedAt,
 		CreatedAt:           a.CreatedAt,
 		UpdatedAt:           a.UpdatedAt,
 		Schedulable:         a.Schedulable,
+		CaptureEnabled:      a.CaptureEnabled,
 		RateLimitedAt:       a.RateLimitedAt,
 		RateLimitResetAt:    a.RateLimitResetAt,
 		OverloadUntil:       a.OverloadUntil,
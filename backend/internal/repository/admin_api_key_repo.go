@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"gorm.io/gorm"
+)
+
+type adminApiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminApiKeyRepository(db *gorm.DB) service.AdminApiKeyRepository {
+	return &adminApiKeyRepository{db: db}
+}
+
+func (r *adminApiKeyRepository) Create(ctx context.Context, key *service.AdminApiKey) error {
+	m := adminApiKeyModelFromService(key)
+	if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
+		return err
+	}
+	key.ID = m.ID
+	key.CreatedAt = m.CreatedAt
+	return nil
+}
+
+func (r *adminApiKeyRepository) GetByID(ctx context.Context, id int64) (*service.AdminApiKey, error) {
+	var m adminApiKeyModel
+	if err := r.db.WithContext(ctx).First(&m, id).Error; err != nil {
+		return nil, translatePersistenceError(err, service.ErrAdminApiKeyNotFound, nil)
+	}
+	return adminApiKeyModelToService(&m), nil
+}
+
+func (r *adminApiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*service.AdminApiKey, error) {
+	var m adminApiKeyModel
+	if err := r.db.WithContext(ctx).Where("key_hash = ?", keyHash).First(&m).Error; err != nil {
+		return nil, translatePersistenceError(err, service.ErrAdminApiKeyNotFound, nil)
+	}
+	return adminApiKeyModelToService(&m), nil
+}
+
+func (r *adminApiKeyRepository) List(ctx context.Context) ([]service.AdminApiKey, error) {
+	var models []adminApiKeyModel
+	if err := r.db.WithContext(ctx).Order("id DESC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	out := make([]service.AdminApiKey, 0, len(models))
+	for i := range models {
+		out = append(out, *adminApiKeyModelToService(&models[i]))
+	}
+	return out, nil
+}
+
+func (r *adminApiKeyRepository) Revoke(ctx context.Context, id int64, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&adminApiKeyModel{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// 区分不存在与已吊销
+		var count int64
+		if err := r.db.WithContext(ctx).Model(&adminApiKeyModel{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return service.ErrAdminApiKeyNotFound
+		}
+	}
+	return nil
+}
+
+func (r *adminApiKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&adminApiKeyModel{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+type adminApiKeyModel struct {
+	ID         int64      `gorm:"primaryKey"`
+	Name       string     `gorm:"size:100;not null;index"`
+	KeyHint    string     `gorm:"size:32;not null;default:''"`
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex"`
+	Scope      string     `gorm:"size:20;not null"`
+	CreatedBy  int64      `gorm:"not null;default:0"`
+	ExpiresAt  *time.Time `gorm:"index"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"not null"`
+}
+
+func (adminApiKeyModel) TableName() string { return "admin_api_keys" }
+
+func adminApiKeyModelToService(m *adminApiKeyModel) *service.AdminApiKey {
+	if m == nil {
+		return nil
+	}
+	return &service.AdminApiKey{
+		ID:         m.ID,
+		Name:       m.Name,
+		KeyHint:    m.KeyHint,
+		KeyHash:    m.KeyHash,
+		Scope:      m.Scope,
+		CreatedBy:  m.CreatedBy,
+		ExpiresAt:  m.ExpiresAt,
+		LastUsedAt: m.LastUsedAt,
+		RevokedAt:  m.RevokedAt,
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+func adminApiKeyModelFromService(k *service.AdminApiKey) *adminApiKeyModel {
+	if k == nil {
+		return nil
+	}
+	return &adminApiKeyModel{
+		ID:         k.ID,
+		Name:       k.Name,
+		KeyHint:    k.KeyHint,
+		KeyHash:    k.KeyHash,
+		Scope:      k.Scope,
+		CreatedBy:  k.CreatedBy,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
@@ -0,0 +1,71 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type AdminApiKeyRepoSuite struct {
+	suite.Suite
+	ctx  context.Context
+	db   *gorm.DB
+	repo *adminApiKeyRepository
+}
+
+func (s *AdminApiKeyRepoSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.db = testTx(s.T())
+	s.repo = NewAdminApiKeyRepository(s.db).(*adminApiKeyRepository)
+}
+
+func TestAdminApiKeyRepoSuite(t *testing.T) {
+	suite.Run(t, new(AdminApiKeyRepoSuite))
+}
+
+func (s *AdminApiKeyRepoSuite) TestCreateGetAndRevoke() {
+	key := &service.AdminApiKey{Name: "ci", KeyHint: "admin-abc...wxyz", KeyHash: service.HashAdminApiKey("admin-abc"), Scope: service.AdminApiKeyScopeBilling, CreatedBy: 1}
+	s.Require().NoError(s.repo.Create(s.ctx, key), "Create")
+	s.Require().NotZero(key.ID)
+
+	got, err := s.repo.GetByHash(s.ctx, key.KeyHash)
+	s.Require().NoError(err, "GetByHash")
+	s.Require().Equal(key.ID, got.ID)
+	s.Require().Equal(service.AdminApiKeyScopeBilling, got.Scope)
+
+	_, err = s.repo.GetByHash(s.ctx, "missing")
+	s.Require().ErrorIs(err, service.ErrAdminApiKeyNotFound)
+
+	now := time.Now()
+	s.Require().NoError(s.repo.TouchLastUsed(s.ctx, key.ID, now), "TouchLastUsed")
+	s.Require().NoError(s.repo.Revoke(s.ctx, key.ID, now), "Revoke")
+	// 重复吊销保持首次吊销时间
+	s.Require().NoError(s.repo.Revoke(s.ctx, key.ID, now.Add(time.Hour)), "Revoke again")
+
+	got, err = s.repo.GetByID(s.ctx, key.ID)
+	s.Require().NoError(err, "GetByID")
+	s.Require().NotNil(got.LastUsedAt)
+	s.Require().NotNil(got.RevokedAt)
+	s.Require().WithinDuration(now, *got.RevokedAt, time.Second)
+
+	s.Require().ErrorIs(s.repo.Revoke(s.ctx, key.ID+1000, now), service.ErrAdminApiKeyNotFound)
+}
+
+func (s *AdminApiKeyRepoSuite) TestDuplicateHash() {
+	hash := service.HashAdminApiKey("admin-dup")
+	s.Require().NoError(s.repo.Create(s.ctx, &service.AdminApiKey{Name: "a", KeyHash: hash, Scope: service.AdminApiKeyScopeFull}))
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return NewAdminApiKeyRepository(tx).Create(s.ctx, &service.AdminApiKey{Name: "b", KeyHash: hash, Scope: service.AdminApiKeyScopeFull})
+	})
+	s.Require().Error(err)
+
+	keys, err := s.repo.List(s.ctx)
+	s.Require().NoError(err, "List")
+	s.Require().Len(keys, 1)
+}
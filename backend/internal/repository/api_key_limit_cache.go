@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	apiKeyRPMKeyPrefix   = "apikey:rpm:"
+	apiKeyTPMKeyPrefix   = "apikey:tpm:"
+	apiKeySpendKeyPrefix = "apikey:spend:"
+)
+
+// apiKeyRPMKey generates the Redis key for per-minute request counter.
+func apiKeyRPMKey(apiKeyID int64, window string) string {
+	return fmt.Sprintf("%s%d:%s", apiKeyRPMKeyPrefix, apiKeyID, window)
+}
+
+// apiKeyTPMKey generates the Redis key for per-minute token counter.
+func apiKeyTPMKey(apiKeyID int64, window string) string {
+	return fmt.Sprintf("%s%d:%s", apiKeyTPMKeyPrefix, apiKeyID, window)
+}
+
+// apiKeySpendKey generates the Redis key for daily/monthly spend counter.
+func apiKeySpendKey(apiKeyID int64, period string) string {
+	return fmt.Sprintf("%s%d:%s", apiKeySpendKeyPrefix, apiKeyID, period)
+}
+
+var (
+	// incrWithTTLScript 计数累加，键尚无过期时间时设置 TTL
+	incrWithTTLScript = redis.NewScript(`
+		local current = redis.call('INCRBY', KEYS[1], ARGV[1])
+		if redis.call('TTL', KEYS[1]) < 0 then
+			redis.call('EXPIRE', KEYS[1], ARGV[2])
+		end
+		return current
+	`)
+
+	// addSpendScript 仅在计数器存在时累加，避免丢失数据库中的历史花费
+	addSpendScript = redis.NewScript(`
+		if redis.call('EXISTS', KEYS[1]) == 0 then
+			return 0
+		end
+		redis.call('INCRBYFLOAT', KEYS[1], ARGV[1])
+		redis.call('EXPIRE', KEYS[1], ARGV[2])
+		return 1
+	`)
+)
+
+type apiKeyLimitCache struct {
+	rdb *redis.Client
+}
+
+func NewApiKeyLimitCache(rdb *redis.Client) service.ApiKeyLimitCache {
+	return &apiKeyLimitCache{rdb: rdb}
+}
+
+func (c *apiKeyLimitCache) IncrRequestCount(ctx context.Context, apiKeyID int64, window string, ttl time.Duration) (int64, error) {
+	return incrWithTTLScript.Run(ctx, c.rdb, []string{apiKeyRPMKey(apiKeyID, window)}, 1, int(ttl.Seconds())).Int64()
+}
+
+func (c *apiKeyLimitCache) GetTokenCount(ctx context.Context, apiKeyID int64, window string) (int64, error) {
+	val, err := c.rdb.Get(ctx, apiKeyTPMKey(apiKeyID, window)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return val, err
+}
+
+func (c *apiKeyLimitCache) AddTokens(ctx context.Context, apiKeyID int64, window string, tokens int64, ttl time.Duration) error {
+	return incrWithTTLScript.Run(ctx, c.rdb, []string{apiKeyTPMKey(apiKeyID, window)}, tokens, int(ttl.Seconds())).Err()
+}
+
+func (c *apiKeyLimitCache) GetSpend(ctx context.Context, apiKeyID int64, period string) (float64, bool, error) {
+	val, err := c.rdb.Get(ctx, apiKeySpendKey(apiKeyID, period)).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	spend, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return spend, true, nil
+}
+
+func (c *apiKeyLimitCache) SetSpendIfAbsent(ctx context.Context, apiKeyID int64, period string, spend float64, ttl time.Duration) error {
+	return c.rdb.SetNX(ctx, apiKeySpendKey(apiKeyID, period), strconv.FormatFloat(spend, 'f', -1, 64), ttl).Err()
+}
+
+func (c *apiKeyLimitCache) AddSpend(ctx context.Context, apiKeyID int64, period string, cost float64, ttl time.Duration) error {
+	return addSpendScript.Run(ctx, c.rdb, []string{apiKeySpendKey(apiKeyID, period)}, strconv.FormatFloat(cost, 'f', -1, 64), int(ttl.Seconds())).Err()
+}
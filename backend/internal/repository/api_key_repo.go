@@ -8,6 +8,7 @@ import (
 
 	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
 
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
@@ -39,7 +40,10 @@ func (r *apiKeyRepository) GetByID(ctx context.Context, id int64) (*service.ApiK
 
 func (r *apiKeyRepository) GetByKey(ctx context.Context, key string) (*service.ApiKey, error) {
 	var m apiKeyModel
-	err := r.db.WithContext(ctx).Preload("User").Preload("Group").Where("key = ?", key).First(&m).Error
+	// 轮换后的旧 Key 在宽限期内同样有效
+	err := r.db.WithContext(ctx).Preload("User").Preload("Group").
+		Where("key = ? OR (previous_key = ? AND previous_key_expires_at > ?)", key, key, time.Now()).
+		First(&m).Error
 	if err != nil {
 		return nil, translatePersistenceError(err, service.ErrApiKeyNotFound, nil)
 	}
@@ -48,11 +52,17 @@ func (r *apiKeyRepository) GetByKey(ctx context.Context, key string) (*service.A
 
 func (r *apiKeyRepository) Update(ctx context.Context, key *service.ApiKey) error {
 	m := apiKeyModelFromService(key)
-	err := r.db.WithContext(ctx).Model(m).Select("name", "group_id", "status", "updated_at").Updates(m).Error
+	err := r.db.WithContext(ctx).Model(m).Select(
+		"key", "name", "group_id", "status",
+		"rate_limit_rpm", "rate_limit_tpm", "daily_limit_usd", "monthly_limit_usd",
+		"allowed_models", "allowed_ips",
+		"expires_at", "previous_key", "previous_key_expires_at",
+		"updated_at",
+	).Updates(m).Error
 	if err == nil {
 		applyApiKeyModelToService(key, m)
 	}
-	return err
+	return translatePersistenceError(err, nil, service.ErrApiKeyExists)
 }
 
 func (r *apiKeyRepository) Delete(ctx context.Context, id int64) error {
@@ -81,6 +91,28 @@ func (r *apiKeyRepository) ListByUserID(ctx context.Context, userID int64, param
 	return outKeys, paginationResultFromTotal(total, params), nil
 }
 
+func (r *apiKeyRepository) ListByOrganizationID(ctx context.Context, orgID int64, params pagination.PaginationParams) ([]service.ApiKey, *pagination.PaginationResult, error) {
+	var keys []apiKeyModel
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&apiKeyModel{}).Where("organization_id = ?", orgID)
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if err := db.Preload("User").Preload("Group").Offset(params.Offset()).Limit(params.Limit()).Order("id DESC").Find(&keys).Error; err != nil {
+		return nil, nil, err
+	}
+
+	outKeys := make([]service.ApiKey, 0, len(keys))
+	for i := range keys {
+		outKeys = append(outKeys, *apiKeyModelToService(&keys[i]))
+	}
+
+	return outKeys, paginationResultFromTotal(total, params), nil
+}
+
 func (r *apiKeyRepository) VerifyOwnership(ctx context.Context, userID int64, apiKeyIDs []int64) ([]int64, error) {
 	if len(apiKeyIDs) == 0 {
 		return []int64{}, nil
@@ -105,7 +137,7 @@ func (r *apiKeyRepository) CountByUserID(ctx context.Context, userID int64) (int
 
 func (r *apiKeyRepository) ExistsByKey(ctx context.Context, key string) (bool, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(&apiKeyModel{}).Where("key = ?", key).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&apiKeyModel{}).Where("key = ? OR previous_key = ?", key, key).Count(&count).Error
 	return count > 0, err
 }
 
@@ -172,13 +204,51 @@ func (r *apiKeyRepository) CountByGroupID(ctx context.Context, groupID int64) (i
 	return count, err
 }
 
+// ExpireKeys 将已过期的 active API Key 置为 inactive
+func (r *apiKeyRepository) ExpireKeys(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&apiKeyModel{}).
+		Where("status = ? AND expires_at IS NOT NULL AND expires_at <= ?", service.StatusActive, now).
+		Updates(map[string]any{"status": service.StatusInactive, "updated_at": now})
+	return result.RowsAffected, result.Error
+}
+
+// ClearExpiredPreviousKeys 清除宽限期已结束的轮换旧 Key
+func (r *apiKeyRepository) ClearExpiredPreviousKeys(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&apiKeyModel{}).
+		Where("previous_key IS NOT NULL AND previous_key_expires_at <= ?", now).
+		Updates(map[string]any{"previous_key": nil, "previous_key_expires_at": nil})
+	return result.RowsAffected, result.Error
+}
+
+func (r *apiKeyRepository) SetCaptureEnabled(ctx context.Context, id int64, enabled bool) error {
+	return r.db.WithContext(ctx).Model(&apiKeyModel{}).Where("id = ?", id).
+		Update("capture_enabled", enabled).Error
+}
+
 type apiKeyModel struct {
-	ID        int64          `gorm:"primaryKey"`
-	UserID    int64          `gorm:"index;not null"`
-	Key       string         `gorm:"uniqueIndex;size:128;not null"`
-	Name      string         `gorm:"size:100;not null"`
-	GroupID   *int64         `gorm:"index"`
-	Status    string         `gorm:"size:20;default:active;not null"`
+	ID      int64  `gorm:"primaryKey"`
+	UserID  int64  `gorm:"index;not null"`
+	Key     string `gorm:"uniqueIndex;size:128;not null"`
+	Name    string `gorm:"size:100;not null"`
+	GroupID *int64 `gorm:"index"`
+	Status  string `gorm:"size:20;default:active;not null"`
+
+	RateLimitRPM    int      `gorm:"default:0;not null"`
+	RateLimitTPM    int      `gorm:"default:0;not null"`
+	DailyLimitUSD   *float64 `gorm:"type:decimal(20,8)"`
+	MonthlyLimitUSD *float64 `gorm:"type:decimal(20,8)"`
+
+	AllowedModels pq.StringArray `gorm:"type:text[]"`
+	AllowedIPs    pq.StringArray `gorm:"type:text[]"`
+
+	ExpiresAt            *time.Time `gorm:"index"`
+	PreviousKey          *string    `gorm:"index;size:128"`
+	PreviousKeyExpiresAt *time.Time
+
+	CaptureEnabled bool `gorm:"default:false;not null"`
+
+	OrganizationID *int64 `gorm:"index"`
+
 	CreatedAt time.Time      `gorm:"not null"`
 	UpdatedAt time.Time      `gorm:"not null"`
 	DeletedAt gorm.DeletedAt `gorm:"index"`
@@ -194,12 +264,28 @@ func apiKeyModelToService(m *apiKeyModel) *service.ApiKey {
 		return nil
 	}
 	return &service.ApiKey{
-		ID:        m.ID,
-		UserID:    m.UserID,
-		Key:       m.Key,
-		Name:      m.Name,
-		GroupID:   m.GroupID,
-		Status:    m.Status,
+		ID:      m.ID,
+		UserID:  m.UserID,
+		Key:     m.Key,
+		Name:    m.Name,
+		GroupID: m.GroupID,
+		Status:  m.Status,
+
+		RateLimitRPM:    m.RateLimitRPM,
+		RateLimitTPM:    m.RateLimitTPM,
+		DailyLimitUSD:   m.DailyLimitUSD,
+		MonthlyLimitUSD: m.MonthlyLimitUSD,
+
+		AllowedModels: []string(m.AllowedModels),
+		AllowedIPs:    []string(m.AllowedIPs),
+
+		ExpiresAt:            m.ExpiresAt,
+		PreviousKey:          m.PreviousKey,
+		PreviousKeyExpiresAt: m.PreviousKeyExpiresAt,
+
+		CaptureEnabled: m.CaptureEnabled,
+		OrganizationID: m.OrganizationID,
+
 		CreatedAt: m.CreatedAt,
 		UpdatedAt: m.UpdatedAt,
 		User:      userModelToService(m.User),
@@ -212,12 +298,28 @@ func apiKeyModelFromService(k *service.ApiKey) *apiKeyModel {
 		return nil
 	}
 	return &apiKeyModel{
-		ID:        k.ID,
-		UserID:    k.UserID,
-		Key:       k.Key,
-		Name:      k.Name,
-		GroupID:   k.GroupID,
-		Status:    k.Status,
+		ID:      k.ID,
+		UserID:  k.UserID,
+		Key:     k.Key,
+		Name:    k.Name,
+		GroupID: k.GroupID,
+		Status:  k.Status,
+
+		RateLimitRPM:    k.RateLimitRPM,
+		RateLimitTPM:    k.RateLimitTPM,
+		DailyLimitUSD:   k.DailyLimitUSD,
+		MonthlyLimitUSD: k.MonthlyLimitUSD,
+
+		AllowedModels: pq.StringArray(k.AllowedModels),
+		AllowedIPs:    pq.StringArray(k.AllowedIPs),
+
+		ExpiresAt:            k.ExpiresAt,
+		PreviousKey:          k.PreviousKey,
+		PreviousKeyExpiresAt: k.PreviousKeyExpiresAt,
+
+		CaptureEnabled: k.CaptureEnabled,
+		OrganizationID: k.OrganizationID,
+
 		CreatedAt: k.CreatedAt,
 		UpdatedAt: k.UpdatedAt,
 	}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) service.AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+func (r *auditLogRepository) Create(ctx context.Context, log *service.AuditLog) error {
+	m, err := auditLogModelFromService(log)
+	if err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
+		return err
+	}
+	log.ID = m.ID
+	log.CreatedAt = m.CreatedAt
+	return nil
+}
+
+func (r *auditLogRepository) List(ctx context.Context, params pagination.PaginationParams, filters service.AuditLogFilters) ([]service.AuditLog, *pagination.PaginationResult, error) {
+	var logs []auditLogModel
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&auditLogModel{})
+	if filters.ActorID > 0 {
+		db = db.Where("actor_id = ?", filters.ActorID)
+	}
+	if filters.AdminApiKeyID > 0 {
+		db = db.Where("admin_api_key_id = ?", filters.AdminApiKeyID)
+	}
+	if filters.Action != "" {
+		db = db.Where("action = ?", filters.Action)
+	}
+	if filters.ResourceType != "" {
+		db = db.Where("resource_type = ?", filters.ResourceType)
+	}
+	if filters.ResourceID != "" {
+		db = db.Where("resource_id = ?", filters.ResourceID)
+	}
+	if filters.StartTime != nil {
+		db = db.Where("created_at >= ?", *filters.StartTime)
+	}
+	if filters.EndTime != nil {
+		db = db.Where("created_at <= ?", *filters.EndTime)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if err := db.Offset(params.Offset()).Limit(params.Limit()).Order("id DESC").Find(&logs).Error; err != nil {
+		return nil, nil, err
+	}
+
+	out := make([]service.AuditLog, 0, len(logs))
+	for i := range logs {
+		out = append(out, *auditLogModelToService(&logs[i]))
+	}
+	return out, paginationResultFromTotal(total, params), nil
+}
+
+// auditLogModel 审计日志，表上的触发器禁止 UPDATE/DELETE
+type auditLogModel struct {
+	ID            int64          `gorm:"primaryKey"`
+	ActorID       int64          `gorm:"index;not null;default:0"`
+	ActorRole     string         `gorm:"size:30;not null;default:''"`
+	AuthMethod    string         `gorm:"size:30;not null;default:''"`
+	AdminApiKeyID *int64         `gorm:"index"`
+	IP            string         `gorm:"size:64;not null;default:''"`
+	Action        string         `gorm:"size:30;not null"`
+	ResourceType  string         `gorm:"size:50;not null;index:idx_audit_logs_resource,priority:1"`
+	ResourceID    string         `gorm:"size:64;not null;default:'';index:idx_audit_logs_resource,priority:2"`
+	Changes       datatypes.JSON `gorm:"type:jsonb;not null;default:'{}'"`
+	CreatedAt     time.Time      `gorm:"index;not null"`
+}
+
+func (auditLogModel) TableName() string { return "audit_logs" }
+
+func auditLogModelToService(m *auditLogModel) *service.AuditLog {
+	if m == nil {
+		return nil
+	}
+	log := &service.AuditLog{
+		ID:            m.ID,
+		ActorID:       m.ActorID,
+		ActorRole:     m.ActorRole,
+		AuthMethod:    m.AuthMethod,
+		AdminApiKeyID: m.AdminApiKeyID,
+		IP:            m.IP,
+		Action:        m.Action,
+		ResourceType:  m.ResourceType,
+		ResourceID:    m.ResourceID,
+		CreatedAt:     m.CreatedAt,
+	}
+	_ = json.Unmarshal(m.Changes, &log.Changes)
+	return log
+}
+
+func auditLogModelFromService(l *service.AuditLog) (*auditLogModel, error) {
+	changes := l.Changes
+	if changes == nil {
+		changes = map[string]service.AuditChange{}
+	}
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLogModel{
+		ID:            l.ID,
+		ActorID:       l.ActorID,
+		ActorRole:     l.ActorRole,
+		AuthMethod:    l.AuthMethod,
+		AdminApiKeyID: l.AdminApiKeyID,
+		IP:            l.IP,
+		Action:        l.Action,
+		ResourceType:  l.ResourceType,
+		ResourceID:    l.ResourceID,
+		Changes:       datatypes.JSON(raw),
+		CreatedAt:     l.CreatedAt,
+	}, nil
+}
+
+// ensureAuditLogAppendOnly 创建触发器，在数据库层禁止修改与删除审计日志（与 migrations/017_audit_logs.sql 一致）
+func ensureAuditLogAppendOnly(db *gorm.DB) error {
+	if db.Name() != "postgres" {
+		return nil
+	}
+	return db.Exec(`
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_logs_append_only ON audit_logs;
+CREATE TRIGGER trg_audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();`).Error
+}
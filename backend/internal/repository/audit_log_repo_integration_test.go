@@ -0,0 +1,71 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type AuditLogRepoSuite struct {
+	suite.Suite
+	ctx  context.Context
+	db   *gorm.DB
+	repo *auditLogRepository
+}
+
+func (s *AuditLogRepoSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.db = testTx(s.T())
+	s.repo = NewAuditLogRepository(s.db).(*auditLogRepository)
+}
+
+func TestAuditLogRepoSuite(t *testing.T) {
+	suite.Run(t, new(AuditLogRepoSuite))
+}
+
+func (s *AuditLogRepoSuite) TestCreateAndList() {
+	entries := []*service.AuditLog{
+		{ActorID: 1, ActorRole: service.RoleAdmin, Action: service.AuditActionUpdate, ResourceType: service.AuditResourceAccount, ResourceID: "5",
+			Changes: map[string]service.AuditChange{"Name": {Before: "a", After: "b"}}},
+		{ActorID: 2, ActorRole: service.RoleBillingOperator, Action: service.AuditActionUpdate, ResourceType: service.AuditResourceBalance, ResourceID: "9"},
+		{ActorID: 1, ActorRole: service.RoleAdmin, Action: service.AuditActionDelete, ResourceType: service.AuditResourceAccount, ResourceID: "6"},
+	}
+	for _, e := range entries {
+		s.Require().NoError(s.repo.Create(s.ctx, e), "Create")
+		s.Require().NotZero(e.ID)
+	}
+
+	params := pagination.PaginationParams{Page: 1, PageSize: 10}
+	logs, result, err := s.repo.List(s.ctx, params, service.AuditLogFilters{ResourceType: service.AuditResourceAccount})
+	s.Require().NoError(err, "List")
+	s.Require().Equal(int64(2), result.Total)
+	s.Require().Equal("6", logs[0].ResourceID, "newest first")
+	s.Require().Equal(service.AuditChange{Before: "a", After: "b"}, logs[1].Changes["Name"])
+
+	logs, _, err = s.repo.List(s.ctx, params, service.AuditLogFilters{ActorID: 2})
+	s.Require().NoError(err, "List by actor")
+	s.Require().Len(logs, 1)
+	s.Require().Equal(service.AuditResourceBalance, logs[0].ResourceType)
+}
+
+func (s *AuditLogRepoSuite) TestAppendOnly() {
+	entry := &service.AuditLog{ActorID: 1, Action: service.AuditActionCreate, ResourceType: service.AuditResourceGroup, ResourceID: "1"}
+	s.Require().NoError(s.repo.Create(s.ctx, entry), "Create")
+
+	// 嵌套事务使用 savepoint，触发器报错不会中断外层测试事务
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Exec("UPDATE audit_logs SET action = 'delete' WHERE id = ?", entry.ID).Error
+	})
+	s.Require().Error(err, "update should be rejected")
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Exec("DELETE FROM audit_logs WHERE id = ?", entry.ID).Error
+	})
+	s.Require().Error(err, "delete should be rejected")
+}
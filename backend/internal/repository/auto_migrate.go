@@ -5,7 +5,7 @@ import "gorm.io/gorm"
 // AutoMigrate runs schema migrations for all repository persistence models.
 // Persistence models are defined within individual `*_repo.go` files.
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	if err := db.AutoMigrate(
 		&userModel{},
 		&apiKeyModel{},
 		&groupModel{},
@@ -16,5 +16,20 @@ func AutoMigrate(db *gorm.DB) error {
 		&usageLogModel{},
 		&settingModel{},
 		&userSubscriptionModel{},
-	)
+		&balanceTransactionModel{},
+		&requestCaptureModel{},
+		&webhookDeliveryModel{},
+		&userNotificationPrefsModel{},
+		&userTOTPModel{},
+		&webAuthnCredentialModel{},
+		&userIdentityModel{},
+		&auditLogModel{},
+		&adminApiKeyModel{},
+		&organizationModel{},
+		&organizationMemberModel{},
+		&organizationInvitationModel{},
+	); err != nil {
+		return err
+	}
+	return ensureAuditLogAppendOnly(db)
 }
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type balanceTransactionRepository struct {
+	db *gorm.DB
+}
+
+func NewBalanceTransactionRepository(db *gorm.DB) service.BalanceTransactionRepository {
+	return &balanceTransactionRepository{db: db}
+}
+
+func (r *balanceTransactionRepository) Apply(ctx context.Context, tx *service.BalanceTransaction, allowNegative bool) error {
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		current, err := lockBalance(db, tx)
+		if err != nil {
+			return err
+		}
+		if !allowNegative && tx.Amount < 0 && current+tx.Amount < 0 {
+			return service.ErrInsufficientBalance
+		}
+		return applyBalanceChange(db, tx)
+	})
+}
+
+func (r *balanceTransactionRepository) SetBalance(ctx context.Context, tx *service.BalanceTransaction, target float64) error {
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		current, err := lockBalance(db, tx)
+		if err != nil {
+			return err
+		}
+		tx.Amount = target - current
+		if tx.Amount == 0 {
+			tx.BalanceAfter = current
+			return nil
+		}
+		return applyBalanceChange(db, tx)
+	})
+}
+
+func (r *balanceTransactionRepository) CreateUsageWithDebit(ctx context.Context, usageLog *service.UsageLog, tx *service.BalanceTransaction) error {
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		m := usageLogModelFromService(usageLog)
+		if err := db.Create(m).Error; err != nil {
+			return err
+		}
+		applyUsageLogModelToService(usageLog, m)
+
+		if tx == nil || tx.Amount == 0 {
+			return nil
+		}
+		tx.UsageLogID = &usageLog.ID
+		if err := applyBalanceChange(db, tx); err != nil {
+			return err
+		}
+		// 组织余额扣费同时累计成员当月花费（用于成员花费上限）
+		if tx.OrganizationID != nil {
+			return addOrgMemberUsage(db, *tx.OrganizationID, tx.UserID, -tx.Amount, usageLog.CreatedAt)
+		}
+		return nil
+	})
+}
+
+func (r *balanceTransactionRepository) RefundUsage(ctx context.Context, tx *service.BalanceTransaction) error {
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		if _, err := lockBalance(db, tx); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := db.Model(&balanceTransactionModel{}).
+			Where("usage_log_id = ? AND type = ?", tx.UsageLogID, service.BalanceTxTypeRefund).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return service.ErrUsageAlreadyRefunded
+		}
+		if err := applyBalanceChange(db, tx); err != nil {
+			return err
+		}
+		if tx.OrganizationID != nil {
+			return refundOrgMemberUsage(db, *tx.OrganizationID, tx.UserID, tx.Amount, *tx.UsageLogID)
+		}
+		return nil
+	})
+	return translatePersistenceError(err, nil, service.ErrUsageAlreadyRefunded)
+}
+
+func (r *balanceTransactionRepository) List(ctx context.Context, params pagination.PaginationParams, filters service.BalanceTransactionFilters) ([]service.BalanceTransaction, *pagination.PaginationResult, error) {
+	var txs []balanceTransactionModel
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&balanceTransactionModel{})
+	// 按用户查询时只返回个人余额流水，除非同时指定了组织
+	if filters.OrganizationID > 0 {
+		db = db.Where("organization_id = ?", filters.OrganizationID)
+	} else if filters.UserID > 0 {
+		db = db.Where("organization_id IS NULL")
+	}
+	if filters.UserID > 0 {
+		db = db.Where("user_id = ?", filters.UserID)
+	}
+	if filters.Type != "" {
+		db = db.Where("type = ?", filters.Type)
+	}
+	if filters.StartTime != nil {
+		db = db.Where("created_at >= ?", *filters.StartTime)
+	}
+	if filters.EndTime != nil {
+		db = db.Where("created_at <= ?", *filters.EndTime)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if err := db.Preload("User").Offset(params.Offset()).Limit(params.Limit()).Order("id DESC").Find(&txs).Error; err != nil {
+		return nil, nil, err
+	}
+
+	out := make([]service.BalanceTransaction, 0, len(txs))
+	for i := range txs {
+		out = append(out, *balanceTransactionModelToService(&txs[i]))
+	}
+	return out, paginationResultFromTotal(total, params), nil
+}
+
+// lockBalance 锁定流水对应的用户或组织行并返回当前余额
+func lockBalance(db *gorm.DB, tx *service.BalanceTransaction) (float64, error) {
+	if tx.OrganizationID != nil {
+		var org organizationModel
+		err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "balance").First(&org, *tx.OrganizationID).Error
+		if err != nil {
+			return 0, translatePersistenceError(err, service.ErrOrganizationNotFound, nil)
+		}
+		return org.Balance, nil
+	}
+	var user userModel
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "balance").First(&user, tx.UserID).Error
+	if err != nil {
+		return 0, translatePersistenceError(err, service.ErrUserNotFound, nil)
+	}
+	return user.Balance, nil
+}
+
+// applyBalanceChange 在事务内调整余额并写入流水（调用方负责开启事务）
+func applyBalanceChange(db *gorm.DB, tx *service.BalanceTransaction) error {
+	table, id := "users", tx.UserID
+	if tx.OrganizationID != nil {
+		table, id = "organizations", *tx.OrganizationID
+	}
+	var balanceAfter float64
+	if err := db.Raw(
+		"UPDATE "+table+" SET balance = balance + ?, updated_at = ? WHERE id = ? RETURNING balance",
+		tx.Amount, time.Now(), id,
+	).Scan(&balanceAfter).Error; err != nil {
+		return err
+	}
+	tx.BalanceAfter = balanceAfter
+
+	m := balanceTransactionModelFromService(tx)
+	if err := db.Create(m).Error; err != nil {
+		return err
+	}
+	tx.ID = m.ID
+	tx.CreatedAt = m.CreatedAt
+	return nil
+}
+
+type balanceTransactionModel struct {
+	ID             int64   `gorm:"primaryKey"`
+	UserID         int64   `gorm:"index;not null"`
+	OrganizationID *int64  `gorm:"index"`
+	Type           string  `gorm:"size:30;index;not null"`
+	Amount         float64 `gorm:"type:decimal(20,10);not null"`
+	BalanceAfter   float64 `gorm:"type:decimal(20,8);not null"`
+
+	UsageLogID   *int64 `gorm:"index"`
+	RedeemCodeID *int64 `gorm:"index"`
+	OperatorID   *int64
+	Notes        string `gorm:"type:text;default:''"`
+
+	CreatedAt time.Time `gorm:"index;not null"`
+
+	User *userModel `gorm:"foreignKey:UserID"`
+}
+
+func (balanceTransactionModel) TableName() string { return "balance_transactions" }
+
+func balanceTransactionModelToService(m *balanceTransactionModel) *service.BalanceTransaction {
+	if m == nil {
+		return nil
+	}
+	return &service.BalanceTransaction{
+		ID:             m.ID,
+		UserID:         m.UserID,
+		OrganizationID: m.OrganizationID,
+		Type:           m.Type,
+		Amount:         m.Amount,
+		BalanceAfter:   m.BalanceAfter,
+		UsageLogID:     m.UsageLogID,
+		RedeemCodeID:   m.RedeemCodeID,
+		OperatorID:     m.OperatorID,
+		Notes:          m.Notes,
+		CreatedAt:      m.CreatedAt,
+		User:           userModelToService(m.User),
+	}
+}
+
+func balanceTransactionModelFromService(t *service.BalanceTransaction) *balanceTransactionModel {
+	if t == nil {
+		return nil
+	}
+	return &balanceTransactionModel{
+		ID:             t.ID,
+		UserID:         t.UserID,
+		OrganizationID: t.OrganizationID,
+		Type:           t.Type,
+		Amount:         t.Amount,
+		BalanceAfter:   t.BalanceAfter,
+		UsageLogID:     t.UsageLogID,
+		RedeemCodeID:   t.RedeemCodeID,
+		OperatorID:     t.OperatorID,
+		Notes:          t.Notes,
+		CreatedAt:      t.CreatedAt,
+	}
+}
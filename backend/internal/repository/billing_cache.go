@@ -4,10 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"strconv"
 	"time"
 
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 	"github.com/Wei-Shaw/sub2api/internal/service"
 	"github.com/redis/go-redis/v9"
 )
@@ -16,6 +16,15 @@ const (
 	billingBalanceKeyPrefix = "billing:balance:"
 	billingSubKeyPrefix     = "billing:sub:"
 	billingCacheTTL         = 5 * time.Minute
+
+	// Format: billing:hold:{userID}  member=holdID score=expiry(ms)
+	billingHoldKeyPrefix = "billing:hold:"
+	// Format: billing:hold_amount:{userID}  field=holdID value=amount
+	billingHoldAmountKeyPrefix = "billing:hold_amount:"
+	// Format: billing:org_hold:{orgID} / billing:org_hold:{orgID}:{userID}  member=holdID score=expiry(ms)
+	billingOrgHoldKeyPrefix = "billing:org_hold:"
+	// Format: billing:org_hold_amount:{orgID} / billing:org_hold_amount:{orgID}:{userID}  field=holdID value=amount
+	billingOrgHoldAmountKeyPrefix = "billing:org_hold_amount:"
 )
 
 // billingBalanceKey generates the Redis key for user balance cache.
@@ -28,6 +37,26 @@ func billingSubKey(userID, groupID int64) string {
 	return fmt.Sprintf("%s%d:%d", billingSubKeyPrefix, userID, groupID)
 }
 
+// billingHoldKey generates the Redis key for the user's hold expiry sorted set.
+func billingHoldKey(userID int64) string {
+	return fmt.Sprintf("%s%d", billingHoldKeyPrefix, userID)
+}
+
+// billingHoldAmountKey generates the Redis key for the user's hold amount hash.
+func billingHoldAmountKey(userID int64) string {
+	return fmt.Sprintf("%s%d", billingHoldAmountKeyPrefix, userID)
+}
+
+// billingOrgHoldKeys generates the Redis keys for the organization's holds and the member's holds within it.
+func billingOrgHoldKeys(orgID, userID int64) []string {
+	return []string{
+		fmt.Sprintf("%s%d", billingOrgHoldKeyPrefix, orgID),
+		fmt.Sprintf("%s%d", billingOrgHoldAmountKeyPrefix, orgID),
+		fmt.Sprintf("%s%d:%d", billingOrgHoldKeyPrefix, orgID, userID),
+		fmt.Sprintf("%s%d:%d", billingOrgHoldAmountKeyPrefix, orgID, userID),
+	}
+}
+
 const (
 	subFieldStatus       = "status"
 	subFieldExpiresAt    = "expires_at"
@@ -61,6 +90,97 @@ var (
 		redis.call('EXPIRE', KEYS[1], ARGV[2])
 		return 1
 	`)
+
+	// reserveHoldScript prunes expired holds and adds a new hold if balance allows
+	// KEYS[1] = hold expiry sorted set key
+	// KEYS[2] = hold amount hash key
+	// ARGV[1] = holdID
+	// ARGV[2] = amount
+	// ARGV[3] = current balance
+	// ARGV[4] = TTL in milliseconds
+	reserveHoldScript = redis.NewScript(`
+		local member = ARGV[1]
+		local amount = tonumber(ARGV[2])
+		local balance = tonumber(ARGV[3])
+		local ttl = tonumber(ARGV[4])
+
+		local t = redis.call('TIME')
+		local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+		-- 清理已过期的预授权（请求异常退出时未释放）
+		local expired = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', now)
+		for _, id in ipairs(expired) do
+			redis.call('HDEL', KEYS[2], id)
+		end
+		redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+
+		local held = 0
+		for _, v in ipairs(redis.call('HVALS', KEYS[2])) do
+			held = held + tonumber(v)
+		end
+
+		if balance - held - amount < 0 then
+			return 0
+		end
+
+		redis.call('ZADD', KEYS[1], now + ttl, member)
+		redis.call('HSET', KEYS[2], member, ARGV[2])
+		redis.call('PEXPIRE', KEYS[1], ttl)
+		redis.call('PEXPIRE', KEYS[2], ttl)
+		return 1
+	`)
+
+	// reserveOrgHoldScript prunes expired holds and adds a new hold to both the
+	// organization and the member if the organization balance and the member's
+	// remaining monthly limit allow
+	// KEYS[1], KEYS[2] = organization hold expiry sorted set / amount hash keys
+	// KEYS[3], KEYS[4] = member hold expiry sorted set / amount hash keys
+	// ARGV[1] = holdID
+	// ARGV[2] = amount
+	// ARGV[3] = organization balance
+	// ARGV[4] = member remaining monthly limit (negative means unlimited)
+	// ARGV[5] = TTL in milliseconds
+	// Returns 1 on success, 0 if the organization balance is short, -1 if the member limit is short
+	reserveOrgHoldScript = redis.NewScript(`
+		local member = ARGV[1]
+		local amount = tonumber(ARGV[2])
+		local balance = tonumber(ARGV[3])
+		local limit = tonumber(ARGV[4])
+		local ttl = tonumber(ARGV[5])
+
+		local t = redis.call('TIME')
+		local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+		-- 清理已过期的预授权并返回未结算总额
+		local function held(zkey, hkey)
+			local expired = redis.call('ZRANGEBYSCORE', zkey, '-inf', now)
+			for _, id in ipairs(expired) do
+				redis.call('HDEL', hkey, id)
+			end
+			redis.call('ZREMRANGEBYSCORE', zkey, '-inf', now)
+			local sum = 0
+			for _, v in ipairs(redis.call('HVALS', hkey)) do
+				sum = sum + tonumber(v)
+			end
+			return sum
+		end
+
+		if balance - held(KEYS[1], KEYS[2]) - amount < 0 then
+			return 0
+		end
+		local memberHeld = held(KEYS[3], KEYS[4])
+		if limit >= 0 and limit - memberHeld - amount < 0 then
+			return -1
+		end
+
+		for i = 1, 3, 2 do
+			redis.call('ZADD', KEYS[i], now + ttl, member)
+			redis.call('HSET', KEYS[i + 1], member, ARGV[2])
+			redis.call('PEXPIRE', KEYS[i], ttl)
+			redis.call('PEXPIRE', KEYS[i + 1], ttl)
+		end
+		return 1
+	`)
 )
 
 type billingCache struct {
@@ -89,7 +209,7 @@ func (c *billingCache) DeductUserBalance(ctx context.Context, userID int64, amou
 	key := billingBalanceKey(userID)
 	_, err := deductBalanceScript.Run(ctx, c.rdb, []string{key}, amount, int(billingCacheTTL.Seconds())).Result()
 	if err != nil && !errors.Is(err, redis.Nil) {
-		log.Printf("Warning: deduct balance cache failed for user %d: %v", userID, err)
+		logger.FromContext(ctx).Warn("deduct balance cache failed", "user_id", userID, logger.Err(err))
 	}
 	return nil
 }
@@ -99,6 +219,48 @@ func (c *billingCache) InvalidateUserBalance(ctx context.Context, userID int64)
 	return c.rdb.Del(ctx, key).Err()
 }
 
+func (c *billingCache) ReserveBalanceHold(ctx context.Context, userID int64, holdID string, amount, balance float64, ttl time.Duration) (bool, error) {
+	keys := []string{billingHoldKey(userID), billingHoldAmountKey(userID)}
+	result, err := reserveHoldScript.Run(ctx, c.rdb, keys, holdID, amount, balance, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return result == 1, nil
+}
+
+func (c *billingCache) ReleaseBalanceHold(ctx context.Context, userID int64, holdID string) error {
+	pipe := c.rdb.TxPipeline()
+	pipe.ZRem(ctx, billingHoldKey(userID), holdID)
+	pipe.HDel(ctx, billingHoldAmountKey(userID), holdID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *billingCache) ReserveOrgBalanceHold(ctx context.Context, orgID, userID int64, holdID string, amount, balance, memberLimit float64, ttl time.Duration) error {
+	result, err := reserveOrgHoldScript.Run(ctx, c.rdb, billingOrgHoldKeys(orgID, userID), holdID, amount, balance, memberLimit, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	switch result {
+	case 0:
+		return service.ErrInsufficientBalance
+	case -1:
+		return service.ErrOrgMemberSpendingLimit
+	}
+	return nil
+}
+
+func (c *billingCache) ReleaseOrgBalanceHold(ctx context.Context, orgID, userID int64, holdID string) error {
+	keys := billingOrgHoldKeys(orgID, userID)
+	pipe := c.rdb.TxPipeline()
+	pipe.ZRem(ctx, keys[0], holdID)
+	pipe.HDel(ctx, keys[1], holdID)
+	pipe.ZRem(ctx, keys[2], holdID)
+	pipe.HDel(ctx, keys[3], holdID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
 func (c *billingCache) GetSubscriptionCache(ctx context.Context, userID, groupID int64) (*service.SubscriptionCacheData, error) {
 	key := billingSubKey(userID, groupID)
 	result, err := c.rdb.HGetAll(ctx, key).Result()
@@ -172,7 +334,7 @@ func (c *billingCache) UpdateSubscriptionUsage(ctx context.Context, userID, grou
 	key := billingSubKey(userID, groupID)
 	_, err := updateSubUsageScript.Run(ctx, c.rdb, []string{key}, cost, int(billingCacheTTL.Seconds())).Result()
 	if err != nil && !errors.Is(err, redis.Nil) {
-		log.Printf("Warning: update subscription usage cache failed for user %d group %d: %v", userID, groupID, err)
+		logger.FromContext(ctx).Warn("update subscription usage cache failed", "user_id", userID, "group_id", groupID, logger.Err(err))
 	}
 	return nil
 }
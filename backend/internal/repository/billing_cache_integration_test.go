@@ -5,6 +5,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -130,6 +131,70 @@ func (s *BillingCacheSuite) TestUserBalance() {
 	}
 }
 
+func (s *BillingCacheSuite) TestBalanceHold() {
+	rdb := testRedis(s.T())
+	cache := NewBillingCache(rdb)
+	ctx := context.Background()
+	userID := int64(201)
+
+	ok, err := cache.ReserveBalanceHold(ctx, userID, "h1", 6, 10, time.Minute)
+	require.NoError(s.T(), err, "ReserveBalanceHold h1")
+	require.True(s.T(), ok, "first hold should fit in balance")
+
+	ok, err = cache.ReserveBalanceHold(ctx, userID, "h2", 6, 10, time.Minute)
+	require.NoError(s.T(), err, "ReserveBalanceHold h2")
+	require.False(s.T(), ok, "second hold should exceed balance minus outstanding holds")
+
+	require.NoError(s.T(), cache.ReleaseBalanceHold(ctx, userID, "h1"), "ReleaseBalanceHold")
+
+	ok, err = cache.ReserveBalanceHold(ctx, userID, "h2", 6, 10, time.Minute)
+	require.NoError(s.T(), err, "ReserveBalanceHold h2 after release")
+	require.True(s.T(), ok, "hold should fit after release")
+
+	ttl, err := rdb.PTTL(ctx, billingHoldKey(userID)).Result()
+	require.NoError(s.T(), err, "PTTL hold key")
+	s.AssertTTLWithin(ttl, 1*time.Second, time.Minute)
+}
+
+func (s *BillingCacheSuite) TestOrgBalanceHoldConcurrent() {
+	rdb := testRedis(s.T())
+	cache := NewBillingCache(rdb)
+	ctx := context.Background()
+	orgID := int64(301)
+
+	// 组织余额 10，每个预授权 3：并发 10 个请求只有 3 个成功
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := cache.ReserveOrgBalanceHold(ctx, orgID, int64(i%2+1), fmt.Sprintf("h%d", i), 3, 10, -1, time.Minute)
+			if err == nil {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+				return
+			}
+			require.ErrorIs(s.T(), err, service.ErrInsufficientBalance)
+		}(i)
+	}
+	wg.Wait()
+	require.Equal(s.T(), 3, reserved, "organization holds must not exceed balance")
+
+	// 成员剩余额度 4：组织余额充足时仍受成员上限限制
+	otherOrg := int64(302)
+	require.NoError(s.T(), cache.ReserveOrgBalanceHold(ctx, otherOrg, 1, "m1", 3, 100, 4, time.Minute))
+	require.ErrorIs(s.T(), cache.ReserveOrgBalanceHold(ctx, otherOrg, 1, "m2", 3, 100, 4, time.Minute), service.ErrOrgMemberSpendingLimit)
+	require.NoError(s.T(), cache.ReserveOrgBalanceHold(ctx, otherOrg, 2, "m3", 3, 100, 4, time.Minute), "limit is per member")
+
+	require.NoError(s.T(), cache.ReleaseOrgBalanceHold(ctx, otherOrg, 1, "m1"))
+	require.NoError(s.T(), cache.ReserveOrgBalanceHold(ctx, otherOrg, 1, "m2", 3, 100, 4, time.Minute), "hold should fit after release")
+}
+
 func (s *BillingCacheSuite) TestSubscriptionCache() {
 	tests := []struct {
 		name string
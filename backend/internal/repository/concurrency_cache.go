@@ -10,10 +10,10 @@ import (
 )
 
 const (
-	// Key prefixes for independent slot keys
-	// Format: concurrency:account:{accountID}:{requestID}
+	// Key prefixes for per-account/per-user slot sorted sets
+	// Format: concurrency:account:{accountID}  member=requestID score=expiry(ms)
 	accountSlotKeyPrefix = "concurrency:account:"
-	// Format: concurrency:user:{userID}:{requestID}
+	// Format: concurrency:user:{userID}  member=requestID score=expiry(ms)
 	userSlotKeyPrefix = "concurrency:user:"
 	// Wait queue keeps counter format: concurrency:wait:{userID}
 	waitQueueKeyPrefix = "concurrency:wait:"
@@ -23,47 +23,47 @@ const (
 )
 
 var (
-	// acquireScript uses SCAN to count existing slots and creates new slot if under limit
-	// KEYS[1] = pattern for SCAN (e.g., "concurrency:account:2:*")
-	// KEYS[2] = full slot key (e.g., "concurrency:account:2:req_xxx")
+	// acquireScript prunes expired slots and adds a new slot if under limit
+	// KEYS[1] = slot sorted set key (e.g., "concurrency:account:2")
 	// ARGV[1] = maxConcurrency
-	// ARGV[2] = TTL in seconds
+	// ARGV[2] = TTL in milliseconds
+	// ARGV[3] = requestID (member)
 	acquireScript = redis.NewScript(`
-		local pattern = KEYS[1]
-		local slotKey = KEYS[2]
+		local key = KEYS[1]
 		local maxConcurrency = tonumber(ARGV[1])
 		local ttl = tonumber(ARGV[2])
+		local member = ARGV[3]
+
+		-- 使用 Redis 服务器时间，避免多实例时钟不一致
+		local t = redis.call('TIME')
+		local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+		-- 清理已过期的槽位
+		redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
+
+		-- 同一 requestID 重复获取视为续期（幂等）
+		if redis.call('ZSCORE', key, member) then
+			redis.call('ZADD', key, now + ttl, member)
+			redis.call('PEXPIRE', key, ttl)
+			return 1
+		end
 
-		-- Count existing slots using SCAN
-		local cursor = "0"
-		local count = 0
-		repeat
-			local result = redis.call('SCAN', cursor, 'MATCH', pattern, 'COUNT', 100)
-			cursor = result[1]
-			count = count + #result[2]
-		until cursor == "0"
-
-		-- Check if we can acquire a slot
-		if count < maxConcurrency then
-			redis.call('SET', slotKey, '1', 'EX', ttl)
+		if redis.call('ZCARD', key) < maxConcurrency then
+			redis.call('ZADD', key, now + ttl, member)
+			-- 整个集合在最后一个槽位过期后自动删除
+			redis.call('PEXPIRE', key, ttl)
 			return 1
 		end
 
 		return 0
 	`)
 
-	// getCountScript counts slots using SCAN
-	// KEYS[1] = pattern for SCAN
+	// getCountScript counts unexpired slots without modifying the set
+	// KEYS[1] = slot sorted set key
 	getCountScript = redis.NewScript(`
-		local pattern = KEYS[1]
-		local cursor = "0"
-		local count = 0
-		repeat
-			local result = redis.call('SCAN', cursor, 'MATCH', pattern, 'COUNT', 100)
-			cursor = result[1]
-			count = count + #result[2]
-		until cursor == "0"
-		return count
+		local t = redis.call('TIME')
+		local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+		return redis.call('ZCOUNT', KEYS[1], '(' .. string.format('%d', now), '+inf')
 	`)
 
 	// incrementWaitScript - only sets TTL on first creation to avoid refreshing
@@ -111,20 +111,12 @@ func NewConcurrencyCache(rdb *redis.Client) service.ConcurrencyCache {
 }
 
 // Helper functions for key generation
-func accountSlotKey(accountID int64, requestID string) string {
-	return fmt.Sprintf("%s%d:%s", accountSlotKeyPrefix, accountID, requestID)
-}
-
-func accountSlotPattern(accountID int64) string {
-	return fmt.Sprintf("%s%d:*", accountSlotKeyPrefix, accountID)
+func accountSlotKey(accountID int64) string {
+	return fmt.Sprintf("%s%d", accountSlotKeyPrefix, accountID)
 }
 
-func userSlotKey(userID int64, requestID string) string {
-	return fmt.Sprintf("%s%d:%s", userSlotKeyPrefix, userID, requestID)
-}
-
-func userSlotPattern(userID int64) string {
-	return fmt.Sprintf("%s%d:*", userSlotKeyPrefix, userID)
+func userSlotKey(userID int64) string {
+	return fmt.Sprintf("%s%d", userSlotKeyPrefix, userID)
 }
 
 func waitQueueKey(userID int64) string {
@@ -134,51 +126,41 @@ func waitQueueKey(userID int64) string {
 // Account slot operations
 
 func (c *concurrencyCache) AcquireAccountSlot(ctx context.Context, accountID int64, maxConcurrency int, requestID string) (bool, error) {
-	pattern := accountSlotPattern(accountID)
-	slotKey := accountSlotKey(accountID, requestID)
-
-	result, err := acquireScript.Run(ctx, c.rdb, []string{pattern, slotKey}, maxConcurrency, int(slotTTL.Seconds())).Int()
-	if err != nil {
-		return false, err
-	}
-	return result == 1, nil
+	return c.acquireSlot(ctx, accountSlotKey(accountID), maxConcurrency, requestID)
 }
 
 func (c *concurrencyCache) ReleaseAccountSlot(ctx context.Context, accountID int64, requestID string) error {
-	slotKey := accountSlotKey(accountID, requestID)
-	return c.rdb.Del(ctx, slotKey).Err()
+	return c.rdb.ZRem(ctx, accountSlotKey(accountID), requestID).Err()
 }
 
 func (c *concurrencyCache) GetAccountConcurrency(ctx context.Context, accountID int64) (int, error) {
-	pattern := accountSlotPattern(accountID)
-	result, err := getCountScript.Run(ctx, c.rdb, []string{pattern}).Int()
-	if err != nil {
-		return 0, err
-	}
-	return result, nil
+	return c.countSlots(ctx, accountSlotKey(accountID))
 }
 
 // User slot operations
 
 func (c *concurrencyCache) AcquireUserSlot(ctx context.Context, userID int64, maxConcurrency int, requestID string) (bool, error) {
-	pattern := userSlotPattern(userID)
-	slotKey := userSlotKey(userID, requestID)
+	return c.acquireSlot(ctx, userSlotKey(userID), maxConcurrency, requestID)
+}
+
+func (c *concurrencyCache) ReleaseUserSlot(ctx context.Context, userID int64, requestID string) error {
+	return c.rdb.ZRem(ctx, userSlotKey(userID), requestID).Err()
+}
 
-	result, err := acquireScript.Run(ctx, c.rdb, []string{pattern, slotKey}, maxConcurrency, int(slotTTL.Seconds())).Int()
+func (c *concurrencyCache) GetUserConcurrency(ctx context.Context, userID int64) (int, error) {
+	return c.countSlots(ctx, userSlotKey(userID))
+}
+
+func (c *concurrencyCache) acquireSlot(ctx context.Context, key string, maxConcurrency int, requestID string) (bool, error) {
+	result, err := acquireScript.Run(ctx, c.rdb, []string{key}, maxConcurrency, slotTTL.Milliseconds(), requestID).Int()
 	if err != nil {
 		return false, err
 	}
 	return result == 1, nil
 }
 
-func (c *concurrencyCache) ReleaseUserSlot(ctx context.Context, userID int64, requestID string) error {
-	slotKey := userSlotKey(userID, requestID)
-	return c.rdb.Del(ctx, slotKey).Err()
-}
-
-func (c *concurrencyCache) GetUserConcurrency(ctx context.Context, userID int64) (int, error) {
-	pattern := userSlotPattern(userID)
-	result, err := getCountScript.Run(ctx, c.rdb, []string{pattern}).Int()
+func (c *concurrencyCache) countSlots(ctx context.Context, key string) (int, error) {
+	result, err := getCountScript.Run(ctx, c.rdb, []string{key}).Int()
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,83 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// 填充与并发槽位无关的键，模拟线上较大的 keyspace（旧的 SCAN 实现耗时随之线性增长）
+const benchmarkNoiseKeys = 50000
+
+func BenchmarkConcurrencyCache_AcquireRelease(b *testing.B) {
+	ctx := context.Background()
+	rdb := testRedis(b)
+	cache := NewConcurrencyCache(rdb)
+
+	pipe := rdb.Pipeline()
+	for i := 0; i < benchmarkNoiseKeys; i++ {
+		pipe.Set(ctx, fmt.Sprintf("noise:%d", i), "1", 0)
+	}
+	_, err := pipe.Exec(ctx)
+	require.NoError(b, err)
+
+	accountID := int64(1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reqID := strconv.Itoa(i)
+		ok, err := cache.AcquireAccountSlot(ctx, accountID, 10, reqID)
+		if err != nil || !ok {
+			b.Fatalf("acquire failed: ok=%v err=%v", ok, err)
+		}
+		if err := cache.ReleaseAccountSlot(ctx, accountID, reqID); err != nil {
+			b.Fatalf("release failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkConcurrencyCache_AcquireReleaseParallel(b *testing.B) {
+	ctx := context.Background()
+	cache := NewConcurrencyCache(testRedis(b))
+
+	var seq uint64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n := atomic.AddUint64(&seq, 1)
+			accountID := int64(n % 8)
+			reqID := strconv.FormatUint(n, 10)
+			if _, err := cache.AcquireAccountSlot(ctx, accountID, 1000, reqID); err != nil {
+				b.Fatalf("acquire failed: %v", err)
+			}
+			if err := cache.ReleaseAccountSlot(ctx, accountID, reqID); err != nil {
+				b.Fatalf("release failed: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkConcurrencyCache_GetAccountConcurrency(b *testing.B) {
+	ctx := context.Background()
+	cache := NewConcurrencyCache(testRedis(b))
+
+	accountID := int64(2)
+	for i := 0; i < 50; i++ {
+		ok, err := cache.AcquireAccountSlot(ctx, accountID, 100, strconv.Itoa(i))
+		require.NoError(b, err)
+		require.True(b, ok)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cur, err := cache.GetAccountConcurrency(ctx, accountID)
+		if err != nil || cur != 50 {
+			b.Fatalf("unexpected concurrency: cur=%d err=%v", cur, err)
+		}
+	}
+}
@@ -5,6 +5,8 @@ package repository
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -54,7 +56,7 @@ func (s *ConcurrencyCacheSuite) TestAccountSlot_AcquireAndRelease() {
 func (s *ConcurrencyCacheSuite) TestAccountSlot_TTL() {
 	accountID := int64(11)
 	reqID := "req_ttl_test"
-	slotKey := fmt.Sprintf("%s%d:%s", accountSlotKeyPrefix, accountID, reqID)
+	slotKey := fmt.Sprintf("%s%d", accountSlotKeyPrefix, accountID)
 
 	ok, err := s.cache.AcquireAccountSlot(s.ctx, accountID, 5, reqID)
 	require.NoError(s.T(), err, "AcquireAccountSlot")
@@ -63,6 +65,36 @@ func (s *ConcurrencyCacheSuite) TestAccountSlot_TTL() {
 	ttl, err := s.rdb.TTL(s.ctx, slotKey).Result()
 	require.NoError(s.T(), err, "TTL")
 	s.AssertTTLWithin(ttl, 1*time.Second, slotTTL)
+
+	// 槽位分数为过期时间戳（毫秒）
+	score, err := s.rdb.ZScore(s.ctx, slotKey, reqID).Result()
+	require.NoError(s.T(), err, "ZScore")
+	expiresAt := time.UnixMilli(int64(score))
+	require.WithinDuration(s.T(), time.Now().Add(slotTTL), expiresAt, 5*time.Second)
+}
+
+func (s *ConcurrencyCacheSuite) TestAccountSlot_ExpiredSlotsPruned() {
+	accountID := int64(15)
+	slotKey := fmt.Sprintf("%s%d", accountSlotKeyPrefix, accountID)
+
+	// 模拟两个已过期但未释放的槽位
+	past := float64(time.Now().Add(-time.Minute).UnixMilli())
+	require.NoError(s.T(), s.rdb.ZAdd(s.ctx, slotKey,
+		redis.Z{Score: past, Member: "stale1"},
+		redis.Z{Score: past, Member: "stale2"},
+	).Err())
+
+	cur, err := s.cache.GetAccountConcurrency(s.ctx, accountID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 0, cur, "expired slots should not be counted")
+
+	ok, err := s.cache.AcquireAccountSlot(s.ctx, accountID, 1, "fresh")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok, "expired slots should not block acquire")
+
+	members, err := s.rdb.ZRange(s.ctx, slotKey, 0, -1).Result()
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{"fresh"}, members, "expired slots should be pruned on acquire")
 }
 
 func (s *ConcurrencyCacheSuite) TestAccountSlot_DuplicateReqID() {
@@ -102,6 +134,31 @@ func (s *ConcurrencyCacheSuite) TestAccountSlot_ReleaseIdempotent() {
 	require.Equal(s.T(), 0, cur)
 }
 
+func (s *ConcurrencyCacheSuite) TestAccountSlot_ConcurrentAcquireNeverExceedsLimit() {
+	accountID := int64(16)
+	const limit = 5
+	const workers = 50
+
+	var acquired atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ok, err := s.cache.AcquireAccountSlot(s.ctx, accountID, limit, fmt.Sprintf("req-%d", i))
+			if err == nil && ok {
+				acquired.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(s.T(), int32(limit), acquired.Load())
+	cur, err := s.cache.GetAccountConcurrency(s.ctx, accountID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), limit, cur)
+}
+
 func (s *ConcurrencyCacheSuite) TestAccountSlot_MaxZero() {
 	accountID := int64(14)
 	reqID := "max-zero-test"
@@ -139,7 +196,7 @@ func (s *ConcurrencyCacheSuite) TestUserSlot_AcquireAndRelease() {
 func (s *ConcurrencyCacheSuite) TestUserSlot_TTL() {
 	userID := int64(200)
 	reqID := "req_ttl_test"
-	slotKey := fmt.Sprintf("%s%d:%s", userSlotKeyPrefix, userID, reqID)
+	slotKey := fmt.Sprintf("%s%d", userSlotKeyPrefix, userID)
 
 	ok, err := s.cache.AcquireUserSlot(s.ctx, userID, 5, reqID)
 	require.NoError(s.T(), err, "AcquireUserSlot")
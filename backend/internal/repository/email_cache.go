@@ -9,7 +9,10 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-const verifyCodeKeyPrefix = "verify_code:"
+const (
+	verifyCodeKeyPrefix = "verify_code:"
+	emailRateKeyPrefix  = "email_rate:"
+)
 
 // verifyCodeKey generates the Redis key for email verification code.
 func verifyCodeKey(email string) string {
@@ -50,3 +53,17 @@ func (c *emailCache) DeleteVerificationCode(ctx context.Context, email string) e
 	key := verifyCodeKey(email)
 	return c.rdb.Del(ctx, key).Err()
 }
+
+func (c *emailCache) IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error) {
+	rateKey := emailRateKeyPrefix + key
+	count, err := c.rdb.Incr(ctx, rateKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err := c.rdb.Expire(ctx, rateKey, window).Err(); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
@@ -87,6 +87,19 @@ func (s *EmailCacheSuite) TestGetVerificationCode_JSONCorruption() {
 	require.False(s.T(), errors.Is(err, redis.Nil), "expected decoding error, not redis.Nil")
 }
 
+func (s *EmailCacheSuite) TestIncrementRateLimit() {
+	window := 2 * time.Minute
+	for i := int64(1); i <= 3; i++ {
+		count, err := s.cache.IncrementRateLimit(s.ctx, "pwd_reset:a@example.com", window)
+		require.NoError(s.T(), err, "IncrementRateLimit")
+		require.Equal(s.T(), i, count)
+	}
+
+	ttl, err := s.rdb.TTL(s.ctx, emailRateKeyPrefix+"pwd_reset:a@example.com").Result()
+	require.NoError(s.T(), err, "TTL rate key")
+	s.AssertTTLWithin(ttl, 1*time.Second, window)
+}
+
 func TestEmailCacheSuite(t *testing.T) {
 	suite.Run(t, new(EmailCacheSuite))
 }
@@ -2,13 +2,71 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/service"
 	"github.com/redis/go-redis/v9"
 )
 
-const stickySessionPrefix = "sticky_session:"
+const (
+	stickySessionPrefix = "sticky_session:"
+	// stickyAccountIndexPrefix 账号 -> 粘性会话索引（ZSET，member 为会话 key，score 为过期时间戳）
+	stickyAccountIndexPrefix = "sticky_session_account:"
+)
+
+func stickyAccountIndexKey(accountID int64) string {
+	return stickyAccountIndexPrefix + strconv.FormatInt(accountID, 10)
+}
+
+var (
+	// setStickySessionScript 写入绑定并登记到账号索引
+	// KEYS[1] = 会话 key, KEYS[2] = 账号索引
+	// ARGV[1] = accountID, ARGV[2] = ttl 秒, ARGV[3] = 会话 key（不含前缀）
+	setStickySessionScript = redis.NewScript(`
+		local ttl = tonumber(ARGV[2])
+		local now = tonumber(redis.call('TIME')[1])
+		redis.call('SET', KEYS[1], ARGV[1], 'EX', ttl)
+		redis.call('ZADD', KEYS[2], now + ttl, ARGV[3])
+		redis.call('EXPIRE', KEYS[2], ttl)
+		return 1
+	`)
+
+	// refreshStickySessionScript 续期绑定并同步账号索引中的过期时间
+	// KEYS[1] = 会话 key
+	// ARGV[1] = ttl 秒, ARGV[2] = 会话 key（不含前缀）, ARGV[3] = 账号索引前缀
+	refreshStickySessionScript = redis.NewScript(`
+		local accountID = redis.call('GET', KEYS[1])
+		if not accountID then
+			return 0
+		end
+		local ttl = tonumber(ARGV[1])
+		local now = tonumber(redis.call('TIME')[1])
+		local index = ARGV[3] .. accountID
+		redis.call('EXPIRE', KEYS[1], ttl)
+		redis.call('ZADD', index, now + ttl, ARGV[2])
+		redis.call('EXPIRE', index, ttl)
+		return 1
+	`)
+
+	// deleteAccountSessionsScript 删除仍绑定到该账号的会话（已改绑到其他账号的不删除）并清空索引
+	// KEYS[1] = 账号索引
+	// ARGV[1] = 会话 key 前缀, ARGV[2] = accountID
+	deleteAccountSessionsScript = redis.NewScript(`
+		local members = redis.call('ZRANGE', KEYS[1], 0, -1)
+		local deleted = 0
+		for _, member in ipairs(members) do
+			local key = ARGV[1] .. member
+			if redis.call('GET', key) == ARGV[2] then
+				redis.call('DEL', key)
+				deleted = deleted + 1
+			end
+		end
+		redis.call('DEL', KEYS[1])
+		return deleted
+	`)
+)
 
 type gatewayCache struct {
 	rdb *redis.Client
@@ -18,17 +76,71 @@ func NewGatewayCache(rdb *redis.Client) service.GatewayCache {
 	return &gatewayCache{rdb: rdb}
 }
 
-func (c *gatewayCache) GetSessionAccountID(ctx context.Context, sessionHash string) (int64, error) {
-	key := stickySessionPrefix + sessionHash
+func (c *gatewayCache) GetSessionAccountID(ctx context.Context, sessionKey string) (int64, error) {
+	key := stickySessionPrefix + sessionKey
 	return c.rdb.Get(ctx, key).Int64()
 }
 
-func (c *gatewayCache) SetSessionAccountID(ctx context.Context, sessionHash string, accountID int64, ttl time.Duration) error {
-	key := stickySessionPrefix + sessionHash
-	return c.rdb.Set(ctx, key, accountID, ttl).Err()
+func (c *gatewayCache) SetSessionAccountID(ctx context.Context, sessionKey string, accountID int64, ttl time.Duration) error {
+	keys := []string{stickySessionPrefix + sessionKey, stickyAccountIndexKey(accountID)}
+	return setStickySessionScript.Run(ctx, c.rdb, keys, accountID, int(ttl.Seconds()), sessionKey).Err()
+}
+
+func (c *gatewayCache) RefreshSessionTTL(ctx context.Context, sessionKey string, ttl time.Duration) error {
+	keys := []string{stickySessionPrefix + sessionKey}
+	return refreshStickySessionScript.Run(ctx, c.rdb, keys, int(ttl.Seconds()), sessionKey, stickyAccountIndexPrefix).Err()
+}
+
+func (c *gatewayCache) ListAccountSessions(ctx context.Context, accountID int64) ([]service.StickySessionBinding, error) {
+	indexKey := stickyAccountIndexKey(accountID)
+	now := time.Now().Unix()
+	if err := c.rdb.ZRemRangeByScore(ctx, indexKey, "-inf", strconv.FormatInt(now, 10)).Err(); err != nil {
+		return nil, err
+	}
+	members, err := c.rdb.ZRangeWithScores(ctx, indexKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	pipe := c.rdb.Pipeline()
+	cmds := make([]*redis.StringCmd, len(members))
+	for i, m := range members {
+		cmds[i] = pipe.Get(ctx, stickySessionPrefix+m.Member.(string))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	want := strconv.FormatInt(accountID, 10)
+	bindings := make([]service.StickySessionBinding, 0, len(members))
+	var stale []any
+	for i, m := range members {
+		member := m.Member.(string)
+		if val, err := cmds[i].Result(); err != nil || val != want {
+			// 会话已过期或已改绑到其他账号
+			stale = append(stale, member)
+			continue
+		}
+		bindings = append(bindings, service.StickySessionBinding{
+			Key:       member,
+			AccountID: accountID,
+			ExpiresAt: time.Unix(int64(m.Score), 0),
+		})
+	}
+	if len(stale) > 0 {
+		_ = c.rdb.ZRem(ctx, indexKey, stale...).Err()
+	}
+	return bindings, nil
 }
 
-func (c *gatewayCache) RefreshSessionTTL(ctx context.Context, sessionHash string, ttl time.Duration) error {
-	key := stickySessionPrefix + sessionHash
-	return c.rdb.Expire(ctx, key, ttl).Err()
+func (c *gatewayCache) DeleteAccountSessions(ctx context.Context, accountID int64) (int, error) {
+	keys := []string{stickyAccountIndexKey(accountID)}
+	n, err := deleteAccountSessionsScript.Run(ctx, c.rdb, keys, stickySessionPrefix, strconv.FormatInt(accountID, 10)).Int()
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
 }
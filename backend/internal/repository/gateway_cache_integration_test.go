@@ -87,6 +87,31 @@ func (s *GatewayCacheSuite) TestGetSessionAccountID_CorruptedValue() {
 	require.False(s.T(), errors.Is(err, redis.Nil), "expected parsing error, not redis.Nil")
 }
 
+func (s *GatewayCacheSuite) TestListAndDeleteAccountSessions() {
+	require.NoError(s.T(), s.cache.SetSessionAccountID(s.ctx, "anthropic:1:7:h1", 200, time.Minute))
+	require.NoError(s.T(), s.cache.SetSessionAccountID(s.ctx, "anthropic:1:8:h2", 200, time.Minute))
+	require.NoError(s.T(), s.cache.SetSessionAccountID(s.ctx, "anthropic:1:9:h3", 201, time.Minute))
+
+	// 重新绑定到其他账号后，旧账号的索引不再返回该会话
+	require.NoError(s.T(), s.cache.SetSessionAccountID(s.ctx, "anthropic:1:8:h2", 201, time.Minute))
+
+	bindings, err := s.cache.ListAccountSessions(s.ctx, 200)
+	require.NoError(s.T(), err, "ListAccountSessions")
+	require.Len(s.T(), bindings, 1)
+	require.Equal(s.T(), "anthropic:1:7:h1", bindings[0].Key)
+	require.Equal(s.T(), int64(200), bindings[0].AccountID)
+	require.WithinDuration(s.T(), time.Now().Add(time.Minute), bindings[0].ExpiresAt, 5*time.Second)
+
+	cleared, err := s.cache.DeleteAccountSessions(s.ctx, 201)
+	require.NoError(s.T(), err, "DeleteAccountSessions")
+	require.Equal(s.T(), 2, cleared)
+
+	_, err = s.cache.GetSessionAccountID(s.ctx, "anthropic:1:9:h3")
+	require.True(s.T(), errors.Is(err, redis.Nil), "expected session to be deleted")
+	_, err = s.cache.GetSessionAccountID(s.ctx, "anthropic:1:7:h1")
+	require.NoError(s.T(), err, "session bound to another account must remain")
+}
+
 func TestGatewayCacheSuite(t *testing.T) {
 	suite.Run(t, new(GatewayCacheSuite))
 }
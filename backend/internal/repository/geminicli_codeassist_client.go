@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/pkg/geminicli"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 	"github.com/Wei-Shaw/sub2api/internal/service"
 
 	"github.com/imroc/req/v3"
@@ -34,15 +35,15 @@ func (c *geminiCliCodeAssistClient) LoadCodeAssist(ctx context.Context, accessTo
 		SetSuccessResult(&out).
 		Post(c.baseURL + "/v1internal:loadCodeAssist")
 	if err != nil {
-		fmt.Printf("[CodeAssist] LoadCodeAssist request error: %v\n", err)
+		logger.FromContext(ctx).Warn("code assist loadCodeAssist request failed", logger.Err(err))
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	if !resp.IsSuccessState() {
 		body := geminicli.SanitizeBodyForLogs(resp.String())
-		fmt.Printf("[CodeAssist] LoadCodeAssist failed: status %d, body: %s\n", resp.StatusCode, body)
+		logger.FromContext(ctx).Warn("code assist loadCodeAssist failed", "status", resp.StatusCode, "body", body)
 		return nil, fmt.Errorf("loadCodeAssist failed: status %d, body: %s", resp.StatusCode, body)
 	}
-	fmt.Printf("[CodeAssist] LoadCodeAssist success: status %d, response: %+v\n", resp.StatusCode, out)
+	logger.FromContext(ctx).Debug("code assist loadCodeAssist succeeded", "status", resp.StatusCode)
 	return &out, nil
 }
 
@@ -51,8 +52,6 @@ func (c *geminiCliCodeAssistClient) OnboardUser(ctx context.Context, accessToken
 		reqBody = defaultOnboardUserRequest()
 	}
 
-	fmt.Printf("[CodeAssist] OnboardUser request body: %+v\n", reqBody)
-
 	var out geminicli.OnboardUserResponse
 	resp, err := createGeminiCliReqClient(proxyURL).R().
 		SetContext(ctx).
@@ -63,15 +62,15 @@ func (c *geminiCliCodeAssistClient) OnboardUser(ctx context.Context, accessToken
 		SetSuccessResult(&out).
 		Post(c.baseURL + "/v1internal:onboardUser")
 	if err != nil {
-		fmt.Printf("[CodeAssist] OnboardUser request error: %v\n", err)
+		logger.FromContext(ctx).Warn("code assist onboardUser request failed", logger.Err(err))
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	if !resp.IsSuccessState() {
 		body := geminicli.SanitizeBodyForLogs(resp.String())
-		fmt.Printf("[CodeAssist] OnboardUser failed: status %d, body: %s\n", resp.StatusCode, body)
+		logger.FromContext(ctx).Warn("code assist onboardUser failed", "status", resp.StatusCode, "body", body)
 		return nil, fmt.Errorf("onboardUser failed: status %d, body: %s", resp.StatusCode, body)
 	}
-	fmt.Printf("[CodeAssist] OnboardUser success: status %d, response: %+v\n", resp.StatusCode, out)
+	logger.FromContext(ctx).Debug("code assist onboardUser succeeded", "status", resp.StatusCode)
 	return &out, nil
 }
 
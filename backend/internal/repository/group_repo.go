@@ -222,6 +222,12 @@ type groupModel struct {
 	WeeklyLimitUSD   *float64 `gorm:"type:decimal(20,8)"`
 	MonthlyLimitUSD  *float64 `gorm:"type:decimal(20,8)"`
 
+	SchedulingStrategy string `gorm:"size:30;default:priority;not null"`
+
+	ResponseCacheEnabled    bool    `gorm:"default:false;not null"`
+	ResponseCacheTTLSeconds int     `gorm:"default:0;not null"`
+	ResponseCacheMultiplier float64 `gorm:"type:decimal(10,4);default:1.0;not null"`
+
 	CreatedAt time.Time      `gorm:"not null"`
 	UpdatedAt time.Time      `gorm:"not null"`
 	DeletedAt gorm.DeletedAt `gorm:"index"`
@@ -234,19 +240,25 @@ func groupModelToService(m *groupModel) *service.Group {
 		return nil
 	}
 	return &service.Group{
-		ID:               m.ID,
-		Name:             m.Name,
-		Description:      m.Description,
-		Platform:         m.Platform,
-		RateMultiplier:   m.RateMultiplier,
-		IsExclusive:      m.IsExclusive,
-		Status:           m.Status,
-		SubscriptionType: m.SubscriptionType,
-		DailyLimitUSD:    m.DailyLimitUSD,
-		WeeklyLimitUSD:   m.WeeklyLimitUSD,
-		MonthlyLimitUSD:  m.MonthlyLimitUSD,
-		CreatedAt:        m.CreatedAt,
-		UpdatedAt:        m.UpdatedAt,
+		ID:                 m.ID,
+		Name:               m.Name,
+		Description:        m.Description,
+		Platform:           m.Platform,
+		RateMultiplier:     m.RateMultiplier,
+		IsExclusive:        m.IsExclusive,
+		Status:             m.Status,
+		SubscriptionType:   m.SubscriptionType,
+		DailyLimitUSD:      m.DailyLimitUSD,
+		WeeklyLimitUSD:     m.WeeklyLimitUSD,
+		MonthlyLimitUSD:    m.MonthlyLimitUSD,
+		SchedulingStrategy: m.SchedulingStrategy,
+
+		ResponseCacheEnabled:    m.ResponseCacheEnabled,
+		ResponseCacheTTLSeconds: m.ResponseCacheTTLSeconds,
+		ResponseCacheMultiplier: m.ResponseCacheMultiplier,
+
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
 	}
 }
 
@@ -255,19 +267,25 @@ func groupModelFromService(sg *service.Group) *groupModel {
 		return nil
 	}
 	return &groupModel{
-		ID:               sg.ID,
-		Name:             sg.Name,
-		Description:      sg.Description,
-		Platform:         sg.Platform,
-		RateMultiplier:   sg.RateMultiplier,
-		IsExclusive:      sg.IsExclusive,
-		Status:           sg.Status,
-		SubscriptionType: sg.SubscriptionType,
-		DailyLimitUSD:    sg.DailyLimitUSD,
-		WeeklyLimitUSD:   sg.WeeklyLimitUSD,
-		MonthlyLimitUSD:  sg.MonthlyLimitUSD,
-		CreatedAt:        sg.CreatedAt,
-		UpdatedAt:        sg.UpdatedAt,
+		ID:                 sg.ID,
+		Name:               sg.Name,
+		Description:        sg.Description,
+		Platform:           sg.Platform,
+		RateMultiplier:     sg.RateMultiplier,
+		IsExclusive:        sg.IsExclusive,
+		Status:             sg.Status,
+		SubscriptionType:   sg.SubscriptionType,
+		DailyLimitUSD:      sg.DailyLimitUSD,
+		WeeklyLimitUSD:     sg.WeeklyLimitUSD,
+		MonthlyLimitUSD:    sg.MonthlyLimitUSD,
+		SchedulingStrategy: sg.SchedulingStrategy,
+
+		ResponseCacheEnabled:    sg.ResponseCacheEnabled,
+		ResponseCacheTTLSeconds: sg.ResponseCacheTTLSeconds,
+		ResponseCacheMultiplier: sg.ResponseCacheMultiplier,
+
+		CreatedAt: sg.CreatedAt,
+		UpdatedAt: sg.UpdatedAt,
 	}
 }
 
@@ -1,39 +1,58 @@
 package repository
 
 import (
+	"container/list"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/config"
 	"github.com/Wei-Shaw/sub2api/internal/service"
 )
 
+const (
+	defaultMaxIdleConns          = 100
+	defaultMaxIdleConnsPerHost   = 10
+	defaultIdleConnTimeout       = 90 * time.Second
+	defaultMaxProxyTransports    = 100
+	defaultProxyTransportIdleTTL = 10 * time.Minute
+
+	// proxyClientSweepInterval 空闲代理连接池的最小清理间隔
+	proxyClientSweepInterval = time.Minute
+)
+
 // httpUpstreamService is a generic HTTP upstream service that can be used for
 // making requests to any HTTP API (Claude, OpenAI, etc.) with optional proxy support.
 type httpUpstreamService struct {
 	defaultClient *http.Client
 	cfg           *config.Config
+
+	// 按代理URL缓存的客户端（LRU），复用 keep-alive 连接与 TLS 会话
+	mu           sync.Mutex
+	proxyClients map[string]*list.Element
+	lru          *list.List // front = most recently used
+	lastSweep    time.Time
+}
+
+// proxyClientEntry 代理客户端缓存条目
+type proxyClientEntry struct {
+	proxyURL  string
+	client    *http.Client
+	transport *http.Transport
+	lastUsed  time.Time
 }
 
 // NewHTTPUpstream creates a new generic HTTP upstream service
 func NewHTTPUpstream(cfg *config.Config) service.HTTPUpstream {
-	responseHeaderTimeout := time.Duration(cfg.Gateway.ResponseHeaderTimeout) * time.Second
-	if responseHeaderTimeout == 0 {
-		responseHeaderTimeout = 300 * time.Second
-	}
-
-	transport := &http.Transport{
-		MaxIdleConns:          100,
-		MaxIdleConnsPerHost:   10,
-		IdleConnTimeout:       90 * time.Second,
-		ResponseHeaderTimeout: responseHeaderTimeout,
-	}
-
-	return &httpUpstreamService{
-		defaultClient: &http.Client{Transport: transport},
-		cfg:           cfg,
+	s := &httpUpstreamService{
+		cfg:          cfg,
+		proxyClients: make(map[string]*list.Element),
+		lru:          list.New(),
+		lastSweep:    time.Now(),
 	}
+	s.defaultClient = &http.Client{Transport: s.newTransport(nil)}
+	return s
 }
 
 func (s *httpUpstreamService) Do(req *http.Request, proxyURL string) (*http.Response, error) {
@@ -44,24 +63,118 @@ func (s *httpUpstreamService) Do(req *http.Request, proxyURL string) (*http.Resp
 	return client.Do(req)
 }
 
+// InvalidateProxy 移除并关闭指定代理URL的连接池
+func (s *httpUpstreamService) InvalidateProxy(proxyURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.proxyClients[proxyURL]; ok {
+		s.removeLocked(elem)
+	}
+}
+
+// createProxyClient 返回指定代理的客户端，同一代理URL复用同一个 Transport
 func (s *httpUpstreamService) createProxyClient(proxyURL string) *http.Client {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= proxyClientSweepInterval {
+		s.sweepIdleLocked(now)
+	}
+
+	if elem, ok := s.proxyClients[proxyURL]; ok {
+		entry := elem.Value.(*proxyClientEntry)
+		entry.lastUsed = now
+		s.lru.MoveToFront(elem)
+		return entry.client
+	}
+
 	parsedURL, err := url.Parse(proxyURL)
 	if err != nil {
 		return s.defaultClient
 	}
 
-	responseHeaderTimeout := time.Duration(s.cfg.Gateway.ResponseHeaderTimeout) * time.Second
+	transport := s.newTransport(http.ProxyURL(parsedURL))
+	entry := &proxyClientEntry{
+		proxyURL:  proxyURL,
+		client:    &http.Client{Transport: transport},
+		transport: transport,
+		lastUsed:  now,
+	}
+	s.proxyClients[proxyURL] = s.lru.PushFront(entry)
+
+	// 超出容量时淘汰最久未使用的代理
+	for s.lru.Len() > s.maxProxyTransports() {
+		s.removeLocked(s.lru.Back())
+	}
+
+	return entry.client
+}
+
+// sweepIdleLocked 关闭长时间未使用的代理连接池
+func (s *httpUpstreamService) sweepIdleLocked(now time.Time) {
+	s.lastSweep = now
+	idleTTL := s.proxyTransportIdleTTL()
+	for elem := s.lru.Back(); elem != nil; {
+		entry := elem.Value.(*proxyClientEntry)
+		if now.Sub(entry.lastUsed) < idleTTL {
+			// LRU 尾部之前的条目都更新，无需继续
+			break
+		}
+		prev := elem.Prev()
+		s.removeLocked(elem)
+		elem = prev
+	}
+}
+
+func (s *httpUpstreamService) removeLocked(elem *list.Element) {
+	entry := s.lru.Remove(elem).(*proxyClientEntry)
+	delete(s.proxyClients, entry.proxyURL)
+	// 进行中的请求不受影响，连接归还后随 IdleConnTimeout 关闭
+	entry.transport.CloseIdleConnections()
+}
+
+func (s *httpUpstreamService) newTransport(proxy func(*http.Request) (*url.URL, error)) *http.Transport {
+	gw := s.cfg.Gateway
+
+	responseHeaderTimeout := time.Duration(gw.ResponseHeaderTimeout) * time.Second
 	if responseHeaderTimeout == 0 {
 		responseHeaderTimeout = 300 * time.Second
 	}
+	maxIdleConns := gw.MaxIdleConns
+	if maxIdleConns <= 0 {
+		maxIdleConns = defaultMaxIdleConns
+	}
+	maxIdleConnsPerHost := gw.MaxIdleConnsPerHost
+	if maxIdleConnsPerHost <= 0 {
+		maxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+	idleConnTimeout := time.Duration(gw.IdleConnTimeout) * time.Second
+	if idleConnTimeout <= 0 {
+		idleConnTimeout = defaultIdleConnTimeout
+	}
 
-	transport := &http.Transport{
-		Proxy:                 http.ProxyURL(parsedURL),
-		MaxIdleConns:          100,
-		MaxIdleConnsPerHost:   10,
-		IdleConnTimeout:       90 * time.Second,
+	return &http.Transport{
+		Proxy:                 proxy,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		MaxConnsPerHost:       gw.MaxConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
 		ResponseHeaderTimeout: responseHeaderTimeout,
 	}
+}
 
-	return &http.Client{Transport: transport}
+func (s *httpUpstreamService) maxProxyTransports() int {
+	if s.cfg.Gateway.MaxProxyTransports > 0 {
+		return s.cfg.Gateway.MaxProxyTransports
+	}
+	return defaultMaxProxyTransports
+}
+
+func (s *httpUpstreamService) proxyTransportIdleTTL() time.Duration {
+	if s.cfg.Gateway.ProxyTransportIdleTTL > 0 {
+		return time.Duration(s.cfg.Gateway.ProxyTransportIdleTTL) * time.Second
+	}
+	return defaultProxyTransportIdleTTL
 }
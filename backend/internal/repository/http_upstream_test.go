@@ -110,6 +110,72 @@ func (s *HTTPUpstreamSuite) TestDo_EmptyProxy_UsesDirect() {
 	require.Equal(s.T(), "direct-empty", string(b))
 }
 
+func (s *HTTPUpstreamSuite) TestCreateProxyClient_ReusesTransportPerProxy() {
+	svc := NewHTTPUpstream(s.cfg).(*httpUpstreamService)
+
+	c1 := svc.createProxyClient("http://127.0.0.1:8001")
+	c2 := svc.createProxyClient("http://127.0.0.1:8001")
+	c3 := svc.createProxyClient("http://127.0.0.1:8002")
+
+	require.Same(s.T(), c1, c2, "same proxy should reuse client")
+	require.NotSame(s.T(), c1, c3, "different proxies should not share client")
+	require.Equal(s.T(), 2, svc.lru.Len())
+}
+
+func (s *HTTPUpstreamSuite) TestCreateProxyClient_EvictsLeastRecentlyUsed() {
+	s.cfg.Gateway = config.GatewayConfig{MaxProxyTransports: 2}
+	svc := NewHTTPUpstream(s.cfg).(*httpUpstreamService)
+
+	c1 := svc.createProxyClient("http://127.0.0.1:8001")
+	svc.createProxyClient("http://127.0.0.1:8002")
+	svc.createProxyClient("http://127.0.0.1:8001") // 8001 变为最近使用
+	svc.createProxyClient("http://127.0.0.1:8003") // 淘汰 8002
+
+	require.Equal(s.T(), 2, svc.lru.Len())
+	require.Contains(s.T(), svc.proxyClients, "http://127.0.0.1:8001")
+	require.NotContains(s.T(), svc.proxyClients, "http://127.0.0.1:8002")
+	require.Same(s.T(), c1, svc.createProxyClient("http://127.0.0.1:8001"))
+}
+
+func (s *HTTPUpstreamSuite) TestInvalidateProxy() {
+	svc := NewHTTPUpstream(s.cfg).(*httpUpstreamService)
+
+	c1 := svc.createProxyClient("http://127.0.0.1:8001")
+	svc.InvalidateProxy("http://127.0.0.1:8001")
+	svc.InvalidateProxy("http://127.0.0.1:9999") // 不存在时无副作用
+
+	require.Zero(s.T(), svc.lru.Len())
+	require.NotSame(s.T(), c1, svc.createProxyClient("http://127.0.0.1:8001"), "invalidated proxy should get a new client")
+}
+
+func (s *HTTPUpstreamSuite) TestCreateProxyClient_SweepsIdleTransports() {
+	s.cfg.Gateway = config.GatewayConfig{ProxyTransportIdleTTL: 60}
+	svc := NewHTTPUpstream(s.cfg).(*httpUpstreamService)
+
+	svc.createProxyClient("http://127.0.0.1:8001")
+	svc.createProxyClient("http://127.0.0.1:8002")
+
+	// 模拟 8001 长时间未使用且已到清理周期
+	entry := svc.proxyClients["http://127.0.0.1:8001"].Value.(*proxyClientEntry)
+	entry.lastUsed = time.Now().Add(-2 * time.Minute)
+	svc.lastSweep = time.Now().Add(-2 * proxyClientSweepInterval)
+
+	svc.createProxyClient("http://127.0.0.1:8002")
+	require.NotContains(s.T(), svc.proxyClients, "http://127.0.0.1:8001")
+	require.Contains(s.T(), svc.proxyClients, "http://127.0.0.1:8002")
+}
+
+func (s *HTTPUpstreamSuite) TestTransportPoolConfig() {
+	s.cfg.Gateway = config.GatewayConfig{MaxIdleConns: 50, MaxIdleConnsPerHost: 20, MaxConnsPerHost: 30, IdleConnTimeout: 15}
+	svc := NewHTTPUpstream(s.cfg).(*httpUpstreamService)
+
+	transport := svc.createProxyClient("http://127.0.0.1:8001").Transport.(*http.Transport)
+	require.Equal(s.T(), 50, transport.MaxIdleConns)
+	require.Equal(s.T(), 20, transport.MaxIdleConnsPerHost)
+	require.Equal(s.T(), 30, transport.MaxConnsPerHost)
+	require.Equal(s.T(), 15*time.Second, transport.IdleConnTimeout)
+}
+
 func TestHTTPUpstreamSuite(t *testing.T) {
 	suite.Run(t, new(HTTPUpstreamSuite))
 }
@@ -197,7 +197,7 @@ func testTx(t *testing.T) *gorm.DB {
 	return tx
 }
 
-func testRedis(t *testing.T) *redisclient.Client {
+func testRedis(t testing.TB) *redisclient.Client {
 	t.Helper()
 
 	prefix := fmt.Sprintf(
@@ -294,7 +294,8 @@ func (h prefixHook) prefixCmd(cmd redisclient.Cmder) {
 
 	switch strings.ToLower(cmd.Name()) {
 	case "get", "set", "setnx", "setex", "psetex", "incr", "decr", "incrby", "expire", "pexpire", "ttl", "pttl",
-		"hgetall", "hget", "hset", "hdel", "hincrbyfloat", "exists":
+		"hgetall", "hget", "hset", "hdel", "hincrbyfloat", "exists",
+		"zadd", "zrem", "zscore", "zcard", "zcount", "zrange", "zremrangebyscore":
 		prefixOne(1)
 	case "del", "unlink":
 		for i := 1; i < len(args); i++ {
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+const liveEventChannel = "live_events"
+
+type liveEventPubSub struct {
+	rdb *redis.Client
+}
+
+func NewLiveEventPubSub(rdb *redis.Client) service.LiveEventPubSub {
+	return &liveEventPubSub{rdb: rdb}
+}
+
+func (p *liveEventPubSub) Publish(ctx context.Context, event *service.LiveEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.rdb.Publish(ctx, liveEventChannel, data).Err()
+}
+
+func (p *liveEventPubSub) Subscribe(ctx context.Context) (<-chan *service.LiveEvent, error) {
+	ps := p.rdb.Subscribe(ctx, liveEventChannel)
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+
+	out := make(chan *service.LiveEvent, 64)
+	go func() {
+		defer close(out)
+		defer func() { _ = ps.Close() }()
+
+		msgs := ps.Channel()
+		for {
+			select {
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				var event service.LiveEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					continue
+				}
+				select {
+				case out <- &event:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
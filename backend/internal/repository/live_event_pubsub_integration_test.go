@@ -0,0 +1,54 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type LiveEventPubSubSuite struct {
+	IntegrationRedisSuite
+	pubsub service.LiveEventPubSub
+}
+
+func (s *LiveEventPubSubSuite) SetupTest() {
+	s.IntegrationRedisSuite.SetupTest()
+	s.pubsub = NewLiveEventPubSub(s.rdb)
+}
+
+func (s *LiveEventPubSubSuite) TestPublishAndSubscribe() {
+	ctx, cancel := context.WithCancel(s.ctx)
+	events, err := s.pubsub.Subscribe(ctx)
+	require.NoError(s.T(), err, "Subscribe")
+
+	require.NoError(s.T(), s.pubsub.Publish(s.ctx, &service.LiveEvent{
+		Type:       service.LiveEventRequestStarted,
+		InstanceID: "replica-a",
+		Model:      "claude-3",
+	}), "Publish")
+
+	select {
+	case event := <-events:
+		require.Equal(s.T(), service.LiveEventRequestStarted, event.Type)
+		require.Equal(s.T(), "replica-a", event.InstanceID)
+		require.Equal(s.T(), "claude-3", event.Model)
+	case <-time.After(5 * time.Second):
+		s.T().Fatal("timed out waiting for live event")
+	}
+
+	cancel()
+	require.Eventually(s.T(), func() bool {
+		_, ok := <-events
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond, "channel should close after ctx cancel")
+}
+
+func TestLiveEventPubSubSuite(t *testing.T) {
+	suite.Run(t, new(LiveEventPubSubSuite))
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+const notificationDedupKeyPrefix = "notify_sent:"
+
+type notificationDedupCache struct {
+	rdb *redis.Client
+}
+
+func NewNotificationDedupCache(rdb *redis.Client) service.NotificationDedupCache {
+	return &notificationDedupCache{rdb: rdb}
+}
+
+func (c *notificationDedupCache) TryMark(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return c.rdb.SetNX(ctx, notificationDedupKeyPrefix+key, 1, ttl).Result()
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/oidc"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/imroc/req/v3"
+)
+
+// NewOIDCClient creates a new OIDC identity provider client
+func NewOIDCClient() service.OIDCClient {
+	return &oidcClient{client: req.C().SetTimeout(15 * time.Second)}
+}
+
+type oidcClient struct {
+	client *req.Client
+}
+
+func (c *oidcClient) Discover(ctx context.Context, issuer string) (*oidc.ProviderMetadata, error) {
+	var metadata oidc.ProviderMetadata
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetSuccessResult(&metadata).
+		Get(oidc.DiscoveryURL(issuer))
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if !resp.IsSuccessState() {
+		return nil, fmt.Errorf("discovery failed: status %d", resp.StatusCode)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document is missing required endpoints")
+	}
+	return &metadata, nil
+}
+
+func (c *oidcClient) FetchKeySet(ctx context.Context, jwksURI string) (*oidc.KeySet, error) {
+	var keys oidc.KeySet
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetSuccessResult(&keys).
+		Get(jwksURI)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if !resp.IsSuccessState() {
+		return nil, fmt.Errorf("fetch jwks failed: status %d", resp.StatusCode)
+	}
+	return &keys, nil
+}
+
+func (c *oidcClient) ExchangeCode(ctx context.Context, tokenEndpoint, clientID, clientSecret, code, codeVerifier, redirectURI string) (*oidc.TokenResponse, error) {
+	formData := url.Values{}
+	formData.Set("grant_type", "authorization_code")
+	formData.Set("code", code)
+	formData.Set("redirect_uri", redirectURI)
+	formData.Set("code_verifier", codeVerifier)
+
+	r := c.client.R().SetContext(ctx)
+	if clientSecret != "" {
+		// client_secret_basic（RFC 6749 2.3.1 要求先进行 form 编码）
+		r.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	} else {
+		formData.Set("client_id", clientID)
+	}
+
+	var tokenResp oidc.TokenResponse
+	resp, err := r.
+		SetFormDataFromValues(formData).
+		SetSuccessResult(&tokenResp).
+		Post(tokenEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if !resp.IsSuccessState() {
+		return nil, fmt.Errorf("token exchange failed: status %d, body: %s", resp.StatusCode, resp.String())
+	}
+	return &tokenResp, nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/oidc"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOIDCServer(t *testing.T, received chan<- *http.Request) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case oidc.DiscoveryPath:
+			_ = json.NewEncoder(w).Encode(oidc.ProviderMetadata{
+				Issuer:                srv.URL,
+				AuthorizationEndpoint: srv.URL + "/authorize",
+				TokenEndpoint:         srv.URL + "/token",
+				JWKSURI:               srv.URL + "/jwks",
+			})
+		case "/jwks":
+			_, _ = w.Write([]byte(`{"keys":[{"kty":"RSA","kid":"k1","n":"AQAB","e":"AQAB"}]}`))
+		case "/token":
+			_ = r.ParseForm()
+			received <- r
+			_, _ = w.Write([]byte(`{"access_token":"at","token_type":"Bearer","id_token":"idt"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOIDCClient_DiscoverAndFetchKeys(t *testing.T) {
+	srv := newTestOIDCServer(t, nil)
+	client := NewOIDCClient()
+
+	metadata, err := client.Discover(context.Background(), srv.URL+"/")
+	require.NoError(t, err)
+	require.Equal(t, srv.URL+"/token", metadata.TokenEndpoint)
+
+	keys, err := client.FetchKeySet(context.Background(), metadata.JWKSURI)
+	require.NoError(t, err)
+	require.Len(t, keys.Keys, 1)
+	require.Equal(t, "k1", keys.Keys[0].Kid)
+
+	_, err = client.FetchKeySet(context.Background(), srv.URL+"/missing")
+	require.Error(t, err)
+}
+
+func TestOIDCClient_ExchangeCode(t *testing.T) {
+	received := make(chan *http.Request, 2)
+	srv := newTestOIDCServer(t, received)
+	client := NewOIDCClient()
+
+	// 机密客户端使用 client_secret_basic
+	resp, err := client.ExchangeCode(context.Background(), srv.URL+"/token", "client id", "s&cret", "code-1", "verifier-1", "https://app.example.com/cb")
+	require.NoError(t, err)
+	require.Equal(t, "idt", resp.IDToken)
+
+	r := <-received
+	user, pass, ok := r.BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, url.QueryEscape("client id"), user)
+	require.Equal(t, url.QueryEscape("s&cret"), pass)
+	require.Equal(t, "authorization_code", r.PostForm.Get("grant_type"))
+	require.Equal(t, "code-1", r.PostForm.Get("code"))
+	require.Equal(t, "verifier-1", r.PostForm.Get("code_verifier"))
+	require.Equal(t, "https://app.example.com/cb", r.PostForm.Get("redirect_uri"))
+	require.Empty(t, r.PostForm.Get("client_id"))
+
+	// 公共客户端在表单中携带 client_id
+	_, err = client.ExchangeCode(context.Background(), srv.URL+"/token", "public", "", "code-2", "verifier-2", "https://app.example.com/cb")
+	require.NoError(t, err)
+	r = <-received
+	_, _, ok = r.BasicAuth()
+	require.False(t, ok)
+	require.Equal(t, "public", r.PostForm.Get("client_id"))
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const oidcStateKeyPrefix = "oidc_state:"
+
+type oidcStateCache struct {
+	rdb *redis.Client
+}
+
+func NewOIDCStateCache(rdb *redis.Client) service.OIDCStateCache {
+	return &oidcStateCache{rdb: rdb}
+}
+
+func (c *oidcStateCache) SetOIDCState(ctx context.Context, state string, data *service.OIDCAuthState, ttl time.Duration) error {
+	val, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return c.rdb.Set(ctx, oidcStateKeyPrefix+state, val, ttl).Err()
+}
+
+func (c *oidcStateCache) TakeOIDCState(ctx context.Context, state string) (*service.OIDCAuthState, error) {
+	key := oidcStateKeyPrefix + state
+	pipe := c.rdb.TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var data service.OIDCAuthState
+	if err := json.Unmarshal([]byte(get.Val()), &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
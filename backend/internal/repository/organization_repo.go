@@ -0,0 +1,449 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"gorm.io/gorm"
+)
+
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) service.OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+func (r *organizationRepository) Create(ctx context.Context, org *service.Organization, owner *service.OrganizationMember) error {
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		m := organizationModelFromService(org)
+		if err := db.Create(m).Error; err != nil {
+			return err
+		}
+		org.ID = m.ID
+		org.CreatedAt = m.CreatedAt
+		org.UpdatedAt = m.UpdatedAt
+
+		owner.OrganizationID = m.ID
+		mm := organizationMemberModelFromService(owner)
+		if err := db.Create(mm).Error; err != nil {
+			return err
+		}
+		owner.ID = mm.ID
+		owner.CreatedAt = mm.CreatedAt
+		owner.UpdatedAt = mm.UpdatedAt
+		return nil
+	})
+}
+
+func (r *organizationRepository) GetByID(ctx context.Context, id int64) (*service.Organization, error) {
+	var m organizationModel
+	err := r.db.WithContext(ctx).First(&m, id).Error
+	if err != nil {
+		return nil, translatePersistenceError(err, service.ErrOrganizationNotFound, nil)
+	}
+	return organizationModelToService(&m), nil
+}
+
+// Update 只更新名称与状态，余额只能通过余额流水变动
+func (r *organizationRepository) Update(ctx context.Context, org *service.Organization) error {
+	org.UpdatedAt = time.Now()
+	return r.db.WithContext(ctx).Model(&organizationModel{}).Where("id = ?", org.ID).
+		Updates(map[string]any{
+			"name":       org.Name,
+			"status":     org.Status,
+			"updated_at": org.UpdatedAt,
+		}).Error
+}
+
+func (r *organizationRepository) List(ctx context.Context, params pagination.PaginationParams, search string) ([]service.Organization, *pagination.PaginationResult, error) {
+	var orgs []organizationModel
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&organizationModel{})
+	if search != "" {
+		db = db.Where("name ILIKE ?", "%"+search+"%")
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := db.Offset(params.Offset()).Limit(params.Limit()).Order("id DESC").Find(&orgs).Error; err != nil {
+		return nil, nil, err
+	}
+
+	out := make([]service.Organization, 0, len(orgs))
+	for i := range orgs {
+		out = append(out, *organizationModelToService(&orgs[i]))
+	}
+	return out, paginationResultFromTotal(total, params), nil
+}
+
+func (r *organizationRepository) GetMember(ctx context.Context, orgID, userID int64) (*service.OrganizationMember, error) {
+	var m organizationMemberModel
+	err := r.db.WithContext(ctx).Preload("User").
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		First(&m).Error
+	if err != nil {
+		return nil, translatePersistenceError(err, service.ErrOrgMemberNotFound, nil)
+	}
+	return organizationMemberModelToService(&m), nil
+}
+
+func (r *organizationRepository) ListMembers(ctx context.Context, orgID int64) ([]service.OrganizationMember, error) {
+	var members []organizationMemberModel
+	err := r.db.WithContext(ctx).Preload("User").
+		Where("organization_id = ?", orgID).
+		Order("id ASC").
+		Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return organizationMemberModelsToService(members), nil
+}
+
+func (r *organizationRepository) ListMembershipsByUserID(ctx context.Context, userID int64) ([]service.OrganizationMember, error) {
+	var members []organizationMemberModel
+	err := r.db.WithContext(ctx).Preload("Organization").
+		Where("user_id = ?", userID).
+		Order("id ASC").
+		Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return organizationMemberModelsToService(members), nil
+}
+
+// UpdateMember 只更新角色与花费上限，当月花费由扣费事务累计
+func (r *organizationRepository) UpdateMember(ctx context.Context, member *service.OrganizationMember) error {
+	member.UpdatedAt = time.Now()
+	result := r.db.WithContext(ctx).Model(&organizationMemberModel{}).
+		Where("organization_id = ? AND user_id = ?", member.OrganizationID, member.UserID).
+		Updates(map[string]any{
+			"role":              member.Role,
+			"monthly_limit_usd": member.MonthlyLimitUSD,
+			"updated_at":        member.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return service.ErrOrgMemberNotFound
+	}
+	return nil
+}
+
+func (r *organizationRepository) RemoveMember(ctx context.Context, orgID, userID int64) error {
+	result := r.db.WithContext(ctx).
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		Delete(&organizationMemberModel{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return service.ErrOrgMemberNotFound
+	}
+	return nil
+}
+
+func (r *organizationRepository) CreateInvitation(ctx context.Context, inv *service.OrganizationInvitation) error {
+	m := organizationInvitationModelFromService(inv)
+	if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
+		return err
+	}
+	inv.ID = m.ID
+	inv.CreatedAt = m.CreatedAt
+	return nil
+}
+
+func (r *organizationRepository) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*service.OrganizationInvitation, error) {
+	var m organizationInvitationModel
+	err := r.db.WithContext(ctx).Preload("Organization").Where("token_hash = ?", tokenHash).First(&m).Error
+	if err != nil {
+		return nil, translatePersistenceError(err, service.ErrOrgInvitationNotFound, nil)
+	}
+	return organizationInvitationModelToService(&m), nil
+}
+
+func (r *organizationRepository) ListPendingInvitations(ctx context.Context, orgID int64, now time.Time) ([]service.OrganizationInvitation, error) {
+	var invs []organizationInvitationModel
+	err := r.db.WithContext(ctx).
+		Where("organization_id = ? AND accepted_at IS NULL AND expires_at > ?", orgID, now).
+		Order("id DESC").
+		Find(&invs).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]service.OrganizationInvitation, 0, len(invs))
+	for i := range invs {
+		out = append(out, *organizationInvitationModelToService(&invs[i]))
+	}
+	return out, nil
+}
+
+func (r *organizationRepository) DeleteInvitation(ctx context.Context, orgID, id int64) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND organization_id = ? AND accepted_at IS NULL", id, orgID).
+		Delete(&organizationInvitationModel{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return service.ErrOrgInvitationNotFound
+	}
+	return nil
+}
+
+func (r *organizationRepository) AcceptInvitation(ctx context.Context, inv *service.OrganizationInvitation, member *service.OrganizationMember) error {
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		now := time.Now()
+		// 条件更新保证同一邀请只能被接受一次
+		result := db.Model(&organizationInvitationModel{}).
+			Where("id = ? AND accepted_at IS NULL", inv.ID).
+			Update("accepted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return service.ErrOrgInvitationNotFound
+		}
+		inv.AcceptedAt = &now
+
+		m := organizationMemberModelFromService(member)
+		if err := db.Create(m).Error; err != nil {
+			return err
+		}
+		member.ID = m.ID
+		member.CreatedAt = m.CreatedAt
+		member.UpdatedAt = m.UpdatedAt
+		return nil
+	})
+	return translatePersistenceError(err, nil, service.ErrOrgMemberExists)
+}
+
+func (r *organizationRepository) MemberUsage(ctx context.Context, orgID int64, startTime, endTime time.Time) ([]service.OrganizationMemberUsage, error) {
+	var rows []struct {
+		UserID       int64
+		Requests     int64
+		InputTokens  int64
+		OutputTokens int64
+		TotalCost    float64
+		ActualCost   float64
+	}
+	err := r.db.WithContext(ctx).Model(&usageLogModel{}).
+		Select(`
+			user_id,
+			COUNT(*) as requests,
+			COALESCE(SUM(input_tokens), 0) as input_tokens,
+			COALESCE(SUM(output_tokens), 0) as output_tokens,
+			COALESCE(SUM(total_cost), 0) as total_cost,
+			COALESCE(SUM(actual_cost), 0) as actual_cost
+		`).
+		Where("organization_id = ? AND created_at >= ? AND created_at < ?", orgID, startTime, endTime).
+		Group("user_id").
+		Order("actual_cost DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]service.OrganizationMemberUsage, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, service.OrganizationMemberUsage{
+			UserID:       row.UserID,
+			Requests:     row.Requests,
+			InputTokens:  row.InputTokens,
+			OutputTokens: row.OutputTokens,
+			TotalCost:    row.TotalCost,
+			ActualCost:   row.ActualCost,
+		})
+	}
+	return out, nil
+}
+
+// addOrgMemberUsage 在扣费事务内累计成员当月花费，跨月时从本次花费重新计数
+func addOrgMemberUsage(db *gorm.DB, orgID, userID int64, amount float64, at time.Time) error {
+	month := at.UTC().Format("2006-01")
+	return db.Exec(
+		`UPDATE organization_members
+		SET monthly_usage_usd = CASE WHEN usage_month = ? THEN monthly_usage_usd + ? ELSE ? END,
+			usage_month = ?, updated_at = ?
+		WHERE organization_id = ? AND user_id = ?`,
+		month, amount, amount, month, time.Now(), orgID, userID,
+	).Error
+}
+
+// refundOrgMemberUsage 退款时回退成员花费，仅当被退款的使用记录属于成员当前累计的月份
+func refundOrgMemberUsage(db *gorm.DB, orgID, userID int64, amount float64, usageLogID int64) error {
+	var usage usageLogModel
+	if err := db.Select("id", "created_at").First(&usage, usageLogID).Error; err != nil {
+		return translatePersistenceError(err, service.ErrUsageLogNotFound, nil)
+	}
+	return db.Exec(
+		`UPDATE organization_members
+		SET monthly_usage_usd = GREATEST(monthly_usage_usd - ?, 0), updated_at = ?
+		WHERE organization_id = ? AND user_id = ? AND usage_month = ?`,
+		amount, time.Now(), orgID, userID, usage.CreatedAt.UTC().Format("2006-01"),
+	).Error
+}
+
+type organizationModel struct {
+	ID      int64   `gorm:"primaryKey"`
+	Name    string  `gorm:"size:100;not null"`
+	Balance float64 `gorm:"type:decimal(20,8);default:0;not null"`
+	Status  string  `gorm:"size:20;default:active;not null"`
+	OwnerID int64   `gorm:"index;not null"`
+
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+func (organizationModel) TableName() string { return "organizations" }
+
+type organizationMemberModel struct {
+	ID             int64  `gorm:"primaryKey"`
+	OrganizationID int64  `gorm:"not null;uniqueIndex:idx_organization_members_org_user"`
+	UserID         int64  `gorm:"not null;index;uniqueIndex:idx_organization_members_org_user"`
+	Role           string `gorm:"size:20;default:member;not null"`
+
+	MonthlyLimitUSD *float64 `gorm:"type:decimal(20,8)"`
+	MonthlyUsageUSD float64  `gorm:"type:decimal(20,10);default:0;not null"`
+	UsageMonth      string   `gorm:"size:7;default:'';not null"`
+
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+
+	User         *userModel         `gorm:"foreignKey:UserID"`
+	Organization *organizationModel `gorm:"foreignKey:OrganizationID"`
+}
+
+func (organizationMemberModel) TableName() string { return "organization_members" }
+
+type organizationInvitationModel struct {
+	ID             int64      `gorm:"primaryKey"`
+	OrganizationID int64      `gorm:"index;not null"`
+	Email          string     `gorm:"size:255;not null"`
+	Role           string     `gorm:"size:20;default:member;not null"`
+	TokenHash      string     `gorm:"size:64;uniqueIndex;not null"`
+	InvitedBy      int64      `gorm:"not null"`
+	ExpiresAt      time.Time  `gorm:"not null"`
+	AcceptedAt     *time.Time `gorm:"index"`
+	CreatedAt      time.Time  `gorm:"not null"`
+
+	Organization *organizationModel `gorm:"foreignKey:OrganizationID"`
+}
+
+func (organizationInvitationModel) TableName() string { return "organization_invitations" }
+
+func organizationModelToService(m *organizationModel) *service.Organization {
+	if m == nil {
+		return nil
+	}
+	return &service.Organization{
+		ID:        m.ID,
+		Name:      m.Name,
+		Balance:   m.Balance,
+		Status:    m.Status,
+		OwnerID:   m.OwnerID,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func organizationModelFromService(o *service.Organization) *organizationModel {
+	if o == nil {
+		return nil
+	}
+	return &organizationModel{
+		ID:        o.ID,
+		Name:      o.Name,
+		Balance:   o.Balance,
+		Status:    o.Status,
+		OwnerID:   o.OwnerID,
+		CreatedAt: o.CreatedAt,
+		UpdatedAt: o.UpdatedAt,
+	}
+}
+
+func organizationMemberModelToService(m *organizationMemberModel) *service.OrganizationMember {
+	if m == nil {
+		return nil
+	}
+	return &service.OrganizationMember{
+		ID:              m.ID,
+		OrganizationID:  m.OrganizationID,
+		UserID:          m.UserID,
+		Role:            m.Role,
+		MonthlyLimitUSD: m.MonthlyLimitUSD,
+		MonthlyUsageUSD: m.MonthlyUsageUSD,
+		UsageMonth:      m.UsageMonth,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+		User:            userModelToService(m.User),
+		Organization:    organizationModelToService(m.Organization),
+	}
+}
+
+func organizationMemberModelsToService(models []organizationMemberModel) []service.OrganizationMember {
+	out := make([]service.OrganizationMember, 0, len(models))
+	for i := range models {
+		out = append(out, *organizationMemberModelToService(&models[i]))
+	}
+	return out
+}
+
+func organizationMemberModelFromService(m *service.OrganizationMember) *organizationMemberModel {
+	if m == nil {
+		return nil
+	}
+	return &organizationMemberModel{
+		ID:              m.ID,
+		OrganizationID:  m.OrganizationID,
+		UserID:          m.UserID,
+		Role:            m.Role,
+		MonthlyLimitUSD: m.MonthlyLimitUSD,
+		MonthlyUsageUSD: m.MonthlyUsageUSD,
+		UsageMonth:      m.UsageMonth,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+	}
+}
+
+func organizationInvitationModelToService(m *organizationInvitationModel) *service.OrganizationInvitation {
+	if m == nil {
+		return nil
+	}
+	return &service.OrganizationInvitation{
+		ID:             m.ID,
+		OrganizationID: m.OrganizationID,
+		Email:          m.Email,
+		Role:           m.Role,
+		TokenHash:      m.TokenHash,
+		InvitedBy:      m.InvitedBy,
+		ExpiresAt:      m.ExpiresAt,
+		AcceptedAt:     m.AcceptedAt,
+		CreatedAt:      m.CreatedAt,
+		Organization:   organizationModelToService(m.Organization),
+	}
+}
+
+func organizationInvitationModelFromService(i *service.OrganizationInvitation) *organizationInvitationModel {
+	if i == nil {
+		return nil
+	}
+	return &organizationInvitationModel{
+		ID:             i.ID,
+		OrganizationID: i.OrganizationID,
+		Email:          i.Email,
+		Role:           i.Role,
+		TokenHash:      i.TokenHash,
+		InvitedBy:      i.InvitedBy,
+		ExpiresAt:      i.ExpiresAt,
+		AcceptedAt:     i.AcceptedAt,
+		CreatedAt:      i.CreatedAt,
+	}
+}
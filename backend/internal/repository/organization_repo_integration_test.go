@@ -0,0 +1,207 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type OrganizationRepoSuite struct {
+	suite.Suite
+	ctx    context.Context
+	db     *gorm.DB
+	repo   *organizationRepository
+	txRepo service.BalanceTransactionRepository
+}
+
+func (s *OrganizationRepoSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.db = testTx(s.T())
+	s.repo = NewOrganizationRepository(s.db).(*organizationRepository)
+	s.txRepo = NewBalanceTransactionRepository(s.db)
+}
+
+func TestOrganizationRepoSuite(t *testing.T) {
+	suite.Run(t, new(OrganizationRepoSuite))
+}
+
+func (s *OrganizationRepoSuite) createOrg(owner *userModel) *service.Organization {
+	org := &service.Organization{Name: "team", Status: service.StatusActive, OwnerID: owner.ID}
+	s.Require().NoError(s.repo.Create(s.ctx, org, &service.OrganizationMember{UserID: owner.ID, Role: service.OrgRoleOwner}))
+	return org
+}
+
+func (s *OrganizationRepoSuite) TestCreateAndMembers() {
+	owner := mustCreateUser(s.T(), s.db, &userModel{Email: "org-owner@test.com"})
+	org := s.createOrg(owner)
+	s.Require().NotZero(org.ID)
+
+	member, err := s.repo.GetMember(s.ctx, org.ID, owner.ID)
+	s.Require().NoError(err, "GetMember")
+	s.Require().Equal(service.OrgRoleOwner, member.Role)
+
+	memberships, err := s.repo.ListMembershipsByUserID(s.ctx, owner.ID)
+	s.Require().NoError(err, "ListMembershipsByUserID")
+	s.Require().Len(memberships, 1)
+	s.Require().NotNil(memberships[0].Organization)
+	s.Require().Equal("team", memberships[0].Organization.Name)
+
+	_, err = s.repo.GetMember(s.ctx, org.ID, owner.ID+1000)
+	s.Require().ErrorIs(err, service.ErrOrgMemberNotFound)
+}
+
+func (s *OrganizationRepoSuite) TestAcceptInvitation() {
+	owner := mustCreateUser(s.T(), s.db, &userModel{Email: "inv-owner@test.com"})
+	invitee := mustCreateUser(s.T(), s.db, &userModel{Email: "inv-member@test.com"})
+	org := s.createOrg(owner)
+
+	inv := &service.OrganizationInvitation{
+		OrganizationID: org.ID,
+		Email:          invitee.Email,
+		Role:           service.OrgRoleMember,
+		TokenHash:      "hash-accept",
+		InvitedBy:      owner.ID,
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}
+	s.Require().NoError(s.repo.CreateInvitation(s.ctx, inv))
+
+	pending, err := s.repo.ListPendingInvitations(s.ctx, org.ID, time.Now())
+	s.Require().NoError(err)
+	s.Require().Len(pending, 1)
+
+	got, err := s.repo.GetInvitationByTokenHash(s.ctx, "hash-accept")
+	s.Require().NoError(err)
+	member := &service.OrganizationMember{OrganizationID: org.ID, UserID: invitee.ID, Role: got.Role}
+	s.Require().NoError(s.repo.AcceptInvitation(s.ctx, got, member))
+
+	pending, err = s.repo.ListPendingInvitations(s.ctx, org.ID, time.Now())
+	s.Require().NoError(err)
+	s.Require().Empty(pending, "accepted invitation should no longer be pending")
+
+	members, err := s.repo.ListMembers(s.ctx, org.ID)
+	s.Require().NoError(err)
+	s.Require().Len(members, 2)
+}
+
+func (s *OrganizationRepoSuite) TestUsageDebitsOrganizationBalance() {
+	owner := mustCreateUser(s.T(), s.db, &userModel{Email: "debit-owner@test.com", Balance: 7})
+	org := s.createOrg(owner)
+	apiKey := mustCreateApiKey(s.T(), s.db, &apiKeyModel{UserID: owner.ID, Key: "sk-org-debit", Name: "k", OrganizationID: &org.ID})
+	account := mustCreateAccount(s.T(), s.db, &accountModel{Name: "acc-org-debit"})
+
+	// 管理员充值到组织余额，个人余额不变
+	topUp := &service.BalanceTransaction{UserID: owner.ID, OrganizationID: &org.ID, Type: service.BalanceTxTypeAdminAdjustment, Amount: 10}
+	s.Require().NoError(s.txRepo.Apply(s.ctx, topUp, false))
+	s.Require().Equal(10.0, topUp.BalanceAfter)
+
+	now := time.Now()
+	usage := &service.UsageLog{
+		UserID:         owner.ID,
+		ApiKeyID:       apiKey.ID,
+		AccountID:      account.ID,
+		OrganizationID: &org.ID,
+		Model:          "claude-3",
+		InputTokens:    10,
+		OutputTokens:   20,
+		TotalCost:      2.5,
+		ActualCost:     2.5,
+		CreatedAt:      now,
+	}
+	debit := &service.BalanceTransaction{UserID: owner.ID, OrganizationID: &org.ID, Type: service.BalanceTxTypeUsage, Amount: -2.5}
+	s.Require().NoError(s.txRepo.CreateUsageWithDebit(s.ctx, usage, debit))
+
+	got, err := s.repo.GetByID(s.ctx, org.ID)
+	s.Require().NoError(err)
+	s.Require().InDelta(7.5, got.Balance, 1e-9)
+
+	var user userModel
+	s.Require().NoError(s.db.First(&user, owner.ID).Error)
+	s.Require().InDelta(7.0, user.Balance, 1e-9, "personal balance must not change")
+
+	member, err := s.repo.GetMember(s.ctx, org.ID, owner.ID)
+	s.Require().NoError(err)
+	s.Require().InDelta(2.5, member.CurrentMonthUsage(now), 1e-9)
+
+	usageByMember, err := s.repo.MemberUsage(s.ctx, org.ID, now.Add(-time.Hour), now.Add(time.Hour))
+	s.Require().NoError(err)
+	s.Require().Len(usageByMember, 1)
+	s.Require().Equal(int64(1), usageByMember[0].Requests)
+
+	// 组织流水与个人流水分开
+	orgTxs, _, err := s.txRepo.List(s.ctx, pagination.PaginationParams{Page: 1, PageSize: 10}, service.BalanceTransactionFilters{OrganizationID: org.ID})
+	s.Require().NoError(err)
+	s.Require().Len(orgTxs, 2)
+	personalTxs, _, err := s.txRepo.List(s.ctx, pagination.PaginationParams{Page: 1, PageSize: 10}, service.BalanceTransactionFilters{UserID: owner.ID})
+	s.Require().NoError(err)
+	s.Require().Empty(personalTxs)
+
+	// 退款回到组织余额并回退成员当月花费
+	refund := &service.BalanceTransaction{UserID: owner.ID, OrganizationID: &org.ID, Type: service.BalanceTxTypeRefund, Amount: 2.5, UsageLogID: &usage.ID}
+	s.Require().NoError(s.txRepo.RefundUsage(s.ctx, refund))
+	got, err = s.repo.GetByID(s.ctx, org.ID)
+	s.Require().NoError(err)
+	s.Require().InDelta(10.0, got.Balance, 1e-9)
+	member, err = s.repo.GetMember(s.ctx, org.ID, owner.ID)
+	s.Require().NoError(err)
+	s.Require().InDelta(0, member.CurrentMonthUsage(now), 1e-9)
+
+	// 组织余额不足时不能扣成负数
+	overdraw := &service.BalanceTransaction{UserID: owner.ID, OrganizationID: &org.ID, Type: service.BalanceTxTypeAdminAdjustment, Amount: -20}
+	s.Require().ErrorIs(s.txRepo.Apply(s.ctx, overdraw, false), service.ErrInsufficientBalance)
+}
+
+func (s *OrganizationRepoSuite) TestMemberUsageResetsOnNewMonth() {
+	owner := mustCreateUser(s.T(), s.db, &userModel{Email: "month-owner@test.com"})
+	org := s.createOrg(owner)
+
+	lastMonth := time.Now().UTC().AddDate(0, -1, 0)
+	s.Require().NoError(addOrgMemberUsage(s.db, org.ID, owner.ID, 4, lastMonth))
+	s.Require().NoError(addOrgMemberUsage(s.db, org.ID, owner.ID, 1, lastMonth))
+
+	member, err := s.repo.GetMember(s.ctx, org.ID, owner.ID)
+	s.Require().NoError(err)
+	s.Require().InDelta(5, member.MonthlyUsageUSD, 1e-9)
+	s.Require().InDelta(0, member.CurrentMonthUsage(time.Now()), 1e-9, "last month's spend should not count")
+
+	// 新月份的第一笔花费从 0 开始累计
+	s.Require().NoError(addOrgMemberUsage(s.db, org.ID, owner.ID, 2, time.Now()))
+	member, err = s.repo.GetMember(s.ctx, org.ID, owner.ID)
+	s.Require().NoError(err)
+	s.Require().InDelta(2, member.CurrentMonthUsage(time.Now()), 1e-9)
+}
+
+func (s *OrganizationRepoSuite) TestSubscriptionUniquenessPerOwnerAndOrganization() {
+	owner := mustCreateUser(s.T(), s.db, &userModel{Email: "sub-owner@test.com"})
+	orgA := s.createOrg(owner)
+	orgB := s.createOrg(owner)
+	group := mustCreateGroup(s.T(), s.db, &groupModel{Name: "g-org-sub"})
+	subRepo := NewUserSubscriptionRepository(s.db)
+	now := time.Now()
+	newSub := func(orgID *int64) *service.UserSubscription {
+		return &service.UserSubscription{
+			UserID:         owner.ID,
+			GroupID:        group.ID,
+			OrganizationID: orgID,
+			StartsAt:       now,
+			ExpiresAt:      now.Add(24 * time.Hour),
+			Status:         service.SubscriptionStatusActive,
+			AssignedAt:     now,
+		}
+	}
+
+	// owner 的个人订阅与其名下多个组织的共享订阅可以同时存在
+	s.Require().NoError(subRepo.Create(s.ctx, newSub(nil)), "personal subscription")
+	s.Require().NoError(subRepo.Create(s.ctx, newSub(&orgA.ID)), "organization A subscription")
+	s.Require().NoError(subRepo.Create(s.ctx, newSub(&orgB.ID)), "organization B subscription")
+
+	// 同一个人或同一组织对同一分组仍只能有一个订阅
+	s.Require().ErrorIs(subRepo.Create(s.ctx, newSub(nil)), service.ErrSubscriptionAlreadyExists)
+	s.Require().ErrorIs(subRepo.Create(s.ctx, newSub(&orgA.ID)), service.ErrSubscriptionAlreadyExists)
+}
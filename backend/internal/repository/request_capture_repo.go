@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// requestCaptureSummaryColumns 列表查询不加载请求/响应正文
+var requestCaptureSummaryColumns = []string{
+	"id", "user_id", "api_key_id", "account_id", "platform", "model", "request_id",
+	"method", "path", "upstream_url", "response_status",
+	"request_truncated", "response_truncated", "duration_ms", "created_at",
+}
+
+type requestCaptureRepository struct {
+	db *gorm.DB
+}
+
+func NewRequestCaptureRepository(db *gorm.DB) service.RequestCaptureRepository {
+	return &requestCaptureRepository{db: db}
+}
+
+func (r *requestCaptureRepository) Create(ctx context.Context, capture *service.RequestCapture) error {
+	m := requestCaptureModelFromService(capture)
+	if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
+		return err
+	}
+	capture.ID = m.ID
+	capture.CreatedAt = m.CreatedAt
+	return nil
+}
+
+func (r *requestCaptureRepository) GetByID(ctx context.Context, id int64) (*service.RequestCapture, error) {
+	var m requestCaptureModel
+	if err := r.db.WithContext(ctx).First(&m, id).Error; err != nil {
+		return nil, translatePersistenceError(err, service.ErrRequestCaptureNotFound, nil)
+	}
+	return requestCaptureModelToService(&m), nil
+}
+
+func (r *requestCaptureRepository) List(ctx context.Context, params pagination.PaginationParams, filters service.RequestCaptureFilters) ([]service.RequestCapture, *pagination.PaginationResult, error) {
+	var captures []requestCaptureModel
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&requestCaptureModel{})
+	if filters.ApiKeyID > 0 {
+		db = db.Where("api_key_id = ?", filters.ApiKeyID)
+	}
+	if filters.AccountID > 0 {
+		db = db.Where("account_id = ?", filters.AccountID)
+	}
+	if filters.Platform != "" {
+		db = db.Where("platform = ?", filters.Platform)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if err := db.Select(requestCaptureSummaryColumns).Offset(params.Offset()).Limit(params.Limit()).Order("id DESC").Find(&captures).Error; err != nil {
+		return nil, nil, err
+	}
+
+	out := make([]service.RequestCapture, 0, len(captures))
+	for i := range captures {
+		out = append(out, *requestCaptureModelToService(&captures[i]))
+	}
+	return out, paginationResultFromTotal(total, params), nil
+}
+
+func (r *requestCaptureRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&requestCaptureModel{}, id).Error
+}
+
+func (r *requestCaptureRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&requestCaptureModel{})
+	return result.RowsAffected, result.Error
+}
+
+type requestCaptureModel struct {
+	ID        int64  `gorm:"primaryKey"`
+	UserID    int64  `gorm:"index;not null"`
+	ApiKeyID  int64  `gorm:"index;not null"`
+	AccountID *int64 `gorm:"index"`
+	Platform  string `gorm:"size:50;not null"`
+	Model     string `gorm:"size:100;default:''"`
+	RequestID string `gorm:"size:64;default:''"`
+	Method    string `gorm:"size:10;not null"`
+	Path      string `gorm:"size:255;not null"`
+
+	ClientHeaders datatypes.JSONMap `gorm:"type:jsonb;default:'{}'"`
+	ClientBody    string            `gorm:"type:text;default:''"`
+
+	UpstreamURL     string            `gorm:"type:text;default:''"`
+	UpstreamHeaders datatypes.JSONMap `gorm:"type:jsonb;default:'{}'"`
+	UpstreamBody    string            `gorm:"type:text;default:''"`
+
+	ResponseStatus  int               `gorm:"default:0;not null"`
+	ResponseHeaders datatypes.JSONMap `gorm:"type:jsonb;default:'{}'"`
+	ResponseBody    string            `gorm:"type:text;default:''"`
+
+	RequestTruncated  bool `gorm:"default:false;not null"`
+	ResponseTruncated bool `gorm:"default:false;not null"`
+
+	DurationMs int64     `gorm:"default:0;not null"`
+	CreatedAt  time.Time `gorm:"index;not null"`
+}
+
+func (requestCaptureModel) TableName() string { return "request_captures" }
+
+func headersToJSONMap(h map[string]string) datatypes.JSONMap {
+	m := make(datatypes.JSONMap, len(h))
+	for k, v := range h {
+		m[k] = v
+	}
+	return m
+}
+
+func headersFromJSONMap(m datatypes.JSONMap) map[string]string {
+	if m == nil {
+		return nil
+	}
+	h := make(map[string]string, len(m))
+	for k, v := range m {
+		h[k] = fmt.Sprint(v)
+	}
+	return h
+}
+
+func requestCaptureModelToService(m *requestCaptureModel) *service.RequestCapture {
+	if m == nil {
+		return nil
+	}
+	return &service.RequestCapture{
+		ID:                m.ID,
+		UserID:            m.UserID,
+		ApiKeyID:          m.ApiKeyID,
+		AccountID:         m.AccountID,
+		Platform:          m.Platform,
+		Model:             m.Model,
+		RequestID:         m.RequestID,
+		Method:            m.Method,
+		Path:              m.Path,
+		ClientHeaders:     headersFromJSONMap(m.ClientHeaders),
+		ClientBody:        m.ClientBody,
+		UpstreamURL:       m.UpstreamURL,
+		UpstreamHeaders:   headersFromJSONMap(m.UpstreamHeaders),
+		UpstreamBody:      m.UpstreamBody,
+		ResponseStatus:    m.ResponseStatus,
+		ResponseHeaders:   headersFromJSONMap(m.ResponseHeaders),
+		ResponseBody:      m.ResponseBody,
+		RequestTruncated:  m.RequestTruncated,
+		ResponseTruncated: m.ResponseTruncated,
+		DurationMs:        m.DurationMs,
+		CreatedAt:         m.CreatedAt,
+	}
+}
+
+func requestCaptureModelFromService(c *service.RequestCapture) *requestCaptureModel {
+	if c == nil {
+		return nil
+	}
+	return &requestCaptureModel{
+		ID:                c.ID,
+		UserID:            c.UserID,
+		ApiKeyID:          c.ApiKeyID,
+		AccountID:         c.AccountID,
+		Platform:          c.Platform,
+		Model:             c.Model,
+		RequestID:         c.RequestID,
+		Method:            c.Method,
+		Path:              c.Path,
+		ClientHeaders:     headersToJSONMap(c.ClientHeaders),
+		ClientBody:        c.ClientBody,
+		UpstreamURL:       c.UpstreamURL,
+		UpstreamHeaders:   headersToJSONMap(c.UpstreamHeaders),
+		UpstreamBody:      c.UpstreamBody,
+		ResponseStatus:    c.ResponseStatus,
+		ResponseHeaders:   headersToJSONMap(c.ResponseHeaders),
+		ResponseBody:      c.ResponseBody,
+		RequestTruncated:  c.RequestTruncated,
+		ResponseTruncated: c.ResponseTruncated,
+		DurationMs:        c.DurationMs,
+		CreatedAt:         c.CreatedAt,
+	}
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+const responseCachePrefix = "response_cache:"
+
+type responseCache struct {
+	rdb *redis.Client
+}
+
+func NewResponseCache(rdb *redis.Client) service.ResponseCache {
+	return &responseCache{rdb: rdb}
+}
+
+func (c *responseCache) GetResponse(ctx context.Context, key string) (*service.CachedResponse, error) {
+	data, err := c.rdb.Get(ctx, responseCachePrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var resp service.CachedResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *responseCache) SetResponse(ctx context.Context, key string, resp *service.CachedResponse, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return c.rdb.Set(ctx, responseCachePrefix+key, data, ttl).Err()
+}
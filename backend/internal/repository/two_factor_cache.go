@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	webAuthnSessionKeyPrefix   = "webauthn_session:"
+	totpUsedKeyPrefix          = "totp_used:"
+	twoFactorAttemptsKeyPrefix = "2fa_attempts:"
+)
+
+type twoFactorCache struct {
+	rdb *redis.Client
+}
+
+func NewTwoFactorCache(rdb *redis.Client) service.TwoFactorCache {
+	return &twoFactorCache{rdb: rdb}
+}
+
+func (c *twoFactorCache) SetWebAuthnSession(ctx context.Context, key string, session *webauthn.SessionData, ttl time.Duration) error {
+	val, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return c.rdb.Set(ctx, webAuthnSessionKeyPrefix+key, val, ttl).Err()
+}
+
+func (c *twoFactorCache) TakeWebAuthnSession(ctx context.Context, key string) (*webauthn.SessionData, error) {
+	fullKey := webAuthnSessionKeyPrefix + key
+	pipe := c.rdb.TxPipeline()
+	get := pipe.Get(ctx, fullKey)
+	pipe.Del(ctx, fullKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(get.Val()), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (c *twoFactorCache) MarkTOTPUsed(ctx context.Context, userID, counter int64, ttl time.Duration) (bool, error) {
+	key := totpUsedKeyPrefix + strconv.FormatInt(userID, 10) + ":" + strconv.FormatInt(counter, 10)
+	return c.rdb.SetNX(ctx, key, 1, ttl).Result()
+}
+
+func (c *twoFactorCache) IncrementAttempts(ctx context.Context, userID int64, window time.Duration) (int64, error) {
+	key := twoFactorAttemptsKeyPrefix + strconv.FormatInt(userID, 10)
+	count, err := c.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err := c.rdb.Expire(ctx, key, window).Err(); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
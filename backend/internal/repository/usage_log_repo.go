@@ -1174,10 +1174,12 @@ type usageLogModel struct {
 	ApiKeyID  int64  `gorm:"index;not null"`
 	AccountID int64  `gorm:"index;not null"`
 	RequestID string `gorm:"size:64"`
+	TraceID   string `gorm:"size:32;index;default:''"`
 	Model     string `gorm:"size:100;index;not null"`
 
 	GroupID        *int64 `gorm:"index"`
 	SubscriptionID *int64 `gorm:"index"`
+	OrganizationID *int64 `gorm:"index"`
 
 	InputTokens         int `gorm:"default:0;not null"`
 	OutputTokens        int `gorm:"default:0;not null"`
@@ -1197,6 +1199,7 @@ type usageLogModel struct {
 
 	BillingType  int8 `gorm:"type:smallint;default:0;not null"`
 	Stream       bool `gorm:"default:false;not null"`
+	CacheHit     bool `gorm:"default:false;not null"`
 	DurationMs   *int
 	FirstTokenMs *int
 
@@ -1221,9 +1224,11 @@ func usageLogModelToService(m *usageLogModel) *service.UsageLog {
 		ApiKeyID:              m.ApiKeyID,
 		AccountID:             m.AccountID,
 		RequestID:             m.RequestID,
+		TraceID:               m.TraceID,
 		Model:                 m.Model,
 		GroupID:               m.GroupID,
 		SubscriptionID:        m.SubscriptionID,
+		OrganizationID:        m.OrganizationID,
 		InputTokens:           m.InputTokens,
 		OutputTokens:          m.OutputTokens,
 		CacheCreationTokens:   m.CacheCreationTokens,
@@ -1239,6 +1244,7 @@ func usageLogModelToService(m *usageLogModel) *service.UsageLog {
 		RateMultiplier:        m.RateMultiplier,
 		BillingType:           m.BillingType,
 		Stream:                m.Stream,
+		CacheHit:              m.CacheHit,
 		DurationMs:            m.DurationMs,
 		FirstTokenMs:          m.FirstTokenMs,
 		CreatedAt:             m.CreatedAt,
@@ -1270,9 +1276,11 @@ func usageLogModelFromService(log *service.UsageLog) *usageLogModel {
 		ApiKeyID:              log.ApiKeyID,
 		AccountID:             log.AccountID,
 		RequestID:             log.RequestID,
+		TraceID:               log.TraceID,
 		Model:                 log.Model,
 		GroupID:               log.GroupID,
 		SubscriptionID:        log.SubscriptionID,
+		OrganizationID:        log.OrganizationID,
 		InputTokens:           log.InputTokens,
 		OutputTokens:          log.OutputTokens,
 		CacheCreationTokens:   log.CacheCreationTokens,
@@ -1288,6 +1296,7 @@ func usageLogModelFromService(log *service.UsageLog) *usageLogModel {
 		RateMultiplier:        log.RateMultiplier,
 		BillingType:           log.BillingType,
 		Stream:                log.Stream,
+		CacheHit:              log.CacheHit,
 		DurationMs:            log.DurationMs,
 		FirstTokenMs:          log.FirstTokenMs,
 		CreatedAt:             log.CreatedAt,
@@ -59,6 +59,8 @@ func (s *UsageLogRepoSuite) TestCreate() {
 		UserID:       user.ID,
 		ApiKeyID:     apiKey.ID,
 		AccountID:    account.ID,
+		RequestID:    "req_upstream_1",
+		TraceID:      "4bf92f3577b34da6a3ce929d0e0e4736",
 		Model:        "claude-3",
 		InputTokens:  10,
 		OutputTokens: 20,
@@ -69,6 +71,12 @@ func (s *UsageLogRepoSuite) TestCreate() {
 	err := s.repo.Create(s.ctx, log)
 	s.Require().NoError(err, "Create")
 	s.Require().NotZero(log.ID)
+
+	// 上游请求 ID 与 trace id 分别保存
+	got, err := s.repo.GetByID(s.ctx, log.ID)
+	s.Require().NoError(err, "GetByID")
+	s.Require().Equal("req_upstream_1", got.RequestID)
+	s.Require().Equal("4bf92f3577b34da6a3ce929d0e0e4736", got.TraceID)
 }
 
 func (s *UsageLogRepoSuite) TestGetByID() {
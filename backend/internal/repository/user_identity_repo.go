@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"gorm.io/gorm"
+)
+
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewUserIdentityRepository(db *gorm.DB) service.UserIdentityRepository {
+	return &userIdentityRepository{db: db}
+}
+
+func (r *userIdentityRepository) GetByIssuerSubject(ctx context.Context, issuer, subject string) (*service.UserIdentity, error) {
+	var m userIdentityModel
+	err := r.db.WithContext(ctx).Where("issuer = ? AND subject = ?", issuer, subject).First(&m).Error
+	if err != nil {
+		return nil, translatePersistenceError(err, service.ErrUserIdentityNotFound, nil)
+	}
+	return userIdentityModelToService(&m), nil
+}
+
+func (r *userIdentityRepository) Create(ctx context.Context, identity *service.UserIdentity) error {
+	m := userIdentityModelFromService(identity)
+	err := r.db.WithContext(ctx).Create(m).Error
+	if err != nil {
+		return translatePersistenceError(err, nil, service.ErrOIDCIdentityConflict)
+	}
+	identity.ID = m.ID
+	identity.CreatedAt = m.CreatedAt
+	return nil
+}
+
+func (r *userIdentityRepository) TouchLastLogin(ctx context.Context, id int64, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&userIdentityModel{}).Where("id = ?", id).Update("last_login", at).Error
+}
+
+type userIdentityModel struct {
+	ID int64 `gorm:"primaryKey"`
+	// 同一账号在同一 IdP 下只能绑定一个身份
+	UserID    int64      `gorm:"not null;uniqueIndex:idx_user_identities_user_issuer"`
+	Issuer    string     `gorm:"size:255;not null;uniqueIndex:idx_user_identities_issuer_subject;uniqueIndex:idx_user_identities_user_issuer"`
+	Subject   string     `gorm:"size:255;not null;uniqueIndex:idx_user_identities_issuer_subject"`
+	Email     string     `gorm:"size:255;not null;default:''"`
+	CreatedAt time.Time  `gorm:"not null"`
+	LastLogin *time.Time `gorm:"column:last_login"`
+}
+
+func (userIdentityModel) TableName() string { return "user_identities" }
+
+func userIdentityModelToService(m *userIdentityModel) *service.UserIdentity {
+	if m == nil {
+		return nil
+	}
+	return &service.UserIdentity{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		Issuer:    m.Issuer,
+		Subject:   m.Subject,
+		Email:     m.Email,
+		CreatedAt: m.CreatedAt,
+		LastLogin: m.LastLogin,
+	}
+}
+
+func userIdentityModelFromService(i *service.UserIdentity) *userIdentityModel {
+	if i == nil {
+		return nil
+	}
+	return &userIdentityModel{
+		ID:        i.ID,
+		UserID:    i.UserID,
+		Issuer:    i.Issuer,
+		Subject:   i.Subject,
+		Email:     i.Email,
+		CreatedAt: i.CreatedAt,
+		LastLogin: i.LastLogin,
+	}
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type userNotificationPrefsRepository struct {
+	db *gorm.DB
+}
+
+func NewUserNotificationPrefsRepository(db *gorm.DB) service.UserNotificationPrefsRepository {
+	return &userNotificationPrefsRepository{db: db}
+}
+
+func (r *userNotificationPrefsRepository) GetByUserID(ctx context.Context, userID int64) (*service.UserNotificationPrefs, error) {
+	var m userNotificationPrefsModel
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&m).Error
+	if err != nil {
+		return nil, translatePersistenceError(err, service.ErrNotificationPrefsNotFound, nil)
+	}
+	return userNotificationPrefsModelToService(&m), nil
+}
+
+func (r *userNotificationPrefsRepository) Upsert(ctx context.Context, prefs *service.UserNotificationPrefs) error {
+	m := userNotificationPrefsModelFromService(prefs)
+	m.UpdatedAt = time.Now()
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"balance_low_enabled", "balance_threshold", "subscription_expiry_enabled", "quota_reached_enabled", "updated_at"}),
+	}).Create(m).Error
+	if err != nil {
+		return err
+	}
+	prefs.UpdatedAt = m.UpdatedAt
+	return nil
+}
+
+type userNotificationPrefsModel struct {
+	UserID int64 `gorm:"primaryKey;autoIncrement:false"`
+
+	// 不声明 default：GORM 插入时会跳过带默认值的零值字段，导致关闭的开关被写成 true
+	BalanceLowEnabled         bool    `gorm:"not null"`
+	BalanceThreshold          float64 `gorm:"type:decimal(20,8);not null"`
+	SubscriptionExpiryEnabled bool    `gorm:"not null"`
+	QuotaReachedEnabled       bool    `gorm:"not null"`
+
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+func (userNotificationPrefsModel) TableName() string { return "user_notification_prefs" }
+
+func userNotificationPrefsModelToService(m *userNotificationPrefsModel) *service.UserNotificationPrefs {
+	if m == nil {
+		return nil
+	}
+	return &service.UserNotificationPrefs{
+		UserID:                    m.UserID,
+		BalanceLowEnabled:         m.BalanceLowEnabled,
+		BalanceThreshold:          m.BalanceThreshold,
+		SubscriptionExpiryEnabled: m.SubscriptionExpiryEnabled,
+		QuotaReachedEnabled:       m.QuotaReachedEnabled,
+		UpdatedAt:                 m.UpdatedAt,
+	}
+}
+
+func userNotificationPrefsModelFromService(p *service.UserNotificationPrefs) *userNotificationPrefsModel {
+	if p == nil {
+		return nil
+	}
+	return &userNotificationPrefsModel{
+		UserID:                    p.UserID,
+		BalanceLowEnabled:         p.BalanceLowEnabled,
+		BalanceThreshold:          p.BalanceThreshold,
+		SubscriptionExpiryEnabled: p.SubscriptionExpiryEnabled,
+		QuotaReachedEnabled:       p.QuotaReachedEnabled,
+		UpdatedAt:                 p.UpdatedAt,
+	}
+}
@@ -0,0 +1,63 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type UserNotificationPrefsRepoSuite struct {
+	suite.Suite
+	ctx  context.Context
+	db   *gorm.DB
+	repo *userNotificationPrefsRepository
+}
+
+func (s *UserNotificationPrefsRepoSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.db = testTx(s.T())
+	s.repo = NewUserNotificationPrefsRepository(s.db).(*userNotificationPrefsRepository)
+}
+
+func TestUserNotificationPrefsRepoSuite(t *testing.T) {
+	suite.Run(t, new(UserNotificationPrefsRepoSuite))
+}
+
+func (s *UserNotificationPrefsRepoSuite) TestGetByUserID_NotFound() {
+	_, err := s.repo.GetByUserID(s.ctx, 999999)
+	s.Require().ErrorIs(err, service.ErrNotificationPrefsNotFound)
+}
+
+func (s *UserNotificationPrefsRepoSuite) TestUpsert_PersistsDisabledFlags() {
+	user := mustCreateUser(s.T(), s.db, &userModel{Email: "notify@test.com"})
+
+	prefs := &service.UserNotificationPrefs{
+		UserID:                    user.ID,
+		BalanceLowEnabled:         false,
+		BalanceThreshold:          2.5,
+		SubscriptionExpiryEnabled: true,
+		QuotaReachedEnabled:       false,
+	}
+	s.Require().NoError(s.repo.Upsert(s.ctx, prefs), "Upsert")
+
+	got, err := s.repo.GetByUserID(s.ctx, user.ID)
+	s.Require().NoError(err, "GetByUserID")
+	s.Require().False(got.BalanceLowEnabled)
+	s.Require().False(got.QuotaReachedEnabled)
+	s.Require().True(got.SubscriptionExpiryEnabled)
+	s.Require().Equal(2.5, got.BalanceThreshold)
+
+	prefs.QuotaReachedEnabled = true
+	prefs.BalanceThreshold = 0
+	s.Require().NoError(s.repo.Upsert(s.ctx, prefs), "Upsert update")
+
+	got, err = s.repo.GetByUserID(s.ctx, user.ID)
+	s.Require().NoError(err)
+	s.Require().True(got.QuotaReachedEnabled)
+	s.Require().Zero(got.BalanceThreshold)
+}
@@ -44,7 +44,7 @@ func (r *userSubscriptionRepository) GetByUserIDAndGroupID(ctx context.Context,
 	var m userSubscriptionModel
 	err := r.db.WithContext(ctx).
 		Preload("Group").
-		Where("user_id = ? AND group_id = ?", userID, groupID).
+		Where("user_id = ? AND group_id = ? AND organization_id IS NULL", userID, groupID).
 		First(&m).Error
 	if err != nil {
 		return nil, translatePersistenceError(err, service.ErrSubscriptionNotFound, nil)
@@ -56,7 +56,7 @@ func (r *userSubscriptionRepository) GetActiveByUserIDAndGroupID(ctx context.Con
 	var m userSubscriptionModel
 	err := r.db.WithContext(ctx).
 		Preload("Group").
-		Where("user_id = ? AND group_id = ? AND status = ? AND expires_at > ?",
+		Where("user_id = ? AND group_id = ? AND organization_id IS NULL AND status = ? AND expires_at > ?",
 			userID, groupID, service.SubscriptionStatusActive, time.Now()).
 		First(&m).Error
 	if err != nil {
@@ -65,6 +65,19 @@ func (r *userSubscriptionRepository) GetActiveByUserIDAndGroupID(ctx context.Con
 	return userSubscriptionModelToService(&m), nil
 }
 
+func (r *userSubscriptionRepository) GetActiveByOrganizationIDAndGroupID(ctx context.Context, orgID, groupID int64) (*service.UserSubscription, error) {
+	var m userSubscriptionModel
+	err := r.db.WithContext(ctx).
+		Preload("Group").
+		Where("organization_id = ? AND group_id = ? AND status = ? AND expires_at > ?",
+			orgID, groupID, service.SubscriptionStatusActive, time.Now()).
+		First(&m).Error
+	if err != nil {
+		return nil, translatePersistenceError(err, service.ErrSubscriptionNotFound, nil)
+	}
+	return userSubscriptionModelToService(&m), nil
+}
+
 func (r *userSubscriptionRepository) Update(ctx context.Context, sub *service.UserSubscription) error {
 	sub.UpdatedAt = time.Now()
 	m := userSubscriptionModelFromService(sub)
@@ -83,7 +96,20 @@ func (r *userSubscriptionRepository) ListByUserID(ctx context.Context, userID in
 	var subs []userSubscriptionModel
 	err := r.db.WithContext(ctx).
 		Preload("Group").
-		Where("user_id = ?", userID).
+		Where("user_id = ? AND organization_id IS NULL", userID).
+		Order("created_at DESC").
+		Find(&subs).Error
+	if err != nil {
+		return nil, err
+	}
+	return userSubscriptionModelsToService(subs), nil
+}
+
+func (r *userSubscriptionRepository) ListByOrganizationID(ctx context.Context, orgID int64) ([]service.UserSubscription, error) {
+	var subs []userSubscriptionModel
+	err := r.db.WithContext(ctx).
+		Preload("Group").
+		Where("organization_id = ?", orgID).
 		Order("created_at DESC").
 		Find(&subs).Error
 	if err != nil {
@@ -96,7 +122,7 @@ func (r *userSubscriptionRepository) ListActiveByUserID(ctx context.Context, use
 	var subs []userSubscriptionModel
 	err := r.db.WithContext(ctx).
 		Preload("Group").
-		Where("user_id = ? AND status = ? AND expires_at > ?",
+		Where("user_id = ? AND organization_id IS NULL AND status = ? AND expires_at > ?",
 			userID, service.SubscriptionStatusActive, time.Now()).
 		Order("created_at DESC").
 		Find(&subs).Error
@@ -166,7 +192,7 @@ func (r *userSubscriptionRepository) List(ctx context.Context, params pagination
 func (r *userSubscriptionRepository) ExistsByUserIDAndGroupID(ctx context.Context, userID, groupID int64) (bool, error) {
 	var count int64
 	err := r.db.WithContext(ctx).Model(&userSubscriptionModel{}).
-		Where("user_id = ? AND group_id = ?", userID, groupID).
+		Where("user_id = ? AND group_id = ? AND organization_id IS NULL", userID, groupID).
 		Count(&count).Error
 	return count > 0, err
 }
@@ -260,6 +286,20 @@ func (r *userSubscriptionRepository) BatchUpdateExpiredStatus(ctx context.Contex
 	return result.RowsAffected, result.Error
 }
 
+func (r *userSubscriptionRepository) ListExpiringBefore(ctx context.Context, before time.Time) ([]service.UserSubscription, error) {
+	var subs []userSubscriptionModel
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Preload("Group").
+		Where("status = ? AND expires_at > ? AND expires_at <= ?", service.SubscriptionStatusActive, time.Now(), before).
+		Order("expires_at ASC").
+		Find(&subs).Error
+	if err != nil {
+		return nil, err
+	}
+	return userSubscriptionModelsToService(subs), nil
+}
+
 // Extra repository helpers (currently used only by integration tests).
 
 func (r *userSubscriptionRepository) ListExpired(ctx context.Context) ([]service.UserSubscription, error) {
@@ -297,8 +337,10 @@ func (r *userSubscriptionRepository) DeleteByGroupID(ctx context.Context, groupI
 
 type userSubscriptionModel struct {
 	ID      int64 `gorm:"primaryKey"`
-	UserID  int64 `gorm:"index;not null"`
-	GroupID int64 `gorm:"index;not null"`
+	UserID  int64 `gorm:"index;not null;uniqueIndex:idx_user_subscriptions_user_group,priority:1,where:organization_id IS NULL"`
+	GroupID int64 `gorm:"index;not null;uniqueIndex:idx_user_subscriptions_user_group,priority:2;uniqueIndex:idx_user_subscriptions_org_group,priority:2"`
+	// 组织共享订阅（user_id 为组织 owner）：个人订阅按 (user_id, group_id) 唯一，组织订阅按 (organization_id, group_id) 唯一
+	OrganizationID *int64 `gorm:"index;uniqueIndex:idx_user_subscriptions_org_group,priority:1,where:organization_id IS NOT NULL"`
 
 	StartsAt  time.Time `gorm:"not null"`
 	ExpiresAt time.Time `gorm:"not null"`
@@ -334,6 +376,7 @@ func userSubscriptionModelToService(m *userSubscriptionModel) *service.UserSubsc
 		ID:                 m.ID,
 		UserID:             m.UserID,
 		GroupID:            m.GroupID,
+		OrganizationID:     m.OrganizationID,
 		StartsAt:           m.StartsAt,
 		ExpiresAt:          m.ExpiresAt,
 		Status:             m.Status,
@@ -372,6 +415,7 @@ func userSubscriptionModelFromService(s *service.UserSubscription) *userSubscrip
 		ID:                 s.ID,
 		UserID:             s.UserID,
 		GroupID:            s.GroupID,
+		OrganizationID:     s.OrganizationID,
 		StartsAt:           s.StartsAt,
 		ExpiresAt:          s.ExpiresAt,
 		Status:             s.Status,
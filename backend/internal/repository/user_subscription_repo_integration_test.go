@@ -555,6 +555,38 @@ func (s *UserSubscriptionRepoSuite) TestListExpired() {
 	s.Require().Len(expired, 1)
 }
 
+func (s *UserSubscriptionRepoSuite) TestListExpiringBefore() {
+	user := mustCreateUser(s.T(), s.db, &userModel{Email: "expiring@test.com"})
+	group := mustCreateGroup(s.T(), s.db, &groupModel{Name: "g-expiring"})
+
+	soon := mustCreateSubscription(s.T(), s.db, &userSubscriptionModel{
+		UserID:    user.ID,
+		GroupID:   group.ID,
+		Status:    service.SubscriptionStatusActive,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	})
+	mustCreateSubscription(s.T(), s.db, &userSubscriptionModel{
+		UserID:    user.ID,
+		GroupID:   group.ID,
+		Status:    service.SubscriptionStatusActive,
+		ExpiresAt: time.Now().Add(10 * 24 * time.Hour),
+	})
+	mustCreateSubscription(s.T(), s.db, &userSubscriptionModel{
+		UserID:    user.ID,
+		GroupID:   group.ID,
+		Status:    service.SubscriptionStatusActive,
+		ExpiresAt: time.Now().Add(-time.Hour),
+	})
+
+	subs, err := s.repo.ListExpiringBefore(s.ctx, time.Now().Add(3*24*time.Hour))
+	s.Require().NoError(err, "ListExpiringBefore")
+	s.Require().Len(subs, 1)
+	s.Require().Equal(soon.ID, subs[0].ID)
+	s.Require().NotNil(subs[0].User)
+	s.Require().Equal("expiring@test.com", subs[0].User.Email)
+	s.Require().NotNil(subs[0].Group)
+}
+
 func (s *UserSubscriptionRepoSuite) TestBatchUpdateExpiredStatus() {
 	user := mustCreateUser(s.T(), s.db, &userModel{Email: "batch@test.com"})
 	group := mustCreateGroup(s.T(), s.db, &groupModel{Name: "g-batch"})
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type userTOTPRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTOTPRepository(db *gorm.DB) service.UserTOTPRepository {
+	return &userTOTPRepository{db: db}
+}
+
+func (r *userTOTPRepository) GetByUserID(ctx context.Context, userID int64) (*service.UserTOTP, error) {
+	var m userTOTPModel
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&m).Error
+	if err != nil {
+		return nil, translatePersistenceError(err, service.ErrTOTPNotFound, nil)
+	}
+	return userTOTPModelToService(&m), nil
+}
+
+func (r *userTOTPRepository) Upsert(ctx context.Context, totp *service.UserTOTP) error {
+	m := userTOTPModelFromService(totp)
+	m.UpdatedAt = time.Now()
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "enabled", "recovery_codes", "enabled_at", "updated_at"}),
+	}).Create(m).Error
+	if err != nil {
+		return err
+	}
+	totp.UpdatedAt = m.UpdatedAt
+	return nil
+}
+
+func (r *userTOTPRepository) UpdateRecoveryCodes(ctx context.Context, userID int64, expected, codes []string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&userTOTPModel{}).
+		Where("user_id = ? AND recovery_codes = ?", userID, pq.StringArray(expected)).
+		Updates(map[string]any{
+			"recovery_codes": pq.StringArray(codes),
+			"updated_at":     time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *userTOTPRepository) Delete(ctx context.Context, userID int64) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&userTOTPModel{}).Error
+}
+
+type userTOTPModel struct {
+	UserID int64  `gorm:"primaryKey;autoIncrement:false"`
+	Secret string `gorm:"size:64;not null"`
+	// 不声明 default，避免插入时跳过 false
+	Enabled       bool           `gorm:"not null"`
+	RecoveryCodes pq.StringArray `gorm:"type:text[];not null"`
+	EnabledAt     *time.Time
+	UpdatedAt     time.Time `gorm:"not null"`
+}
+
+func (userTOTPModel) TableName() string { return "user_totp" }
+
+func userTOTPModelToService(m *userTOTPModel) *service.UserTOTP {
+	if m == nil {
+		return nil
+	}
+	return &service.UserTOTP{
+		UserID:        m.UserID,
+		Secret:        m.Secret,
+		Enabled:       m.Enabled,
+		RecoveryCodes: []string(m.RecoveryCodes),
+		EnabledAt:     m.EnabledAt,
+		UpdatedAt:     m.UpdatedAt,
+	}
+}
+
+func userTOTPModelFromService(t *service.UserTOTP) *userTOTPModel {
+	if t == nil {
+		return nil
+	}
+	codes := t.RecoveryCodes
+	if codes == nil {
+		codes = []string{}
+	}
+	return &userTOTPModel{
+		UserID:        t.UserID,
+		Secret:        t.Secret,
+		Enabled:       t.Enabled,
+		RecoveryCodes: pq.StringArray(codes),
+		EnabledAt:     t.EnabledAt,
+		UpdatedAt:     t.UpdatedAt,
+	}
+}
@@ -0,0 +1,81 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type UserTOTPRepoSuite struct {
+	suite.Suite
+	ctx  context.Context
+	db   *gorm.DB
+	repo *userTOTPRepository
+}
+
+func (s *UserTOTPRepoSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.db = testTx(s.T())
+	s.repo = NewUserTOTPRepository(s.db).(*userTOTPRepository)
+}
+
+func TestUserTOTPRepoSuite(t *testing.T) {
+	suite.Run(t, new(UserTOTPRepoSuite))
+}
+
+func (s *UserTOTPRepoSuite) TestGetByUserID_NotFound() {
+	_, err := s.repo.GetByUserID(s.ctx, 999999)
+	s.Require().ErrorIs(err, service.ErrTOTPNotFound)
+}
+
+func (s *UserTOTPRepoSuite) TestUpsert_PendingThenEnabled() {
+	user := mustCreateUser(s.T(), s.db, &userModel{Email: "totp@test.com"})
+
+	s.Require().NoError(s.repo.Upsert(s.ctx, &service.UserTOTP{UserID: user.ID, Secret: "SECRET1"}), "Upsert pending")
+	got, err := s.repo.GetByUserID(s.ctx, user.ID)
+	s.Require().NoError(err)
+	s.Require().False(got.Enabled)
+	s.Require().Empty(got.RecoveryCodes)
+
+	got.Enabled = true
+	got.RecoveryCodes = []string{"a", "b"}
+	s.Require().NoError(s.repo.Upsert(s.ctx, got), "Upsert enabled")
+
+	got, err = s.repo.GetByUserID(s.ctx, user.ID)
+	s.Require().NoError(err)
+	s.Require().True(got.Enabled)
+	s.Require().Equal("SECRET1", got.Secret)
+	s.Require().Equal([]string{"a", "b"}, got.RecoveryCodes)
+}
+
+func (s *UserTOTPRepoSuite) TestUpdateRecoveryCodes_CompareAndSwap() {
+	user := mustCreateUser(s.T(), s.db, &userModel{Email: "totp-cas@test.com"})
+	s.Require().NoError(s.repo.Upsert(s.ctx, &service.UserTOTP{
+		UserID:        user.ID,
+		Secret:        "SECRET",
+		Enabled:       true,
+		RecoveryCodes: []string{"a", "b", "c"},
+	}))
+
+	ok, err := s.repo.UpdateRecoveryCodes(s.ctx, user.ID, []string{"a", "b", "c"}, []string{"b", "c"})
+	s.Require().NoError(err)
+	s.Require().True(ok)
+
+	// 基于旧值的并发更新应失败
+	ok, err = s.repo.UpdateRecoveryCodes(s.ctx, user.ID, []string{"a", "b", "c"}, []string{"a", "c"})
+	s.Require().NoError(err)
+	s.Require().False(ok)
+
+	got, err := s.repo.GetByUserID(s.ctx, user.ID)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"b", "c"}, got.RecoveryCodes)
+
+	s.Require().NoError(s.repo.Delete(s.ctx, user.ID))
+	_, err = s.repo.GetByUserID(s.ctx, user.ID)
+	s.Require().ErrorIs(err, service.ErrTOTPNotFound)
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type webAuthnCredentialRepository struct {
+	db *gorm.DB
+}
+
+func NewWebAuthnCredentialRepository(db *gorm.DB) service.WebAuthnCredentialRepository {
+	return &webAuthnCredentialRepository{db: db}
+}
+
+func (r *webAuthnCredentialRepository) ListByUserID(ctx context.Context, userID int64) ([]service.WebAuthnCredential, error) {
+	var models []webAuthnCredentialModel
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id ASC").Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]service.WebAuthnCredential, 0, len(models))
+	for i := range models {
+		c, err := webAuthnCredentialModelToService(&models[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return out, nil
+}
+
+func (r *webAuthnCredentialRepository) Create(ctx context.Context, credential *service.WebAuthnCredential) error {
+	m, err := webAuthnCredentialModelFromService(credential)
+	if err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
+		return err
+	}
+	credential.ID = m.ID
+	credential.CreatedAt = m.CreatedAt
+	return nil
+}
+
+func (r *webAuthnCredentialRepository) UpdateCredential(ctx context.Context, id int64, credential webauthn.Credential, lastUsedAt time.Time) error {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Model(&webAuthnCredentialModel{}).Where("id = ?", id).Updates(map[string]any{
+		"credential":   datatypes.JSON(data),
+		"sign_count":   credential.Authenticator.SignCount,
+		"last_used_at": lastUsedAt,
+	}).Error
+}
+
+func (r *webAuthnCredentialRepository) Delete(ctx context.Context, userID, id int64) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&webAuthnCredentialModel{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return service.ErrWebAuthnCredentialMissing
+	}
+	return nil
+}
+
+type webAuthnCredentialModel struct {
+	ID     int64  `gorm:"primaryKey"`
+	UserID int64  `gorm:"index;not null"`
+	Name   string `gorm:"size:100;not null"`
+	// base64url 编码的凭据 ID，用于唯一约束
+	CredentialID string `gorm:"size:1400;uniqueIndex;not null"`
+	// 完整的 webauthn.Credential（公钥、标志位、AAGUID 等）
+	Credential datatypes.JSON `gorm:"type:jsonb;not null"`
+	SignCount  uint32         `gorm:"default:0;not null"`
+	CreatedAt  time.Time      `gorm:"not null"`
+	LastUsedAt *time.Time
+}
+
+func (webAuthnCredentialModel) TableName() string { return "webauthn_credentials" }
+
+func webAuthnCredentialModelToService(m *webAuthnCredentialModel) (*service.WebAuthnCredential, error) {
+	c := &service.WebAuthnCredential{
+		ID:         m.ID,
+		UserID:     m.UserID,
+		Name:       m.Name,
+		CreatedAt:  m.CreatedAt,
+		LastUsedAt: m.LastUsedAt,
+	}
+	if err := json.Unmarshal(m.Credential, &c.Credential); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func webAuthnCredentialModelFromService(c *service.WebAuthnCredential) (*webAuthnCredentialModel, error) {
+	data, err := json.Marshal(c.Credential)
+	if err != nil {
+		return nil, err
+	}
+	return &webAuthnCredentialModel{
+		ID:           c.ID,
+		UserID:       c.UserID,
+		Name:         c.Name,
+		CredentialID: base64.RawURLEncoding.EncodeToString(c.Credential.ID),
+		Credential:   datatypes.JSON(data),
+		SignCount:    c.Credential.Authenticator.SignCount,
+		CreatedAt:    c.CreatedAt,
+		LastUsedAt:   c.LastUsedAt,
+	}, nil
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type webhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) service.WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: db}
+}
+
+func (r *webhookDeliveryRepository) Enqueue(ctx context.Context, delivery *service.WebhookDelivery) (bool, error) {
+	m, err := webhookDeliveryModelFromService(delivery)
+	if err != nil {
+		return false, err
+	}
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "dedupe_key"}}, DoNothing: true}).
+		Create(m)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	delivery.ID = m.ID
+	delivery.CreatedAt = m.CreatedAt
+	delivery.UpdatedAt = m.UpdatedAt
+	return true, nil
+}
+
+// ClaimDue 使用 FOR UPDATE SKIP LOCKED 领取任务，多副本同时轮询时不会重复投递
+func (r *webhookDeliveryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]service.WebhookDelivery, error) {
+	var models []webhookDeliveryModel
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1, next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(lease), now, service.WebhookDeliveryPending, now, limit,
+	).Scan(&models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]service.WebhookDelivery, 0, len(models))
+	for i := range models {
+		out = append(out, *webhookDeliveryModelToService(&models[i]))
+	}
+	return out, nil
+}
+
+func (r *webhookDeliveryRepository) MarkSucceeded(ctx context.Context, id int64, statusCode int) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Model(&webhookDeliveryModel{}).Where("id = ?", id).Updates(map[string]any{
+		"status":           service.WebhookDeliverySucceeded,
+		"last_status_code": statusCode,
+		"last_error":       "",
+		"delivered_at":     now,
+		"updated_at":       now,
+	}).Error
+}
+
+func (r *webhookDeliveryRepository) MarkRetry(ctx context.Context, id int64, statusCode int, lastErr string, nextAttemptAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&webhookDeliveryModel{}).Where("id = ?", id).Updates(map[string]any{
+		"last_status_code": statusCode,
+		"last_error":       lastErr,
+		"next_attempt_at":  nextAttemptAt,
+		"updated_at":       time.Now(),
+	}).Error
+}
+
+func (r *webhookDeliveryRepository) MarkFailed(ctx context.Context, id int64, statusCode int, lastErr string) error {
+	return r.db.WithContext(ctx).Model(&webhookDeliveryModel{}).Where("id = ?", id).Updates(map[string]any{
+		"status":           service.WebhookDeliveryFailed,
+		"last_status_code": statusCode,
+		"last_error":       lastErr,
+		"updated_at":       time.Now(),
+	}).Error
+}
+
+func (r *webhookDeliveryRepository) DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status <> ? AND updated_at < ?", service.WebhookDeliveryPending, before).
+		Delete(&webhookDeliveryModel{})
+	return result.RowsAffected, result.Error
+}
+
+type webhookDeliveryModel struct {
+	ID        int64          `gorm:"primaryKey"`
+	EventType string         `gorm:"size:64;index;not null"`
+	DedupeKey *string        `gorm:"size:255;uniqueIndex"`
+	Url       string         `gorm:"type:text;not null"`
+	Format    string         `gorm:"size:20;not null"`
+	Payload   datatypes.JSON `gorm:"type:jsonb;not null"`
+
+	Status         string    `gorm:"size:20;not null;default:pending;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int       `gorm:"default:0;not null"`
+	NextAttemptAt  time.Time `gorm:"not null;index:idx_webhook_deliveries_due,priority:2"`
+	LastStatusCode int       `gorm:"default:0;not null"`
+	LastError      string    `gorm:"type:text;default:''"`
+	DeliveredAt    *time.Time
+
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+func (webhookDeliveryModel) TableName() string { return "webhook_deliveries" }
+
+func webhookDeliveryModelToService(m *webhookDeliveryModel) *service.WebhookDelivery {
+	if m == nil {
+		return nil
+	}
+	d := &service.WebhookDelivery{
+		ID:             m.ID,
+		EventType:      m.EventType,
+		Url:            m.Url,
+		Format:         m.Format,
+		Status:         m.Status,
+		Attempts:       m.Attempts,
+		NextAttemptAt:  m.NextAttemptAt,
+		LastStatusCode: m.LastStatusCode,
+		LastError:      m.LastError,
+		DeliveredAt:    m.DeliveredAt,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+	if m.DedupeKey != nil {
+		d.DedupeKey = *m.DedupeKey
+	}
+	_ = json.Unmarshal(m.Payload, &d.Event)
+	return d
+}
+
+func webhookDeliveryModelFromService(d *service.WebhookDelivery) (*webhookDeliveryModel, error) {
+	payload, err := json.Marshal(d.Event)
+	if err != nil {
+		return nil, err
+	}
+	m := &webhookDeliveryModel{
+		ID:             d.ID,
+		EventType:      d.EventType,
+		Url:            d.Url,
+		Format:         d.Format,
+		Payload:        datatypes.JSON(payload),
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+	if d.DedupeKey != "" {
+		key := d.DedupeKey
+		m.DedupeKey = &key
+	}
+	return m, nil
+}
@@ -0,0 +1,115 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type WebhookDeliveryRepoSuite struct {
+	suite.Suite
+	ctx  context.Context
+	db   *gorm.DB
+	repo *webhookDeliveryRepository
+}
+
+func (s *WebhookDeliveryRepoSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.db = testTx(s.T())
+	s.repo = NewWebhookDeliveryRepository(s.db).(*webhookDeliveryRepository)
+}
+
+func TestWebhookDeliveryRepoSuite(t *testing.T) {
+	suite.Run(t, new(WebhookDeliveryRepoSuite))
+}
+
+func (s *WebhookDeliveryRepoSuite) newDelivery(dedupeKey string, nextAttemptAt time.Time) *service.WebhookDelivery {
+	return &service.WebhookDelivery{
+		EventType: service.WebhookEventAccountError,
+		DedupeKey: dedupeKey,
+		Url:       "https://hooks.example.com",
+		Format:    service.WebhookFormatGeneric,
+		Event: service.WebhookEvent{
+			Type:       service.WebhookEventAccountError,
+			Title:      "Account disabled",
+			Data:       map[string]any{"account_id": float64(7)},
+			OccurredAt: nextAttemptAt,
+		},
+		Status:        service.WebhookDeliveryPending,
+		NextAttemptAt: nextAttemptAt,
+	}
+}
+
+func (s *WebhookDeliveryRepoSuite) TestEnqueue_Dedupe() {
+	now := time.Now()
+	created, err := s.repo.Enqueue(s.ctx, s.newDelivery("k1", now))
+	s.Require().NoError(err, "Enqueue")
+	s.Require().True(created)
+
+	created, err = s.repo.Enqueue(s.ctx, s.newDelivery("k1", now))
+	s.Require().NoError(err, "Enqueue duplicate")
+	s.Require().False(created, "duplicate dedupe key should be ignored")
+
+	// 空去重键不参与去重
+	for i := 0; i < 2; i++ {
+		created, err = s.repo.Enqueue(s.ctx, s.newDelivery("", now))
+		s.Require().NoError(err, "Enqueue without dedupe key")
+		s.Require().True(created)
+	}
+}
+
+func (s *WebhookDeliveryRepoSuite) TestClaimDue_LeasesAndIncrementsAttempts() {
+	now := time.Now()
+	due := s.newDelivery("", now.Add(-time.Minute))
+	_, err := s.repo.Enqueue(s.ctx, due)
+	s.Require().NoError(err)
+	_, err = s.repo.Enqueue(s.ctx, s.newDelivery("", now.Add(time.Hour)))
+	s.Require().NoError(err)
+
+	claimed, err := s.repo.ClaimDue(s.ctx, now, 2*time.Minute, 10)
+	s.Require().NoError(err, "ClaimDue")
+	s.Require().Len(claimed, 1)
+	s.Require().Equal(due.ID, claimed[0].ID)
+	s.Require().Equal(1, claimed[0].Attempts)
+	s.Require().Equal(float64(7), claimed[0].Event.Data["account_id"])
+	s.Require().WithinDuration(now.Add(2*time.Minute), claimed[0].NextAttemptAt, time.Second)
+
+	// 租约期内不会被再次领取
+	claimed, err = s.repo.ClaimDue(s.ctx, now, 2*time.Minute, 10)
+	s.Require().NoError(err)
+	s.Require().Empty(claimed)
+}
+
+func (s *WebhookDeliveryRepoSuite) TestMarkAndDeleteFinished() {
+	now := time.Now()
+	ok := s.newDelivery("", now)
+	failed := s.newDelivery("", now)
+	pending := s.newDelivery("", now)
+	for _, d := range []*service.WebhookDelivery{ok, failed, pending} {
+		_, err := s.repo.Enqueue(s.ctx, d)
+		s.Require().NoError(err)
+	}
+
+	s.Require().NoError(s.repo.MarkSucceeded(s.ctx, ok.ID, 200))
+	s.Require().NoError(s.repo.MarkFailed(s.ctx, failed.ID, 500, "boom"))
+	s.Require().NoError(s.repo.MarkRetry(s.ctx, pending.ID, 502, "bad gateway", now.Add(time.Minute)))
+
+	var got webhookDeliveryModel
+	s.Require().NoError(s.db.First(&got, ok.ID).Error)
+	s.Require().Equal(service.WebhookDeliverySucceeded, got.Status)
+	s.Require().NotNil(got.DeliveredAt)
+
+	deleted, err := s.repo.DeleteFinishedBefore(s.ctx, time.Now().Add(time.Minute))
+	s.Require().NoError(err, "DeleteFinishedBefore")
+	s.Require().Equal(int64(2), deleted)
+
+	var remaining int64
+	s.Require().NoError(s.db.Model(&webhookDeliveryModel{}).Count(&remaining).Error)
+	s.Require().Equal(int64(1), remaining)
+}
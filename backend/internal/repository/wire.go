@@ -15,11 +15,27 @@ var ProviderSet = wire.NewSet(
 	NewUsageLogRepository,
 	NewSettingRepository,
 	NewUserSubscriptionRepository,
+	NewBalanceTransactionRepository,
+	NewRequestCaptureRepository,
+	NewWebhookDeliveryRepository,
+	NewUserNotificationPrefsRepository,
+	NewUserTOTPRepository,
+	NewWebAuthnCredentialRepository,
+	NewUserIdentityRepository,
+	NewAuditLogRepository,
+	NewAdminApiKeyRepository,
+	NewOrganizationRepository,
 
 	// Cache implementations
 	NewGatewayCache,
+	NewResponseCache,
+	NewLiveEventPubSub,
+	NewNotificationDedupCache,
+	NewTwoFactorCache,
+	NewOIDCStateCache,
 	NewBillingCache,
 	NewApiKeyCache,
+	NewApiKeyLimitCache,
 	NewConcurrencyCache,
 	NewEmailCache,
 	NewIdentityCache,
@@ -36,6 +52,7 @@ var ProviderSet = wire.NewSet(
 	NewClaudeOAuthClient,
 	NewHTTPUpstream,
 	NewOpenAIOAuthClient,
+	NewOIDCClient,
 	NewGeminiOAuthClient,
 	NewGeminiCliCodeAssistClient,
 )
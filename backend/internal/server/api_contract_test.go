@@ -82,6 +82,16 @@ func TestAPIContracts(t *testing.T) {
 					"name": "Key One",
 					"group_id": null,
 					"status": "active",
+					"rate_limit_rpm": 0,
+					"rate_limit_tpm": 0,
+					"daily_limit_usd": null,
+					"monthly_limit_usd": null,
+					"allowed_models": null,
+					"allowed_ips": null,
+					"expires_at": null,
+					"is_expired": false,
+					"previous_key_expires_at": null,
+					"capture_enabled": false,
 					"created_at": "2025-01-02T03:04:05Z",
 					"updated_at": "2025-01-02T03:04:05Z"
 				}
@@ -116,6 +126,16 @@ func TestAPIContracts(t *testing.T) {
 							"name": "Key One",
 							"group_id": null,
 							"status": "active",
+							"rate_limit_rpm": 0,
+							"rate_limit_tpm": 0,
+							"daily_limit_usd": null,
+							"monthly_limit_usd": null,
+							"allowed_models": null,
+							"allowed_ips": null,
+							"expires_at": null,
+							"is_expired": false,
+							"previous_key_expires_at": null,
+							"capture_enabled": false,
 							"created_at": "2025-01-02T03:04:05Z",
 							"updated_at": "2025-01-02T03:04:05Z"
 						}
@@ -239,6 +259,7 @@ func TestAPIContracts(t *testing.T) {
 							"rate_multiplier": 1,
 							"billing_type": 0,
 							"stream": true,
+							"cache_hit": false,
 							"duration_ms": 100,
 							"first_token_ms": 50,
 							"created_at": "2025-01-02T03:04:05Z"
@@ -280,6 +301,13 @@ func TestAPIContracts(t *testing.T) {
 
 					service.SettingKeyDefaultConcurrency: "5",
 					service.SettingKeyDefaultBalance:     "1.25",
+
+					service.SettingKeyWebhookEnabled:          "true",
+					service.SettingKeyWebhookUrl:              "https://hooks.example.com/sub2api",
+					service.SettingKeyWebhookSecret:           "hook-secret",
+					service.SettingKeyWebhookFormat:           "slack",
+					service.SettingKeyWebhookEvents:           "account.error,user.balance_low",
+					service.SettingKeyWebhookBalanceThreshold: "5",
 				})
 			},
 			method:     http.MethodGet,
@@ -308,7 +336,25 @@ func TestAPIContracts(t *testing.T) {
 					"contact_info": "support",
 					"doc_url": "https://docs.example.com",
 					"default_concurrency": 5,
-					"default_balance": 1.25
+					"default_balance": 1.25,
+					"webhook_enabled": true,
+					"webhook_url": "https://hooks.example.com/sub2api",
+					"webhook_secret": "hook-secret",
+					"webhook_format": "slack",
+					"webhook_events": ["account.error", "user.balance_low"],
+					"webhook_balance_threshold": 5,
+					"webhook_subscription_expiry_days": 3,
+					"admin_2fa_required": true,
+					"oidc_enabled": false,
+					"oidc_provider_name": "SSO",
+					"oidc_issuer": "",
+					"oidc_client_id": "",
+					"oidc_redirect_url": "",
+					"oidc_scopes": "openid email profile",
+					"oidc_auto_provision": false,
+					"oidc_groups_claim": "groups",
+					"oidc_admin_groups": [],
+					"oidc_group_mapping": {}
 				}
 			}`,
 		},
@@ -375,7 +421,7 @@ func newContractDeps(t *testing.T) *contractDeps {
 	apiKeyService := service.NewApiKeyService(apiKeyRepo, userRepo, groupRepo, userSubRepo, apiKeyCache, cfg)
 
 	usageRepo := newStubUsageLogRepo()
-	usageService := service.NewUsageService(usageRepo, userRepo)
+	usageService := service.NewUsageService(usageRepo, userRepo, nil)
 
 	settingRepo := newStubSettingRepo()
 	settingService := service.NewSettingService(settingRepo, cfg)
@@ -383,7 +429,7 @@ func newContractDeps(t *testing.T) *contractDeps {
 	authHandler := handler.NewAuthHandler(nil, userService)
 	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
 	usageHandler := handler.NewUsageHandler(usageService, apiKeyService)
-	adminSettingHandler := adminhandler.NewSettingHandler(settingService, nil)
+	adminSettingHandler := adminhandler.NewSettingHandler(settingService, nil, nil, nil)
 
 	jwtAuth := func(c *gin.Context) {
 		c.Set(string(middleware.ContextKeyUser), middleware.AuthSubject{
@@ -611,6 +657,12 @@ func (stubUserSubscriptionRepo) GetByUserIDAndGroupID(ctx context.Context, userI
 func (stubUserSubscriptionRepo) GetActiveByUserIDAndGroupID(ctx context.Context, userID, groupID int64) (*service.UserSubscription, error) {
 	return nil, errors.New("not implemented")
 }
+func (stubUserSubscriptionRepo) GetActiveByOrganizationIDAndGroupID(ctx context.Context, orgID, groupID int64) (*service.UserSubscription, error) {
+	return nil, errors.New("not implemented")
+}
+func (stubUserSubscriptionRepo) ListByOrganizationID(ctx context.Context, orgID int64) ([]service.UserSubscription, error) {
+	return nil, errors.New("not implemented")
+}
 func (stubUserSubscriptionRepo) Update(ctx context.Context, sub *service.UserSubscription) error {
 	return errors.New("not implemented")
 }
@@ -659,6 +711,9 @@ func (stubUserSubscriptionRepo) IncrementUsage(ctx context.Context, id int64, co
 func (stubUserSubscriptionRepo) BatchUpdateExpiredStatus(ctx context.Context) (int64, error) {
 	return 0, errors.New("not implemented")
 }
+func (stubUserSubscriptionRepo) ListExpiringBefore(ctx context.Context, before time.Time) ([]service.UserSubscription, error) {
+	return nil, errors.New("not implemented")
+}
 
 type stubApiKeyRepo struct {
 	now time.Time
@@ -838,6 +893,22 @@ func (r *stubApiKeyRepo) CountByGroupID(ctx context.Context, groupID int64) (int
 	return 0, errors.New("not implemented")
 }
 
+func (r *stubApiKeyRepo) ListByOrganizationID(ctx context.Context, orgID int64, params pagination.PaginationParams) ([]service.ApiKey, *pagination.PaginationResult, error) {
+	return nil, nil, errors.New("not implemented")
+}
+
+func (r *stubApiKeyRepo) ExpireKeys(ctx context.Context, now time.Time) (int64, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (r *stubApiKeyRepo) ClearExpiredPreviousKeys(ctx context.Context, now time.Time) (int64, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (r *stubApiKeyRepo) SetCaptureEnabled(ctx context.Context, id int64, enabled bool) error {
+	return errors.New("not implemented")
+}
+
 type stubUsageLogRepo struct {
 	userLogs map[int64][]service.UsageLog
 }
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -22,21 +23,37 @@ var ProviderSet = wire.NewSet(
 // ProvideRouter 提供路由器
 func ProvideRouter(
 	cfg *config.Config,
+	log *slog.Logger,
 	handlers *handler.Handlers,
 	jwtAuth middleware2.JWTAuthMiddleware,
 	adminAuth middleware2.AdminAuthMiddleware,
 	apiKeyAuth middleware2.ApiKeyAuthMiddleware,
 	apiKeyService *service.ApiKeyService,
 	subscriptionService *service.SubscriptionService,
+	apiKeyLimitService *service.ApiKeyLimitService,
+	organizationService *service.OrganizationService,
+	metricsAuth middleware2.MetricsAuthMiddleware,
 ) *gin.Engine {
 	if cfg.Server.Mode == "release" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	r := newEngine(cfg, log)
+
+	return SetupRouter(r, log, handlers, jwtAuth, adminAuth, apiKeyAuth, apiKeyService, subscriptionService, apiKeyLimitService, organizationService, metricsAuth)
+}
+
+// newEngine 创建 gin 引擎，只信任配置中的反向代理
+// gin 默认信任所有代理，客户端可以伪造 X-Forwarded-For 绕过按 IP 的限制（API Key IP 白名单、限流等）
+func newEngine(cfg *config.Config, log *slog.Logger) *gin.Engine {
 	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		// 配置已在加载时校验，这里仅作兜底：不信任任何代理
+		log.Error("invalid server.trusted_proxies, trusting no proxies", "error", err)
+		_ = r.SetTrustedProxies(nil)
+	}
 	r.Use(middleware2.Recovery())
-
-	return SetupRouter(r, handlers, jwtAuth, adminAuth, apiKeyAuth, apiKeyService, subscriptionService)
+	return r
 }
 
 // ProvideHTTPServer 提供 HTTP 服务器
@@ -0,0 +1,50 @@
+//go:build unit
+
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEngine_TrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	apiKey := &service.ApiKey{AllowedIPs: []string{"203.0.113.7"}}
+
+	// 路由与网关 handler 一样使用 c.ClientIP() 校验 API Key 的 IP 白名单
+	do := func(cfg *config.Config, remoteAddr string) int {
+		r := newEngine(cfg, log)
+		r.GET("/check", func(c *gin.Context) {
+			if err := apiKey.CheckAccess("", c.ClientIP()); err != nil {
+				c.Status(http.StatusForbidden)
+				return
+			}
+			c.Status(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/check", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req.Header.Set("X-Real-IP", "203.0.113.7")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 默认不信任任何代理：伪造的 X-Forwarded-For 被忽略
+	require.Equal(t, http.StatusForbidden, do(&config.Config{}, "198.51.100.9:40000"))
+
+	// 来自受信任代理的请求才使用 X-Forwarded-For
+	trusted := &config.Config{Server: config.ServerConfig{TrustedProxies: []string{"10.0.0.0/8"}}}
+	require.Equal(t, http.StatusOK, do(trusted, "10.1.2.3:40000"))
+	require.Equal(t, http.StatusForbidden, do(trusted, "198.51.100.9:40000"))
+}
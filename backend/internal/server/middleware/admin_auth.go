@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"crypto/subtle"
 	"errors"
 	"strings"
 
@@ -10,31 +9,36 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// adminApiKeyIDContextKey 通过 Admin API Key 认证时的 Key ID（int64）
+const adminApiKeyIDContextKey = "admin_api_key_id"
+
 // NewAdminAuthMiddleware 创建管理员认证中间件
 func NewAdminAuthMiddleware(
 	authService *service.AuthService,
 	userService *service.UserService,
-	settingService *service.SettingService,
+	adminApiKeyService *service.AdminApiKeyService,
 ) AdminAuthMiddleware {
-	return AdminAuthMiddleware(adminAuth(authService, userService, settingService))
+	return AdminAuthMiddleware(adminAuth(authService, userService, adminApiKeyService))
 }
 
 // adminAuth 管理员认证中间件实现
 // 支持两种认证方式（通过不同的 header 区分）：
 // 1. Admin API Key: x-api-key: <admin-api-key>
-// 2. JWT Token: Authorization: Bearer <jwt-token> (需要管理员角色)
+// 2. JWT Token: Authorization: Bearer <jwt-token> (需要管理角色)
+// 认证通过后将操作人写入 request context，供审计日志使用
 func adminAuth(
 	authService *service.AuthService,
 	userService *service.UserService,
-	settingService *service.SettingService,
+	adminApiKeyService *service.AdminApiKeyService,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 检查 x-api-key header（Admin API Key 认证）
 		apiKey := c.GetHeader("x-api-key")
 		if apiKey != "" {
-			if !validateAdminApiKey(c, apiKey, settingService, userService) {
+			if !validateAdminApiKey(c, apiKey, adminApiKeyService, userService) {
 				return
 			}
+			setAuditActor(c)
 			c.Next()
 			return
 		}
@@ -44,9 +48,10 @@ func adminAuth(
 		if authHeader != "" {
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) == 2 && parts[0] == "Bearer" {
-				if !validateJWTForAdmin(c, parts[1], authService, userService) {
+				if !validateJWTForAdmin(c, parts[1], authService) {
 					return
 				}
+				setAuditActor(c)
 				c.Next()
 				return
 			}
@@ -57,25 +62,24 @@ func adminAuth(
 	}
 }
 
-// validateAdminApiKey 验证管理员 API Key
+// validateAdminApiKey 验证管理员 API Key，权限由 Key 的 scope 决定
 func validateAdminApiKey(
 	c *gin.Context,
-	key string,
-	settingService *service.SettingService,
+	raw string,
+	adminApiKeyService *service.AdminApiKeyService,
 	userService *service.UserService,
 ) bool {
-	storedKey, err := settingService.GetAdminApiKey(c.Request.Context())
+	key, err := adminApiKeyService.Authenticate(c.Request.Context(), raw)
 	if err != nil {
+		// 未配置、不匹配、过期或已吊销，统一返回相同错误（避免信息泄露）
+		if errors.Is(err, service.ErrAdminApiKeyInvalid) {
+			AbortWithError(c, 401, "INVALID_ADMIN_KEY", "Invalid admin API key")
+			return false
+		}
 		AbortWithError(c, 500, "INTERNAL_ERROR", "Internal server error")
 		return false
 	}
 
-	// 未配置或不匹配，统一返回相同错误（避免信息泄露）
-	if storedKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(storedKey)) != 1 {
-		AbortWithError(c, 401, "INVALID_ADMIN_KEY", "Invalid admin API key")
-		return false
-	}
-
 	// 获取真实的管理员用户
 	admin, err := userService.GetFirstAdmin(c.Request.Context())
 	if err != nil {
@@ -87,8 +91,10 @@ func validateAdminApiKey(
 		UserID:      admin.ID,
 		Concurrency: admin.Concurrency,
 	})
-	c.Set(string(ContextKeyUserRole), admin.Role)
+	// API Key 的权限由其 scope 决定，而不是关联的管理员账号
+	c.Set(string(ContextKeyUserRole), key.Role())
 	c.Set("auth_method", "admin_api_key")
+	c.Set(adminApiKeyIDContextKey, key.ID)
 	return true
 }
 
@@ -97,23 +103,11 @@ func validateJWTForAdmin(
 	c *gin.Context,
 	token string,
 	authService *service.AuthService,
-	userService *service.UserService,
 ) bool {
-	// 验证 JWT token
-	claims, err := authService.ValidateToken(token)
+	// 验证 JWT token 并获取用户
+	_, user, err := authService.ValidateToken(c.Request.Context(), token)
 	if err != nil {
-		if errors.Is(err, service.ErrTokenExpired) {
-			AbortWithError(c, 401, "TOKEN_EXPIRED", "Token has expired")
-			return false
-		}
-		AbortWithError(c, 401, "INVALID_TOKEN", "Invalid token")
-		return false
-	}
-
-	// 从数据库获取用户
-	user, err := userService.GetByID(c.Request.Context(), claims.UserID)
-	if err != nil {
-		AbortWithError(c, 401, "USER_NOT_FOUND", "User not found")
+		abortWithTokenError(c, err)
 		return false
 	}
 
@@ -123,8 +117,8 @@ func validateJWTForAdmin(
 		return false
 	}
 
-	// 检查管理员权限
-	if !user.IsAdmin() {
+	// 检查管理后台访问权限，具体操作权限由 RequirePermission 检查
+	if !user.HasAdminAccess() {
 		AbortWithError(c, 403, "FORBIDDEN", "Admin access required")
 		return false
 	}
@@ -138,3 +132,20 @@ func validateJWTForAdmin(
 
 	return true
 }
+
+// setAuditActor 将操作人写入 request context
+func setAuditActor(c *gin.Context) {
+	subject, _ := GetAuthSubjectFromContext(c)
+	role, _ := GetUserRoleFromContext(c)
+	actor := &service.AuditActor{
+		UserID:     subject.UserID,
+		Role:       role,
+		AuthMethod: c.GetString("auth_method"),
+		IP:         c.ClientIP(),
+	}
+	if keyID, ok := c.Get(adminApiKeyIDContextKey); ok {
+		id := keyID.(int64)
+		actor.AdminApiKeyID = &id
+	}
+	c.Request = c.Request.WithContext(service.WithAuditActor(c.Request.Context(), actor))
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AdminOnly 管理员权限中间件
+// AdminOnly 超级管理员权限中间件
 // 必须在JWTAuth中间件之后使用
 func AdminOnly() gin.HandlerFunc {
 	return func(c *gin.Context) {
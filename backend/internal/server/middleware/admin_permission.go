@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 检查当前管理角色是否具备指定权限
+// 必须在 AdminAuth 中间件之后使用
+func RequirePermission(permission service.AdminPermission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := GetUserRoleFromContext(c)
+		if !ok {
+			AbortWithError(c, 401, "UNAUTHORIZED", "User not found in context")
+			return
+		}
+
+		if !service.RoleHasPermission(role, permission) {
+			AbortWithError(c, 403, "PERMISSION_DENIED", "Permission required: "+string(permission))
+			return
+		}
+
+		c.Next()
+	}
+}
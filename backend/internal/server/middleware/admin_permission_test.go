@@ -0,0 +1,44 @@
+//go:build unit
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(role string) *gin.Engine {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			if role != "" {
+				c.Set(string(ContextKeyUserRole), role)
+			}
+			c.Next()
+		})
+		r.POST("/accounts", RequirePermission(service.PermissionAccountsManage), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return r
+	}
+
+	cases := map[string]int{
+		service.RoleAdmin:           http.StatusOK,
+		service.RoleAccountOperator: http.StatusOK,
+		service.RoleBillingOperator: http.StatusForbidden,
+		service.RoleViewer:          http.StatusForbidden,
+		"":                          http.StatusUnauthorized,
+	}
+	for role, want := range cases {
+		w := httptest.NewRecorder()
+		newRouter(role).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/accounts", nil))
+		require.Equal(t, want, w.Code, role)
+	}
+}
@@ -2,22 +2,46 @@ package middleware
 
 import (
 	"errors"
-	"log"
+	"log/slog"
+	"net/http"
 	"strings"
 
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tracing"
 	"github.com/Wei-Shaw/sub2api/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // NewApiKeyAuthMiddleware 创建 API Key 认证中间件
-func NewApiKeyAuthMiddleware(apiKeyService *service.ApiKeyService, subscriptionService *service.SubscriptionService) ApiKeyAuthMiddleware {
-	return ApiKeyAuthMiddleware(apiKeyAuthWithSubscription(apiKeyService, subscriptionService))
+func NewApiKeyAuthMiddleware(apiKeyService *service.ApiKeyService, subscriptionService *service.SubscriptionService, apiKeyLimitService *service.ApiKeyLimitService, organizationService *service.OrganizationService) ApiKeyAuthMiddleware {
+	return ApiKeyAuthMiddleware(apiKeyAuthWithSubscription(apiKeyService, subscriptionService, apiKeyLimitService, organizationService))
 }
 
 // apiKeyAuthWithSubscription API Key认证中间件（支持订阅验证）
-func apiKeyAuthWithSubscription(apiKeyService *service.ApiKeyService, subscriptionService *service.SubscriptionService) gin.HandlerFunc {
+func apiKeyAuthWithSubscription(apiKeyService *service.ApiKeyService, subscriptionService *service.SubscriptionService, apiKeyLimitService *service.ApiKeyLimitService, organizationService *service.OrganizationService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 认证阶段单独记录 span，进入后续 handler 前结束并恢复父 context
+		parentCtx := c.Request.Context()
+		ctx, span := tracing.Start(parentCtx, "apiKeyAuthWithSubscription")
+		c.Request = c.Request.WithContext(ctx)
+		spanEnded := false
+		endSpan := func() {
+			if spanEnded {
+				return
+			}
+			spanEnded = true
+			if c.IsAborted() {
+				span.SetStatus(codes.Error, "authentication rejected")
+			}
+			span.End()
+			c.Request = c.Request.WithContext(parentCtx)
+		}
+		defer endSpan()
+
 		// 尝试从Authorization header中提取API key (Bearer scheme)
 		authHeader := c.GetHeader("Authorization")
 		var apiKeyString string
@@ -63,6 +87,10 @@ func apiKeyAuthWithSubscription(apiKeyService *service.ApiKeyService, subscripti
 				AbortWithError(c, 401, "INVALID_API_KEY", "Invalid API key")
 				return
 			}
+			if errors.Is(err, service.ErrApiKeyExpired) {
+				AbortWithError(c, 401, "API_KEY_EXPIRED", "API key has expired")
+				return
+			}
 			AbortWithError(c, 500, "INTERNAL_ERROR", "Failed to validate API key")
 			return
 		}
@@ -85,16 +113,22 @@ func apiKeyAuthWithSubscription(apiKeyService *service.ApiKeyService, subscripti
 			return
 		}
 
+		// 组织 Key：校验组织状态、成员资格、组织余额与成员花费上限
+		isOrganizationKey := apiKey.IsOrganizationOwned()
+		if isOrganizationKey && organizationService != nil {
+			if err := organizationService.CheckApiKeyAccess(c.Request.Context(), apiKey); err != nil {
+				status, code, message := organizationAccessError(err)
+				AbortWithError(c, status, code, message)
+				return
+			}
+		}
+
 		// 判断计费方式：订阅模式 vs 余额模式
 		isSubscriptionType := apiKey.Group != nil && apiKey.Group.IsSubscriptionType()
 
 		if isSubscriptionType && subscriptionService != nil {
-			// 订阅模式：验证订阅
-			subscription, err := subscriptionService.GetActiveSubscription(
-				c.Request.Context(),
-				apiKey.User.ID,
-				apiKey.Group.ID,
-			)
+			// 订阅模式：验证订阅（组织 Key 使用组织共享订阅）
+			subscription, err := subscriptionService.GetActiveSubscriptionForApiKey(c.Request.Context(), apiKey)
 			if err != nil {
 				AbortWithError(c, 403, "SUBSCRIPTION_NOT_FOUND", "No active subscription found for this group")
 				return
@@ -108,12 +142,12 @@ func apiKeyAuthWithSubscription(apiKeyService *service.ApiKeyService, subscripti
 
 			// 激活滑动窗口（首次使用时）
 			if err := subscriptionService.CheckAndActivateWindow(c.Request.Context(), subscription); err != nil {
-				log.Printf("Failed to activate subscription windows: %v", err)
+				logger.FromContext(c.Request.Context()).Warn("activate subscription windows failed", "subscription_id", subscription.ID, logger.Err(err))
 			}
 
 			// 检查并重置过期窗口
 			if err := subscriptionService.CheckAndResetWindows(c.Request.Context(), subscription); err != nil {
-				log.Printf("Failed to reset subscription windows: %v", err)
+				logger.FromContext(c.Request.Context()).Warn("reset subscription windows failed", "subscription_id", subscription.ID, logger.Err(err))
 			}
 
 			// 预检查用量限制（使用0作为额外费用进行预检查）
@@ -124,14 +158,20 @@ func apiKeyAuthWithSubscription(apiKeyService *service.ApiKeyService, subscripti
 
 			// 将订阅信息存入上下文
 			c.Set(string(ContextKeySubscription), subscription)
-		} else {
-			// 余额模式：检查用户余额
+		} else if !isOrganizationKey {
+			// 余额模式：检查用户余额（组织 Key 已在上面检查组织余额）
 			if apiKey.User.Balance <= 0 {
 				AbortWithError(c, 403, "INSUFFICIENT_BALANCE", "Insufficient account balance")
 				return
 			}
 		}
 
+		// 检查 API Key 级别的限流与花费上限
+		if err := apiKeyLimitService.CheckLimits(c.Request.Context(), apiKey); err != nil {
+			abortWithApiKeyLimitError(c, err)
+			return
+		}
+
 		// 将API key和用户信息存入上下文
 		c.Set(string(ContextKeyApiKey), apiKey)
 		c.Set(string(ContextKeyUser), AuthSubject{
@@ -140,10 +180,77 @@ func apiKeyAuthWithSubscription(apiKeyService *service.ApiKeyService, subscripti
 		})
 		c.Set(string(ContextKeyUserRole), apiKey.User.Role)
 
+		span.SetAttributes(
+			attribute.Int64("user.id", apiKey.User.ID),
+			attribute.Int64("api_key.id", apiKey.ID),
+		)
+		endSpan()
+		// 后续选号直接使用已加载分组的调度策略
+		c.Request = c.Request.WithContext(service.WithSchedulingGroup(c.Request.Context(), apiKey.Group))
+		logger.AddAttrs(c.Request.Context(),
+			slog.Int64("api_key_id", apiKey.ID),
+			slog.Int64("user_id", apiKey.User.ID),
+		)
+
 		c.Next()
 	}
 }
 
+// organizationAccessError 将组织 Key 的校验错误映射为响应状态码与错误码
+// 非成员、组织不存在等情况统一按 403 返回，避免泄露组织信息
+func organizationAccessError(err error) (int, string, string) {
+	if errors.Is(err, service.ErrInsufficientBalance) {
+		return http.StatusForbidden, "INSUFFICIENT_BALANCE", "Insufficient organization balance"
+	}
+	appErr := infraerrors.FromError(err)
+	if appErr == nil || appErr.Code >= http.StatusInternalServerError {
+		return http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to validate organization access"
+	}
+	return http.StatusForbidden, appErr.Reason, appErr.Message
+}
+
+// abortWithApiKeyLimitError 返回与上游格式一致的 429 错误（OpenAI 路径使用 OpenAI 格式，其余使用 Claude 格式）
+func abortWithApiKeyLimitError(c *gin.Context, err error) {
+	message := infraerrors.Message(err)
+	setRetryAfterHeader(c, err)
+
+	if isOpenAIPath(c.Request.URL.Path) {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": gin.H{
+				"type":    "rate_limit_error",
+				"code":    "rate_limit_exceeded",
+				"message": message,
+			},
+		})
+	} else {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"type": "error",
+			"error": gin.H{
+				"type":    "rate_limit_error",
+				"message": message,
+			},
+		})
+	}
+	c.Abort()
+}
+
+// setRetryAfterHeader 将限流错误中的重试等待秒数写入 Retry-After 头
+func setRetryAfterHeader(c *gin.Context, err error) {
+	if appErr := infraerrors.FromError(err); appErr != nil {
+		if retryAfter := appErr.Metadata[service.ApiKeyLimitRetryAfterKey]; retryAfter != "" {
+			c.Header("Retry-After", retryAfter)
+		}
+	}
+}
+
+func isOpenAIPath(path string) bool {
+	switch path {
+	case "/v1/chat/completions", "/v1/responses", "/responses":
+		return true
+	}
+	return false
+}
+
 // GetApiKeyFromContext 从上下文中获取API key
 func GetApiKeyFromContext(c *gin.Context) (*service.ApiKey, bool) {
 	value, exists := c.Get(string(ContextKeyApiKey))
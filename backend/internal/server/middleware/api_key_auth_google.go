@@ -2,9 +2,12 @@ package middleware
 
 import (
 	"errors"
+	"log/slog"
 	"strings"
 
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/googleapi"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 	"github.com/Wei-Shaw/sub2api/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -13,14 +16,14 @@ import (
 
 // ApiKeyAuthGoogle is a Google-style error wrapper for API key auth.
 func ApiKeyAuthGoogle(apiKeyService *service.ApiKeyService) gin.HandlerFunc {
-	return ApiKeyAuthWithSubscriptionGoogle(apiKeyService, nil)
+	return ApiKeyAuthWithSubscriptionGoogle(apiKeyService, nil, nil, nil)
 }
 
 // ApiKeyAuthWithSubscriptionGoogle behaves like ApiKeyAuthWithSubscription but returns Google-style errors:
 // {"error":{"code":401,"message":"...","status":"UNAUTHENTICATED"}}
 //
 // It is intended for Gemini native endpoints (/v1beta) to match Gemini SDK expectations.
-func ApiKeyAuthWithSubscriptionGoogle(apiKeyService *service.ApiKeyService, subscriptionService *service.SubscriptionService) gin.HandlerFunc {
+func ApiKeyAuthWithSubscriptionGoogle(apiKeyService *service.ApiKeyService, subscriptionService *service.SubscriptionService, apiKeyLimitService *service.ApiKeyLimitService, organizationService *service.OrganizationService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKeyString := extractAPIKeyFromRequest(c)
 		if apiKeyString == "" {
@@ -34,6 +37,10 @@ func ApiKeyAuthWithSubscriptionGoogle(apiKeyService *service.ApiKeyService, subs
 				abortWithGoogleError(c, 401, "Invalid API key")
 				return
 			}
+			if errors.Is(err, service.ErrApiKeyExpired) {
+				abortWithGoogleError(c, 401, "API key has expired")
+				return
+			}
 			abortWithGoogleError(c, 500, "Failed to validate API key")
 			return
 		}
@@ -51,13 +58,18 @@ func ApiKeyAuthWithSubscriptionGoogle(apiKeyService *service.ApiKeyService, subs
 			return
 		}
 
+		isOrganizationKey := apiKey.IsOrganizationOwned()
+		if isOrganizationKey && organizationService != nil {
+			if err := organizationService.CheckApiKeyAccess(c.Request.Context(), apiKey); err != nil {
+				status, _, message := organizationAccessError(err)
+				abortWithGoogleError(c, status, message)
+				return
+			}
+		}
+
 		isSubscriptionType := apiKey.Group != nil && apiKey.Group.IsSubscriptionType()
 		if isSubscriptionType && subscriptionService != nil {
-			subscription, err := subscriptionService.GetActiveSubscription(
-				c.Request.Context(),
-				apiKey.User.ID,
-				apiKey.Group.ID,
-			)
+			subscription, err := subscriptionService.GetActiveSubscriptionForApiKey(c.Request.Context(), apiKey)
 			if err != nil {
 				abortWithGoogleError(c, 403, "No active subscription found for this group")
 				return
@@ -73,19 +85,30 @@ func ApiKeyAuthWithSubscriptionGoogle(apiKeyService *service.ApiKeyService, subs
 				return
 			}
 			c.Set(string(ContextKeySubscription), subscription)
-		} else {
+		} else if !isOrganizationKey {
 			if apiKey.User.Balance <= 0 {
 				abortWithGoogleError(c, 403, "Insufficient account balance")
 				return
 			}
 		}
 
+		if err := apiKeyLimitService.CheckLimits(c.Request.Context(), apiKey); err != nil {
+			setRetryAfterHeader(c, err)
+			abortWithGoogleError(c, 429, infraerrors.Message(err))
+			return
+		}
+
 		c.Set(string(ContextKeyApiKey), apiKey)
 		c.Set(string(ContextKeyUser), AuthSubject{
 			UserID:      apiKey.User.ID,
 			Concurrency: apiKey.User.Concurrency,
 		})
 		c.Set(string(ContextKeyUserRole), apiKey.User.Role)
+		c.Request = c.Request.WithContext(service.WithSchedulingGroup(c.Request.Context(), apiKey.Group))
+		logger.AddAttrs(c.Request.Context(),
+			slog.Int64("api_key_id", apiKey.ID),
+			slog.Int64("user_id", apiKey.User.ID),
+		)
 		c.Next()
 	}
 }
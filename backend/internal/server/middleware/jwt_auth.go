@@ -10,12 +10,12 @@ import (
 )
 
 // NewJWTAuthMiddleware 创建 JWT 认证中间件
-func NewJWTAuthMiddleware(authService *service.AuthService, userService *service.UserService) JWTAuthMiddleware {
-	return JWTAuthMiddleware(jwtAuth(authService, userService))
+func NewJWTAuthMiddleware(authService *service.AuthService) JWTAuthMiddleware {
+	return JWTAuthMiddleware(jwtAuth(authService))
 }
 
 // jwtAuth JWT认证中间件实现
-func jwtAuth(authService *service.AuthService, userService *service.UserService) gin.HandlerFunc {
+func jwtAuth(authService *service.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从Authorization header中提取token
 		authHeader := c.GetHeader("Authorization")
@@ -37,21 +37,10 @@ func jwtAuth(authService *service.AuthService, userService *service.UserService)
 			return
 		}
 
-		// 验证token
-		claims, err := authService.ValidateToken(tokenString)
+		// 验证token并获取最新的用户信息
+		_, user, err := authService.ValidateToken(c.Request.Context(), tokenString)
 		if err != nil {
-			if errors.Is(err, service.ErrTokenExpired) {
-				AbortWithError(c, 401, "TOKEN_EXPIRED", "Token has expired")
-				return
-			}
-			AbortWithError(c, 401, "INVALID_TOKEN", "Invalid token")
-			return
-		}
-
-		// 从数据库获取最新的用户信息
-		user, err := userService.GetByID(c.Request.Context(), claims.UserID)
-		if err != nil {
-			AbortWithError(c, 401, "USER_NOT_FOUND", "User not found")
+			abortWithTokenError(c, err)
 			return
 		}
 
@@ -71,4 +60,20 @@ func jwtAuth(authService *service.AuthService, userService *service.UserService)
 	}
 }
 
+// abortWithTokenError 将 token 校验错误映射为 401 响应
+func abortWithTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTokenExpired):
+		AbortWithError(c, 401, "TOKEN_EXPIRED", "Token has expired")
+	case errors.Is(err, service.ErrTokenRevoked):
+		AbortWithError(c, 401, "TOKEN_REVOKED", "Token has been revoked")
+	case errors.Is(err, service.ErrUserNotFound):
+		AbortWithError(c, 401, "USER_NOT_FOUND", "User not found")
+	case errors.Is(err, service.ErrServiceUnavailable):
+		AbortWithError(c, 503, "SERVICE_UNAVAILABLE", "Service temporarily unavailable")
+	default:
+		AbortWithError(c, 401, "INVALID_TOKEN", "Invalid token")
+	}
+}
+
 // Deprecated: prefer GetAuthSubjectFromContext in auth_subject.go.
@@ -1,52 +1,68 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
+	"regexp"
 	"time"
 
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tracing"
+
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
+// RequestIDHeader 请求 ID 请求/响应头
+const RequestIDHeader = "X-Request-Id"
+
+// validRequestID 客户端传入的请求 ID 仅接受有限长度的安全字符，避免日志注入
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
 // Logger 请求日志中间件
-func Logger() gin.HandlerFunc {
+// 为每个请求生成 request_id 并创建请求级 logger（后续中间件/handler/service 通过
+// logger.FromContext 获取，并可用 logger.AddAttrs 补充 user_id、account_id 等字段），
+// 请求结束时输出一条访问日志。
+func Logger(l *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 开始时间
 		startTime := time.Now()
 
-		// 处理请求
-		c.Next()
-
-		// 结束时间
-		endTime := time.Now()
-
-		// 执行时间
-		latency := endTime.Sub(startTime)
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, requestID)
+		c.Set(string(ContextKeyRequestID), requestID)
 
-		// 请求方法
-		method := c.Request.Method
+		attrs := []slog.Attr{slog.String("request_id", requestID)}
+		if traceID := tracing.TraceID(c.Request.Context()); traceID != "" {
+			attrs = append(attrs, slog.String("trace_id", traceID))
+		}
+		ctx := logger.NewContext(c.Request.Context(), l, attrs...)
+		c.Request = c.Request.WithContext(ctx)
 
-		// 请求路径
-		path := c.Request.URL.Path
+		// 处理请求
+		c.Next()
 
-		// 状态码
 		statusCode := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case statusCode >= 500:
+			level = slog.LevelError
+		case statusCode >= 400:
+			level = slog.LevelWarn
+		}
 
-		// 客户端IP
-		clientIP := c.ClientIP()
-
-		// 日志格式: [时间] 状态码 | 延迟 | IP | 方法 路径
-		log.Printf("[GIN] %v | %3d | %13v | %15s | %-7s %s",
-			endTime.Format("2006/01/02 - 15:04:05"),
-			statusCode,
-			latency,
-			clientIP,
-			method,
-			path,
-		)
-
+		fields := []slog.Attr{
+			slog.Int("status", statusCode),
+			slog.Duration("latency", time.Since(startTime)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+		}
 		// 如果有错误，额外记录错误信息
 		if len(c.Errors) > 0 {
-			log.Printf("[GIN] Errors: %v", c.Errors.String())
+			fields = append(fields, slog.String("errors", c.Errors.String()))
 		}
+		logger.FromContext(ctx).LogAttrs(ctx, level, "http request", fields...)
 	}
 }
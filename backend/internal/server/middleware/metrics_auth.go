@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewMetricsAuthMiddleware 创建 /metrics 认证中间件
+func NewMetricsAuthMiddleware(cfg *config.Config, adminApiKeyService *service.AdminApiKeyService) MetricsAuthMiddleware {
+	return MetricsAuthMiddleware(metricsAuth(cfg, adminApiKeyService))
+}
+
+// metricsAuth /metrics 认证中间件实现
+// 启用 require_admin_key 时，支持以下两种方式携带管理员 API Key（便于 Prometheus 抓取配置）：
+// 1. x-api-key: <admin-api-key>
+// 2. Authorization: Bearer <admin-api-key>
+func metricsAuth(cfg *config.Config, adminApiKeyService *service.AdminApiKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Metrics.Enabled {
+			AbortWithError(c, 404, "NOT_FOUND", "Metrics are disabled")
+			return
+		}
+		if !cfg.Metrics.RequireAdminKey {
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader("x-api-key")
+		if key == "" {
+			if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+				key = strings.TrimPrefix(auth, "Bearer ")
+			}
+		}
+		if key == "" {
+			AbortWithError(c, 401, "UNAUTHORIZED", "Admin API key required")
+			return
+		}
+
+		// 任意有效的 Admin API Key 均可读取指标
+		if _, err := adminApiKeyService.Authenticate(c.Request.Context(), key); err != nil {
+			if errors.Is(err, service.ErrAdminApiKeyInvalid) {
+				AbortWithError(c, 401, "INVALID_ADMIN_KEY", "Invalid admin API key")
+				return
+			}
+			AbortWithError(c, 500, "INTERNAL_ERROR", "Internal server error")
+			return
+		}
+
+		c.Next()
+	}
+}
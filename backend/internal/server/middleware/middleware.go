@@ -14,6 +14,8 @@ const (
 	ContextKeyApiKey ContextKey = "api_key"
 	// ContextKeySubscription 订阅上下文键
 	ContextKeySubscription ContextKey = "subscription"
+	// ContextKeyRequestID 请求 ID（string）
+	ContextKeyRequestID ContextKey = "request_id"
 )
 
 // ErrorResponse 标准错误响应结构
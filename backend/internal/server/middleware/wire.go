@@ -14,9 +14,13 @@ type AdminAuthMiddleware gin.HandlerFunc
 // ApiKeyAuthMiddleware API Key 认证中间件类型
 type ApiKeyAuthMiddleware gin.HandlerFunc
 
+// MetricsAuthMiddleware /metrics 认证中间件类型
+type MetricsAuthMiddleware gin.HandlerFunc
+
 // ProviderSet 中间件层的依赖注入
 var ProviderSet = wire.NewSet(
 	NewJWTAuthMiddleware,
 	NewAdminAuthMiddleware,
 	NewApiKeyAuthMiddleware,
+	NewMetricsAuthMiddleware,
 )
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"log/slog"
+
 	"github.com/Wei-Shaw/sub2api/internal/handler"
 	middleware2 "github.com/Wei-Shaw/sub2api/internal/server/middleware"
 	"github.com/Wei-Shaw/sub2api/internal/server/routes"
@@ -13,15 +15,21 @@ import (
 // SetupRouter 配置路由器中间件和路由
 func SetupRouter(
 	r *gin.Engine,
+	log *slog.Logger,
 	handlers *handler.Handlers,
 	jwtAuth middleware2.JWTAuthMiddleware,
 	adminAuth middleware2.AdminAuthMiddleware,
 	apiKeyAuth middleware2.ApiKeyAuthMiddleware,
 	apiKeyService *service.ApiKeyService,
 	subscriptionService *service.SubscriptionService,
+	apiKeyLimitService *service.ApiKeyLimitService,
+	organizationService *service.OrganizationService,
+	metricsAuth middleware2.MetricsAuthMiddleware,
 ) *gin.Engine {
 	// 应用中间件
-	r.Use(middleware2.Logger())
+	// Tracing 在前，使请求日志可以带上 trace_id
+	r.Use(middleware2.Tracing())
+	r.Use(middleware2.Logger(log))
 	r.Use(middleware2.CORS())
 
 	// Serve embedded frontend if available
@@ -30,7 +38,7 @@ func SetupRouter(
 	}
 
 	// 注册路由
-	registerRoutes(r, handlers, jwtAuth, adminAuth, apiKeyAuth, apiKeyService, subscriptionService)
+	registerRoutes(r, handlers, jwtAuth, adminAuth, apiKeyAuth, apiKeyService, subscriptionService, apiKeyLimitService, organizationService, metricsAuth)
 
 	return r
 }
@@ -44,10 +52,16 @@ func registerRoutes(
 	apiKeyAuth middleware2.ApiKeyAuthMiddleware,
 	apiKeyService *service.ApiKeyService,
 	subscriptionService *service.SubscriptionService,
+	apiKeyLimitService *service.ApiKeyLimitService,
+	organizationService *service.OrganizationService,
+	metricsAuth middleware2.MetricsAuthMiddleware,
 ) {
 	// 通用路由（健康检查、状态等）
 	routes.RegisterCommonRoutes(r)
 
+	// Prometheus 指标
+	routes.RegisterMetricsRoutes(r, h, metricsAuth)
+
 	// API v1
 	v1 := r.Group("/api/v1")
 
@@ -55,5 +69,5 @@ func registerRoutes(
 	routes.RegisterAuthRoutes(v1, h, jwtAuth)
 	routes.RegisterUserRoutes(v1, h, jwtAuth)
 	routes.RegisterAdminRoutes(v1, h, adminAuth)
-	routes.RegisterGatewayRoutes(r, h, apiKeyAuth, apiKeyService, subscriptionService)
+	routes.RegisterGatewayRoutes(r, h, apiKeyAuth, apiKeyService, subscriptionService, apiKeyLimitService, organizationService)
 }
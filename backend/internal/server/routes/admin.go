@@ -3,11 +3,13 @@ package routes
 import (
 	"github.com/Wei-Shaw/sub2api/internal/handler"
 	"github.com/Wei-Shaw/sub2api/internal/server/middleware"
+	"github.com/Wei-Shaw/sub2api/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterAdminRoutes 注册管理员路由
+// 每个路由通过 RequirePermission 声明所需权限，角色与权限的对应关系见 service.RoleHasPermission
 func RegisterAdminRoutes(
 	v1 *gin.RouterGroup,
 	h *handler.Handlers,
@@ -22,6 +24,9 @@ func RegisterAdminRoutes(
 		// 用户管理
 		registerUserManagementRoutes(admin, h)
 
+		// 组织管理
+		registerOrganizationRoutes(admin, h)
+
 		// 分组管理
 		registerGroupRoutes(admin, h)
 
@@ -51,183 +56,282 @@ func RegisterAdminRoutes(
 
 		// 使用记录管理
 		registerUsageRoutes(admin, h)
+
+		// 余额流水
+		registerBalanceRoutes(admin, h)
+
+		// 请求抓取
+		registerCaptureRoutes(admin, h)
+
+		// 审计日志
+		registerAuditRoutes(admin, h)
 	}
 }
 
 func registerDashboardRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canView := middleware.RequirePermission(service.PermissionDashboardView)
+
 	dashboard := admin.Group("/dashboard")
 	{
-		dashboard.GET("/stats", h.Admin.Dashboard.GetStats)
-		dashboard.GET("/realtime", h.Admin.Dashboard.GetRealtimeMetrics)
-		dashboard.GET("/trend", h.Admin.Dashboard.GetUsageTrend)
-		dashboard.GET("/models", h.Admin.Dashboard.GetModelStats)
-		dashboard.GET("/api-keys-trend", h.Admin.Dashboard.GetApiKeyUsageTrend)
-		dashboard.GET("/users-trend", h.Admin.Dashboard.GetUserUsageTrend)
-		dashboard.POST("/users-usage", h.Admin.Dashboard.GetBatchUsersUsage)
-		dashboard.POST("/api-keys-usage", h.Admin.Dashboard.GetBatchApiKeysUsage)
+		dashboard.GET("/stats", canView, h.Admin.Dashboard.GetStats)
+		dashboard.GET("/realtime", canView, h.Admin.Dashboard.GetRealtimeMetrics)
+		dashboard.GET("/live", canView, h.Admin.Dashboard.StreamLiveEvents)
+		dashboard.GET("/trend", canView, h.Admin.Dashboard.GetUsageTrend)
+		dashboard.GET("/models", canView, h.Admin.Dashboard.GetModelStats)
+		dashboard.GET("/api-keys-trend", canView, h.Admin.Dashboard.GetApiKeyUsageTrend)
+		dashboard.GET("/users-trend", canView, h.Admin.Dashboard.GetUserUsageTrend)
+		dashboard.POST("/users-usage", canView, h.Admin.Dashboard.GetBatchUsersUsage)
+		dashboard.POST("/api-keys-usage", canView, h.Admin.Dashboard.GetBatchApiKeysUsage)
 	}
 }
 
 func registerUserManagementRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canView := middleware.RequirePermission(service.PermissionUsersView)
+	canManage := middleware.RequirePermission(service.PermissionUsersManage)
+	canManageBalance := middleware.RequirePermission(service.PermissionBalanceManage)
+
 	users := admin.Group("/users")
 	{
-		users.GET("", h.Admin.User.List)
-		users.GET("/:id", h.Admin.User.GetByID)
-		users.POST("", h.Admin.User.Create)
-		users.PUT("/:id", h.Admin.User.Update)
-		users.DELETE("/:id", h.Admin.User.Delete)
-		users.POST("/:id/balance", h.Admin.User.UpdateBalance)
-		users.GET("/:id/api-keys", h.Admin.User.GetUserAPIKeys)
-		users.GET("/:id/usage", h.Admin.User.GetUserUsage)
+		users.GET("", canView, h.Admin.User.List)
+		users.GET("/:id", canView, h.Admin.User.GetByID)
+		users.POST("", canManage, h.Admin.User.Create)
+		users.PUT("/:id", canManage, h.Admin.User.Update)
+		users.DELETE("/:id", canManage, h.Admin.User.Delete)
+		users.POST("/:id/balance", canManageBalance, h.Admin.User.UpdateBalance)
+		users.GET("/:id/api-keys", canView, h.Admin.User.GetUserAPIKeys)
+		users.GET("/:id/usage", canView, h.Admin.User.GetUserUsage)
+	}
+}
+
+func registerOrganizationRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canView := middleware.RequirePermission(service.PermissionUsersView)
+	canManage := middleware.RequirePermission(service.PermissionUsersManage)
+	canManageBalance := middleware.RequirePermission(service.PermissionBalanceManage)
+	canManageSubscriptions := middleware.RequirePermission(service.PermissionSubscriptionsManage)
+
+	orgs := admin.Group("/organizations")
+	{
+		orgs.GET("", canView, h.Admin.Organization.List)
+		orgs.GET("/:id", canView, h.Admin.Organization.GetByID)
+		orgs.PUT("/:id/status", canManage, h.Admin.Organization.UpdateStatus)
+		orgs.POST("/:id/balance", canManageBalance, h.Admin.Organization.AdjustBalance)
+		orgs.POST("/:id/subscriptions", canManageSubscriptions, h.Admin.Organization.AssignSubscription)
 	}
 }
 
 func registerGroupRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canView := middleware.RequirePermission(service.PermissionGroupsView)
+	canManage := middleware.RequirePermission(service.PermissionGroupsManage)
+
 	groups := admin.Group("/groups")
 	{
-		groups.GET("", h.Admin.Group.List)
-		groups.GET("/all", h.Admin.Group.GetAll)
-		groups.GET("/:id", h.Admin.Group.GetByID)
-		groups.POST("", h.Admin.Group.Create)
-		groups.PUT("/:id", h.Admin.Group.Update)
-		groups.DELETE("/:id", h.Admin.Group.Delete)
-		groups.GET("/:id/stats", h.Admin.Group.GetStats)
-		groups.GET("/:id/api-keys", h.Admin.Group.GetGroupAPIKeys)
+		groups.GET("", canView, h.Admin.Group.List)
+		groups.GET("/all", canView, h.Admin.Group.GetAll)
+		groups.GET("/:id", canView, h.Admin.Group.GetByID)
+		groups.POST("", canManage, h.Admin.Group.Create)
+		groups.PUT("/:id", canManage, h.Admin.Group.Update)
+		groups.DELETE("/:id", canManage, h.Admin.Group.Delete)
+		groups.GET("/:id/stats", canView, h.Admin.Group.GetStats)
+		groups.GET("/:id/api-keys", canView, h.Admin.Group.GetGroupAPIKeys)
 	}
 }
 
 func registerAccountRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canView := middleware.RequirePermission(service.PermissionAccountsView)
+	canManage := middleware.RequirePermission(service.PermissionAccountsManage)
+	canCapture := middleware.RequirePermission(service.PermissionCapturesManage)
+
 	accounts := admin.Group("/accounts")
 	{
-		accounts.GET("", h.Admin.Account.List)
-		accounts.GET("/:id", h.Admin.Account.GetByID)
-		accounts.POST("", h.Admin.Account.Create)
-		accounts.POST("/sync/crs", h.Admin.Account.SyncFromCRS)
-		accounts.PUT("/:id", h.Admin.Account.Update)
-		accounts.DELETE("/:id", h.Admin.Account.Delete)
-		accounts.POST("/:id/test", h.Admin.Account.Test)
-		accounts.POST("/:id/refresh", h.Admin.Account.Refresh)
-		accounts.GET("/:id/stats", h.Admin.Account.GetStats)
-		accounts.POST("/:id/clear-error", h.Admin.Account.ClearError)
-		accounts.GET("/:id/usage", h.Admin.Account.GetUsage)
-		accounts.GET("/:id/today-stats", h.Admin.Account.GetTodayStats)
-		accounts.POST("/:id/clear-rate-limit", h.Admin.Account.ClearRateLimit)
-		accounts.POST("/:id/schedulable", h.Admin.Account.SetSchedulable)
-		accounts.GET("/:id/models", h.Admin.Account.GetAvailableModels)
-		accounts.POST("/batch", h.Admin.Account.BatchCreate)
-		accounts.POST("/batch-update-credentials", h.Admin.Account.BatchUpdateCredentials)
-		accounts.POST("/bulk-update", h.Admin.Account.BulkUpdate)
+		accounts.GET("", canView, h.Admin.Account.List)
+		accounts.GET("/:id", canView, h.Admin.Account.GetByID)
+		accounts.POST("", canManage, h.Admin.Account.Create)
+		accounts.POST("/sync/crs", canManage, h.Admin.Account.SyncFromCRS)
+		accounts.PUT("/:id", canManage, h.Admin.Account.Update)
+		accounts.DELETE("/:id", canManage, h.Admin.Account.Delete)
+		accounts.POST("/:id/test", canManage, h.Admin.Account.Test)
+		accounts.POST("/:id/refresh", canManage, h.Admin.Account.Refresh)
+		accounts.GET("/:id/stats", canView, h.Admin.Account.GetStats)
+		accounts.POST("/:id/clear-error", canManage, h.Admin.Account.ClearError)
+		accounts.GET("/:id/usage", canView, h.Admin.Account.GetUsage)
+		accounts.GET("/:id/today-stats", canView, h.Admin.Account.GetTodayStats)
+		accounts.POST("/:id/clear-rate-limit", canManage, h.Admin.Account.ClearRateLimit)
+		accounts.POST("/:id/schedulable", canManage, h.Admin.Account.SetSchedulable)
+		accounts.GET("/:id/models", canView, h.Admin.Account.GetAvailableModels)
+		accounts.GET("/:id/sticky-sessions", canView, h.Admin.Account.ListStickySessions)
+		accounts.DELETE("/:id/sticky-sessions", canManage, h.Admin.Account.ClearStickySessions)
+		accounts.POST("/:id/capture", canCapture, h.Admin.Capture.SetAccountCapture)
+		accounts.POST("/batch", canManage, h.Admin.Account.BatchCreate)
+		accounts.POST("/batch-update-credentials", canManage, h.Admin.Account.BatchUpdateCredentials)
+		accounts.POST("/bulk-update", canManage, h.Admin.Account.BulkUpdate)
 
 		// Claude OAuth routes
-		accounts.POST("/generate-auth-url", h.Admin.OAuth.GenerateAuthURL)
-		accounts.POST("/generate-setup-token-url", h.Admin.OAuth.GenerateSetupTokenURL)
-		accounts.POST("/exchange-code", h.Admin.OAuth.ExchangeCode)
-		accounts.POST("/exchange-setup-token-code", h.Admin.OAuth.ExchangeSetupTokenCode)
-		accounts.POST("/cookie-auth", h.Admin.OAuth.CookieAuth)
-		accounts.POST("/setup-token-cookie-auth", h.Admin.OAuth.SetupTokenCookieAuth)
+		accounts.POST("/generate-auth-url", canManage, h.Admin.OAuth.GenerateAuthURL)
+		accounts.POST("/generate-setup-token-url", canManage, h.Admin.OAuth.GenerateSetupTokenURL)
+		accounts.POST("/exchange-code", canManage, h.Admin.OAuth.ExchangeCode)
+		accounts.POST("/exchange-setup-token-code", canManage, h.Admin.OAuth.ExchangeSetupTokenCode)
+		accounts.POST("/cookie-auth", canManage, h.Admin.OAuth.CookieAuth)
+		accounts.POST("/setup-token-cookie-auth", canManage, h.Admin.OAuth.SetupTokenCookieAuth)
 	}
 }
 
 func registerOpenAIOAuthRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canManage := middleware.RequirePermission(service.PermissionAccountsManage)
+
 	openai := admin.Group("/openai")
 	{
-		openai.POST("/generate-auth-url", h.Admin.OpenAIOAuth.GenerateAuthURL)
-		openai.POST("/exchange-code", h.Admin.OpenAIOAuth.ExchangeCode)
-		openai.POST("/refresh-token", h.Admin.OpenAIOAuth.RefreshToken)
-		openai.POST("/accounts/:id/refresh", h.Admin.OpenAIOAuth.RefreshAccountToken)
-		openai.POST("/create-from-oauth", h.Admin.OpenAIOAuth.CreateAccountFromOAuth)
+		openai.POST("/generate-auth-url", canManage, h.Admin.OpenAIOAuth.GenerateAuthURL)
+		openai.POST("/exchange-code", canManage, h.Admin.OpenAIOAuth.ExchangeCode)
+		openai.POST("/refresh-token", canManage, h.Admin.OpenAIOAuth.RefreshToken)
+		openai.POST("/accounts/:id/refresh", canManage, h.Admin.OpenAIOAuth.RefreshAccountToken)
+		openai.POST("/create-from-oauth", canManage, h.Admin.OpenAIOAuth.CreateAccountFromOAuth)
 	}
 }
 
 func registerGeminiOAuthRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canManage := middleware.RequirePermission(service.PermissionAccountsManage)
+	canView := middleware.RequirePermission(service.PermissionAccountsView)
+
 	gemini := admin.Group("/gemini")
 	{
-		gemini.POST("/oauth/auth-url", h.Admin.GeminiOAuth.GenerateAuthURL)
-		gemini.POST("/oauth/exchange-code", h.Admin.GeminiOAuth.ExchangeCode)
-		gemini.GET("/oauth/capabilities", h.Admin.GeminiOAuth.GetCapabilities)
+		gemini.POST("/oauth/auth-url", canManage, h.Admin.GeminiOAuth.GenerateAuthURL)
+		gemini.POST("/oauth/exchange-code", canManage, h.Admin.GeminiOAuth.ExchangeCode)
+		gemini.GET("/oauth/capabilities", canView, h.Admin.GeminiOAuth.GetCapabilities)
 	}
 }
 
 func registerProxyRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canView := middleware.RequirePermission(service.PermissionProxiesView)
+	canManage := middleware.RequirePermission(service.PermissionProxiesManage)
+
 	proxies := admin.Group("/proxies")
 	{
-		proxies.GET("", h.Admin.Proxy.List)
-		proxies.GET("/all", h.Admin.Proxy.GetAll)
-		proxies.GET("/:id", h.Admin.Proxy.GetByID)
-		proxies.POST("", h.Admin.Proxy.Create)
-		proxies.PUT("/:id", h.Admin.Proxy.Update)
-		proxies.DELETE("/:id", h.Admin.Proxy.Delete)
-		proxies.POST("/:id/test", h.Admin.Proxy.Test)
-		proxies.GET("/:id/stats", h.Admin.Proxy.GetStats)
-		proxies.GET("/:id/accounts", h.Admin.Proxy.GetProxyAccounts)
-		proxies.POST("/batch", h.Admin.Proxy.BatchCreate)
+		proxies.GET("", canView, h.Admin.Proxy.List)
+		proxies.GET("/all", canView, h.Admin.Proxy.GetAll)
+		proxies.GET("/:id", canView, h.Admin.Proxy.GetByID)
+		proxies.POST("", canManage, h.Admin.Proxy.Create)
+		proxies.PUT("/:id", canManage, h.Admin.Proxy.Update)
+		proxies.DELETE("/:id", canManage, h.Admin.Proxy.Delete)
+		proxies.POST("/:id/test", canManage, h.Admin.Proxy.Test)
+		proxies.GET("/:id/stats", canView, h.Admin.Proxy.GetStats)
+		proxies.GET("/:id/accounts", canView, h.Admin.Proxy.GetProxyAccounts)
+		proxies.POST("/batch", canManage, h.Admin.Proxy.BatchCreate)
 	}
 }
 
 func registerRedeemCodeRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canView := middleware.RequirePermission(service.PermissionRedeemView)
+	canManage := middleware.RequirePermission(service.PermissionRedeemManage)
+
 	codes := admin.Group("/redeem-codes")
 	{
-		codes.GET("", h.Admin.Redeem.List)
-		codes.GET("/stats", h.Admin.Redeem.GetStats)
-		codes.GET("/export", h.Admin.Redeem.Export)
-		codes.GET("/:id", h.Admin.Redeem.GetByID)
-		codes.POST("/generate", h.Admin.Redeem.Generate)
-		codes.DELETE("/:id", h.Admin.Redeem.Delete)
-		codes.POST("/batch-delete", h.Admin.Redeem.BatchDelete)
-		codes.POST("/:id/expire", h.Admin.Redeem.Expire)
+		codes.GET("", canView, h.Admin.Redeem.List)
+		codes.GET("/stats", canView, h.Admin.Redeem.GetStats)
+		codes.GET("/export", canView, h.Admin.Redeem.Export)
+		codes.GET("/:id", canView, h.Admin.Redeem.GetByID)
+		codes.POST("/generate", canManage, h.Admin.Redeem.Generate)
+		codes.DELETE("/:id", canManage, h.Admin.Redeem.Delete)
+		codes.POST("/batch-delete", canManage, h.Admin.Redeem.BatchDelete)
+		codes.POST("/:id/expire", canManage, h.Admin.Redeem.Expire)
 	}
 }
 
 func registerSettingsRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canManage := middleware.RequirePermission(service.PermissionSettingsManage)
+
 	adminSettings := admin.Group("/settings")
 	{
-		adminSettings.GET("", h.Admin.Setting.GetSettings)
-		adminSettings.PUT("", h.Admin.Setting.UpdateSettings)
-		adminSettings.POST("/test-smtp", h.Admin.Setting.TestSmtpConnection)
-		adminSettings.POST("/send-test-email", h.Admin.Setting.SendTestEmail)
-		// Admin API Key 管理
-		adminSettings.GET("/admin-api-key", h.Admin.Setting.GetAdminApiKey)
-		adminSettings.POST("/admin-api-key/regenerate", h.Admin.Setting.RegenerateAdminApiKey)
-		adminSettings.DELETE("/admin-api-key", h.Admin.Setting.DeleteAdminApiKey)
+		adminSettings.GET("", canManage, h.Admin.Setting.GetSettings)
+		adminSettings.PUT("", canManage, h.Admin.Setting.UpdateSettings)
+		adminSettings.POST("/test-smtp", canManage, h.Admin.Setting.TestSmtpConnection)
+		adminSettings.POST("/send-test-email", canManage, h.Admin.Setting.SendTestEmail)
+		adminSettings.POST("/test-webhook", canManage, h.Admin.Setting.TestWebhook)
+		adminSettings.GET("/notifications", canManage, h.Admin.Setting.GetNotificationSettings)
+		adminSettings.PUT("/notifications", canManage, h.Admin.Setting.UpdateNotificationSettings)
+		// 旧版单一 Admin API Key 接口
+		adminSettings.GET("/admin-api-key", canManage, h.Admin.AdminApiKey.GetDefault)
+		adminSettings.POST("/admin-api-key/regenerate", canManage, h.Admin.AdminApiKey.RegenerateDefault)
+		adminSettings.DELETE("/admin-api-key", canManage, h.Admin.AdminApiKey.DeleteDefault)
+	}
+
+	// Admin API Key 管理
+	keys := admin.Group("/admin-api-keys")
+	{
+		keys.GET("", canManage, h.Admin.AdminApiKey.List)
+		keys.POST("", canManage, h.Admin.AdminApiKey.Create)
+		keys.DELETE("/:id", canManage, h.Admin.AdminApiKey.Revoke)
 	}
 }
 
 func registerSystemRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canManage := middleware.RequirePermission(service.PermissionSystemManage)
+
 	system := admin.Group("/system")
 	{
-		system.GET("/version", h.Admin.System.GetVersion)
-		system.GET("/check-updates", h.Admin.System.CheckUpdates)
-		system.POST("/update", h.Admin.System.PerformUpdate)
-		system.POST("/rollback", h.Admin.System.Rollback)
-		system.POST("/restart", h.Admin.System.RestartService)
+		system.GET("/version", canManage, h.Admin.System.GetVersion)
+		system.GET("/check-updates", canManage, h.Admin.System.CheckUpdates)
+		system.POST("/update", canManage, h.Admin.System.PerformUpdate)
+		system.POST("/rollback", canManage, h.Admin.System.Rollback)
+		system.POST("/restart", canManage, h.Admin.System.RestartService)
 	}
 }
 
 func registerSubscriptionRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canView := middleware.RequirePermission(service.PermissionSubscriptionsView)
+	canManage := middleware.RequirePermission(service.PermissionSubscriptionsManage)
+
 	subscriptions := admin.Group("/subscriptions")
 	{
-		subscriptions.GET("", h.Admin.Subscription.List)
-		subscriptions.GET("/:id", h.Admin.Subscription.GetByID)
-		subscriptions.GET("/:id/progress", h.Admin.Subscription.GetProgress)
-		subscriptions.POST("/assign", h.Admin.Subscription.Assign)
-		subscriptions.POST("/bulk-assign", h.Admin.Subscription.BulkAssign)
-		subscriptions.POST("/:id/extend", h.Admin.Subscription.Extend)
-		subscriptions.DELETE("/:id", h.Admin.Subscription.Revoke)
+		subscriptions.GET("", canView, h.Admin.Subscription.List)
+		subscriptions.GET("/:id", canView, h.Admin.Subscription.GetByID)
+		subscriptions.GET("/:id/progress", canView, h.Admin.Subscription.GetProgress)
+		subscriptions.POST("/assign", canManage, h.Admin.Subscription.Assign)
+		subscriptions.POST("/bulk-assign", canManage, h.Admin.Subscription.BulkAssign)
+		subscriptions.POST("/:id/extend", canManage, h.Admin.Subscription.Extend)
+		subscriptions.DELETE("/:id", canManage, h.Admin.Subscription.Revoke)
 	}
 
 	// 分组下的订阅列表
-	admin.GET("/groups/:id/subscriptions", h.Admin.Subscription.ListByGroup)
+	admin.GET("/groups/:id/subscriptions", canView, h.Admin.Subscription.ListByGroup)
 
 	// 用户下的订阅列表
-	admin.GET("/users/:id/subscriptions", h.Admin.Subscription.ListByUser)
+	admin.GET("/users/:id/subscriptions", canView, h.Admin.Subscription.ListByUser)
 }
 
 func registerUsageRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canView := middleware.RequirePermission(service.PermissionUsageView)
+	canManageBalance := middleware.RequirePermission(service.PermissionBalanceManage)
+
 	usage := admin.Group("/usage")
 	{
-		usage.GET("", h.Admin.Usage.List)
-		usage.GET("/stats", h.Admin.Usage.Stats)
-		usage.GET("/search-users", h.Admin.Usage.SearchUsers)
-		usage.GET("/search-api-keys", h.Admin.Usage.SearchApiKeys)
+		usage.GET("", canView, h.Admin.Usage.List)
+		usage.GET("/stats", canView, h.Admin.Usage.Stats)
+		usage.GET("/search-users", canView, h.Admin.Usage.SearchUsers)
+		usage.GET("/search-api-keys", canView, h.Admin.Usage.SearchApiKeys)
+		usage.POST("/:id/refund", canManageBalance, h.Admin.Balance.RefundUsage)
 	}
 }
+
+func registerBalanceRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canView := middleware.RequirePermission(service.PermissionUsageView)
+
+	admin.GET("/balance-transactions", canView, h.Admin.Balance.ListTransactions)
+}
+
+func registerCaptureRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	canManage := middleware.RequirePermission(service.PermissionCapturesManage)
+
+	captures := admin.Group("/captures")
+	{
+		captures.GET("", canManage, h.Admin.Capture.List)
+		captures.GET("/:id", canManage, h.Admin.Capture.GetByID)
+		captures.GET("/:id/download", canManage, h.Admin.Capture.Download)
+		captures.DELETE("/:id", canManage, h.Admin.Capture.Delete)
+		captures.POST("/:id/replay", canManage, h.Admin.Capture.Replay)
+	}
+	admin.POST("/api-keys/:id/capture", canManage, h.Admin.Capture.SetApiKeyCapture)
+}
+
+func registerAuditRoutes(admin *gin.RouterGroup, h *handler.Handlers) {
+	admin.GET("/audit-logs", middleware.RequirePermission(service.PermissionAuditView), h.Admin.Audit.List)
+}
@@ -19,6 +19,19 @@ func RegisterAuthRoutes(
 		auth.POST("/register", h.Auth.Register)
 		auth.POST("/login", h.Auth.Login)
 		auth.POST("/send-verify-code", h.Auth.SendVerifyCode)
+		auth.POST("/forgot-password", h.Auth.ForgotPassword)
+		auth.POST("/reset-password", h.Auth.ResetPassword)
+
+		// OIDC 单点登录
+		auth.GET("/oidc/authorize", h.Auth.OIDCAuthorize)
+		auth.POST("/oidc/callback", h.Auth.OIDCCallback)
+
+		// 登录二次验证（使用预认证 token）
+		auth.POST("/2fa/verify", h.Auth.VerifyTwoFactor)
+		auth.POST("/2fa/webauthn/begin", h.Auth.BeginWebAuthnLogin)
+		auth.POST("/2fa/webauthn/finish", h.Auth.FinishWebAuthnLogin)
+		auth.POST("/2fa/enroll/setup", h.Auth.SetupTwoFactorEnrollment)
+		auth.POST("/2fa/enroll/enable", h.Auth.CompleteTwoFactorEnrollment)
 	}
 
 	// 公开设置（无需认证）
@@ -32,5 +45,7 @@ func RegisterAuthRoutes(
 	authenticated.Use(gin.HandlerFunc(jwtAuth))
 	{
 		authenticated.GET("/auth/me", h.Auth.GetCurrentUser)
+		authenticated.POST("/auth/change-email/send-code", h.Auth.SendChangeEmailCode)
+		authenticated.POST("/auth/change-email", h.Auth.ChangeEmail)
 	}
 }
@@ -15,6 +15,8 @@ func RegisterGatewayRoutes(
 	apiKeyAuth middleware.ApiKeyAuthMiddleware,
 	apiKeyService *service.ApiKeyService,
 	subscriptionService *service.SubscriptionService,
+	apiKeyLimitService *service.ApiKeyLimitService,
+	organizationService *service.OrganizationService,
 ) {
 	// API网关（Claude API兼容）
 	gateway := r.Group("/v1")
@@ -24,13 +26,15 @@ func RegisterGatewayRoutes(
 		gateway.POST("/messages/count_tokens", h.Gateway.CountTokens)
 		gateway.GET("/models", h.Gateway.Models)
 		gateway.GET("/usage", h.Gateway.Usage)
+		// OpenAI Chat Completions API（由Claude账号提供）
+		gateway.POST("/chat/completions", h.Gateway.ChatCompletions)
 		// OpenAI Responses API
 		gateway.POST("/responses", h.OpenAIGateway.Responses)
 	}
 
 	// Gemini 原生 API 兼容层（Gemini SDK/CLI 直连）
 	gemini := r.Group("/v1beta")
-	gemini.Use(middleware.ApiKeyAuthWithSubscriptionGoogle(apiKeyService, subscriptionService))
+	gemini.Use(middleware.ApiKeyAuthWithSubscriptionGoogle(apiKeyService, subscriptionService, apiKeyLimitService, organizationService))
 	{
 		gemini.GET("/models", h.Gateway.GeminiV1BetaListModels)
 		gemini.GET("/models/:model", h.Gateway.GeminiV1BetaGetModel)
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/Wei-Shaw/sub2api/internal/handler"
+	"github.com/Wei-Shaw/sub2api/internal/server/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterMetricsRoutes 注册 Prometheus 指标路由
+func RegisterMetricsRoutes(
+	r *gin.Engine,
+	h *handler.Handlers,
+	metricsAuth middleware.MetricsAuthMiddleware,
+) {
+	r.GET("/metrics", gin.HandlerFunc(metricsAuth), h.Metrics.Metrics)
+}
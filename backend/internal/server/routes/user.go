@@ -22,6 +22,19 @@ func RegisterUserRoutes(
 			user.GET("/profile", h.User.GetProfile)
 			user.PUT("/password", h.User.ChangePassword)
 			user.PUT("", h.User.UpdateProfile)
+			user.GET("/balance-transactions", h.Balance.ListTransactions)
+			user.GET("/notification-prefs", h.User.GetNotificationPrefs)
+			user.PUT("/notification-prefs", h.User.UpdateNotificationPrefs)
+
+			// 二次验证管理
+			user.GET("/2fa", h.TwoFactor.GetStatus)
+			user.POST("/2fa/totp/setup", h.TwoFactor.SetupTOTP)
+			user.POST("/2fa/totp/enable", h.TwoFactor.EnableTOTP)
+			user.POST("/2fa/totp/disable", h.TwoFactor.DisableTOTP)
+			user.POST("/2fa/recovery-codes", h.TwoFactor.RegenerateRecoveryCodes)
+			user.POST("/2fa/webauthn/register/begin", h.TwoFactor.BeginWebAuthnRegistration)
+			user.POST("/2fa/webauthn/register/finish", h.TwoFactor.FinishWebAuthnRegistration)
+			user.DELETE("/2fa/webauthn/:id", h.TwoFactor.DeleteWebAuthnCredential)
 		}
 
 		// API Key管理
@@ -31,6 +44,7 @@ func RegisterUserRoutes(
 			keys.GET("/:id", h.APIKey.GetByID)
 			keys.POST("", h.APIKey.Create)
 			keys.PUT("/:id", h.APIKey.Update)
+			keys.POST("/:id/rotate", h.APIKey.Rotate)
 			keys.DELETE("/:id", h.APIKey.Delete)
 		}
 
@@ -68,5 +82,27 @@ func RegisterUserRoutes(
 			subscriptions.GET("/progress", h.Subscription.GetProgress)
 			subscriptions.GET("/summary", h.Subscription.GetSummary)
 		}
+
+		// 组织（团队）：成员管理、邀请、组织 Key 与共享余额
+		orgs := authenticated.Group("/organizations")
+		{
+			orgs.GET("", h.Organization.List)
+			orgs.POST("", h.Organization.Create)
+			orgs.POST("/invitations/accept", h.Organization.AcceptInvitation)
+			orgs.GET("/:id", h.Organization.GetByID)
+			orgs.PUT("/:id", h.Organization.Update)
+			orgs.GET("/:id/members", h.Organization.ListMembers)
+			orgs.PUT("/:id/members/:user_id", h.Organization.UpdateMember)
+			orgs.DELETE("/:id/members/:user_id", h.Organization.RemoveMember)
+			orgs.GET("/:id/invitations", h.Organization.ListInvitations)
+			orgs.POST("/:id/invitations", h.Organization.Invite)
+			orgs.DELETE("/:id/invitations/:invitation_id", h.Organization.CancelInvitation)
+			orgs.GET("/:id/usage", h.Organization.MemberUsage)
+			orgs.GET("/:id/keys", h.Organization.ListApiKeys)
+			orgs.POST("/:id/keys", h.Organization.CreateApiKey)
+			orgs.DELETE("/:id/keys/:key_id", h.Organization.DeleteApiKey)
+			orgs.GET("/:id/balance-transactions", h.Organization.ListTransactions)
+			orgs.GET("/:id/subscriptions", h.Organization.ListSubscriptions)
+		}
 	}
 }
@@ -15,6 +15,7 @@ type Account struct {
 	ProxyID      *int64
 	Concurrency  int
 	Priority     int
+	Weight       int
 	Status       string
 	ErrorMessage string
 	LastUsedAt   *time.Time
@@ -22,6 +23,8 @@ type Account struct {
 	UpdatedAt    time.Time
 
 	Schedulable bool
+	// CaptureEnabled 是否抓取经由该账号的上游请求/响应（需同时开启 capture.enabled）
+	CaptureEnabled bool
 
 	RateLimitedAt    *time.Time
 	RateLimitResetAt *time.Time
@@ -41,6 +44,14 @@ func (a *Account) IsActive() bool {
 	return a.Status == StatusActive
 }
 
+// GetWeight 返回加权调度使用的权重，未设置或非法值按 1 处理
+func (a *Account) GetWeight() int {
+	if a.Weight <= 0 {
+		return 1
+	}
+	return a.Weight
+}
+
 func (a *Account) IsSchedulable() bool {
 	if !a.IsActive() || !a.Schedulable {
 		return false
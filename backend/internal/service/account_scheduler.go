@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+)
+
+// AccountPreferFunc 同优先级且均未使用过时的额外偏好，返回 true 表示 a 优于 b
+type AccountPreferFunc func(a, b *Account) bool
+
+// AccountScheduler 按分组调度策略从候选账号中选出一个账号。
+// Claude/OpenAI/Gemini 三个平台的选择器共用此实现，粘性会话与候选过滤仍由各选择器负责。
+//
+// 所有策略都只在最高优先级（priority 值最小）的账号中生效，低优先级账号仅作为兜底。
+type AccountScheduler struct {
+	groupRepo          GroupRepository
+	concurrencyService *ConcurrencyService
+
+	mu         sync.Mutex
+	rrCounters map[string]uint64
+	rng        *rand.Rand
+}
+
+// NewAccountScheduler creates a new AccountScheduler
+func NewAccountScheduler(groupRepo GroupRepository, concurrencyService *ConcurrencyService) *AccountScheduler {
+	return &AccountScheduler{
+		groupRepo:          groupRepo,
+		concurrencyService: concurrencyService,
+		rrCounters:         make(map[string]uint64),
+		rng:                rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+// Select 按分组策略选择账号，candidates 需已完成排除与模型过滤；无候选时返回 nil
+func (s *AccountScheduler) Select(ctx context.Context, groupID *int64, platform string, candidates []*Account, prefer AccountPreferFunc) *Account {
+	tier := topPriorityTier(candidates)
+	if len(tier) == 0 {
+		return nil
+	}
+	if len(tier) == 1 {
+		return tier[0]
+	}
+
+	switch s.strategyFor(ctx, groupID) {
+	case SchedulingStrategyWeighted:
+		return s.selectWeighted(tier)
+	case SchedulingStrategyLeastConcurrency:
+		return s.selectLeastConcurrency(ctx, tier, prefer)
+	case SchedulingStrategyRoundRobin:
+		return s.selectRoundRobin(groupID, platform, tier)
+	default:
+		return selectLeastRecentlyUsed(tier, prefer)
+	}
+}
+
+// schedulingGroupKey context 中已加载分组的 key
+type schedulingGroupKey struct{}
+
+// WithSchedulingGroup 将认证阶段已加载的分组放入 context，选号时直接读取调度策略，避免每次请求查询分组
+func WithSchedulingGroup(ctx context.Context, group *Group) context.Context {
+	if group == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, schedulingGroupKey{}, group)
+}
+
+func (s *AccountScheduler) strategyFor(ctx context.Context, groupID *int64) string {
+	if s == nil || groupID == nil {
+		return SchedulingStrategyPriority
+	}
+	if group, ok := ctx.Value(schedulingGroupKey{}).(*Group); ok && group.ID == *groupID {
+		return group.GetSchedulingStrategy()
+	}
+	// 非网关请求（如后台测试连接）没有预加载分组，回退到查库
+	if s.groupRepo == nil {
+		return SchedulingStrategyPriority
+	}
+	group, err := s.groupRepo.GetByID(ctx, *groupID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("load group scheduling strategy failed", "group_id", *groupID, logger.Err(err))
+		return SchedulingStrategyPriority
+	}
+	return group.GetSchedulingStrategy()
+}
+
+// selectWeighted 按账号权重加权随机
+func (s *AccountScheduler) selectWeighted(accounts []*Account) *Account {
+	total := 0
+	for _, acc := range accounts {
+		total += acc.GetWeight()
+	}
+
+	s.mu.Lock()
+	n := s.rng.Intn(total)
+	s.mu.Unlock()
+
+	for _, acc := range accounts {
+		n -= acc.GetWeight()
+		if n < 0 {
+			return acc
+		}
+	}
+	return accounts[len(accounts)-1]
+}
+
+// selectLeastConcurrency 选当前并发数最少的账号，并发相同时选最久未用的
+func (s *AccountScheduler) selectLeastConcurrency(ctx context.Context, accounts []*Account, prefer AccountPreferFunc) *Account {
+	if s.concurrencyService == nil {
+		return selectLeastRecentlyUsed(accounts, prefer)
+	}
+
+	ids := make([]int64, 0, len(accounts))
+	for _, acc := range accounts {
+		ids = append(ids, acc.ID)
+	}
+	counts, err := s.concurrencyService.GetAccountConcurrencyBatch(ctx, ids)
+	if err != nil {
+		logger.FromContext(ctx).Warn("get account concurrency failed, fallback to priority", logger.Err(err))
+		return selectLeastRecentlyUsed(accounts, prefer)
+	}
+
+	minCount := -1
+	var least []*Account
+	for _, acc := range accounts {
+		count := counts[acc.ID]
+		switch {
+		case minCount < 0 || count < minCount:
+			minCount = count
+			least = []*Account{acc}
+		case count == minCount:
+			least = append(least, acc)
+		}
+	}
+	return selectLeastRecentlyUsed(least, prefer)
+}
+
+// selectRoundRobin 按账号ID顺序轮询，计数器按平台+分组隔离（仅进程内有效）
+func (s *AccountScheduler) selectRoundRobin(groupID *int64, platform string, accounts []*Account) *Account {
+	sorted := make([]*Account, len(accounts))
+	copy(sorted, accounts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	var gid int64
+	if groupID != nil {
+		gid = *groupID
+	}
+	key := fmt.Sprintf("%s:%d", platform, gid)
+
+	s.mu.Lock()
+	n := s.rrCounters[key]
+	s.rrCounters[key] = n + 1
+	s.mu.Unlock()
+
+	return sorted[n%uint64(len(sorted))]
+}
+
+// topPriorityTier 返回 priority 值最小的一组账号
+func topPriorityTier(accounts []*Account) []*Account {
+	var tier []*Account
+	for _, acc := range accounts {
+		switch {
+		case len(tier) == 0 || acc.Priority < tier[0].Priority:
+			tier = []*Account{acc}
+		case acc.Priority == tier[0].Priority:
+			tier = append(tier, acc)
+		}
+	}
+	return tier
+}
+
+// selectLeastRecentlyUsed 选最久未用的账号，从未使用过的优先
+func selectLeastRecentlyUsed(accounts []*Account, prefer AccountPreferFunc) *Account {
+	var selected *Account
+	for _, acc := range accounts {
+		if selected == nil {
+			selected = acc
+			continue
+		}
+		switch {
+		case acc.LastUsedAt == nil && selected.LastUsedAt != nil:
+			selected = acc
+		case acc.LastUsedAt != nil && selected.LastUsedAt == nil:
+			// keep selected (never used is preferred)
+		case acc.LastUsedAt == nil && selected.LastUsedAt == nil:
+			if prefer != nil && prefer(acc, selected) {
+				selected = acc
+			}
+		default:
+			if acc.LastUsedAt.Before(*selected.LastUsedAt) {
+				selected = acc
+			}
+		}
+	}
+	return selected
+}
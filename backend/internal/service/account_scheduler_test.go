@@ -0,0 +1,124 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type schedulerGroupRepoStub struct {
+	GroupRepository
+	strategy string
+	calls    int
+}
+
+func (s *schedulerGroupRepoStub) GetByID(ctx context.Context, id int64) (*Group, error) {
+	s.calls++
+	return &Group{ID: id, SchedulingStrategy: s.strategy}, nil
+}
+
+type schedulerConcurrencyCacheStub struct {
+	ConcurrencyCache
+	counts map[int64]int
+}
+
+func (s *schedulerConcurrencyCacheStub) GetAccountConcurrency(ctx context.Context, accountID int64) (int, error) {
+	return s.counts[accountID], nil
+}
+
+func TestAccountScheduler_Select(t *testing.T) {
+	ctx := context.Background()
+	groupID := int64(1)
+	earlier := time.Now().Add(-time.Hour)
+	later := time.Now()
+
+	newCandidates := func() []*Account {
+		return []*Account{
+			{ID: 1, Priority: 1, Weight: 1, LastUsedAt: &later},
+			{ID: 2, Priority: 1, Weight: 3, LastUsedAt: &earlier},
+			{ID: 3, Priority: 1, Weight: 0, LastUsedAt: &later},
+			{ID: 4, Priority: 9, Weight: 100}, // 低优先级账号不参与调度
+		}
+	}
+	newScheduler := func(strategy string, counts map[int64]int) *AccountScheduler {
+		return NewAccountScheduler(
+			&schedulerGroupRepoStub{strategy: strategy},
+			NewConcurrencyService(&schedulerConcurrencyCacheStub{counts: counts}),
+		)
+	}
+
+	t.Run("priority", func(t *testing.T) {
+		s := newScheduler(SchedulingStrategyPriority, nil)
+		require.Equal(t, int64(2), s.Select(ctx, &groupID, PlatformAnthropic, newCandidates(), nil).ID)
+	})
+
+	t.Run("no group falls back to priority", func(t *testing.T) {
+		s := newScheduler(SchedulingStrategyRoundRobin, nil)
+		for i := 0; i < 3; i++ {
+			require.Equal(t, int64(2), s.Select(ctx, nil, PlatformAnthropic, newCandidates(), nil).ID)
+		}
+	})
+
+	t.Run("weighted", func(t *testing.T) {
+		s := newScheduler(SchedulingStrategyWeighted, nil)
+		hits := map[int64]int{}
+		for i := 0; i < 5000; i++ {
+			hits[s.Select(ctx, &groupID, PlatformAnthropic, newCandidates(), nil).ID]++
+		}
+		require.Zero(t, hits[4])
+		require.Greater(t, hits[2], hits[1]*2)
+		require.Positive(t, hits[3])
+	})
+
+	t.Run("least concurrency", func(t *testing.T) {
+		s := newScheduler(SchedulingStrategyLeastConcurrency, map[int64]int{1: 0, 2: 2, 3: 0, 4: 0})
+		// 1 和 3 并发相同，取最久未用；都同样时间使用过则保留先出现的
+		require.Equal(t, int64(1), s.Select(ctx, &groupID, PlatformAnthropic, newCandidates(), nil).ID)
+	})
+
+	t.Run("round robin", func(t *testing.T) {
+		s := newScheduler(SchedulingStrategyRoundRobin, nil)
+		var got []int64
+		for i := 0; i < 4; i++ {
+			got = append(got, s.Select(ctx, &groupID, PlatformOpenAI, newCandidates(), nil).ID)
+		}
+		require.Equal(t, []int64{1, 2, 3, 1}, got)
+	})
+
+	t.Run("prefer func breaks ties between unused accounts", func(t *testing.T) {
+		s := newScheduler(SchedulingStrategyPriority, nil)
+		candidates := []*Account{
+			{ID: 1, Priority: 1, Type: AccountTypeApiKey},
+			{ID: 2, Priority: 1, Type: AccountTypeOAuth},
+		}
+		selected := s.Select(ctx, &groupID, PlatformGemini, candidates, func(a, b *Account) bool {
+			return a.Type == AccountTypeOAuth && b.Type != AccountTypeOAuth
+		})
+		require.Equal(t, int64(2), selected.ID)
+	})
+
+	t.Run("preloaded group skips lookup", func(t *testing.T) {
+		repo := &schedulerGroupRepoStub{strategy: SchedulingStrategyWeighted}
+		s := NewAccountScheduler(repo, nil)
+		groupCtx := WithSchedulingGroup(ctx, &Group{ID: groupID, SchedulingStrategy: SchedulingStrategyRoundRobin})
+		var got []int64
+		for i := 0; i < 3; i++ {
+			got = append(got, s.Select(groupCtx, &groupID, PlatformAnthropic, newCandidates(), nil).ID)
+		}
+		require.Equal(t, []int64{1, 2, 3}, got)
+		require.Zero(t, repo.calls)
+
+		// context 中的分组与请求分组不一致时仍查库
+		otherID := groupID + 1
+		s.Select(groupCtx, &otherID, PlatformAnthropic, newCandidates(), nil)
+		require.Equal(t, 1, repo.calls)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		require.Nil(t, newScheduler(SchedulingStrategyWeighted, nil).Select(ctx, &groupID, PlatformAnthropic, nil, nil))
+	})
+}
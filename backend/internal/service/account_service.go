@@ -31,6 +31,7 @@ type AccountRepository interface {
 	UpdateLastUsed(ctx context.Context, id int64) error
 	SetError(ctx context.Context, id int64, errorMsg string) error
 	SetSchedulable(ctx context.Context, id int64, schedulable bool) error
+	SetCaptureEnabled(ctx context.Context, id int64, enabled bool) error
 	BindGroups(ctx context.Context, accountID int64, groupIDs []int64) error
 
 	ListSchedulable(ctx context.Context) ([]Account, error)
@@ -53,6 +54,7 @@ type AccountBulkUpdate struct {
 	ProxyID     *int64
 	Concurrency *int
 	Priority    *int
+	Weight      *int
 	Status      *string
 	Credentials map[string]any
 	Extra       map[string]any
@@ -3,10 +3,11 @@ package service
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/usagestats"
 )
@@ -194,7 +195,7 @@ func (s *AccountUsageService) addWindowStats(ctx context.Context, account *Accou
 
 	stats, err := s.usageLogRepo.GetAccountWindowStats(ctx, account.ID, startTime)
 	if err != nil {
-		log.Printf("Failed to get window stats for account %d: %v", account.ID, err)
+		logger.FromContext(ctx).Warn("get window stats failed", "account_id", account.ID, logger.Err(err))
 		return
 	}
 
@@ -279,7 +280,7 @@ func (s *AccountUsageService) buildUsageInfo(resp *ClaudeUsageResponse, updatedA
 				RemainingSeconds: int(time.Until(fiveHourReset).Seconds()),
 			}
 		} else {
-			log.Printf("Failed to parse FiveHour.ResetsAt: %s, error: %v", resp.FiveHour.ResetsAt, err)
+			slog.Warn("parse usage reset time failed", "window", "five_hour", "resets_at", resp.FiveHour.ResetsAt, logger.Err(err))
 			// 即使解析失败也返回utilization
 			info.FiveHour = &UsageProgress{
 				Utilization: resp.FiveHour.Utilization,
@@ -296,7 +297,7 @@ func (s *AccountUsageService) buildUsageInfo(resp *ClaudeUsageResponse, updatedA
 				RemainingSeconds: int(time.Until(sevenDayReset).Seconds()),
 			}
 		} else {
-			log.Printf("Failed to parse SevenDay.ResetsAt: %s, error: %v", resp.SevenDay.ResetsAt, err)
+			slog.Warn("parse usage reset time failed", "window", "seven_day", "resets_at", resp.SevenDay.ResetsAt, logger.Err(err))
 			info.SevenDay = &UsageProgress{
 				Utilization: resp.SevenDay.Utilization,
 			}
@@ -312,7 +313,7 @@ func (s *AccountUsageService) buildUsageInfo(resp *ClaudeUsageResponse, updatedA
 				RemainingSeconds: int(time.Until(sonnetReset).Seconds()),
 			}
 		} else {
-			log.Printf("Failed to parse SevenDaySonnet.ResetsAt: %s, error: %v", resp.SevenDaySonnet.ResetsAt, err)
+			slog.Warn("parse usage reset time failed", "window", "seven_day_sonnet", "resets_at", resp.SevenDaySonnet.ResetsAt, logger.Err(err))
 			info.SevenDaySonnet = &UsageProgress{
 				Utilization: resp.SevenDaySonnet.Utilization,
 			}
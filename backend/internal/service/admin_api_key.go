@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+)
+
+var (
+	ErrAdminApiKeyNotFound     = infraerrors.NotFound("ADMIN_API_KEY_NOT_FOUND", "admin api key not found")
+	ErrAdminApiKeyInvalid      = infraerrors.Unauthorized("INVALID_ADMIN_KEY", "Invalid admin API key")
+	ErrAdminApiKeyScopeInvalid = infraerrors.BadRequest("ADMIN_API_KEY_SCOPE_INVALID", "invalid admin api key scope")
+	ErrAdminApiKeyExpiryPast   = infraerrors.BadRequest("ADMIN_API_KEY_EXPIRY_PAST", "expiry time must be in the future")
+)
+
+// Admin API Key 权限范围，对应的管理角色见 AdminApiKeyScopeRole
+const (
+	AdminApiKeyScopeReadOnly = "read_only"
+	AdminApiKeyScopeAccounts = "accounts"
+	AdminApiKeyScopeBilling  = "billing"
+	AdminApiKeyScopeFull     = "full"
+)
+
+// AdminApiKeyDefaultName 旧版单一 Key 接口使用的名称
+const AdminApiKeyDefaultName = "default"
+
+var adminApiKeyScopeRoles = map[string]string{
+	AdminApiKeyScopeReadOnly: RoleViewer,
+	AdminApiKeyScopeAccounts: RoleAccountOperator,
+	AdminApiKeyScopeBilling:  RoleBillingOperator,
+	AdminApiKeyScopeFull:     RoleAdmin,
+}
+
+// IsValidAdminApiKeyScope 是否为有效的权限范围
+func IsValidAdminApiKeyScope(scope string) bool {
+	_, ok := adminApiKeyScopeRoles[scope]
+	return ok
+}
+
+// AdminApiKeyScopeRole 返回权限范围对应的管理角色，无效范围返回空字符串
+func AdminApiKeyScopeRole(scope string) string {
+	return adminApiKeyScopeRoles[scope]
+}
+
+// AdminApiKeyScopeFromRole 返回管理角色对应的权限范围，用于迁移旧版 Key
+func AdminApiKeyScopeFromRole(role string) string {
+	for scope, r := range adminApiKeyScopeRoles {
+		if r == role {
+			return scope
+		}
+	}
+	return AdminApiKeyScopeReadOnly
+}
+
+// HashAdminApiKey 计算 Key 的存储哈希。
+// Key 为 256 位随机数，无需加盐或慢哈希即可抵御暴力破解。
+func HashAdminApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// maskAdminApiKey 脱敏：显示前 10 位和后 4 位
+func maskAdminApiKey(key string) string {
+	if len(key) > 14 {
+		return key[:10] + "..." + key[len(key)-4:]
+	}
+	return key
+}
+
+// AdminApiKey 管理员 API Key，只保存哈希，明文仅在创建时返回一次
+type AdminApiKey struct {
+	ID         int64
+	Name       string
+	KeyHint    string // 脱敏后的 Key，用于在列表中辨认
+	KeyHash    string
+	Scope      string
+	CreatedBy  int64
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// IsActive 未吊销且未过期
+func (k *AdminApiKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// Role 返回 Key 对应的管理角色
+func (k *AdminApiKey) Role() string {
+	return AdminApiKeyScopeRole(k.Scope)
+}
+
+type AdminApiKeyRepository interface {
+	Create(ctx context.Context, key *AdminApiKey) error
+	GetByID(ctx context.Context, id int64) (*AdminApiKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*AdminApiKey, error)
+	// List 返回全部 Key（包括已吊销的），按创建时间倒序
+	List(ctx context.Context) ([]AdminApiKey, error)
+	// Revoke 吊销 Key，已吊销时保持原吊销时间
+	Revoke(ctx context.Context, id int64, at time.Time) error
+	TouchLastUsed(ctx context.Context, id int64, at time.Time) error
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+)
+
+// adminApiKeyTouchInterval 最后使用时间的最小更新间隔，避免每个请求都写库
+const adminApiKeyTouchInterval = time.Minute
+
+// CreateAdminApiKeyInput 创建管理员 API Key 的参数
+type CreateAdminApiKeyInput struct {
+	Name      string
+	Scope     string
+	ExpiresAt *time.Time // nil 表示永不过期
+	CreatedBy int64
+}
+
+// AdminApiKeyService 管理员 API Key 服务
+type AdminApiKeyService struct {
+	keyRepo        AdminApiKeyRepository
+	settingService *SettingService
+}
+
+// NewAdminApiKeyService 创建管理员 API Key 服务
+func NewAdminApiKeyService(keyRepo AdminApiKeyRepository, settingService *SettingService) *AdminApiKeyService {
+	return &AdminApiKeyService{
+		keyRepo:        keyRepo,
+		settingService: settingService,
+	}
+}
+
+// Create 生成新的 Key，返回记录与明文（明文只在此时返回）
+func (s *AdminApiKeyService) Create(ctx context.Context, input *CreateAdminApiKeyInput) (*AdminApiKey, string, error) {
+	if !IsValidAdminApiKeyScope(input.Scope) {
+		return nil, "", ErrAdminApiKeyScopeInvalid
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, "", ErrAdminApiKeyExpiryPast
+	}
+
+	// 生成 32 字节随机数 = 64 位十六进制字符
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, "", fmt.Errorf("generate random bytes: %w", err)
+	}
+	raw := AdminApiKeyPrefix + hex.EncodeToString(bytes)
+
+	key := &AdminApiKey{
+		Name:      input.Name,
+		KeyHint:   maskAdminApiKey(raw),
+		KeyHash:   HashAdminApiKey(raw),
+		Scope:     input.Scope,
+		CreatedBy: input.CreatedBy,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := s.keyRepo.Create(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("save admin api key: %w", err)
+	}
+	return key, raw, nil
+}
+
+// List 返回全部 Key
+func (s *AdminApiKeyService) List(ctx context.Context) ([]AdminApiKey, error) {
+	return s.keyRepo.List(ctx)
+}
+
+// Revoke 吊销 Key，返回吊销前的记录
+func (s *AdminApiKeyService) Revoke(ctx context.Context, id int64) (*AdminApiKey, error) {
+	key, err := s.keyRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.keyRepo.Revoke(ctx, id, time.Now()); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Authenticate 校验 Key，返回有效的 Key 记录；无效、过期或已吊销时返回 ErrAdminApiKeyInvalid
+func (s *AdminApiKeyService) Authenticate(ctx context.Context, raw string) (*AdminApiKey, error) {
+	key, err := s.keyRepo.GetByHash(ctx, HashAdminApiKey(raw))
+	if errors.Is(err, ErrAdminApiKeyNotFound) {
+		key, err = s.migrateLegacyKey(ctx, raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !key.IsActive(now) {
+		return nil, ErrAdminApiKeyInvalid
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= adminApiKeyTouchInterval {
+		if err := s.keyRepo.TouchLastUsed(ctx, key.ID, now); err != nil {
+			logger.FromContext(ctx).Warn("update admin api key last used failed", "key_id", key.ID, logger.Err(err))
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// migrateLegacyKey 将旧版保存在系统设置中的明文 Key 迁移为哈希存储，
+// 迁移后沿用原 Key，不影响已有的集成。
+func (s *AdminApiKeyService) migrateLegacyKey(ctx context.Context, raw string) (*AdminApiKey, error) {
+	if s.settingService == nil {
+		return nil, ErrAdminApiKeyInvalid
+	}
+	legacy, err := s.settingService.GetAdminApiKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if legacy == "" || subtle.ConstantTimeCompare([]byte(raw), []byte(legacy)) != 1 {
+		return nil, ErrAdminApiKeyInvalid
+	}
+
+	key := &AdminApiKey{
+		Name:    AdminApiKeyDefaultName,
+		KeyHint: maskAdminApiKey(raw),
+		KeyHash: HashAdminApiKey(raw),
+		Scope:   AdminApiKeyScopeFromRole(s.settingService.GetAdminApiKeyRole(ctx)),
+	}
+	if err := s.keyRepo.Create(ctx, key); err != nil {
+		// 并发请求可能已完成迁移
+		if existing, getErr := s.keyRepo.GetByHash(ctx, key.KeyHash); getErr == nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("migrate legacy admin api key: %w", err)
+	}
+	if err := s.settingService.DeleteAdminApiKey(ctx); err != nil {
+		logger.FromContext(ctx).Warn("delete legacy admin api key failed", logger.Err(err))
+	}
+	return key, nil
+}
+
+// ActiveByName 返回指定名称下仍有效的 Key
+func (s *AdminApiKeyService) ActiveByName(ctx context.Context, name string) ([]AdminApiKey, error) {
+	keys, err := s.keyRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	active := make([]AdminApiKey, 0, len(keys))
+	for _, k := range keys {
+		if k.Name == name && k.IsActive(now) {
+			active = append(active, k)
+		}
+	}
+	return active, nil
+}
@@ -0,0 +1,208 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type adminApiKeyRepoStub struct {
+	keys    []*AdminApiKey
+	nextID  int64
+	touches int
+}
+
+func (s *adminApiKeyRepoStub) Create(ctx context.Context, key *AdminApiKey) error {
+	s.nextID++
+	key.ID = s.nextID
+	key.CreatedAt = time.Now()
+	cp := *key
+	s.keys = append(s.keys, &cp)
+	return nil
+}
+
+func (s *adminApiKeyRepoStub) GetByID(ctx context.Context, id int64) (*AdminApiKey, error) {
+	for _, k := range s.keys {
+		if k.ID == id {
+			cp := *k
+			return &cp, nil
+		}
+	}
+	return nil, ErrAdminApiKeyNotFound
+}
+
+func (s *adminApiKeyRepoStub) GetByHash(ctx context.Context, hash string) (*AdminApiKey, error) {
+	for _, k := range s.keys {
+		if k.KeyHash == hash {
+			cp := *k
+			return &cp, nil
+		}
+	}
+	return nil, ErrAdminApiKeyNotFound
+}
+
+func (s *adminApiKeyRepoStub) List(ctx context.Context) ([]AdminApiKey, error) {
+	out := make([]AdminApiKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		out = append(out, *k)
+	}
+	return out, nil
+}
+
+func (s *adminApiKeyRepoStub) Revoke(ctx context.Context, id int64, at time.Time) error {
+	for _, k := range s.keys {
+		if k.ID == id {
+			if k.RevokedAt == nil {
+				k.RevokedAt = &at
+			}
+			return nil
+		}
+	}
+	return ErrAdminApiKeyNotFound
+}
+
+func (s *adminApiKeyRepoStub) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	s.touches++
+	for _, k := range s.keys {
+		if k.ID == id {
+			k.LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+type adminApiKeySettingRepoStub struct {
+	SettingRepository
+	values map[string]string
+}
+
+func (s *adminApiKeySettingRepoStub) GetValue(ctx context.Context, key string) (string, error) {
+	if v, ok := s.values[key]; ok {
+		return v, nil
+	}
+	return "", ErrSettingNotFound
+}
+
+func (s *adminApiKeySettingRepoStub) Delete(ctx context.Context, key string) error {
+	delete(s.values, key)
+	return nil
+}
+
+func TestAdminApiKeyScopeRole(t *testing.T) {
+	require.Equal(t, RoleViewer, AdminApiKeyScopeRole(AdminApiKeyScopeReadOnly))
+	require.Equal(t, RoleAccountOperator, AdminApiKeyScopeRole(AdminApiKeyScopeAccounts))
+	require.Equal(t, RoleBillingOperator, AdminApiKeyScopeRole(AdminApiKeyScopeBilling))
+	require.Equal(t, RoleAdmin, AdminApiKeyScopeRole(AdminApiKeyScopeFull))
+	// 未知 scope 不映射到任何角色，因此没有任何权限
+	require.Empty(t, AdminApiKeyScopeRole("bogus"))
+	require.False(t, IsValidAdminApiKeyScope("bogus"))
+
+	for _, scope := range []string{AdminApiKeyScopeReadOnly, AdminApiKeyScopeAccounts, AdminApiKeyScopeBilling, AdminApiKeyScopeFull} {
+		require.Equal(t, scope, AdminApiKeyScopeFromRole(AdminApiKeyScopeRole(scope)))
+	}
+}
+
+func TestAdminApiKeyService_CreateAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	repo := &adminApiKeyRepoStub{}
+	svc := NewAdminApiKeyService(repo, nil)
+
+	key, raw, err := svc.Create(ctx, &CreateAdminApiKeyInput{Name: "ci", Scope: AdminApiKeyScopeBilling, CreatedBy: 1})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(raw, AdminApiKeyPrefix))
+	// 只保存哈希，不保存明文
+	require.Equal(t, HashAdminApiKey(raw), repo.keys[0].KeyHash)
+	require.NotContains(t, repo.keys[0].KeyHint, raw[len(AdminApiKeyPrefix):])
+
+	got, err := svc.Authenticate(ctx, raw)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, got.ID)
+	require.Equal(t, RoleBillingOperator, got.Role())
+	require.NotNil(t, got.LastUsedAt)
+
+	// 一分钟内重复使用不再更新最后使用时间
+	_, err = svc.Authenticate(ctx, raw)
+	require.NoError(t, err)
+	require.Equal(t, 1, repo.touches)
+
+	_, err = svc.Authenticate(ctx, raw+"x")
+	require.ErrorIs(t, err, ErrAdminApiKeyInvalid)
+}
+
+func TestAdminApiKeyService_CreateValidation(t *testing.T) {
+	ctx := context.Background()
+	svc := NewAdminApiKeyService(&adminApiKeyRepoStub{}, nil)
+
+	_, _, err := svc.Create(ctx, &CreateAdminApiKeyInput{Name: "x", Scope: "superuser"})
+	require.ErrorIs(t, err, ErrAdminApiKeyScopeInvalid)
+
+	past := time.Now().Add(-time.Hour)
+	_, _, err = svc.Create(ctx, &CreateAdminApiKeyInput{Name: "x", Scope: AdminApiKeyScopeFull, ExpiresAt: &past})
+	require.ErrorIs(t, err, ErrAdminApiKeyExpiryPast)
+}
+
+func TestAdminApiKeyService_ExpiredAndRevoked(t *testing.T) {
+	ctx := context.Background()
+	repo := &adminApiKeyRepoStub{}
+	svc := NewAdminApiKeyService(repo, nil)
+
+	expiresAt := time.Now().Add(time.Hour)
+	expiring, expiringRaw, err := svc.Create(ctx, &CreateAdminApiKeyInput{Name: "temp", Scope: AdminApiKeyScopeReadOnly, ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+	past := time.Now().Add(-time.Second)
+	repo.keys[0].ExpiresAt = &past
+	_, err = svc.Authenticate(ctx, expiringRaw)
+	require.ErrorIs(t, err, ErrAdminApiKeyInvalid)
+
+	revoked, revokedRaw, err := svc.Create(ctx, &CreateAdminApiKeyInput{Name: "old", Scope: AdminApiKeyScopeFull})
+	require.NoError(t, err)
+	_, err = svc.Authenticate(ctx, revokedRaw)
+	require.NoError(t, err)
+
+	before, err := svc.Revoke(ctx, revoked.ID)
+	require.NoError(t, err)
+	require.Nil(t, before.RevokedAt)
+	_, err = svc.Authenticate(ctx, revokedRaw)
+	require.ErrorIs(t, err, ErrAdminApiKeyInvalid)
+
+	_, err = svc.Revoke(ctx, 999)
+	require.ErrorIs(t, err, ErrAdminApiKeyNotFound)
+
+	active, err := svc.ActiveByName(ctx, "old")
+	require.NoError(t, err)
+	require.Empty(t, active)
+	require.NotEqual(t, expiring.ID, revoked.ID)
+}
+
+func TestAdminApiKeyService_MigratesLegacyKey(t *testing.T) {
+	ctx := context.Background()
+	const legacy = "admin-legacykey"
+	settingRepo := &adminApiKeySettingRepoStub{values: map[string]string{
+		SettingKeyAdminApiKey:     legacy,
+		SettingKeyAdminApiKeyRole: RoleAccountOperator,
+	}}
+	repo := &adminApiKeyRepoStub{}
+	svc := NewAdminApiKeyService(repo, NewSettingService(settingRepo, nil))
+
+	_, err := svc.Authenticate(ctx, "admin-wrong")
+	require.ErrorIs(t, err, ErrAdminApiKeyInvalid)
+	require.Empty(t, repo.keys)
+
+	key, err := svc.Authenticate(ctx, legacy)
+	require.NoError(t, err)
+	require.Equal(t, AdminApiKeyDefaultName, key.Name)
+	require.Equal(t, AdminApiKeyScopeAccounts, key.Scope)
+	require.Equal(t, HashAdminApiKey(legacy), repo.keys[0].KeyHash)
+
+	// 迁移后旧设置被删除，Key 继续可用
+	require.NotContains(t, settingRepo.values, SettingKeyAdminApiKey)
+	again, err := svc.Authenticate(ctx, legacy)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, again.ID)
+	require.Len(t, repo.keys, 1)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"slices"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+)
+
+var (
+	ErrInvalidRole        = infraerrors.BadRequest("INVALID_ROLE", "invalid role")
+	ErrAdminRoleImmutable = infraerrors.Forbidden("ADMIN_ROLE_IMMUTABLE", "cannot change the role of a super admin")
+)
+
+// AdminPermission 管理后台权限，格式为 <资源>:<操作>
+type AdminPermission string
+
+const (
+	PermissionDashboardView       AdminPermission = "dashboard:view"
+	PermissionUsersView           AdminPermission = "users:view"
+	PermissionUsersManage         AdminPermission = "users:manage"
+	PermissionBalanceManage       AdminPermission = "balance:manage"
+	PermissionGroupsView          AdminPermission = "groups:view"
+	PermissionGroupsManage        AdminPermission = "groups:manage"
+	PermissionAccountsView        AdminPermission = "accounts:view"
+	PermissionAccountsManage      AdminPermission = "accounts:manage"
+	PermissionProxiesView         AdminPermission = "proxies:view"
+	PermissionProxiesManage       AdminPermission = "proxies:manage"
+	PermissionRedeemView          AdminPermission = "redeem:view"
+	PermissionRedeemManage        AdminPermission = "redeem:manage"
+	PermissionSubscriptionsView   AdminPermission = "subscriptions:view"
+	PermissionSubscriptionsManage AdminPermission = "subscriptions:manage"
+	PermissionUsageView           AdminPermission = "usage:view"
+	PermissionCapturesManage      AdminPermission = "captures:manage"
+	PermissionSettingsManage      AdminPermission = "settings:manage"
+	PermissionSystemManage        AdminPermission = "system:manage"
+	PermissionAuditView           AdminPermission = "audit:view"
+)
+
+// AdminRoles 可访问管理后台的角色
+var AdminRoles = []string{RoleAdmin, RoleViewer, RoleBillingOperator, RoleAccountOperator}
+
+// viewerPermissions 只读权限，不包含请求抓取、系统设置与审计日志
+var viewerPermissions = []AdminPermission{
+	PermissionDashboardView,
+	PermissionUsersView,
+	PermissionGroupsView,
+	PermissionAccountsView,
+	PermissionProxiesView,
+	PermissionRedeemView,
+	PermissionSubscriptionsView,
+	PermissionUsageView,
+}
+
+// rolePermissions 受限角色的权限，RoleAdmin 拥有全部权限不在此列出
+var rolePermissions = map[string][]AdminPermission{
+	RoleViewer: viewerPermissions,
+	RoleBillingOperator: append(slices.Clone(viewerPermissions),
+		PermissionBalanceManage,
+		PermissionRedeemManage,
+		PermissionSubscriptionsManage,
+	),
+	RoleAccountOperator: append(slices.Clone(viewerPermissions),
+		PermissionAccountsManage,
+		PermissionProxiesManage,
+		PermissionGroupsManage,
+		PermissionCapturesManage,
+	),
+}
+
+// IsAdminRole 是否为管理角色
+func IsAdminRole(role string) bool {
+	return slices.Contains(AdminRoles, role)
+}
+
+// IsValidRole 是否为有效的用户角色
+func IsValidRole(role string) bool {
+	return role == RoleUser || IsAdminRole(role)
+}
+
+// RoleHasPermission 检查角色是否具备指定权限
+func RoleHasPermission(role string, permission AdminPermission) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// RolePermissions 返回角色的全部权限，供前端控制菜单显示
+func RolePermissions(role string) []AdminPermission {
+	if role == RoleAdmin {
+		return []AdminPermission{
+			PermissionDashboardView, PermissionUsersView, PermissionUsersManage, PermissionBalanceManage,
+			PermissionGroupsView, PermissionGroupsManage, PermissionAccountsView, PermissionAccountsManage,
+			PermissionProxiesView, PermissionProxiesManage, PermissionRedeemView, PermissionRedeemManage,
+			PermissionSubscriptionsView, PermissionSubscriptionsManage, PermissionUsageView, PermissionCapturesManage,
+			PermissionSettingsManage, PermissionSystemManage, PermissionAuditView,
+		}
+	}
+	return slices.Clone(rolePermissions[role])
+}
@@ -0,0 +1,74 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleHasPermission(t *testing.T) {
+	cases := []struct {
+		role       string
+		permission AdminPermission
+		want       bool
+	}{
+		{RoleAdmin, PermissionSettingsManage, true},
+		{RoleAdmin, PermissionAuditView, true},
+		{RoleViewer, PermissionAccountsView, true},
+		{RoleViewer, PermissionAccountsManage, false},
+		{RoleViewer, PermissionCapturesManage, false},
+		{RoleViewer, PermissionAuditView, false},
+		{RoleBillingOperator, PermissionBalanceManage, true},
+		{RoleBillingOperator, PermissionRedeemManage, true},
+		{RoleBillingOperator, PermissionSubscriptionsManage, true},
+		{RoleBillingOperator, PermissionAccountsManage, false},
+		{RoleAccountOperator, PermissionAccountsManage, true},
+		{RoleAccountOperator, PermissionProxiesManage, true},
+		{RoleAccountOperator, PermissionBalanceManage, false},
+		{RoleAccountOperator, PermissionUsersManage, false},
+		{RoleUser, PermissionDashboardView, false},
+		{"unknown", PermissionDashboardView, false},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.want, RoleHasPermission(tc.role, tc.permission), "%s %s", tc.role, tc.permission)
+	}
+
+	// 超级管理员的权限列表覆盖所有角色的权限
+	for role := range rolePermissions {
+		for _, p := range RolePermissions(role) {
+			require.Contains(t, RolePermissions(RoleAdmin), p)
+		}
+	}
+}
+
+func TestAdminService_UpdateUserRole(t *testing.T) {
+	ctx := context.Background()
+	users := &authUserRepoStub{users: map[int64]*User{
+		1: {ID: 1, Role: RoleAdmin, Status: StatusActive},
+		2: {ID: 2, Role: RoleUser, Status: StatusActive},
+	}}
+	audits := &auditLogRepoStub{}
+	svc := NewAdminService(users, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, NewAuditService(audits))
+
+	user, err := svc.UpdateUser(ctx, 2, &UpdateUserInput{Role: RoleBillingOperator})
+	require.NoError(t, err)
+	require.Equal(t, RoleBillingOperator, user.Role)
+	require.True(t, user.HasAdminAccess())
+	require.False(t, user.IsAdmin())
+	require.Len(t, audits.logs, 1)
+	require.Equal(t, AuditChange{Before: RoleUser, After: RoleBillingOperator}, audits.logs[0].Changes["Role"])
+
+	// 不能通过后台提升为超级管理员
+	_, err = svc.UpdateUser(ctx, 2, &UpdateUserInput{Role: RoleAdmin})
+	require.ErrorIs(t, err, ErrInvalidRole)
+	_, err = svc.UpdateUser(ctx, 2, &UpdateUserInput{Role: "root"})
+	require.ErrorIs(t, err, ErrInvalidRole)
+
+	// 超级管理员的角色不能被修改
+	_, err = svc.UpdateUser(ctx, 1, &UpdateUserInput{Role: RoleViewer})
+	require.ErrorIs(t, err, ErrAdminRoleImmutable)
+	require.Equal(t, RoleAdmin, users.users[1].Role)
+}
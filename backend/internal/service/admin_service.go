@@ -4,9 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"time"
 
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
 )
 
@@ -18,7 +18,7 @@ type AdminService interface {
 	CreateUser(ctx context.Context, input *CreateUserInput) (*User, error)
 	UpdateUser(ctx context.Context, id int64, input *UpdateUserInput) (*User, error)
 	DeleteUser(ctx context.Context, id int64) error
-	UpdateUserBalance(ctx context.Context, userID int64, balance float64, operation string, notes string) (*User, error)
+	UpdateUserBalance(ctx context.Context, userID int64, balance float64, operation string, notes string, operatorID int64) (*User, error)
 	GetUserAPIKeys(ctx context.Context, userID int64, page, pageSize int) ([]ApiKey, int64, error)
 	GetUserUsageStats(ctx context.Context, userID int64, period string) (any, error)
 
@@ -85,32 +85,43 @@ type UpdateUserInput struct {
 	Balance       *float64 // 使用指针区分"未提供"和"设置为0"
 	Concurrency   *int     // 使用指针区分"未提供"和"设置为0"
 	Status        string
+	Role          string   // 为空时不修改，不能设置为超级管理员
 	AllowedGroups *[]int64 // 使用指针区分"未提供"和"设置为空数组"
 }
 
 type CreateGroupInput struct {
-	Name             string
-	Description      string
-	Platform         string
-	RateMultiplier   float64
-	IsExclusive      bool
-	SubscriptionType string   // standard/subscription
-	DailyLimitUSD    *float64 // 日限额 (USD)
-	WeeklyLimitUSD   *float64 // 周限额 (USD)
-	MonthlyLimitUSD  *float64 // 月限额 (USD)
+	Name               string
+	Description        string
+	Platform           string
+	RateMultiplier     float64
+	IsExclusive        bool
+	SubscriptionType   string   // standard/subscription
+	DailyLimitUSD      *float64 // 日限额 (USD)
+	WeeklyLimitUSD     *float64 // 周限额 (USD)
+	MonthlyLimitUSD    *float64 // 月限额 (USD)
+	SchedulingStrategy string   // priority/weighted/least_concurrency/round_robin
+
+	ResponseCacheEnabled    bool
+	ResponseCacheTTLSeconds int
+	ResponseCacheMultiplier *float64 // nil 时为 1
 }
 
 type UpdateGroupInput struct {
-	Name             string
-	Description      string
-	Platform         string
-	RateMultiplier   *float64 // 使用指针以支持设置为0
-	IsExclusive      *bool
-	Status           string
-	SubscriptionType string   // standard/subscription
-	DailyLimitUSD    *float64 // 日限额 (USD)
-	WeeklyLimitUSD   *float64 // 周限额 (USD)
-	MonthlyLimitUSD  *float64 // 月限额 (USD)
+	Name               string
+	Description        string
+	Platform           string
+	RateMultiplier     *float64 // 使用指针以支持设置为0
+	IsExclusive        *bool
+	Status             string
+	SubscriptionType   string   // standard/subscription
+	DailyLimitUSD      *float64 // 日限额 (USD)
+	WeeklyLimitUSD     *float64 // 周限额 (USD)
+	MonthlyLimitUSD    *float64 // 月限额 (USD)
+	SchedulingStrategy string
+
+	ResponseCacheEnabled    *bool
+	ResponseCacheTTLSeconds *int
+	ResponseCacheMultiplier *float64
 }
 
 type CreateAccountInput struct {
@@ -122,6 +133,7 @@ type CreateAccountInput struct {
 	ProxyID     *int64
 	Concurrency int
 	Priority    int
+	Weight      int
 	GroupIDs    []int64
 }
 
@@ -133,6 +145,7 @@ type UpdateAccountInput struct {
 	ProxyID     *int64
 	Concurrency *int // 使用指针区分"未提供"和"设置为0"
 	Priority    *int // 使用指针区分"未提供"和"设置为0"
+	Weight      *int
 	Status      string
 	GroupIDs    *[]int64
 }
@@ -144,6 +157,7 @@ type BulkUpdateAccountsInput struct {
 	ProxyID     *int64
 	Concurrency *int
 	Priority    *int
+	Weight      *int
 	Status      string
 	GroupIDs    *[]int64
 	Credentials map[string]any
@@ -225,6 +239,10 @@ type adminServiceImpl struct {
 	redeemCodeRepo      RedeemCodeRepository
 	billingCacheService *BillingCacheService
 	proxyProber         ProxyExitInfoProber
+	httpUpstream        HTTPUpstream
+	balanceTxRepo       BalanceTransactionRepository
+	webhookService      *WebhookService
+	auditService        *AuditService
 }
 
 // NewAdminService creates a new AdminService
@@ -237,6 +255,10 @@ func NewAdminService(
 	redeemCodeRepo RedeemCodeRepository,
 	billingCacheService *BillingCacheService,
 	proxyProber ProxyExitInfoProber,
+	httpUpstream HTTPUpstream,
+	balanceTxRepo BalanceTransactionRepository,
+	webhookService *WebhookService,
+	auditService *AuditService,
 ) AdminService {
 	return &adminServiceImpl{
 		userRepo:            userRepo,
@@ -247,6 +269,10 @@ func NewAdminService(
 		redeemCodeRepo:      redeemCodeRepo,
 		billingCacheService: billingCacheService,
 		proxyProber:         proxyProber,
+		httpUpstream:        httpUpstream,
+		balanceTxRepo:       balanceTxRepo,
+		webhookService:      webhookService,
+		auditService:        auditService,
 	}
 }
 
@@ -282,6 +308,7 @@ func (s *adminServiceImpl) CreateUser(ctx context.Context, input *CreateUserInpu
 	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(ctx, AuditActionCreate, AuditResourceUser, user.ID, nil, user)
 	return user, nil
 }
 
@@ -295,7 +322,16 @@ func (s *adminServiceImpl) UpdateUser(ctx context.Context, id int64, input *Upda
 	if user.Role == "admin" && input.Status == "disabled" {
 		return nil, errors.New("cannot disable admin user")
 	}
+	if input.Role != "" && input.Role != user.Role {
+		if user.IsAdmin() {
+			return nil, ErrAdminRoleImmutable
+		}
+		if !IsValidRole(input.Role) || input.Role == RoleAdmin {
+			return nil, ErrInvalidRole
+		}
+	}
 
+	before := *user
 	oldConcurrency := user.Concurrency
 
 	if input.Email != "" {
@@ -320,6 +356,9 @@ func (s *adminServiceImpl) UpdateUser(ctx context.Context, id int64, input *Upda
 	if input.Status != "" {
 		user.Status = input.Status
 	}
+	if input.Role != "" {
+		user.Role = input.Role
+	}
 
 	if input.Concurrency != nil {
 		user.Concurrency = *input.Concurrency
@@ -332,12 +371,13 @@ func (s *adminServiceImpl) UpdateUser(ctx context.Context, id int64, input *Upda
 	if err := s.userRepo.Update(ctx, user); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(ctx, AuditActionUpdate, AuditResourceUser, user.ID, &before, user)
 
 	concurrencyDiff := user.Concurrency - oldConcurrency
 	if concurrencyDiff != 0 {
 		code, err := GenerateRedeemCode()
 		if err != nil {
-			log.Printf("failed to generate adjustment redeem code: %v", err)
+			logger.FromContext(ctx).Error("generate concurrency adjustment redeem code failed", "user_id", id, logger.Err(err))
 			return user, nil
 		}
 		adjustmentRecord := &RedeemCode{
@@ -350,7 +390,7 @@ func (s *adminServiceImpl) UpdateUser(ctx context.Context, id int64, input *Upda
 		now := time.Now()
 		adjustmentRecord.UsedAt = &now
 		if err := s.redeemCodeRepo.Create(ctx, adjustmentRecord); err != nil {
-			log.Printf("failed to create concurrency adjustment redeem code: %v", err)
+			logger.FromContext(ctx).Error("create concurrency adjustment redeem code failed", "user_id", id, logger.Err(err))
 		}
 	}
 
@@ -366,69 +406,53 @@ func (s *adminServiceImpl) DeleteUser(ctx context.Context, id int64) error {
 	if user.Role == "admin" {
 		return errors.New("cannot delete admin user")
 	}
-	return s.userRepo.Delete(ctx, id)
+	if err := s.userRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.auditService.Record(ctx, AuditActionDelete, AuditResourceUser, id, user, nil)
+	return nil
 }
 
-func (s *adminServiceImpl) UpdateUserBalance(ctx context.Context, userID int64, balance float64, operation string, notes string) (*User, error) {
-	user, err := s.userRepo.GetByID(ctx, userID)
-	if err != nil {
-		return nil, err
+func (s *adminServiceImpl) UpdateUserBalance(ctx context.Context, userID int64, balance float64, operation string, notes string, operatorID int64) (*User, error) {
+	tx := &BalanceTransaction{
+		UserID:     userID,
+		Type:       BalanceTxTypeAdminAdjustment,
+		OperatorID: &operatorID,
+		Notes:      notes,
 	}
 
-	oldBalance := user.Balance
-
+	var err error
 	switch operation {
 	case "set":
-		user.Balance = balance
+		if balance < 0 {
+			return nil, fmt.Errorf("balance cannot be negative, requested balance: %.2f", balance)
+		}
+		err = s.balanceTxRepo.SetBalance(ctx, tx, balance)
 	case "add":
-		user.Balance += balance
+		tx.Amount = balance
+		err = s.balanceTxRepo.Apply(ctx, tx, false)
 	case "subtract":
-		user.Balance -= balance
-	}
-
-	if user.Balance < 0 {
-		return nil, fmt.Errorf("balance cannot be negative, current balance: %.2f, requested operation would result in: %.2f", oldBalance, user.Balance)
+		tx.Amount = -balance
+		err = s.balanceTxRepo.Apply(ctx, tx, false)
 	}
-
-	if err := s.userRepo.Update(ctx, user); err != nil {
+	if err != nil {
 		return nil, err
 	}
+	s.auditService.Record(ctx, AuditActionUpdate, AuditResourceBalance, userID,
+		map[string]any{"balance": tx.BalanceAfter - tx.Amount},
+		map[string]any{"balance": tx.BalanceAfter, "operation": operation, "notes": notes})
 
 	if s.billingCacheService != nil {
 		go func() {
 			cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 			if err := s.billingCacheService.InvalidateUserBalance(cacheCtx, userID); err != nil {
-				log.Printf("invalidate user balance cache failed: user_id=%d err=%v", userID, err)
+				logger.FromContext(cacheCtx).Warn("invalidate user balance cache failed", "user_id", userID, logger.Err(err))
 			}
 		}()
 	}
 
-	balanceDiff := user.Balance - oldBalance
-	if balanceDiff != 0 {
-		code, err := GenerateRedeemCode()
-		if err != nil {
-			log.Printf("failed to generate adjustment redeem code: %v", err)
-			return user, nil
-		}
-
-		adjustmentRecord := &RedeemCode{
-			Code:   code,
-			Type:   AdjustmentTypeAdminBalance,
-			Value:  balanceDiff,
-			Status: StatusUsed,
-			UsedBy: &user.ID,
-			Notes:  notes,
-		}
-		now := time.Now()
-		adjustmentRecord.UsedAt = &now
-
-		if err := s.redeemCodeRepo.Create(ctx, adjustmentRecord); err != nil {
-			log.Printf("failed to create balance adjustment redeem code: %v", err)
-		}
-	}
-
-	return user, nil
+	return s.userRepo.GetByID(ctx, userID)
 }
 
 func (s *adminServiceImpl) GetUserAPIKeys(ctx context.Context, userID int64, page, pageSize int) ([]ApiKey, int64, error) {
@@ -484,21 +508,37 @@ func (s *adminServiceImpl) CreateGroup(ctx context.Context, input *CreateGroupIn
 		subscriptionType = SubscriptionTypeStandard
 	}
 
+	schedulingStrategy := input.SchedulingStrategy
+	if schedulingStrategy == "" {
+		schedulingStrategy = SchedulingStrategyPriority
+	}
+
+	responseCacheMultiplier := 1.0
+	if input.ResponseCacheMultiplier != nil {
+		responseCacheMultiplier = *input.ResponseCacheMultiplier
+	}
+
 	group := &Group{
-		Name:             input.Name,
-		Description:      input.Description,
-		Platform:         platform,
-		RateMultiplier:   input.RateMultiplier,
-		IsExclusive:      input.IsExclusive,
-		Status:           StatusActive,
-		SubscriptionType: subscriptionType,
-		DailyLimitUSD:    input.DailyLimitUSD,
-		WeeklyLimitUSD:   input.WeeklyLimitUSD,
-		MonthlyLimitUSD:  input.MonthlyLimitUSD,
+		Name:               input.Name,
+		Description:        input.Description,
+		Platform:           platform,
+		RateMultiplier:     input.RateMultiplier,
+		IsExclusive:        input.IsExclusive,
+		Status:             StatusActive,
+		SubscriptionType:   subscriptionType,
+		DailyLimitUSD:      input.DailyLimitUSD,
+		WeeklyLimitUSD:     input.WeeklyLimitUSD,
+		MonthlyLimitUSD:    input.MonthlyLimitUSD,
+		SchedulingStrategy: schedulingStrategy,
+
+		ResponseCacheEnabled:    input.ResponseCacheEnabled,
+		ResponseCacheTTLSeconds: input.ResponseCacheTTLSeconds,
+		ResponseCacheMultiplier: responseCacheMultiplier,
 	}
 	if err := s.groupRepo.Create(ctx, group); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(ctx, AuditActionCreate, AuditResourceGroup, group.ID, nil, group)
 	return group, nil
 }
 
@@ -507,6 +547,7 @@ func (s *adminServiceImpl) UpdateGroup(ctx context.Context, id int64, input *Upd
 	if err != nil {
 		return nil, err
 	}
+	before := *group
 
 	if input.Name != "" {
 		group.Name = input.Name
@@ -541,18 +582,38 @@ func (s *adminServiceImpl) UpdateGroup(ctx context.Context, id int64, input *Upd
 	if input.MonthlyLimitUSD != nil {
 		group.MonthlyLimitUSD = input.MonthlyLimitUSD
 	}
+	if input.SchedulingStrategy != "" {
+		group.SchedulingStrategy = input.SchedulingStrategy
+	}
+
+	// 响应缓存
+	if input.ResponseCacheEnabled != nil {
+		group.ResponseCacheEnabled = *input.ResponseCacheEnabled
+	}
+	if input.ResponseCacheTTLSeconds != nil {
+		group.ResponseCacheTTLSeconds = *input.ResponseCacheTTLSeconds
+	}
+	if input.ResponseCacheMultiplier != nil {
+		group.ResponseCacheMultiplier = *input.ResponseCacheMultiplier
+	}
 
 	if err := s.groupRepo.Update(ctx, group); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(ctx, AuditActionUpdate, AuditResourceGroup, group.ID, &before, group)
 	return group, nil
 }
 
 func (s *adminServiceImpl) DeleteGroup(ctx context.Context, id int64) error {
+	group, err := s.groupRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
 	affectedUserIDs, err := s.groupRepo.DeleteCascade(ctx, id)
 	if err != nil {
 		return err
 	}
+	s.auditService.Record(ctx, AuditActionDelete, AuditResourceGroup, id, group, nil)
 
 	// 事务成功后，异步失效受影响用户的订阅缓存
 	if len(affectedUserIDs) > 0 && s.billingCacheService != nil {
@@ -562,7 +623,7 @@ func (s *adminServiceImpl) DeleteGroup(ctx context.Context, id int64) error {
 			defer cancel()
 			for _, userID := range affectedUserIDs {
 				if err := s.billingCacheService.InvalidateSubscription(cacheCtx, userID, groupID); err != nil {
-					log.Printf("invalidate subscription cache failed: user_id=%d group_id=%d err=%v", userID, groupID, err)
+					logger.FromContext(cacheCtx).Warn("invalidate subscription cache failed", "user_id", userID, "group_id", groupID, logger.Err(err))
 				}
 			}
 		}()
@@ -604,6 +665,7 @@ func (s *adminServiceImpl) CreateAccount(ctx context.Context, input *CreateAccou
 		ProxyID:     input.ProxyID,
 		Concurrency: input.Concurrency,
 		Priority:    input.Priority,
+		Weight:      input.Weight,
 		Status:      StatusActive,
 	}
 	if err := s.accountRepo.Create(ctx, account); err != nil {
@@ -615,6 +677,8 @@ func (s *adminServiceImpl) CreateAccount(ctx context.Context, input *CreateAccou
 			return nil, err
 		}
 	}
+	account.GroupIDs = input.GroupIDs
+	s.auditService.Record(ctx, AuditActionCreate, AuditResourceAccount, account.ID, nil, account)
 	return account, nil
 }
 
@@ -623,6 +687,7 @@ func (s *adminServiceImpl) UpdateAccount(ctx context.Context, id int64, input *U
 	if err != nil {
 		return nil, err
 	}
+	before := *account
 
 	if input.Name != "" {
 		account.Name = input.Name
@@ -648,6 +713,9 @@ func (s *adminServiceImpl) UpdateAccount(ctx context.Context, id int64, input *U
 	if input.Priority != nil {
 		account.Priority = *input.Priority
 	}
+	if input.Weight != nil {
+		account.Weight = *input.Weight
+	}
 	if input.Status != "" {
 		account.Status = input.Status
 	}
@@ -673,7 +741,12 @@ func (s *adminServiceImpl) UpdateAccount(ctx context.Context, id int64, input *U
 	}
 
 	// 重新查询以确保返回完整数据（包括正确的 Proxy 关联对象）
-	return s.accountRepo.GetByID(ctx, id)
+	updated, err := s.accountRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(ctx, AuditActionUpdate, AuditResourceAccount, id, &before, updated)
+	return updated, nil
 }
 
 // BulkUpdateAccounts updates multiple accounts in one request.
@@ -704,6 +777,9 @@ func (s *adminServiceImpl) BulkUpdateAccounts(ctx context.Context, input *BulkUp
 	if input.Priority != nil {
 		repoUpdates.Priority = input.Priority
 	}
+	if input.Weight != nil {
+		repoUpdates.Weight = input.Weight
+	}
 	if input.Status != "" {
 		repoUpdates.Status = &input.Status
 	}
@@ -712,6 +788,10 @@ func (s *adminServiceImpl) BulkUpdateAccounts(ctx context.Context, input *BulkUp
 	if _, err := s.accountRepo.BulkUpdate(ctx, input.AccountIDs, repoUpdates); err != nil {
 		return nil, err
 	}
+	// 批量更新没有逐个账号的原值，记录本次提交的变更
+	for _, accountID := range input.AccountIDs {
+		s.auditService.Record(ctx, AuditActionUpdate, AuditResourceAccount, accountID, nil, input)
+	}
 
 	// Handle group bindings per account (requires individual operations).
 	for _, accountID := range input.AccountIDs {
@@ -736,7 +816,15 @@ func (s *adminServiceImpl) BulkUpdateAccounts(ctx context.Context, input *BulkUp
 }
 
 func (s *adminServiceImpl) DeleteAccount(ctx context.Context, id int64) error {
-	return s.accountRepo.Delete(ctx, id)
+	account, err := s.accountRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.accountRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.auditService.Record(ctx, AuditActionDelete, AuditResourceAccount, id, account, nil)
+	return nil
 }
 
 func (s *adminServiceImpl) RefreshAccountCredentials(ctx context.Context, id int64) (*Account, error) {
@@ -753,11 +841,13 @@ func (s *adminServiceImpl) ClearAccountError(ctx context.Context, id int64) (*Ac
 	if err != nil {
 		return nil, err
 	}
+	before := *account
 	account.Status = StatusActive
 	account.ErrorMessage = ""
 	if err := s.accountRepo.Update(ctx, account); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(ctx, AuditActionUpdate, AuditResourceAccount, id, &before, account)
 	return account, nil
 }
 
@@ -765,6 +855,8 @@ func (s *adminServiceImpl) SetAccountSchedulable(ctx context.Context, id int64,
 	if err := s.accountRepo.SetSchedulable(ctx, id, schedulable); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(ctx, AuditActionUpdate, AuditResourceAccount, id,
+		map[string]any{"Schedulable": !schedulable}, map[string]any{"Schedulable": schedulable})
 	return s.accountRepo.GetByID(ctx, id)
 }
 
@@ -803,6 +895,7 @@ func (s *adminServiceImpl) CreateProxy(ctx context.Context, input *CreateProxyIn
 	if err := s.proxyRepo.Create(ctx, proxy); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(ctx, AuditActionCreate, AuditResourceProxy, proxy.ID, nil, proxy)
 	return proxy, nil
 }
 
@@ -811,6 +904,8 @@ func (s *adminServiceImpl) UpdateProxy(ctx context.Context, id int64, input *Upd
 	if err != nil {
 		return nil, err
 	}
+	oldURL := proxy.URL()
+	before := *proxy
 
 	if input.Name != "" {
 		proxy.Name = input.Name
@@ -837,11 +932,29 @@ func (s *adminServiceImpl) UpdateProxy(ctx context.Context, id int64, input *Upd
 	if err := s.proxyRepo.Update(ctx, proxy); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(ctx, AuditActionUpdate, AuditResourceProxy, id, &before, proxy)
+	s.invalidateProxyTransport(oldURL)
 	return proxy, nil
 }
 
 func (s *adminServiceImpl) DeleteProxy(ctx context.Context, id int64) error {
-	return s.proxyRepo.Delete(ctx, id)
+	proxy, err := s.proxyRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.proxyRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.auditService.Record(ctx, AuditActionDelete, AuditResourceProxy, id, proxy, nil)
+	s.invalidateProxyTransport(proxy.URL())
+	return nil
+}
+
+// invalidateProxyTransport 关闭代理的复用连接池，后续请求按新配置重建
+func (s *adminServiceImpl) invalidateProxyTransport(proxyURL string) {
+	if s.httpUpstream != nil {
+		s.httpUpstream.InvalidateProxy(proxyURL)
+	}
 }
 
 func (s *adminServiceImpl) GetProxyAccounts(ctx context.Context, proxyID int64, page, pageSize int) ([]Account, int64, error) {
@@ -906,19 +1019,28 @@ func (s *adminServiceImpl) GenerateRedeemCodes(ctx context.Context, input *Gener
 		if err := s.redeemCodeRepo.Create(ctx, &code); err != nil {
 			return nil, err
 		}
+		s.auditService.Record(ctx, AuditActionCreate, AuditResourceRedeemCode, code.ID, nil, &code)
 		codes = append(codes, code)
 	}
 	return codes, nil
 }
 
 func (s *adminServiceImpl) DeleteRedeemCode(ctx context.Context, id int64) error {
-	return s.redeemCodeRepo.Delete(ctx, id)
+	code, err := s.redeemCodeRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.redeemCodeRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.auditService.Record(ctx, AuditActionDelete, AuditResourceRedeemCode, id, code, nil)
+	return nil
 }
 
 func (s *adminServiceImpl) BatchDeleteRedeemCodes(ctx context.Context, ids []int64) (int64, error) {
 	var deleted int64
 	for _, id := range ids {
-		if err := s.redeemCodeRepo.Delete(ctx, id); err == nil {
+		if err := s.DeleteRedeemCode(ctx, id); err == nil {
 			deleted++
 		}
 	}
@@ -930,10 +1052,12 @@ func (s *adminServiceImpl) ExpireRedeemCode(ctx context.Context, id int64) (*Red
 	if err != nil {
 		return nil, err
 	}
+	before := *code
 	code.Status = StatusExpired
 	if err := s.redeemCodeRepo.Update(ctx, code); err != nil {
 		return nil, err
 	}
+	s.auditService.Record(ctx, AuditActionUpdate, AuditResourceRedeemCode, id, &before, code)
 	return code, nil
 }
 
@@ -946,6 +1070,7 @@ func (s *adminServiceImpl) TestProxy(ctx context.Context, id int64) (*ProxyTestR
 	proxyURL := proxy.URL()
 	exitInfo, latencyMs, err := s.proxyProber.ProbeProxy(ctx, proxyURL)
 	if err != nil {
+		s.webhookService.NotifyProxyTestFailed(ctx, proxy, err.Error())
 		return &ProxyTestResult{
 			Success: false,
 			Message: err.Error(),
@@ -1,14 +1,41 @@
 package service
 
-import "time"
+import (
+	"net"
+	"strings"
+	"time"
+)
 
 type ApiKey struct {
-	ID        int64
-	UserID    int64
-	Key       string
-	Name      string
-	GroupID   *int64
-	Status    string
+	ID      int64
+	UserID  int64
+	Key     string
+	Name    string
+	GroupID *int64
+	Status  string
+
+	// 限流与花费上限（0/nil 表示不限制）
+	RateLimitRPM    int
+	RateLimitTPM    int
+	DailyLimitUSD   *float64
+	MonthlyLimitUSD *float64
+
+	// 访问控制（空表示不限制）
+	AllowedModels []string // 模型名，支持末尾 * 前缀匹配
+	AllowedIPs    []string // IP 或 CIDR
+
+	// 过期时间（nil 表示永不过期）
+	ExpiresAt *time.Time
+	// 轮换后旧 Key 在宽限期内仍可使用
+	PreviousKey          *string
+	PreviousKeyExpiresAt *time.Time
+
+	// CaptureEnabled 是否抓取该 Key 的请求/响应（仅管理员可修改）
+	CaptureEnabled bool
+
+	// OrganizationID 组织 Key：使用组织余额与订阅计费（UserID 为创建该 Key 的成员）
+	OrganizationID *int64
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	User      *User
@@ -18,3 +45,95 @@ type ApiKey struct {
 func (k *ApiKey) IsActive() bool {
 	return k.Status == StatusActive
 }
+
+// IsOrganizationOwned 是否为组织 Key
+func (k *ApiKey) IsOrganizationOwned() bool {
+	return k.OrganizationID != nil
+}
+
+// IsExpired 是否已过期
+func (k *ApiKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// HasRateLimits 是否配置了任意限流/花费上限
+func (k *ApiKey) HasRateLimits() bool {
+	return k.RateLimitRPM > 0 || k.RateLimitTPM > 0 || k.HasDailyLimit() || k.HasMonthlyLimit()
+}
+
+func (k *ApiKey) HasDailyLimit() bool {
+	return k.DailyLimitUSD != nil && *k.DailyLimitUSD > 0
+}
+
+func (k *ApiKey) HasMonthlyLimit() bool {
+	return k.MonthlyLimitUSD != nil && *k.MonthlyLimitUSD > 0
+}
+
+// IsModelAllowed 检查模型是否在允许列表内（空列表表示不限制）
+func (k *ApiKey) IsModelAllowed(model string) bool {
+	if len(k.AllowedModels) == 0 {
+		return true
+	}
+	model = strings.ToLower(strings.TrimSpace(model))
+	for _, pattern := range k.AllowedModels {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(model, prefix) {
+				return true
+			}
+			continue
+		}
+		if model == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIPAllowed 检查客户端IP是否在允许列表内（空列表表示不限制）
+func (k *ApiKey) IsIPAllowed(clientIP string) bool {
+	if len(k.AllowedIPs) == 0 {
+		return true
+	}
+	ip := net.ParseIP(strings.TrimSpace(clientIP))
+	if ip == nil {
+		return false
+	}
+	for _, entry := range k.AllowedIPs {
+		if ipNet := parseIPOrCIDR(entry); ipNet != nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAccess 检查客户端IP与请求模型是否被允许
+func (k *ApiKey) CheckAccess(model, clientIP string) error {
+	if !k.IsIPAllowed(clientIP) {
+		return ErrApiKeyIPNotAllowed
+	}
+	if !k.IsModelAllowed(model) {
+		return ErrApiKeyModelNotAllowed
+	}
+	return nil
+}
+
+// parseIPOrCIDR 将单个IP或CIDR解析为网段，无效时返回 nil
+func parseIPOrCIDR(entry string) *net.IPNet {
+	entry = strings.TrimSpace(entry)
+	if strings.Contains(entry, "/") {
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil
+		}
+		return ipNet
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
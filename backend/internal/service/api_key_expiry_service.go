@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+)
+
+// apiKeyExpiryCheckInterval API Key 过期检查间隔
+const apiKeyExpiryCheckInterval = time.Minute
+
+// ApiKeyExpiryService API Key 过期处理服务
+// 定期将已过期的 Key 置为 inactive，并清除宽限期结束的轮换旧 Key
+type ApiKeyExpiryService struct {
+	apiKeyRepo ApiKeyRepository
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewApiKeyExpiryService 创建 API Key 过期处理服务
+func NewApiKeyExpiryService(apiKeyRepo ApiKeyRepository) *ApiKeyExpiryService {
+	return &ApiKeyExpiryService{
+		apiKeyRepo: apiKeyRepo,
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Start 启动后台检查
+func (s *ApiKeyExpiryService) Start() {
+	s.wg.Add(1)
+	go s.loop()
+	logger.Component("api_key_expiry").Info("service started", "interval", apiKeyExpiryCheckInterval)
+}
+
+// Stop 停止后台检查
+func (s *ApiKeyExpiryService) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+	logger.Component("api_key_expiry").Info("service stopped")
+}
+
+func (s *ApiKeyExpiryService) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(apiKeyExpiryCheckInterval)
+	defer ticker.Stop()
+
+	// 启动时立即执行一次
+	s.processExpiry()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.processExpiry()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// processExpiry 执行一次过期处理
+func (s *ApiKeyExpiryService) processExpiry() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	if expired, err := s.apiKeyRepo.ExpireKeys(ctx, now); err != nil {
+		logger.Component("api_key_expiry").Error("expire api keys failed", logger.Err(err))
+	} else if expired > 0 {
+		logger.Component("api_key_expiry").Info("marked expired api keys inactive", "count", expired)
+	}
+
+	if cleared, err := s.apiKeyRepo.ClearExpiredPreviousKeys(ctx, now); err != nil {
+		logger.Component("api_key_expiry").Error("clear rotated keys failed", logger.Err(err))
+	} else if cleared > 0 {
+		logger.Component("api_key_expiry").Info("cleared rotated keys past grace period", "count", cleared)
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/timezone"
+)
+
+var (
+	ErrApiKeyRPMExceeded          = infraerrors.TooManyRequests("API_KEY_RPM_EXCEEDED", "api key requests per minute limit exceeded")
+	ErrApiKeyTPMExceeded          = infraerrors.TooManyRequests("API_KEY_TPM_EXCEEDED", "api key tokens per minute limit exceeded")
+	ErrApiKeyDailyLimitExceeded   = infraerrors.TooManyRequests("API_KEY_DAILY_LIMIT_EXCEEDED", "api key daily spending limit exceeded")
+	ErrApiKeyMonthlyLimitExceeded = infraerrors.TooManyRequests("API_KEY_MONTHLY_LIMIT_EXCEEDED", "api key monthly spending limit exceeded")
+)
+
+// ApiKeyLimitRetryAfterKey 限流错误 Metadata 中的重试等待秒数
+const ApiKeyLimitRetryAfterKey = "retry_after"
+
+const (
+	apiKeyMinuteWindowTTL = 2 * time.Minute
+	apiKeyDailySpendTTL   = 48 * time.Hour
+	apiKeyMonthlySpendTTL = 32 * 24 * time.Hour
+)
+
+// ApiKeyLimitCache defines counters for per-API-key rate and spending limits
+type ApiKeyLimitCache interface {
+	// IncrRequestCount 当前分钟窗口请求数加一，返回加一后的值
+	IncrRequestCount(ctx context.Context, apiKeyID int64, window string, ttl time.Duration) (int64, error)
+	GetTokenCount(ctx context.Context, apiKeyID int64, window string) (int64, error)
+	AddTokens(ctx context.Context, apiKeyID int64, window string, tokens int64, ttl time.Duration) error
+
+	// GetSpend 返回周期内已花费金额，计数器不存在时 found=false
+	GetSpend(ctx context.Context, apiKeyID int64, period string) (spend float64, found bool, err error)
+	// SetSpendIfAbsent 计数器不存在时用数据库统计值初始化
+	SetSpendIfAbsent(ctx context.Context, apiKeyID int64, period string, spend float64, ttl time.Duration) error
+	// AddSpend 仅在计数器已存在时累加，不存在时由下次检查从数据库重建
+	AddSpend(ctx context.Context, apiKeyID int64, period string, cost float64, ttl time.Duration) error
+}
+
+// ApiKeyLimitService 按 API Key 维度的 RPM/TPM/日/月花费限制
+type ApiKeyLimitService struct {
+	cache        ApiKeyLimitCache
+	usageLogRepo UsageLogRepository
+}
+
+// NewApiKeyLimitService creates a new ApiKeyLimitService
+func NewApiKeyLimitService(cache ApiKeyLimitCache, usageLogRepo UsageLogRepository) *ApiKeyLimitService {
+	return &ApiKeyLimitService{
+		cache:        cache,
+		usageLogRepo: usageLogRepo,
+	}
+}
+
+// CheckLimits 请求进入网关前检查 API Key 限制，超限时返回带 retry_after 的 429 错误。
+// Redis 异常时放行，避免缓存故障导致全部请求失败。
+func (s *ApiKeyLimitService) CheckLimits(ctx context.Context, apiKey *ApiKey) error {
+	if s == nil || apiKey == nil || !apiKey.HasRateLimits() {
+		return nil
+	}
+
+	now := timezone.Now()
+	minute := minuteWindow(now)
+	untilNextMinute := now.Truncate(time.Minute).Add(time.Minute).Sub(now)
+
+	if apiKey.RateLimitRPM > 0 {
+		count, err := s.cache.IncrRequestCount(ctx, apiKey.ID, minute, apiKeyMinuteWindowTTL)
+		if err != nil {
+			logger.FromContext(ctx).Warn("api key rpm counter failed", "api_key_id", apiKey.ID, logger.Err(err))
+		} else if count > int64(apiKey.RateLimitRPM) {
+			return withRetryAfter(ErrApiKeyRPMExceeded, untilNextMinute)
+		}
+	}
+
+	if apiKey.RateLimitTPM > 0 {
+		tokens, err := s.cache.GetTokenCount(ctx, apiKey.ID, minute)
+		if err != nil {
+			logger.FromContext(ctx).Warn("api key tpm counter failed", "api_key_id", apiKey.ID, logger.Err(err))
+		} else if tokens >= int64(apiKey.RateLimitTPM) {
+			return withRetryAfter(ErrApiKeyTPMExceeded, untilNextMinute)
+		}
+	}
+
+	if apiKey.HasDailyLimit() {
+		dayStart := timezone.StartOfDay(now)
+		spend, err := s.getSpend(ctx, apiKey.ID, dailyPeriod(now), dayStart, apiKeyDailySpendTTL)
+		if err != nil {
+			logger.FromContext(ctx).Warn("api key daily spend check failed", "api_key_id", apiKey.ID, logger.Err(err))
+		} else if spend >= *apiKey.DailyLimitUSD {
+			return withRetryAfter(ErrApiKeyDailyLimitExceeded, dayStart.AddDate(0, 0, 1).Sub(now))
+		}
+	}
+
+	if apiKey.HasMonthlyLimit() {
+		monthStart := timezone.StartOfMonth(now)
+		spend, err := s.getSpend(ctx, apiKey.ID, monthlyPeriod(now), monthStart, apiKeyMonthlySpendTTL)
+		if err != nil {
+			logger.FromContext(ctx).Warn("api key monthly spend check failed", "api_key_id", apiKey.ID, logger.Err(err))
+		} else if spend >= *apiKey.MonthlyLimitUSD {
+			return withRetryAfter(ErrApiKeyMonthlyLimitExceeded, monthStart.AddDate(0, 1, 0).Sub(now))
+		}
+	}
+
+	return nil
+}
+
+// RecordUsage 请求完成后累加 token 与花费计数（需在写入 usage_log 之后调用）
+func (s *ApiKeyLimitService) RecordUsage(ctx context.Context, apiKey *ApiKey, tokens int64, cost float64) {
+	if s == nil || apiKey == nil || !apiKey.HasRateLimits() {
+		return
+	}
+
+	now := timezone.Now()
+	if apiKey.RateLimitTPM > 0 && tokens > 0 {
+		if err := s.cache.AddTokens(ctx, apiKey.ID, minuteWindow(now), tokens, apiKeyMinuteWindowTTL); err != nil {
+			logger.FromContext(ctx).Warn("api key tpm update failed", "api_key_id", apiKey.ID, logger.Err(err))
+		}
+	}
+	if cost <= 0 {
+		return
+	}
+	if apiKey.HasDailyLimit() {
+		if err := s.cache.AddSpend(ctx, apiKey.ID, dailyPeriod(now), cost, apiKeyDailySpendTTL); err != nil {
+			logger.FromContext(ctx).Warn("api key daily spend update failed", "api_key_id", apiKey.ID, logger.Err(err))
+		}
+	}
+	if apiKey.HasMonthlyLimit() {
+		if err := s.cache.AddSpend(ctx, apiKey.ID, monthlyPeriod(now), cost, apiKeyMonthlySpendTTL); err != nil {
+			logger.FromContext(ctx).Warn("api key monthly spend update failed", "api_key_id", apiKey.ID, logger.Err(err))
+		}
+	}
+}
+
+// getSpend 读取周期花费，缓存缺失时从 usage_logs 汇总并回填
+func (s *ApiKeyLimitService) getSpend(ctx context.Context, apiKeyID int64, period string, start time.Time, ttl time.Duration) (float64, error) {
+	spend, found, err := s.cache.GetSpend(ctx, apiKeyID, period)
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return spend, nil
+	}
+
+	stats, err := s.usageLogRepo.GetApiKeyStatsAggregated(ctx, apiKeyID, start, timezone.Now().Add(time.Minute))
+	if err != nil {
+		return 0, err
+	}
+	if err := s.cache.SetSpendIfAbsent(ctx, apiKeyID, period, stats.TotalActualCost, ttl); err != nil {
+		logger.FromContext(ctx).Warn("api key spend cache fill failed", "api_key_id", apiKeyID, logger.Err(err))
+	}
+	return stats.TotalActualCost, nil
+}
+
+func withRetryAfter(err *infraerrors.ApplicationError, d time.Duration) error {
+	seconds := int(d.Seconds())
+	if d > time.Duration(seconds)*time.Second {
+		seconds++
+	}
+	if seconds < 1 {
+		seconds = 1
+	}
+	return err.WithMetadata(map[string]string{ApiKeyLimitRetryAfterKey: strconv.Itoa(seconds)})
+}
+
+func minuteWindow(t time.Time) string {
+	return t.Format("200601021504")
+}
+
+func dailyPeriod(t time.Time) string {
+	return t.Format("20060102")
+}
+
+func monthlyPeriod(t time.Time) string {
+	return t.Format("200601")
+}
@@ -0,0 +1,135 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/usagestats"
+	"github.com/stretchr/testify/require"
+)
+
+type apiKeyLimitCacheStub struct {
+	requests map[string]int64
+	tokens   map[string]int64
+	spend    map[string]float64
+	err      error
+}
+
+func newApiKeyLimitCacheStub() *apiKeyLimitCacheStub {
+	return &apiKeyLimitCacheStub{
+		requests: map[string]int64{},
+		tokens:   map[string]int64{},
+		spend:    map[string]float64{},
+	}
+}
+
+func (s *apiKeyLimitCacheStub) IncrRequestCount(ctx context.Context, apiKeyID int64, window string, ttl time.Duration) (int64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	s.requests[window]++
+	return s.requests[window], nil
+}
+
+func (s *apiKeyLimitCacheStub) GetTokenCount(ctx context.Context, apiKeyID int64, window string) (int64, error) {
+	return s.tokens[window], s.err
+}
+
+func (s *apiKeyLimitCacheStub) AddTokens(ctx context.Context, apiKeyID int64, window string, tokens int64, ttl time.Duration) error {
+	s.tokens[window] += tokens
+	return nil
+}
+
+func (s *apiKeyLimitCacheStub) GetSpend(ctx context.Context, apiKeyID int64, period string) (float64, bool, error) {
+	v, ok := s.spend[period]
+	return v, ok, s.err
+}
+
+func (s *apiKeyLimitCacheStub) SetSpendIfAbsent(ctx context.Context, apiKeyID int64, period string, spend float64, ttl time.Duration) error {
+	if _, ok := s.spend[period]; !ok {
+		s.spend[period] = spend
+	}
+	return nil
+}
+
+func (s *apiKeyLimitCacheStub) AddSpend(ctx context.Context, apiKeyID int64, period string, cost float64, ttl time.Duration) error {
+	if _, ok := s.spend[period]; ok {
+		s.spend[period] += cost
+	}
+	return nil
+}
+
+type apiKeyLimitUsageRepoStub struct {
+	UsageLogRepository
+	cost float64
+}
+
+func (s *apiKeyLimitUsageRepoStub) GetApiKeyStatsAggregated(ctx context.Context, apiKeyID int64, startTime, endTime time.Time) (*usagestats.UsageStats, error) {
+	return &usagestats.UsageStats{TotalActualCost: s.cost}, nil
+}
+
+func requireRetryAfter(t *testing.T, err error, reason string) {
+	t.Helper()
+	require.True(t, infraerrors.IsTooManyRequests(err))
+	require.Equal(t, reason, infraerrors.Reason(err))
+	retryAfter, convErr := strconv.Atoi(infraerrors.FromError(err).Metadata[ApiKeyLimitRetryAfterKey])
+	require.NoError(t, convErr)
+	require.Positive(t, retryAfter)
+}
+
+func TestApiKeyLimitService_CheckLimits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no limits", func(t *testing.T) {
+		cache := newApiKeyLimitCacheStub()
+		svc := NewApiKeyLimitService(cache, &apiKeyLimitUsageRepoStub{})
+		require.NoError(t, svc.CheckLimits(ctx, &ApiKey{ID: 1}))
+		require.Empty(t, cache.requests)
+	})
+
+	t.Run("rpm", func(t *testing.T) {
+		svc := NewApiKeyLimitService(newApiKeyLimitCacheStub(), &apiKeyLimitUsageRepoStub{})
+		key := &ApiKey{ID: 1, RateLimitRPM: 2}
+		require.NoError(t, svc.CheckLimits(ctx, key))
+		require.NoError(t, svc.CheckLimits(ctx, key))
+		requireRetryAfter(t, svc.CheckLimits(ctx, key), "API_KEY_RPM_EXCEEDED")
+	})
+
+	t.Run("tpm", func(t *testing.T) {
+		svc := NewApiKeyLimitService(newApiKeyLimitCacheStub(), &apiKeyLimitUsageRepoStub{})
+		key := &ApiKey{ID: 1, RateLimitTPM: 1000}
+		require.NoError(t, svc.CheckLimits(ctx, key))
+		svc.RecordUsage(ctx, key, 1000, 0)
+		requireRetryAfter(t, svc.CheckLimits(ctx, key), "API_KEY_TPM_EXCEEDED")
+	})
+
+	t.Run("daily spend seeded from usage logs", func(t *testing.T) {
+		svc := NewApiKeyLimitService(newApiKeyLimitCacheStub(), &apiKeyLimitUsageRepoStub{cost: 4.5})
+		limit := 5.0
+		key := &ApiKey{ID: 1, DailyLimitUSD: &limit}
+		require.NoError(t, svc.CheckLimits(ctx, key))
+		svc.RecordUsage(ctx, key, 0, 0.5)
+		requireRetryAfter(t, svc.CheckLimits(ctx, key), "API_KEY_DAILY_LIMIT_EXCEEDED")
+	})
+
+	t.Run("monthly spend", func(t *testing.T) {
+		svc := NewApiKeyLimitService(newApiKeyLimitCacheStub(), &apiKeyLimitUsageRepoStub{cost: 30})
+		limit := 30.0
+		key := &ApiKey{ID: 1, MonthlyLimitUSD: &limit}
+		requireRetryAfter(t, svc.CheckLimits(ctx, key), "API_KEY_MONTHLY_LIMIT_EXCEEDED")
+	})
+
+	t.Run("cache error fails open", func(t *testing.T) {
+		cache := newApiKeyLimitCacheStub()
+		cache.err = errors.New("redis down")
+		svc := NewApiKeyLimitService(cache, &apiKeyLimitUsageRepoStub{cost: 100})
+		limit := 1.0
+		require.NoError(t, svc.CheckLimits(ctx, &ApiKey{ID: 1, RateLimitRPM: 1, RateLimitTPM: 1, DailyLimitUSD: &limit}))
+	})
+}
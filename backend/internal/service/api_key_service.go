@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/config"
@@ -20,10 +21,22 @@ var (
 	ErrApiKeyTooShort     = infraerrors.BadRequest("API_KEY_TOO_SHORT", "api key must be at least 16 characters")
 	ErrApiKeyInvalidChars = infraerrors.BadRequest("API_KEY_INVALID_CHARS", "api key can only contain letters, numbers, underscores, and hyphens")
 	ErrApiKeyRateLimited  = infraerrors.TooManyRequests("API_KEY_RATE_LIMITED", "too many failed attempts, please try again later")
+
+	ErrApiKeyModelNotAllowed = infraerrors.Forbidden("API_KEY_MODEL_NOT_ALLOWED", "this api key is not allowed to use the requested model")
+	ErrApiKeyIPNotAllowed    = infraerrors.Forbidden("API_KEY_IP_NOT_ALLOWED", "this api key is not allowed to be used from this IP address")
+	ErrInvalidAllowedIP      = infraerrors.BadRequest("INVALID_ALLOWED_IP", "allowed_ips must contain valid IP addresses or CIDR ranges")
+
+	ErrApiKeyExpired      = infraerrors.Unauthorized("API_KEY_EXPIRED", "api key has expired")
+	ErrApiKeyExpiryInPast = infraerrors.BadRequest("API_KEY_EXPIRY_IN_PAST", "expires_at must be in the future")
+	ErrInvalidGracePeriod = infraerrors.BadRequest("INVALID_GRACE_PERIOD", "grace period must be between 0 and 7 days")
 )
 
 const (
 	apiKeyMaxErrorsPerHour = 20
+
+	// 轮换时旧 Key 宽限期的默认值与上限
+	defaultApiKeyRotationGrace = time.Hour
+	maxApiKeyRotationGrace     = 7 * 24 * time.Hour
 )
 
 type ApiKeyRepository interface {
@@ -34,6 +47,7 @@ type ApiKeyRepository interface {
 	Delete(ctx context.Context, id int64) error
 
 	ListByUserID(ctx context.Context, userID int64, params pagination.PaginationParams) ([]ApiKey, *pagination.PaginationResult, error)
+	ListByOrganizationID(ctx context.Context, orgID int64, params pagination.PaginationParams) ([]ApiKey, *pagination.PaginationResult, error)
 	VerifyOwnership(ctx context.Context, userID int64, apiKeyIDs []int64) ([]int64, error)
 	CountByUserID(ctx context.Context, userID int64) (int64, error)
 	ExistsByKey(ctx context.Context, key string) (bool, error)
@@ -41,6 +55,13 @@ type ApiKeyRepository interface {
 	SearchApiKeys(ctx context.Context, userID int64, keyword string, limit int) ([]ApiKey, error)
 	ClearGroupIDByGroupID(ctx context.Context, groupID int64) (int64, error)
 	CountByGroupID(ctx context.Context, groupID int64) (int64, error)
+
+	// ExpireKeys 将已过期的 active Key 置为 inactive，返回影响行数
+	ExpireKeys(ctx context.Context, now time.Time) (int64, error)
+	// ClearExpiredPreviousKeys 清除宽限期已结束的轮换旧 Key
+	ClearExpiredPreviousKeys(ctx context.Context, now time.Time) (int64, error)
+	// SetCaptureEnabled 开关请求抓取
+	SetCaptureEnabled(ctx context.Context, id int64, enabled bool) error
 }
 
 // ApiKeyCache defines cache operations for API key service
@@ -58,6 +79,22 @@ type CreateApiKeyRequest struct {
 	Name      string  `json:"name"`
 	GroupID   *int64  `json:"group_id"`
 	CustomKey *string `json:"custom_key"` // 可选的自定义key
+
+	// 可选的限流与花费上限（0/nil 表示不限制）
+	RateLimitRPM    int      `json:"rate_limit_rpm"`
+	RateLimitTPM    int      `json:"rate_limit_tpm"`
+	DailyLimitUSD   *float64 `json:"daily_limit_usd"`
+	MonthlyLimitUSD *float64 `json:"monthly_limit_usd"`
+
+	// 可选的模型/IP 允许列表（空表示不限制）
+	AllowedModels []string `json:"allowed_models"`
+	AllowedIPs    []string `json:"allowed_ips"`
+
+	// 可选的过期时间
+	ExpiresAt *time.Time `json:"expires_at"`
+
+	// OrganizationID 创建组织 Key（由组织接口在校验成员身份后设置）
+	OrganizationID *int64 `json:"-"`
 }
 
 // UpdateApiKeyRequest 更新API Key请求
@@ -65,6 +102,20 @@ type UpdateApiKeyRequest struct {
 	Name    *string `json:"name"`
 	GroupID *int64  `json:"group_id"`
 	Status  *string `json:"status"`
+
+	// 限流与花费上限：传 0 表示取消限制
+	RateLimitRPM    *int     `json:"rate_limit_rpm"`
+	RateLimitTPM    *int     `json:"rate_limit_tpm"`
+	DailyLimitUSD   *float64 `json:"daily_limit_usd"`
+	MonthlyLimitUSD *float64 `json:"monthly_limit_usd"`
+
+	// 模型/IP 允许列表：nil 表示不修改，空数组表示取消限制
+	AllowedModels *[]string `json:"allowed_models"`
+	AllowedIPs    *[]string `json:"allowed_ips"`
+
+	// 过期时间：ExpiresAt 设置新的过期时间，ClearExpiresAt 取消过期
+	ExpiresAt      *time.Time `json:"expires_at"`
+	ClearExpiresAt bool       `json:"clear_expires_at"`
 }
 
 // ApiKeyService API Key服务
@@ -164,12 +215,17 @@ func (s *ApiKeyService) incrementApiKeyErrorCount(ctx context.Context, userID in
 }
 
 // canUserBindGroup 检查用户是否可以绑定指定分组
-// 对于订阅类型分组：检查用户是否有有效订阅
+// 对于订阅类型分组：检查用户（组织 Key 为组织）是否有有效订阅
 // 对于标准类型分组：使用原有的 AllowedGroups 和 IsExclusive 逻辑
-func (s *ApiKeyService) canUserBindGroup(ctx context.Context, user *User, group *Group) bool {
+func (s *ApiKeyService) canUserBindGroup(ctx context.Context, user *User, group *Group, orgID *int64) bool {
 	// 订阅类型分组：需要有效订阅
 	if group.IsSubscriptionType() {
-		_, err := s.userSubRepo.GetActiveByUserIDAndGroupID(ctx, user.ID, group.ID)
+		var err error
+		if orgID != nil {
+			_, err = s.userSubRepo.GetActiveByOrganizationIDAndGroupID(ctx, *orgID, group.ID)
+		} else {
+			_, err = s.userSubRepo.GetActiveByUserIDAndGroupID(ctx, user.ID, group.ID)
+		}
 		return err == nil // 有有效订阅则允许
 	}
 	// 标准类型分组：使用原有逻辑
@@ -192,11 +248,21 @@ func (s *ApiKeyService) Create(ctx context.Context, userID int64, req CreateApiK
 		}
 
 		// 检查用户是否可以绑定该分组
-		if !s.canUserBindGroup(ctx, user, group) {
+		if !s.canUserBindGroup(ctx, user, group, req.OrganizationID) {
 			return nil, ErrGroupNotAllowed
 		}
 	}
 
+	allowedIPs, err := normalizeAllowedIPs(req.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+	allowedModels := normalizeAllowedModels(req.AllowedModels)
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(timezone.Now()) {
+		return nil, ErrApiKeyExpiryInPast
+	}
+
 	var key string
 
 	// 判断是否使用自定义Key
@@ -239,6 +305,18 @@ func (s *ApiKeyService) Create(ctx context.Context, userID int64, req CreateApiK
 		Name:    req.Name,
 		GroupID: req.GroupID,
 		Status:  StatusActive,
+
+		RateLimitRPM:    req.RateLimitRPM,
+		RateLimitTPM:    req.RateLimitTPM,
+		DailyLimitUSD:   normalizeLimitUSD(req.DailyLimitUSD),
+		MonthlyLimitUSD: normalizeLimitUSD(req.MonthlyLimitUSD),
+
+		AllowedModels: allowedModels,
+		AllowedIPs:    allowedIPs,
+
+		ExpiresAt: req.ExpiresAt,
+
+		OrganizationID: req.OrganizationID,
 	}
 
 	if err := s.apiKeyRepo.Create(ctx, apiKey); err != nil {
@@ -257,6 +335,15 @@ func (s *ApiKeyService) List(ctx context.Context, userID int64, params paginatio
 	return keys, pagination, nil
 }
 
+// ListByOrganization 获取组织的全部 Key
+func (s *ApiKeyService) ListByOrganization(ctx context.Context, orgID int64, params pagination.PaginationParams) ([]ApiKey, *pagination.PaginationResult, error) {
+	keys, pagination, err := s.apiKeyRepo.ListByOrganizationID(ctx, orgID, params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list organization api keys: %w", err)
+	}
+	return keys, pagination, nil
+}
+
 func (s *ApiKeyService) VerifyOwnership(ctx context.Context, userID int64, apiKeyIDs []int64) ([]int64, error) {
 	if len(apiKeyIDs) == 0 {
 		return []int64{}, nil
@@ -295,9 +382,65 @@ func (s *ApiKeyService) GetByKey(ctx context.Context, key string) (*ApiKey, erro
 		_ = cacheKey // 使用变量避免未使用错误
 	}
 
+	// 每次认证都从数据库读取并检查过期；若日后为 GetByKey 加入缓存，缓存命中同样需要做此检查
+	if apiKey.IsExpired(timezone.Now()) {
+		return nil, ErrApiKeyExpired
+	}
+
 	return apiKey, nil
 }
 
+// Rotate 为 API Key 生成新密钥，旧密钥在宽限期内仍可使用。
+// gracePeriod 为 nil 时使用配置的默认值，为 0 时旧密钥立即失效。
+func (s *ApiKeyService) Rotate(ctx context.Context, id int64, userID int64, gracePeriod *time.Duration) (*ApiKey, error) {
+	apiKey, err := s.apiKeyRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+
+	// 验证所有权
+	if apiKey.UserID != userID {
+		return nil, ErrInsufficientPerms
+	}
+
+	grace := s.defaultRotationGrace()
+	if gracePeriod != nil {
+		grace = *gracePeriod
+	}
+	if grace < 0 || grace > maxApiKeyRotationGrace {
+		return nil, ErrInvalidGracePeriod
+	}
+
+	newKey, err := s.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+
+	if grace > 0 {
+		oldKey := apiKey.Key
+		graceEnd := timezone.Now().Add(grace)
+		apiKey.PreviousKey = &oldKey
+		apiKey.PreviousKeyExpiresAt = &graceEnd
+	} else {
+		apiKey.PreviousKey = nil
+		apiKey.PreviousKeyExpiresAt = nil
+	}
+	apiKey.Key = newKey
+
+	if err := s.apiKeyRepo.Update(ctx, apiKey); err != nil {
+		return nil, fmt.Errorf("rotate api key: %w", err)
+	}
+
+	return apiKey, nil
+}
+
+func (s *ApiKeyService) defaultRotationGrace() time.Duration {
+	if s.cfg != nil && s.cfg.Default.ApiKeyRotationGraceMinutes > 0 {
+		return time.Duration(s.cfg.Default.ApiKeyRotationGraceMinutes) * time.Minute
+	}
+	return defaultApiKeyRotationGrace
+}
+
 // Update 更新API Key
 func (s *ApiKeyService) Update(ctx context.Context, id int64, userID int64, req UpdateApiKeyRequest) (*ApiKey, error) {
 	apiKey, err := s.apiKeyRepo.GetByID(ctx, id)
@@ -327,7 +470,7 @@ func (s *ApiKeyService) Update(ctx context.Context, id int64, userID int64, req
 			return nil, fmt.Errorf("get group: %w", err)
 		}
 
-		if !s.canUserBindGroup(ctx, user, group) {
+		if !s.canUserBindGroup(ctx, user, group, apiKey.OrganizationID) {
 			return nil, ErrGroupNotAllowed
 		}
 
@@ -342,6 +485,39 @@ func (s *ApiKeyService) Update(ctx context.Context, id int64, userID int64, req
 		}
 	}
 
+	if req.RateLimitRPM != nil {
+		apiKey.RateLimitRPM = *req.RateLimitRPM
+	}
+	if req.RateLimitTPM != nil {
+		apiKey.RateLimitTPM = *req.RateLimitTPM
+	}
+	if req.DailyLimitUSD != nil {
+		apiKey.DailyLimitUSD = normalizeLimitUSD(req.DailyLimitUSD)
+	}
+	if req.MonthlyLimitUSD != nil {
+		apiKey.MonthlyLimitUSD = normalizeLimitUSD(req.MonthlyLimitUSD)
+	}
+
+	if req.AllowedModels != nil {
+		apiKey.AllowedModels = normalizeAllowedModels(*req.AllowedModels)
+	}
+	if req.AllowedIPs != nil {
+		allowedIPs, err := normalizeAllowedIPs(*req.AllowedIPs)
+		if err != nil {
+			return nil, err
+		}
+		apiKey.AllowedIPs = allowedIPs
+	}
+
+	if req.ClearExpiresAt {
+		apiKey.ExpiresAt = nil
+	} else if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(timezone.Now()) {
+			return nil, ErrApiKeyExpiryInPast
+		}
+		apiKey.ExpiresAt = req.ExpiresAt
+	}
+
 	if err := s.apiKeyRepo.Update(ctx, apiKey); err != nil {
 		return nil, fmt.Errorf("update api key: %w", err)
 	}
@@ -373,6 +549,22 @@ func (s *ApiKeyService) Delete(ctx context.Context, id int64, userID int64) erro
 	return nil
 }
 
+// DeleteForOrganization 删除组织 Key（由组织管理员操作，已在调用方校验权限）
+func (s *ApiKeyService) DeleteForOrganization(ctx context.Context, id int64, orgID int64) error {
+	apiKey, err := s.apiKeyRepo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get api key: %w", err)
+	}
+	if apiKey.OrganizationID == nil || *apiKey.OrganizationID != orgID {
+		return ErrApiKeyNotFound
+	}
+
+	if err := s.apiKeyRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("delete api key: %w", err)
+	}
+	return nil
+}
+
 // ValidateKey 验证API Key是否有效（用于认证中间件）
 func (s *ApiKeyService) ValidateKey(ctx context.Context, key string) (*ApiKey, *User, error) {
 	// 获取API Key
@@ -471,3 +663,57 @@ func (s *ApiKeyService) SearchApiKeys(ctx context.Context, userID int64, keyword
 	}
 	return keys, nil
 }
+
+// normalizeLimitUSD 花费上限 <=0 视为不限制
+func normalizeLimitUSD(v *float64) *float64 {
+	if v == nil || *v <= 0 {
+		return nil
+	}
+	limit := *v
+	return &limit
+}
+
+// normalizeAllowedModels 去除空白与重复项，结果为空时返回 nil（不限制）
+func normalizeAllowedModels(models []string) []string {
+	out := make([]string, 0, len(models))
+	seen := make(map[string]struct{}, len(models))
+	for _, m := range models {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		if _, ok := seen[m]; ok {
+			continue
+		}
+		seen[m] = struct{}{}
+		out = append(out, m)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// normalizeAllowedIPs 校验并去重 IP/CIDR 列表
+func normalizeAllowedIPs(entries []string) ([]string, error) {
+	out := make([]string, 0, len(entries))
+	seen := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if parseIPOrCIDR(entry) == nil {
+			return nil, ErrInvalidAllowedIP
+		}
+		if _, ok := seen[entry]; ok {
+			continue
+		}
+		seen[entry] = struct{}{}
+		out = append(out, entry)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
@@ -0,0 +1,105 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiKey_CheckAccess(t *testing.T) {
+	key := &ApiKey{
+		AllowedModels: []string{"claude-3-5-haiku*", "gpt-4o"},
+		AllowedIPs:    []string{"10.0.0.0/8", "192.168.1.10", "2001:db8::/32"},
+	}
+
+	require.NoError(t, key.CheckAccess("claude-3-5-haiku-20241022", "10.1.2.3"))
+	require.NoError(t, key.CheckAccess("GPT-4o", "192.168.1.10"))
+	require.NoError(t, key.CheckAccess("gpt-4o", "2001:db8::1"))
+
+	require.ErrorIs(t, key.CheckAccess("claude-sonnet-4-20250514", "10.1.2.3"), ErrApiKeyModelNotAllowed)
+	require.ErrorIs(t, key.CheckAccess("gpt-4o-mini", "10.1.2.3"), ErrApiKeyModelNotAllowed)
+	require.ErrorIs(t, key.CheckAccess("", "10.1.2.3"), ErrApiKeyModelNotAllowed)
+	require.ErrorIs(t, key.CheckAccess("gpt-4o", "192.168.1.11"), ErrApiKeyIPNotAllowed)
+	require.ErrorIs(t, key.CheckAccess("gpt-4o", "not-an-ip"), ErrApiKeyIPNotAllowed)
+
+	// 空列表不限制
+	require.NoError(t, (&ApiKey{}).CheckAccess("any-model", "8.8.8.8"))
+}
+
+func TestNormalizeAllowedIPs(t *testing.T) {
+	ips, err := normalizeAllowedIPs([]string{" 10.0.0.0/8 ", "", "10.0.0.0/8", "::1"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.0/8", "::1"}, ips)
+
+	ips, err = normalizeAllowedIPs([]string{""})
+	require.NoError(t, err)
+	require.Nil(t, ips)
+
+	_, err = normalizeAllowedIPs([]string{"10.0.0.0/33"})
+	require.ErrorIs(t, err, ErrInvalidAllowedIP)
+	_, err = normalizeAllowedIPs([]string{"example.com"})
+	require.ErrorIs(t, err, ErrInvalidAllowedIP)
+}
+
+type apiKeyRepoStub struct {
+	ApiKeyRepository
+	key     *ApiKey
+	updated *ApiKey
+}
+
+func (s *apiKeyRepoStub) GetByID(ctx context.Context, id int64) (*ApiKey, error) {
+	clone := *s.key
+	return &clone, nil
+}
+
+func (s *apiKeyRepoStub) GetByKey(ctx context.Context, key string) (*ApiKey, error) {
+	clone := *s.key
+	return &clone, nil
+}
+
+func (s *apiKeyRepoStub) Update(ctx context.Context, key *ApiKey) error {
+	s.updated = key
+	return nil
+}
+
+func TestApiKeyService_GetByKeyExpired(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	svc := NewApiKeyService(&apiKeyRepoStub{key: &ApiKey{ID: 1, Key: "sk-old", ExpiresAt: &past}}, nil, nil, nil, nil, &config.Config{})
+
+	_, err := svc.GetByKey(context.Background(), "sk-old")
+	require.ErrorIs(t, err, ErrApiKeyExpired)
+}
+
+func TestApiKeyService_Rotate(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{Default: config.DefaultConfig{ApiKeyPrefix: "sk-", ApiKeyRotationGraceMinutes: 30}}
+
+	repo := &apiKeyRepoStub{key: &ApiKey{ID: 1, UserID: 7, Key: "sk-old"}}
+	svc := NewApiKeyService(repo, nil, nil, nil, nil, cfg)
+
+	_, err := svc.Rotate(ctx, 1, 8, nil)
+	require.ErrorIs(t, err, ErrInsufficientPerms)
+
+	rotated, err := svc.Rotate(ctx, 1, 7, nil)
+	require.NoError(t, err)
+	require.NotEqual(t, "sk-old", rotated.Key)
+	require.Equal(t, rotated, repo.updated)
+	require.NotNil(t, rotated.PreviousKey)
+	require.Equal(t, "sk-old", *rotated.PreviousKey)
+	require.WithinDuration(t, time.Now().Add(30*time.Minute), *rotated.PreviousKeyExpiresAt, time.Minute)
+
+	zero := time.Duration(0)
+	rotated, err = svc.Rotate(ctx, 1, 7, &zero)
+	require.NoError(t, err)
+	require.Nil(t, rotated.PreviousKey)
+	require.Nil(t, rotated.PreviousKeyExpiresAt)
+
+	tooLong := 8 * 24 * time.Hour
+	_, err = svc.Rotate(ctx, 1, 7, &tooLong)
+	require.ErrorIs(t, err, ErrInvalidGracePeriod)
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+)
+
+// Audit actions
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// Audit resource types
+const (
+	AuditResourceUser         = "user"
+	AuditResourceBalance      = "balance"
+	AuditResourceGroup        = "group"
+	AuditResourceAccount      = "account"
+	AuditResourceProxy        = "proxy"
+	AuditResourceRedeemCode   = "redeem_code"
+	AuditResourceSubscription = "subscription"
+	AuditResourceSetting      = "setting"
+	AuditResourceAdminApiKey  = "admin_api_key"
+	AuditResourceOrganization = "organization"
+)
+
+// auditRedacted 敏感字段在审计日志中的占位值
+const auditRedacted = "[REDACTED]"
+
+// auditSensitiveKeys 字段名包含这些片段时不记录原值
+var auditSensitiveKeys = []string{"password", "secret", "token", "credential", "api_key", "apikey", "private_key", "hash"}
+
+// auditIgnoredKeys 不参与比较的字段：时间戳与预加载的关联对象
+var auditIgnoredKeys = []string{"UpdatedAt", "LastUsedAt", "ApiKeys", "Subscriptions", "Proxy", "AccountGroups", "Groups", "User", "Group"}
+
+// AuditActor 执行操作的管理员
+type AuditActor struct {
+	UserID     int64  `json:"user_id"`
+	Role       string `json:"role"`
+	AuthMethod string `json:"auth_method"` // jwt / admin_api_key
+	// AdminApiKeyID 通过 Admin API Key 操作时对应的 Key
+	AdminApiKeyID *int64 `json:"admin_api_key_id,omitempty"`
+	IP            string `json:"ip"`
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor 将操作人写入 context，由管理后台认证中间件设置
+func WithAuditActor(ctx context.Context, actor *AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActorFromContext 读取操作人，不存在时返回 nil
+func AuditActorFromContext(ctx context.Context) *AuditActor {
+	actor, _ := ctx.Value(auditActorKey{}).(*AuditActor)
+	return actor
+}
+
+// AuditChange 单个字段的变更
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditLog 管理操作审计日志，只追加不修改
+type AuditLog struct {
+	ID            int64
+	ActorID       int64
+	ActorRole     string
+	AuthMethod    string
+	AdminApiKeyID *int64
+	IP            string
+	Action        string
+	ResourceType  string
+	ResourceID    string
+	// Changes 变更字段，key 为字段名
+	Changes   map[string]AuditChange
+	CreatedAt time.Time
+}
+
+// AuditLogFilters 审计日志查询条件
+type AuditLogFilters struct {
+	ActorID       int64
+	AdminApiKeyID int64
+	Action        string
+	ResourceType  string
+	ResourceID    string
+	StartTime     *time.Time
+	EndTime       *time.Time
+}
+
+// AuditLogRepository 审计日志仓储，只提供写入与查询
+type AuditLogRepository interface {
+	Create(ctx context.Context, log *AuditLog) error
+	List(ctx context.Context, params pagination.PaginationParams, filters AuditLogFilters) ([]AuditLog, *pagination.PaginationResult, error)
+}
+
+// DiffForAudit 比较操作前后的对象，返回发生变化的字段。
+// 对象按 JSON 序列化后逐字段比较，敏感字段只记录发生了变化而不记录原值。
+func DiffForAudit(before, after any) map[string]AuditChange {
+	b := auditFields(before)
+	a := auditFields(after)
+
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make(map[string]AuditChange)
+	for _, k := range keys {
+		if slices.Contains(auditIgnoredKeys, k) {
+			continue
+		}
+		bv, bok := b[k]
+		av, aok := a[k]
+		if bok == aok && reflect.DeepEqual(bv, av) {
+			continue
+		}
+		if isAuditSensitiveKey(k) {
+			if bok && bv != nil {
+				bv = auditRedacted
+			}
+			if aok && av != nil {
+				av = auditRedacted
+			}
+		}
+		changes[k] = AuditChange{Before: bv, After: av}
+	}
+	return changes
+}
+
+// auditFields 将对象转换为字段表，非对象类型记录在 "value" 下
+func auditFields(v any) map[string]any {
+	if v == nil {
+		return map[string]any{}
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return map[string]any{}
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return map[string]any{}
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		var value any
+		_ = json.Unmarshal(raw, &value)
+		return map[string]any{"value": value}
+	}
+	return fields
+}
+
+func isAuditSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range auditSensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
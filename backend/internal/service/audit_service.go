@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+)
+
+// AuditService 记录管理后台的变更操作
+type AuditService struct {
+	auditRepo AuditLogRepository
+}
+
+// NewAuditService 创建审计日志服务
+func NewAuditService(auditRepo AuditLogRepository) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+// Record 记录一次变更。before 为 nil 表示创建，after 为 nil 表示删除；
+// 更新操作没有字段变化时不记录。写入失败只记日志，不影响业务操作。
+func (s *AuditService) Record(ctx context.Context, action, resourceType string, resourceID any, before, after any) {
+	if s == nil || s.auditRepo == nil {
+		return
+	}
+	changes := DiffForAudit(before, after)
+	if action == AuditActionUpdate && len(changes) == 0 {
+		return
+	}
+
+	entry := &AuditLog{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   fmt.Sprint(resourceID),
+		Changes:      changes,
+	}
+	if actor := AuditActorFromContext(ctx); actor != nil {
+		entry.ActorID = actor.UserID
+		entry.ActorRole = actor.Role
+		entry.AuthMethod = actor.AuthMethod
+		entry.AdminApiKeyID = actor.AdminApiKeyID
+		entry.IP = actor.IP
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		logger.FromContext(ctx).Error("write audit log failed",
+			"action", action, "resource_type", resourceType, "resource_id", entry.ResourceID, logger.Err(err))
+	}
+}
+
+// List 查询审计日志
+func (s *AuditService) List(ctx context.Context, params pagination.PaginationParams, filters AuditLogFilters) ([]AuditLog, *pagination.PaginationResult, error) {
+	return s.auditRepo.List(ctx, params, filters)
+}
@@ -0,0 +1,71 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+	"github.com/stretchr/testify/require"
+)
+
+type auditLogRepoStub struct {
+	logs []*AuditLog
+}
+
+func (s *auditLogRepoStub) Create(ctx context.Context, log *AuditLog) error {
+	log.ID = int64(len(s.logs) + 1)
+	s.logs = append(s.logs, log)
+	return nil
+}
+
+func (s *auditLogRepoStub) List(ctx context.Context, params pagination.PaginationParams, filters AuditLogFilters) ([]AuditLog, *pagination.PaginationResult, error) {
+	return nil, nil, nil
+}
+
+func TestDiffForAudit(t *testing.T) {
+	before := &Account{ID: 1, Name: "a", Priority: 1, Credentials: map[string]any{"api_key": "sk-old"}}
+	after := &Account{ID: 1, Name: "b", Priority: 1, Credentials: map[string]any{"api_key": "sk-new"}}
+
+	changes := DiffForAudit(before, after)
+	require.Equal(t, AuditChange{Before: "a", After: "b"}, changes["Name"])
+	require.NotContains(t, changes, "Priority")
+	require.NotContains(t, changes, "ID")
+	// 敏感字段只记录发生了变化
+	require.Equal(t, AuditChange{Before: auditRedacted, After: auditRedacted}, changes["Credentials"])
+
+	// 创建与删除
+	created := DiffForAudit(nil, &Proxy{Name: "p", Password: "secret"})
+	require.Equal(t, AuditChange{Before: nil, After: "p"}, created["Name"])
+	require.Equal(t, AuditChange{Before: nil, After: auditRedacted}, created["Password"])
+
+	deleted := DiffForAudit(&User{Email: "a@example.com", PasswordHash: "hash"}, nil)
+	require.Equal(t, AuditChange{Before: "a@example.com", After: nil}, deleted["Email"])
+	require.Equal(t, AuditChange{Before: auditRedacted, After: nil}, deleted["PasswordHash"])
+
+	require.Empty(t, DiffForAudit(&User{ID: 1}, &User{ID: 1}))
+}
+
+func TestAuditService_Record(t *testing.T) {
+	repo := &auditLogRepoStub{}
+	svc := NewAuditService(repo)
+	ctx := WithAuditActor(context.Background(), &AuditActor{UserID: 7, Role: RoleViewer, AuthMethod: "jwt", IP: "10.0.0.1"})
+
+	// 没有变化的更新不记录
+	svc.Record(ctx, AuditActionUpdate, AuditResourceGroup, int64(3), &Group{Name: "g"}, &Group{Name: "g"})
+	require.Empty(t, repo.logs)
+
+	svc.Record(ctx, AuditActionDelete, AuditResourceGroup, int64(3), &Group{Name: "g"}, nil)
+	require.Len(t, repo.logs, 1)
+	log := repo.logs[0]
+	require.Equal(t, int64(7), log.ActorID)
+	require.Equal(t, RoleViewer, log.ActorRole)
+	require.Equal(t, "10.0.0.1", log.IP)
+	require.Equal(t, "3", log.ResourceID)
+	require.Equal(t, "g", log.Changes["Name"].Before)
+
+	// 未配置时不记录也不报错
+	var nilSvc *AuditService
+	nilSvc.Record(ctx, AuditActionCreate, AuditResourceGroup, 1, nil, &Group{})
+}
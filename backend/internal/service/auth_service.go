@@ -4,12 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/config"
 	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/oauth"
 
+	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -20,6 +22,7 @@ var (
 	ErrEmailExists         = infraerrors.Conflict("EMAIL_EXISTS", "email already exists")
 	ErrInvalidToken        = infraerrors.Unauthorized("INVALID_TOKEN", "invalid token")
 	ErrTokenExpired        = infraerrors.Unauthorized("TOKEN_EXPIRED", "token has expired")
+	ErrTokenRevoked        = infraerrors.Unauthorized("TOKEN_REVOKED", "token has been revoked")
 	ErrEmailVerifyRequired = infraerrors.BadRequest("EMAIL_VERIFY_REQUIRED", "email verification is required")
 	ErrRegDisabled         = infraerrors.Forbidden("REGISTRATION_DISABLED", "registration is currently disabled")
 	ErrServiceUnavailable  = infraerrors.ServiceUnavailable("SERVICE_UNAVAILABLE", "service temporarily unavailable")
@@ -27,9 +30,12 @@ var (
 
 // JWTClaims JWT载荷数据
 type JWTClaims struct {
-	UserID int64  `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID       int64  `json:"user_id"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	TokenVersion int64  `json:"token_version"`
+	// Purpose 非空表示受限用途的 token（如登录二次验证），不能用于访问接口
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -41,6 +47,8 @@ type AuthService struct {
 	emailService      *EmailService
 	turnstileService  *TurnstileService
 	emailQueueService *EmailQueueService
+	twoFactorService  *TwoFactorService
+	oidcService       *OIDCService
 }
 
 // NewAuthService 创建认证服务实例
@@ -51,6 +59,8 @@ func NewAuthService(
 	emailService *EmailService,
 	turnstileService *TurnstileService,
 	emailQueueService *EmailQueueService,
+	twoFactorService *TwoFactorService,
+	oidcService *OIDCService,
 ) *AuthService {
 	return &AuthService{
 		userRepo:          userRepo,
@@ -59,6 +69,8 @@ func NewAuthService(
 		emailService:      emailService,
 		turnstileService:  turnstileService,
 		emailQueueService: emailQueueService,
+		twoFactorService:  twoFactorService,
+		oidcService:       oidcService,
 	}
 }
 
@@ -90,7 +102,7 @@ func (s *AuthService) RegisterWithVerification(ctx context.Context, email, passw
 	// 检查邮箱是否已存在
 	existsEmail, err := s.userRepo.ExistsByEmail(ctx, email)
 	if err != nil {
-		log.Printf("[Auth] Database error checking email exists: %v", err)
+		logger.FromContext(ctx).Error("check email exists failed", logger.Err(err))
 		return "", nil, ErrServiceUnavailable
 	}
 	if existsEmail {
@@ -122,7 +134,7 @@ func (s *AuthService) RegisterWithVerification(ctx context.Context, email, passw
 	}
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
-		log.Printf("[Auth] Database error creating user: %v", err)
+		logger.FromContext(ctx).Error("create user failed", logger.Err(err))
 		return "", nil, ErrServiceUnavailable
 	}
 
@@ -150,7 +162,7 @@ func (s *AuthService) SendVerifyCode(ctx context.Context, email string) error {
 	// 检查邮箱是否已存在
 	existsEmail, err := s.userRepo.ExistsByEmail(ctx, email)
 	if err != nil {
-		log.Printf("[Auth] Database error checking email exists: %v", err)
+		logger.FromContext(ctx).Error("check email exists failed", logger.Err(err))
 		return ErrServiceUnavailable
 	}
 	if existsEmail {
@@ -173,28 +185,28 @@ func (s *AuthService) SendVerifyCode(ctx context.Context, email string) error {
 
 // SendVerifyCodeAsync 异步发送邮箱验证码并返回倒计时
 func (s *AuthService) SendVerifyCodeAsync(ctx context.Context, email string) (*SendVerifyCodeResult, error) {
-	log.Printf("[Auth] SendVerifyCodeAsync called for email: %s", email)
+	logger.FromContext(ctx).Debug("send verify code requested", "email", email)
 
 	// 检查是否开放注册
 	if s.settingService != nil && !s.settingService.IsRegistrationEnabled(ctx) {
-		log.Println("[Auth] Registration is disabled")
+		logger.FromContext(ctx).Info("send verify code rejected: registration disabled")
 		return nil, ErrRegDisabled
 	}
 
 	// 检查邮箱是否已存在
 	existsEmail, err := s.userRepo.ExistsByEmail(ctx, email)
 	if err != nil {
-		log.Printf("[Auth] Database error checking email exists: %v", err)
+		logger.FromContext(ctx).Error("check email exists failed", logger.Err(err))
 		return nil, ErrServiceUnavailable
 	}
 	if existsEmail {
-		log.Printf("[Auth] Email already exists: %s", email)
+		logger.FromContext(ctx).Info("send verify code rejected: email already exists", "email", email)
 		return nil, ErrEmailExists
 	}
 
 	// 检查邮件队列服务是否配置
 	if s.emailQueueService == nil {
-		log.Println("[Auth] Email queue service not configured")
+		logger.FromContext(ctx).Error("email queue service not configured")
 		return nil, errors.New("email queue service not configured")
 	}
 
@@ -205,13 +217,13 @@ func (s *AuthService) SendVerifyCodeAsync(ctx context.Context, email string) (*S
 	}
 
 	// 异步发送
-	log.Printf("[Auth] Enqueueing verify code for: %s", email)
+	logger.FromContext(ctx).Debug("enqueueing verify code", "email", email)
 	if err := s.emailQueueService.EnqueueVerifyCode(email, siteName); err != nil {
-		log.Printf("[Auth] Failed to enqueue: %v", err)
+		logger.FromContext(ctx).Error("enqueue verify code failed", "email", email, logger.Err(err))
 		return nil, fmt.Errorf("enqueue verify code: %w", err)
 	}
 
-	log.Printf("[Auth] Verify code enqueued successfully for: %s", email)
+	logger.FromContext(ctx).Info("verify code enqueued", "email", email)
 	return &SendVerifyCodeResult{
 		Countdown: 60, // 60秒倒计时
 	}, nil
@@ -249,41 +261,85 @@ func (s *AuthService) IsEmailVerifyEnabled(ctx context.Context) bool {
 	return s.settingService.IsEmailVerifyEnabled(ctx)
 }
 
-// Login 用户登录，返回JWT token
-func (s *AuthService) Login(ctx context.Context, email, password string) (string, *User, error) {
+// Login 用户登录。未启用二次验证时直接返回JWT token，
+// 否则返回短期有效的预认证 token，完成二次验证后再签发正式 token
+func (s *AuthService) Login(ctx context.Context, email, password string) (*LoginResult, error) {
 	// 查找用户
 	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, ErrUserNotFound) {
-			return "", nil, ErrInvalidCredentials
+			return nil, ErrInvalidCredentials
 		}
 		// 记录数据库错误但不暴露给用户
-		log.Printf("[Auth] Database error during login: %v", err)
-		return "", nil, ErrServiceUnavailable
+		logger.FromContext(ctx).Error("load user during login failed", logger.Err(err))
+		return nil, ErrServiceUnavailable
 	}
 
 	// 验证密码
 	if !s.CheckPassword(password, user.PasswordHash) {
-		return "", nil, ErrInvalidCredentials
+		return nil, ErrInvalidCredentials
 	}
 
 	// 检查用户状态
 	if !user.IsActive() {
-		return "", nil, ErrUserNotActive
+		return nil, ErrUserNotActive
+	}
+
+	return s.issueLogin(ctx, user)
+}
+
+// issueLogin 已通过第一步认证的用户：需要二次验证时返回预认证 token，否则直接签发 token
+func (s *AuthService) issueLogin(ctx context.Context, user *User) (*LoginResult, error) {
+	// 检查二次验证
+	if s.twoFactorService != nil {
+		status, err := s.twoFactorService.Status(ctx, user)
+		if err != nil {
+			logger.FromContext(ctx).Error("load 2fa status during login failed", logger.Err(err))
+			return nil, ErrServiceUnavailable
+		}
+		if status.Enrolled() || status.Required {
+			preAuthToken, err := s.generatePreAuthToken(user)
+			if err != nil {
+				return nil, fmt.Errorf("generate pre-auth token: %w", err)
+			}
+			return &LoginResult{
+				User:               user,
+				PreAuthToken:       preAuthToken,
+				TwoFactorMethods:   status.Methods(),
+				EnrollmentRequired: !status.Enrolled(),
+			}, nil
+		}
 	}
 
 	// 生成JWT token
 	token, err := s.GenerateToken(user)
 	if err != nil {
-		return "", nil, fmt.Errorf("generate token: %w", err)
+		return nil, fmt.Errorf("generate token: %w", err)
 	}
 
-	return token, user, nil
+	return &LoginResult{Token: token, User: user}, nil
 }
 
-// ValidateToken 验证JWT token并返回用户声明
-func (s *AuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (any, error) {
+// ValidateToken 验证JWT token，并加载用户校验 token 版本
+func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*JWTClaims, *User, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, nil, ErrInvalidToken
+	}
+	user, err := s.loadTokenUser(ctx, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+	return claims, user, nil
+}
+
+// parseToken 解析并校验签名。过期时同时返回声明与 ErrTokenExpired，供刷新使用
+func (s *AuthService) parseToken(tokenString string) (*JWTClaims, error) {
+	claims := &JWTClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
 		// 验证签名方法
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -293,16 +349,31 @@ func (s *AuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, ErrTokenExpired
+			return claims, ErrTokenExpired
 		}
 		return nil, ErrInvalidToken
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+	if !token.Valid {
+		return nil, ErrInvalidToken
 	}
+	return claims, nil
+}
 
-	return nil, ErrInvalidToken
+// loadTokenUser 获取 token 对应的用户，版本号不一致说明 token 已被吊销
+func (s *AuthService) loadTokenUser(ctx context.Context, claims *JWTClaims) (*User, error) {
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		logger.FromContext(ctx).Error("load user during token validation failed", logger.Err(err))
+		return nil, ErrServiceUnavailable
+	}
+	if user.TokenVersion != claims.TokenVersion {
+		return nil, ErrTokenRevoked
+	}
+	return user, nil
 }
 
 // GenerateToken 生成JWT token
@@ -311,9 +382,10 @@ func (s *AuthService) GenerateToken(user *User) (string, error) {
 	expiresAt := now.Add(time.Duration(s.cfg.JWT.ExpireHour) * time.Hour)
 
 	claims := &JWTClaims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:       user.ID,
+		Email:        user.Email,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -348,19 +420,21 @@ func (s *AuthService) CheckPassword(password, hashedPassword string) bool {
 // RefreshToken 刷新token
 func (s *AuthService) RefreshToken(ctx context.Context, oldTokenString string) (string, error) {
 	// 验证旧token（即使过期也允许，用于刷新）
-	claims, err := s.ValidateToken(oldTokenString)
+	claims, err := s.parseToken(oldTokenString)
 	if err != nil && !errors.Is(err, ErrTokenExpired) {
 		return "", err
 	}
+	if claims.Purpose != "" {
+		return "", ErrInvalidToken
+	}
 
-	// 获取最新的用户信息
-	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	// 获取最新的用户信息，已吊销的 token 不允许刷新
+	user, err := s.loadTokenUser(ctx, claims)
 	if err != nil {
 		if errors.Is(err, ErrUserNotFound) {
 			return "", ErrInvalidToken
 		}
-		log.Printf("[Auth] Database error refreshing token: %v", err)
-		return "", ErrServiceUnavailable
+		return "", err
 	}
 
 	// 检查用户状态
@@ -371,3 +445,437 @@ func (s *AuthService) RefreshToken(ctx context.Context, oldTokenString string) (
 	// 生成新token
 	return s.GenerateToken(user)
 }
+
+// 找回密码与修改邮箱的发送频率限制
+const (
+	accountEmailRateWindow   = time.Hour
+	accountEmailLimitPerAddr = 5
+	accountEmailLimitPerIP   = 20
+)
+
+// checkAccountEmailRateLimit 按邮箱和来源 IP 限制验证码发送频率
+func (s *AuthService) checkAccountEmailRateLimit(ctx context.Context, purpose, email, clientIP string) error {
+	if err := s.emailService.CheckRateLimit(ctx, purpose+":email:"+email, accountEmailLimitPerAddr, accountEmailRateWindow); err != nil {
+		return err
+	}
+	if clientIP == "" {
+		return nil
+	}
+	return s.emailService.CheckRateLimit(ctx, purpose+":ip:"+clientIP, accountEmailLimitPerIP, accountEmailRateWindow)
+}
+
+// enqueueAccountVerifyCode 异步发送指定用途的验证码
+func (s *AuthService) enqueueAccountVerifyCode(ctx context.Context, purpose, email string) error {
+	if s.emailService == nil || s.emailQueueService == nil {
+		logger.FromContext(ctx).Error("email service not configured")
+		return errors.New("email service not configured")
+	}
+
+	siteName := "Sub2API"
+	if s.settingService != nil {
+		siteName = s.settingService.GetSiteName(ctx)
+	}
+	if err := s.emailQueueService.EnqueueVerifyCodeFor(purpose, email, siteName); err != nil {
+		logger.FromContext(ctx).Error("enqueue verify code failed", "email", email, "purpose", purpose, logger.Err(err))
+		return fmt.Errorf("enqueue verify code: %w", err)
+	}
+	return nil
+}
+
+// RequestPasswordReset 发送重置密码验证码。
+// 邮箱不存在或用户被禁用时同样返回成功，避免泄露账号是否存在。
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email, clientIP string) (*SendVerifyCodeResult, error) {
+	if s.emailService == nil {
+		return nil, errors.New("email service not configured")
+	}
+	if err := s.checkAccountEmailRateLimit(ctx, VerifyPurposePasswordReset, email, clientIP); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			logger.FromContext(ctx).Error("load user during password reset failed", logger.Err(err))
+			return nil, ErrServiceUnavailable
+		}
+		logger.FromContext(ctx).Info("password reset requested for unknown email", "email", email)
+	} else if user.IsActive() {
+		if err := s.enqueueAccountVerifyCode(ctx, VerifyPurposePasswordReset, email); err != nil {
+			return nil, err
+		}
+	}
+
+	return &SendVerifyCodeResult{Countdown: 60}, nil
+}
+
+// ResetPassword 校验重置验证码并设置新密码，同时吊销该用户已签发的所有 token
+func (s *AuthService) ResetPassword(ctx context.Context, email, verifyCode, newPassword string) error {
+	if s.emailService == nil {
+		return errors.New("email service not configured")
+	}
+	if err := s.emailService.VerifyCodeFor(ctx, VerifyPurposePasswordReset, email, verifyCode); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return ErrInvalidVerifyCode
+		}
+		logger.FromContext(ctx).Error("load user during password reset failed", logger.Err(err))
+		return ErrServiceUnavailable
+	}
+	if !user.IsActive() {
+		return ErrUserNotActive
+	}
+
+	hashedPassword, err := s.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+	user.PasswordHash = hashedPassword
+	user.TokenVersion++
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		logger.FromContext(ctx).Error("update user during password reset failed", logger.Err(err))
+		return ErrServiceUnavailable
+	}
+	logger.FromContext(ctx).Info("password reset completed", "user_id", user.ID)
+	return nil
+}
+
+// SendChangeEmailCode 向新邮箱发送修改邮箱验证码
+func (s *AuthService) SendChangeEmailCode(ctx context.Context, userID int64, newEmail, clientIP string) (*SendVerifyCodeResult, error) {
+	if s.emailService == nil {
+		return nil, errors.New("email service not configured")
+	}
+
+	existsEmail, err := s.userRepo.ExistsByEmail(ctx, newEmail)
+	if err != nil {
+		logger.FromContext(ctx).Error("check email exists failed", logger.Err(err))
+		return nil, ErrServiceUnavailable
+	}
+	if existsEmail {
+		return nil, ErrEmailExists
+	}
+
+	purpose := VerifyPurposeChangeEmail(userID)
+	if err := s.checkAccountEmailRateLimit(ctx, purpose, newEmail, clientIP); err != nil {
+		return nil, err
+	}
+	if err := s.enqueueAccountVerifyCode(ctx, purpose, newEmail); err != nil {
+		return nil, err
+	}
+	return &SendVerifyCodeResult{Countdown: 60}, nil
+}
+
+// ChangeEmail 校验当前密码和新邮箱验证码后修改邮箱。
+// 旧 token 随之失效，返回新 token 供当前会话继续使用。
+func (s *AuthService) ChangeEmail(ctx context.Context, userID int64, newEmail, verifyCode, password string) (string, *User, error) {
+	if s.emailService == nil {
+		return "", nil, errors.New("email service not configured")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("get user: %w", err)
+	}
+	if !s.CheckPassword(password, user.PasswordHash) {
+		return "", nil, ErrPasswordIncorrect
+	}
+	if err := s.emailService.VerifyCodeFor(ctx, VerifyPurposeChangeEmail(userID), newEmail, verifyCode); err != nil {
+		return "", nil, err
+	}
+
+	user.Email = newEmail
+	user.TokenVersion++
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		if errors.Is(err, ErrEmailExists) {
+			return "", nil, ErrEmailExists
+		}
+		logger.FromContext(ctx).Error("update user during email change failed", logger.Err(err))
+		return "", nil, ErrServiceUnavailable
+	}
+
+	token, err := s.GenerateToken(user)
+	if err != nil {
+		return "", nil, fmt.Errorf("generate token: %w", err)
+	}
+	return token, user, nil
+}
+
+// 预认证 token 仅用于完成登录二次验证
+const (
+	tokenPurposePreAuth = "2fa"
+	PreAuthTokenTTL     = 5 * time.Minute
+)
+
+// LoginResult 登录结果。PreAuthToken 非空时需要继续完成二次验证
+type LoginResult struct {
+	Token string
+	User  *User
+
+	PreAuthToken     string
+	TwoFactorMethods []string
+	// 账号必须启用二次验证但尚未设置，需先用预认证 token 完成 TOTP 绑定
+	EnrollmentRequired bool
+}
+
+// RequiresTwoFactor 是否需要继续完成二次验证
+func (r *LoginResult) RequiresTwoFactor() bool {
+	return r.PreAuthToken != ""
+}
+
+// TwoFactorLoginResult 完成二次验证后的登录结果
+type TwoFactorLoginResult struct {
+	Token string
+	User  *User
+	// 首次绑定 TOTP 时返回的恢复码
+	RecoveryCodes []string
+}
+
+func (s *AuthService) generatePreAuthToken(user *User) (string, error) {
+	now := time.Now()
+	claims := &JWTClaims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
+		Purpose:      tokenPurposePreAuth,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(PreAuthTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.cfg.JWT.Secret))
+	if err != nil {
+		return "", fmt.Errorf("sign token: %w", err)
+	}
+	return tokenString, nil
+}
+
+// validatePreAuthToken 校验预认证 token 并返回对应的有效用户
+func (s *AuthService) validatePreAuthToken(ctx context.Context, tokenString string) (*User, error) {
+	if s.twoFactorService == nil {
+		return nil, ErrInvalidToken
+	}
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != tokenPurposePreAuth {
+		return nil, ErrInvalidToken
+	}
+	user, err := s.loadTokenUser(ctx, claims)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if !user.IsActive() {
+		return nil, ErrUserNotActive
+	}
+	return user, nil
+}
+
+func (s *AuthService) completeTwoFactorLogin(user *User, recoveryCodes []string) (*TwoFactorLoginResult, error) {
+	token, err := s.GenerateToken(user)
+	if err != nil {
+		return nil, fmt.Errorf("generate token: %w", err)
+	}
+	return &TwoFactorLoginResult{Token: token, User: user, RecoveryCodes: recoveryCodes}, nil
+}
+
+// VerifyTwoFactor 使用 TOTP 验证码或恢复码完成登录
+func (s *AuthService) VerifyTwoFactor(ctx context.Context, preAuthToken, code string) (*TwoFactorLoginResult, error) {
+	user, err := s.validatePreAuthToken(ctx, preAuthToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorService.VerifyCode(ctx, user.ID, code); err != nil {
+		logger.FromContext(ctx).Info("2fa verification failed", "user_id", user.ID, logger.Err(err))
+		return nil, err
+	}
+	return s.completeTwoFactorLogin(user, nil)
+}
+
+// BeginWebAuthnLogin 使用预认证 token 开始安全密钥验证
+func (s *AuthService) BeginWebAuthnLogin(ctx context.Context, preAuthToken string) (*protocol.CredentialAssertion, error) {
+	user, err := s.validatePreAuthToken(ctx, preAuthToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.twoFactorService.BeginWebAuthnLogin(ctx, user)
+}
+
+// FinishWebAuthnLogin 校验安全密钥签名并完成登录
+func (s *AuthService) FinishWebAuthnLogin(ctx context.Context, preAuthToken string, response []byte) (*TwoFactorLoginResult, error) {
+	user, err := s.validatePreAuthToken(ctx, preAuthToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorService.FinishWebAuthnLogin(ctx, user, response); err != nil {
+		logger.FromContext(ctx).Info("webauthn verification failed", "user_id", user.ID, logger.Err(err))
+		return nil, err
+	}
+	return s.completeTwoFactorLogin(user, nil)
+}
+
+// requireUnenrolled 仅允许尚未设置任何二次验证方式的账号在登录阶段绑定 TOTP，
+// 避免仅凭密码即可为已启用二次验证的账号新增验证方式
+func (s *AuthService) requireUnenrolled(ctx context.Context, user *User) error {
+	status, err := s.twoFactorService.Status(ctx, user)
+	if err != nil {
+		return err
+	}
+	if status.Enrolled() {
+		return ErrTwoFactorAlreadyEnrolled
+	}
+	return nil
+}
+
+// BeginTwoFactorEnrollment 必须启用二次验证的账号在登录阶段生成 TOTP 密钥
+func (s *AuthService) BeginTwoFactorEnrollment(ctx context.Context, preAuthToken string) (*TOTPSetup, error) {
+	user, err := s.validatePreAuthToken(ctx, preAuthToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireUnenrolled(ctx, user); err != nil {
+		return nil, err
+	}
+	return s.twoFactorService.SetupTOTP(ctx, user)
+}
+
+// CompleteTwoFactorEnrollment 确认 TOTP 绑定并完成登录
+func (s *AuthService) CompleteTwoFactorEnrollment(ctx context.Context, preAuthToken, code string) (*TwoFactorLoginResult, error) {
+	user, err := s.validatePreAuthToken(ctx, preAuthToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireUnenrolled(ctx, user); err != nil {
+		return nil, err
+	}
+	recoveryCodes, err := s.twoFactorService.EnableTOTP(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	return s.completeTwoFactorLogin(user, recoveryCodes)
+}
+
+// OIDCAuthorizationURL 生成单点登录跳转地址
+func (s *AuthService) OIDCAuthorizationURL(ctx context.Context) (string, error) {
+	return s.oidcService.AuthorizationURL(ctx)
+}
+
+// LoginWithOIDC 使用 IdP 回调的 code 登录。已绑定的身份直接登录；未绑定时按已验证的邮箱关联
+// 现有账号，或在开启自动创建时创建新账号。每次登录都会按配置同步角色与可用分组。
+func (s *AuthService) LoginWithOIDC(ctx context.Context, code, state string) (*LoginResult, error) {
+	identity, cfg, err := s.oidcService.Authenticate(ctx, code, state)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveOIDCUser(ctx, identity, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive() {
+		return nil, ErrUserNotActive
+	}
+
+	if cfg.applyGroups(user, identity.Groups) {
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			logger.FromContext(ctx).Error("sync oidc groups failed", "user_id", user.ID, logger.Err(err))
+			return nil, ErrServiceUnavailable
+		}
+		logger.FromContext(ctx).Info("oidc groups synced", "user_id", user.ID, "role", user.Role, "allowed_groups", user.AllowedGroups)
+	}
+
+	return s.issueLogin(ctx, user)
+}
+
+func (s *AuthService) resolveOIDCUser(ctx context.Context, identity *OIDCIdentity, cfg *OIDCConfig) (*User, error) {
+	userID, err := s.oidcService.LinkedUserID(ctx, identity)
+	if err != nil {
+		logger.FromContext(ctx).Error("load oidc identity failed", logger.Err(err))
+		return nil, ErrServiceUnavailable
+	}
+	if userID != 0 {
+		return s.userRepo.GetByID(ctx, userID)
+	}
+
+	if identity.Email == "" {
+		return nil, ErrOIDCEmailMissing
+	}
+	user, err := s.userRepo.GetByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		// 仅在 IdP 确认邮箱归属时关联已有账号，防止通过伪造邮箱接管账号
+		if !identity.EmailVerified {
+			return nil, ErrOIDCEmailNotVerified
+		}
+	case errors.Is(err, ErrUserNotFound):
+		if !cfg.AutoProvision {
+			return nil, ErrOIDCUserNotProvisioned
+		}
+		if user, err = s.provisionOIDCUser(ctx, identity); err != nil {
+			return nil, err
+		}
+	default:
+		logger.FromContext(ctx).Error("load user during oidc login failed", logger.Err(err))
+		return nil, ErrServiceUnavailable
+	}
+
+	if err := s.oidcService.Link(ctx, user.ID, identity); err != nil {
+		if errors.Is(err, ErrOIDCIdentityConflict) {
+			return nil, ErrOIDCIdentityConflict
+		}
+		logger.FromContext(ctx).Error("link oidc identity failed", logger.Err(err))
+		return nil, ErrServiceUnavailable
+	}
+	logger.FromContext(ctx).Info("oidc identity linked", "user_id", user.ID, "issuer", identity.Issuer)
+	return user, nil
+}
+
+// provisionOIDCUser 首次单点登录时创建账号，使用随机密码，用户可通过找回密码设置本地密码
+func (s *AuthService) provisionOIDCUser(ctx context.Context, identity *OIDCIdentity) (*User, error) {
+	password, err := oauth.GenerateState()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := s.HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("hash password: %w", err)
+	}
+
+	defaultBalance := s.cfg.Default.UserBalance
+	defaultConcurrency := s.cfg.Default.UserConcurrency
+	if s.settingService != nil {
+		defaultBalance = s.settingService.GetDefaultBalance(ctx)
+		defaultConcurrency = s.settingService.GetDefaultConcurrency(ctx)
+	}
+
+	// 与 users.username 列长度一致
+	username := []rune(identity.Name)
+	if len(username) > 100 {
+		username = username[:100]
+	}
+
+	user := &User{
+		Email:        identity.Email,
+		Username:     string(username),
+		PasswordHash: hashedPassword,
+		Role:         RoleUser,
+		Balance:      defaultBalance,
+		Concurrency:  defaultConcurrency,
+		Status:       StatusActive,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		logger.FromContext(ctx).Error("create oidc user failed", logger.Err(err))
+		return nil, ErrServiceUnavailable
+	}
+	logger.FromContext(ctx).Info("oidc user provisioned", "user_id", user.ID)
+	return user, nil
+}
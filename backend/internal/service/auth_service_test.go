@@ -0,0 +1,181 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type authUserRepoStub struct {
+	UserRepository
+	users map[int64]*User
+}
+
+func (s *authUserRepoStub) GetByID(ctx context.Context, id int64) (*User, error) {
+	if u, ok := s.users[id]; ok {
+		cp := *u
+		return &cp, nil
+	}
+	return nil, ErrUserNotFound
+}
+
+func (s *authUserRepoStub) GetByEmail(ctx context.Context, email string) (*User, error) {
+	for _, u := range s.users {
+		if u.Email == email {
+			cp := *u
+			return &cp, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (s *authUserRepoStub) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	_, err := s.GetByEmail(ctx, email)
+	return err == nil, nil
+}
+
+func (s *authUserRepoStub) Create(ctx context.Context, user *User) error {
+	user.ID = int64(len(s.users) + 1)
+	cp := *user
+	s.users[user.ID] = &cp
+	return nil
+}
+
+func (s *authUserRepoStub) Update(ctx context.Context, user *User) error {
+	cp := *user
+	s.users[user.ID] = &cp
+	return nil
+}
+
+type authEmailCacheStub struct {
+	codes map[string]*VerificationCodeData
+	rates map[string]int64
+}
+
+func (s *authEmailCacheStub) GetVerificationCode(ctx context.Context, email string) (*VerificationCodeData, error) {
+	if d, ok := s.codes[email]; ok {
+		return d, nil
+	}
+	return nil, ErrInvalidVerifyCode
+}
+
+func (s *authEmailCacheStub) SetVerificationCode(ctx context.Context, email string, data *VerificationCodeData, ttl time.Duration) error {
+	s.codes[email] = data
+	return nil
+}
+
+func (s *authEmailCacheStub) DeleteVerificationCode(ctx context.Context, email string) error {
+	delete(s.codes, email)
+	return nil
+}
+
+func (s *authEmailCacheStub) IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error) {
+	s.rates[key]++
+	return s.rates[key], nil
+}
+
+func newTestAuthService(t *testing.T) (*AuthService, *authUserRepoStub, *authEmailCacheStub, chan EmailTask) {
+	t.Helper()
+	repo := &authUserRepoStub{users: map[int64]*User{}}
+	cache := &authEmailCacheStub{codes: map[string]*VerificationCodeData{}, rates: map[string]int64{}}
+	queue := &EmailQueueService{taskChan: make(chan EmailTask, 10)}
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret", ExpireHour: 1}}
+	svc := NewAuthService(repo, cfg, nil, NewEmailService(nil, cache), nil, queue, nil, nil)
+
+	hash, err := svc.HashPassword("old-password")
+	require.NoError(t, err)
+	repo.users[1] = &User{ID: 1, Email: "a@example.com", PasswordHash: hash, Role: RoleUser, Status: StatusActive}
+	return svc, repo, cache, queue.taskChan
+}
+
+func TestAuthService_ResetPasswordRevokesTokens(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, cache, tasks := newTestAuthService(t)
+
+	oldLogin, err := svc.Login(ctx, "a@example.com", "old-password")
+	require.NoError(t, err)
+	_, _, err = svc.ValidateToken(ctx, oldLogin.Token)
+	require.NoError(t, err)
+
+	_, err = svc.RequestPasswordReset(ctx, "a@example.com", "1.2.3.4")
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	task := <-tasks
+	require.Equal(t, VerifyPurposePasswordReset, task.Purpose)
+
+	cache.codes[verifyCodeCacheKey(VerifyPurposePasswordReset, "a@example.com")] = &VerificationCodeData{Code: "123456", CreatedAt: time.Now()}
+	// 注册验证码不能用于重置密码
+	require.ErrorIs(t, svc.emailService.VerifyCode(ctx, "a@example.com", "123456"), ErrInvalidVerifyCode)
+
+	require.NoError(t, svc.ResetPassword(ctx, "a@example.com", "123456", "new-password"))
+	require.Equal(t, int64(1), repo.users[1].TokenVersion)
+
+	// 验证码只能使用一次
+	require.ErrorIs(t, svc.ResetPassword(ctx, "a@example.com", "123456", "other-password"), ErrInvalidVerifyCode)
+
+	_, _, err = svc.ValidateToken(ctx, oldLogin.Token)
+	require.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = svc.RefreshToken(ctx, oldLogin.Token)
+	require.ErrorIs(t, err, ErrTokenRevoked)
+
+	_, err = svc.Login(ctx, "a@example.com", "old-password")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+	newLogin, err := svc.Login(ctx, "a@example.com", "new-password")
+	require.NoError(t, err)
+	_, user, err := svc.ValidateToken(ctx, newLogin.Token)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), user.ID)
+}
+
+func TestAuthService_RequestPasswordResetHidesUnknownEmailAndRateLimits(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _, tasks := newTestAuthService(t)
+
+	for i := 0; i < accountEmailLimitPerAddr; i++ {
+		result, err := svc.RequestPasswordReset(ctx, "nobody@example.com", "")
+		require.NoError(t, err)
+		require.Equal(t, 60, result.Countdown)
+	}
+	require.Len(t, tasks, 0)
+
+	_, err := svc.RequestPasswordReset(ctx, "nobody@example.com", "")
+	require.ErrorIs(t, err, ErrEmailRateLimited)
+}
+
+func TestAuthService_ChangeEmail(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, cache, tasks := newTestAuthService(t)
+	hash, err := svc.HashPassword("pw2")
+	require.NoError(t, err)
+	repo.users[2] = &User{ID: 2, Email: "taken@example.com", PasswordHash: hash, Status: StatusActive}
+
+	_, err = svc.SendChangeEmailCode(ctx, 1, "taken@example.com", "")
+	require.ErrorIs(t, err, ErrEmailExists)
+
+	_, err = svc.SendChangeEmailCode(ctx, 1, "b@example.com", "")
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	require.Equal(t, VerifyPurposeChangeEmail(1), (<-tasks).Purpose)
+
+	cache.codes[verifyCodeCacheKey(VerifyPurposeChangeEmail(1), "b@example.com")] = &VerificationCodeData{Code: "654321", CreatedAt: time.Now()}
+
+	_, _, err = svc.ChangeEmail(ctx, 1, "b@example.com", "654321", "wrong-password")
+	require.ErrorIs(t, err, ErrPasswordIncorrect)
+	// 验证码绑定发起修改的用户
+	_, _, err = svc.ChangeEmail(ctx, 2, "b@example.com", "654321", "pw2")
+	require.ErrorIs(t, err, ErrInvalidVerifyCode)
+
+	token, user, err := svc.ChangeEmail(ctx, 1, "b@example.com", "654321", "old-password")
+	require.NoError(t, err)
+	require.Equal(t, "b@example.com", user.Email)
+	require.Equal(t, "b@example.com", repo.users[1].Email)
+
+	claims, _, err := svc.ValidateToken(ctx, token)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), claims.TokenVersion)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// balanceHoldTTL 预授权最长保留时间，超时后视为已释放（覆盖最长的流式请求）
+	balanceHoldTTL = 30 * time.Minute
+	// balanceHoldDefaultOutputTokens 请求未指定 max_tokens 时按此输出量估算
+	balanceHoldDefaultOutputTokens = 4096
+	// balanceHoldBytesPerToken 按请求体字节数粗略估算输入 token
+	balanceHoldBytesPerToken = 4
+	// balanceHoldMediaTokens 每个 base64 图片/文档按此 token 数估算，不按编码后的字节数计算
+	balanceHoldMediaTokens = 2000
+	// balanceHoldMinMediaBytes data 字段达到该长度时视为内联的 base64 媒体数据
+	balanceHoldMinMediaBytes = 1024
+)
+
+// BalanceHold 余额预授权
+// 请求转发前按最大可能费用占用可用余额，在 RecordUsage 中按实际费用结算后释放。
+// 组织 Key 的预授权按组织跟踪，同时占用成员当月剩余额度。
+type BalanceHold struct {
+	ID             string
+	UserID         int64
+	OrganizationID int64 // 组织 Key 的预授权，0 表示个人余额
+	Amount         float64
+
+	service *BillingCacheService
+	once    sync.Once
+}
+
+// Release 释放预授权，可重复调用；nil 安全
+func (h *BalanceHold) Release() {
+	if h == nil {
+		return
+	}
+	h.once.Do(func() {
+		h.service.releaseBalanceHold(h)
+	})
+}
+
+// Transfer 将预授权的释放责任转移给返回的新对象（交给异步的 RecordUsage 结算），
+// 之后对原对象调用 Release 不再生效；nil 安全
+func (h *BalanceHold) Transfer() *BalanceHold {
+	if h == nil {
+		return nil
+	}
+	moved := &BalanceHold{
+		ID:             h.ID,
+		UserID:         h.UserID,
+		OrganizationID: h.OrganizationID,
+		Amount:         h.Amount,
+		service:        h.service,
+	}
+	h.once.Do(func() {})
+	return moved
+}
+
+// estimateInputTokens 按请求体字节数估算输入 token。
+// base64 编码的图片/文档（Anthropic source.data、OpenAI data URI / file_data、Gemini inlineData.data）
+// 不计入字节数，每个按 balanceHoldMediaTokens 估算。
+func estimateInputTokens(body []byte) int {
+	mediaBytes, mediaCount := 0, 0
+	var walk func(v gjson.Result)
+	walk = func(v gjson.Result) {
+		v.ForEach(func(key, value gjson.Result) bool {
+			switch {
+			case value.IsObject() || value.IsArray():
+				walk(value)
+			case value.Type == gjson.String && isBase64Media(key.String(), value.Str):
+				mediaBytes += len(value.Raw)
+				mediaCount++
+			}
+			return true
+		})
+	}
+	walk(gjson.ParseBytes(body))
+	return max(len(body)-mediaBytes, 0)/balanceHoldBytesPerToken + mediaCount*balanceHoldMediaTokens
+}
+
+// isBase64Media 字段值是否为 data URI 或内联的 base64 媒体数据
+func isBase64Media(key, value string) bool {
+	if strings.HasPrefix(value, "data:") && strings.Contains(value[:min(len(value), 128)], ";base64,") {
+		return true
+	}
+	return (key == "data" || key == "file_data") && len(value) >= balanceHoldMinMediaBytes
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+)
+
+var (
+	ErrUsageAlreadyRefunded = infraerrors.Conflict("USAGE_ALREADY_REFUNDED", "usage log has already been refunded")
+	ErrUsageNotRefundable   = infraerrors.BadRequest("USAGE_NOT_REFUNDABLE", "only balance-billed usage with a positive cost can be refunded")
+)
+
+// BalanceTransactionFilters 余额流水查询条件
+type BalanceTransactionFilters struct {
+	UserID         int64
+	OrganizationID int64 // 为 0 且指定 UserID 时只查询个人余额流水
+	Type           string
+	StartTime      *time.Time
+	EndTime        *time.Time
+}
+
+// BalanceTransactionRepository 余额流水仓储。
+// 所有改变余额的方法都在同一个数据库事务中更新 users.balance（组织流水为 organizations.balance）并写入流水，
+// 并回填 tx.BalanceAfter / tx.ID。
+type BalanceTransactionRepository interface {
+	// Apply 按 tx.Amount 调整余额并写入流水；allowNegative=false 时余额不足返回 ErrInsufficientBalance
+	Apply(ctx context.Context, tx *BalanceTransaction, allowNegative bool) error
+	// SetBalance 将余额设置为 target，tx.Amount 由当前余额计算得出
+	SetBalance(ctx context.Context, tx *BalanceTransaction, target float64) error
+	// CreateUsageWithDebit 写入使用记录并扣费（允许扣成负数，保证已发生的费用全部入账）
+	CreateUsageWithDebit(ctx context.Context, usageLog *UsageLog, tx *BalanceTransaction) error
+	// RefundUsage 为使用记录退款，同一使用记录只能退款一次
+	RefundUsage(ctx context.Context, tx *BalanceTransaction) error
+
+	List(ctx context.Context, params pagination.PaginationParams, filters BalanceTransactionFilters) ([]BalanceTransaction, *pagination.PaginationResult, error)
+}
+
+// BalanceService 余额流水服务
+type BalanceService struct {
+	balanceTxRepo       BalanceTransactionRepository
+	usageLogRepo        UsageLogRepository
+	billingCacheService *BillingCacheService
+}
+
+// NewBalanceService 创建余额流水服务
+func NewBalanceService(balanceTxRepo BalanceTransactionRepository, usageLogRepo UsageLogRepository, billingCacheService *BillingCacheService) *BalanceService {
+	return &BalanceService{
+		balanceTxRepo:       balanceTxRepo,
+		usageLogRepo:        usageLogRepo,
+		billingCacheService: billingCacheService,
+	}
+}
+
+// ListByUser 获取用户自己的余额流水
+func (s *BalanceService) ListByUser(ctx context.Context, userID int64, params pagination.PaginationParams, filters BalanceTransactionFilters) ([]BalanceTransaction, *pagination.PaginationResult, error) {
+	filters.UserID = userID
+	txs, result, err := s.balanceTxRepo.List(ctx, params, filters)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list balance transactions: %w", err)
+	}
+	return txs, result, nil
+}
+
+// List 查询余额流水（管理员）
+func (s *BalanceService) List(ctx context.Context, params pagination.PaginationParams, filters BalanceTransactionFilters) ([]BalanceTransaction, *pagination.PaginationResult, error) {
+	txs, result, err := s.balanceTxRepo.List(ctx, params, filters)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list balance transactions: %w", err)
+	}
+	return txs, result, nil
+}
+
+// RefundUsage 退还一条使用记录的实际扣费（管理员）
+func (s *BalanceService) RefundUsage(ctx context.Context, usageLogID int64, operatorID int64, notes string) (*BalanceTransaction, error) {
+	usageLog, err := s.usageLogRepo.GetByID(ctx, usageLogID)
+	if err != nil {
+		return nil, err
+	}
+	// 订阅计费不扣余额，无需退款
+	if usageLog.BillingType != BillingTypeBalance || usageLog.ActualCost <= 0 {
+		return nil, ErrUsageNotRefundable
+	}
+
+	tx := &BalanceTransaction{
+		UserID:         usageLog.UserID,
+		OrganizationID: usageLog.OrganizationID,
+		Type:           BalanceTxTypeRefund,
+		Amount:         usageLog.ActualCost,
+		UsageLogID:     &usageLog.ID,
+		OperatorID:     &operatorID,
+		Notes:          notes,
+	}
+	if err := s.balanceTxRepo.RefundUsage(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	// 组织余额不使用缓存
+	if usageLog.OrganizationID == nil {
+		s.invalidateBalanceCache(usageLog.UserID)
+	}
+	return tx, nil
+}
+
+func (s *BalanceService) invalidateBalanceCache(userID int64) {
+	if s.billingCacheService == nil {
+		return
+	}
+	go func() {
+		cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.billingCacheService.InvalidateUserBalance(cacheCtx, userID); err != nil {
+			logger.FromContext(cacheCtx).Warn("invalidate user balance cache failed", "user_id", userID, logger.Err(err))
+		}
+	}()
+}
@@ -0,0 +1,54 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type balanceUsageRepoStub struct {
+	UsageLogRepository
+	log *UsageLog
+}
+
+func (s *balanceUsageRepoStub) GetByID(ctx context.Context, id int64) (*UsageLog, error) {
+	return s.log, nil
+}
+
+type balanceTxRepoStub struct {
+	BalanceTransactionRepository
+	refunded map[int64]bool
+}
+
+func (s *balanceTxRepoStub) RefundUsage(ctx context.Context, tx *BalanceTransaction) error {
+	if s.refunded[*tx.UsageLogID] {
+		return ErrUsageAlreadyRefunded
+	}
+	s.refunded[*tx.UsageLogID] = true
+	tx.BalanceAfter = 10 + tx.Amount
+	return nil
+}
+
+func TestBalanceService_RefundUsage(t *testing.T) {
+	ctx := context.Background()
+	usageRepo := &balanceUsageRepoStub{log: &UsageLog{ID: 3, UserID: 7, ActualCost: 1.5, BillingType: BillingTypeBalance}}
+	svc := NewBalanceService(&balanceTxRepoStub{refunded: map[int64]bool{}}, usageRepo, nil)
+
+	tx, err := svc.RefundUsage(ctx, 3, 1, "upstream error")
+	require.NoError(t, err)
+	require.Equal(t, BalanceTxTypeRefund, tx.Type)
+	require.Equal(t, int64(7), tx.UserID)
+	require.Equal(t, 1.5, tx.Amount)
+	require.Equal(t, 11.5, tx.BalanceAfter)
+	require.Equal(t, int64(1), *tx.OperatorID)
+
+	_, err = svc.RefundUsage(ctx, 3, 1, "")
+	require.ErrorIs(t, err, ErrUsageAlreadyRefunded)
+
+	usageRepo.log = &UsageLog{ID: 4, UserID: 7, ActualCost: 1.5, BillingType: BillingTypeSubscription}
+	_, err = svc.RefundUsage(ctx, 4, 1, "")
+	require.ErrorIs(t, err, ErrUsageNotRefundable)
+}
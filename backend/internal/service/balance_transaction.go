@@ -0,0 +1,33 @@
+package service
+
+import "time"
+
+// Balance transaction types
+const (
+	BalanceTxTypeUsage           = "usage"            // 使用扣费
+	BalanceTxTypeRedeem          = "redeem"           // 兑换码充值
+	BalanceTxTypeAdminAdjustment = "admin_adjustment" // 管理员调整
+	BalanceTxTypeRefund          = "refund"           // 退款
+)
+
+// BalanceTransaction 余额流水，每次余额变动对应一条记录
+type BalanceTransaction struct {
+	ID     int64
+	UserID int64
+	// OrganizationID 不为空时变动的是组织余额，UserID 为产生变动的成员
+	OrganizationID *int64
+	Type           string
+	// Amount 变动金额，正数为入账，负数为扣减
+	Amount float64
+	// BalanceAfter 本次变动后的余额
+	BalanceAfter float64
+
+	UsageLogID   *int64
+	RedeemCodeID *int64
+	OperatorID   *int64 // 管理员操作人
+	Notes        string
+
+	CreatedAt time.Time
+
+	User *User
+}
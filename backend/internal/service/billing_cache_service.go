@@ -2,11 +2,15 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+
+	"github.com/google/uuid"
 )
 
 // 错误定义
@@ -29,17 +33,21 @@ type subscriptionCacheData struct {
 // BillingCacheService 计费缓存服务
 // 负责余额和订阅数据的缓存管理，提供高性能的计费资格检查
 type BillingCacheService struct {
-	cache    BillingCache
-	userRepo UserRepository
-	subRepo  UserSubscriptionRepository
+	cache          BillingCache
+	userRepo       UserRepository
+	subRepo        UserSubscriptionRepository
+	orgRepo        OrganizationRepository
+	billingService *BillingService
 }
 
 // NewBillingCacheService 创建计费缓存服务
-func NewBillingCacheService(cache BillingCache, userRepo UserRepository, subRepo UserSubscriptionRepository) *BillingCacheService {
+func NewBillingCacheService(cache BillingCache, userRepo UserRepository, subRepo UserSubscriptionRepository, orgRepo OrganizationRepository, billingService *BillingService) *BillingCacheService {
 	return &BillingCacheService{
-		cache:    cache,
-		userRepo: userRepo,
-		subRepo:  subRepo,
+		cache:          cache,
+		userRepo:       userRepo,
+		subRepo:        subRepo,
+		orgRepo:        orgRepo,
+		billingService: billingService,
 	}
 }
 
@@ -91,7 +99,7 @@ func (s *BillingCacheService) setBalanceCache(ctx context.Context, userID int64,
 		return
 	}
 	if err := s.cache.SetUserBalance(ctx, userID, balance); err != nil {
-		log.Printf("Warning: set balance cache failed for user %d: %v", userID, err)
+		logger.FromContext(ctx).Warn("set balance cache failed", "user_id", userID, logger.Err(err))
 	}
 }
 
@@ -109,12 +117,154 @@ func (s *BillingCacheService) InvalidateUserBalance(ctx context.Context, userID
 		return nil
 	}
 	if err := s.cache.InvalidateUserBalance(ctx, userID); err != nil {
-		log.Printf("Warning: invalidate balance cache failed for user %d: %v", userID, err)
+		logger.FromContext(ctx).Warn("invalidate balance cache failed", "user_id", userID, logger.Err(err))
 		return err
 	}
 	return nil
 }
 
+// ============================================
+// 余额预授权方法
+// ============================================
+
+// ReserveBalance 为余额模式的请求按最大可能费用预授权
+// 费用按输入体积与 max_tokens 估算；可用余额（余额减去未结算预授权）不足时返回 ErrInsufficientBalance。
+// 组织 Key 按组织余额预授权，并受成员当月剩余额度限制（不足时返回 ErrOrgMemberSpendingLimit）。
+// 订阅模式、无法估算费用或缓存异常时返回 nil（不预授权）。
+func (s *BillingCacheService) ReserveBalance(ctx context.Context, apiKey *ApiKey, subscription *UserSubscription, model string, body []byte, maxOutputTokens int) (*BalanceHold, error) {
+	group := apiKey.Group
+	if group != nil && group.IsSubscriptionType() && subscription != nil {
+		return nil, nil
+	}
+
+	amount := s.estimateRequestCost(ctx, model, body, maxOutputTokens, group)
+	if amount <= 0 {
+		return nil, nil
+	}
+	if apiKey.IsOrganizationOwned() {
+		return s.reserveOrgBalance(ctx, apiKey, amount)
+	}
+
+	balance, err := s.GetUserBalance(ctx, apiKey.UserID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("get user balance failed, skipping balance hold", logger.Err(err))
+		return nil, nil
+	}
+
+	// Redis 不可用时无法跟踪并发请求的预授权，仅校验单次请求
+	if s.cache == nil {
+		if balance-amount < 0 {
+			return nil, ErrInsufficientBalance
+		}
+		return nil, nil
+	}
+
+	hold := &BalanceHold{
+		ID:      uuid.New().String(),
+		UserID:  apiKey.UserID,
+		Amount:  amount,
+		service: s,
+	}
+	ok, err := s.cache.ReserveBalanceHold(ctx, hold.UserID, hold.ID, amount, balance, balanceHoldTTL)
+	if err != nil {
+		logger.FromContext(ctx).Warn("reserve balance hold failed, allowing request", "user_id", apiKey.UserID, logger.Err(err))
+		return nil, nil
+	}
+	if !ok {
+		return nil, ErrInsufficientBalance
+	}
+	return hold, nil
+}
+
+// reserveOrgBalance 组织 Key 的预授权：按组织 ID 跟踪，可用额度为组织余额与成员当月剩余额度中的较小者。
+// 组织余额不缓存，每次从数据库读取；结算扣费先于释放预授权，两者之间不会出现额度空窗。
+func (s *BillingCacheService) reserveOrgBalance(ctx context.Context, apiKey *ApiKey, amount float64) (*BalanceHold, error) {
+	if s.orgRepo == nil {
+		return nil, nil
+	}
+	orgID := *apiKey.OrganizationID
+	org, err := s.orgRepo.GetByID(ctx, orgID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("get organization failed, skipping balance hold", "organization_id", orgID, logger.Err(err))
+		return nil, nil
+	}
+	member, err := s.orgRepo.GetMember(ctx, orgID, apiKey.UserID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("get organization member failed, skipping balance hold", "organization_id", orgID, logger.Err(err))
+		return nil, nil
+	}
+	memberLimit := -1.0 // 不限制
+	if member.HasMonthlyLimit() {
+		memberLimit = max(*member.MonthlyLimitUSD-member.CurrentMonthUsage(time.Now()), 0)
+	}
+
+	// Redis 不可用时无法跟踪并发请求的预授权，仅校验单次请求
+	if s.cache == nil {
+		if org.Balance-amount < 0 {
+			return nil, ErrInsufficientBalance
+		}
+		if memberLimit >= 0 && memberLimit-amount < 0 {
+			return nil, ErrOrgMemberSpendingLimit
+		}
+		return nil, nil
+	}
+
+	hold := &BalanceHold{
+		ID:             uuid.New().String(),
+		UserID:         apiKey.UserID,
+		OrganizationID: orgID,
+		Amount:         amount,
+		service:        s,
+	}
+	err = s.cache.ReserveOrgBalanceHold(ctx, orgID, hold.UserID, hold.ID, amount, org.Balance, memberLimit, balanceHoldTTL)
+	if errors.Is(err, ErrInsufficientBalance) || errors.Is(err, ErrOrgMemberSpendingLimit) {
+		return nil, err
+	}
+	if err != nil {
+		logger.FromContext(ctx).Warn("reserve organization balance hold failed, allowing request", "organization_id", orgID, "user_id", apiKey.UserID, logger.Err(err))
+		return nil, nil
+	}
+	return hold, nil
+}
+
+// estimateRequestCost 估算请求最大费用（已计入分组倍率）
+func (s *BillingCacheService) estimateRequestCost(ctx context.Context, model string, body []byte, maxOutputTokens int, group *Group) float64 {
+	if s.billingService == nil || model == "" {
+		return 0
+	}
+	if maxOutputTokens <= 0 {
+		maxOutputTokens = balanceHoldDefaultOutputTokens
+	}
+	inputTokens := estimateInputTokens(body)
+
+	cost, err := s.billingService.GetEstimatedCost(model, inputTokens, maxOutputTokens)
+	if err != nil {
+		logger.FromContext(ctx).Warn("estimate cost failed, skipping balance hold", "model", model, logger.Err(err))
+		return 0
+	}
+	if group != nil && group.RateMultiplier > 0 {
+		cost *= group.RateMultiplier
+	}
+	return cost
+}
+
+func (s *BillingCacheService) releaseBalanceHold(hold *BalanceHold) {
+	if s.cache == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if hold.OrganizationID != 0 {
+		if err := s.cache.ReleaseOrgBalanceHold(ctx, hold.OrganizationID, hold.UserID, hold.ID); err != nil {
+			slog.Warn("release organization balance hold failed", "organization_id", hold.OrganizationID, "hold_id", hold.ID, logger.Err(err))
+		}
+		return
+	}
+	if err := s.cache.ReleaseBalanceHold(ctx, hold.UserID, hold.ID); err != nil {
+		slog.Warn("release balance hold failed", "user_id", hold.UserID, "hold_id", hold.ID, logger.Err(err))
+	}
+}
+
 // ============================================
 // 订阅缓存方法
 // ============================================
@@ -192,7 +342,7 @@ func (s *BillingCacheService) setSubscriptionCache(ctx context.Context, userID,
 		return
 	}
 	if err := s.cache.SetSubscriptionCache(ctx, userID, groupID, s.convertToPortsData(data)); err != nil {
-		log.Printf("Warning: set subscription cache failed for user %d group %d: %v", userID, groupID, err)
+		logger.FromContext(ctx).Warn("set subscription cache failed", "user_id", userID, "group_id", groupID, logger.Err(err))
 	}
 }
 
@@ -210,7 +360,7 @@ func (s *BillingCacheService) InvalidateSubscription(ctx context.Context, userID
 		return nil
 	}
 	if err := s.cache.InvalidateSubscriptionCache(ctx, userID, groupID); err != nil {
-		log.Printf("Warning: invalidate subscription cache failed for user %d group %d: %v", userID, groupID, err)
+		logger.FromContext(ctx).Warn("invalidate subscription cache failed", "user_id", userID, "group_id", groupID, logger.Err(err))
 		return err
 	}
 	return nil
@@ -227,6 +377,14 @@ func (s *BillingCacheService) CheckBillingEligibility(ctx context.Context, user
 	// 判断计费模式
 	isSubscriptionMode := group != nil && group.IsSubscriptionType() && subscription != nil
 
+	// 组织 Key：余额与成员上限已在认证阶段按数据库校验，并发请求由 ReserveBalance 按组织预授权；订阅不使用按用户维度的缓存
+	if apiKey != nil && apiKey.IsOrganizationOwned() {
+		if isSubscriptionMode {
+			return s.checkSubscriptionLimitsFallback(subscription, group)
+		}
+		return nil
+	}
+
 	if isSubscriptionMode {
 		return s.checkSubscriptionEligibility(ctx, user.ID, group, subscription)
 	}
@@ -239,7 +397,7 @@ func (s *BillingCacheService) checkBalanceEligibility(ctx context.Context, userI
 	balance, err := s.GetUserBalance(ctx, userID)
 	if err != nil {
 		// 缓存/数据库错误，允许通过（降级处理）
-		log.Printf("Warning: get user balance failed, allowing request: %v", err)
+		logger.FromContext(ctx).Warn("get user balance failed, allowing request", logger.Err(err))
 		return nil
 	}
 
@@ -256,7 +414,7 @@ func (s *BillingCacheService) checkSubscriptionEligibility(ctx context.Context,
 	subData, err := s.GetSubscriptionStatus(ctx, userID, group.ID)
 	if err != nil {
 		// 缓存/数据库错误，降级使用传入的subscription进行检查
-		log.Printf("Warning: get subscription cache failed, using fallback: %v", err)
+		logger.FromContext(ctx).Warn("get subscription cache failed, using fallback", logger.Err(err))
 		return s.checkSubscriptionLimitsFallback(subscription, group)
 	}
 
@@ -0,0 +1,166 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// orgHoldCacheStub 内存实现的组织预授权，与 Redis 脚本一样在锁内原子地检查并占用额度
+type orgHoldCacheStub struct {
+	BillingCache
+	mu          sync.Mutex
+	orgHolds    map[int64]map[string]float64
+	memberHolds map[int64]map[string]float64 // key: userID（单组织测试）
+}
+
+func newOrgHoldCacheStub() *orgHoldCacheStub {
+	return &orgHoldCacheStub{
+		orgHolds:    map[int64]map[string]float64{},
+		memberHolds: map[int64]map[string]float64{},
+	}
+}
+
+func sumHolds(holds map[string]float64) float64 {
+	sum := 0.0
+	for _, v := range holds {
+		sum += v
+	}
+	return sum
+}
+
+func (s *orgHoldCacheStub) ReserveOrgBalanceHold(ctx context.Context, orgID, userID int64, holdID string, amount, balance, memberLimit float64, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if balance-sumHolds(s.orgHolds[orgID])-amount < 0 {
+		return ErrInsufficientBalance
+	}
+	if memberLimit >= 0 && memberLimit-sumHolds(s.memberHolds[userID])-amount < 0 {
+		return ErrOrgMemberSpendingLimit
+	}
+	if s.orgHolds[orgID] == nil {
+		s.orgHolds[orgID] = map[string]float64{}
+	}
+	if s.memberHolds[userID] == nil {
+		s.memberHolds[userID] = map[string]float64{}
+	}
+	s.orgHolds[orgID][holdID] = amount
+	s.memberHolds[userID][holdID] = amount
+	return nil
+}
+
+func (s *orgHoldCacheStub) ReleaseOrgBalanceHold(ctx context.Context, orgID, userID int64, holdID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.orgHolds[orgID], holdID)
+	delete(s.memberHolds[userID], holdID)
+	return nil
+}
+
+// reserveConcurrently 并发发起 n 个组织 Key 请求，返回成功的预授权与失败的错误
+func reserveConcurrently(t *testing.T, svc *BillingCacheService, key *ApiKey, n int) ([]*BalanceHold, []error) {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		holds []*BalanceHold
+		errs  []error
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hold, err := svc.ReserveBalance(context.Background(), key, nil, "claude-opus-4.5", []byte(`{}`), 1000)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			holds = append(holds, hold)
+		}()
+	}
+	wg.Wait()
+	for _, hold := range holds {
+		require.NotNil(t, hold)
+	}
+	return holds, errs
+}
+
+func TestBillingCacheService_ReserveBalance_OrganizationConcurrent(t *testing.T) {
+	orgID := int64(1)
+	org := &Organization{ID: orgID, Status: StatusActive, OwnerID: 1}
+	repo := newOrgRepoStub(org,
+		&OrganizationMember{UserID: 1, Role: OrgRoleOwner},
+		&OrganizationMember{UserID: 2, Role: OrgRoleMember},
+	)
+	cache := newOrgHoldCacheStub()
+	svc := NewBillingCacheService(cache, nil, nil, repo, NewBillingService(&config.Config{}, nil))
+	amount := svc.estimateRequestCost(context.Background(), "claude-opus-4.5", []byte(`{}`), 1000, nil)
+	require.Positive(t, amount)
+
+	// 组织余额只够 3 个并发请求，两个成员共享同一组织额度
+	org.Balance = amount * 3.5
+	ownerHolds, ownerErrs := reserveConcurrently(t, svc, &ApiKey{UserID: 1, OrganizationID: &orgID}, 5)
+	memberHolds, memberErrs := reserveConcurrently(t, svc, &ApiKey{UserID: 2, OrganizationID: &orgID}, 5)
+	require.Len(t, append(ownerHolds, memberHolds...), 3)
+	for _, err := range append(ownerErrs, memberErrs...) {
+		require.ErrorIs(t, err, ErrInsufficientBalance)
+	}
+	for _, hold := range append(ownerHolds, memberHolds...) {
+		require.Equal(t, orgID, hold.OrganizationID)
+		hold.Release()
+	}
+	require.Empty(t, cache.orgHolds[orgID], "released holds should free the organization balance")
+
+	// 成员当月剩余额度只够 2 个请求：组织余额充足时仍按成员上限拒绝
+	org.Balance = amount * 100
+	limit := amount*2.5 + 1
+	repo.members[2].MonthlyLimitUSD = &limit
+	repo.members[2].MonthlyUsageUSD = 1
+	repo.members[2].UsageMonth = orgUsageMonth(time.Now())
+	holds, errs := reserveConcurrently(t, svc, &ApiKey{UserID: 2, OrganizationID: &orgID}, 5)
+	require.Len(t, holds, 2)
+	for _, err := range errs {
+		require.ErrorIs(t, err, ErrOrgMemberSpendingLimit)
+	}
+
+	// 成员上限不影响其他成员
+	ownerHolds, ownerErrs = reserveConcurrently(t, svc, &ApiKey{UserID: 1, OrganizationID: &orgID}, 5)
+	require.Len(t, ownerHolds, 5)
+	require.Empty(t, ownerErrs)
+}
+
+func TestEstimateInputTokens_Base64Media(t *testing.T) {
+	// 约 5 MB 的截图按固定 token 数估算，而不是按字节数估算为上百万 token
+	image := strings.Repeat("A", 5<<20)
+	anthropic := []byte(`{"model":"claude-opus-4.5","messages":[{"role":"user","content":[` +
+		`{"type":"image","source":{"type":"base64","media_type":"image/png","data":"` + image + `"}},` +
+		`{"type":"text","text":"describe this"}]}]}`)
+	tokens := estimateInputTokens(anthropic)
+	require.Greater(t, tokens, balanceHoldMediaTokens)
+	require.Less(t, tokens, balanceHoldMediaTokens+100)
+
+	openai := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":[` +
+		`{"type":"image_url","image_url":{"url":"data:image/png;base64,` + image + `"}}]}]}`)
+	require.Less(t, estimateInputTokens(openai), balanceHoldMediaTokens+100)
+
+	gemini := []byte(`{"contents":[{"parts":[{"inlineData":{"mimeType":"application/pdf","data":"` + image + `"}},` +
+		`{"inlineData":{"mimeType":"image/png","data":"` + image + `"}}]}]}`)
+	require.Less(t, estimateInputTokens(gemini), 2*balanceHoldMediaTokens+100)
+
+	// 普通文本仍按字节数估算
+	text := []byte(`{"messages":[{"role":"user","content":"` + strings.Repeat("x", 4000) + `"}]}`)
+	require.GreaterOrEqual(t, estimateInputTokens(text), 1000)
+
+	// 按图片请求估算的预授权与纯文本请求处于同一数量级
+	svc := NewBillingCacheService(nil, nil, nil, nil, NewBillingService(&config.Config{}, nil))
+	require.Less(t, svc.estimateRequestCost(context.Background(), "claude-opus-4.5", anthropic, 1000, nil), 0.1)
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"fmt"
 
-	"log"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/config"
 )
@@ -18,6 +19,15 @@ type BillingCache interface {
 	DeductUserBalance(ctx context.Context, userID int64, amount float64) error
 	InvalidateUserBalance(ctx context.Context, userID int64) error
 
+	// Balance hold operations
+	// ReserveBalanceHold 在 balance 减去未结算预授权后仍足够时记录预授权，否则返回 false
+	ReserveBalanceHold(ctx context.Context, userID int64, holdID string, amount, balance float64, ttl time.Duration) (bool, error)
+	ReleaseBalanceHold(ctx context.Context, userID int64, holdID string) error
+	// ReserveOrgBalanceHold 组织 Key 的预授权：同时占用组织余额与成员当月剩余额度（memberLimit 为负数表示不限制），
+	// 组织余额不足返回 ErrInsufficientBalance，成员额度不足返回 ErrOrgMemberSpendingLimit
+	ReserveOrgBalanceHold(ctx context.Context, orgID, userID int64, holdID string, amount, balance, memberLimit float64, ttl time.Duration) error
+	ReleaseOrgBalanceHold(ctx context.Context, orgID, userID int64, holdID string) error
+
 	// Subscription operations
 	GetSubscriptionCache(ctx context.Context, userID, groupID int64) (*SubscriptionCacheData, error)
 	SetSubscriptionCache(ctx context.Context, userID, groupID int64, data *SubscriptionCacheData) error
@@ -185,7 +195,7 @@ func (s *BillingService) GetModelPricing(model string) (*ModelPricing, error) {
 	// 2. 使用硬编码回退价格
 	fallback := s.getFallbackPricing(model)
 	if fallback != nil {
-		log.Printf("[Billing] Using fallback pricing for model: %s", model)
+		slog.Info("using fallback pricing", "model", model)
 		return fallback, nil
 	}
 
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	ErrRequestCaptureNotFound   = infraerrors.NotFound("REQUEST_CAPTURE_NOT_FOUND", "request capture not found")
+	ErrCaptureReplayUnsupported = infraerrors.BadRequest("CAPTURE_REPLAY_UNSUPPORTED", "only /v1/messages captures can be replayed against anthropic accounts")
+	ErrCaptureReplayTruncated   = infraerrors.BadRequest("CAPTURE_REPLAY_TRUNCATED", "captured request body is truncated and cannot be replayed")
+)
+
+// captureCleanupInterval 过期抓取记录清理间隔
+const captureCleanupInterval = time.Hour
+
+// RequestCaptureFilters 抓取记录查询条件
+type RequestCaptureFilters struct {
+	ApiKeyID  int64
+	AccountID int64
+	Platform  string
+}
+
+// RequestCaptureRepository 抓取记录仓储
+type RequestCaptureRepository interface {
+	Create(ctx context.Context, capture *RequestCapture) error
+	GetByID(ctx context.Context, id int64) (*RequestCapture, error)
+	// List 返回不含请求/响应正文的摘要
+	List(ctx context.Context, params pagination.PaginationParams, filters RequestCaptureFilters) ([]RequestCapture, *pagination.PaginationResult, error)
+	Delete(ctx context.Context, id int64) error
+	// DeleteBefore 删除早于 before 的记录，返回删除数量
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// CaptureReplayResult 抓取重放结果
+type CaptureReplayResult struct {
+	CaptureID  int64
+	AccountID  int64
+	StatusCode int
+	Body       string
+	Usage      *ClaudeUsage // 转发失败时为 nil
+	DurationMs int64
+	Error      string
+}
+
+// CaptureService 请求抓取与重放服务
+type CaptureService struct {
+	captureRepo    RequestCaptureRepository
+	accountRepo    AccountRepository
+	apiKeyRepo     ApiKeyRepository
+	gatewayService *GatewayService
+	cfg            *config.Config
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewCaptureService 创建请求抓取服务
+func NewCaptureService(
+	captureRepo RequestCaptureRepository,
+	accountRepo AccountRepository,
+	apiKeyRepo ApiKeyRepository,
+	gatewayService *GatewayService,
+	cfg *config.Config,
+) *CaptureService {
+	return &CaptureService{
+		captureRepo:    captureRepo,
+		accountRepo:    accountRepo,
+		apiKeyRepo:     apiKeyRepo,
+		gatewayService: gatewayService,
+		cfg:            cfg,
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// Start 启动过期记录清理
+func (s *CaptureService) Start() {
+	s.wg.Add(1)
+	go s.cleanupLoop()
+	logger.Component("capture").Info("service started", "enabled", s.cfg.Capture.Enabled, "retention_days", s.cfg.Capture.RetentionDays)
+}
+
+// Stop 停止清理并等待未完成的落库
+func (s *CaptureService) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+	logger.Component("capture").Info("service stopped")
+}
+
+func (s *CaptureService) cleanupLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(captureCleanupInterval)
+	defer ticker.Stop()
+
+	s.cleanupExpired()
+	for {
+		select {
+		case <-ticker.C:
+			s.cleanupExpired()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *CaptureService) cleanupExpired() {
+	if s.cfg.Capture.RetentionDays <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	before := time.Now().AddDate(0, 0, -s.cfg.Capture.RetentionDays)
+	deleted, err := s.captureRepo.DeleteBefore(ctx, before)
+	if err != nil {
+		logger.Component("capture").Error("delete expired captures failed", logger.Err(err))
+	} else if deleted > 0 {
+		logger.Component("capture").Info("deleted expired captures", "count", deleted)
+	}
+}
+
+// Begin 为网关请求开启抓取，capture.enabled 关闭时返回 nil。
+// 抓取状态写入 c.Request 的 context，上游请求发出时根据 API Key / 账号开关决定是否记录。
+func (s *CaptureService) Begin(c *gin.Context, apiKey *ApiKey, platform, model string, body []byte) *Capture {
+	if !s.cfg.Capture.Enabled || apiKey == nil {
+		return nil
+	}
+
+	clientBody, truncated := sanitizeCaptureBody(body, s.cfg.Capture.MaxBodyBytes)
+	capture := &Capture{
+		record: RequestCapture{
+			UserID:           apiKey.UserID,
+			ApiKeyID:         apiKey.ID,
+			Platform:         platform,
+			Model:            model,
+			RequestID:        c.Writer.Header().Get("X-Request-Id"),
+			Method:           c.Request.Method,
+			Path:             c.Request.URL.Path,
+			ClientHeaders:    sanitizeCaptureHeaders(c.Request.Header),
+			ClientBody:       clientBody,
+			RequestTruncated: truncated,
+		},
+		apiKeyEnabled: apiKey.CaptureEnabled,
+		maxBytes:      s.cfg.Capture.MaxBodyBytes,
+		startedAt:     time.Now(),
+	}
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), captureContextKey{}, capture))
+	return capture
+}
+
+// Finish 结束抓取并异步落库，capture 为 nil 或无需记录时直接返回
+func (s *CaptureService) Finish(capture *Capture) {
+	if capture == nil {
+		return
+	}
+	record, ok := capture.snapshot()
+	if !ok {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.captureRepo.Create(ctx, &record); err != nil {
+			logger.Component("capture").Error("save request capture failed", "api_key_id", record.ApiKeyID, logger.Err(err))
+		}
+	}()
+}
+
+// List 查询抓取记录摘要
+func (s *CaptureService) List(ctx context.Context, params pagination.PaginationParams, filters RequestCaptureFilters) ([]RequestCapture, *pagination.PaginationResult, error) {
+	captures, result, err := s.captureRepo.List(ctx, params, filters)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list request captures: %w", err)
+	}
+	return captures, result, nil
+}
+
+// GetByID 获取完整抓取记录
+func (s *CaptureService) GetByID(ctx context.Context, id int64) (*RequestCapture, error) {
+	return s.captureRepo.GetByID(ctx, id)
+}
+
+// Delete 删除抓取记录
+func (s *CaptureService) Delete(ctx context.Context, id int64) error {
+	if _, err := s.captureRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	if err := s.captureRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("delete request capture: %w", err)
+	}
+	return nil
+}
+
+// SetAccountCapture 开关账号的请求抓取
+func (s *CaptureService) SetAccountCapture(ctx context.Context, accountID int64, enabled bool) (*Account, error) {
+	if _, err := s.accountRepo.GetByID(ctx, accountID); err != nil {
+		return nil, err
+	}
+	if err := s.accountRepo.SetCaptureEnabled(ctx, accountID, enabled); err != nil {
+		return nil, fmt.Errorf("set account capture: %w", err)
+	}
+	return s.accountRepo.GetByID(ctx, accountID)
+}
+
+// SetApiKeyCapture 开关 API Key 的请求抓取
+func (s *CaptureService) SetApiKeyCapture(ctx context.Context, apiKeyID int64, enabled bool) (*ApiKey, error) {
+	if _, err := s.apiKeyRepo.GetByID(ctx, apiKeyID); err != nil {
+		return nil, err
+	}
+	if err := s.apiKeyRepo.SetCaptureEnabled(ctx, apiKeyID, enabled); err != nil {
+		return nil, fmt.Errorf("set api key capture: %w", err)
+	}
+	return s.apiKeyRepo.GetByID(ctx, apiKeyID)
+}
+
+// Replay 使用抓取到的客户端请求，通过 GatewayService.Forward 重放到指定账号。
+// 重放不计费，也不会产生新的抓取记录。
+func (s *CaptureService) Replay(ctx context.Context, captureID, accountID int64) (*CaptureReplayResult, error) {
+	capture, err := s.captureRepo.GetByID(ctx, captureID)
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.accountRepo.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if capture.Path != "/v1/messages" || account.Platform != PlatformAnthropic {
+		return nil, ErrCaptureReplayUnsupported
+	}
+	if capture.RequestTruncated {
+		return nil, ErrCaptureReplayTruncated
+	}
+
+	body := []byte(capture.ClientBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, capture.Path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build replay request: %w", err)
+	}
+	for key, value := range capture.ClientHeaders {
+		if value == logger.Redacted || http.CanonicalHeaderKey(key) == "Content-Length" {
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = req
+
+	startedAt := time.Now()
+	result, forwardErr := s.gatewayService.Forward(ctx, c, account, body)
+	replay := &CaptureReplayResult{
+		CaptureID:  capture.ID,
+		AccountID:  account.ID,
+		StatusCode: rec.Code,
+		Body:       rec.Body.String(),
+		DurationMs: time.Since(startedAt).Milliseconds(),
+	}
+	if forwardErr != nil {
+		replay.Error = forwardErr.Error()
+		var failoverErr *UpstreamFailoverError
+		if errors.As(forwardErr, &failoverErr) && !c.Writer.Written() {
+			replay.StatusCode = failoverErr.StatusCode
+		}
+	} else {
+		replay.Usage = &result.Usage
+	}
+
+	logger.FromContext(ctx).Info("request capture replayed",
+		"capture_id", capture.ID, "account_id", account.ID,
+		"model", gjson.GetBytes(body, "model").String(), "status", replay.StatusCode)
+	return replay, nil
+}
@@ -5,21 +5,23 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
-	"log"
+	"sync"
 	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 )
 
 // ConcurrencyCache defines cache operations for concurrency service
-// Uses independent keys per request slot with native Redis TTL for automatic cleanup
+// Slots are stored per account/user with an independent expiry per request slot
 type ConcurrencyCache interface {
-	// Account slot management - each slot is a separate key with independent TTL
-	// Key format: concurrency:account:{accountID}:{requestID}
+	// Account slot management - each slot expires independently
+	// Key format: concurrency:account:{accountID} (sorted set, member=requestID)
 	AcquireAccountSlot(ctx context.Context, accountID int64, maxConcurrency int, requestID string) (bool, error)
 	ReleaseAccountSlot(ctx context.Context, accountID int64, requestID string) error
 	GetAccountConcurrency(ctx context.Context, accountID int64) (int, error)
 
-	// User slot management - each slot is a separate key with independent TTL
-	// Key format: concurrency:user:{userID}:{requestID}
+	// User slot management - each slot expires independently
+	// Key format: concurrency:user:{userID} (sorted set, member=requestID)
 	AcquireUserSlot(ctx context.Context, userID int64, maxConcurrency int, requestID string) (bool, error)
 	ReleaseUserSlot(ctx context.Context, userID int64, requestID string) error
 	GetUserConcurrency(ctx context.Context, userID int64) (int, error)
@@ -48,11 +50,18 @@ const (
 // ConcurrencyService manages concurrent request limiting for accounts and users
 type ConcurrencyService struct {
 	cache ConcurrencyCache
+
+	// accountWaiting 本实例内正在等待各账号槽位的请求数（用于监控指标）
+	waitingMu      sync.Mutex
+	accountWaiting map[int64]int
 }
 
 // NewConcurrencyService creates a new ConcurrencyService
 func NewConcurrencyService(cache ConcurrencyCache) *ConcurrencyService {
-	return &ConcurrencyService{cache: cache}
+	return &ConcurrencyService{
+		cache:          cache,
+		accountWaiting: make(map[int64]int),
+	}
 }
 
 // AcquireResult represents the result of acquiring a concurrency slot
@@ -88,7 +97,7 @@ func (s *ConcurrencyService) AcquireAccountSlot(ctx context.Context, accountID i
 				bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 				defer cancel()
 				if err := s.cache.ReleaseAccountSlot(bgCtx, accountID, requestID); err != nil {
-					log.Printf("Warning: failed to release account slot for %d (req=%s): %v", accountID, requestID, err)
+					logger.FromContext(ctx).Warn("release account slot failed", "account_id", accountID, "slot_request_id", requestID, logger.Err(err))
 				}
 			},
 		}, nil
@@ -127,7 +136,7 @@ func (s *ConcurrencyService) AcquireUserSlot(ctx context.Context, userID int64,
 				bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 				defer cancel()
 				if err := s.cache.ReleaseUserSlot(bgCtx, userID, requestID); err != nil {
-					log.Printf("Warning: failed to release user slot for %d (req=%s): %v", userID, requestID, err)
+					logger.FromContext(ctx).Warn("release user slot failed", "user_id", userID, "slot_request_id", requestID, logger.Err(err))
 				}
 			},
 		}, nil
@@ -155,7 +164,7 @@ func (s *ConcurrencyService) IncrementWaitCount(ctx context.Context, userID int6
 	result, err := s.cache.IncrementWaitCount(ctx, userID, maxWait)
 	if err != nil {
 		// On error, allow the request to proceed (fail open)
-		log.Printf("Warning: increment wait count failed for user %d: %v", userID, err)
+		logger.FromContext(ctx).Warn("increment wait count failed", "user_id", userID, logger.Err(err))
 		return true, nil
 	}
 	return result, nil
@@ -173,8 +182,38 @@ func (s *ConcurrencyService) DecrementWaitCount(ctx context.Context, userID int6
 	defer cancel()
 
 	if err := s.cache.DecrementWaitCount(bgCtx, userID); err != nil {
-		log.Printf("Warning: decrement wait count failed for user %d: %v", userID, err)
+		logger.FromContext(ctx).Warn("decrement wait count failed", "user_id", userID, logger.Err(err))
+	}
+}
+
+// TrackAccountWait records that a request on this instance is waiting for an account slot.
+// The returned function MUST be called when the wait ends.
+func (s *ConcurrencyService) TrackAccountWait(accountID int64) func() {
+	s.waitingMu.Lock()
+	s.accountWaiting[accountID]++
+	s.waitingMu.Unlock()
+
+	return func() {
+		s.waitingMu.Lock()
+		defer s.waitingMu.Unlock()
+		if s.accountWaiting[accountID] <= 1 {
+			delete(s.accountWaiting, accountID)
+			return
+		}
+		s.accountWaiting[accountID]--
+	}
+}
+
+// GetAccountWaitingCounts returns a snapshot of accountID -> requests waiting on this instance
+func (s *ConcurrencyService) GetAccountWaitingCounts() map[int64]int {
+	s.waitingMu.Lock()
+	defer s.waitingMu.Unlock()
+
+	result := make(map[int64]int, len(s.accountWaiting))
+	for id, n := range s.accountWaiting {
+		result[id] = n
 	}
+	return result
 }
 
 // CalculateMaxWait calculates the maximum wait queue size for a user
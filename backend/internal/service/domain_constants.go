@@ -3,6 +3,7 @@ package service
 // Status constants
 const (
 	StatusActive   = "active"
+	StatusInactive = "inactive"
 	StatusDisabled = "disabled"
 	StatusError    = "error"
 	StatusUnused   = "unused"
@@ -12,8 +13,13 @@ const (
 
 // Role constants
 const (
-	RoleAdmin = "admin"
+	RoleAdmin = "admin" // 超级管理员，拥有全部权限
 	RoleUser  = "user"
+
+	// 受限管理角色，权限见 admin_permission.go
+	RoleViewer          = "viewer"
+	RoleBillingOperator = "billing_operator"
+	RoleAccountOperator = "account_operator"
 )
 
 // Platform constants
@@ -49,6 +55,14 @@ const (
 	SubscriptionTypeSubscription = "subscription" // 订阅模式（按限额控制）
 )
 
+// Group scheduling strategy constants
+const (
+	SchedulingStrategyPriority         = "priority"          // 优先级+最久未用（默认）
+	SchedulingStrategyWeighted         = "weighted"          // 按账号权重加权随机
+	SchedulingStrategyLeastConcurrency = "least_concurrency" // 当前并发数最少优先
+	SchedulingStrategyRoundRobin       = "round_robin"       // 轮询
+)
+
 // Subscription status constants
 const (
 	SubscriptionStatusActive    = "active"
@@ -88,8 +102,40 @@ const (
 	SettingKeyDefaultConcurrency = "default_concurrency" // 新用户默认并发量
 	SettingKeyDefaultBalance     = "default_balance"     // 新用户默认余额
 
+	// Webhook 运维通知
+	SettingKeyWebhookEnabled                = "webhook_enabled"                  // 是否启用 Webhook 通知
+	SettingKeyWebhookUrl                    = "webhook_url"                      // Webhook 地址
+	SettingKeyWebhookSecret                 = "webhook_secret"                   // 签名密钥
+	SettingKeyWebhookFormat                 = "webhook_format"                   // 消息格式: generic/slack/dingtalk/feishu
+	SettingKeyWebhookEvents                 = "webhook_events"                   // 订阅的事件（逗号分隔，为空表示全部）
+	SettingKeyWebhookBalanceThreshold       = "webhook_balance_threshold"        // 余额低于该值时通知
+	SettingKeyWebhookSubscriptionExpiryDays = "webhook_subscription_expiry_days" // 订阅到期前多少天通知
+
+	// 用户邮件通知
+	SettingKeyUserNotifyEnabled                = "user_notify_enabled"                  // 是否启用用户邮件通知
+	SettingKeyUserNotifySubscriptionExpiryDays = "user_notify_subscription_expiry_days" // 订阅到期前多少天提醒
+	SettingKeyUserNotifyTemplatePrefix         = "user_notify_template_"                // 模板前缀：user_notify_template_<type>_subject/_body
+
+	// 二次验证
+	SettingKeyAdmin2FARequired = "admin_2fa_required" // 管理员是否必须启用二次验证（未设置时默认必须）
+
+	// OIDC 单点登录
+	SettingKeyOidcEnabled       = "oidc_enabled"        // 是否启用 OIDC 登录
+	SettingKeyOidcProviderName  = "oidc_provider_name"  // 登录按钮显示名称
+	SettingKeyOidcIssuer        = "oidc_issuer"         // Issuer 地址（用于服务发现）
+	SettingKeyOidcClientId      = "oidc_client_id"      // Client ID
+	SettingKeyOidcClientSecret  = "oidc_client_secret"  // Client Secret（可选）
+	SettingKeyOidcRedirectUrl   = "oidc_redirect_url"   // 回调地址
+	SettingKeyOidcScopes        = "oidc_scopes"         // 请求的 scope（空格分隔）
+	SettingKeyOidcAutoProvision = "oidc_auto_provision" // 首次登录自动创建账号
+	SettingKeyOidcGroupsClaim   = "oidc_groups_claim"   // 用户组 claim 名称
+	SettingKeyOidcAdminGroups   = "oidc_admin_groups"   // 映射为管理员的 IdP 组（逗号分隔）
+	SettingKeyOidcGroupMapping  = "oidc_group_mapping"  // IdP 组到分组 ID 的映射（JSON）
+
 	// 管理员 API Key
-	SettingKeyAdminApiKey = "admin_api_key" // 全局管理员 API Key（用于外部系统集成）
+	// 旧版全局管理员 API Key，首次使用时迁移到 admin_api_keys 表
+	SettingKeyAdminApiKey     = "admin_api_key"      // 全局管理员 API Key（用于外部系统集成）
+	SettingKeyAdminApiKeyRole = "admin_api_key_role" // Admin API Key 对应的管理角色（未设置时为 admin）
 )
 
 // Admin API Key prefix (distinct from user "sk-" keys)
@@ -3,16 +3,22 @@ package service
 import (
 	"context"
 	"fmt"
-	"log"
 	"sync"
 	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 )
 
 // EmailTask 邮件发送任务
 type EmailTask struct {
 	Email    string
 	SiteName string
-	TaskType string // "verify_code"
+	TaskType string // "verify_code" / "notification"
+	Purpose  string // 验证码用途，为空表示注册
+
+	// 通知邮件的主题与正文（TaskType 为 notification 时使用）
+	Subject string
+	Body    string
 }
 
 // EmailQueueService 异步邮件队列服务
@@ -49,7 +55,7 @@ func (s *EmailQueueService) start() {
 		s.wg.Add(1)
 		go s.worker(i)
 	}
-	log.Printf("[EmailQueue] Started %d workers", s.workers)
+	logger.Component("email_queue").Info("workers started", "workers", s.workers)
 }
 
 // worker 工作协程
@@ -61,7 +67,7 @@ func (s *EmailQueueService) worker(id int) {
 		case task := <-s.taskChan:
 			s.processTask(id, task)
 		case <-s.stopChan:
-			log.Printf("[EmailQueue] Worker %d stopping", id)
+			logger.Component("email_queue").Debug("worker stopping", "worker", id)
 			return
 		}
 	}
@@ -74,27 +80,57 @@ func (s *EmailQueueService) processTask(workerID int, task EmailTask) {
 
 	switch task.TaskType {
 	case "verify_code":
-		if err := s.emailService.SendVerifyCode(ctx, task.Email, task.SiteName); err != nil {
-			log.Printf("[EmailQueue] Worker %d failed to send verify code to %s: %v", workerID, task.Email, err)
+		if err := s.emailService.SendVerifyCodeFor(ctx, task.Purpose, task.Email, task.SiteName); err != nil {
+			logger.Component("email_queue").Error("send verify code failed", "worker", workerID, "email", task.Email, logger.Err(err))
 		} else {
-			log.Printf("[EmailQueue] Worker %d sent verify code to %s", workerID, task.Email)
+			logger.Component("email_queue").Info("verify code sent", "worker", workerID, "email", task.Email)
+		}
+	case "notification":
+		if err := s.emailService.SendEmail(ctx, task.Email, task.Subject, task.Body); err != nil {
+			logger.Component("email_queue").Error("send notification failed", "worker", workerID, "email", task.Email, logger.Err(err))
+		} else {
+			logger.Component("email_queue").Info("notification sent", "worker", workerID, "email", task.Email)
 		}
 	default:
-		log.Printf("[EmailQueue] Worker %d unknown task type: %s", workerID, task.TaskType)
+		logger.Component("email_queue").Warn("unknown task type", "worker", workerID, "task_type", task.TaskType)
 	}
 }
 
-// EnqueueVerifyCode 将验证码发送任务加入队列
+// EnqueueVerifyCode 将注册验证码发送任务加入队列
 func (s *EmailQueueService) EnqueueVerifyCode(email, siteName string) error {
+	return s.EnqueueVerifyCodeFor(VerifyPurposeRegister, email, siteName)
+}
+
+// EnqueueVerifyCodeFor 将指定用途的验证码发送任务加入队列
+func (s *EmailQueueService) EnqueueVerifyCodeFor(purpose, email, siteName string) error {
 	task := EmailTask{
 		Email:    email,
 		SiteName: siteName,
 		TaskType: "verify_code",
+		Purpose:  purpose,
+	}
+
+	select {
+	case s.taskChan <- task:
+		logger.Component("email_queue").Debug("verify code task enqueued", "email", email)
+		return nil
+	default:
+		return fmt.Errorf("email queue is full")
+	}
+}
+
+// EnqueueNotification 将通知邮件加入队列
+func (s *EmailQueueService) EnqueueNotification(email, subject, body string) error {
+	task := EmailTask{
+		Email:    email,
+		TaskType: "notification",
+		Subject:  subject,
+		Body:     body,
 	}
 
 	select {
 	case s.taskChan <- task:
-		log.Printf("[EmailQueue] Enqueued verify code task for %s", email)
+		logger.Component("email_queue").Debug("notification task enqueued", "email", email)
 		return nil
 	default:
 		return fmt.Errorf("email queue is full")
@@ -105,5 +141,5 @@ func (s *EmailQueueService) EnqueueVerifyCode(email, siteName string) error {
 func (s *EmailQueueService) Stop() {
 	close(s.stopChan)
 	s.wg.Wait()
-	log.Println("[EmailQueue] All workers stopped")
+	logger.Component("email_queue").Info("all workers stopped")
 }
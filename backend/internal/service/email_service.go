@@ -18,13 +18,27 @@ var (
 	ErrInvalidVerifyCode     = infraerrors.BadRequest("INVALID_VERIFY_CODE", "invalid or expired verification code")
 	ErrVerifyCodeTooFrequent = infraerrors.TooManyRequests("VERIFY_CODE_TOO_FREQUENT", "please wait before requesting a new code")
 	ErrVerifyCodeMaxAttempts = infraerrors.TooManyRequests("VERIFY_CODE_MAX_ATTEMPTS", "too many failed attempts, please request a new code")
+	ErrEmailRateLimited      = infraerrors.TooManyRequests("EMAIL_RATE_LIMITED", "too many requests, please try again later")
 )
 
+// 验证码用途，不同用途的验证码互不通用
+const (
+	VerifyPurposeRegister      = ""
+	VerifyPurposePasswordReset = "password_reset"
+)
+
+// VerifyPurposeChangeEmail 修改邮箱验证码的用途，绑定到发起修改的用户
+func VerifyPurposeChangeEmail(userID int64) string {
+	return "change_email:" + strconv.FormatInt(userID, 10)
+}
+
 // EmailCache defines cache operations for email service
 type EmailCache interface {
 	GetVerificationCode(ctx context.Context, email string) (*VerificationCodeData, error)
 	SetVerificationCode(ctx context.Context, email string, data *VerificationCodeData, ttl time.Duration) error
 	DeleteVerificationCode(ctx context.Context, email string) error
+	// IncrementRateLimit 计数器加一并返回窗口内的计数，窗口从首次计数开始
+	IncrementRateLimit(ctx context.Context, key string, window time.Duration) (int64, error)
 }
 
 // VerificationCodeData represents verification code data
@@ -201,10 +215,25 @@ func (s *EmailService) GenerateVerifyCode() (string, error) {
 	return string(code), nil
 }
 
-// SendVerifyCode 发送验证码邮件
+// verifyCodeCacheKey 按用途区分验证码的缓存键（注册验证码沿用邮箱本身）
+func verifyCodeCacheKey(purpose, email string) string {
+	if purpose == VerifyPurposeRegister {
+		return email
+	}
+	return purpose + ":" + email
+}
+
+// SendVerifyCode 发送注册验证码邮件
 func (s *EmailService) SendVerifyCode(ctx context.Context, email, siteName string) error {
+	return s.SendVerifyCodeFor(ctx, VerifyPurposeRegister, email, siteName)
+}
+
+// SendVerifyCodeFor 发送指定用途的验证码邮件
+func (s *EmailService) SendVerifyCodeFor(ctx context.Context, purpose, email, siteName string) error {
+	key := verifyCodeCacheKey(purpose, email)
+
 	// 检查是否在冷却期内
-	existing, err := s.cache.GetVerificationCode(ctx, email)
+	existing, err := s.cache.GetVerificationCode(ctx, key)
 	if err == nil && existing != nil {
 		if time.Since(existing.CreatedAt) < verifyCodeCooldown {
 			return ErrVerifyCodeTooFrequent
@@ -223,7 +252,7 @@ func (s *EmailService) SendVerifyCode(ctx context.Context, email, siteName strin
 		Attempts:  0,
 		CreatedAt: time.Now(),
 	}
-	if err := s.cache.SetVerificationCode(ctx, email, data, verifyCodeTTL); err != nil {
+	if err := s.cache.SetVerificationCode(ctx, key, data, verifyCodeTTL); err != nil {
 		return fmt.Errorf("save verify code: %w", err)
 	}
 
@@ -239,9 +268,15 @@ func (s *EmailService) SendVerifyCode(ctx context.Context, email, siteName strin
 	return nil
 }
 
-// VerifyCode 验证验证码
+// VerifyCode 验证注册验证码
 func (s *EmailService) VerifyCode(ctx context.Context, email, code string) error {
-	data, err := s.cache.GetVerificationCode(ctx, email)
+	return s.VerifyCodeFor(ctx, VerifyPurposeRegister, email, code)
+}
+
+// VerifyCodeFor 验证指定用途的验证码，验证成功后验证码失效
+func (s *EmailService) VerifyCodeFor(ctx context.Context, purpose, email, code string) error {
+	key := verifyCodeCacheKey(purpose, email)
+	data, err := s.cache.GetVerificationCode(ctx, key)
 	if err != nil || data == nil {
 		return ErrInvalidVerifyCode
 	}
@@ -254,7 +289,7 @@ func (s *EmailService) VerifyCode(ctx context.Context, email, code string) error
 	// 验证码不匹配
 	if data.Code != code {
 		data.Attempts++
-		_ = s.cache.SetVerificationCode(ctx, email, data, verifyCodeTTL)
+		_ = s.cache.SetVerificationCode(ctx, key, data, verifyCodeTTL)
 		if data.Attempts >= maxVerifyCodeAttempts {
 			return ErrVerifyCodeMaxAttempts
 		}
@@ -262,7 +297,19 @@ func (s *EmailService) VerifyCode(ctx context.Context, email, code string) error
 	}
 
 	// 验证成功，删除验证码
-	_ = s.cache.DeleteVerificationCode(ctx, email)
+	_ = s.cache.DeleteVerificationCode(ctx, key)
+	return nil
+}
+
+// CheckRateLimit 限制同一 key 在窗口内的发送次数，计数失败时放行
+func (s *EmailService) CheckRateLimit(ctx context.Context, key string, limit int64, window time.Duration) error {
+	count, err := s.cache.IncrementRateLimit(ctx, key, window)
+	if err != nil {
+		return nil
+	}
+	if count > limit {
+		return ErrEmailRateLimited
+	}
 	return nil
 }
 
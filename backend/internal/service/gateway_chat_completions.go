@@ -0,0 +1,738 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/tidwall/gjson"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultChatCompletionsMaxTokens Chat Completions 请求未指定 max_tokens 时使用的默认值（Claude 要求必填）
+const defaultChatCompletionsMaxTokens = 8192
+
+// ForwardChatCompletions 将 OpenAI Chat Completions 请求转换为 Claude Messages 转发，
+// 并把 Claude 响应（含 SSE）转换回 Chat Completions 格式写回客户端
+func (s *GatewayService) ForwardChatCompletions(ctx context.Context, c *gin.Context, account *Account, body []byte) (*ForwardResult, error) {
+	startTime := time.Now()
+
+	claudeReq, err := convertChatCompletionsToClaudeRequest(body)
+	if err != nil {
+		return nil, writeChatCompletionsError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+	}
+
+	originalModel, _ := claudeReq["model"].(string)
+	stream, _ := claudeReq["stream"].(bool)
+	includeUsage := gjson.GetBytes(body, "stream_options.include_usage").Bool()
+
+	// 应用模型映射（仅对apikey类型账号）
+	mappedModel := originalModel
+	if account.Type == AccountTypeApiKey {
+		mappedModel = account.GetMappedModel(originalModel)
+		if mappedModel != originalModel {
+			claudeReq["model"] = mappedModel
+			logger.FromContext(ctx).Debug("model mapping applied", "model", originalModel, "mapped_model", mappedModel, "account_name", account.Name)
+		}
+	}
+
+	// OAuth账号需要以Claude Code系统提示词开头
+	if account.IsOAuth() {
+		claudeReq["system"] = prependClaudeCodeSystemPrompt(claudeReq["system"])
+	}
+
+	claudeBody, err := json.Marshal(claudeReq)
+	if err != nil {
+		return nil, writeChatCompletionsError(c, http.StatusInternalServerError, "api_error", "Failed to build upstream request")
+	}
+
+	token, tokenType, err := s.GetAccessToken(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyURL := ""
+	if account.ProxyID != nil && account.Proxy != nil {
+		proxyURL = account.Proxy.URL()
+	}
+
+	resp, err := s.doUpstreamWithRetry(ctx, c, account, claudeBody, token, tokenType, proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// 处理重试耗尽的情况
+	if resp.StatusCode >= 400 && s.shouldRetryUpstreamError(account, resp.StatusCode) {
+		s.handleRetryExhaustedSideEffects(ctx, resp, account)
+		if s.shouldFailoverUpstreamError(resp.StatusCode) {
+			return nil, &UpstreamFailoverError{StatusCode: resp.StatusCode}
+		}
+		return nil, writeChatCompletionsError(c, http.StatusBadGateway, "upstream_error", "Upstream request failed after retries")
+	}
+
+	// 处理可切换账号的错误
+	if resp.StatusCode >= 400 && s.shouldFailoverUpstreamError(resp.StatusCode) {
+		s.handleFailoverSideEffects(ctx, resp, account)
+		return nil, &UpstreamFailoverError{StatusCode: resp.StatusCode}
+	}
+
+	// 处理错误响应（不可重试的错误）
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+		s.rateLimitService.HandleUpstreamError(ctx, account, resp.StatusCode, resp.Header, respBody)
+		if resp.StatusCode == http.StatusBadRequest {
+			msg := gjson.GetBytes(respBody, "error.message").String()
+			if msg == "" {
+				msg = "Invalid request"
+			}
+			return nil, writeChatCompletionsError(c, http.StatusBadRequest, "invalid_request_error", msg)
+		}
+		return nil, writeChatCompletionsError(c, http.StatusBadGateway, "upstream_error", "Upstream request failed")
+	}
+
+	s.rateLimitService.UpdateSessionWindow(ctx, account, resp.Header)
+	if v := resp.Header.Get("x-request-id"); v != "" {
+		c.Header("x-request-id", v)
+	}
+
+	var usage *ClaudeUsage
+	var firstTokenMs *int
+	if stream {
+		streamResult, err := s.handleChatCompletionsStreamingResponse(c, resp, startTime, originalModel, includeUsage)
+		if err != nil {
+			return nil, err
+		}
+		usage = streamResult.usage
+		firstTokenMs = streamResult.firstTokenMs
+	} else {
+		respBody, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+		if err != nil {
+			return nil, writeChatCompletionsError(c, http.StatusBadGateway, "upstream_error", "Failed to read upstream response")
+		}
+		var claudeResp map[string]any
+		if err := json.Unmarshal(respBody, &claudeResp); err != nil {
+			return nil, writeChatCompletionsError(c, http.StatusBadGateway, "upstream_error", "Failed to parse upstream response")
+		}
+		var chatResp map[string]any
+		chatResp, usage = convertClaudeMessageToChatCompletion(claudeResp, originalModel)
+		c.JSON(http.StatusOK, chatResp)
+	}
+
+	return &ForwardResult{
+		RequestID:    resp.Header.Get("x-request-id"),
+		Usage:        *usage,
+		Model:        originalModel, // 使用原始模型用于计费和日志
+		Stream:       stream,
+		Duration:     time.Since(startTime),
+		FirstTokenMs: firstTokenMs,
+	}, nil
+}
+
+// handleChatCompletionsStreamingResponse 将 Claude SSE 事件转换为 chat.completion.chunk
+func (s *GatewayService) handleChatCompletionsStreamingResponse(c *gin.Context, resp *http.Response, startTime time.Time, originalModel string, includeUsage bool) (*streamingResult, error) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming not supported")
+	}
+
+	conv := newChatCompletionsStreamConverter(originalModel)
+	usage := &ClaudeUsage{}
+	var firstTokenMs *int
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !sseDataRe.MatchString(line) {
+			continue
+		}
+		data := sseDataRe.ReplaceAllString(line, "")
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+
+		s.parseSSEUsage(data, usage)
+
+		chunks := conv.convert([]byte(data))
+		if len(chunks) == 0 {
+			continue
+		}
+		if firstTokenMs == nil && conv.sawContent {
+			ms := int(time.Since(startTime).Milliseconds())
+			firstTokenMs = &ms
+		}
+		for _, chunk := range chunks {
+			writeSSE(c.Writer, "", chunk)
+		}
+		flusher.Flush()
+	}
+
+	if err := scanner.Err(); err != nil {
+		return &streamingResult{usage: usage, firstTokenMs: firstTokenMs}, fmt.Errorf("stream read error: %w", err)
+	}
+
+	if includeUsage {
+		writeSSE(c.Writer, "", conv.usageChunk(usage))
+	}
+	_, _ = fmt.Fprint(c.Writer, "data: [DONE]\n\n")
+	flusher.Flush()
+
+	return &streamingResult{usage: usage, firstTokenMs: firstTokenMs}, nil
+}
+
+// chatCompletionsStreamConverter 维护 Claude SSE -> chat.completion.chunk 的转换状态
+type chatCompletionsStreamConverter struct {
+	id         string
+	model      string
+	created    int64
+	sawContent bool
+
+	// Claude content block index -> OpenAI tool_calls index
+	toolIndexByBlock map[int]int
+	nextToolIndex    int
+}
+
+func newChatCompletionsStreamConverter(model string) *chatCompletionsStreamConverter {
+	return &chatCompletionsStreamConverter{
+		id:               "chatcmpl-" + randomHex(12),
+		model:            model,
+		created:          time.Now().Unix(),
+		toolIndexByBlock: make(map[int]int),
+	}
+}
+
+func (cv *chatCompletionsStreamConverter) chunk(delta map[string]any, finishReason any) map[string]any {
+	return map[string]any{
+		"id":      cv.id,
+		"object":  "chat.completion.chunk",
+		"created": cv.created,
+		"model":   cv.model,
+		"choices": []any{
+			map[string]any{
+				"index":         0,
+				"delta":         delta,
+				"finish_reason": finishReason,
+			},
+		},
+	}
+}
+
+func (cv *chatCompletionsStreamConverter) usageChunk(usage *ClaudeUsage) map[string]any {
+	return map[string]any{
+		"id":      cv.id,
+		"object":  "chat.completion.chunk",
+		"created": cv.created,
+		"model":   cv.model,
+		"choices": []any{},
+		"usage":   chatCompletionsUsage(usage),
+	}
+}
+
+// convert 将单个 Claude SSE data 转换为零个或多个 chunk
+func (cv *chatCompletionsStreamConverter) convert(data []byte) []map[string]any {
+	event := gjson.ParseBytes(data)
+	switch event.Get("type").String() {
+	case "message_start":
+		if id := event.Get("message.id").String(); id != "" {
+			cv.id = "chatcmpl-" + strings.TrimPrefix(id, "msg_")
+		}
+		return []map[string]any{cv.chunk(map[string]any{"role": "assistant", "content": ""}, nil)}
+
+	case "content_block_start":
+		block := event.Get("content_block")
+		if block.Get("type").String() != "tool_use" {
+			return nil
+		}
+		idx := cv.nextToolIndex
+		cv.nextToolIndex++
+		cv.toolIndexByBlock[int(event.Get("index").Int())] = idx
+		cv.sawContent = true
+		return []map[string]any{cv.chunk(map[string]any{
+			"tool_calls": []any{
+				map[string]any{
+					"index": idx,
+					"id":    block.Get("id").String(),
+					"type":  "function",
+					"function": map[string]any{
+						"name":      block.Get("name").String(),
+						"arguments": "",
+					},
+				},
+			},
+		}, nil)}
+
+	case "content_block_delta":
+		delta := event.Get("delta")
+		switch delta.Get("type").String() {
+		case "text_delta":
+			text := delta.Get("text").String()
+			if text == "" {
+				return nil
+			}
+			cv.sawContent = true
+			return []map[string]any{cv.chunk(map[string]any{"content": text}, nil)}
+		case "input_json_delta":
+			idx, ok := cv.toolIndexByBlock[int(event.Get("index").Int())]
+			partial := delta.Get("partial_json").String()
+			if !ok || partial == "" {
+				return nil
+			}
+			return []map[string]any{cv.chunk(map[string]any{
+				"tool_calls": []any{
+					map[string]any{
+						"index":    idx,
+						"function": map[string]any{"arguments": partial},
+					},
+				},
+			}, nil)}
+		}
+		return nil
+
+	case "message_delta":
+		stopReason := event.Get("delta.stop_reason").String()
+		if stopReason == "" {
+			return nil
+		}
+		return []map[string]any{cv.chunk(map[string]any{}, mapClaudeStopReasonToFinishReason(stopReason))}
+
+	case "error":
+		return []map[string]any{{
+			"error": map[string]any{
+				"type":    event.Get("error.type").String(),
+				"message": event.Get("error.message").String(),
+			},
+		}}
+	}
+	return nil
+}
+
+// ConvertChatCompletionsToClaudeBody 将 Chat Completions 请求体转换为 Claude Messages 请求体（用于粘性会话hash等）
+func ConvertChatCompletionsToClaudeBody(body []byte) ([]byte, error) {
+	req, err := convertChatCompletionsToClaudeRequest(body)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(req)
+}
+
+func convertChatCompletionsToClaudeRequest(body []byte) (map[string]any, error) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, errors.New("failed to parse request body")
+	}
+
+	model, _ := req["model"].(string)
+	if strings.TrimSpace(model) == "" {
+		return nil, errors.New("model is required")
+	}
+
+	systemText, messages, err := convertChatMessagesToClaude(req["messages"])
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, errors.New("messages must contain at least one user or assistant message")
+	}
+
+	out := map[string]any{
+		"model":      model,
+		"messages":   messages,
+		"max_tokens": defaultChatCompletionsMaxTokens,
+	}
+	if systemText != "" {
+		out["system"] = systemText
+	}
+	if mt, ok := asInt(req["max_completion_tokens"]); ok && mt > 0 {
+		out["max_tokens"] = mt
+	} else if mt, ok := asInt(req["max_tokens"]); ok && mt > 0 {
+		out["max_tokens"] = mt
+	}
+	if stream, ok := req["stream"].(bool); ok && stream {
+		out["stream"] = true
+	}
+	if temp, ok := req["temperature"].(float64); ok {
+		out["temperature"] = temp
+	}
+	if topP, ok := req["top_p"].(float64); ok {
+		out["top_p"] = topP
+	}
+	switch stop := req["stop"].(type) {
+	case string:
+		if stop != "" {
+			out["stop_sequences"] = []any{stop}
+		}
+	case []any:
+		if len(stop) > 0 {
+			out["stop_sequences"] = stop
+		}
+	}
+	if tools := convertChatToolsToClaude(req["tools"]); tools != nil {
+		out["tools"] = tools
+	}
+	if tc := convertChatToolChoiceToClaude(req["tool_choice"]); tc != nil {
+		out["tool_choice"] = tc
+	}
+	if user, ok := req["user"].(string); ok && user != "" {
+		out["metadata"] = map[string]any{"user_id": user}
+	}
+	return out, nil
+}
+
+// convertChatMessagesToClaude 转换消息列表，返回合并后的system文本和Claude messages
+// Claude 要求 user/assistant 交替出现，相邻同角色消息会被合并
+func convertChatMessagesToClaude(messages any) (string, []any, error) {
+	arr, ok := messages.([]any)
+	if !ok {
+		return "", nil, errors.New("messages must be an array")
+	}
+
+	var systemParts []string
+	out := make([]any, 0, len(arr))
+	lastRole := ""
+
+	appendBlocks := func(role string, blocks []any) {
+		if len(blocks) == 0 {
+			return
+		}
+		if role == lastRole && len(out) > 0 {
+			prev := out[len(out)-1].(map[string]any)
+			prev["content"] = append(prev["content"].([]any), blocks...)
+			return
+		}
+		out = append(out, map[string]any{"role": role, "content": blocks})
+		lastRole = role
+	}
+
+	for _, m := range arr {
+		mm, ok := m.(map[string]any)
+		if !ok {
+			continue
+		}
+		role, _ := mm["role"].(string)
+		switch role {
+		case "system", "developer":
+			if text := extractChatContentText(mm["content"]); strings.TrimSpace(text) != "" {
+				systemParts = append(systemParts, text)
+			}
+		case "user":
+			appendBlocks("user", convertChatContentToClaudeBlocks(mm["content"]))
+		case "assistant":
+			blocks := convertChatContentToClaudeBlocks(mm["content"])
+			if toolCalls, ok := mm["tool_calls"].([]any); ok {
+				for _, tc := range toolCalls {
+					tcm, ok := tc.(map[string]any)
+					if !ok {
+						continue
+					}
+					fn, _ := tcm["function"].(map[string]any)
+					name, _ := fn["name"].(string)
+					id, _ := tcm["id"].(string)
+					if id == "" {
+						id = "toolu_" + randomHex(8)
+					}
+					input := map[string]any{}
+					if args, ok := fn["arguments"].(string); ok && strings.TrimSpace(args) != "" {
+						if err := json.Unmarshal([]byte(args), &input); err != nil {
+							return "", nil, fmt.Errorf("invalid arguments for tool call %s: %w", id, err)
+						}
+					}
+					blocks = append(blocks, map[string]any{
+						"type":  "tool_use",
+						"id":    id,
+						"name":  name,
+						"input": input,
+					})
+				}
+			}
+			appendBlocks("assistant", blocks)
+		case "tool", "function":
+			toolCallID, _ := mm["tool_call_id"].(string)
+			appendBlocks("user", []any{map[string]any{
+				"type":        "tool_result",
+				"tool_use_id": toolCallID,
+				"content":     extractChatContentText(mm["content"]),
+			}})
+		default:
+			return "", nil, fmt.Errorf("unsupported message role: %s", role)
+		}
+	}
+
+	return strings.Join(systemParts, "\n\n"), out, nil
+}
+
+func convertChatContentToClaudeBlocks(content any) []any {
+	switch v := content.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []any{map[string]any{"type": "text", "text": v}}
+	case []any:
+		blocks := make([]any, 0, len(v))
+		for _, part := range v {
+			pm, ok := part.(map[string]any)
+			if !ok {
+				continue
+			}
+			switch pm["type"] {
+			case "text":
+				if text, _ := pm["text"].(string); text != "" {
+					blocks = append(blocks, map[string]any{"type": "text", "text": text})
+				}
+			case "image_url":
+				var url string
+				switch iu := pm["image_url"].(type) {
+				case string:
+					url = iu
+				case map[string]any:
+					url, _ = iu["url"].(string)
+				}
+				if block := convertImageURLToClaudeBlock(url); block != nil {
+					blocks = append(blocks, block)
+				}
+			}
+		}
+		return blocks
+	default:
+		return nil
+	}
+}
+
+// convertImageURLToClaudeBlock 支持 data URL（base64）和 http(s) URL 两种图片来源
+func convertImageURLToClaudeBlock(url string) map[string]any {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		meta, data, found := strings.Cut(rest, ",")
+		mediaType, isBase64 := strings.CutSuffix(meta, ";base64")
+		if !found || !isBase64 || data == "" {
+			return nil
+		}
+		return map[string]any{
+			"type": "image",
+			"source": map[string]any{
+				"type":       "base64",
+				"media_type": mediaType,
+				"data":       data,
+			},
+		}
+	}
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return map[string]any{
+			"type":   "image",
+			"source": map[string]any{"type": "url", "url": url},
+		}
+	}
+	return nil
+}
+
+func extractChatContentText(content any) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []any:
+		var sb strings.Builder
+		for _, part := range v {
+			pm, ok := part.(map[string]any)
+			if !ok || pm["type"] != "text" {
+				continue
+			}
+			if text, ok := pm["text"].(string); ok {
+				_, _ = sb.WriteString(text)
+			}
+		}
+		return sb.String()
+	default:
+		return ""
+	}
+}
+
+func convertChatToolsToClaude(tools any) []any {
+	arr, ok := tools.([]any)
+	if !ok || len(arr) == 0 {
+		return nil
+	}
+	out := make([]any, 0, len(arr))
+	for _, t := range arr {
+		tm, ok := t.(map[string]any)
+		if !ok || tm["type"] != "function" {
+			continue
+		}
+		fn, _ := tm["function"].(map[string]any)
+		name, _ := fn["name"].(string)
+		if name == "" {
+			continue
+		}
+		params := fn["parameters"]
+		if params == nil {
+			params = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		tool := map[string]any{
+			"name":         name,
+			"input_schema": params,
+		}
+		if desc, _ := fn["description"].(string); desc != "" {
+			tool["description"] = desc
+		}
+		out = append(out, tool)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func convertChatToolChoiceToClaude(toolChoice any) map[string]any {
+	switch v := toolChoice.(type) {
+	case string:
+		switch v {
+		case "auto":
+			return map[string]any{"type": "auto"}
+		case "none":
+			return map[string]any{"type": "none"}
+		case "required":
+			return map[string]any{"type": "any"}
+		}
+	case map[string]any:
+		fn, _ := v["function"].(map[string]any)
+		if name, _ := fn["name"].(string); name != "" {
+			return map[string]any{"type": "tool", "name": name}
+		}
+	}
+	return nil
+}
+
+// prependClaudeCodeSystemPrompt 在已有system前插入Claude Code系统提示词
+func prependClaudeCodeSystemPrompt(system any) []any {
+	out := []any{
+		map[string]any{
+			"type": "text",
+			"text": claudeCodeSystemPrompt,
+			"cache_control": map[string]string{
+				"type": "ephemeral",
+			},
+		},
+	}
+	if text := extractClaudeSystemText(system); text != "" {
+		out = append(out, map[string]any{"type": "text", "text": text})
+	}
+	return out
+}
+
+func convertClaudeMessageToChatCompletion(claudeResp map[string]any, originalModel string) (map[string]any, *ClaudeUsage) {
+	usage := &ClaudeUsage{}
+	if u, ok := claudeResp["usage"].(map[string]any); ok {
+		usage.InputTokens, _ = asInt(u["input_tokens"])
+		usage.OutputTokens, _ = asInt(u["output_tokens"])
+		usage.CacheCreationInputTokens, _ = asInt(u["cache_creation_input_tokens"])
+		usage.CacheReadInputTokens, _ = asInt(u["cache_read_input_tokens"])
+	}
+
+	var text strings.Builder
+	toolCalls := make([]any, 0)
+	if content, ok := claudeResp["content"].([]any); ok {
+		for _, block := range content {
+			bm, ok := block.(map[string]any)
+			if !ok {
+				continue
+			}
+			switch bm["type"] {
+			case "text":
+				if t, ok := bm["text"].(string); ok {
+					_, _ = text.WriteString(t)
+				}
+			case "tool_use":
+				args, _ := json.Marshal(bm["input"])
+				toolCalls = append(toolCalls, map[string]any{
+					"id":   bm["id"],
+					"type": "function",
+					"function": map[string]any{
+						"name":      bm["name"],
+						"arguments": string(args),
+					},
+				})
+			}
+		}
+	}
+
+	message := map[string]any{
+		"role":    "assistant",
+		"content": nil,
+	}
+	if text.Len() > 0 {
+		message["content"] = text.String()
+	}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+	}
+
+	id := "chatcmpl-" + randomHex(12)
+	if msgID, _ := claudeResp["id"].(string); msgID != "" {
+		id = "chatcmpl-" + strings.TrimPrefix(msgID, "msg_")
+	}
+	stopReason, _ := claudeResp["stop_reason"].(string)
+
+	return map[string]any{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   originalModel,
+		"choices": []any{
+			map[string]any{
+				"index":         0,
+				"message":       message,
+				"finish_reason": mapClaudeStopReasonToFinishReason(stopReason),
+			},
+		},
+		"usage": chatCompletionsUsage(usage),
+	}, usage
+}
+
+// chatCompletionsUsage prompt_tokens 包含缓存读写的 token，与 OpenAI 语义保持一致
+func chatCompletionsUsage(usage *ClaudeUsage) map[string]any {
+	promptTokens := usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+	return map[string]any{
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": usage.OutputTokens,
+		"total_tokens":      promptTokens + usage.OutputTokens,
+		"prompt_tokens_details": map[string]any{
+			"cached_tokens": usage.CacheReadInputTokens,
+		},
+	}
+}
+
+func mapClaudeStopReasonToFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+func writeChatCompletionsError(c *gin.Context, status int, errType, message string) error {
+	c.JSON(status, gin.H{
+		"error": gin.H{
+			"type":    errType,
+			"message": message,
+		},
+	})
+	return fmt.Errorf("%s", message)
+}
@@ -0,0 +1,132 @@
+//go:build unit
+
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertChatCompletionsToClaudeRequest(t *testing.T) {
+	body := []byte(`{
+		"model": "claude-sonnet-4-5",
+		"stream": true,
+		"temperature": 0.2,
+		"max_tokens": 512,
+		"stop": "END",
+		"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "user", "content": [{"type": "text", "text": "weather?"}, {"type": "image_url", "image_url": {"url": "data:image/png;base64,AAAA"}}]},
+			{"role": "assistant", "content": null, "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "sunny"},
+			{"role": "user", "content": "thanks"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "description": "d", "parameters": {"type": "object"}}}],
+		"tool_choice": "required"
+	}`)
+
+	req, err := convertChatCompletionsToClaudeRequest(body)
+	require.NoError(t, err)
+
+	require.Equal(t, "claude-sonnet-4-5", req["model"])
+	require.Equal(t, "be brief", req["system"])
+	require.Equal(t, true, req["stream"])
+	require.Equal(t, 512, req["max_tokens"])
+	require.Equal(t, 0.2, req["temperature"])
+	require.Equal(t, []any{"END"}, req["stop_sequences"])
+	require.Equal(t, map[string]any{"type": "any"}, req["tool_choice"])
+
+	tools := req["tools"].([]any)
+	require.Len(t, tools, 1)
+	require.Equal(t, "get_weather", tools[0].(map[string]any)["name"])
+
+	messages := req["messages"].([]any)
+	require.Len(t, messages, 3)
+
+	user := messages[0].(map[string]any)
+	require.Equal(t, "user", user["role"])
+	userBlocks := user["content"].([]any)
+	require.Len(t, userBlocks, 2)
+	require.Equal(t, "image", userBlocks[1].(map[string]any)["type"])
+
+	assistant := messages[1].(map[string]any)
+	toolUse := assistant["content"].([]any)[0].(map[string]any)
+	require.Equal(t, "tool_use", toolUse["type"])
+	require.Equal(t, "call_1", toolUse["id"])
+	require.Equal(t, map[string]any{"city": "Paris"}, toolUse["input"])
+
+	// tool 结果与后续 user 消息合并为一条 user 消息
+	last := messages[2].(map[string]any)
+	require.Equal(t, "user", last["role"])
+	lastBlocks := last["content"].([]any)
+	require.Len(t, lastBlocks, 2)
+	require.Equal(t, "tool_result", lastBlocks[0].(map[string]any)["type"])
+	require.Equal(t, "call_1", lastBlocks[0].(map[string]any)["tool_use_id"])
+}
+
+func TestConvertChatCompletionsToClaudeRequest_Invalid(t *testing.T) {
+	_, err := convertChatCompletionsToClaudeRequest([]byte(`{"messages":[{"role":"user","content":"hi"}]}`))
+	require.Error(t, err)
+
+	_, err = convertChatCompletionsToClaudeRequest([]byte(`{"model":"m","messages":[{"role":"system","content":"only system"}]}`))
+	require.Error(t, err)
+}
+
+func TestConvertClaudeMessageToChatCompletion(t *testing.T) {
+	var claudeResp map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"id": "msg_abc",
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 10, "output_tokens": 5, "cache_read_input_tokens": 3}
+	}`), &claudeResp))
+
+	out, usage := convertClaudeMessageToChatCompletion(claudeResp, "claude-sonnet-4-5")
+	require.Equal(t, 10, usage.InputTokens)
+	require.Equal(t, 5, usage.OutputTokens)
+	require.Equal(t, 3, usage.CacheReadInputTokens)
+
+	require.Equal(t, "chatcmpl-abc", out["id"])
+	require.Equal(t, "claude-sonnet-4-5", out["model"])
+	choice := out["choices"].([]any)[0].(map[string]any)
+	require.Equal(t, "tool_calls", choice["finish_reason"])
+	message := choice["message"].(map[string]any)
+	require.Equal(t, "Let me check.", message["content"])
+	toolCall := message["tool_calls"].([]any)[0].(map[string]any)
+	require.Equal(t, `{"city":"Paris"}`, toolCall["function"].(map[string]any)["arguments"])
+
+	u := out["usage"].(map[string]any)
+	require.Equal(t, 13, u["prompt_tokens"])
+	require.Equal(t, 18, u["total_tokens"])
+}
+
+func TestChatCompletionsStreamConverter(t *testing.T) {
+	cv := newChatCompletionsStreamConverter("claude-sonnet-4-5")
+
+	chunks := cv.convert([]byte(`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":4}}}`))
+	require.Len(t, chunks, 1)
+	require.Equal(t, "chatcmpl-1", chunks[0]["id"])
+
+	chunks = cv.convert([]byte(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi"}}`))
+	require.Len(t, chunks, 1)
+	delta := chunks[0]["choices"].([]any)[0].(map[string]any)["delta"].(map[string]any)
+	require.Equal(t, "hi", delta["content"])
+
+	chunks = cv.convert([]byte(`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"f"}}`))
+	require.Len(t, chunks, 1)
+	chunks = cv.convert([]byte(`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"a\":1}"}}`))
+	require.Len(t, chunks, 1)
+	toolCall := chunks[0]["choices"].([]any)[0].(map[string]any)["delta"].(map[string]any)["tool_calls"].([]any)[0].(map[string]any)
+	require.Equal(t, 0, toolCall["index"])
+
+	chunks = cv.convert([]byte(`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":7}}`))
+	require.Len(t, chunks, 1)
+	require.Equal(t, "tool_calls", chunks[0]["choices"].([]any)[0].(map[string]any)["finish_reason"])
+
+	require.Empty(t, cv.convert([]byte(`{"type":"ping"}`)))
+}
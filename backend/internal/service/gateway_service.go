@@ -10,7 +10,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"regexp"
 	"strings"
@@ -18,8 +17,12 @@ import (
 
 	"github.com/Wei-Shaw/sub2api/internal/config"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/claude"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/metrics"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tracing"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,6 +31,9 @@ const (
 	claudeAPIURL            = "https://api.anthropic.com/v1/messages?beta=true"
 	claudeAPICountTokensURL = "https://api.anthropic.com/v1/messages/count_tokens?beta=true"
 	stickySessionTTL        = time.Hour // 粘性会话TTL
+
+	// claudeCodeSystemPrompt OAuth账号要求的Claude Code系统提示词
+	claudeCodeSystemPrompt = "You are Claude Code, Anthropic's official CLI for Claude."
 )
 
 // sseDataRe matches SSE data lines with optional whitespace after colon.
@@ -56,11 +62,16 @@ var allowedHeaders = map[string]bool{
 	"content-type":                              true,
 }
 
-// GatewayCache defines cache operations for gateway service
+// GatewayCache defines cache operations for gateway service.
+// sessionKey 为 stickySessionKey 生成的带作用域的粘性会话 key。
 type GatewayCache interface {
-	GetSessionAccountID(ctx context.Context, sessionHash string) (int64, error)
-	SetSessionAccountID(ctx context.Context, sessionHash string, accountID int64, ttl time.Duration) error
-	RefreshSessionTTL(ctx context.Context, sessionHash string, ttl time.Duration) error
+	GetSessionAccountID(ctx context.Context, sessionKey string) (int64, error)
+	SetSessionAccountID(ctx context.Context, sessionKey string, accountID int64, ttl time.Duration) error
+	RefreshSessionTTL(ctx context.Context, sessionKey string, ttl time.Duration) error
+	// ListAccountSessions 列出当前绑定到账号的粘性会话（已过期或已改绑的会被忽略）
+	ListAccountSessions(ctx context.Context, accountID int64) ([]StickySessionBinding, error)
+	// DeleteAccountSessions 删除当前绑定到账号的全部粘性会话，返回删除数量
+	DeleteAccountSessions(ctx context.Context, accountID int64) (int, error)
 }
 
 // ClaudeUsage 表示Claude API返回的usage信息
@@ -79,6 +90,7 @@ type ForwardResult struct {
 	Stream       bool
 	Duration     time.Duration
 	FirstTokenMs *int // 首字时间（流式请求）
+	CacheHit     bool // 由响应缓存直接返回
 }
 
 // UpstreamFailoverError indicates an upstream error that should trigger account failover.
@@ -103,6 +115,11 @@ type GatewayService struct {
 	billingCacheService *BillingCacheService
 	identityService     *IdentityService
 	httpUpstream        HTTPUpstream
+	scheduler           *AccountScheduler
+	apiKeyLimitService  *ApiKeyLimitService
+	balanceTxRepo       BalanceTransactionRepository
+	webhookService      *WebhookService
+	notificationService *UserNotificationService
 }
 
 // NewGatewayService creates a new GatewayService
@@ -118,6 +135,11 @@ func NewGatewayService(
 	billingCacheService *BillingCacheService,
 	identityService *IdentityService,
 	httpUpstream HTTPUpstream,
+	scheduler *AccountScheduler,
+	apiKeyLimitService *ApiKeyLimitService,
+	balanceTxRepo BalanceTransactionRepository,
+	webhookService *WebhookService,
+	notificationService *UserNotificationService,
 ) *GatewayService {
 	return &GatewayService{
 		accountRepo:         accountRepo,
@@ -131,11 +153,20 @@ func NewGatewayService(
 		billingCacheService: billingCacheService,
 		identityService:     identityService,
 		httpUpstream:        httpUpstream,
+		scheduler:           scheduler,
+		apiKeyLimitService:  apiKeyLimitService,
+		balanceTxRepo:       balanceTxRepo,
+		webhookService:      webhookService,
+		notificationService: notificationService,
 	}
 }
 
-// GenerateSessionHash 从请求体计算粘性会话hash
-func (s *GatewayService) GenerateSessionHash(body []byte) string {
+// GenerateSessionHash 从请求体计算粘性会话hash（按 API Key 限定作用域）
+func (s *GatewayService) GenerateSessionHash(apiKeyID int64, body []byte) string {
+	return scopeSessionHash(apiKeyID, s.generateSessionHash(body))
+}
+
+func (s *GatewayService) generateSessionHash(body []byte) string {
 	var req map[string]any
 	if err := json.Unmarshal(body, &req); err != nil {
 		return ""
@@ -288,18 +319,25 @@ func (s *GatewayService) SelectAccountForModel(ctx context.Context, groupID *int
 
 // SelectAccountForModelWithExclusions selects an account supporting the requested model while excluding specified accounts.
 func (s *GatewayService) SelectAccountForModelWithExclusions(ctx context.Context, groupID *int64, sessionHash string, requestedModel string, excludedIDs map[int64]struct{}) (*Account, error) {
-	// 1. 查询粘性会话
+	ctx, span := tracing.Start(ctx, "GatewayService.SelectAccountForModelWithExclusions",
+		attribute.String("model", requestedModel),
+		attribute.Int("excluded_accounts", len(excludedIDs)),
+	)
+	defer span.End()
+
+	// 1. 查询粘性会话（按平台、分组、API Key 隔离）
+	sessionKey := stickySessionKey(PlatformAnthropic, groupID, sessionHash)
 	if sessionHash != "" {
-		accountID, err := s.cache.GetSessionAccountID(ctx, sessionHash)
+		accountID, err := s.cache.GetSessionAccountID(ctx, sessionKey)
 		if err == nil && accountID > 0 {
 			if _, excluded := excludedIDs[accountID]; !excluded {
 				account, err := s.accountRepo.GetByID(ctx, accountID)
 				// 使用IsSchedulable代替IsActive，确保限流/过载账号不会被选中
-				// 同时检查模型支持
-				if err == nil && account.IsSchedulable() && (requestedModel == "" || account.IsModelSupported(requestedModel)) {
+				// 同时检查平台、分组归属与模型支持
+				if err == nil && isStickyAccountEligible(account, PlatformAnthropic, groupID, requestedModel) {
 					// 续期粘性会话
-					if err := s.cache.RefreshSessionTTL(ctx, sessionHash, stickySessionTTL); err != nil {
-						log.Printf("refresh session ttl failed: session=%s err=%v", sessionHash, err)
+					if err := s.cache.RefreshSessionTTL(ctx, sessionKey, stickySessionTTL); err != nil {
+						logger.FromContext(ctx).Warn("refresh session ttl failed", "session", sessionKey, logger.Err(err))
 					}
 					return account, nil
 				}
@@ -319,8 +357,8 @@ func (s *GatewayService) SelectAccountForModelWithExclusions(ctx context.Context
 		return nil, fmt.Errorf("query accounts failed: %w", err)
 	}
 
-	// 3. 按优先级+最久未用选择（考虑模型支持）
-	var selected *Account
+	// 3. 过滤后按分组调度策略选择（默认优先级+最久未用）
+	candidates := make([]*Account, 0, len(accounts))
 	for i := range accounts {
 		acc := &accounts[i]
 		if _, excluded := excludedIDs[acc.ID]; excluded {
@@ -330,29 +368,9 @@ func (s *GatewayService) SelectAccountForModelWithExclusions(ctx context.Context
 		if requestedModel != "" && !acc.IsModelSupported(requestedModel) {
 			continue
 		}
-		if selected == nil {
-			selected = acc
-			continue
-		}
-		// 优先选择priority值更小的（priority值越小优先级越高）
-		if acc.Priority < selected.Priority {
-			selected = acc
-		} else if acc.Priority == selected.Priority {
-			// 优先级相同时，选最久未用的
-			switch {
-			case acc.LastUsedAt == nil && selected.LastUsedAt != nil:
-				selected = acc
-			case acc.LastUsedAt != nil && selected.LastUsedAt == nil:
-				// keep selected (never used is preferred)
-			case acc.LastUsedAt == nil && selected.LastUsedAt == nil:
-				// keep selected (both never used)
-			default:
-				if acc.LastUsedAt.Before(*selected.LastUsedAt) {
-					selected = acc
-				}
-			}
-		}
+		candidates = append(candidates, acc)
 	}
+	selected := s.scheduler.Select(ctx, groupID, PlatformAnthropic, candidates, nil)
 
 	if selected == nil {
 		if requestedModel != "" {
@@ -363,8 +381,8 @@ func (s *GatewayService) SelectAccountForModelWithExclusions(ctx context.Context
 
 	// 4. 建立粘性绑定
 	if sessionHash != "" {
-		if err := s.cache.SetSessionAccountID(ctx, sessionHash, selected.ID, stickySessionTTL); err != nil {
-			log.Printf("set session account failed: session=%s account_id=%d err=%v", sessionHash, selected.ID, err)
+		if err := s.cache.SetSessionAccountID(ctx, sessionKey, selected.ID, stickySessionTTL); err != nil {
+			logger.FromContext(ctx).Warn("set session account failed", "session", sessionKey, "account_id", selected.ID, logger.Err(err))
 		}
 	}
 
@@ -440,7 +458,7 @@ func (s *GatewayService) Forward(ctx context.Context, c *gin.Context, account *A
 		body, _ = sjson.SetBytes(body, "system", []any{
 			map[string]any{
 				"type": "text",
-				"text": "You are Claude Code, Anthropic's official CLI for Claude.",
+				"text": claudeCodeSystemPrompt,
 				"cache_control": map[string]string{
 					"type": "ephemeral",
 				},
@@ -456,7 +474,7 @@ func (s *GatewayService) Forward(ctx context.Context, c *gin.Context, account *A
 			// 替换请求体中的模型名
 			body = s.replaceModelInBody(body, mappedModel)
 			req.Model = mappedModel
-			log.Printf("Model mapping applied: %s -> %s (account: %s)", originalModel, mappedModel, account.Name)
+			logger.FromContext(ctx).Debug("model mapping applied", "model", originalModel, "mapped_model", mappedModel, "account_name", account.Name)
 		}
 	}
 
@@ -473,35 +491,9 @@ func (s *GatewayService) Forward(ctx context.Context, c *gin.Context, account *A
 	}
 
 	// 重试循环
-	var resp *http.Response
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		// 构建上游请求（每次重试需要重新构建，因为请求体需要重新读取）
-		upstreamReq, err := s.buildUpstreamRequest(ctx, c, account, body, token, tokenType)
-		if err != nil {
-			return nil, err
-		}
-
-		// 发送请求
-		resp, err = s.httpUpstream.Do(upstreamReq, proxyURL)
-		if err != nil {
-			return nil, fmt.Errorf("upstream request failed: %w", err)
-		}
-
-		// 检查是否需要重试
-		if resp.StatusCode >= 400 && s.shouldRetryUpstreamError(account, resp.StatusCode) {
-			if attempt < maxRetries {
-				log.Printf("Account %d: upstream error %d, retry %d/%d after %v",
-					account.ID, resp.StatusCode, attempt, maxRetries, retryDelay)
-				_ = resp.Body.Close()
-				time.Sleep(retryDelay)
-				continue
-			}
-			// 最后一次尝试也失败，跳出循环处理重试耗尽
-			break
-		}
-
-		// 不需要重试（成功或不可重试的错误），跳出循环
-		break
+	resp, err := s.doUpstreamWithRetry(ctx, c, account, body, token, tokenType, proxyURL)
+	if err != nil {
+		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
@@ -552,6 +544,43 @@ func (s *GatewayService) Forward(ctx context.Context, c *gin.Context, account *A
 	}, nil
 }
 
+// doUpstreamWithRetry 发送上游请求，对可重试的错误按固定间隔重试
+// 返回最后一次的响应，调用方负责关闭响应体
+func (s *GatewayService) doUpstreamWithRetry(ctx context.Context, c *gin.Context, account *Account, body []byte, token, tokenType, proxyURL string) (*http.Response, error) {
+	var resp *http.Response
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		// 构建上游请求（每次重试需要重新构建，因为请求体需要重新读取）
+		upstreamReq, err := s.buildUpstreamRequest(ctx, c, account, body, token, tokenType)
+		if err != nil {
+			return nil, err
+		}
+
+		// 发送请求
+		resp, err = doUpstreamAttempt(ctx, s.httpUpstream, upstreamReq, proxyURL, account, attempt)
+		if err != nil {
+			return nil, fmt.Errorf("upstream request failed: %w", err)
+		}
+
+		// 检查是否需要重试
+		if resp.StatusCode >= 400 && s.shouldRetryUpstreamError(account, resp.StatusCode) {
+			if attempt < maxRetries {
+				logger.FromContext(ctx).Warn("upstream error, retrying",
+					"account_id", account.ID, "status", resp.StatusCode, "attempt", attempt, "max_retries", maxRetries, "delay", retryDelay)
+				metrics.IncUpstreamRetry(account.Platform)
+				_ = resp.Body.Close()
+				time.Sleep(retryDelay)
+				continue
+			}
+			// 最后一次尝试也失败，跳出循环处理重试耗尽
+			break
+		}
+
+		// 不需要重试（成功或不可重试的错误），跳出循环
+		break
+	}
+	return resp, nil
+}
+
 func (s *GatewayService) buildUpstreamRequest(ctx context.Context, c *gin.Context, account *Account, body []byte, token, tokenType string) (*http.Request, error) {
 	// 确定目标URL
 	targetURL := claudeAPIURL
@@ -566,7 +595,7 @@ func (s *GatewayService) buildUpstreamRequest(ctx context.Context, c *gin.Contex
 		// 1. 获取或创建指纹（包含随机生成的ClientID）
 		fp, err := s.identityService.GetOrCreateFingerprint(ctx, account.ID, c.Request.Header)
 		if err != nil {
-			log.Printf("Warning: failed to get fingerprint for account %d: %v", account.ID, err)
+			logger.FromContext(ctx).Warn("get fingerprint failed", "account_id", account.ID, logger.Err(err))
 			// 失败时降级为透传原始headers
 		} else {
 			fingerprint = fp
@@ -738,10 +767,10 @@ func (s *GatewayService) handleRetryExhaustedSideEffects(ctx context.Context, re
 	// OAuth/Setup Token 账号的 403：标记账号异常
 	if account.IsOAuth() && statusCode == 403 {
 		s.rateLimitService.HandleUpstreamError(ctx, account, statusCode, resp.Header, body)
-		log.Printf("Account %d: marked as error after %d retries for status %d", account.ID, maxRetries, statusCode)
+		logger.FromContext(ctx).Warn("account marked as error after retries", "account_id", account.ID, "retries", maxRetries, "status", statusCode)
 	} else {
 		// API Key 未配置错误码：不标记账号状态
-		log.Printf("Account %d: upstream error %d after %d retries (not marking account)", account.ID, statusCode, maxRetries)
+		logger.FromContext(ctx).Warn("upstream error after retries, account not marked", "account_id", account.ID, "status", statusCode, "retries", maxRetries)
 	}
 }
 
@@ -986,6 +1015,7 @@ type RecordUsageInput struct {
 	User         *User
 	Account      *Account
 	Subscription *UserSubscription // 可选：订阅信息
+	BalanceHold  *BalanceHold      // 可选：余额预授权，结算后释放
 }
 
 // RecordUsage 记录使用量并扣费（或更新订阅用量）
@@ -996,6 +1026,15 @@ func (s *GatewayService) RecordUsage(ctx context.Context, input *RecordUsageInpu
 	account := input.Account
 	subscription := input.Subscription
 
+	ctx, span := tracing.Start(ctx, "GatewayService.RecordUsage",
+		attribute.Int64("account.id", account.ID),
+		attribute.String("upstream.request_id", result.RequestID),
+	)
+	defer span.End()
+
+	// 实际费用入账（含余额缓存扣减）后释放预授权
+	defer input.BalanceHold.Release()
+
 	// 计算费用
 	tokens := UsageTokens{
 		InputTokens:         result.Usage.InputTokens,
@@ -1012,11 +1051,16 @@ func (s *GatewayService) RecordUsage(ctx context.Context, input *RecordUsageInpu
 
 	cost, err := s.billingService.CalculateCost(result.Model, tokens, multiplier)
 	if err != nil {
-		log.Printf("Calculate cost failed: %v", err)
+		logger.FromContext(ctx).Error("calculate cost failed", logger.Err(err))
 		// 使用默认费用继续
 		cost = &CostBreakdown{ActualCost: 0}
 	}
 
+	// 命中响应缓存时按分组的缓存倍率计费
+	if result.CacheHit {
+		multiplier = applyResponseCacheMultiplier(cost, apiKey.Group, multiplier)
+	}
+
 	// 判断计费方式：订阅模式 vs 余额模式
 	isSubscriptionBilling := subscription != nil && apiKey.Group != nil && apiKey.Group.IsSubscriptionType()
 	billingType := BillingTypeBalance
@@ -1031,6 +1075,7 @@ func (s *GatewayService) RecordUsage(ctx context.Context, input *RecordUsageInpu
 		ApiKeyID:            apiKey.ID,
 		AccountID:           account.ID,
 		RequestID:           result.RequestID,
+		TraceID:             tracing.TraceID(ctx),
 		Model:               result.Model,
 		InputTokens:         result.Usage.InputTokens,
 		OutputTokens:        result.Usage.OutputTokens,
@@ -1045,6 +1090,7 @@ func (s *GatewayService) RecordUsage(ctx context.Context, input *RecordUsageInpu
 		RateMultiplier:      multiplier,
 		BillingType:         billingType,
 		Stream:              result.Stream,
+		CacheHit:            result.CacheHit,
 		DurationMs:          &durationMs,
 		FirstTokenMs:        result.FirstTokenMs,
 		CreatedAt:           time.Now(),
@@ -1057,52 +1103,90 @@ func (s *GatewayService) RecordUsage(ctx context.Context, input *RecordUsageInpu
 	if subscription != nil {
 		usageLog.SubscriptionID = &subscription.ID
 	}
-
-	if err := s.usageLogRepo.Create(ctx, usageLog); err != nil {
-		log.Printf("Create usage log failed: %v", err)
+	usageLog.OrganizationID = apiKey.OrganizationID
+
+	// 余额模式：使用记录、扣费与余额流水在同一事务中写入（组织 Key 扣组织余额）
+	if !isSubscriptionBilling && cost.ActualCost > 0 {
+		debit := &BalanceTransaction{UserID: user.ID, OrganizationID: apiKey.OrganizationID, Type: BalanceTxTypeUsage, Amount: -cost.ActualCost}
+		if err := s.balanceTxRepo.CreateUsageWithDebit(ctx, usageLog, debit); err != nil {
+			logger.FromContext(ctx).Error("create usage log with balance debit failed",
+				"user_id", user.ID, "api_key_id", apiKey.ID, "cost", cost.ActualCost, logger.Err(err))
+		} else if !apiKey.IsOrganizationOwned() {
+			s.webhookService.NotifyBalanceDebited(ctx, user, debit.BalanceAfter, cost.ActualCost)
+			s.notificationService.NotifyBalanceDebited(ctx, user, debit.BalanceAfter, cost.ActualCost)
+		}
+	} else if err := s.usageLogRepo.Create(ctx, usageLog); err != nil {
+		logger.FromContext(ctx).Error("create usage log failed",
+			"user_id", user.ID, "api_key_id", apiKey.ID, "cost", cost.ActualCost, logger.Err(err))
 	}
 
+	// 记录 token、费用与首 token 延迟指标
+	metrics.AddUsage(metrics.Usage{
+		Platform:            account.Platform,
+		Model:               result.Model,
+		BillingType:         usageMetricsBillingType(isSubscriptionBilling),
+		InputTokens:         result.Usage.InputTokens,
+		OutputTokens:        result.Usage.OutputTokens,
+		CacheCreationTokens: result.Usage.CacheCreationInputTokens,
+		CacheReadTokens:     result.Usage.CacheReadInputTokens,
+		CostUSD:             cost.ActualCost,
+	})
+	metrics.ObserveFirstToken(account.Platform, result.Model, result.FirstTokenMs)
+
+	// 更新 API Key 级别的 TPM 与花费计数
+	s.apiKeyLimitService.RecordUsage(ctx, apiKey, int64(result.Usage.InputTokens+result.Usage.OutputTokens+result.Usage.CacheCreationInputTokens), cost.ActualCost)
+
 	// 根据计费类型执行扣费
 	if isSubscriptionBilling {
 		// 订阅模式：更新订阅用量（使用 TotalCost 原始费用，不考虑倍率）
 		if cost.TotalCost > 0 {
 			if err := s.userSubRepo.IncrementUsage(ctx, subscription.ID, cost.TotalCost); err != nil {
-				log.Printf("Increment subscription usage failed: %v", err)
+				logger.FromContext(ctx).Error("increment subscription usage failed", logger.Err(err))
+			}
+			// 异步更新订阅缓存（组织订阅不使用缓存）
+			if subscription.OrganizationID == nil {
+				go func() {
+					cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					defer cancel()
+					if err := s.billingCacheService.UpdateSubscriptionUsage(cacheCtx, user.ID, *apiKey.GroupID, cost.TotalCost); err != nil {
+						logger.FromContext(ctx).Warn("update subscription cache failed", logger.Err(err))
+					}
+				}()
 			}
-			// 异步更新订阅缓存
-			go func() {
-				cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-				if err := s.billingCacheService.UpdateSubscriptionUsage(cacheCtx, user.ID, *apiKey.GroupID, cost.TotalCost); err != nil {
-					log.Printf("Update subscription cache failed: %v", err)
-				}
-			}()
 		}
 	} else {
-		// 余额模式：扣除用户余额（使用 ActualCost 考虑倍率后的费用）
-		if cost.ActualCost > 0 {
-			if err := s.userRepo.DeductBalance(ctx, user.ID, cost.ActualCost); err != nil {
-				log.Printf("Deduct balance failed: %v", err)
+		// 余额模式：数据库扣费已随使用记录完成（使用 ActualCost 考虑倍率后的费用）
+		// 组织余额不使用缓存
+		if cost.ActualCost > 0 && !apiKey.IsOrganizationOwned() {
+			// 同步更新余额缓存，确保释放预授权前实际费用已计入可用余额
+			if err := s.billingCacheService.DeductBalanceCache(ctx, user.ID, cost.ActualCost); err != nil {
+				logger.FromContext(ctx).Warn("update balance cache failed", logger.Err(err))
 			}
-			// 异步更新余额缓存
-			go func() {
-				cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-				if err := s.billingCacheService.DeductBalanceCache(cacheCtx, user.ID, cost.ActualCost); err != nil {
-					log.Printf("Update balance cache failed: %v", err)
-				}
-			}()
 		}
 	}
 
-	// 更新账号最后使用时间
+	// 推送请求完成实时事件
+	publishLiveCompleted(ctx, account, result.Model, result.Usage.InputTokens, result.Usage.OutputTokens, result.CacheHit)
+
+	// 更新账号最后使用时间（命中缓存时未使用账号）
+	if result.CacheHit {
+		return nil
+	}
 	if err := s.accountRepo.UpdateLastUsed(ctx, account.ID); err != nil {
-		log.Printf("Update last used failed: %v", err)
+		logger.FromContext(ctx).Warn("update account last used failed", logger.Err(err))
 	}
 
 	return nil
 }
 
+// usageMetricsBillingType 指标中的计费类型标签
+func usageMetricsBillingType(isSubscriptionBilling bool) string {
+	if isSubscriptionBilling {
+		return "subscription"
+	}
+	return "balance"
+}
+
 // ForwardCountTokens 转发 count_tokens 请求到上游 API
 // 特点：不记录使用量、仅支持非流式响应
 func (s *GatewayService) ForwardCountTokens(ctx context.Context, c *gin.Context, account *Account, body []byte) error {
@@ -1115,7 +1199,7 @@ func (s *GatewayService) ForwardCountTokens(ctx context.Context, c *gin.Context,
 			mappedModel := account.GetMappedModel(req.Model)
 			if mappedModel != req.Model {
 				body = s.replaceModelInBody(body, mappedModel)
-				log.Printf("CountTokens model mapping applied: %s -> %s (account: %s)", req.Model, mappedModel, account.Name)
+				logger.FromContext(ctx).Debug("count_tokens model mapping applied", "model", req.Model, "mapped_model", mappedModel, "account_name", account.Name)
 			}
 		}
 	}
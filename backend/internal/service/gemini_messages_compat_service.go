@@ -10,7 +10,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"math"
 	mathrand "math/rand"
 	"net/http"
@@ -20,6 +19,10 @@ import (
 
 	"github.com/Wei-Shaw/sub2api/internal/pkg/geminicli"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/googleapi"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/metrics"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/gin-gonic/gin"
 )
@@ -38,6 +41,7 @@ type GeminiMessagesCompatService struct {
 	tokenProvider    *GeminiTokenProvider
 	rateLimitService *RateLimitService
 	httpUpstream     HTTPUpstream
+	scheduler        *AccountScheduler
 }
 
 func NewGeminiMessagesCompatService(
@@ -46,6 +50,7 @@ func NewGeminiMessagesCompatService(
 	tokenProvider *GeminiTokenProvider,
 	rateLimitService *RateLimitService,
 	httpUpstream HTTPUpstream,
+	scheduler *AccountScheduler,
 ) *GeminiMessagesCompatService {
 	return &GeminiMessagesCompatService{
 		accountRepo:      accountRepo,
@@ -53,6 +58,7 @@ func NewGeminiMessagesCompatService(
 		tokenProvider:    tokenProvider,
 		rateLimitService: rateLimitService,
 		httpUpstream:     httpUpstream,
+		scheduler:        scheduler,
 	}
 }
 
@@ -66,13 +72,19 @@ func (s *GeminiMessagesCompatService) SelectAccountForModel(ctx context.Context,
 }
 
 func (s *GeminiMessagesCompatService) SelectAccountForModelWithExclusions(ctx context.Context, groupID *int64, sessionHash string, requestedModel string, excludedIDs map[int64]struct{}) (*Account, error) {
-	cacheKey := "gemini:" + sessionHash
+	ctx, span := tracing.Start(ctx, "GeminiMessagesCompatService.SelectAccountForModelWithExclusions",
+		attribute.String("model", requestedModel),
+		attribute.Int("excluded_accounts", len(excludedIDs)),
+	)
+	defer span.End()
+
+	cacheKey := stickySessionKey(PlatformGemini, groupID, sessionHash)
 	if sessionHash != "" {
 		accountID, err := s.cache.GetSessionAccountID(ctx, cacheKey)
 		if err == nil && accountID > 0 {
 			if _, excluded := excludedIDs[accountID]; !excluded {
 				account, err := s.accountRepo.GetByID(ctx, accountID)
-				if err == nil && account.IsSchedulable() && account.Platform == PlatformGemini && (requestedModel == "" || account.IsModelSupported(requestedModel)) {
+				if err == nil && isStickyAccountEligible(account, PlatformGemini, groupID, requestedModel) {
 					_ = s.cache.RefreshSessionTTL(ctx, cacheKey, geminiStickySessionTTL)
 					return account, nil
 				}
@@ -91,7 +103,7 @@ func (s *GeminiMessagesCompatService) SelectAccountForModelWithExclusions(ctx co
 		return nil, fmt.Errorf("query accounts failed: %w", err)
 	}
 
-	var selected *Account
+	candidates := make([]*Account, 0, len(accounts))
 	for i := range accounts {
 		acc := &accounts[i]
 		if _, excluded := excludedIDs[acc.ID]; excluded {
@@ -100,30 +112,12 @@ func (s *GeminiMessagesCompatService) SelectAccountForModelWithExclusions(ctx co
 		if requestedModel != "" && !acc.IsModelSupported(requestedModel) {
 			continue
 		}
-		if selected == nil {
-			selected = acc
-			continue
-		}
-		if acc.Priority < selected.Priority {
-			selected = acc
-		} else if acc.Priority == selected.Priority {
-			switch {
-			case acc.LastUsedAt == nil && selected.LastUsedAt != nil:
-				selected = acc
-			case acc.LastUsedAt != nil && selected.LastUsedAt == nil:
-				// keep selected (never used is preferred)
-			case acc.LastUsedAt == nil && selected.LastUsedAt == nil:
-				// Prefer OAuth accounts when both are unused (more compatible for Code Assist flows).
-				if acc.Type == AccountTypeOAuth && selected.Type != AccountTypeOAuth {
-					selected = acc
-				}
-			default:
-				if acc.LastUsedAt.Before(*selected.LastUsedAt) {
-					selected = acc
-				}
-			}
-		}
+		candidates = append(candidates, acc)
 	}
+	// Prefer OAuth accounts when both are unused (more compatible for Code Assist flows).
+	selected := s.scheduler.Select(ctx, groupID, PlatformGemini, candidates, func(a, b *Account) bool {
+		return a.Type == AccountTypeOAuth && b.Type != AccountTypeOAuth
+	})
 
 	if selected == nil {
 		if requestedModel != "" {
@@ -387,10 +381,11 @@ func (s *GeminiMessagesCompatService) Forward(ctx context.Context, c *gin.Contex
 		}
 		requestIDHeader = idHeader
 
-		resp, err = s.httpUpstream.Do(upstreamReq, proxyURL)
+		resp, err = doUpstreamAttempt(ctx, s.httpUpstream, upstreamReq, proxyURL, account, attempt)
 		if err != nil {
 			if attempt < geminiMaxRetries {
-				log.Printf("Gemini account %d: upstream request failed, retry %d/%d: %v", account.ID, attempt, geminiMaxRetries, err)
+				logger.FromContext(ctx).Warn("upstream request failed, retrying", "account_id", account.ID, "attempt", attempt, "max_retries", geminiMaxRetries, logger.Err(err))
+				metrics.IncUpstreamRetry(account.Platform)
 				sleepGeminiBackoff(attempt)
 				continue
 			}
@@ -414,7 +409,8 @@ func (s *GeminiMessagesCompatService) Forward(ctx context.Context, c *gin.Contex
 				s.handleGeminiUpstreamError(ctx, account, resp.StatusCode, resp.Header, respBody)
 			}
 			if attempt < geminiMaxRetries {
-				log.Printf("Gemini account %d: upstream status %d, retry %d/%d", account.ID, resp.StatusCode, attempt, geminiMaxRetries)
+				logger.FromContext(ctx).Warn("upstream error, retrying", "account_id", account.ID, "status", resp.StatusCode, "attempt", attempt, "max_retries", geminiMaxRetries)
+				metrics.IncUpstreamRetry(account.Platform)
 				sleepGeminiBackoff(attempt)
 				continue
 			}
@@ -640,10 +636,11 @@ func (s *GeminiMessagesCompatService) ForwardNative(ctx context.Context, c *gin.
 		}
 		requestIDHeader = idHeader
 
-		resp, err = s.httpUpstream.Do(upstreamReq, proxyURL)
+		resp, err = doUpstreamAttempt(ctx, s.httpUpstream, upstreamReq, proxyURL, account, attempt)
 		if err != nil {
 			if attempt < geminiMaxRetries {
-				log.Printf("Gemini account %d: upstream request failed, retry %d/%d: %v", account.ID, attempt, geminiMaxRetries, err)
+				logger.FromContext(ctx).Warn("upstream request failed, retrying", "account_id", account.ID, "attempt", attempt, "max_retries", geminiMaxRetries, logger.Err(err))
+				metrics.IncUpstreamRetry(account.Platform)
 				sleepGeminiBackoff(attempt)
 				continue
 			}
@@ -678,7 +675,8 @@ func (s *GeminiMessagesCompatService) ForwardNative(ctx context.Context, c *gin.
 				s.handleGeminiUpstreamError(ctx, account, resp.StatusCode, resp.Header, respBody)
 			}
 			if attempt < geminiMaxRetries {
-				log.Printf("Gemini account %d: upstream status %d, retry %d/%d", account.ID, resp.StatusCode, attempt, geminiMaxRetries)
+				logger.FromContext(ctx).Warn("upstream error, retrying", "account_id", account.ID, "status", resp.StatusCode, "attempt", attempt, "max_retries", geminiMaxRetries)
+				metrics.IncUpstreamRetry(account.Platform)
 				sleepGeminiBackoff(attempt)
 				continue
 			}
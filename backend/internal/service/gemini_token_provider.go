@@ -3,10 +3,11 @@ package service
 import (
 	"context"
 	"errors"
-	"log"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 )
 
 const (
@@ -114,7 +115,7 @@ func (p *GeminiTokenProvider) GetAccessToken(ctx context.Context, account *Accou
 
 		detected, err := p.geminiOAuthService.fetchProjectID(ctx, accessToken, proxyURL)
 		if err != nil {
-			log.Printf("[GeminiTokenProvider] Auto-detect project_id failed: %v, fallback to AI Studio API mode", err)
+			logger.FromContext(ctx).Warn("auto-detect gemini project_id failed, falling back to AI Studio API mode", "account_id", account.ID, logger.Err(err))
 			return accessToken, nil
 		}
 		detected = strings.TrimSpace(detected)
@@ -16,6 +16,13 @@ type Group struct {
 	WeeklyLimitUSD   *float64
 	MonthlyLimitUSD  *float64
 
+	SchedulingStrategy string
+
+	// 相同非流式请求的响应缓存（Redis）
+	ResponseCacheEnabled    bool
+	ResponseCacheTTLSeconds int     // <=0 时使用默认 TTL
+	ResponseCacheMultiplier float64 // 命中缓存时在分组倍率基础上再乘以该倍率，可为 0
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 
@@ -46,3 +53,19 @@ func (g *Group) HasWeeklyLimit() bool {
 func (g *Group) HasMonthlyLimit() bool {
 	return g.MonthlyLimitUSD != nil && *g.MonthlyLimitUSD > 0
 }
+
+// GetResponseCacheTTL 返回响应缓存 TTL，未设置时为 defaultResponseCacheTTL
+func (g *Group) GetResponseCacheTTL() time.Duration {
+	if g.ResponseCacheTTLSeconds <= 0 {
+		return defaultResponseCacheTTL
+	}
+	return time.Duration(g.ResponseCacheTTLSeconds) * time.Second
+}
+
+// GetSchedulingStrategy 返回分组的账号调度策略，未设置时为 priority
+func (g *Group) GetSchedulingStrategy() string {
+	if g == nil || g.SchedulingStrategy == "" {
+		return SchedulingStrategyPriority
+	}
+	return g.SchedulingStrategy
+}
@@ -6,4 +6,6 @@ import "net/http"
 // This is a generic interface that can be used for any HTTP-based upstream service.
 type HTTPUpstream interface {
 	Do(req *http.Request, proxyURL string) (*http.Response, error)
+	// InvalidateProxy drops the pooled connections for a proxy URL (called when a proxy is updated or deleted)
+	InvalidateProxy(proxyURL string)
 }
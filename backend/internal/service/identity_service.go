@@ -7,11 +7,13 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"regexp"
 	"strconv"
 	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 )
 
 // 预编译正则表达式（避免每次调用重新编译）
@@ -75,7 +77,7 @@ func (s *IdentityService) GetOrCreateFingerprint(ctx context.Context, accountID
 			cached.UserAgent = clientUA
 			// 保存更新后的指纹
 			_ = s.cache.SetFingerprint(ctx, accountID, cached)
-			log.Printf("Updated fingerprint user-agent for account %d: %s", accountID, clientUA)
+			logger.FromContext(ctx).Info("updated fingerprint user-agent", "account_id", accountID, "user_agent", clientUA)
 		}
 		return cached, nil
 	}
@@ -88,10 +90,10 @@ func (s *IdentityService) GetOrCreateFingerprint(ctx context.Context, accountID
 
 	// 保存到缓存（永不过期）
 	if err := s.cache.SetFingerprint(ctx, accountID, fp); err != nil {
-		log.Printf("Warning: failed to cache fingerprint for account %d: %v", accountID, err)
+		logger.FromContext(ctx).Warn("cache fingerprint failed", "account_id", accountID, logger.Err(err))
 	}
 
-	log.Printf("Created new fingerprint for account %d with client_id: %s", accountID, fp.ClientID)
+	logger.FromContext(ctx).Info("created fingerprint", "account_id", accountID, "client_id", fp.ClientID)
 	return fp, nil
 }
 
@@ -208,7 +210,7 @@ func generateClientID() string {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
 		// 极罕见的情况，使用时间戳+固定值作为fallback
-		log.Printf("Warning: crypto/rand.Read failed: %v, using fallback", err)
+		slog.Warn("crypto/rand read failed, using fallback", logger.Err(err))
 		// 使用SHA256(当前纳秒时间)作为fallback
 		h := sha256.Sum256([]byte(fmt.Sprintf("%d", time.Now().UnixNano())))
 		return hex.EncodeToString(h[:])
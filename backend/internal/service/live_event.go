@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 实时请求事件类型
+const (
+	LiveEventRequestStarted   = "request_started"
+	LiveEventAccountSelected  = "account_selected"
+	LiveEventFailover         = "failover"
+	LiveEventRequestCompleted = "request_completed"
+	LiveEventRequestFailed    = "request_failed"
+)
+
+// LiveEvent 网关请求生命周期中的实时事件，推送给管理后台。
+// 经 Redis pub/sub 在多副本间广播，因此带 json tag。
+type LiveEvent struct {
+	Type       string `json:"type"`
+	InstanceID string `json:"instance_id"` // 产生事件的副本，用于忽略自己发出的广播
+	RequestID  string `json:"request_id"`
+
+	UserID    int64  `json:"user_id"`
+	UserEmail string `json:"user_email,omitempty"`
+	ApiKeyID  int64  `json:"api_key_id"`
+	GroupID   *int64 `json:"group_id,omitempty"`
+	Platform  string `json:"platform"`
+	Model     string `json:"model"`
+	Stream    bool   `json:"stream"`
+
+	AccountID   int64  `json:"account_id,omitempty"`
+	AccountName string `json:"account_name,omitempty"`
+
+	// failover / request_failed 时为上游或返回给客户端的状态码
+	StatusCode int `json:"status_code,omitempty"`
+
+	// request_completed 时填写
+	InputTokens  int  `json:"input_tokens,omitempty"`
+	OutputTokens int  `json:"output_tokens,omitempty"`
+	CacheHit     bool `json:"cache_hit,omitempty"`
+
+	LatencyMs int64     `json:"latency_ms"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type liveRequestContextKey struct{}
+
+// liveRequest 单个网关请求的实时事件状态，随请求 context 传递
+type liveRequest struct {
+	svc       *LiveEventService
+	startedAt time.Time
+
+	mu   sync.Mutex
+	base LiveEvent
+}
+
+// liveRequestFromContext 获取请求上的实时事件状态，未开启时返回 nil
+func liveRequestFromContext(ctx context.Context) *liveRequest {
+	r, _ := ctx.Value(liveRequestContextKey{}).(*liveRequest)
+	return r
+}
+
+// publish 以请求的基础信息为模板发布事件，fill 用于填充事件特有字段
+func (r *liveRequest) publish(eventType string, fill func(e *LiveEvent)) {
+	r.mu.Lock()
+	event := r.base
+	r.mu.Unlock()
+
+	event.Type = eventType
+	event.LatencyMs = time.Since(r.startedAt).Milliseconds()
+	if fill != nil {
+		fill(&event)
+	}
+	r.svc.Publish(&event)
+}
+
+// PublishLiveAccountSelected 发布选中上游账号事件（故障转移后会再次发布）
+func PublishLiveAccountSelected(ctx context.Context, account *Account) {
+	r := liveRequestFromContext(ctx)
+	if r == nil || account == nil {
+		return
+	}
+	r.mu.Lock()
+	r.base.AccountID = account.ID
+	r.base.AccountName = account.Name
+	r.mu.Unlock()
+	r.publish(LiveEventAccountSelected, nil)
+}
+
+// PublishLiveFailover 发布账号故障转移事件
+func PublishLiveFailover(ctx context.Context, account *Account, statusCode int) {
+	r := liveRequestFromContext(ctx)
+	if r == nil {
+		return
+	}
+	r.publish(LiveEventFailover, func(e *LiveEvent) {
+		e.AccountID = account.ID
+		e.AccountName = account.Name
+		e.StatusCode = statusCode
+	})
+}
+
+// publishLiveCompleted 由 RecordUsage 在记录使用量后发布请求完成事件
+func publishLiveCompleted(ctx context.Context, account *Account, model string, inputTokens, outputTokens int, cacheHit bool) {
+	r := liveRequestFromContext(ctx)
+	if r == nil {
+		return
+	}
+	r.publish(LiveEventRequestCompleted, func(e *LiveEvent) {
+		if account != nil {
+			e.AccountID = account.ID
+			if account.Name != "" {
+				e.AccountName = account.Name
+			}
+		}
+		if model != "" {
+			e.Model = model
+		}
+		e.InputTokens = inputTokens
+		e.OutputTokens = outputTokens
+		e.CacheHit = cacheHit
+	})
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// liveEventQueueSize 待发布事件队列长度，队列满时丢弃事件，不阻塞网关请求
+	liveEventQueueSize = 1024
+	// liveEventSubscriberBuffer 单个订阅者的缓冲长度，消费过慢时丢弃事件
+	liveEventSubscriberBuffer = 256
+	// liveEventResubscribeDelay Redis 订阅断开后的重试间隔
+	liveEventResubscribeDelay = 5 * time.Second
+)
+
+// LiveEventPubSub 跨副本广播实时事件
+type LiveEventPubSub interface {
+	Publish(ctx context.Context, event *LiveEvent) error
+	// Subscribe 订阅所有副本发布的事件，ctx 取消后关闭返回的 channel
+	Subscribe(ctx context.Context) (<-chan *LiveEvent, error)
+}
+
+// LiveEventService 进程内实时事件总线：网关请求与 RecordUsage 发布事件，
+// 本副本的订阅者直接收到，其他副本通过 Redis pub/sub 转发。
+type LiveEventService struct {
+	pubsub     LiveEventPubSub
+	instanceID string
+
+	queue chan *LiveEvent
+
+	mu          sync.RWMutex
+	subscribers map[chan *LiveEvent]struct{}
+
+	stopCh chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewLiveEventService 创建实时事件服务
+func NewLiveEventService(pubsub LiveEventPubSub) *LiveEventService {
+	return &LiveEventService{
+		pubsub:      pubsub,
+		instanceID:  newLiveEventInstanceID(),
+		queue:       make(chan *LiveEvent, liveEventQueueSize),
+		subscribers: make(map[chan *LiveEvent]struct{}),
+		stopCh:      make(chan struct{}),
+	}
+}
+
+func newLiveEventInstanceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Start 启动发布与跨副本订阅
+func (s *LiveEventService) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(2)
+	go s.publishLoop()
+	go s.subscribeLoop(ctx)
+	logger.Component("live_events").Info("service started", "instance_id", s.instanceID)
+}
+
+// Stop 停止后台任务并关闭所有订阅
+func (s *LiveEventService) Stop() {
+	close(s.stopCh)
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+
+	s.mu.Lock()
+	for ch := range s.subscribers {
+		close(ch)
+		delete(s.subscribers, ch)
+	}
+	s.mu.Unlock()
+	logger.Component("live_events").Info("service stopped")
+}
+
+// Publish 发布事件，不阻塞调用方
+func (s *LiveEventService) Publish(event *LiveEvent) {
+	event.InstanceID = s.instanceID
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	select {
+	case s.queue <- event:
+	default:
+	}
+}
+
+// Subscribe 订阅实时事件，调用返回的函数取消订阅；服务停止时关闭返回的 channel
+func (s *LiveEventService) Subscribe() (<-chan *LiveEvent, func()) {
+	ch := make(chan *LiveEvent, liveEventSubscriberBuffer)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subscribers, ch)
+			s.mu.Unlock()
+		})
+	}
+}
+
+// Begin 为网关请求开启实时事件并发布 request_started，
+// 后续的选号、故障转移与完成事件通过 c.Request 的 context 关联到同一请求。
+func (s *LiveEventService) Begin(c *gin.Context, apiKey *ApiKey, platform, model string, stream bool) {
+	if apiKey == nil {
+		return
+	}
+	if apiKey.Group != nil && apiKey.Group.Platform != "" {
+		platform = apiKey.Group.Platform
+	}
+	r := &liveRequest{
+		svc:       s,
+		startedAt: time.Now(),
+		base: LiveEvent{
+			RequestID: c.Writer.Header().Get("X-Request-Id"),
+			UserID:    apiKey.UserID,
+			ApiKeyID:  apiKey.ID,
+			GroupID:   apiKey.GroupID,
+			Platform:  platform,
+			Model:     model,
+			Stream:    stream,
+		},
+	}
+	if apiKey.User != nil {
+		r.base.UserEmail = apiKey.User.Email
+	}
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), liveRequestContextKey{}, r))
+	r.publish(LiveEventRequestStarted, nil)
+}
+
+// Finish 在 handler 返回时调用，请求以错误状态码结束时发布 request_failed。
+// 成功的请求由 RecordUsage 发布 request_completed。
+func (s *LiveEventService) Finish(c *gin.Context) {
+	r := liveRequestFromContext(c.Request.Context())
+	if r == nil {
+		return
+	}
+	if status := c.Writer.Status(); status >= 400 {
+		r.publish(LiveEventRequestFailed, func(e *LiveEvent) {
+			e.StatusCode = status
+		})
+	}
+}
+
+// dispatch 将事件推送给本副本的所有订阅者
+func (s *LiveEventService) dispatch(event *LiveEvent) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (s *LiveEventService) publishLoop() {
+	defer s.wg.Done()
+	for {
+		select {
+		case event := <-s.queue:
+			s.dispatch(event)
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			if err := s.pubsub.Publish(ctx, event); err != nil {
+				logger.Component("live_events").Debug("publish live event failed", logger.Err(err))
+			}
+			cancel()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// subscribeLoop 接收其他副本的事件，订阅断开后自动重连
+func (s *LiveEventService) subscribeLoop(ctx context.Context) {
+	defer s.wg.Done()
+	for {
+		events, err := s.pubsub.Subscribe(ctx)
+		if err != nil {
+			logger.Component("live_events").Warn("subscribe live events failed", logger.Err(err))
+		} else {
+			for event := range events {
+				if event.InstanceID != s.instanceID {
+					s.dispatch(event)
+				}
+			}
+		}
+
+		select {
+		case <-time.After(liveEventResubscribeDelay):
+		case <-s.stopCh:
+			return
+		}
+	}
+}
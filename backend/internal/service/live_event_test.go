@@ -0,0 +1,123 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type liveEventPubSubStub struct {
+	published chan *LiveEvent
+	incoming  chan *LiveEvent
+}
+
+func (s *liveEventPubSubStub) Publish(ctx context.Context, event *LiveEvent) error {
+	s.published <- event
+	return nil
+}
+
+func (s *liveEventPubSubStub) Subscribe(ctx context.Context) (<-chan *LiveEvent, error) {
+	out := make(chan *LiveEvent)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case event := <-s.incoming:
+				out <- event
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func receiveLiveEvent(t *testing.T, ch <-chan *LiveEvent) *LiveEvent {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for live event")
+		return nil
+	}
+}
+
+func TestLiveEventService_RequestLifecycle(t *testing.T) {
+	stub := &liveEventPubSubStub{published: make(chan *LiveEvent, 16), incoming: make(chan *LiveEvent)}
+	svc := NewLiveEventService(stub)
+	svc.Start()
+	defer svc.Stop()
+
+	events, unsubscribe := svc.Subscribe()
+	defer unsubscribe()
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+
+	apiKey := &ApiKey{ID: 3, UserID: 2, User: &User{ID: 2, Email: "a@example.com"}, Group: &Group{Platform: PlatformOpenAI}}
+	svc.Begin(c, apiKey, PlatformAnthropic, "claude-3", true)
+
+	started := receiveLiveEvent(t, events)
+	require.Equal(t, LiveEventRequestStarted, started.Type)
+	require.Equal(t, PlatformOpenAI, started.Platform)
+	require.Equal(t, "a@example.com", started.UserEmail)
+	require.True(t, started.Stream)
+
+	account := &Account{ID: 9, Name: "acc"}
+	PublishLiveAccountSelected(c.Request.Context(), account)
+	selected := receiveLiveEvent(t, events)
+	require.Equal(t, LiveEventAccountSelected, selected.Type)
+	require.Equal(t, int64(9), selected.AccountID)
+
+	PublishLiveFailover(c.Request.Context(), account, http.StatusTooManyRequests)
+	require.Equal(t, http.StatusTooManyRequests, receiveLiveEvent(t, events).StatusCode)
+
+	publishLiveCompleted(c.Request.Context(), account, "claude-3", 10, 20, false)
+	completed := receiveLiveEvent(t, events)
+	require.Equal(t, LiveEventRequestCompleted, completed.Type)
+	require.Equal(t, 20, completed.OutputTokens)
+	require.Equal(t, "acc", completed.AccountName)
+
+	// 成功的请求 Finish 不再发布事件
+	svc.Finish(c)
+	c.Status(http.StatusBadGateway)
+	svc.Finish(c)
+	failed := receiveLiveEvent(t, events)
+	require.Equal(t, LiveEventRequestFailed, failed.Type)
+	require.Equal(t, http.StatusBadGateway, failed.StatusCode)
+
+	// 本副本的事件同时广播到 Redis
+	require.Equal(t, LiveEventRequestStarted, receiveLiveEvent(t, stub.published).Type)
+}
+
+func TestLiveEventService_IgnoresOwnBroadcast(t *testing.T) {
+	stub := &liveEventPubSubStub{published: make(chan *LiveEvent, 16), incoming: make(chan *LiveEvent)}
+	svc := NewLiveEventService(stub)
+	svc.Start()
+	defer svc.Stop()
+
+	events, unsubscribe := svc.Subscribe()
+	defer unsubscribe()
+
+	stub.incoming <- &LiveEvent{Type: LiveEventRequestStarted, InstanceID: svc.instanceID}
+	stub.incoming <- &LiveEvent{Type: LiveEventRequestCompleted, InstanceID: "other"}
+
+	event := receiveLiveEvent(t, events)
+	require.Equal(t, LiveEventRequestCompleted, event.Type)
+	require.Equal(t, "other", event.InstanceID)
+}
+
+func TestPublishLive_NoRequestIsNoop(t *testing.T) {
+	PublishLiveAccountSelected(context.Background(), &Account{ID: 1})
+	PublishLiveFailover(context.Background(), &Account{ID: 1}, http.StatusBadGateway)
+	publishLiveCompleted(context.Background(), nil, "", 0, 0, false)
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	accountConcurrencyDesc = prometheus.NewDesc(
+		"sub2api_account_concurrency",
+		"Requests currently holding a concurrency slot on the account.",
+		[]string{"account_id", "platform"}, nil,
+	)
+	accountWaitQueueDesc = prometheus.NewDesc(
+		"sub2api_account_wait_queue_depth",
+		"Requests on this instance waiting for a concurrency slot on the account.",
+		[]string{"account_id", "platform"}, nil,
+	)
+	accountsRateLimitedDesc = prometheus.NewDesc(
+		"sub2api_accounts_rate_limited",
+		"Active accounts currently rate limited by the upstream.",
+		[]string{"platform"}, nil,
+	)
+	accountsOverloadedDesc = prometheus.NewDesc(
+		"sub2api_accounts_overloaded",
+		"Active accounts currently marked overloaded.",
+		[]string{"platform"}, nil,
+	)
+)
+
+// AccountMetricsCollector 在抓取时从数据库与 ConcurrencyService 采集账号状态指标
+type AccountMetricsCollector struct {
+	accountRepo        AccountRepository
+	concurrencyService *ConcurrencyService
+}
+
+// NewAccountMetricsCollector 创建账号指标采集器
+func NewAccountMetricsCollector(accountRepo AccountRepository, concurrencyService *ConcurrencyService) *AccountMetricsCollector {
+	return &AccountMetricsCollector{
+		accountRepo:        accountRepo,
+		concurrencyService: concurrencyService,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *AccountMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- accountConcurrencyDesc
+	ch <- accountWaitQueueDesc
+	ch <- accountsRateLimitedDesc
+	ch <- accountsOverloadedDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *AccountMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	accounts, err := c.accountRepo.ListActive(ctx)
+	if err != nil {
+		logger.Component("metrics").Warn("list active accounts failed", logger.Err(err))
+		return
+	}
+
+	ids := make([]int64, 0, len(accounts))
+	for i := range accounts {
+		ids = append(ids, accounts[i].ID)
+	}
+	concurrency, _ := c.concurrencyService.GetAccountConcurrencyBatch(ctx, ids)
+	waiting := c.concurrencyService.GetAccountWaitingCounts()
+
+	rateLimited := make(map[string]int)
+	overloaded := make(map[string]int)
+	for i := range accounts {
+		account := &accounts[i]
+		accountID := strconv.FormatInt(account.ID, 10)
+
+		ch <- prometheus.MustNewConstMetric(accountConcurrencyDesc, prometheus.GaugeValue, float64(concurrency[account.ID]), accountID, account.Platform)
+		ch <- prometheus.MustNewConstMetric(accountWaitQueueDesc, prometheus.GaugeValue, float64(waiting[account.ID]), accountID, account.Platform)
+
+		// 保证每个平台都有序列（值可能为 0）
+		rateLimited[account.Platform] += 0
+		overloaded[account.Platform] += 0
+		if account.IsRateLimited() {
+			rateLimited[account.Platform]++
+		}
+		if account.IsOverloaded() {
+			overloaded[account.Platform]++
+		}
+	}
+
+	for platform, n := range rateLimited {
+		ch <- prometheus.MustNewConstMetric(accountsRateLimitedDesc, prometheus.GaugeValue, float64(n), platform)
+	}
+	for platform, n := range overloaded {
+		ch <- prometheus.MustNewConstMetric(accountsOverloadedDesc, prometheus.GaugeValue, float64(n), platform)
+	}
+}
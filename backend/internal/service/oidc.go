@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/oidc"
+)
+
+var (
+	ErrOIDCDisabled           = infraerrors.BadRequest("OIDC_DISABLED", "single sign-on is not enabled")
+	ErrOIDCStateInvalid       = infraerrors.BadRequest("OIDC_STATE_INVALID", "sign-in session expired or is invalid, please try again")
+	ErrOIDCExchangeFailed     = infraerrors.Unauthorized("OIDC_EXCHANGE_FAILED", "single sign-on failed")
+	ErrOIDCEmailMissing       = infraerrors.Forbidden("OIDC_EMAIL_MISSING", "identity provider did not return an email address")
+	ErrOIDCEmailNotVerified   = infraerrors.Forbidden("OIDC_EMAIL_NOT_VERIFIED", "email is not verified by the identity provider")
+	ErrOIDCUserNotProvisioned = infraerrors.Forbidden("OIDC_USER_NOT_PROVISIONED", "no account exists for this identity")
+	ErrOIDCIdentityConflict   = infraerrors.Conflict("OIDC_IDENTITY_CONFLICT", "account is already linked to another identity")
+	ErrUserIdentityNotFound   = infraerrors.NotFound("USER_IDENTITY_NOT_FOUND", "user identity not found")
+)
+
+// OIDCAuthStateTTL 授权请求的有效期
+const OIDCAuthStateTTL = 10 * time.Minute
+
+// OIDCConfig 单点登录配置，保存在系统设置中
+type OIDCConfig struct {
+	Enabled      bool
+	ProviderName string // 登录按钮显示的名称
+	Issuer       string
+	ClientID     string
+	ClientSecret string // 为空时作为公共客户端，仅依赖 PKCE
+	RedirectUrl  string // 前端回调地址，需在 IdP 中登记
+	Scopes       string
+
+	// 首次登录时自动创建账号
+	AutoProvision bool
+	// 包含用户组的 claim 名称
+	GroupsClaim string
+	// 属于这些 IdP 组的用户为管理员；为空时不同步角色
+	AdminGroups []string
+	// IdP 组到分组 ID 的映射，用于同步 AllowedGroups；为空时不同步
+	GroupMapping map[string][]int64
+}
+
+// Ready 是否已启用且配置完整
+func (c *OIDCConfig) Ready() bool {
+	return c != nil && c.Enabled && c.Issuer != "" && c.ClientID != "" && c.RedirectUrl != ""
+}
+
+// applyGroups 根据 IdP 组同步用户角色与可用分组，返回是否有变更。
+// 每次登录都会同步，因此 IdP 中移除的组会在下次登录时生效。
+func (c *OIDCConfig) applyGroups(user *User, groups []string) bool {
+	changed := false
+
+	if len(c.AdminGroups) > 0 {
+		role := RoleUser
+		for _, g := range groups {
+			if slices.Contains(c.AdminGroups, g) {
+				role = RoleAdmin
+				break
+			}
+		}
+		// 受限管理角色由管理员在后台分配，不属于管理员组时保持不变
+		if role == RoleUser && user.Role != RoleAdmin {
+			role = user.Role
+		}
+		if user.Role != role {
+			user.Role = role
+			changed = true
+		}
+	}
+
+	if len(c.GroupMapping) > 0 {
+		allowed := []int64{}
+		for _, g := range groups {
+			allowed = append(allowed, c.GroupMapping[g]...)
+		}
+		slices.Sort(allowed)
+		allowed = slices.Compact(allowed)
+		if !slices.Equal(user.AllowedGroups, allowed) && (len(user.AllowedGroups) > 0 || len(allowed) > 0) {
+			user.AllowedGroups = allowed
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func parseOidcConfig(settings map[string]string) *OIDCConfig {
+	cfg := &OIDCConfig{
+		Enabled:       settings[SettingKeyOidcEnabled] == "true",
+		ProviderName:  settings[SettingKeyOidcProviderName],
+		Issuer:        strings.TrimRight(settings[SettingKeyOidcIssuer], "/"),
+		ClientID:      settings[SettingKeyOidcClientId],
+		ClientSecret:  settings[SettingKeyOidcClientSecret],
+		RedirectUrl:   settings[SettingKeyOidcRedirectUrl],
+		Scopes:        settings[SettingKeyOidcScopes],
+		AutoProvision: settings[SettingKeyOidcAutoProvision] == "true",
+		GroupsClaim:   settings[SettingKeyOidcGroupsClaim],
+		AdminGroups:   []string{},
+		GroupMapping:  map[string][]int64{},
+	}
+	if cfg.ProviderName == "" {
+		cfg.ProviderName = "SSO"
+	}
+	if cfg.Scopes == "" {
+		cfg.Scopes = oidc.DefaultScopes
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	for _, g := range strings.Split(settings[SettingKeyOidcAdminGroups], ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			cfg.AdminGroups = append(cfg.AdminGroups, g)
+		}
+	}
+	if raw := settings[SettingKeyOidcGroupMapping]; raw != "" {
+		_ = json.Unmarshal([]byte(raw), &cfg.GroupMapping)
+	}
+	return cfg
+}
+
+// OIDCAuthState 授权请求状态，回调时按 state 取回
+type OIDCAuthState struct {
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"code_verifier"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// OIDCIdentity 从 ID Token 中解析出的身份信息
+type OIDCIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+// UserIdentity 本地账号与外部身份的绑定关系
+type UserIdentity struct {
+	ID        int64
+	UserID    int64
+	Issuer    string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+	LastLogin *time.Time
+}
+
+// OIDCClient 与 IdP 交互的 HTTP 客户端
+type OIDCClient interface {
+	Discover(ctx context.Context, issuer string) (*oidc.ProviderMetadata, error)
+	FetchKeySet(ctx context.Context, jwksURI string) (*oidc.KeySet, error)
+	ExchangeCode(ctx context.Context, tokenEndpoint, clientID, clientSecret, code, codeVerifier, redirectURI string) (*oidc.TokenResponse, error)
+}
+
+// OIDCStateCache 保存授权请求状态，每个 state 只能使用一次
+type OIDCStateCache interface {
+	SetOIDCState(ctx context.Context, state string, data *OIDCAuthState, ttl time.Duration) error
+	// TakeOIDCState 读取并删除状态，不存在时返回 nil
+	TakeOIDCState(ctx context.Context, state string) (*OIDCAuthState, error)
+}
+
+type UserIdentityRepository interface {
+	GetByIssuerSubject(ctx context.Context, issuer, subject string) (*UserIdentity, error)
+	// Create 同一账号在同一 IdP 下只能绑定一个身份，冲突时返回 ErrOIDCIdentityConflict
+	Create(ctx context.Context, identity *UserIdentity) error
+	TouchLastLogin(ctx context.Context, id int64, at time.Time) error
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/oauth"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/oidc"
+)
+
+const (
+	// 服务发现结果与 JWKS 的缓存时间
+	oidcProviderCacheTTL = time.Hour
+	// 遇到未知 kid 时刷新 JWKS 的最小间隔，防止被用来放大请求
+	oidcKeyRefreshInterval = time.Minute
+)
+
+// oidcProvider 缓存的 IdP 元数据与签名密钥
+type oidcProvider struct {
+	issuer        string
+	metadata      *oidc.ProviderMetadata
+	keys          *oidc.KeySet
+	fetchedAt     time.Time
+	keysFetchedAt time.Time
+}
+
+// OIDCService 处理 OIDC 授权码 + PKCE 流程
+type OIDCService struct {
+	settingService *SettingService
+	client         OIDCClient
+	stateCache     OIDCStateCache
+	identityRepo   UserIdentityRepository
+
+	mu       sync.Mutex
+	provider *oidcProvider
+}
+
+// NewOIDCService 创建 OIDC 服务
+func NewOIDCService(settingService *SettingService, client OIDCClient, stateCache OIDCStateCache, identityRepo UserIdentityRepository) *OIDCService {
+	return &OIDCService{
+		settingService: settingService,
+		client:         client,
+		stateCache:     stateCache,
+		identityRepo:   identityRepo,
+	}
+}
+
+// config 返回已启用的配置
+func (s *OIDCService) config(ctx context.Context) (*OIDCConfig, error) {
+	if s == nil || s.settingService == nil {
+		return nil, ErrOIDCDisabled
+	}
+	cfg, err := s.settingService.GetOIDCConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Ready() {
+		return nil, ErrOIDCDisabled
+	}
+	return cfg, nil
+}
+
+// getProvider 返回 IdP 元数据，issuer 变更或缓存过期时重新发现
+func (s *OIDCService) getProvider(ctx context.Context, issuer string) (*oidcProvider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.provider; p != nil && p.issuer == issuer && time.Since(p.fetchedAt) < oidcProviderCacheTTL {
+		return p, nil
+	}
+
+	metadata, err := s.client.Discover(ctx, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover oidc provider: %w", err)
+	}
+	// 防止元数据被替换为其他 issuer
+	if strings.TrimRight(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc issuer mismatch: %s", metadata.Issuer)
+	}
+	keys, err := s.client.FetchKeySet(ctx, metadata.JWKSURI)
+	if err != nil {
+		return nil, fmt.Errorf("fetch oidc keys: %w", err)
+	}
+
+	now := time.Now()
+	s.provider = &oidcProvider{issuer: issuer, metadata: metadata, keys: keys, fetchedAt: now, keysFetchedAt: now}
+	return s.provider, nil
+}
+
+// refreshKeys 在 IdP 轮换密钥后重新获取 JWKS
+func (s *OIDCService) refreshKeys(ctx context.Context, p *oidcProvider) (*oidc.KeySet, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(p.keysFetchedAt) < oidcKeyRefreshInterval {
+		return p.keys, false
+	}
+	keys, err := s.client.FetchKeySet(ctx, p.metadata.JWKSURI)
+	if err != nil {
+		logger.FromContext(ctx).Warn("refresh oidc keys failed", logger.Err(err))
+		return p.keys, false
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	return keys, true
+}
+
+// AuthorizationURL 生成跳转到 IdP 的授权地址
+func (s *OIDCService) AuthorizationURL(ctx context.Context) (string, error) {
+	cfg, err := s.config(ctx)
+	if err != nil {
+		return "", err
+	}
+	provider, err := s.getProvider(ctx, cfg.Issuer)
+	if err != nil {
+		logger.FromContext(ctx).Error("load oidc provider failed", logger.Err(err))
+		return "", ErrServiceUnavailable
+	}
+
+	state, err := oauth.GenerateState()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oauth.GenerateState()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oauth.GenerateCodeVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	data := &OIDCAuthState{Nonce: nonce, CodeVerifier: verifier, CreatedAt: time.Now()}
+	if err := s.stateCache.SetOIDCState(ctx, state, data, OIDCAuthStateTTL); err != nil {
+		return "", fmt.Errorf("save oidc state: %w", err)
+	}
+
+	return oidc.BuildAuthorizationURL(provider.metadata.AuthorizationEndpoint, oidc.AuthorizationRequest{
+		ClientID:      cfg.ClientID,
+		RedirectURI:   cfg.RedirectUrl,
+		Scope:         cfg.Scopes,
+		State:         state,
+		Nonce:         nonce,
+		CodeChallenge: oauth.GenerateCodeChallenge(verifier),
+	})
+}
+
+// Authenticate 使用回调中的 code 换取并校验 ID Token，返回身份信息与当前配置
+func (s *OIDCService) Authenticate(ctx context.Context, code, state string) (*OIDCIdentity, *OIDCConfig, error) {
+	cfg, err := s.config(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if code == "" || state == "" {
+		return nil, nil, ErrOIDCStateInvalid
+	}
+	authState, err := s.stateCache.TakeOIDCState(ctx, state)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load oidc state: %w", err)
+	}
+	if authState == nil {
+		return nil, nil, ErrOIDCStateInvalid
+	}
+
+	provider, err := s.getProvider(ctx, cfg.Issuer)
+	if err != nil {
+		logger.FromContext(ctx).Error("load oidc provider failed", logger.Err(err))
+		return nil, nil, ErrServiceUnavailable
+	}
+
+	tokenResp, err := s.client.ExchangeCode(ctx, provider.metadata.TokenEndpoint, cfg.ClientID, cfg.ClientSecret, code, authState.CodeVerifier, cfg.RedirectUrl)
+	if err != nil {
+		logger.FromContext(ctx).Warn("oidc code exchange failed", logger.Err(err))
+		return nil, nil, ErrOIDCExchangeFailed
+	}
+	if tokenResp.IDToken == "" {
+		logger.FromContext(ctx).Warn("oidc token response has no id_token")
+		return nil, nil, ErrOIDCExchangeFailed
+	}
+
+	opts := oidc.VerifyOptions{Issuer: provider.metadata.Issuer, ClientID: cfg.ClientID, Nonce: authState.Nonce}
+	claims, err := oidc.VerifyIDToken(tokenResp.IDToken, provider.keys, opts)
+	if errors.Is(err, oidc.ErrUnknownKey) {
+		if keys, refreshed := s.refreshKeys(ctx, provider); refreshed {
+			claims, err = oidc.VerifyIDToken(tokenResp.IDToken, keys, opts)
+		}
+	}
+	if err != nil {
+		logger.FromContext(ctx).Warn("oidc id token verification failed", logger.Err(err))
+		return nil, nil, ErrOIDCExchangeFailed
+	}
+
+	identity := &OIDCIdentity{
+		Issuer:        cfg.Issuer,
+		Subject:       oidc.StringClaim(claims, "sub"),
+		Email:         strings.ToLower(strings.TrimSpace(oidc.StringClaim(claims, "email"))),
+		EmailVerified: oidc.BoolClaim(claims, "email_verified"),
+		Name:          oidc.StringClaim(claims, "name"),
+		Groups:        oidc.StringsClaim(claims, cfg.GroupsClaim),
+	}
+	if identity.Name == "" {
+		identity.Name = oidc.StringClaim(claims, "preferred_username")
+	}
+	return identity, cfg, nil
+}
+
+// LinkedUserID 返回与外部身份绑定的用户 ID，未绑定时返回 0
+func (s *OIDCService) LinkedUserID(ctx context.Context, identity *OIDCIdentity) (int64, error) {
+	linked, err := s.identityRepo.GetByIssuerSubject(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		if errors.Is(err, ErrUserIdentityNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if err := s.identityRepo.TouchLastLogin(ctx, linked.ID, time.Now()); err != nil {
+		logger.FromContext(ctx).Warn("update identity last login failed", logger.Err(err))
+	}
+	return linked.UserID, nil
+}
+
+// Link 将外部身份绑定到本地账号
+func (s *OIDCService) Link(ctx context.Context, userID int64, identity *OIDCIdentity) error {
+	now := time.Now()
+	return s.identityRepo.Create(ctx, &UserIdentity{
+		UserID:    userID,
+		Issuer:    identity.Issuer,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		LastLogin: &now,
+	})
+}
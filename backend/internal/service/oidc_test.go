@@ -0,0 +1,289 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/oauth"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const testIssuer = "https://idp.example.com"
+
+// mockIdP 模拟 IdP：签发 ID Token 并校验 PKCE
+type mockIdP struct {
+	key *rsa.PrivateKey
+
+	challenge string
+	claims    jwt.MapClaims
+	exchanges int
+}
+
+func (p *mockIdP) Discover(ctx context.Context, issuer string) (*oidc.ProviderMetadata, error) {
+	return &oidc.ProviderMetadata{
+		Issuer:                testIssuer,
+		AuthorizationEndpoint: testIssuer + "/authorize",
+		TokenEndpoint:         testIssuer + "/token",
+		JWKSURI:               testIssuer + "/jwks",
+	}, nil
+}
+
+func (p *mockIdP) FetchKeySet(ctx context.Context, jwksURI string) (*oidc.KeySet, error) {
+	return &oidc.KeySet{Keys: []oidc.JSONWebKey{{
+		Kty: "RSA",
+		Kid: "k1",
+		N:   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+	}}}, nil
+}
+
+func (p *mockIdP) ExchangeCode(ctx context.Context, tokenEndpoint, clientID, clientSecret, code, codeVerifier, redirectURI string) (*oidc.TokenResponse, error) {
+	p.exchanges++
+	if code != "good-code" || oauth.GenerateCodeChallenge(codeVerifier) != p.challenge {
+		return nil, errors.New("invalid_grant")
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": testIssuer,
+		"aud": clientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Minute).Unix(),
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	raw, err := token.SignedString(p.key)
+	if err != nil {
+		return nil, err
+	}
+	return &oidc.TokenResponse{AccessToken: "at", IDToken: raw}, nil
+}
+
+type oidcStateCacheStub struct {
+	states map[string]*OIDCAuthState
+}
+
+func (s *oidcStateCacheStub) SetOIDCState(ctx context.Context, state string, data *OIDCAuthState, ttl time.Duration) error {
+	s.states[state] = data
+	return nil
+}
+
+func (s *oidcStateCacheStub) TakeOIDCState(ctx context.Context, state string) (*OIDCAuthState, error) {
+	data := s.states[state]
+	delete(s.states, state)
+	return data, nil
+}
+
+type userIdentityRepoStub struct {
+	identities []*UserIdentity
+}
+
+func (s *userIdentityRepoStub) GetByIssuerSubject(ctx context.Context, issuer, subject string) (*UserIdentity, error) {
+	for _, i := range s.identities {
+		if i.Issuer == issuer && i.Subject == subject {
+			return i, nil
+		}
+	}
+	return nil, ErrUserIdentityNotFound
+}
+
+func (s *userIdentityRepoStub) Create(ctx context.Context, identity *UserIdentity) error {
+	for _, i := range s.identities {
+		if i.Issuer == identity.Issuer && (i.Subject == identity.Subject || i.UserID == identity.UserID) {
+			return ErrOIDCIdentityConflict
+		}
+	}
+	identity.ID = int64(len(s.identities) + 1)
+	s.identities = append(s.identities, identity)
+	return nil
+}
+
+func (s *userIdentityRepoStub) TouchLastLogin(ctx context.Context, id int64, at time.Time) error {
+	return nil
+}
+
+type oidcSettingRepoStub struct {
+	SettingRepository
+	values map[string]string
+}
+
+func (s *oidcSettingRepoStub) GetMultiple(ctx context.Context, keys []string) (map[string]string, error) {
+	out := make(map[string]string, len(keys))
+	for _, k := range keys {
+		if v, ok := s.values[k]; ok {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+type oidcTestEnv struct {
+	svc        *AuthService
+	users      *authUserRepoStub
+	identities *userIdentityRepoStub
+	idp        *mockIdP
+	settings   map[string]string
+}
+
+func newOIDCTestEnv(t *testing.T) *oidcTestEnv {
+	t.Helper()
+	svc, users, _, _ := newTestAuthService(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	env := &oidcTestEnv{
+		svc:        svc,
+		users:      users,
+		identities: &userIdentityRepoStub{},
+		idp:        &mockIdP{key: key},
+		settings: map[string]string{
+			SettingKeyOidcEnabled:       "true",
+			SettingKeyOidcIssuer:        testIssuer,
+			SettingKeyOidcClientId:      "sub2api",
+			SettingKeyOidcRedirectUrl:   "https://app.example.com/auth/oidc/callback",
+			SettingKeyOidcAutoProvision: "true",
+		},
+	}
+	settingService := NewSettingService(&oidcSettingRepoStub{values: env.settings}, nil)
+	svc.oidcService = NewOIDCService(settingService, env.idp, &oidcStateCacheStub{states: map[string]*OIDCAuthState{}}, env.identities)
+	return env
+}
+
+// authorize 模拟浏览器跳转到 IdP，返回 state 并让 IdP 在 ID Token 中回传 nonce
+func (e *oidcTestEnv) authorize(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	authURL, err := e.svc.OIDCAuthorizationURL(context.Background())
+	require.NoError(t, err)
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	q := u.Query()
+	require.Equal(t, "sub2api", q.Get("client_id"))
+	require.Equal(t, "S256", q.Get("code_challenge_method"))
+
+	e.idp.challenge = q.Get("code_challenge")
+	e.idp.claims = jwt.MapClaims{"nonce": q.Get("nonce")}
+	for k, v := range claims {
+		e.idp.claims[k] = v
+	}
+	return q.Get("state")
+}
+
+func TestAuthService_LoginWithOIDCProvisionsAndSyncsGroups(t *testing.T) {
+	ctx := context.Background()
+	env := newOIDCTestEnv(t)
+	env.settings[SettingKeyOidcAdminGroups] = "ops-admin"
+	env.settings[SettingKeyOidcGroupMapping] = `{"eng":[3,1],"research":[1]}`
+
+	state := env.authorize(t, jwt.MapClaims{
+		"sub":   "idp-42",
+		"email": "New@Example.com",
+		"name":  "New User",
+		"groups": []any{
+			"eng", "research", "ops-admin",
+		},
+	})
+	result, err := env.svc.LoginWithOIDC(ctx, "good-code", state)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Token)
+	require.Equal(t, "new@example.com", result.User.Email)
+	require.Equal(t, "New User", result.User.Username)
+	require.Equal(t, RoleAdmin, result.User.Role)
+	require.Equal(t, []int64{1, 3}, result.User.AllowedGroups)
+	userID := result.User.ID
+
+	// state 只能使用一次
+	_, err = env.svc.LoginWithOIDC(ctx, "good-code", state)
+	require.ErrorIs(t, err, ErrOIDCStateInvalid)
+
+	// 再次登录按 sub 找到同一账号，并按最新的组同步
+	state = env.authorize(t, jwt.MapClaims{"sub": "idp-42", "email": "renamed@example.com", "groups": "research"})
+	result, err = env.svc.LoginWithOIDC(ctx, "good-code", state)
+	require.NoError(t, err)
+	require.Equal(t, userID, result.User.ID)
+	require.Equal(t, RoleUser, env.users.users[userID].Role)
+	require.Equal(t, []int64{1}, env.users.users[userID].AllowedGroups)
+	require.Len(t, env.users.users, 2)
+}
+
+func TestAuthService_LoginWithOIDCLinksVerifiedEmailOnly(t *testing.T) {
+	ctx := context.Background()
+	env := newOIDCTestEnv(t)
+
+	state := env.authorize(t, jwt.MapClaims{"sub": "idp-1", "email": "a@example.com"})
+	_, err := env.svc.LoginWithOIDC(ctx, "good-code", state)
+	require.ErrorIs(t, err, ErrOIDCEmailNotVerified)
+	require.Empty(t, env.identities.identities)
+
+	state = env.authorize(t, jwt.MapClaims{"sub": "idp-1", "email": "a@example.com", "email_verified": true})
+	result, err := env.svc.LoginWithOIDC(ctx, "good-code", state)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.User.ID)
+	// 未配置映射时不修改角色与分组
+	require.Equal(t, RoleUser, result.User.Role)
+	require.Empty(t, result.User.AllowedGroups)
+
+	// 已绑定的账号不能再绑定同一 IdP 的其他身份
+	state = env.authorize(t, jwt.MapClaims{"sub": "idp-2", "email": "a@example.com", "email_verified": true})
+	_, err = env.svc.LoginWithOIDC(ctx, "good-code", state)
+	require.ErrorIs(t, err, ErrOIDCIdentityConflict)
+}
+
+func TestAuthService_LoginWithOIDCRejects(t *testing.T) {
+	ctx := context.Background()
+	env := newOIDCTestEnv(t)
+
+	// 关闭自动创建
+	env.settings[SettingKeyOidcAutoProvision] = "false"
+	state := env.authorize(t, jwt.MapClaims{"sub": "idp-9", "email": "nobody@example.com", "email_verified": true})
+	_, err := env.svc.LoginWithOIDC(ctx, "good-code", state)
+	require.ErrorIs(t, err, ErrOIDCUserNotProvisioned)
+
+	// nonce 不匹配
+	state = env.authorize(t, jwt.MapClaims{"sub": "idp-1", "email": "a@example.com", "email_verified": true})
+	env.idp.claims["nonce"] = "replayed"
+	_, err = env.svc.LoginWithOIDC(ctx, "good-code", state)
+	require.ErrorIs(t, err, ErrOIDCExchangeFailed)
+
+	// code 无效
+	state = env.authorize(t, jwt.MapClaims{"sub": "idp-1"})
+	_, err = env.svc.LoginWithOIDC(ctx, "bad-code", state)
+	require.ErrorIs(t, err, ErrOIDCExchangeFailed)
+
+	// 未知 state 不会请求 IdP
+	exchanges := env.idp.exchanges
+	_, err = env.svc.LoginWithOIDC(ctx, "good-code", "unknown")
+	require.ErrorIs(t, err, ErrOIDCStateInvalid)
+	require.Equal(t, exchanges, env.idp.exchanges)
+
+	env.settings[SettingKeyOidcEnabled] = "false"
+	_, err = env.svc.OIDCAuthorizationURL(ctx)
+	require.ErrorIs(t, err, ErrOIDCDisabled)
+}
+
+func TestAuthService_LoginWithOIDCRequiresTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	env := newOIDCTestEnv(t)
+	tf, _, _ := newTestTwoFactorAuthService(t)
+	env.svc.twoFactorService = tf.twoFactorService
+	env.users.users[1].Role = RoleAdmin
+
+	state := env.authorize(t, jwt.MapClaims{"sub": "idp-1", "email": "a@example.com", "email_verified": true})
+	result, err := env.svc.LoginWithOIDC(ctx, "good-code", state)
+	require.NoError(t, err)
+	require.True(t, result.RequiresTwoFactor())
+	require.True(t, result.EnrollmentRequired)
+	require.Empty(t, result.Token)
+}
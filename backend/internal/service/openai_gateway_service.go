@@ -17,7 +17,11 @@ import (
 	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/metrics"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tracing"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -69,6 +73,7 @@ type OpenAIForwardResult struct {
 	Stream       bool
 	Duration     time.Duration
 	FirstTokenMs *int
+	CacheHit     bool // served from the response cache
 }
 
 // OpenAIGatewayService handles OpenAI API gateway operations
@@ -83,6 +88,11 @@ type OpenAIGatewayService struct {
 	rateLimitService    *RateLimitService
 	billingCacheService *BillingCacheService
 	httpUpstream        HTTPUpstream
+	scheduler           *AccountScheduler
+	apiKeyLimitService  *ApiKeyLimitService
+	balanceTxRepo       BalanceTransactionRepository
+	webhookService      *WebhookService
+	notificationService *UserNotificationService
 }
 
 // NewOpenAIGatewayService creates a new OpenAIGatewayService
@@ -97,6 +107,11 @@ func NewOpenAIGatewayService(
 	rateLimitService *RateLimitService,
 	billingCacheService *BillingCacheService,
 	httpUpstream HTTPUpstream,
+	scheduler *AccountScheduler,
+	apiKeyLimitService *ApiKeyLimitService,
+	balanceTxRepo BalanceTransactionRepository,
+	webhookService *WebhookService,
+	notificationService *UserNotificationService,
 ) *OpenAIGatewayService {
 	return &OpenAIGatewayService{
 		accountRepo:         accountRepo,
@@ -109,17 +124,23 @@ func NewOpenAIGatewayService(
 		rateLimitService:    rateLimitService,
 		billingCacheService: billingCacheService,
 		httpUpstream:        httpUpstream,
+		scheduler:           scheduler,
+		apiKeyLimitService:  apiKeyLimitService,
+		balanceTxRepo:       balanceTxRepo,
+		webhookService:      webhookService,
+		notificationService: notificationService,
 	}
 }
 
-// GenerateSessionHash generates session hash from header (OpenAI uses session_id header)
-func (s *OpenAIGatewayService) GenerateSessionHash(c *gin.Context) string {
+// GenerateSessionHash generates session hash from header (OpenAI uses session_id header),
+// scoped to the API key
+func (s *OpenAIGatewayService) GenerateSessionHash(c *gin.Context, apiKeyID int64) string {
 	sessionID := c.GetHeader("session_id")
 	if sessionID == "" {
 		return ""
 	}
 	hash := sha256.Sum256([]byte(sessionID))
-	return hex.EncodeToString(hash[:])
+	return scopeSessionHash(apiKeyID, hex.EncodeToString(hash[:]))
 }
 
 // SelectAccount selects an OpenAI account with sticky session support
@@ -134,15 +155,22 @@ func (s *OpenAIGatewayService) SelectAccountForModel(ctx context.Context, groupI
 
 // SelectAccountForModelWithExclusions selects an account supporting the requested model while excluding specified accounts.
 func (s *OpenAIGatewayService) SelectAccountForModelWithExclusions(ctx context.Context, groupID *int64, sessionHash string, requestedModel string, excludedIDs map[int64]struct{}) (*Account, error) {
-	// 1. Check sticky session
+	ctx, span := tracing.Start(ctx, "OpenAIGatewayService.SelectAccountForModelWithExclusions",
+		attribute.String("model", requestedModel),
+		attribute.Int("excluded_accounts", len(excludedIDs)),
+	)
+	defer span.End()
+
+	// 1. Check sticky session (scoped by platform, group and API key)
+	sessionKey := stickySessionKey(PlatformOpenAI, groupID, sessionHash)
 	if sessionHash != "" {
-		accountID, err := s.cache.GetSessionAccountID(ctx, "openai:"+sessionHash)
+		accountID, err := s.cache.GetSessionAccountID(ctx, sessionKey)
 		if err == nil && accountID > 0 {
 			if _, excluded := excludedIDs[accountID]; !excluded {
 				account, err := s.accountRepo.GetByID(ctx, accountID)
-				if err == nil && account.IsSchedulable() && account.IsOpenAI() && (requestedModel == "" || account.IsModelSupported(requestedModel)) {
+				if err == nil && isStickyAccountEligible(account, PlatformOpenAI, groupID, requestedModel) {
 					// Refresh sticky session TTL
-					_ = s.cache.RefreshSessionTTL(ctx, "openai:"+sessionHash, openaiStickySessionTTL)
+					_ = s.cache.RefreshSessionTTL(ctx, sessionKey, openaiStickySessionTTL)
 					return account, nil
 				}
 			}
@@ -161,8 +189,8 @@ func (s *OpenAIGatewayService) SelectAccountForModelWithExclusions(ctx context.C
 		return nil, fmt.Errorf("query accounts failed: %w", err)
 	}
 
-	// 3. Select by priority + LRU
-	var selected *Account
+	// 3. Filter candidates and select by group scheduling strategy
+	candidates := make([]*Account, 0, len(accounts))
 	for i := range accounts {
 		acc := &accounts[i]
 		if _, excluded := excludedIDs[acc.ID]; excluded {
@@ -172,29 +200,9 @@ func (s *OpenAIGatewayService) SelectAccountForModelWithExclusions(ctx context.C
 		if requestedModel != "" && !acc.IsModelSupported(requestedModel) {
 			continue
 		}
-		if selected == nil {
-			selected = acc
-			continue
-		}
-		// Lower priority value means higher priority
-		if acc.Priority < selected.Priority {
-			selected = acc
-		} else if acc.Priority == selected.Priority {
-			switch {
-			case acc.LastUsedAt == nil && selected.LastUsedAt != nil:
-				selected = acc
-			case acc.LastUsedAt != nil && selected.LastUsedAt == nil:
-				// keep selected (never used is preferred)
-			case acc.LastUsedAt == nil && selected.LastUsedAt == nil:
-				// keep selected (both never used)
-			default:
-				// Same priority, select least recently used
-				if acc.LastUsedAt.Before(*selected.LastUsedAt) {
-					selected = acc
-				}
-			}
-		}
+		candidates = append(candidates, acc)
 	}
+	selected := s.scheduler.Select(ctx, groupID, PlatformOpenAI, candidates, nil)
 
 	if selected == nil {
 		if requestedModel != "" {
@@ -205,7 +213,7 @@ func (s *OpenAIGatewayService) SelectAccountForModelWithExclusions(ctx context.C
 
 	// 4. Set sticky session
 	if sessionHash != "" {
-		_ = s.cache.SetSessionAccountID(ctx, "openai:"+sessionHash, selected.ID, openaiStickySessionTTL)
+		_ = s.cache.SetSessionAccountID(ctx, sessionKey, selected.ID, openaiStickySessionTTL)
 	}
 
 	return selected, nil
@@ -304,7 +312,7 @@ func (s *OpenAIGatewayService) Forward(ctx context.Context, c *gin.Context, acco
 	}
 
 	// Send request
-	resp, err := s.httpUpstream.Do(upstreamReq, proxyURL)
+	resp, err := doUpstreamAttempt(ctx, s.httpUpstream, upstreamReq, proxyURL, account, 1)
 	if err != nil {
 		return nil, fmt.Errorf("upstream request failed: %w", err)
 	}
@@ -673,6 +681,7 @@ type OpenAIRecordUsageInput struct {
 	User         *User
 	Account      *Account
 	Subscription *UserSubscription
+	BalanceHold  *BalanceHold // optional, released once the actual cost is settled
 }
 
 // RecordUsage records usage and deducts balance
@@ -683,6 +692,15 @@ func (s *OpenAIGatewayService) RecordUsage(ctx context.Context, input *OpenAIRec
 	account := input.Account
 	subscription := input.Subscription
 
+	ctx, span := tracing.Start(ctx, "OpenAIGatewayService.RecordUsage",
+		attribute.Int64("account.id", account.ID),
+		attribute.String("upstream.request_id", result.RequestID),
+	)
+	defer span.End()
+
+	// Release the pre-authorization hold once the actual cost is settled
+	defer input.BalanceHold.Release()
+
 	// 计算实际的新输入token（减去缓存读取的token）
 	// 因为 input_tokens 包含了 cache_read_tokens，而缓存读取的token不应按输入价格计费
 	actualInputTokens := result.Usage.InputTokens - result.Usage.CacheReadInputTokens
@@ -709,6 +727,11 @@ func (s *OpenAIGatewayService) RecordUsage(ctx context.Context, input *OpenAIRec
 		cost = &CostBreakdown{ActualCost: 0}
 	}
 
+	// Cache hits are billed with the group's response cache multiplier
+	if result.CacheHit {
+		multiplier = applyResponseCacheMultiplier(cost, apiKey.Group, multiplier)
+	}
+
 	// Determine billing type
 	isSubscriptionBilling := subscription != nil && apiKey.Group != nil && apiKey.Group.IsSubscriptionType()
 	billingType := BillingTypeBalance
@@ -723,6 +746,7 @@ func (s *OpenAIGatewayService) RecordUsage(ctx context.Context, input *OpenAIRec
 		ApiKeyID:            apiKey.ID,
 		AccountID:           account.ID,
 		RequestID:           result.RequestID,
+		TraceID:             tracing.TraceID(ctx),
 		Model:               result.Model,
 		InputTokens:         actualInputTokens,
 		OutputTokens:        result.Usage.OutputTokens,
@@ -737,6 +761,7 @@ func (s *OpenAIGatewayService) RecordUsage(ctx context.Context, input *OpenAIRec
 		RateMultiplier:      multiplier,
 		BillingType:         billingType,
 		Stream:              result.Stream,
+		CacheHit:            result.CacheHit,
 		DurationMs:          &durationMs,
 		FirstTokenMs:        result.FirstTokenMs,
 		CreatedAt:           time.Now(),
@@ -748,32 +773,70 @@ func (s *OpenAIGatewayService) RecordUsage(ctx context.Context, input *OpenAIRec
 	if subscription != nil {
 		usageLog.SubscriptionID = &subscription.ID
 	}
+	usageLog.OrganizationID = apiKey.OrganizationID
+
+	// Balance billing writes the usage log, debit and ledger entry in one transaction
+	// (organization keys debit the organization wallet)
+	if !isSubscriptionBilling && cost.ActualCost > 0 {
+		debit := &BalanceTransaction{UserID: user.ID, OrganizationID: apiKey.OrganizationID, Type: BalanceTxTypeUsage, Amount: -cost.ActualCost}
+		if err := s.balanceTxRepo.CreateUsageWithDebit(ctx, usageLog, debit); err != nil {
+			logger.FromContext(ctx).Error("create usage log with balance debit failed",
+				"user_id", user.ID, "api_key_id", apiKey.ID, "cost", cost.ActualCost, logger.Err(err))
+		} else if !apiKey.IsOrganizationOwned() {
+			s.webhookService.NotifyBalanceDebited(ctx, user, debit.BalanceAfter, cost.ActualCost)
+			s.notificationService.NotifyBalanceDebited(ctx, user, debit.BalanceAfter, cost.ActualCost)
+		}
+	} else if err := s.usageLogRepo.Create(ctx, usageLog); err != nil {
+		logger.FromContext(ctx).Error("create usage log failed",
+			"user_id", user.ID, "api_key_id", apiKey.ID, "cost", cost.ActualCost, logger.Err(err))
+	}
 
-	_ = s.usageLogRepo.Create(ctx, usageLog)
+	// Export token, cost and time-to-first-token metrics
+	metrics.AddUsage(metrics.Usage{
+		Platform:            account.Platform,
+		Model:               result.Model,
+		BillingType:         usageMetricsBillingType(isSubscriptionBilling),
+		InputTokens:         actualInputTokens,
+		OutputTokens:        result.Usage.OutputTokens,
+		CacheCreationTokens: result.Usage.CacheCreationInputTokens,
+		CacheReadTokens:     result.Usage.CacheReadInputTokens,
+		CostUSD:             cost.ActualCost,
+	})
+	metrics.ObserveFirstToken(account.Platform, result.Model, result.FirstTokenMs)
+
+	// Update per-API-key TPM and spend counters (cache reads excluded, same as the Claude path)
+	s.apiKeyLimitService.RecordUsage(ctx, apiKey, int64(actualInputTokens+result.Usage.OutputTokens+result.Usage.CacheCreationInputTokens), cost.ActualCost)
 
 	// Deduct based on billing type
 	if isSubscriptionBilling {
 		if cost.TotalCost > 0 {
 			_ = s.userSubRepo.IncrementUsage(ctx, subscription.ID, cost.TotalCost)
-			go func() {
-				cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-				_ = s.billingCacheService.UpdateSubscriptionUsage(cacheCtx, user.ID, *apiKey.GroupID, cost.TotalCost)
-			}()
+			// Organization subscriptions are not cached per user
+			if subscription.OrganizationID == nil {
+				go func() {
+					cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					defer cancel()
+					_ = s.billingCacheService.UpdateSubscriptionUsage(cacheCtx, user.ID, *apiKey.GroupID, cost.TotalCost)
+				}()
+			}
 		}
 	} else {
-		if cost.ActualCost > 0 {
-			_ = s.userRepo.DeductBalance(ctx, user.ID, cost.ActualCost)
-			go func() {
-				cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-				_ = s.billingCacheService.DeductBalanceCache(cacheCtx, user.ID, cost.ActualCost)
-			}()
+		// Organization balances are not cached
+		if cost.ActualCost > 0 && !apiKey.IsOrganizationOwned() {
+			// Update the balance cache before the hold is released
+			if err := s.billingCacheService.DeductBalanceCache(ctx, user.ID, cost.ActualCost); err != nil {
+				logger.FromContext(ctx).Warn("update balance cache failed", logger.Err(err))
+			}
 		}
 	}
 
-	// Update account last used
-	_ = s.accountRepo.UpdateLastUsed(ctx, account.ID)
+	// Push the request_completed live event
+	publishLiveCompleted(ctx, account, result.Model, result.Usage.InputTokens, result.Usage.OutputTokens, result.CacheHit)
+
+	// Update account last used (no account is used for cache hits)
+	if !result.CacheHit {
+		_ = s.accountRepo.UpdateLastUsed(ctx, account.ID)
+	}
 
 	return nil
 }
@@ -0,0 +1,795 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/openai"
+	"github.com/tidwall/gjson"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// openaiCompatDefaultModel Claude 模型名未配置映射时使用的默认 OpenAI 模型
+	openaiCompatDefaultModel = "gpt-5.1-codex"
+	// openaiCompatDefaultMiniModel haiku 系列未配置映射时使用的默认 OpenAI 模型
+	openaiCompatDefaultMiniModel = "gpt-5.1-codex-mini"
+)
+
+// OpenAIMessagesCompatService 让 Claude Messages 请求（/v1/messages）由 OpenAI 平台账号处理：
+// 请求转换为 Responses API，上游 SSE 转换回 Claude 格式
+type OpenAIMessagesCompatService struct {
+	openaiGatewayService *OpenAIGatewayService
+	rateLimitService     *RateLimitService
+	httpUpstream         HTTPUpstream
+}
+
+func NewOpenAIMessagesCompatService(
+	openaiGatewayService *OpenAIGatewayService,
+	rateLimitService *RateLimitService,
+	httpUpstream HTTPUpstream,
+) *OpenAIMessagesCompatService {
+	return &OpenAIMessagesCompatService{
+		openaiGatewayService: openaiGatewayService,
+		rateLimitService:     rateLimitService,
+		httpUpstream:         httpUpstream,
+	}
+}
+
+// SelectAccountForModelWithExclusions 复用 OpenAI 网关的账号选择逻辑
+func (s *OpenAIMessagesCompatService) SelectAccountForModelWithExclusions(ctx context.Context, groupID *int64, sessionHash string, requestedModel string, excludedIDs map[int64]struct{}) (*Account, error) {
+	return s.openaiGatewayService.SelectAccountForModelWithExclusions(ctx, groupID, sessionHash, requestedModel, excludedIDs)
+}
+
+// Forward 将 Claude Messages 请求转发到 OpenAI Responses API，并以 Claude 格式写回响应
+// 上游始终使用流式请求（Codex 接口仅支持流式），非流式客户端由服务端聚合
+func (s *OpenAIMessagesCompatService) Forward(ctx context.Context, c *gin.Context, account *Account, body []byte) (*OpenAIForwardResult, error) {
+	startTime := time.Now()
+
+	var req struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("parse request: %w", err)
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		return nil, s.writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", "model is required")
+	}
+
+	originalModel := req.Model
+	mappedModel := resolveOpenAICompatModel(account, originalModel)
+	if mappedModel != originalModel {
+		logger.FromContext(ctx).Debug("model mapping applied", "model", originalModel, "mapped_model", mappedModel, "account_name", account.Name)
+	}
+
+	responsesReq, err := convertClaudeMessagesToOpenAIResponses(body, mappedModel, account.Type == AccountTypeOAuth)
+	if err != nil {
+		return nil, s.writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+	}
+
+	token, _, err := s.openaiGatewayService.GetAccessToken(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	upstreamReq, err := s.openaiGatewayService.buildUpstreamRequest(ctx, c, account, responsesReq, token, true)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyURL := ""
+	if account.ProxyID != nil && account.Proxy != nil {
+		proxyURL = account.Proxy.URL()
+	}
+
+	resp, err := doUpstreamAttempt(ctx, s.httpUpstream, upstreamReq, proxyURL, account, 1)
+	if err != nil {
+		return nil, s.writeClaudeError(c, http.StatusBadGateway, "upstream_error", "Upstream request failed: "+sanitizeUpstreamErrorMessage(err.Error()))
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		if s.openaiGatewayService.shouldFailoverUpstreamError(resp.StatusCode) {
+			s.openaiGatewayService.handleFailoverSideEffects(ctx, resp, account)
+			return nil, &UpstreamFailoverError{StatusCode: resp.StatusCode}
+		}
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+		s.rateLimitService.HandleUpstreamError(ctx, account, resp.StatusCode, resp.Header, respBody)
+		if resp.StatusCode == http.StatusBadRequest {
+			msg := gjson.GetBytes(respBody, "error.message").String()
+			if msg == "" {
+				msg = "Invalid request"
+			}
+			return nil, s.writeClaudeError(c, http.StatusBadRequest, "invalid_request_error", msg)
+		}
+		return nil, s.writeClaudeError(c, http.StatusBadGateway, "upstream_error", "Upstream request failed")
+	}
+
+	requestID := resp.Header.Get("x-request-id")
+	if requestID != "" {
+		c.Header("x-request-id", requestID)
+	}
+
+	var usage *OpenAIUsage
+	var firstTokenMs *int
+	if req.Stream {
+		streamRes, err := s.handleStreamingResponse(c, resp, startTime, originalModel)
+		if err != nil {
+			return nil, err
+		}
+		usage = streamRes.usage
+		firstTokenMs = streamRes.firstTokenMs
+	} else {
+		usage, err = s.handleNonStreamingResponse(c, resp, originalModel)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if account.Type == AccountTypeOAuth {
+		if snapshot := extractCodexUsageHeaders(resp.Header); snapshot != nil {
+			s.openaiGatewayService.updateCodexUsageSnapshot(ctx, account.ID, snapshot)
+		}
+	}
+
+	return &OpenAIForwardResult{
+		RequestID:    requestID,
+		Usage:        *usage,
+		Model:        originalModel, // 保留原始模型名用于计费和日志
+		Stream:       req.Stream,
+		Duration:     time.Since(startTime),
+		FirstTokenMs: firstTokenMs,
+	}, nil
+}
+
+func (s *OpenAIMessagesCompatService) handleStreamingResponse(c *gin.Context, resp *http.Response, startTime time.Time, originalModel string) (*openaiStreamingResult, error) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming not supported")
+	}
+
+	conv := newResponsesToClaudeConverter(originalModel)
+	var firstTokenMs *int
+	err := readResponsesSSE(resp.Body, func(data []byte) {
+		events := conv.convert(data)
+		if len(events) == 0 {
+			return
+		}
+		if firstTokenMs == nil && conv.sawContent {
+			ms := int(time.Since(startTime).Milliseconds())
+			firstTokenMs = &ms
+		}
+		for _, ev := range events {
+			writeSSE(c.Writer, ev["type"].(string), ev)
+		}
+		flusher.Flush()
+	})
+	if err != nil {
+		return &openaiStreamingResult{usage: &conv.usage, firstTokenMs: firstTokenMs}, err
+	}
+
+	for _, ev := range conv.finish() {
+		writeSSE(c.Writer, ev["type"].(string), ev)
+	}
+	flusher.Flush()
+
+	return &openaiStreamingResult{usage: &conv.usage, firstTokenMs: firstTokenMs}, nil
+}
+
+func (s *OpenAIMessagesCompatService) handleNonStreamingResponse(c *gin.Context, resp *http.Response, originalModel string) (*OpenAIUsage, error) {
+	conv := newResponsesToClaudeConverter(originalModel)
+	acc := newClaudeMessageAccumulator()
+	err := readResponsesSSE(resp.Body, func(data []byte) {
+		acc.add(conv.convert(data))
+	})
+	if err != nil {
+		return nil, s.writeClaudeError(c, http.StatusBadGateway, "upstream_error", "Failed to read upstream stream")
+	}
+	acc.add(conv.finish())
+
+	if acc.errorEvent != nil {
+		errObj, _ := acc.errorEvent["error"].(map[string]any)
+		msg, _ := errObj["message"].(string)
+		return nil, s.writeClaudeError(c, http.StatusBadGateway, "upstream_error", msg)
+	}
+
+	c.JSON(http.StatusOK, acc.message)
+	return &conv.usage, nil
+}
+
+func (s *OpenAIMessagesCompatService) writeClaudeError(c *gin.Context, status int, errType, message string) error {
+	c.JSON(status, gin.H{
+		"type":  "error",
+		"error": gin.H{"type": errType, "message": message},
+	})
+	return fmt.Errorf("%s", message)
+}
+
+// resolveOpenAICompatModel 优先使用账号模型映射；Claude 模型名未映射时回退到默认 Codex 模型
+func resolveOpenAICompatModel(account *Account, requestedModel string) string {
+	mapped := account.GetMappedModel(requestedModel)
+	if mapped != requestedModel || !strings.HasPrefix(strings.ToLower(requestedModel), "claude") {
+		return mapped
+	}
+	if strings.Contains(strings.ToLower(requestedModel), "haiku") {
+		return openaiCompatDefaultMiniModel
+	}
+	return openaiCompatDefaultModel
+}
+
+// readResponsesSSE 逐条读取 SSE data 并回调
+func readResponsesSSE(body io.Reader, onData func(data []byte)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !openaiSSEDataRe.MatchString(line) {
+			continue
+		}
+		data := openaiSSEDataRe.ReplaceAllString(line, "")
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		onData([]byte(data))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("stream read error: %w", err)
+	}
+	return nil
+}
+
+// convertClaudeMessagesToOpenAIResponses 将 Claude Messages 请求体转换为 Responses API 请求体
+// OAuth（Codex）账号要求 instructions 为 Codex 默认指令，原 system 作为 developer 消息放入 input
+func convertClaudeMessagesToOpenAIResponses(body []byte, model string, isOAuth bool) ([]byte, error) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	input, err := convertClaudeMessagesToResponsesInput(req["messages"])
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]any{
+		"model":  model,
+		"stream": true,
+	}
+
+	systemText := extractClaudeSystemText(req["system"])
+	if isOAuth {
+		out["instructions"] = openai.DefaultInstructions
+		out["store"] = false
+		if systemText != "" {
+			input = append([]any{map[string]any{
+				"type":    "message",
+				"role":    "developer",
+				"content": []any{map[string]any{"type": "input_text", "text": systemText}},
+			}}, input...)
+		}
+	} else {
+		if systemText != "" {
+			out["instructions"] = systemText
+		}
+		// Codex 接口不接受以下采样参数，仅对 Platform API 透传
+		if mt, ok := asInt(req["max_tokens"]); ok && mt > 0 {
+			out["max_output_tokens"] = mt
+		}
+		if temp, ok := req["temperature"].(float64); ok {
+			out["temperature"] = temp
+		}
+		if topP, ok := req["top_p"].(float64); ok {
+			out["top_p"] = topP
+		}
+	}
+	out["input"] = input
+
+	if tools := convertClaudeToolsToResponsesTools(req["tools"]); tools != nil {
+		out["tools"] = tools
+		out["parallel_tool_calls"] = true
+	}
+	if tc := convertClaudeToolChoiceToResponses(req["tool_choice"]); tc != nil {
+		out["tool_choice"] = tc
+	}
+	if reasoning := convertClaudeThinkingToReasoning(req["thinking"]); reasoning != nil {
+		out["reasoning"] = reasoning
+	}
+
+	return json.Marshal(out)
+}
+
+func convertClaudeMessagesToResponsesInput(messages any) ([]any, error) {
+	arr, ok := messages.([]any)
+	if !ok {
+		return nil, errors.New("messages must be an array")
+	}
+
+	out := make([]any, 0, len(arr))
+	for _, m := range arr {
+		mm, ok := m.(map[string]any)
+		if !ok {
+			continue
+		}
+		role, _ := mm["role"].(string)
+		if role != "user" && role != "assistant" {
+			return nil, fmt.Errorf("unsupported message role: %s", role)
+		}
+		textType := "input_text"
+		if role == "assistant" {
+			textType = "output_text"
+		}
+
+		// 连续的文本/图片块合并为一条 message，工具调用/结果作为独立 item
+		var parts []any
+		flush := func() {
+			if len(parts) == 0 {
+				return
+			}
+			out = append(out, map[string]any{"type": "message", "role": role, "content": parts})
+			parts = nil
+		}
+
+		switch content := mm["content"].(type) {
+		case string:
+			if content != "" {
+				parts = append(parts, map[string]any{"type": textType, "text": content})
+			}
+		case []any:
+			for _, block := range content {
+				bm, ok := block.(map[string]any)
+				if !ok {
+					continue
+				}
+				switch bm["type"] {
+				case "text":
+					if text, _ := bm["text"].(string); text != "" {
+						parts = append(parts, map[string]any{"type": textType, "text": text})
+					}
+				case "image":
+					src, _ := bm["source"].(map[string]any)
+					switch src["type"] {
+					case "base64":
+						mediaType, _ := src["media_type"].(string)
+						data, _ := src["data"].(string)
+						if mediaType != "" && data != "" {
+							parts = append(parts, map[string]any{"type": "input_image", "image_url": "data:" + mediaType + ";base64," + data})
+						}
+					case "url":
+						if url, _ := src["url"].(string); url != "" {
+							parts = append(parts, map[string]any{"type": "input_image", "image_url": url})
+						}
+					}
+				case "tool_use":
+					flush()
+					args, _ := json.Marshal(bm["input"])
+					out = append(out, map[string]any{
+						"type":      "function_call",
+						"call_id":   bm["id"],
+						"name":      bm["name"],
+						"arguments": string(args),
+					})
+				case "tool_result":
+					flush()
+					output := extractClaudeContentText(bm["content"])
+					if isErr, _ := bm["is_error"].(bool); isErr && output != "" {
+						output = "Error: " + output
+					}
+					out = append(out, map[string]any{
+						"type":    "function_call_output",
+						"call_id": bm["tool_use_id"],
+						"output":  output,
+					})
+				case "thinking", "redacted_thinking":
+					// 上游的推理内容不可回传，忽略历史中的 thinking 块
+				}
+			}
+		}
+		flush()
+	}
+	return out, nil
+}
+
+func convertClaudeToolsToResponsesTools(tools any) []any {
+	arr, ok := tools.([]any)
+	if !ok || len(arr) == 0 {
+		return nil
+	}
+	out := make([]any, 0, len(arr))
+	for _, t := range arr {
+		tm, ok := t.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := tm["name"].(string)
+		// 跳过 Anthropic 服务端工具（如 web_search），它们没有 input_schema
+		if name == "" || tm["input_schema"] == nil {
+			continue
+		}
+		tool := map[string]any{
+			"type":       "function",
+			"name":       name,
+			"parameters": tm["input_schema"],
+		}
+		if desc, _ := tm["description"].(string); desc != "" {
+			tool["description"] = desc
+		}
+		out = append(out, tool)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func convertClaudeToolChoiceToResponses(toolChoice any) any {
+	tc, ok := toolChoice.(map[string]any)
+	if !ok {
+		return nil
+	}
+	switch tc["type"] {
+	case "auto":
+		return "auto"
+	case "any":
+		return "required"
+	case "none":
+		return "none"
+	case "tool":
+		if name, _ := tc["name"].(string); name != "" {
+			return map[string]any{"type": "function", "name": name}
+		}
+	}
+	return nil
+}
+
+// convertClaudeThinkingToReasoning 按 budget_tokens 映射推理强度
+func convertClaudeThinkingToReasoning(thinking any) map[string]any {
+	tm, ok := thinking.(map[string]any)
+	if !ok || tm["type"] != "enabled" {
+		return nil
+	}
+	effort := "medium"
+	if budget, ok := asInt(tm["budget_tokens"]); ok {
+		switch {
+		case budget >= 16000:
+			effort = "high"
+		case budget < 4000:
+			effort = "low"
+		}
+	}
+	return map[string]any{"effort": effort, "summary": "auto"}
+}
+
+// responsesToClaudeConverter 将 Responses API 流事件转换为 Claude Messages 流事件
+type responsesToClaudeConverter struct {
+	model      string
+	started    bool
+	finished   bool
+	sawContent bool
+	sawToolUse bool
+	stopReason string
+	usage      OpenAIUsage
+
+	nextBlockIndex int
+	openBlockIndex int
+	openBlockType  string
+	// output_index -> 工具调用状态
+	toolCalls map[int64]*responsesToolCallState
+}
+
+type responsesToolCallState struct {
+	blockIndex int
+	sawDelta   bool
+}
+
+func newResponsesToClaudeConverter(model string) *responsesToClaudeConverter {
+	return &responsesToClaudeConverter{
+		model:          model,
+		openBlockIndex: -1,
+		toolCalls:      make(map[int64]*responsesToolCallState),
+	}
+}
+
+func (cv *responsesToClaudeConverter) messageStart(id string) map[string]any {
+	cv.started = true
+	if id == "" {
+		id = randomHex(12)
+	}
+	return map[string]any{
+		"type": "message_start",
+		"message": map[string]any{
+			"id":            "msg_" + strings.TrimPrefix(id, "resp_"),
+			"type":          "message",
+			"role":          "assistant",
+			"model":         cv.model,
+			"content":       []any{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage": map[string]any{
+				"input_tokens":  0,
+				"output_tokens": 0,
+			},
+		},
+	}
+}
+
+func (cv *responsesToClaudeConverter) closeOpenBlock() []map[string]any {
+	if cv.openBlockIndex < 0 {
+		return nil
+	}
+	ev := map[string]any{"type": "content_block_stop", "index": cv.openBlockIndex}
+	cv.openBlockIndex = -1
+	cv.openBlockType = ""
+	return []map[string]any{ev}
+}
+
+// ensureBlock 确保当前打开的是指定类型的块（text/thinking），必要时关闭旧块并开启新块
+func (cv *responsesToClaudeConverter) ensureBlock(blockType string) []map[string]any {
+	if cv.openBlockType == blockType {
+		return nil
+	}
+	events := cv.closeOpenBlock()
+	cv.openBlockType = blockType
+	cv.openBlockIndex = cv.nextBlockIndex
+	cv.nextBlockIndex++
+	block := map[string]any{"type": "text", "text": ""}
+	if blockType == "thinking" {
+		block = map[string]any{"type": "thinking", "thinking": "", "signature": ""}
+	}
+	return append(events, map[string]any{
+		"type":          "content_block_start",
+		"index":         cv.openBlockIndex,
+		"content_block": block,
+	})
+}
+
+func (cv *responsesToClaudeConverter) convert(data []byte) []map[string]any {
+	event := gjson.ParseBytes(data)
+	var events []map[string]any
+	if !cv.started {
+		events = append(events, cv.messageStart(event.Get("response.id").String()))
+	}
+
+	switch event.Get("type").String() {
+	case "response.output_text.delta":
+		delta := event.Get("delta").String()
+		if delta == "" {
+			break
+		}
+		cv.sawContent = true
+		events = append(events, cv.ensureBlock("text")...)
+		events = append(events, map[string]any{
+			"type":  "content_block_delta",
+			"index": cv.openBlockIndex,
+			"delta": map[string]any{"type": "text_delta", "text": delta},
+		})
+
+	case "response.reasoning_summary_text.delta":
+		delta := event.Get("delta").String()
+		if delta == "" {
+			break
+		}
+		cv.sawContent = true
+		events = append(events, cv.ensureBlock("thinking")...)
+		events = append(events, map[string]any{
+			"type":  "content_block_delta",
+			"index": cv.openBlockIndex,
+			"delta": map[string]any{"type": "thinking_delta", "thinking": delta},
+		})
+
+	case "response.output_item.added":
+		item := event.Get("item")
+		if item.Get("type").String() != "function_call" {
+			break
+		}
+		cv.sawContent = true
+		cv.sawToolUse = true
+		events = append(events, cv.closeOpenBlock()...)
+		state := &responsesToolCallState{blockIndex: cv.nextBlockIndex}
+		cv.nextBlockIndex++
+		cv.toolCalls[event.Get("output_index").Int()] = state
+		callID := item.Get("call_id").String()
+		if callID == "" {
+			callID = "toolu_" + randomHex(8)
+		}
+		events = append(events, map[string]any{
+			"type":  "content_block_start",
+			"index": state.blockIndex,
+			"content_block": map[string]any{
+				"type":  "tool_use",
+				"id":    callID,
+				"name":  item.Get("name").String(),
+				"input": map[string]any{},
+			},
+		})
+
+	case "response.function_call_arguments.delta":
+		state, ok := cv.toolCalls[event.Get("output_index").Int()]
+		delta := event.Get("delta").String()
+		if !ok || delta == "" {
+			break
+		}
+		state.sawDelta = true
+		events = append(events, map[string]any{
+			"type":  "content_block_delta",
+			"index": state.blockIndex,
+			"delta": map[string]any{"type": "input_json_delta", "partial_json": delta},
+		})
+
+	case "response.output_item.done":
+		state, ok := cv.toolCalls[event.Get("output_index").Int()]
+		if !ok {
+			break
+		}
+		// 部分上游只在 done 事件中给出完整参数
+		if args := event.Get("item.arguments").String(); !state.sawDelta && args != "" {
+			events = append(events, map[string]any{
+				"type":  "content_block_delta",
+				"index": state.blockIndex,
+				"delta": map[string]any{"type": "input_json_delta", "partial_json": args},
+			})
+		}
+		events = append(events, map[string]any{"type": "content_block_stop", "index": state.blockIndex})
+		delete(cv.toolCalls, event.Get("output_index").Int())
+
+	case "response.completed", "response.incomplete":
+		u := event.Get("response.usage")
+		cv.usage.InputTokens = int(u.Get("input_tokens").Int())
+		cv.usage.OutputTokens = int(u.Get("output_tokens").Int())
+		cv.usage.CacheReadInputTokens = int(u.Get("input_tokens_details.cached_tokens").Int())
+		if event.Get("response.incomplete_details.reason").String() == "max_output_tokens" {
+			cv.stopReason = "max_tokens"
+		}
+
+	case "response.failed", "error":
+		msg := event.Get("response.error.message").String()
+		if msg == "" {
+			msg = event.Get("message").String()
+		}
+		if msg == "" {
+			msg = "Upstream request failed"
+		}
+		events = append(events, cv.closeOpenBlock()...)
+		cv.finished = true
+		events = append(events, map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": "api_error", "message": msg},
+		})
+	}
+	return events
+}
+
+// finish 关闭未结束的块并输出 message_delta/message_stop
+func (cv *responsesToClaudeConverter) finish() []map[string]any {
+	if cv.finished {
+		return nil
+	}
+	cv.finished = true
+	var events []map[string]any
+	if !cv.started {
+		events = append(events, cv.messageStart(""))
+	}
+	events = append(events, cv.closeOpenBlock()...)
+	for outputIndex, state := range cv.toolCalls {
+		events = append(events, map[string]any{"type": "content_block_stop", "index": state.blockIndex})
+		delete(cv.toolCalls, outputIndex)
+	}
+
+	stopReason := cv.stopReason
+	if stopReason == "" {
+		stopReason = "end_turn"
+		if cv.sawToolUse {
+			stopReason = "tool_use"
+		}
+	}
+
+	inputTokens := cv.usage.InputTokens - cv.usage.CacheReadInputTokens
+	if inputTokens < 0 {
+		inputTokens = 0
+	}
+	events = append(events, map[string]any{
+		"type": "message_delta",
+		"delta": map[string]any{
+			"stop_reason":   stopReason,
+			"stop_sequence": nil,
+		},
+		"usage": map[string]any{
+			"input_tokens":            inputTokens,
+			"output_tokens":           cv.usage.OutputTokens,
+			"cache_read_input_tokens": cv.usage.CacheReadInputTokens,
+		},
+	})
+	events = append(events, map[string]any{"type": "message_stop"})
+	return events
+}
+
+// claudeMessageAccumulator 将 Claude 流事件聚合为非流式 message 响应
+type claudeMessageAccumulator struct {
+	message     map[string]any
+	blocks      []map[string]any
+	partialJSON map[int]*strings.Builder
+	errorEvent  map[string]any
+}
+
+func newClaudeMessageAccumulator() *claudeMessageAccumulator {
+	return &claudeMessageAccumulator{
+		message:     map[string]any{},
+		partialJSON: make(map[int]*strings.Builder),
+	}
+}
+
+func (a *claudeMessageAccumulator) add(events []map[string]any) {
+	for _, ev := range events {
+		switch ev["type"] {
+		case "message_start":
+			msg, _ := ev["message"].(map[string]any)
+			for k, v := range msg {
+				a.message[k] = v
+			}
+		case "content_block_start":
+			block, _ := ev["content_block"].(map[string]any)
+			copied := make(map[string]any, len(block))
+			for k, v := range block {
+				copied[k] = v
+			}
+			a.blocks = append(a.blocks, copied)
+			if copied["type"] == "tool_use" {
+				a.partialJSON[len(a.blocks)-1] = &strings.Builder{}
+			}
+		case "content_block_delta":
+			idx, _ := ev["index"].(int)
+			if idx < 0 || idx >= len(a.blocks) {
+				continue
+			}
+			delta, _ := ev["delta"].(map[string]any)
+			block := a.blocks[idx]
+			switch delta["type"] {
+			case "text_delta":
+				block["text"] = block["text"].(string) + delta["text"].(string)
+			case "thinking_delta":
+				block["thinking"] = block["thinking"].(string) + delta["thinking"].(string)
+			case "input_json_delta":
+				if sb, ok := a.partialJSON[idx]; ok {
+					_, _ = sb.WriteString(delta["partial_json"].(string))
+				}
+			}
+		case "content_block_stop":
+			idx, _ := ev["index"].(int)
+			if sb, ok := a.partialJSON[idx]; ok && idx < len(a.blocks) {
+				input := map[string]any{}
+				if sb.Len() > 0 {
+					_ = json.Unmarshal([]byte(sb.String()), &input)
+				}
+				a.blocks[idx]["input"] = input
+				delete(a.partialJSON, idx)
+			}
+		case "message_delta":
+			delta, _ := ev["delta"].(map[string]any)
+			a.message["stop_reason"] = delta["stop_reason"]
+			a.message["stop_sequence"] = delta["stop_sequence"]
+			a.message["usage"] = ev["usage"]
+		case "error":
+			a.errorEvent = ev
+		}
+	}
+	content := make([]any, 0, len(a.blocks))
+	for _, b := range a.blocks {
+		content = append(content, b)
+	}
+	a.message["content"] = content
+}
@@ -0,0 +1,127 @@
+//go:build unit
+
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertClaudeMessagesToOpenAIResponses(t *testing.T) {
+	body := []byte(`{
+		"model": "claude-sonnet-4-5",
+		"system": [{"type": "text", "text": "you are helpful"}],
+		"max_tokens": 1024,
+		"thinking": {"type": "enabled", "budget_tokens": 20000},
+		"messages": [
+			{"role": "user", "content": "list files"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "hmm", "signature": "sig"},
+				{"type": "text", "text": "Running ls"},
+				{"type": "tool_use", "id": "toolu_1", "name": "bash", "input": {"cmd": "ls"}}
+			]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "a.go"}]}]}
+		],
+		"tools": [{"name": "bash", "description": "run", "input_schema": {"type": "object"}}, {"type": "web_search_20250305", "name": "web_search"}],
+		"tool_choice": {"type": "any"}
+	}`)
+
+	t.Run("api key account", func(t *testing.T) {
+		out, err := convertClaudeMessagesToOpenAIResponses(body, "gpt-5.1", false)
+		require.NoError(t, err)
+
+		var req map[string]any
+		require.NoError(t, json.Unmarshal(out, &req))
+		require.Equal(t, "gpt-5.1", req["model"])
+		require.Equal(t, "you are helpful", req["instructions"])
+		require.Equal(t, float64(1024), req["max_output_tokens"])
+		require.Equal(t, true, req["stream"])
+		require.Equal(t, "required", req["tool_choice"])
+		require.Equal(t, map[string]any{"effort": "high", "summary": "auto"}, req["reasoning"])
+		require.Len(t, req["tools"], 1)
+
+		input := req["input"].([]any)
+		require.Len(t, input, 4)
+		require.Equal(t, "message", input[0].(map[string]any)["type"])
+		assistant := input[1].(map[string]any)
+		require.Equal(t, "assistant", assistant["role"])
+		require.Equal(t, "output_text", assistant["content"].([]any)[0].(map[string]any)["type"])
+		call := input[2].(map[string]any)
+		require.Equal(t, "function_call", call["type"])
+		require.Equal(t, "toolu_1", call["call_id"])
+		require.Equal(t, `{"cmd":"ls"}`, call["arguments"])
+		output := input[3].(map[string]any)
+		require.Equal(t, "function_call_output", output["type"])
+		require.Equal(t, "a.go", output["output"])
+	})
+
+	t.Run("oauth account", func(t *testing.T) {
+		out, err := convertClaudeMessagesToOpenAIResponses(body, "gpt-5.1-codex", true)
+		require.NoError(t, err)
+
+		var req map[string]any
+		require.NoError(t, json.Unmarshal(out, &req))
+		require.Equal(t, false, req["store"])
+		require.NotContains(t, req, "max_output_tokens")
+		input := req["input"].([]any)
+		require.Equal(t, "developer", input[0].(map[string]any)["role"])
+	})
+}
+
+func TestResponsesToClaudeConverter(t *testing.T) {
+	cv := newResponsesToClaudeConverter("claude-sonnet-4-5")
+	acc := newClaudeMessageAccumulator()
+
+	feed := []string{
+		`{"type":"response.created","response":{"id":"resp_123"}}`,
+		`{"type":"response.reasoning_summary_text.delta","delta":"thinking..."}`,
+		`{"type":"response.output_text.delta","delta":"Hello"}`,
+		`{"type":"response.output_text.delta","delta":" world"}`,
+		`{"type":"response.output_item.added","output_index":2,"item":{"type":"function_call","call_id":"call_1","name":"bash"}}`,
+		`{"type":"response.function_call_arguments.delta","output_index":2,"delta":"{\"cmd\":"}`,
+		`{"type":"response.function_call_arguments.delta","output_index":2,"delta":"\"ls\"}"}`,
+		`{"type":"response.output_item.done","output_index":2,"item":{"type":"function_call","arguments":"{\"cmd\":\"ls\"}"}}`,
+		`{"type":"response.completed","response":{"usage":{"input_tokens":100,"output_tokens":20,"input_tokens_details":{"cached_tokens":40}}}}`,
+	}
+	var types []string
+	for _, data := range feed {
+		events := cv.convert([]byte(data))
+		for _, ev := range events {
+			types = append(types, ev["type"].(string))
+		}
+		acc.add(events)
+	}
+	final := cv.finish()
+	acc.add(final)
+
+	require.Equal(t, "message_start", types[0])
+	require.Equal(t, "message_stop", final[len(final)-1]["type"])
+
+	require.Equal(t, 100, cv.usage.InputTokens)
+	require.Equal(t, 20, cv.usage.OutputTokens)
+	require.Equal(t, 40, cv.usage.CacheReadInputTokens)
+
+	msg := acc.message
+	require.Equal(t, "msg_123", msg["id"])
+	require.Equal(t, "tool_use", msg["stop_reason"])
+	content := msg["content"].([]any)
+	require.Len(t, content, 3)
+	require.Equal(t, "thinking...", content[0].(map[string]any)["thinking"])
+	require.Equal(t, "Hello world", content[1].(map[string]any)["text"])
+	toolUse := content[2].(map[string]any)
+	require.Equal(t, "call_1", toolUse["id"])
+	require.Equal(t, map[string]any{"cmd": "ls"}, toolUse["input"])
+	require.Equal(t, 60, msg["usage"].(map[string]any)["input_tokens"])
+}
+
+func TestResolveOpenAICompatModel(t *testing.T) {
+	account := &Account{Credentials: map[string]any{}}
+	require.Equal(t, openaiCompatDefaultModel, resolveOpenAICompatModel(account, "claude-sonnet-4-5"))
+	require.Equal(t, openaiCompatDefaultMiniModel, resolveOpenAICompatModel(account, "claude-haiku-4-5"))
+	require.Equal(t, "gpt-5.1", resolveOpenAICompatModel(account, "gpt-5.1"))
+
+	account.Credentials["model_mapping"] = map[string]any{"claude-sonnet-4-5": "gpt-5.2"}
+	require.Equal(t, "gpt-5.2", resolveOpenAICompatModel(account, "claude-sonnet-4-5"))
+}
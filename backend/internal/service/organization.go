@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+)
+
+// Organization member roles
+const (
+	OrgRoleOwner  = "owner"  // 创建者，拥有全部权限，不可移除
+	OrgRoleAdmin  = "admin"  // 管理成员、邀请、查看账单
+	OrgRoleMember = "member" // 使用组织余额与订阅
+)
+
+var (
+	ErrOrganizationNotFound       = infraerrors.NotFound("ORGANIZATION_NOT_FOUND", "organization not found")
+	ErrOrganizationInactive       = infraerrors.Forbidden("ORGANIZATION_INACTIVE", "organization is not active")
+	ErrOrgMemberNotFound          = infraerrors.NotFound("ORGANIZATION_MEMBER_NOT_FOUND", "organization member not found")
+	ErrOrgMemberExists            = infraerrors.Conflict("ORGANIZATION_MEMBER_EXISTS", "user is already a member of this organization")
+	ErrOrgPermissionDenied        = infraerrors.Forbidden("ORGANIZATION_PERMISSION_DENIED", "insufficient organization permissions")
+	ErrOrgOwnerImmutable          = infraerrors.Forbidden("ORGANIZATION_OWNER_IMMUTABLE", "the organization owner cannot be changed or removed")
+	ErrOrgInvalidRole             = infraerrors.BadRequest("ORGANIZATION_INVALID_ROLE", "role must be admin or member")
+	ErrOrgInvitationNotFound      = infraerrors.NotFound("ORGANIZATION_INVITATION_NOT_FOUND", "invitation not found or has expired")
+	ErrOrgInvitationEmailMismatch = infraerrors.Forbidden("ORGANIZATION_INVITATION_EMAIL_MISMATCH", "invitation was sent to a different email address")
+	ErrOrgMemberSpendingLimit     = infraerrors.Forbidden("MEMBER_SPENDING_LIMIT_EXCEEDED", "monthly spending limit for this organization member has been reached")
+)
+
+// Organization 组织：成员共享余额与订阅
+type Organization struct {
+	ID      int64
+	Name    string
+	Balance float64
+	Status  string
+	OwnerID int64
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (o *Organization) IsActive() bool {
+	return o.Status == StatusActive
+}
+
+// OrganizationMember 组织成员
+// 每月花费按自然月（UTC）累计在成员记录上，UsageMonth 不是当月时视为 0。
+type OrganizationMember struct {
+	ID             int64
+	OrganizationID int64
+	UserID         int64
+	Role           string
+
+	// MonthlyLimitUSD 每月可使用组织余额的上限（nil 表示不限制）
+	MonthlyLimitUSD *float64
+	MonthlyUsageUSD float64
+	UsageMonth      string // 格式 2006-01
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	User         *User
+	Organization *Organization
+}
+
+// orgUsageMonth 返回花费累计使用的月份标识
+func orgUsageMonth(now time.Time) string {
+	return now.UTC().Format("2006-01")
+}
+
+// CurrentMonthUsage 当月已使用的组织余额
+func (m *OrganizationMember) CurrentMonthUsage(now time.Time) float64 {
+	if m.UsageMonth != orgUsageMonth(now) {
+		return 0
+	}
+	return m.MonthlyUsageUSD
+}
+
+func (m *OrganizationMember) HasMonthlyLimit() bool {
+	return m.MonthlyLimitUSD != nil && *m.MonthlyLimitUSD > 0
+}
+
+// IsOverMonthlyLimit 当月花费是否已达到上限
+func (m *OrganizationMember) IsOverMonthlyLimit(now time.Time) bool {
+	return m.HasMonthlyLimit() && m.CurrentMonthUsage(now) >= *m.MonthlyLimitUSD
+}
+
+// CanManage 是否可以管理成员、邀请与查看账单
+func (m *OrganizationMember) CanManage() bool {
+	return m.Role == OrgRoleOwner || m.Role == OrgRoleAdmin
+}
+
+// OrganizationInvitation 成员邀请，Token 只保存哈希
+type OrganizationInvitation struct {
+	ID             int64
+	OrganizationID int64
+	Email          string
+	Role           string
+	TokenHash      string
+	InvitedBy      int64
+	ExpiresAt      time.Time
+	AcceptedAt     *time.Time
+	CreatedAt      time.Time
+
+	Organization *Organization
+}
+
+// IsPending 未接受且未过期
+func (i *OrganizationInvitation) IsPending(now time.Time) bool {
+	return i.AcceptedAt == nil && now.Before(i.ExpiresAt)
+}
+
+// OrganizationMemberUsage 成员在组织内的用量汇总
+type OrganizationMemberUsage struct {
+	UserID       int64
+	Requests     int64
+	InputTokens  int64
+	OutputTokens int64
+	TotalCost    float64
+	ActualCost   float64
+}
+
+type OrganizationRepository interface {
+	// Create 创建组织并将 owner 写入成员表（同一事务）
+	Create(ctx context.Context, org *Organization, owner *OrganizationMember) error
+	GetByID(ctx context.Context, id int64) (*Organization, error)
+	Update(ctx context.Context, org *Organization) error
+	List(ctx context.Context, params pagination.PaginationParams, search string) ([]Organization, *pagination.PaginationResult, error)
+
+	GetMember(ctx context.Context, orgID, userID int64) (*OrganizationMember, error)
+	ListMembers(ctx context.Context, orgID int64) ([]OrganizationMember, error)
+	// ListMembershipsByUserID 返回用户加入的全部组织（含组织信息）
+	ListMembershipsByUserID(ctx context.Context, userID int64) ([]OrganizationMember, error)
+	UpdateMember(ctx context.Context, member *OrganizationMember) error
+	RemoveMember(ctx context.Context, orgID, userID int64) error
+
+	CreateInvitation(ctx context.Context, inv *OrganizationInvitation) error
+	GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*OrganizationInvitation, error)
+	ListPendingInvitations(ctx context.Context, orgID int64, now time.Time) ([]OrganizationInvitation, error)
+	DeleteInvitation(ctx context.Context, orgID, id int64) error
+	// AcceptInvitation 标记邀请已接受并加入成员（同一事务），已是成员时返回 ErrOrgMemberExists
+	AcceptInvitation(ctx context.Context, inv *OrganizationInvitation, member *OrganizationMember) error
+
+	// MemberUsage 按成员汇总组织内的用量
+	MemberUsage(ctx context.Context, orgID int64, startTime, endTime time.Time) ([]OrganizationMemberUsage, error)
+}
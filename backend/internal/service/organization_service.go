@@ -0,0 +1,535 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
+)
+
+// orgInvitationTTL 邀请有效期
+const orgInvitationTTL = 7 * 24 * time.Hour
+
+// UpdateOrgMemberInput 更新成员的参数（nil 表示不修改）
+type UpdateOrgMemberInput struct {
+	Role *string
+	// MonthlyLimitUSD 每月花费上限，传 0 表示取消限制
+	MonthlyLimitUSD *float64
+}
+
+// OrganizationService 组织服务：成员管理、邀请、共享余额与组织 Key
+type OrganizationService struct {
+	orgRepo             OrganizationRepository
+	userRepo            UserRepository
+	balanceTxRepo       BalanceTransactionRepository
+	apiKeyService       *ApiKeyService
+	subscriptionService *SubscriptionService
+	emailQueueService   *EmailQueueService
+	settingService      *SettingService
+	auditService        *AuditService
+}
+
+// NewOrganizationService 创建组织服务
+func NewOrganizationService(
+	orgRepo OrganizationRepository,
+	userRepo UserRepository,
+	balanceTxRepo BalanceTransactionRepository,
+	apiKeyService *ApiKeyService,
+	subscriptionService *SubscriptionService,
+	emailQueueService *EmailQueueService,
+	settingService *SettingService,
+	auditService *AuditService,
+) *OrganizationService {
+	return &OrganizationService{
+		orgRepo:             orgRepo,
+		userRepo:            userRepo,
+		balanceTxRepo:       balanceTxRepo,
+		apiKeyService:       apiKeyService,
+		subscriptionService: subscriptionService,
+		emailQueueService:   emailQueueService,
+		settingService:      settingService,
+		auditService:        auditService,
+	}
+}
+
+// Create 创建组织，创建者成为 owner
+func (s *OrganizationService) Create(ctx context.Context, userID int64, name string) (*Organization, error) {
+	org := &Organization{
+		Name:    strings.TrimSpace(name),
+		Status:  StatusActive,
+		OwnerID: userID,
+	}
+	owner := &OrganizationMember{UserID: userID, Role: OrgRoleOwner}
+	if err := s.orgRepo.Create(ctx, org, owner); err != nil {
+		return nil, fmt.Errorf("create organization: %w", err)
+	}
+	return org, nil
+}
+
+// ListForUser 返回用户加入的组织及其角色
+func (s *OrganizationService) ListForUser(ctx context.Context, userID int64) ([]OrganizationMember, error) {
+	return s.orgRepo.ListMembershipsByUserID(ctx, userID)
+}
+
+// Get 返回组织与当前用户的成员信息（非成员视为组织不存在）
+func (s *OrganizationService) Get(ctx context.Context, userID, orgID int64) (*Organization, *OrganizationMember, error) {
+	member, err := s.requireMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	org, err := s.orgRepo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return org, member, nil
+}
+
+// Rename 修改组织名称（owner/admin）
+func (s *OrganizationService) Rename(ctx context.Context, userID, orgID int64, name string) (*Organization, error) {
+	if _, err := s.requireManager(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	org, err := s.orgRepo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	org.Name = strings.TrimSpace(name)
+	if err := s.orgRepo.Update(ctx, org); err != nil {
+		return nil, fmt.Errorf("update organization: %w", err)
+	}
+	return org, nil
+}
+
+// ListMembers 列出成员（任意成员可查看）
+func (s *OrganizationService) ListMembers(ctx context.Context, userID, orgID int64) ([]OrganizationMember, error) {
+	if _, err := s.requireMember(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.orgRepo.ListMembers(ctx, orgID)
+}
+
+// Invite 邀请成员并发送邀请邮件（owner/admin，只有 owner 可以邀请 admin）
+func (s *OrganizationService) Invite(ctx context.Context, userID, orgID int64, email, role string) (*OrganizationInvitation, error) {
+	actor, err := s.requireManager(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOrgRoleGrant(actor, role); err != nil {
+		return nil, err
+	}
+	org, err := s.orgRepo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if !org.IsActive() {
+		return nil, ErrOrganizationInactive
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if invitee, err := s.userRepo.GetByEmail(ctx, email); err == nil {
+		if _, err := s.orgRepo.GetMember(ctx, orgID, invitee.ID); err == nil {
+			return nil, ErrOrgMemberExists
+		}
+	}
+
+	token, err := generateOrgInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	inv := &OrganizationInvitation{
+		OrganizationID: orgID,
+		Email:          email,
+		Role:           role,
+		TokenHash:      hashOrgInvitationToken(token),
+		InvitedBy:      userID,
+		ExpiresAt:      time.Now().Add(orgInvitationTTL),
+	}
+	if err := s.orgRepo.CreateInvitation(ctx, inv); err != nil {
+		return nil, fmt.Errorf("create invitation: %w", err)
+	}
+
+	s.sendInvitation(ctx, org, inv, token)
+	return inv, nil
+}
+
+// sendInvitation 将邀请邮件加入发送队列，Token 只通过邮件下发
+func (s *OrganizationService) sendInvitation(ctx context.Context, org *Organization, inv *OrganizationInvitation, token string) {
+	if s.emailQueueService == nil {
+		logger.FromContext(ctx).Warn("email queue not configured, organization invitation not sent", "organization_id", org.ID)
+		return
+	}
+	siteName := s.settingService.GetSiteName(ctx)
+	subject := fmt.Sprintf("[%s] Invitation to join %s", siteName, org.Name)
+	body := fmt.Sprintf(
+		"<p>You have been invited to join the organization <strong>%s</strong> on %s as %s.</p>"+
+			"<p>Sign in with this email address and accept the invitation using the code below. The code expires on %s.</p>"+
+			"<p style=\"font-family:monospace;font-size:16px\">%s</p>",
+		html.EscapeString(org.Name), html.EscapeString(siteName), inv.Role,
+		inv.ExpiresAt.UTC().Format("2006-01-02 15:04 UTC"), token,
+	)
+	if err := s.emailQueueService.EnqueueNotification(inv.Email, subject, body); err != nil {
+		logger.FromContext(ctx).Warn("enqueue organization invitation failed", "organization_id", org.ID, logger.Err(err))
+	}
+}
+
+// ListInvitations 列出未处理的邀请（owner/admin）
+func (s *OrganizationService) ListInvitations(ctx context.Context, userID, orgID int64) ([]OrganizationInvitation, error) {
+	if _, err := s.requireManager(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.orgRepo.ListPendingInvitations(ctx, orgID, time.Now())
+}
+
+// CancelInvitation 撤销邀请（owner/admin）
+func (s *OrganizationService) CancelInvitation(ctx context.Context, userID, orgID, invitationID int64) error {
+	if _, err := s.requireManager(ctx, orgID, userID); err != nil {
+		return err
+	}
+	return s.orgRepo.DeleteInvitation(ctx, orgID, invitationID)
+}
+
+// AcceptInvitation 当前用户接受邀请，邀请邮箱需与账号邮箱一致
+func (s *OrganizationService) AcceptInvitation(ctx context.Context, userID int64, token string) (*OrganizationMember, error) {
+	inv, err := s.orgRepo.GetInvitationByTokenHash(ctx, hashOrgInvitationToken(strings.TrimSpace(token)))
+	if err != nil {
+		return nil, err
+	}
+	if !inv.IsPending(time.Now()) {
+		return nil, ErrOrgInvitationNotFound
+	}
+	if inv.Organization != nil && !inv.Organization.IsActive() {
+		return nil, ErrOrganizationInactive
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	if !strings.EqualFold(user.Email, inv.Email) {
+		return nil, ErrOrgInvitationEmailMismatch
+	}
+
+	member := &OrganizationMember{
+		OrganizationID: inv.OrganizationID,
+		UserID:         userID,
+		Role:           inv.Role,
+		Organization:   inv.Organization,
+	}
+	if err := s.orgRepo.AcceptInvitation(ctx, inv, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// UpdateMember 修改成员角色或花费上限（owner/admin）
+func (s *OrganizationService) UpdateMember(ctx context.Context, userID, orgID, targetUserID int64, input *UpdateOrgMemberInput) (*OrganizationMember, error) {
+	actor, err := s.requireManager(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.orgRepo.GetMember(ctx, orgID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOrgMemberManageable(actor, target); err != nil {
+		return nil, err
+	}
+
+	if input.Role != nil {
+		if err := checkOrgRoleGrant(actor, *input.Role); err != nil {
+			return nil, err
+		}
+		target.Role = *input.Role
+	}
+	if input.MonthlyLimitUSD != nil {
+		target.MonthlyLimitUSD = normalizeLimitUSD(input.MonthlyLimitUSD)
+	}
+	if err := s.orgRepo.UpdateMember(ctx, target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// RemoveMember 移除成员（owner/admin），成员也可以自行退出；owner 不可移除
+func (s *OrganizationService) RemoveMember(ctx context.Context, userID, orgID, targetUserID int64) error {
+	if userID == targetUserID {
+		member, err := s.requireMember(ctx, orgID, userID)
+		if err != nil {
+			return err
+		}
+		if member.Role == OrgRoleOwner {
+			return ErrOrgOwnerImmutable
+		}
+		return s.orgRepo.RemoveMember(ctx, orgID, userID)
+	}
+
+	actor, err := s.requireManager(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	target, err := s.orgRepo.GetMember(ctx, orgID, targetUserID)
+	if err != nil {
+		return err
+	}
+	if err := checkOrgMemberManageable(actor, target); err != nil {
+		return err
+	}
+	// 成员的组织 Key 在认证时校验成员身份，移除后立即失效
+	return s.orgRepo.RemoveMember(ctx, orgID, targetUserID)
+}
+
+// MemberUsage 按成员汇总用量：owner/admin 查看全部成员，普通成员只能查看自己
+func (s *OrganizationService) MemberUsage(ctx context.Context, userID, orgID int64, startTime, endTime time.Time) ([]OrganizationMemberUsage, error) {
+	member, err := s.requireMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := s.orgRepo.MemberUsage(ctx, orgID, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("get member usage: %w", err)
+	}
+	if member.CanManage() {
+		return usage, nil
+	}
+	own := make([]OrganizationMemberUsage, 0, 1)
+	for _, u := range usage {
+		if u.UserID == userID {
+			own = append(own, u)
+		}
+	}
+	return own, nil
+}
+
+// CreateApiKey 以组织名义创建 Key（任意成员），Key 归属创建者并使用组织余额与订阅
+func (s *OrganizationService) CreateApiKey(ctx context.Context, userID, orgID int64, req CreateApiKeyRequest) (*ApiKey, error) {
+	org, _, err := s.Get(ctx, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if !org.IsActive() {
+		return nil, ErrOrganizationInactive
+	}
+	req.OrganizationID = &orgID
+	return s.apiKeyService.Create(ctx, userID, req)
+}
+
+// ListApiKeys 列出组织的全部 Key（owner/admin）
+func (s *OrganizationService) ListApiKeys(ctx context.Context, userID, orgID int64, params pagination.PaginationParams) ([]ApiKey, *pagination.PaginationResult, error) {
+	if _, err := s.requireManager(ctx, orgID, userID); err != nil {
+		return nil, nil, err
+	}
+	return s.apiKeyService.ListByOrganization(ctx, orgID, params)
+}
+
+// DeleteApiKey 删除组织内任意成员的 Key（owner/admin）
+func (s *OrganizationService) DeleteApiKey(ctx context.Context, userID, orgID, keyID int64) error {
+	if _, err := s.requireManager(ctx, orgID, userID); err != nil {
+		return err
+	}
+	return s.apiKeyService.DeleteForOrganization(ctx, keyID, orgID)
+}
+
+// ListTransactions 组织余额流水（owner/admin）
+func (s *OrganizationService) ListTransactions(ctx context.Context, userID, orgID int64, params pagination.PaginationParams, filters BalanceTransactionFilters) ([]BalanceTransaction, *pagination.PaginationResult, error) {
+	if _, err := s.requireManager(ctx, orgID, userID); err != nil {
+		return nil, nil, err
+	}
+	filters.OrganizationID = orgID
+	txs, result, err := s.balanceTxRepo.List(ctx, params, filters)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list organization balance transactions: %w", err)
+	}
+	return txs, result, nil
+}
+
+// ListSubscriptions 组织共享订阅（任意成员可查看）
+func (s *OrganizationService) ListSubscriptions(ctx context.Context, userID, orgID int64) ([]UserSubscription, error) {
+	if _, err := s.requireMember(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.subscriptionService.ListOrganizationSubscriptions(ctx, orgID)
+}
+
+// CheckApiKeyAccess 校验组织 Key 能否发起请求：组织有效、创建者仍是成员；
+// 余额模式下还需组织余额为正且成员未超过当月花费上限。
+// 组织余额与成员花费直接读取数据库，不经过按用户维度的余额缓存。
+func (s *OrganizationService) CheckApiKeyAccess(ctx context.Context, apiKey *ApiKey) error {
+	if !apiKey.IsOrganizationOwned() {
+		return nil
+	}
+	org, err := s.orgRepo.GetByID(ctx, *apiKey.OrganizationID)
+	if err != nil {
+		return err
+	}
+	if !org.IsActive() {
+		return ErrOrganizationInactive
+	}
+	member, err := s.orgRepo.GetMember(ctx, org.ID, apiKey.UserID)
+	if err != nil {
+		return err
+	}
+
+	if apiKey.Group != nil && apiKey.Group.IsSubscriptionType() {
+		return nil
+	}
+	if org.Balance <= 0 {
+		return ErrInsufficientBalance
+	}
+	if member.IsOverMonthlyLimit(time.Now()) {
+		return ErrOrgMemberSpendingLimit
+	}
+	return nil
+}
+
+// ============================================
+// 管理员接口
+// ============================================
+
+// AdminList 查询全部组织（管理员）
+func (s *OrganizationService) AdminList(ctx context.Context, params pagination.PaginationParams, search string) ([]Organization, *pagination.PaginationResult, error) {
+	return s.orgRepo.List(ctx, params, strings.TrimSpace(search))
+}
+
+// AdminGet 获取组织及成员（管理员）
+func (s *OrganizationService) AdminGet(ctx context.Context, orgID int64) (*Organization, []OrganizationMember, error) {
+	org, err := s.orgRepo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	members, err := s.orgRepo.ListMembers(ctx, orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return org, members, nil
+}
+
+// AdminUpdateStatus 启用或停用组织（管理员），停用后组织 Key 无法使用
+func (s *OrganizationService) AdminUpdateStatus(ctx context.Context, orgID int64, status string) (*Organization, error) {
+	org, err := s.orgRepo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	before := *org
+	org.Status = status
+	if err := s.orgRepo.Update(ctx, org); err != nil {
+		return nil, fmt.Errorf("update organization: %w", err)
+	}
+	s.auditService.Record(ctx, AuditActionUpdate, AuditResourceOrganization, org.ID, &before, org)
+	return org, nil
+}
+
+// AdjustBalance 调整组织余额（管理员），amount 为正数充值、负数扣减，扣减后余额不能为负
+func (s *OrganizationService) AdjustBalance(ctx context.Context, orgID int64, amount float64, notes string, operatorID int64) (*BalanceTransaction, error) {
+	org, err := s.orgRepo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	tx := &BalanceTransaction{
+		UserID:         org.OwnerID,
+		OrganizationID: &org.ID,
+		Type:           BalanceTxTypeAdminAdjustment,
+		Amount:         amount,
+		OperatorID:     &operatorID,
+		Notes:          notes,
+	}
+	if err := s.balanceTxRepo.Apply(ctx, tx, false); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(ctx, AuditActionUpdate, AuditResourceOrganization, org.ID,
+		map[string]any{"balance": tx.BalanceAfter - tx.Amount},
+		map[string]any{"balance": tx.BalanceAfter, "notes": notes})
+	return tx, nil
+}
+
+// AssignSubscription 为组织分配共享订阅（管理员），订阅记录挂在组织 owner 名下
+func (s *OrganizationService) AssignSubscription(ctx context.Context, orgID, groupID int64, validityDays int, notes string, operatorID int64) (*UserSubscription, error) {
+	org, err := s.orgRepo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := s.subscriptionService.AssignSubscription(ctx, &AssignSubscriptionInput{
+		UserID:         org.OwnerID,
+		GroupID:        groupID,
+		ValidityDays:   validityDays,
+		AssignedBy:     operatorID,
+		Notes:          notes,
+		OrganizationID: &org.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.auditService.Record(ctx, AuditActionCreate, AuditResourceSubscription, sub.ID, nil, sub)
+	return sub, nil
+}
+
+// requireMember 校验用户是组织成员；非成员统一返回组织不存在，避免泄露组织信息
+func (s *OrganizationService) requireMember(ctx context.Context, orgID, userID int64) (*OrganizationMember, error) {
+	member, err := s.orgRepo.GetMember(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, ErrOrgMemberNotFound) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return member, nil
+}
+
+// requireManager 校验用户是组织的 owner 或 admin
+func (s *OrganizationService) requireManager(ctx context.Context, orgID, userID int64) (*OrganizationMember, error) {
+	member, err := s.requireMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !member.CanManage() {
+		return nil, ErrOrgPermissionDenied
+	}
+	return member, nil
+}
+
+// checkOrgRoleGrant 校验 actor 能否授予该角色：只能授予 admin/member，且只有 owner 能授予 admin
+func checkOrgRoleGrant(actor *OrganizationMember, role string) error {
+	switch role {
+	case OrgRoleMember:
+		return nil
+	case OrgRoleAdmin:
+		if actor.Role != OrgRoleOwner {
+			return ErrOrgPermissionDenied
+		}
+		return nil
+	default:
+		return ErrOrgInvalidRole
+	}
+}
+
+// checkOrgMemberManageable 校验 actor 能否管理 target：owner 不可被修改，admin 只能管理普通成员
+func checkOrgMemberManageable(actor, target *OrganizationMember) error {
+	if target.Role == OrgRoleOwner {
+		return ErrOrgOwnerImmutable
+	}
+	if actor.Role != OrgRoleOwner && target.Role != OrgRoleMember {
+		return ErrOrgPermissionDenied
+	}
+	return nil
+}
+
+// generateOrgInvitationToken 生成 32 字节随机邀请码
+func generateOrgInvitationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate invitation token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashOrgInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
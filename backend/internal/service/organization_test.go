@@ -0,0 +1,247 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type orgRepoStub struct {
+	OrganizationRepository
+	orgs        map[int64]*Organization
+	members     map[int64]*OrganizationMember // key: userID（单组织测试）
+	invitations []*OrganizationInvitation
+	usage       []OrganizationMemberUsage
+}
+
+func newOrgRepoStub(org *Organization, members ...*OrganizationMember) *orgRepoStub {
+	s := &orgRepoStub{
+		orgs:    map[int64]*Organization{org.ID: org},
+		members: map[int64]*OrganizationMember{},
+	}
+	for _, m := range members {
+		m.OrganizationID = org.ID
+		s.members[m.UserID] = m
+	}
+	return s
+}
+
+func (s *orgRepoStub) GetByID(ctx context.Context, id int64) (*Organization, error) {
+	if o, ok := s.orgs[id]; ok {
+		cp := *o
+		return &cp, nil
+	}
+	return nil, ErrOrganizationNotFound
+}
+
+func (s *orgRepoStub) GetMember(ctx context.Context, orgID, userID int64) (*OrganizationMember, error) {
+	if m, ok := s.members[userID]; ok && m.OrganizationID == orgID {
+		cp := *m
+		return &cp, nil
+	}
+	return nil, ErrOrgMemberNotFound
+}
+
+func (s *orgRepoStub) UpdateMember(ctx context.Context, member *OrganizationMember) error {
+	cp := *member
+	s.members[member.UserID] = &cp
+	return nil
+}
+
+func (s *orgRepoStub) RemoveMember(ctx context.Context, orgID, userID int64) error {
+	delete(s.members, userID)
+	return nil
+}
+
+func (s *orgRepoStub) CreateInvitation(ctx context.Context, inv *OrganizationInvitation) error {
+	inv.ID = int64(len(s.invitations) + 1)
+	s.invitations = append(s.invitations, inv)
+	return nil
+}
+
+func (s *orgRepoStub) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*OrganizationInvitation, error) {
+	for _, inv := range s.invitations {
+		if inv.TokenHash == tokenHash {
+			cp := *inv
+			return &cp, nil
+		}
+	}
+	return nil, ErrOrgInvitationNotFound
+}
+
+func (s *orgRepoStub) AcceptInvitation(ctx context.Context, inv *OrganizationInvitation, member *OrganizationMember) error {
+	if _, ok := s.members[member.UserID]; ok {
+		return ErrOrgMemberExists
+	}
+	cp := *member
+	s.members[member.UserID] = &cp
+	return nil
+}
+
+func (s *orgRepoStub) MemberUsage(ctx context.Context, orgID int64, startTime, endTime time.Time) ([]OrganizationMemberUsage, error) {
+	return s.usage, nil
+}
+
+func newTestOrganizationService(repo *orgRepoStub, users ...*User) *OrganizationService {
+	userRepo := &authUserRepoStub{users: map[int64]*User{}}
+	for _, u := range users {
+		userRepo.users[u.ID] = u
+	}
+	return NewOrganizationService(repo, userRepo, nil, nil, nil, nil, nil, nil)
+}
+
+func TestOrganizationService_RoleRules(t *testing.T) {
+	ctx := context.Background()
+	repo := newOrgRepoStub(&Organization{ID: 1, Status: StatusActive, OwnerID: 1},
+		&OrganizationMember{UserID: 1, Role: OrgRoleOwner},
+		&OrganizationMember{UserID: 2, Role: OrgRoleAdmin},
+		&OrganizationMember{UserID: 3, Role: OrgRoleMember},
+		&OrganizationMember{UserID: 4, Role: OrgRoleMember},
+	)
+	svc := newTestOrganizationService(repo)
+	admin, member := OrgRoleAdmin, OrgRoleMember
+	owner := OrgRoleOwner
+
+	// 只有 owner 可以授予 admin
+	_, err := svc.UpdateMember(ctx, 2, 1, 3, &UpdateOrgMemberInput{Role: &admin})
+	require.ErrorIs(t, err, ErrOrgPermissionDenied)
+	_, err = svc.UpdateMember(ctx, 1, 1, 3, &UpdateOrgMemberInput{Role: &owner})
+	require.ErrorIs(t, err, ErrOrgInvalidRole)
+
+	// admin 不能管理其他 admin，任何人都不能修改 owner
+	_, err = svc.UpdateMember(ctx, 1, 1, 3, &UpdateOrgMemberInput{Role: &admin})
+	require.NoError(t, err)
+	_, err = svc.UpdateMember(ctx, 2, 1, 3, &UpdateOrgMemberInput{Role: &member})
+	require.ErrorIs(t, err, ErrOrgPermissionDenied)
+	require.ErrorIs(t, svc.RemoveMember(ctx, 2, 1, 1), ErrOrgOwnerImmutable)
+	require.ErrorIs(t, svc.RemoveMember(ctx, 1, 1, 1), ErrOrgOwnerImmutable)
+
+	// 普通成员不能管理他人，但可以自行退出
+	require.ErrorIs(t, svc.RemoveMember(ctx, 4, 1, 3), ErrOrgPermissionDenied)
+	require.NoError(t, svc.RemoveMember(ctx, 4, 1, 4))
+	require.NotContains(t, repo.members, int64(4))
+
+	// 花费上限：传 0 表示取消限制
+	limit := 25.0
+	updated, err := svc.UpdateMember(ctx, 2, 1, 4, &UpdateOrgMemberInput{MonthlyLimitUSD: &limit})
+	require.ErrorIs(t, err, ErrOrgMemberNotFound)
+	require.Nil(t, updated)
+	repo.members[4] = &OrganizationMember{OrganizationID: 1, UserID: 4, Role: OrgRoleMember}
+	updated, err = svc.UpdateMember(ctx, 2, 1, 4, &UpdateOrgMemberInput{MonthlyLimitUSD: &limit})
+	require.NoError(t, err)
+	require.Equal(t, 25.0, *updated.MonthlyLimitUSD)
+	zero := 0.0
+	updated, err = svc.UpdateMember(ctx, 2, 1, 4, &UpdateOrgMemberInput{MonthlyLimitUSD: &zero})
+	require.NoError(t, err)
+	require.Nil(t, updated.MonthlyLimitUSD)
+
+	// 非成员看不到组织
+	_, _, err = svc.Get(ctx, 99, 1)
+	require.ErrorIs(t, err, ErrOrganizationNotFound)
+}
+
+func TestOrganizationService_AcceptInvitation(t *testing.T) {
+	ctx := context.Background()
+	repo := newOrgRepoStub(&Organization{ID: 1, Status: StatusActive, OwnerID: 1},
+		&OrganizationMember{UserID: 1, Role: OrgRoleOwner},
+	)
+	svc := newTestOrganizationService(repo,
+		&User{ID: 1, Email: "owner@example.com"},
+		&User{ID: 2, Email: "invitee@example.com"},
+		&User{ID: 3, Email: "other@example.com"},
+	)
+
+	inv, err := svc.Invite(ctx, 1, 1, " Invitee@Example.com ", OrgRoleMember)
+	require.NoError(t, err)
+	require.Equal(t, "invitee@example.com", inv.Email)
+	require.Len(t, inv.TokenHash, 64)
+
+	// 直接写入已知 Token 的哈希，模拟邮件中的邀请码
+	const token = "test-invitation-token"
+	repo.invitations[0].TokenHash = hashOrgInvitationToken(token)
+
+	_, err = svc.AcceptInvitation(ctx, 3, token)
+	require.ErrorIs(t, err, ErrOrgInvitationEmailMismatch)
+
+	member, err := svc.AcceptInvitation(ctx, 2, token)
+	require.NoError(t, err)
+	require.Equal(t, OrgRoleMember, member.Role)
+	require.Contains(t, repo.members, int64(2))
+
+	_, err = svc.AcceptInvitation(ctx, 2, "wrong-token")
+	require.ErrorIs(t, err, ErrOrgInvitationNotFound)
+
+	// 过期邀请不可接受
+	repo.invitations[0].ExpiresAt = time.Now().Add(-time.Minute)
+	_, err = svc.AcceptInvitation(ctx, 2, token)
+	require.ErrorIs(t, err, ErrOrgInvitationNotFound)
+
+	// 已是成员时不能再次邀请
+	_, err = svc.Invite(ctx, 1, 1, "invitee@example.com", OrgRoleMember)
+	require.ErrorIs(t, err, ErrOrgMemberExists)
+}
+
+func TestOrganizationService_CheckApiKeyAccess(t *testing.T) {
+	ctx := context.Background()
+	limit := 10.0
+	org := &Organization{ID: 1, Status: StatusActive, OwnerID: 1, Balance: 50}
+	repo := newOrgRepoStub(org,
+		&OrganizationMember{UserID: 1, Role: OrgRoleOwner},
+		&OrganizationMember{UserID: 2, Role: OrgRoleMember, MonthlyLimitUSD: &limit},
+	)
+	svc := newTestOrganizationService(repo)
+	orgID := int64(1)
+
+	// 个人 Key 不受影响
+	require.NoError(t, svc.CheckApiKeyAccess(ctx, &ApiKey{UserID: 2}))
+
+	key := &ApiKey{UserID: 2, OrganizationID: &orgID}
+	require.NoError(t, svc.CheckApiKeyAccess(ctx, key))
+
+	// 当月花费达到上限；上月的花费不计入
+	repo.members[2].MonthlyUsageUSD = 10
+	repo.members[2].UsageMonth = orgUsageMonth(time.Now())
+	require.ErrorIs(t, svc.CheckApiKeyAccess(ctx, key), ErrOrgMemberSpendingLimit)
+	repo.members[2].UsageMonth = "2000-01"
+	require.NoError(t, svc.CheckApiKeyAccess(ctx, key))
+
+	// 组织余额不足；订阅分组不检查余额与花费上限
+	org.Balance = 0
+	require.ErrorIs(t, svc.CheckApiKeyAccess(ctx, key), ErrInsufficientBalance)
+	subKey := &ApiKey{UserID: 2, OrganizationID: &orgID, Group: &Group{SubscriptionType: SubscriptionTypeSubscription}}
+	require.NoError(t, svc.CheckApiKeyAccess(ctx, subKey))
+
+	// 组织停用或创建者已离开组织
+	org.Balance = 50
+	org.Status = StatusDisabled
+	require.ErrorIs(t, svc.CheckApiKeyAccess(ctx, key), ErrOrganizationInactive)
+	org.Status = StatusActive
+	delete(repo.members, 2)
+	require.ErrorIs(t, svc.CheckApiKeyAccess(ctx, key), ErrOrgMemberNotFound)
+}
+
+func TestOrganizationService_MemberUsageVisibility(t *testing.T) {
+	ctx := context.Background()
+	repo := newOrgRepoStub(&Organization{ID: 1, Status: StatusActive, OwnerID: 1},
+		&OrganizationMember{UserID: 1, Role: OrgRoleOwner},
+		&OrganizationMember{UserID: 2, Role: OrgRoleMember},
+	)
+	repo.usage = []OrganizationMemberUsage{{UserID: 1, Requests: 3}, {UserID: 2, Requests: 5}}
+	svc := newTestOrganizationService(repo)
+	now := time.Now()
+
+	all, err := svc.MemberUsage(ctx, 1, 1, now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	own, err := svc.MemberUsage(ctx, 2, 1, now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	require.Equal(t, []OrganizationMemberUsage{{UserID: 2, Requests: 5}}, own)
+
+	_, err = svc.MemberUsage(ctx, 3, 1, now.Add(-time.Hour), now)
+	require.ErrorIs(t, err, ErrOrganizationNotFound)
+}
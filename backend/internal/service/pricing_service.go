@@ -6,7 +6,6 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -15,6 +14,8 @@ import (
 	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/metrics"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/openai"
 )
 
@@ -76,12 +77,12 @@ func NewPricingService(cfg *config.Config, remoteClient PricingRemoteClient) *Pr
 func (s *PricingService) Initialize() error {
 	// 确保数据目录存在
 	if err := os.MkdirAll(s.cfg.Pricing.DataDir, 0755); err != nil {
-		log.Printf("[Pricing] Failed to create data directory: %v", err)
+		logger.Component("pricing").Warn("create data directory failed", logger.Err(err))
 	}
 
 	// 首次加载价格数据
 	if err := s.checkAndUpdatePricing(); err != nil {
-		log.Printf("[Pricing] Initial load failed, using fallback: %v", err)
+		logger.Component("pricing").Warn("initial load failed, using fallback", logger.Err(err))
 		if err := s.useFallbackPricing(); err != nil {
 			return fmt.Errorf("failed to load pricing data: %w", err)
 		}
@@ -90,7 +91,7 @@ func (s *PricingService) Initialize() error {
 	// 启动定时更新
 	s.startUpdateScheduler()
 
-	log.Printf("[Pricing] Service initialized with %d models", len(s.pricingData))
+	logger.Component("pricing").Info("service initialized", "models", len(s.pricingData))
 	return nil
 }
 
@@ -98,7 +99,7 @@ func (s *PricingService) Initialize() error {
 func (s *PricingService) Stop() {
 	close(s.stopCh)
 	s.wg.Wait()
-	log.Println("[Pricing] Service stopped")
+	logger.Component("pricing").Info("service stopped")
 }
 
 // startUpdateScheduler 启动定时更新调度器
@@ -119,7 +120,7 @@ func (s *PricingService) startUpdateScheduler() {
 			select {
 			case <-ticker.C:
 				if err := s.syncWithRemote(); err != nil {
-					log.Printf("[Pricing] Sync failed: %v", err)
+					logger.Component("pricing").Warn("sync failed", logger.Err(err))
 				}
 			case <-s.stopCh:
 				return
@@ -127,7 +128,7 @@ func (s *PricingService) startUpdateScheduler() {
 		}
 	}()
 
-	log.Printf("[Pricing] Update scheduler started (check every %v)", hashInterval)
+	logger.Component("pricing").Info("update scheduler started", "interval", hashInterval)
 }
 
 // checkAndUpdatePricing 检查并更新价格数据
@@ -136,7 +137,7 @@ func (s *PricingService) checkAndUpdatePricing() error {
 
 	// 检查本地文件是否存在
 	if _, err := os.Stat(pricingFile); os.IsNotExist(err) {
-		log.Println("[Pricing] Local pricing file not found, downloading...")
+		logger.Component("pricing").Info("local pricing file not found, downloading")
 		return s.downloadPricingData()
 	}
 
@@ -150,9 +151,9 @@ func (s *PricingService) checkAndUpdatePricing() error {
 	maxAge := time.Duration(s.cfg.Pricing.UpdateIntervalHours) * time.Hour
 
 	if fileAge > maxAge {
-		log.Printf("[Pricing] Local file is %v old, updating...", fileAge.Round(time.Hour))
+		logger.Component("pricing").Info("local pricing file outdated, updating", "age", fileAge.Round(time.Hour))
 		if err := s.downloadPricingData(); err != nil {
-			log.Printf("[Pricing] Download failed, using existing file: %v", err)
+			logger.Component("pricing").Warn("download failed, using existing file", logger.Err(err))
 		}
 	}
 
@@ -167,7 +168,7 @@ func (s *PricingService) syncWithRemote() error {
 	// 计算本地文件哈希
 	localHash, err := s.computeFileHash(pricingFile)
 	if err != nil {
-		log.Printf("[Pricing] Failed to compute local hash: %v", err)
+		logger.Component("pricing").Warn("compute local hash failed", logger.Err(err))
 		return s.downloadPricingData()
 	}
 
@@ -175,15 +176,15 @@ func (s *PricingService) syncWithRemote() error {
 	if s.cfg.Pricing.HashURL != "" {
 		remoteHash, err := s.fetchRemoteHash()
 		if err != nil {
-			log.Printf("[Pricing] Failed to fetch remote hash: %v", err)
+			logger.Component("pricing").Warn("fetch remote hash failed", logger.Err(err))
 			return nil // 哈希获取失败不影响正常使用
 		}
 
 		if remoteHash != localHash {
-			log.Println("[Pricing] Remote hash differs, downloading new version...")
+			logger.Component("pricing").Info("remote hash differs, downloading new version")
 			return s.downloadPricingData()
 		}
-		log.Println("[Pricing] Hash check passed, no update needed")
+		logger.Component("pricing").Debug("hash check passed, no update needed")
 		return nil
 	}
 
@@ -197,7 +198,7 @@ func (s *PricingService) syncWithRemote() error {
 	maxAge := time.Duration(s.cfg.Pricing.UpdateIntervalHours) * time.Hour
 
 	if fileAge > maxAge {
-		log.Printf("[Pricing] File is %v old, downloading...", fileAge.Round(time.Hour))
+		logger.Component("pricing").Info("pricing file outdated, downloading", "age", fileAge.Round(time.Hour))
 		return s.downloadPricingData()
 	}
 
@@ -206,7 +207,7 @@ func (s *PricingService) syncWithRemote() error {
 
 // downloadPricingData 从远程下载价格数据
 func (s *PricingService) downloadPricingData() error {
-	log.Printf("[Pricing] Downloading from %s", s.cfg.Pricing.RemoteURL)
+	logger.Component("pricing").Info("downloading pricing data", "url", s.cfg.Pricing.RemoteURL)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -225,7 +226,7 @@ func (s *PricingService) downloadPricingData() error {
 	// 保存到本地文件
 	pricingFile := s.getPricingFilePath()
 	if err := os.WriteFile(pricingFile, body, 0644); err != nil {
-		log.Printf("[Pricing] Failed to save file: %v", err)
+		logger.Component("pricing").Warn("save pricing file failed", logger.Err(err))
 	}
 
 	// 保存哈希
@@ -233,7 +234,7 @@ func (s *PricingService) downloadPricingData() error {
 	hashStr := hex.EncodeToString(hash[:])
 	hashFile := s.getHashFilePath()
 	if err := os.WriteFile(hashFile, []byte(hashStr+"\n"), 0644); err != nil {
-		log.Printf("[Pricing] Failed to save hash: %v", err)
+		logger.Component("pricing").Warn("save pricing hash failed", logger.Err(err))
 	}
 
 	// 更新内存数据
@@ -242,8 +243,9 @@ func (s *PricingService) downloadPricingData() error {
 	s.lastUpdated = time.Now()
 	s.localHash = hashStr
 	s.mu.Unlock()
+	registerPricingModels(data)
 
-	log.Printf("[Pricing] Downloaded %d models successfully", len(data))
+	logger.Component("pricing").Info("pricing data downloaded", "models", len(data))
 	return nil
 }
 
@@ -299,7 +301,7 @@ func (s *PricingService) parsePricingData(body []byte) (map[string]*LiteLLMModel
 	}
 
 	if skipped > 0 {
-		log.Printf("[Pricing] Skipped %d invalid entries", skipped)
+		logger.Component("pricing").Debug("skipped invalid pricing entries", "count", skipped)
 	}
 
 	if len(result) == 0 {
@@ -338,10 +340,21 @@ func (s *PricingService) loadPricingData(filePath string) error {
 	}
 	s.mu.Unlock()
 
-	log.Printf("[Pricing] Loaded %d models from %s", len(pricingData), filePath)
+	registerPricingModels(pricingData)
+
+	logger.Component("pricing").Info("pricing data loaded", "models", len(pricingData), "path", filePath)
 	return nil
 }
 
+// registerPricingModels 价格表中的模型可作为指标标签
+func registerPricingModels(data map[string]*LiteLLMModelPricing) {
+	models := make([]string, 0, len(data))
+	for model := range data {
+		models = append(models, model)
+	}
+	metrics.RegisterKnownModels(models...)
+}
+
 // useFallbackPricing 使用回退价格文件
 func (s *PricingService) useFallbackPricing() error {
 	fallbackFile := s.cfg.Pricing.FallbackFile
@@ -350,7 +363,7 @@ func (s *PricingService) useFallbackPricing() error {
 		return fmt.Errorf("fallback file not found: %s", fallbackFile)
 	}
 
-	log.Printf("[Pricing] Using fallback file: %s", fallbackFile)
+	logger.Component("pricing").Info("using fallback pricing file", "path", fallbackFile)
 
 	// 复制到数据目录
 	data, err := os.ReadFile(fallbackFile)
@@ -360,7 +373,7 @@ func (s *PricingService) useFallbackPricing() error {
 
 	pricingFile := s.getPricingFilePath()
 	if err := os.WriteFile(pricingFile, data, 0644); err != nil {
-		log.Printf("[Pricing] Failed to copy fallback: %v", err)
+		logger.Component("pricing").Warn("copy fallback pricing file failed", logger.Err(err))
 	}
 
 	return s.loadPricingData(fallbackFile)
@@ -580,7 +593,7 @@ func (s *PricingService) matchByModelFamily(model string) *LiteLLMModelPricing {
 		for key, pricing := range s.pricingData {
 			keyLower := strings.ToLower(key)
 			if strings.Contains(keyLower, pattern) {
-				log.Printf("[Pricing] Fuzzy matched %s -> %s", model, key)
+				logger.Component("pricing").Debug("fuzzy matched model pricing", "model", model, "matched", key)
 				return pricing
 			}
 		}
@@ -603,7 +616,7 @@ func (s *PricingService) matchOpenAIModel(model string) *LiteLLMModelPricing {
 
 	for _, variant := range variants {
 		if pricing, ok := s.pricingData[variant]; ok {
-			log.Printf("[Pricing] OpenAI fallback matched %s -> %s", model, variant)
+			logger.Component("pricing").Debug("openai fallback matched model pricing", "model", model, "matched", variant)
 			return pricing
 		}
 	}
@@ -611,7 +624,7 @@ func (s *PricingService) matchOpenAIModel(model string) *LiteLLMModelPricing {
 	// 最终回退到 DefaultTestModel
 	defaultModel := strings.ToLower(openai.DefaultTestModel)
 	if pricing, ok := s.pricingData[defaultModel]; ok {
-		log.Printf("[Pricing] OpenAI fallback to default model %s -> %s", model, defaultModel)
+		logger.Component("pricing").Debug("openai fallback to default model pricing", "model", model, "matched", defaultModel)
 		return pricing
 	}
 
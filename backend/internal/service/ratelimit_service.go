@@ -2,25 +2,27 @@ package service
 
 import (
 	"context"
-	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 )
 
 // RateLimitService 处理限流和过载状态管理
 type RateLimitService struct {
-	accountRepo AccountRepository
-	cfg         *config.Config
+	accountRepo    AccountRepository
+	cfg            *config.Config
+	webhookService *WebhookService
 }
 
 // NewRateLimitService 创建RateLimitService实例
-func NewRateLimitService(accountRepo AccountRepository, cfg *config.Config) *RateLimitService {
+func NewRateLimitService(accountRepo AccountRepository, cfg *config.Config, webhookService *WebhookService) *RateLimitService {
 	return &RateLimitService{
-		accountRepo: accountRepo,
-		cfg:         cfg,
+		accountRepo:    accountRepo,
+		cfg:            cfg,
+		webhookService: webhookService,
 	}
 }
 
@@ -30,7 +32,7 @@ func (s *RateLimitService) HandleUpstreamError(ctx context.Context, account *Acc
 	// apikey 类型账号：检查自定义错误码配置
 	// 如果启用且错误码不在列表中，则不处理（不停止调度、不标记限流/过载）
 	if !account.ShouldHandleErrorCode(statusCode) {
-		log.Printf("Account %d: error %d skipped (not in custom error codes)", account.ID, statusCode)
+		logger.FromContext(ctx).Info("upstream error skipped: not in custom error codes", "account_id", account.ID, "status", statusCode)
 		return false
 	}
 
@@ -52,7 +54,7 @@ func (s *RateLimitService) HandleUpstreamError(ctx context.Context, account *Acc
 	default:
 		// 其他5xx错误：记录但不停止调度
 		if statusCode >= 500 {
-			log.Printf("Account %d received upstream error %d", account.ID, statusCode)
+			logger.FromContext(ctx).Warn("upstream error", "account_id", account.ID, "status", statusCode)
 		}
 		return false
 	}
@@ -61,10 +63,11 @@ func (s *RateLimitService) HandleUpstreamError(ctx context.Context, account *Acc
 // handleAuthError 处理认证类错误(401/403)，停止账号调度
 func (s *RateLimitService) handleAuthError(ctx context.Context, account *Account, errorMsg string) {
 	if err := s.accountRepo.SetError(ctx, account.ID, errorMsg); err != nil {
-		log.Printf("SetError failed for account %d: %v", account.ID, err)
+		logger.FromContext(ctx).Error("set account error failed", "account_id", account.ID, logger.Err(err))
 		return
 	}
-	log.Printf("Account %d disabled due to auth error: %s", account.ID, errorMsg)
+	logger.FromContext(ctx).Warn("account disabled due to auth error", "account_id", account.ID, "reason", errorMsg)
+	s.webhookService.NotifyAccountError(ctx, account, errorMsg)
 }
 
 // handle429 处理429限流错误
@@ -76,19 +79,23 @@ func (s *RateLimitService) handle429(ctx context.Context, account *Account, head
 		// 没有重置时间，使用默认5分钟
 		resetAt := time.Now().Add(5 * time.Minute)
 		if err := s.accountRepo.SetRateLimited(ctx, account.ID, resetAt); err != nil {
-			log.Printf("SetRateLimited failed for account %d: %v", account.ID, err)
+			logger.FromContext(ctx).Error("set account rate limited failed", "account_id", account.ID, logger.Err(err))
+			return
 		}
+		s.webhookService.NotifyAccountRateLimited(ctx, account, resetAt)
 		return
 	}
 
 	// 解析Unix时间戳
 	ts, err := strconv.ParseInt(resetTimestamp, 10, 64)
 	if err != nil {
-		log.Printf("Parse reset timestamp failed: %v", err)
+		logger.FromContext(ctx).Warn("parse rate limit reset timestamp failed", "account_id", account.ID, logger.Err(err))
 		resetAt := time.Now().Add(5 * time.Minute)
 		if err := s.accountRepo.SetRateLimited(ctx, account.ID, resetAt); err != nil {
-			log.Printf("SetRateLimited failed for account %d: %v", account.ID, err)
+			logger.FromContext(ctx).Error("set account rate limited failed", "account_id", account.ID, logger.Err(err))
+			return
 		}
+		s.webhookService.NotifyAccountRateLimited(ctx, account, resetAt)
 		return
 	}
 
@@ -96,7 +103,7 @@ func (s *RateLimitService) handle429(ctx context.Context, account *Account, head
 
 	// 标记限流状态
 	if err := s.accountRepo.SetRateLimited(ctx, account.ID, resetAt); err != nil {
-		log.Printf("SetRateLimited failed for account %d: %v", account.ID, err)
+		logger.FromContext(ctx).Error("set account rate limited failed", "account_id", account.ID, logger.Err(err))
 		return
 	}
 
@@ -104,10 +111,11 @@ func (s *RateLimitService) handle429(ctx context.Context, account *Account, head
 	windowEnd := resetAt
 	windowStart := resetAt.Add(-5 * time.Hour)
 	if err := s.accountRepo.UpdateSessionWindow(ctx, account.ID, &windowStart, &windowEnd, "rejected"); err != nil {
-		log.Printf("UpdateSessionWindow failed for account %d: %v", account.ID, err)
+		logger.FromContext(ctx).Error("update session window failed", "account_id", account.ID, logger.Err(err))
 	}
 
-	log.Printf("Account %d rate limited until %v", account.ID, resetAt)
+	logger.FromContext(ctx).Warn("account rate limited", "account_id", account.ID, "until", resetAt)
+	s.webhookService.NotifyAccountRateLimited(ctx, account, resetAt)
 }
 
 // handle529 处理529过载错误
@@ -120,11 +128,12 @@ func (s *RateLimitService) handle529(ctx context.Context, account *Account) {
 
 	until := time.Now().Add(time.Duration(cooldownMinutes) * time.Minute)
 	if err := s.accountRepo.SetOverloaded(ctx, account.ID, until); err != nil {
-		log.Printf("SetOverloaded failed for account %d: %v", account.ID, err)
+		logger.FromContext(ctx).Error("set account overloaded failed", "account_id", account.ID, logger.Err(err))
 		return
 	}
 
-	log.Printf("Account %d overloaded until %v", account.ID, until)
+	logger.FromContext(ctx).Warn("account overloaded", "account_id", account.ID, "until", until)
+	s.webhookService.NotifyAccountOverloaded(ctx, account, until)
 }
 
 // UpdateSessionWindow 从成功响应更新5h窗口状态
@@ -147,17 +156,17 @@ func (s *RateLimitService) UpdateSessionWindow(ctx context.Context, account *Acc
 		end := start.Add(5 * time.Hour)
 		windowStart = &start
 		windowEnd = &end
-		log.Printf("Account %d: initializing 5h window from %v to %v (status: %s)", account.ID, start, end, status)
+		logger.FromContext(ctx).Info("initializing 5h session window", "account_id", account.ID, "start", start, "end", end, "window_status", status)
 	}
 
 	if err := s.accountRepo.UpdateSessionWindow(ctx, account.ID, windowStart, windowEnd, status); err != nil {
-		log.Printf("UpdateSessionWindow failed for account %d: %v", account.ID, err)
+		logger.FromContext(ctx).Error("update session window failed", "account_id", account.ID, logger.Err(err))
 	}
 
 	// 如果状态为allowed且之前有限流，说明窗口已重置，清除限流状态
 	if status == "allowed" && account.IsRateLimited() {
 		if err := s.accountRepo.ClearRateLimit(ctx, account.ID); err != nil {
-			log.Printf("ClearRateLimit failed for account %d: %v", account.ID, err)
+			logger.FromContext(ctx).Error("clear account rate limit failed", "account_id", account.ID, logger.Err(err))
 		}
 	}
 }
@@ -72,6 +72,7 @@ type RedeemService struct {
 	subscriptionService *SubscriptionService
 	cache               RedeemCache
 	billingCacheService *BillingCacheService
+	balanceTxRepo       BalanceTransactionRepository
 }
 
 // NewRedeemService 创建兑换码服务实例
@@ -81,6 +82,7 @@ func NewRedeemService(
 	subscriptionService *SubscriptionService,
 	cache RedeemCache,
 	billingCacheService *BillingCacheService,
+	balanceTxRepo BalanceTransactionRepository,
 ) *RedeemService {
 	return &RedeemService{
 		redeemRepo:          redeemRepo,
@@ -88,6 +90,7 @@ func NewRedeemService(
 		subscriptionService: subscriptionService,
 		cache:               cache,
 		billingCacheService: billingCacheService,
+		balanceTxRepo:       balanceTxRepo,
 	}
 }
 
@@ -260,8 +263,14 @@ func (s *RedeemService) Redeem(ctx context.Context, userID int64, code string) (
 	// 执行兑换逻辑（兑换码已被锁定，此时可安全操作）
 	switch redeemCode.Type {
 	case RedeemTypeBalance:
-		// 增加用户余额
-		if err := s.userRepo.UpdateBalance(ctx, userID, redeemCode.Value); err != nil {
+		// 增加用户余额（同事务写入余额流水）
+		credit := &BalanceTransaction{
+			UserID:       userID,
+			Type:         BalanceTxTypeRedeem,
+			Amount:       redeemCode.Value,
+			RedeemCodeID: &redeemCode.ID,
+		}
+		if err := s.balanceTxRepo.Apply(ctx, credit, true); err != nil {
 			return nil, fmt.Errorf("update user balance: %w", err)
 		}
 		// 失效余额缓存
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+)
+
+// RequestCapture 一次网关请求的抓取记录，用于排查上游问题
+type RequestCapture struct {
+	ID        int64
+	UserID    int64
+	ApiKeyID  int64
+	AccountID *int64 // 未发出上游请求时为 nil
+	Platform  string
+	Model     string
+	RequestID string
+	Method    string
+	Path      string
+
+	// 客户端请求（已脱敏）
+	ClientHeaders map[string]string
+	ClientBody    string
+
+	// 改写后的上游请求（模型映射、系统提示词注入、user_id 改写之后）
+	UpstreamURL     string
+	UpstreamHeaders map[string]string
+	UpstreamBody    string
+
+	// 上游原始响应，流式请求为 SSE 原文
+	ResponseStatus  int
+	ResponseHeaders map[string]string
+	ResponseBody    string
+
+	// 请求体或响应体超过 capture.max_body_bytes 时被截断
+	RequestTruncated  bool
+	ResponseTruncated bool
+
+	DurationMs int64
+	CreatedAt  time.Time
+}
+
+// sensitiveCaptureHeaders 抓取时需要脱敏的请求/响应头（小写）
+var sensitiveCaptureHeaders = map[string]struct{}{
+	"authorization":       {},
+	"proxy-authorization": {},
+	"x-api-key":           {},
+	"x-goog-api-key":      {},
+	"cookie":              {},
+	"set-cookie":          {},
+}
+
+// sanitizeCaptureHeaders 将请求头转换为 map 并脱敏认证信息
+func sanitizeCaptureHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for key, values := range h {
+		if _, ok := sensitiveCaptureHeaders[strings.ToLower(key)]; ok {
+			out[key] = logger.Redacted
+			continue
+		}
+		out[key] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// sanitizeCaptureBody 脱敏并截断抓取内容，返回是否被截断。
+// Postgres text 不接受 NUL 与非法 UTF-8，一并清理。
+func sanitizeCaptureBody(body []byte, maxBytes int) (string, bool) {
+	truncated := len(body) > maxBytes
+	if truncated {
+		body = body[:maxBytes]
+	}
+	s := strings.ToValidUTF8(string(body), "")
+	s = strings.ReplaceAll(s, "\x00", "")
+	return logger.RedactString(s), truncated
+}
+
+type captureContextKey struct{}
+
+// Capture 单个网关请求的抓取状态，随请求 context 传递。
+// 只有 API Key 开启抓取或上游账号开启抓取时才会落库。
+type Capture struct {
+	mu sync.Mutex
+
+	record        RequestCapture
+	apiKeyEnabled bool
+	maxBytes      int
+	startedAt     time.Time
+
+	upstreamRecorded bool
+	response         *captureBuffer
+}
+
+// captureFromContext 获取请求上的抓取状态，未开启时返回 nil
+func captureFromContext(ctx context.Context) *Capture {
+	capture, _ := ctx.Value(captureContextKey{}).(*Capture)
+	return capture
+}
+
+// recordUpstreamRequest 记录一次上游尝试的请求，账号与 API Key 均未开启抓取时返回 false。
+// 失败重试/切换账号时，后一次尝试会覆盖前一次。
+func (c *Capture) recordUpstreamRequest(req *http.Request, account *Account) bool {
+	if !c.apiKeyEnabled && !account.CaptureEnabled {
+		return false
+	}
+
+	var body []byte
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			body, _ = io.ReadAll(rc)
+			_ = rc.Close()
+		}
+	}
+	upstreamBody, truncated := sanitizeCaptureBody(body, c.maxBytes)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	accountID := account.ID
+	c.record.AccountID = &accountID
+	c.record.UpstreamURL = logger.RedactString(req.URL.String())
+	c.record.UpstreamHeaders = sanitizeCaptureHeaders(req.Header)
+	c.record.UpstreamBody = upstreamBody
+	c.record.RequestTruncated = c.record.RequestTruncated || truncated
+	c.record.ResponseStatus = 0
+	c.record.ResponseHeaders = nil
+	c.upstreamRecorded = true
+	c.response = &captureBuffer{max: c.maxBytes}
+	return true
+}
+
+// recordUpstreamResponse 记录上游响应头，并包装响应体以在读取时同步抓取原文
+func (c *Capture) recordUpstreamResponse(resp *http.Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.record.ResponseStatus = resp.StatusCode
+	c.record.ResponseHeaders = sanitizeCaptureHeaders(resp.Header)
+	resp.Body = &captureReadCloser{ReadCloser: resp.Body, capture: c, buf: c.response}
+}
+
+// snapshot 返回待落库的记录，无需落库时返回 false
+func (c *Capture) snapshot() (RequestCapture, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.apiKeyEnabled && !c.upstreamRecorded {
+		return RequestCapture{}, false
+	}
+	record := c.record
+	if c.response != nil {
+		record.ResponseBody, record.ResponseTruncated = sanitizeCaptureBody(c.response.Bytes(), c.maxBytes)
+		record.ResponseTruncated = record.ResponseTruncated || c.response.truncated
+	}
+	record.DurationMs = time.Since(c.startedAt).Milliseconds()
+	return record, true
+}
+
+// captureBuffer 有上限的缓冲区，超出部分丢弃
+type captureBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *captureBuffer) write(p []byte) {
+	if remaining := b.max - b.Len(); remaining < len(p) {
+		p = p[:max(remaining, 0)]
+		b.truncated = true
+	}
+	b.Write(p)
+}
+
+// captureReadCloser 读取上游响应体的同时写入抓取缓冲区
+type captureReadCloser struct {
+	io.ReadCloser
+	capture *Capture
+	buf     *captureBuffer
+}
+
+func (r *captureReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.capture.mu.Lock()
+		r.buf.write(p[:n])
+		r.capture.mu.Unlock()
+	}
+	return n, err
+}
@@ -0,0 +1,85 @@
+//go:build unit
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+type captureUpstreamStub struct {
+	HTTPUpstream
+	body string
+}
+
+func (s *captureUpstreamStub) Do(req *http.Request, proxyURL string) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(strings.NewReader(s.body)),
+	}, nil
+}
+
+func TestSanitizeCapture(t *testing.T) {
+	headers := sanitizeCaptureHeaders(http.Header{
+		"Authorization":     []string{"Bearer sk-secret"},
+		"X-Api-Key":         []string{"sk-secret"},
+		"Anthropic-Version": []string{"2023-06-01"},
+	})
+	require.Equal(t, logger.Redacted, headers["Authorization"])
+	require.Equal(t, logger.Redacted, headers["X-Api-Key"])
+	require.Equal(t, "2023-06-01", headers["Anthropic-Version"])
+
+	body, truncated := sanitizeCaptureBody([]byte("abc\x00def"), 5)
+	require.True(t, truncated)
+	require.Equal(t, "abcd", body)
+
+	body, truncated = sanitizeCaptureBody([]byte(`{"auth":"Bearer abc.def"}`), 1024)
+	require.False(t, truncated)
+	require.NotContains(t, body, "abc.def")
+}
+
+func TestCapture_RecordsUpstreamAttempt(t *testing.T) {
+	newCtx := func(apiKeyEnabled bool) (context.Context, *Capture) {
+		capture := &Capture{apiKeyEnabled: apiKeyEnabled, maxBytes: 16}
+		return context.WithValue(context.Background(), captureContextKey{}, capture), capture
+	}
+	upstream := &captureUpstreamStub{body: "data: {\"type\":\"message_start\"}\n\n"}
+	account := &Account{ID: 9, Platform: PlatformAnthropic}
+
+	send := func(ctx context.Context) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.anthropic.com/v1/messages", bytes.NewReader([]byte(`{"model":"x"}`)))
+		require.NoError(t, err)
+		req.Header.Set("x-api-key", "sk-upstream")
+		resp, err := doUpstreamAttempt(ctx, upstream, req, "", account, 1)
+		require.NoError(t, err)
+		_, _ = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	// API Key 与账号均未开启时不记录
+	ctx, capture := newCtx(false)
+	send(ctx)
+	_, ok := capture.snapshot()
+	require.False(t, ok)
+
+	// 账号开启抓取
+	account.CaptureEnabled = true
+	ctx, capture = newCtx(false)
+	send(ctx)
+	record, ok := capture.snapshot()
+	require.True(t, ok)
+	require.Equal(t, int64(9), *record.AccountID)
+	require.Equal(t, `{"model":"x"}`, record.UpstreamBody)
+	require.Equal(t, logger.Redacted, record.UpstreamHeaders["X-Api-Key"])
+	require.Equal(t, http.StatusOK, record.ResponseStatus)
+	require.Equal(t, upstream.body[:16], record.ResponseBody)
+	require.True(t, record.ResponseTruncated)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// defaultResponseCacheTTL 分组未设置 TTL 时的默认值
+	defaultResponseCacheTTL = 5 * time.Minute
+	// MaxCachedResponseBytes 超过该大小的响应不缓存
+	MaxCachedResponseBytes = 1 << 20
+)
+
+// CachedResponse 缓存的非流式响应及其用量，命中时据此写回客户端并记录使用量
+type CachedResponse struct {
+	StatusCode  int         `json:"status_code"`
+	ContentType string      `json:"content_type"`
+	Body        []byte      `json:"body"`
+	Model       string      `json:"model"`
+	AccountID   int64       `json:"account_id"` // 产生该响应的账号，命中时用于使用记录
+	Platform    string      `json:"platform"`
+	Usage       ClaudeUsage `json:"usage"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// SourceAccount 返回产生该响应的账号（仅含 ID 与平台），用于记录命中缓存的使用量
+func (r *CachedResponse) SourceAccount() *Account {
+	return &Account{ID: r.AccountID, Platform: r.Platform}
+}
+
+// ResponseCache 响应缓存存储
+type ResponseCache interface {
+	// GetResponse 未命中时返回 (nil, nil)
+	GetResponse(ctx context.Context, key string) (*CachedResponse, error)
+	SetResponse(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error
+}
+
+// ResponseCacheService 按分组缓存相同的非流式请求的响应。
+// 同一分组内的所有用户共享缓存，仅应在可接受该行为的分组（如 CI）上开启。
+// 只有显式指定 temperature 为 0 的请求才会缓存，其余请求每次都应得到独立采样的结果。
+type ResponseCacheService struct {
+	cache ResponseCache
+}
+
+// NewResponseCacheService 创建响应缓存服务
+func NewResponseCacheService(cache ResponseCache) *ResponseCacheService {
+	return &ResponseCacheService{cache: cache}
+}
+
+// temperaturePaths 各协议请求体中的 temperature 字段
+var temperaturePaths = []string{
+	"temperature",
+	"generationConfig.temperature",
+}
+
+// CacheKey 计算请求的缓存 key：分组 ID + 端点、模型与规范化请求体的 SHA-256。
+// 分组未开启缓存、请求未指定 temperature 为 0 或请求体不是合法 JSON 时返回 ""。
+func (s *ResponseCacheService) CacheKey(group *Group, endpoint, model string, body []byte) string {
+	if group == nil || !group.ResponseCacheEnabled || !isDeterministicRequest(body) {
+		return ""
+	}
+	canonical, err := canonicalizeJSON(body)
+	if err != nil {
+		return ""
+	}
+
+	h := sha256.New()
+	h.Write([]byte(endpoint))
+	h.Write([]byte{0})
+	h.Write([]byte(model))
+	h.Write([]byte{0})
+	h.Write(canonical)
+	return strconv.FormatInt(group.ID, 10) + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// isDeterministicRequest 请求是否显式指定 temperature 为 0（未指定时上游默认为随机采样）
+func isDeterministicRequest(body []byte) bool {
+	for _, path := range temperaturePaths {
+		if v := gjson.GetBytes(body, path); v.Exists() {
+			return v.Type == gjson.Number && v.Num == 0
+		}
+	}
+	return false
+}
+
+// Get 查询缓存，key 为空、未命中或出错时返回 nil
+func (s *ResponseCacheService) Get(ctx context.Context, key string) *CachedResponse {
+	if key == "" {
+		return nil
+	}
+	cached, err := s.cache.GetResponse(ctx, key)
+	if err != nil {
+		logger.FromContext(ctx).Warn("get response cache failed", logger.Err(err))
+		return nil
+	}
+	return cached
+}
+
+// Set 写入缓存，仅缓存 200 且不超过 MaxCachedResponseBytes 的响应
+func (s *ResponseCacheService) Set(ctx context.Context, key string, group *Group, resp *CachedResponse) {
+	if key == "" || group == nil || resp.StatusCode != http.StatusOK || len(resp.Body) > MaxCachedResponseBytes {
+		return
+	}
+	if resp.CreatedAt.IsZero() {
+		resp.CreatedAt = time.Now()
+	}
+	if err := s.cache.SetResponse(ctx, key, resp, group.GetResponseCacheTTL()); err != nil {
+		logger.FromContext(ctx).Warn("set response cache failed", logger.Err(err))
+	}
+}
+
+// canonicalizeJSON 规范化 JSON：去除空白并按 key 排序，数字保持原样
+func canonicalizeJSON(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// applyResponseCacheMultiplier 命中响应缓存时，在分组倍率基础上按缓存倍率折算费用，返回最终倍率
+func applyResponseCacheMultiplier(cost *CostBreakdown, group *Group, multiplier float64) float64 {
+	if group == nil {
+		return multiplier
+	}
+	m := group.ResponseCacheMultiplier
+	cost.InputCost *= m
+	cost.OutputCost *= m
+	cost.CacheCreationCost *= m
+	cost.CacheReadCost *= m
+	cost.TotalCost *= m
+	cost.ActualCost *= m
+	return multiplier * m
+}
@@ -0,0 +1,88 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type responseCacheStub struct {
+	entries map[string]*CachedResponse
+	ttl     time.Duration
+}
+
+func (s *responseCacheStub) GetResponse(ctx context.Context, key string) (*CachedResponse, error) {
+	return s.entries[key], nil
+}
+
+func (s *responseCacheStub) SetResponse(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error {
+	s.entries[key] = resp
+	s.ttl = ttl
+	return nil
+}
+
+func TestResponseCacheService_CacheKey(t *testing.T) {
+	svc := NewResponseCacheService(&responseCacheStub{entries: map[string]*CachedResponse{}})
+	group := &Group{ID: 7, ResponseCacheEnabled: true}
+
+	key := svc.CacheKey(group, "/v1/messages", "claude-3", []byte(`{"model":"claude-3","max_tokens":1024,"temperature":0,"messages":[{"role":"user","content":"hi"}]}`))
+	require.NotEmpty(t, key)
+	require.Equal(t, "7:", key[:2])
+
+	// key 顺序与空白不影响缓存 key
+	same := svc.CacheKey(group, "/v1/messages", "claude-3", []byte("{\n  \"messages\": [{\"content\": \"hi\", \"role\": \"user\"}],\n  \"max_tokens\": 1024,\n  \"temperature\": 0,\n  \"model\": \"claude-3\"\n}"))
+	require.Equal(t, key, same)
+
+	require.NotEqual(t, key, svc.CacheKey(group, "/v1/messages", "claude-3-opus", []byte(`{"model":"claude-3","max_tokens":1024,"temperature":0,"messages":[{"role":"user","content":"hi"}]}`)))
+	require.NotEqual(t, key, svc.CacheKey(group, "/v1/messages", "claude-3", []byte(`{"model":"claude-3","max_tokens":1025,"temperature":0,"messages":[{"role":"user","content":"hi"}]}`)))
+	require.NotEqual(t, key, svc.CacheKey(&Group{ID: 8, ResponseCacheEnabled: true}, "/v1/messages", "claude-3", []byte(`{"model":"claude-3","max_tokens":1024,"temperature":0,"messages":[{"role":"user","content":"hi"}]}`)))
+
+	require.Empty(t, svc.CacheKey(&Group{ID: 7}, "/v1/messages", "claude-3", []byte(`{}`)))
+	require.Empty(t, svc.CacheKey(nil, "/v1/messages", "claude-3", []byte(`{}`)))
+	require.Empty(t, svc.CacheKey(group, "/v1/messages", "claude-3", []byte(`not json`)))
+
+	// 只缓存 temperature 为 0 的请求
+	require.Empty(t, svc.CacheKey(group, "/v1/messages", "claude-3", []byte(`{"model":"claude-3","messages":[]}`)))
+	require.Empty(t, svc.CacheKey(group, "/v1/messages", "claude-3", []byte(`{"model":"claude-3","temperature":0.7,"messages":[]}`)))
+	require.Empty(t, svc.CacheKey(group, "/v1/messages", "claude-3", []byte(`{"model":"claude-3","temperature":"0","messages":[]}`)))
+	require.NotEmpty(t, svc.CacheKey(group, "/v1beta/models/gemini-2.5-pro:generateContent", "gemini-2.5-pro", []byte(`{"contents":[],"generationConfig":{"temperature":0}}`)))
+}
+
+func TestResponseCacheService_SetOnlyCachesSuccess(t *testing.T) {
+	stub := &responseCacheStub{entries: map[string]*CachedResponse{}}
+	svc := NewResponseCacheService(stub)
+	group := &Group{ID: 1, ResponseCacheEnabled: true, ResponseCacheTTLSeconds: 60}
+	ctx := context.Background()
+
+	svc.Set(ctx, "1:bad", group, &CachedResponse{StatusCode: http.StatusBadRequest, Body: []byte("{}")})
+	require.Nil(t, svc.Get(ctx, "1:bad"))
+
+	svc.Set(ctx, "1:big", group, &CachedResponse{StatusCode: http.StatusOK, Body: make([]byte, MaxCachedResponseBytes+1)})
+	require.Nil(t, svc.Get(ctx, "1:big"))
+
+	svc.Set(ctx, "1:ok", group, &CachedResponse{StatusCode: http.StatusOK, Body: []byte("{}")})
+	cached := svc.Get(ctx, "1:ok")
+	require.NotNil(t, cached)
+	require.False(t, cached.CreatedAt.IsZero())
+	require.Equal(t, time.Minute, stub.ttl)
+
+	require.Nil(t, svc.Get(ctx, ""))
+}
+
+func TestApplyResponseCacheMultiplier(t *testing.T) {
+	cost := &CostBreakdown{InputCost: 1, OutputCost: 2, TotalCost: 3, ActualCost: 3}
+	multiplier := applyResponseCacheMultiplier(cost, &Group{ResponseCacheMultiplier: 0.5}, 2)
+	require.Equal(t, 1.0, multiplier)
+	require.Equal(t, 0.5, cost.InputCost)
+	require.Equal(t, 1.5, cost.TotalCost)
+	require.Equal(t, 1.5, cost.ActualCost)
+
+	free := &CostBreakdown{TotalCost: 3, ActualCost: 3}
+	require.Equal(t, 0.0, applyResponseCacheMultiplier(free, &Group{ResponseCacheMultiplier: 0}, 1))
+	require.Zero(t, free.ActualCost)
+}
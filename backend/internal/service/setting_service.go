@@ -2,11 +2,11 @@ package service
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/Wei-Shaw/sub2api/internal/config"
 	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
@@ -64,6 +64,11 @@ func (s *SettingService) GetPublicSettings(ctx context.Context) (*PublicSettings
 		SettingKeyApiBaseUrl,
 		SettingKeyContactInfo,
 		SettingKeyDocUrl,
+		SettingKeyOidcEnabled,
+		SettingKeyOidcProviderName,
+		SettingKeyOidcIssuer,
+		SettingKeyOidcClientId,
+		SettingKeyOidcRedirectUrl,
 	}
 
 	settings, err := s.settingRepo.GetMultiple(ctx, keys)
@@ -71,6 +76,7 @@ func (s *SettingService) GetPublicSettings(ctx context.Context) (*PublicSettings
 		return nil, fmt.Errorf("get public settings: %w", err)
 	}
 
+	oidcConfig := parseOidcConfig(settings)
 	return &PublicSettings{
 		RegistrationEnabled: settings[SettingKeyRegistrationEnabled] == "true",
 		EmailVerifyEnabled:  settings[SettingKeyEmailVerifyEnabled] == "true",
@@ -82,6 +88,8 @@ func (s *SettingService) GetPublicSettings(ctx context.Context) (*PublicSettings
 		ApiBaseUrl:          settings[SettingKeyApiBaseUrl],
 		ContactInfo:         settings[SettingKeyContactInfo],
 		DocUrl:              settings[SettingKeyDocUrl],
+		OidcEnabled:         oidcConfig.Ready(),
+		OidcProviderName:    oidcConfig.ProviderName,
 	}, nil
 }
 
@@ -123,6 +131,43 @@ func (s *SettingService) UpdateSettings(ctx context.Context, settings *SystemSet
 	updates[SettingKeyDefaultConcurrency] = strconv.Itoa(settings.DefaultConcurrency)
 	updates[SettingKeyDefaultBalance] = strconv.FormatFloat(settings.DefaultBalance, 'f', 8, 64)
 
+	// Webhook 通知设置（只有非空才更新密钥）
+	updates[SettingKeyWebhookEnabled] = strconv.FormatBool(settings.WebhookEnabled)
+	updates[SettingKeyWebhookUrl] = settings.WebhookUrl
+	if settings.WebhookSecret != "" {
+		updates[SettingKeyWebhookSecret] = settings.WebhookSecret
+	}
+	updates[SettingKeyWebhookFormat] = settings.WebhookFormat
+	updates[SettingKeyWebhookEvents] = strings.Join(settings.WebhookEvents, ",")
+	updates[SettingKeyWebhookBalanceThreshold] = strconv.FormatFloat(settings.WebhookBalanceThreshold, 'f', 8, 64)
+	updates[SettingKeyWebhookSubscriptionExpiryDays] = strconv.Itoa(settings.WebhookSubscriptionExpiryDays)
+
+	// 二次验证
+	updates[SettingKeyAdmin2FARequired] = strconv.FormatBool(settings.Admin2FARequired)
+
+	// OIDC 单点登录（只有非空才更新密钥）
+	updates[SettingKeyOidcEnabled] = strconv.FormatBool(settings.OidcEnabled)
+	updates[SettingKeyOidcProviderName] = settings.OidcProviderName
+	updates[SettingKeyOidcIssuer] = strings.TrimRight(settings.OidcIssuer, "/")
+	updates[SettingKeyOidcClientId] = settings.OidcClientId
+	if settings.OidcClientSecret != "" {
+		updates[SettingKeyOidcClientSecret] = settings.OidcClientSecret
+	}
+	updates[SettingKeyOidcRedirectUrl] = settings.OidcRedirectUrl
+	updates[SettingKeyOidcScopes] = settings.OidcScopes
+	updates[SettingKeyOidcAutoProvision] = strconv.FormatBool(settings.OidcAutoProvision)
+	updates[SettingKeyOidcGroupsClaim] = settings.OidcGroupsClaim
+	updates[SettingKeyOidcAdminGroups] = strings.Join(settings.OidcAdminGroups, ",")
+	groupMapping := settings.OidcGroupMapping
+	if groupMapping == nil {
+		groupMapping = map[string][]int64{}
+	}
+	mappingJSON, err := json.Marshal(groupMapping)
+	if err != nil {
+		return fmt.Errorf("marshal oidc group mapping: %w", err)
+	}
+	updates[SettingKeyOidcGroupMapping] = string(mappingJSON)
+
 	return s.settingRepo.SetMultiple(ctx, updates)
 }
 
@@ -145,6 +190,16 @@ func (s *SettingService) IsEmailVerifyEnabled(ctx context.Context) bool {
 	return value == "true"
 }
 
+// IsAdmin2FARequired 检查管理员是否必须启用二次验证
+func (s *SettingService) IsAdmin2FARequired(ctx context.Context) bool {
+	value, err := s.settingRepo.GetValue(ctx, SettingKeyAdmin2FARequired)
+	if err != nil {
+		// 默认要求
+		return true
+	}
+	return value != "false"
+}
+
 // GetSiteName 获取网站名称
 func (s *SettingService) GetSiteName(ctx context.Context) string {
 	value, err := s.settingRepo.GetValue(ctx, SettingKeySiteName)
@@ -245,13 +300,164 @@ func (s *SettingService) parseSettings(settings map[string]string) *SystemSettin
 		result.DefaultBalance = s.cfg.Default.UserBalance
 	}
 
+	// Webhook 通知设置
+	webhook := parseWebhookConfig(settings)
+	result.WebhookEnabled = webhook.Enabled
+	result.WebhookUrl = webhook.Url
+	result.WebhookFormat = webhook.Format
+	result.WebhookEvents = webhook.Events
+	result.WebhookBalanceThreshold = webhook.BalanceThreshold
+	result.WebhookSubscriptionExpiryDays = webhook.SubscriptionExpiryDays
+
+	// 二次验证（未设置时默认要求管理员启用）
+	result.Admin2FARequired = settings[SettingKeyAdmin2FARequired] != "false"
+
+	// OIDC 单点登录
+	oidcConfig := parseOidcConfig(settings)
+	result.OidcEnabled = oidcConfig.Enabled
+	result.OidcProviderName = oidcConfig.ProviderName
+	result.OidcIssuer = oidcConfig.Issuer
+	result.OidcClientId = oidcConfig.ClientID
+	result.OidcRedirectUrl = oidcConfig.RedirectUrl
+	result.OidcScopes = oidcConfig.Scopes
+	result.OidcAutoProvision = oidcConfig.AutoProvision
+	result.OidcGroupsClaim = oidcConfig.GroupsClaim
+	result.OidcAdminGroups = oidcConfig.AdminGroups
+	result.OidcGroupMapping = oidcConfig.GroupMapping
+
 	// 敏感信息直接返回，方便测试连接时使用
 	result.SmtpPassword = settings[SettingKeySmtpPassword]
 	result.TurnstileSecretKey = settings[SettingKeyTurnstileSecretKey]
+	result.WebhookSecret = webhook.Secret
+	result.OidcClientSecret = oidcConfig.ClientSecret
 
 	return result
 }
 
+// GetWebhookConfig 获取 Webhook 通知配置
+func (s *SettingService) GetWebhookConfig(ctx context.Context) (*WebhookConfig, error) {
+	settings, err := s.settingRepo.GetMultiple(ctx, []string{
+		SettingKeyWebhookEnabled,
+		SettingKeyWebhookUrl,
+		SettingKeyWebhookSecret,
+		SettingKeyWebhookFormat,
+		SettingKeyWebhookEvents,
+		SettingKeyWebhookBalanceThreshold,
+		SettingKeyWebhookSubscriptionExpiryDays,
+		SettingKeySiteName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get webhook settings: %w", err)
+	}
+
+	cfg := parseWebhookConfig(settings)
+	cfg.SiteName = s.getStringOrDefault(settings, SettingKeySiteName, "Sub2API")
+	return cfg, nil
+}
+
+// GetOIDCConfig 获取 OIDC 单点登录配置
+func (s *SettingService) GetOIDCConfig(ctx context.Context) (*OIDCConfig, error) {
+	settings, err := s.settingRepo.GetMultiple(ctx, []string{
+		SettingKeyOidcEnabled,
+		SettingKeyOidcProviderName,
+		SettingKeyOidcIssuer,
+		SettingKeyOidcClientId,
+		SettingKeyOidcClientSecret,
+		SettingKeyOidcRedirectUrl,
+		SettingKeyOidcScopes,
+		SettingKeyOidcAutoProvision,
+		SettingKeyOidcGroupsClaim,
+		SettingKeyOidcAdminGroups,
+		SettingKeyOidcGroupMapping,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get oidc settings: %w", err)
+	}
+	return parseOidcConfig(settings), nil
+}
+
+// notificationTemplateKeys 返回模板主题与正文的设置键
+func notificationTemplateKeys(notificationType string) (subjectKey, bodyKey string) {
+	prefix := SettingKeyUserNotifyTemplatePrefix + notificationType
+	return prefix + "_subject", prefix + "_body"
+}
+
+// GetNotificationSettings 获取用户通知设置，未自定义的模板使用内置默认模板
+func (s *SettingService) GetNotificationSettings(ctx context.Context) (*NotificationSettings, error) {
+	keys := []string{SettingKeyUserNotifyEnabled, SettingKeyUserNotifySubscriptionExpiryDays}
+	for _, t := range NotificationTypes {
+		subjectKey, bodyKey := notificationTemplateKeys(t)
+		keys = append(keys, subjectKey, bodyKey)
+	}
+	settings, err := s.settingRepo.GetMultiple(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("get notification settings: %w", err)
+	}
+
+	result := &NotificationSettings{
+		Enabled:                settings[SettingKeyUserNotifyEnabled] == "true",
+		SubscriptionExpiryDays: 3,
+		Templates:              make([]NotificationTemplate, 0, len(NotificationTypes)),
+	}
+	if v, err := strconv.Atoi(settings[SettingKeyUserNotifySubscriptionExpiryDays]); err == nil && v > 0 {
+		result.SubscriptionExpiryDays = v
+	}
+	for _, t := range NotificationTypes {
+		tpl := DefaultNotificationTemplate(t)
+		subjectKey, bodyKey := notificationTemplateKeys(t)
+		if v := settings[subjectKey]; v != "" {
+			tpl.Subject = v
+		}
+		if v := settings[bodyKey]; v != "" {
+			tpl.Body = v
+		}
+		result.Templates = append(result.Templates, tpl)
+	}
+	return result, nil
+}
+
+// UpdateNotificationSettings 更新用户通知设置（模板为空时恢复默认模板）
+func (s *SettingService) UpdateNotificationSettings(ctx context.Context, settings *NotificationSettings) error {
+	updates := map[string]string{
+		SettingKeyUserNotifyEnabled:                strconv.FormatBool(settings.Enabled),
+		SettingKeyUserNotifySubscriptionExpiryDays: strconv.Itoa(settings.SubscriptionExpiryDays),
+	}
+	for _, t := range settings.Templates {
+		subjectKey, bodyKey := notificationTemplateKeys(t.Type)
+		updates[subjectKey] = t.Subject
+		updates[bodyKey] = t.Body
+	}
+	return s.settingRepo.SetMultiple(ctx, updates)
+}
+
+// parseWebhookConfig 解析 Webhook 设置
+func parseWebhookConfig(settings map[string]string) *WebhookConfig {
+	cfg := &WebhookConfig{
+		Enabled: settings[SettingKeyWebhookEnabled] == "true",
+		Url:     settings[SettingKeyWebhookUrl],
+		Secret:  settings[SettingKeyWebhookSecret],
+		Format:  settings[SettingKeyWebhookFormat],
+		Events:  []string{},
+	}
+	if !IsValidWebhookFormat(cfg.Format) {
+		cfg.Format = WebhookFormatGeneric
+	}
+	for _, e := range strings.Split(settings[SettingKeyWebhookEvents], ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			cfg.Events = append(cfg.Events, e)
+		}
+	}
+	if v, err := strconv.ParseFloat(settings[SettingKeyWebhookBalanceThreshold], 64); err == nil {
+		cfg.BalanceThreshold = v
+	}
+	if v, err := strconv.Atoi(settings[SettingKeyWebhookSubscriptionExpiryDays]); err == nil {
+		cfg.SubscriptionExpiryDays = v
+	} else {
+		cfg.SubscriptionExpiryDays = 3
+	}
+	return cfg
+}
+
 // getStringOrDefault 获取字符串值或默认值
 func (s *SettingService) getStringOrDefault(settings map[string]string, key, defaultValue string) string {
 	if value, ok := settings[key]; ok && value != "" {
@@ -278,25 +484,26 @@ func (s *SettingService) GetTurnstileSecretKey(ctx context.Context) string {
 	return value
 }
 
-// GenerateAdminApiKey 生成新的管理员 API Key
-func (s *SettingService) GenerateAdminApiKey(ctx context.Context) (string, error) {
-	// 生成 32 字节随机数 = 64 位十六进制字符
-	bytes := make([]byte, 32)
-	if _, err := rand.Read(bytes); err != nil {
-		return "", fmt.Errorf("generate random bytes: %w", err)
+// GetAdminApiKeyRole 获取旧版 Admin API Key 对应的管理角色。
+// 未设置时为超级管理员（兼容升级前生成的 Key），读取失败时降级为只读角色。
+func (s *SettingService) GetAdminApiKeyRole(ctx context.Context) string {
+	role, err := s.settingRepo.GetValue(ctx, SettingKeyAdminApiKeyRole)
+	if err != nil {
+		if errors.Is(err, ErrSettingNotFound) {
+			return RoleAdmin
+		}
+		return RoleViewer
 	}
-
-	key := AdminApiKeyPrefix + hex.EncodeToString(bytes)
-
-	// 存储到 settings 表
-	if err := s.settingRepo.Set(ctx, SettingKeyAdminApiKey, key); err != nil {
-		return "", fmt.Errorf("save admin api key: %w", err)
+	if role == "" {
+		return RoleAdmin
 	}
-
-	return key, nil
+	if !IsAdminRole(role) {
+		return RoleViewer
+	}
+	return role
 }
 
-// GetAdminApiKeyStatus 获取管理员 API Key 状态
+// GetAdminApiKeyStatus 获取旧版管理员 API Key 状态（尚未迁移到 AdminApiKeyService 时）
 // 返回脱敏的 key、是否存在、错误
 func (s *SettingService) GetAdminApiKeyStatus(ctx context.Context) (maskedKey string, exists bool, err error) {
 	key, err := s.settingRepo.GetValue(ctx, SettingKeyAdminApiKey)
@@ -309,18 +516,10 @@ func (s *SettingService) GetAdminApiKeyStatus(ctx context.Context) (maskedKey st
 	if key == "" {
 		return "", false, nil
 	}
-
-	// 脱敏：显示前 10 位和后 4 位
-	if len(key) > 14 {
-		maskedKey = key[:10] + "..." + key[len(key)-4:]
-	} else {
-		maskedKey = key
-	}
-
-	return maskedKey, true, nil
+	return maskAdminApiKey(key), true, nil
 }
 
-// GetAdminApiKey 获取完整的管理员 API Key（仅供内部验证使用）
+// GetAdminApiKey 获取旧版完整的管理员 API Key（仅供迁移使用）
 // 如果未配置返回空字符串和 nil 错误，只有数据库错误时才返回 error
 func (s *SettingService) GetAdminApiKey(ctx context.Context) (string, error) {
 	key, err := s.settingRepo.GetValue(ctx, SettingKeyAdminApiKey)
@@ -333,7 +532,10 @@ func (s *SettingService) GetAdminApiKey(ctx context.Context) (string, error) {
 	return key, nil
 }
 
-// DeleteAdminApiKey 删除管理员 API Key
+// DeleteAdminApiKey 删除旧版管理员 API Key
 func (s *SettingService) DeleteAdminApiKey(ctx context.Context) error {
-	return s.settingRepo.Delete(ctx, SettingKeyAdminApiKey)
+	if err := s.settingRepo.Delete(ctx, SettingKeyAdminApiKey); err != nil {
+		return err
+	}
+	return s.settingRepo.Delete(ctx, SettingKeyAdminApiKeyRole)
 }
@@ -25,6 +25,28 @@ type SystemSettings struct {
 
 	DefaultConcurrency int
 	DefaultBalance     float64
+
+	WebhookEnabled                bool
+	WebhookUrl                    string
+	WebhookSecret                 string
+	WebhookFormat                 string
+	WebhookEvents                 []string
+	WebhookBalanceThreshold       float64
+	WebhookSubscriptionExpiryDays int
+
+	Admin2FARequired bool
+
+	OidcEnabled       bool
+	OidcProviderName  string
+	OidcIssuer        string
+	OidcClientId      string
+	OidcClientSecret  string
+	OidcRedirectUrl   string
+	OidcScopes        string
+	OidcAutoProvision bool
+	OidcGroupsClaim   string
+	OidcAdminGroups   []string
+	OidcGroupMapping  map[string][]int64
 }
 
 type PublicSettings struct {
@@ -38,5 +60,7 @@ type PublicSettings struct {
 	ApiBaseUrl          string
 	ContactInfo         string
 	DocUrl              string
+	OidcEnabled         bool
+	OidcProviderName    string
 	Version             string
 }
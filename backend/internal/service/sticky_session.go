@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StickySessionBinding 缓存中的一条粘性会话绑定
+type StickySessionBinding struct {
+	// Key 粘性会话 key（不含缓存前缀），格式见 stickySessionKey
+	Key       string
+	AccountID int64
+	ExpiresAt time.Time
+}
+
+// StickySession 粘性会话（管理端展示）
+type StickySession struct {
+	Platform    string
+	GroupID     int64 // 0 表示未分组
+	ApiKeyID    int64
+	SessionHash string
+	AccountID   int64
+	ExpiresAt   time.Time
+}
+
+// scopeSessionHash 将会话 hash 限定到 API Key，避免不同用户发送相同系统提示词时共享同一粘性账号
+func scopeSessionHash(apiKeyID int64, sessionHash string) string {
+	if sessionHash == "" {
+		return ""
+	}
+	return strconv.FormatInt(apiKeyID, 10) + ":" + sessionHash
+}
+
+// stickySessionKey 构造粘性会话 key：{platform}:{groupID}:{apiKeyID}:{hash}
+// scopedHash 为 scopeSessionHash 的结果；未分组时 groupID 记为 0
+func stickySessionKey(platform string, groupID *int64, scopedHash string) string {
+	var gid int64
+	if groupID != nil {
+		gid = *groupID
+	}
+	return fmt.Sprintf("%s:%d:%s", platform, gid, scopedHash)
+}
+
+// parseStickySessionKey 解析 stickySessionKey 生成的 key
+func parseStickySessionKey(key string) (StickySession, bool) {
+	parts := strings.SplitN(key, ":", 4)
+	if len(parts) != 4 {
+		return StickySession{}, false
+	}
+	groupID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return StickySession{}, false
+	}
+	apiKeyID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return StickySession{}, false
+	}
+	return StickySession{
+		Platform:    parts[0],
+		GroupID:     groupID,
+		ApiKeyID:    apiKeyID,
+		SessionHash: parts[3],
+	}, true
+}
+
+// isStickyAccountEligible 粘性账号必须仍然可调度、平台一致、属于请求的分组并支持请求的模型，
+// 否则重新选择账号（新的绑定会覆盖旧绑定）
+func isStickyAccountEligible(account *Account, platform string, groupID *int64, requestedModel string) bool {
+	if !account.IsSchedulable() || account.Platform != platform {
+		return false
+	}
+	if groupID != nil && !slices.Contains(account.GroupIDs, *groupID) {
+		return false
+	}
+	return requestedModel == "" || account.IsModelSupported(requestedModel)
+}
+
+// StickySessionService 粘性会话管理
+type StickySessionService struct {
+	cache       GatewayCache
+	accountRepo AccountRepository
+}
+
+// NewStickySessionService 创建粘性会话管理服务
+func NewStickySessionService(cache GatewayCache, accountRepo AccountRepository) *StickySessionService {
+	return &StickySessionService{
+		cache:       cache,
+		accountRepo: accountRepo,
+	}
+}
+
+// ListByAccount 列出绑定到账号的粘性会话
+func (s *StickySessionService) ListByAccount(ctx context.Context, accountID int64) ([]StickySession, error) {
+	if _, err := s.accountRepo.GetByID(ctx, accountID); err != nil {
+		return nil, err
+	}
+	bindings, err := s.cache.ListAccountSessions(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("list sticky sessions: %w", err)
+	}
+
+	sessions := make([]StickySession, 0, len(bindings))
+	for _, b := range bindings {
+		session, ok := parseStickySessionKey(b.Key)
+		if !ok {
+			continue
+		}
+		session.AccountID = b.AccountID
+		session.ExpiresAt = b.ExpiresAt
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// ClearByAccount 清除绑定到账号的全部粘性会话，返回清除数量
+func (s *StickySessionService) ClearByAccount(ctx context.Context, accountID int64) (int, error) {
+	if _, err := s.accountRepo.GetByID(ctx, accountID); err != nil {
+		return 0, err
+	}
+	cleared, err := s.cache.DeleteAccountSessions(ctx, accountID)
+	if err != nil {
+		return 0, fmt.Errorf("clear sticky sessions: %w", err)
+	}
+	return cleared, nil
+}
@@ -0,0 +1,47 @@
+//go:build unit
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStickySessionKey_RoundTrip(t *testing.T) {
+	require.Equal(t, "", scopeSessionHash(7, ""))
+
+	groupID := int64(3)
+	key := stickySessionKey(PlatformGemini, &groupID, scopeSessionHash(7, "abc"))
+	require.Equal(t, "gemini:3:7:abc", key)
+
+	session, ok := parseStickySessionKey(key)
+	require.True(t, ok)
+	require.Equal(t, StickySession{Platform: PlatformGemini, GroupID: 3, ApiKeyID: 7, SessionHash: "abc"}, session)
+
+	require.Equal(t, "anthropic:0:7:abc", stickySessionKey(PlatformAnthropic, nil, scopeSessionHash(7, "abc")))
+
+	_, ok = parseStickySessionKey("legacy-hash")
+	require.False(t, ok)
+	_, ok = parseStickySessionKey("openai:x:7:abc")
+	require.False(t, ok)
+}
+
+func TestIsStickyAccountEligible(t *testing.T) {
+	groupID := int64(3)
+	otherGroupID := int64(4)
+	account := &Account{
+		Platform:    PlatformAnthropic,
+		Status:      StatusActive,
+		Schedulable: true,
+		GroupIDs:    []int64{3},
+	}
+
+	require.True(t, isStickyAccountEligible(account, PlatformAnthropic, &groupID, "claude-sonnet-4-20250514"))
+	require.True(t, isStickyAccountEligible(account, PlatformAnthropic, nil, ""))
+	require.False(t, isStickyAccountEligible(account, PlatformAnthropic, &otherGroupID, ""))
+	require.False(t, isStickyAccountEligible(account, PlatformOpenAI, &groupID, ""))
+
+	account.Schedulable = false
+	require.False(t, isStickyAccountEligible(account, PlatformAnthropic, &groupID, ""))
+}
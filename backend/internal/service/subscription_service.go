@@ -2,11 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 	"github.com/Wei-Shaw/sub2api/internal/pkg/pagination"
 )
 
@@ -26,14 +27,16 @@ type SubscriptionService struct {
 	groupRepo           GroupRepository
 	userSubRepo         UserSubscriptionRepository
 	billingCacheService *BillingCacheService
+	notificationService *UserNotificationService
 }
 
 // NewSubscriptionService 创建订阅服务
-func NewSubscriptionService(groupRepo GroupRepository, userSubRepo UserSubscriptionRepository, billingCacheService *BillingCacheService) *SubscriptionService {
+func NewSubscriptionService(groupRepo GroupRepository, userSubRepo UserSubscriptionRepository, billingCacheService *BillingCacheService, notificationService *UserNotificationService) *SubscriptionService {
 	return &SubscriptionService{
 		groupRepo:           groupRepo,
 		userSubRepo:         userSubRepo,
 		billingCacheService: billingCacheService,
+		notificationService: notificationService,
 	}
 }
 
@@ -44,6 +47,9 @@ type AssignSubscriptionInput struct {
 	ValidityDays int
 	AssignedBy   int64
 	Notes        string
+
+	// OrganizationID 分配为组织共享订阅（UserID 需为组织 owner）
+	OrganizationID *int64
 }
 
 // AssignSubscription 分配订阅给用户（不允许重复分配）
@@ -58,7 +64,7 @@ func (s *SubscriptionService) AssignSubscription(ctx context.Context, input *Ass
 	}
 
 	// 检查是否已存在订阅
-	exists, err := s.userSubRepo.ExistsByUserIDAndGroupID(ctx, input.UserID, input.GroupID)
+	exists, err := s.subscriptionExists(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -70,6 +76,10 @@ func (s *SubscriptionService) AssignSubscription(ctx context.Context, input *Ass
 	if err != nil {
 		return nil, err
 	}
+	// 组织订阅不使用按用户维度的订阅缓存
+	if input.OrganizationID != nil {
+		return sub, nil
+	}
 
 	// 失效订阅缓存
 	if s.billingCacheService != nil {
@@ -145,7 +155,7 @@ func (s *SubscriptionService) AssignOrExtendSubscription(ctx context.Context, in
 			}
 			newNotes += input.Notes
 			if err := s.userSubRepo.UpdateNotes(ctx, existingSub.ID, newNotes); err != nil {
-				log.Printf("update subscription notes failed: sub_id=%d err=%v", existingSub.ID, err)
+				logger.FromContext(ctx).Warn("update subscription notes failed", "subscription_id", existingSub.ID, logger.Err(err))
 			}
 		}
 
@@ -183,6 +193,21 @@ func (s *SubscriptionService) AssignOrExtendSubscription(ctx context.Context, in
 	return sub, false, nil // false 表示是新建
 }
 
+// subscriptionExists 检查用户（或组织）在该分组下是否已有订阅
+func (s *SubscriptionService) subscriptionExists(ctx context.Context, input *AssignSubscriptionInput) (bool, error) {
+	if input.OrganizationID == nil {
+		return s.userSubRepo.ExistsByUserIDAndGroupID(ctx, input.UserID, input.GroupID)
+	}
+	_, err := s.userSubRepo.GetActiveByOrganizationIDAndGroupID(ctx, *input.OrganizationID, input.GroupID)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, ErrSubscriptionNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
 // createSubscription 创建新订阅（内部方法）
 func (s *SubscriptionService) createSubscription(ctx context.Context, input *AssignSubscriptionInput) (*UserSubscription, error) {
 	validityDays := input.ValidityDays
@@ -192,15 +217,16 @@ func (s *SubscriptionService) createSubscription(ctx context.Context, input *Ass
 
 	now := time.Now()
 	sub := &UserSubscription{
-		UserID:     input.UserID,
-		GroupID:    input.GroupID,
-		StartsAt:   now,
-		ExpiresAt:  now.AddDate(0, 0, validityDays),
-		Status:     SubscriptionStatusActive,
-		AssignedAt: now,
-		Notes:      input.Notes,
-		CreatedAt:  now,
-		UpdatedAt:  now,
+		UserID:         input.UserID,
+		GroupID:        input.GroupID,
+		OrganizationID: input.OrganizationID,
+		StartsAt:       now,
+		ExpiresAt:      now.AddDate(0, 0, validityDays),
+		Status:         SubscriptionStatusActive,
+		AssignedAt:     now,
+		Notes:          input.Notes,
+		CreatedAt:      now,
+		UpdatedAt:      now,
 	}
 	// 只有当 AssignedBy > 0 时才设置（0 表示系统分配，如兑换码）
 	if input.AssignedBy > 0 {
@@ -331,6 +357,30 @@ func (s *SubscriptionService) GetActiveSubscription(ctx context.Context, userID,
 	return sub, nil
 }
 
+// GetActiveSubscriptionForApiKey 获取 API Key 所属分组的有效订阅（组织 Key 使用组织共享订阅）
+func (s *SubscriptionService) GetActiveSubscriptionForApiKey(ctx context.Context, apiKey *ApiKey) (*UserSubscription, error) {
+	if apiKey.GroupID == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	if !apiKey.IsOrganizationOwned() {
+		return s.GetActiveSubscription(ctx, apiKey.UserID, *apiKey.GroupID)
+	}
+	sub, err := s.userSubRepo.GetActiveByOrganizationIDAndGroupID(ctx, *apiKey.OrganizationID, *apiKey.GroupID)
+	if err != nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	return sub, nil
+}
+
+// ListOrganizationSubscriptions 获取组织的共享订阅
+func (s *SubscriptionService) ListOrganizationSubscriptions(ctx context.Context, orgID int64) ([]UserSubscription, error) {
+	subs, err := s.userSubRepo.ListByOrganizationID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
 // ListUserSubscriptions 获取用户的所有订阅
 func (s *SubscriptionService) ListUserSubscriptions(ctx context.Context, userID int64) ([]UserSubscription, error) {
 	subs, err := s.userSubRepo.ListByUserID(ctx, userID)
@@ -459,12 +509,15 @@ func (s *SubscriptionService) CheckAndResetWindows(ctx context.Context, sub *Use
 // CheckUsageLimits 检查使用限额（返回错误如果超限）
 func (s *SubscriptionService) CheckUsageLimits(ctx context.Context, sub *UserSubscription, group *Group, additionalCost float64) error {
 	if !sub.CheckDailyLimit(group, additionalCost) {
+		s.notificationService.NotifyQuotaReached(ctx, sub, group, "daily", *group.DailyLimitUSD, sub.DailyResetTime())
 		return ErrDailyLimitExceeded
 	}
 	if !sub.CheckWeeklyLimit(group, additionalCost) {
+		s.notificationService.NotifyQuotaReached(ctx, sub, group, "weekly", *group.WeeklyLimitUSD, sub.WeeklyResetTime())
 		return ErrWeeklyLimitExceeded
 	}
 	if !sub.CheckMonthlyLimit(group, additionalCost) {
+		s.notificationService.NotifyQuotaReached(ctx, sub, group, "monthly", *group.MonthlyLimitUSD, sub.MonthlyResetTime())
 		return ErrMonthlyLimitExceeded
 	}
 	return nil
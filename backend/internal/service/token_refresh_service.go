@@ -3,19 +3,20 @@ package service
 import (
 	"context"
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
 	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 )
 
 // TokenRefreshService OAuth token自动刷新服务
 // 定期检查并刷新即将过期的token
 type TokenRefreshService struct {
-	accountRepo AccountRepository
-	refreshers  []TokenRefresher
-	cfg         *config.TokenRefreshConfig
+	accountRepo    AccountRepository
+	refreshers     []TokenRefresher
+	cfg            *config.TokenRefreshConfig
+	webhookService *WebhookService
 
 	stopCh chan struct{}
 	wg     sync.WaitGroup
@@ -28,11 +29,13 @@ func NewTokenRefreshService(
 	openaiOAuthService *OpenAIOAuthService,
 	geminiOAuthService *GeminiOAuthService,
 	cfg *config.Config,
+	webhookService *WebhookService,
 ) *TokenRefreshService {
 	s := &TokenRefreshService{
-		accountRepo: accountRepo,
-		cfg:         &cfg.TokenRefresh,
-		stopCh:      make(chan struct{}),
+		accountRepo:    accountRepo,
+		cfg:            &cfg.TokenRefresh,
+		webhookService: webhookService,
+		stopCh:         make(chan struct{}),
 	}
 
 	// 注册平台特定的刷新器
@@ -48,22 +51,22 @@ func NewTokenRefreshService(
 // Start 启动后台刷新服务
 func (s *TokenRefreshService) Start() {
 	if !s.cfg.Enabled {
-		log.Println("[TokenRefresh] Service disabled by configuration")
+		logger.Component("token_refresh").Info("service disabled by configuration")
 		return
 	}
 
 	s.wg.Add(1)
 	go s.refreshLoop()
 
-	log.Printf("[TokenRefresh] Service started (check every %d minutes, refresh %v hours before expiry)",
-		s.cfg.CheckIntervalMinutes, s.cfg.RefreshBeforeExpiryHours)
+	logger.Component("token_refresh").Info("service started",
+		"check_interval_minutes", s.cfg.CheckIntervalMinutes, "refresh_before_expiry_hours", s.cfg.RefreshBeforeExpiryHours)
 }
 
 // Stop 停止刷新服务
 func (s *TokenRefreshService) Stop() {
 	close(s.stopCh)
 	s.wg.Wait()
-	log.Println("[TokenRefresh] Service stopped")
+	logger.Component("token_refresh").Info("service stopped")
 }
 
 // refreshLoop 刷新循环
@@ -102,7 +105,7 @@ func (s *TokenRefreshService) processRefresh() {
 	// 获取所有active状态的账号
 	accounts, err := s.listActiveAccounts(ctx)
 	if err != nil {
-		log.Printf("[TokenRefresh] Failed to list accounts: %v", err)
+		logger.Component("token_refresh").Error("list accounts failed", logger.Err(err))
 		return
 	}
 
@@ -124,10 +127,10 @@ func (s *TokenRefreshService) processRefresh() {
 
 			// 执行刷新
 			if err := s.refreshWithRetry(ctx, account, refresher); err != nil {
-				log.Printf("[TokenRefresh] Account %d (%s) failed: %v", account.ID, account.Name, err)
+				logger.Component("token_refresh").Error("refresh account token failed", "account_id", account.ID, "account_name", account.Name, logger.Err(err))
 				failed++
 			} else {
-				log.Printf("[TokenRefresh] Account %d (%s) refreshed successfully", account.ID, account.Name)
+				logger.Component("token_refresh").Info("account token refreshed", "account_id", account.ID, "account_name", account.Name)
 				refreshed++
 			}
 
@@ -137,7 +140,7 @@ func (s *TokenRefreshService) processRefresh() {
 	}
 
 	if refreshed > 0 || failed > 0 {
-		log.Printf("[TokenRefresh] Cycle complete: %d refreshed, %d failed", refreshed, failed)
+		logger.Component("token_refresh").Info("refresh cycle complete", "refreshed", refreshed, "failed", failed)
 	}
 }
 
@@ -163,8 +166,8 @@ func (s *TokenRefreshService) refreshWithRetry(ctx context.Context, account *Acc
 		}
 
 		lastErr = err
-		log.Printf("[TokenRefresh] Account %d attempt %d/%d failed: %v",
-			account.ID, attempt, s.cfg.MaxRetries, err)
+		logger.Component("token_refresh").Warn("refresh attempt failed",
+			"account_id", account.ID, "attempt", attempt, "max_retries", s.cfg.MaxRetries, logger.Err(err))
 
 		// 如果还有重试机会，等待后重试
 		if attempt < s.cfg.MaxRetries {
@@ -177,8 +180,9 @@ func (s *TokenRefreshService) refreshWithRetry(ctx context.Context, account *Acc
 	// 所有重试都失败，标记账号为error状态
 	errorMsg := fmt.Sprintf("Token refresh failed after %d retries: %v", s.cfg.MaxRetries, lastErr)
 	if err := s.accountRepo.SetError(ctx, account.ID, errorMsg); err != nil {
-		log.Printf("[TokenRefresh] Failed to set error status for account %d: %v", account.ID, err)
+		logger.Component("token_refresh").Error("set account error status failed", "account_id", account.ID, logger.Err(err))
 	}
+	s.webhookService.NotifyTokenRefreshFailed(ctx, account, s.cfg.MaxRetries, lastErr)
 
 	return lastErr
 }
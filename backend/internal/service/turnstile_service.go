@@ -3,9 +3,9 @@ package service
 import (
 	"context"
 	"fmt"
-	"log"
 
 	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
 )
 
 var (
@@ -46,36 +46,36 @@ func NewTurnstileService(settingService *SettingService, verifier TurnstileVerif
 func (s *TurnstileService) VerifyToken(ctx context.Context, token string, remoteIP string) error {
 	// 检查是否启用 Turnstile
 	if !s.settingService.IsTurnstileEnabled(ctx) {
-		log.Println("[Turnstile] Disabled, skipping verification")
+		logger.FromContext(ctx).Debug("turnstile disabled, skipping verification")
 		return nil
 	}
 
 	// 获取 Secret Key
 	secretKey := s.settingService.GetTurnstileSecretKey(ctx)
 	if secretKey == "" {
-		log.Println("[Turnstile] Secret key not configured")
+		logger.FromContext(ctx).Error("turnstile secret key not configured")
 		return ErrTurnstileNotConfigured
 	}
 
 	// 如果 token 为空，返回错误
 	if token == "" {
-		log.Println("[Turnstile] Token is empty")
+		logger.FromContext(ctx).Info("turnstile token is empty")
 		return ErrTurnstileVerificationFailed
 	}
 
-	log.Printf("[Turnstile] Verifying token for IP: %s", remoteIP)
+	logger.FromContext(ctx).Debug("verifying turnstile token", "client_ip", remoteIP)
 	result, err := s.verifier.VerifyToken(ctx, secretKey, token, remoteIP)
 	if err != nil {
-		log.Printf("[Turnstile] Request failed: %v", err)
+		logger.FromContext(ctx).Error("turnstile request failed", logger.Err(err))
 		return fmt.Errorf("send request: %w", err)
 	}
 
 	if !result.Success {
-		log.Printf("[Turnstile] Verification failed, error codes: %v", result.ErrorCodes)
+		logger.FromContext(ctx).Info("turnstile verification failed", "error_codes", result.ErrorCodes)
 		return ErrTurnstileVerificationFailed
 	}
 
-	log.Println("[Turnstile] Verification successful")
+	logger.FromContext(ctx).Debug("turnstile verification successful")
 	return nil
 }
 
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// 二次验证方式
+const (
+	TwoFactorMethodTOTP         = "totp"
+	TwoFactorMethodRecoveryCode = "recovery_code"
+	TwoFactorMethodWebAuthn     = "webauthn"
+)
+
+var (
+	ErrTOTPNotFound              = infraerrors.NotFound("TOTP_NOT_FOUND", "totp is not set up")
+	ErrTOTPAlreadyEnabled        = infraerrors.Conflict("TOTP_ALREADY_ENABLED", "totp is already enabled")
+	ErrTOTPNotEnabled            = infraerrors.BadRequest("TOTP_NOT_ENABLED", "totp is not enabled")
+	ErrInvalidTwoFactorCode      = infraerrors.Unauthorized("INVALID_2FA_CODE", "invalid two-factor authentication code")
+	ErrTwoFactorTooManyAttempts  = infraerrors.TooManyRequests("2FA_TOO_MANY_ATTEMPTS", "too many two-factor attempts, please try again later")
+	ErrTwoFactorRequired         = infraerrors.Forbidden("2FA_REQUIRED", "two-factor authentication is required for this account")
+	ErrTwoFactorAlreadyEnrolled  = infraerrors.Conflict("2FA_ALREADY_ENROLLED", "two-factor authentication is already set up")
+	ErrWebAuthnDisabled          = infraerrors.BadRequest("WEBAUTHN_DISABLED", "webauthn is not configured on this server")
+	ErrWebAuthnSessionExpired    = infraerrors.BadRequest("WEBAUTHN_SESSION_EXPIRED", "webauthn session expired, please try again")
+	ErrWebAuthnVerifyFailed      = infraerrors.Unauthorized("WEBAUTHN_VERIFY_FAILED", "webauthn verification failed")
+	ErrWebAuthnCredentialLimit   = infraerrors.BadRequest("WEBAUTHN_CREDENTIAL_LIMIT", "too many security keys registered")
+	ErrWebAuthnCredentialMissing = infraerrors.NotFound("WEBAUTHN_CREDENTIAL_NOT_FOUND", "security key not found")
+)
+
+// UserTOTP 用户 TOTP 配置。Enabled 为 false 表示已生成密钥但尚未确认
+type UserTOTP struct {
+	UserID  int64
+	Secret  string
+	Enabled bool
+	// 恢复码的 SHA-256 摘要，使用后移除
+	RecoveryCodes []string
+
+	EnabledAt *time.Time
+	UpdatedAt time.Time
+}
+
+// WebAuthnCredential 用户注册的安全密钥 / Passkey
+type WebAuthnCredential struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	Credential webauthn.Credential
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+// TOTPSetup 开启 TOTP 时返回给用户的密钥信息
+type TOTPSetup struct {
+	Secret string
+	URI    string
+}
+
+// TwoFactorStatus 用户二次验证状态
+type TwoFactorStatus struct {
+	Required               bool
+	TOTPEnabled            bool
+	RecoveryCodesRemaining int
+	WebAuthnAvailable      bool
+	WebAuthnCredentials    []WebAuthnCredential
+}
+
+// Enrolled 是否已启用任一二次验证方式
+func (s *TwoFactorStatus) Enrolled() bool {
+	return s.TOTPEnabled || len(s.WebAuthnCredentials) > 0
+}
+
+// Methods 可用于登录的二次验证方式
+func (s *TwoFactorStatus) Methods() []string {
+	methods := make([]string, 0, 3)
+	if s.TOTPEnabled {
+		methods = append(methods, TwoFactorMethodTOTP)
+		if s.RecoveryCodesRemaining > 0 {
+			methods = append(methods, TwoFactorMethodRecoveryCode)
+		}
+	}
+	if s.WebAuthnAvailable && len(s.WebAuthnCredentials) > 0 {
+		methods = append(methods, TwoFactorMethodWebAuthn)
+	}
+	return methods
+}
+
+type UserTOTPRepository interface {
+	GetByUserID(ctx context.Context, userID int64) (*UserTOTP, error)
+	Upsert(ctx context.Context, totp *UserTOTP) error
+	// UpdateRecoveryCodes 仅在恢复码未被并发修改时更新，返回是否更新成功
+	UpdateRecoveryCodes(ctx context.Context, userID int64, expected, codes []string) (bool, error)
+	Delete(ctx context.Context, userID int64) error
+}
+
+type WebAuthnCredentialRepository interface {
+	ListByUserID(ctx context.Context, userID int64) ([]WebAuthnCredential, error)
+	Create(ctx context.Context, credential *WebAuthnCredential) error
+	UpdateCredential(ctx context.Context, id int64, credential webauthn.Credential, lastUsedAt time.Time) error
+	Delete(ctx context.Context, userID, id int64) error
+}
+
+// TwoFactorCache 保存 WebAuthn 会话、已使用的 TOTP 时间步与尝试次数
+type TwoFactorCache interface {
+	SetWebAuthnSession(ctx context.Context, key string, session *webauthn.SessionData, ttl time.Duration) error
+	// TakeWebAuthnSession 读取并删除会话，会话只能使用一次
+	TakeWebAuthnSession(ctx context.Context, key string) (*webauthn.SessionData, error)
+	// MarkTOTPUsed 记录已使用的时间步，已使用过返回 false
+	MarkTOTPUsed(ctx context.Context, userID, counter int64, ttl time.Duration) (bool, error)
+	IncrementAttempts(ctx context.Context, userID int64, window time.Duration) (int64, error)
+}
+
+const (
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 5
+)
+
+// generateRecoveryCodes 生成明文恢复码及其摘要，明文只展示一次
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	codes = make([]string, recoveryCodeCount)
+	hashes = make([]string, recoveryCodeCount)
+	buf := make([]byte, recoveryCodeBytes)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := hex.EncodeToString(buf)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashRecoveryCode(raw)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode 忽略大小写、空格与连字符
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
+
+func hashRecoveryCode(normalized string) string {
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// webAuthnUser 适配 webauthn.User 接口
+type webAuthnUser struct {
+	user        *User
+	credentials []webauthn.Credential
+}
+
+func newWebAuthnUser(user *User, credentials []WebAuthnCredential) *webAuthnUser {
+	u := &webAuthnUser{user: user, credentials: make([]webauthn.Credential, 0, len(credentials))}
+	for _, c := range credentials {
+		u.credentials = append(u.credentials, c.Credential)
+	}
+	return u
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatInt(u.user.ID, 10))
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	if u.user.Username != "" {
+		return u.user.Username
+	}
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
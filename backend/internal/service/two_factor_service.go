@@ -0,0 +1,439 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/totp"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	totpSkew               = 1
+	totpReplayTTL          = 3 * totp.Period * time.Second
+	twoFactorAttemptWindow = 15 * time.Minute
+	twoFactorMaxAttempts   = 10
+	webAuthnSessionTTL     = 5 * time.Minute
+	maxWebAuthnCredentials = 10
+)
+
+// TwoFactorService 二次验证服务：TOTP、恢复码与 WebAuthn
+type TwoFactorService struct {
+	totpRepo       UserTOTPRepository
+	credentialRepo WebAuthnCredentialRepository
+	cache          TwoFactorCache
+	settingService *SettingService
+	webAuthn       *webauthn.WebAuthn
+}
+
+// NewTwoFactorService 创建二次验证服务，未配置 webauthn.rp_id 时只支持 TOTP
+func NewTwoFactorService(
+	totpRepo UserTOTPRepository,
+	credentialRepo WebAuthnCredentialRepository,
+	cache TwoFactorCache,
+	settingService *SettingService,
+	cfg *config.Config,
+) *TwoFactorService {
+	s := &TwoFactorService{
+		totpRepo:       totpRepo,
+		credentialRepo: credentialRepo,
+		cache:          cache,
+		settingService: settingService,
+	}
+	if cfg != nil && cfg.WebAuthn.RPID != "" {
+		wa, err := webauthn.New(&webauthn.Config{
+			RPID:          cfg.WebAuthn.RPID,
+			RPDisplayName: cfg.WebAuthn.RPDisplayName,
+			RPOrigins:     cfg.WebAuthn.RPOrigins,
+		})
+		if err != nil {
+			logger.Component("two_factor").Error("init webauthn failed, passkeys disabled", logger.Err(err))
+		} else {
+			s.webAuthn = wa
+		}
+	}
+	return s
+}
+
+// WebAuthnEnabled 服务端是否启用了 WebAuthn
+func (s *TwoFactorService) WebAuthnEnabled() bool {
+	return s != nil && s.webAuthn != nil
+}
+
+// IsRequired 该用户是否必须启用二次验证（所有管理角色）
+func (s *TwoFactorService) IsRequired(ctx context.Context, user *User) bool {
+	if s == nil || s.settingService == nil || !user.HasAdminAccess() {
+		return false
+	}
+	return s.settingService.IsAdmin2FARequired(ctx)
+}
+
+// Status 获取用户二次验证状态
+func (s *TwoFactorService) Status(ctx context.Context, user *User) (*TwoFactorStatus, error) {
+	status := &TwoFactorStatus{
+		Required:          s.IsRequired(ctx, user),
+		WebAuthnAvailable: s.WebAuthnEnabled(),
+	}
+	if s == nil {
+		return status, nil
+	}
+
+	t, err := s.totpRepo.GetByUserID(ctx, user.ID)
+	if err != nil && !errors.Is(err, ErrTOTPNotFound) {
+		return nil, fmt.Errorf("get totp: %w", err)
+	}
+	if t != nil && t.Enabled {
+		status.TOTPEnabled = true
+		status.RecoveryCodesRemaining = len(t.RecoveryCodes)
+	}
+
+	credentials, err := s.credentialRepo.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list webauthn credentials: %w", err)
+	}
+	status.WebAuthnCredentials = credentials
+	return status, nil
+}
+
+// SetupTOTP 生成新的 TOTP 密钥，需调用 EnableTOTP 确认后才生效
+func (s *TwoFactorService) SetupTOTP(ctx context.Context, user *User) (*TOTPSetup, error) {
+	existing, err := s.totpRepo.GetByUserID(ctx, user.ID)
+	if err != nil && !errors.Is(err, ErrTOTPNotFound) {
+		return nil, fmt.Errorf("get totp: %w", err)
+	}
+	if existing != nil && existing.Enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.totpRepo.Upsert(ctx, &UserTOTP{UserID: user.ID, Secret: secret}); err != nil {
+		return nil, fmt.Errorf("save totp: %w", err)
+	}
+
+	issuer := "Sub2API"
+	if s.settingService != nil {
+		issuer = s.settingService.GetSiteName(ctx)
+	}
+	return &TOTPSetup{Secret: secret, URI: totp.KeyURI(issuer, user.Email, secret)}, nil
+}
+
+// EnableTOTP 校验验证码后启用 TOTP，返回一次性展示的恢复码
+func (s *TwoFactorService) EnableTOTP(ctx context.Context, user *User, code string) ([]string, error) {
+	t, err := s.totpRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if err := s.checkAttempts(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if !s.validateTOTP(ctx, t, code) {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("generate recovery codes: %w", err)
+	}
+	now := time.Now()
+	t.Enabled = true
+	t.EnabledAt = &now
+	t.RecoveryCodes = hashes
+	if err := s.totpRepo.Upsert(ctx, t); err != nil {
+		return nil, fmt.Errorf("save totp: %w", err)
+	}
+	logger.FromContext(ctx).Info("totp enabled", "user_id", user.ID)
+	return codes, nil
+}
+
+// DisableTOTP 关闭 TOTP，需要当前密码和有效的验证码
+func (s *TwoFactorService) DisableTOTP(ctx context.Context, user *User, password, code string) error {
+	if !user.CheckPassword(password) {
+		return ErrPasswordIncorrect
+	}
+	status, err := s.Status(ctx, user)
+	if err != nil {
+		return err
+	}
+	if !status.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+	// 必须启用二次验证的用户不能移除最后一种方式
+	if status.Required && len(status.WebAuthnCredentials) == 0 {
+		return ErrTwoFactorRequired
+	}
+	if err := s.VerifyCode(ctx, user.ID, code); err != nil {
+		return err
+	}
+
+	if err := s.totpRepo.Delete(ctx, user.ID); err != nil {
+		return fmt.Errorf("delete totp: %w", err)
+	}
+	logger.FromContext(ctx).Info("totp disabled", "user_id", user.ID)
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部失效
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, user *User, code string) ([]string, error) {
+	t, err := s.totpRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, ErrTOTPNotFound) {
+			return nil, ErrTOTPNotEnabled
+		}
+		return nil, err
+	}
+	if !t.Enabled {
+		return nil, ErrTOTPNotEnabled
+	}
+	if err := s.checkAttempts(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if !s.validateTOTP(ctx, t, code) {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("generate recovery codes: %w", err)
+	}
+	t.RecoveryCodes = hashes
+	if err := s.totpRepo.Upsert(ctx, t); err != nil {
+		return nil, fmt.Errorf("save totp: %w", err)
+	}
+	return codes, nil
+}
+
+// VerifyCode 校验 TOTP 验证码或恢复码，恢复码使用后失效
+func (s *TwoFactorService) VerifyCode(ctx context.Context, userID int64, code string) error {
+	if err := s.checkAttempts(ctx, userID); err != nil {
+		return err
+	}
+
+	t, err := s.totpRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrTOTPNotFound) {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+	if !t.Enabled {
+		return ErrInvalidTwoFactorCode
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		if s.validateTOTP(ctx, t, code) {
+			return nil
+		}
+		return ErrInvalidTwoFactorCode
+	}
+	return s.consumeRecoveryCode(ctx, t, code)
+}
+
+func (s *TwoFactorService) consumeRecoveryCode(ctx context.Context, t *UserTOTP, code string) error {
+	hash := hashRecoveryCode(normalizeRecoveryCode(code))
+	idx := slices.Index(t.RecoveryCodes, hash)
+	if idx < 0 {
+		return ErrInvalidTwoFactorCode
+	}
+
+	remaining := slices.Delete(slices.Clone(t.RecoveryCodes), idx, idx+1)
+	updated, err := s.totpRepo.UpdateRecoveryCodes(ctx, t.UserID, t.RecoveryCodes, remaining)
+	if err != nil {
+		return fmt.Errorf("update recovery codes: %w", err)
+	}
+	if !updated {
+		// 并发使用了同一批恢复码
+		return ErrInvalidTwoFactorCode
+	}
+	logger.FromContext(ctx).Info("recovery code used", "user_id", t.UserID, "remaining", len(remaining))
+	return nil
+}
+
+// validateTOTP 校验 TOTP 验证码，同一时间步的验证码只能使用一次
+func (s *TwoFactorService) validateTOTP(ctx context.Context, t *UserTOTP, code string) bool {
+	counter, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return false
+	}
+	fresh, err := s.cache.MarkTOTPUsed(ctx, t.UserID, counter, totpReplayTTL)
+	if err != nil {
+		// 缓存不可用时不阻断登录
+		logger.FromContext(ctx).Warn("mark totp used failed", logger.Err(err))
+		return true
+	}
+	return fresh
+}
+
+// checkAttempts 限制单个用户的二次验证尝试次数，防止暴力破解
+func (s *TwoFactorService) checkAttempts(ctx context.Context, userID int64) error {
+	count, err := s.cache.IncrementAttempts(ctx, userID, twoFactorAttemptWindow)
+	if err != nil {
+		logger.FromContext(ctx).Warn("increment 2fa attempts failed", logger.Err(err))
+		return nil
+	}
+	if count > twoFactorMaxAttempts {
+		return ErrTwoFactorTooManyAttempts
+	}
+	return nil
+}
+
+func webAuthnSessionKey(kind string, userID int64) string {
+	return kind + ":" + strconv.FormatInt(userID, 10)
+}
+
+// BeginWebAuthnRegistration 开始注册安全密钥，返回浏览器 navigator.credentials.create 的参数
+func (s *TwoFactorService) BeginWebAuthnRegistration(ctx context.Context, user *User) (*protocol.CredentialCreation, error) {
+	if !s.WebAuthnEnabled() {
+		return nil, ErrWebAuthnDisabled
+	}
+	credentials, err := s.credentialRepo.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list webauthn credentials: %w", err)
+	}
+	if len(credentials) >= maxWebAuthnCredentials {
+		return nil, ErrWebAuthnCredentialLimit
+	}
+
+	waUser := newWebAuthnUser(user, credentials)
+	creation, session, err := s.webAuthn.BeginRegistration(waUser,
+		webauthn.WithExclusions(webauthn.Credentials(waUser.credentials).CredentialDescriptors()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("begin webauthn registration: %w", err)
+	}
+	if err := s.cache.SetWebAuthnSession(ctx, webAuthnSessionKey("register", user.ID), session, webAuthnSessionTTL); err != nil {
+		return nil, fmt.Errorf("save webauthn session: %w", err)
+	}
+	return creation, nil
+}
+
+// FinishWebAuthnRegistration 校验浏览器返回的注册结果并保存安全密钥
+func (s *TwoFactorService) FinishWebAuthnRegistration(ctx context.Context, user *User, name string, response []byte) (*WebAuthnCredential, error) {
+	if !s.WebAuthnEnabled() {
+		return nil, ErrWebAuthnDisabled
+	}
+	session, err := s.cache.TakeWebAuthnSession(ctx, webAuthnSessionKey("register", user.ID))
+	if err != nil || session == nil {
+		return nil, ErrWebAuthnSessionExpired
+	}
+	credentials, err := s.credentialRepo.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list webauthn credentials: %w", err)
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, ErrWebAuthnVerifyFailed.WithCause(err)
+	}
+	credential, err := s.webAuthn.CreateCredential(newWebAuthnUser(user, credentials), *session, parsed)
+	if err != nil {
+		return nil, ErrWebAuthnVerifyFailed.WithCause(err)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Security key " + strconv.Itoa(len(credentials)+1)
+	}
+	record := &WebAuthnCredential{UserID: user.ID, Name: name, Credential: *credential}
+	if err := s.credentialRepo.Create(ctx, record); err != nil {
+		return nil, fmt.Errorf("save webauthn credential: %w", err)
+	}
+	logger.FromContext(ctx).Info("webauthn credential registered", "user_id", user.ID, "credential_id", record.ID)
+	return record, nil
+}
+
+// DeleteWebAuthnCredential 删除安全密钥
+func (s *TwoFactorService) DeleteWebAuthnCredential(ctx context.Context, user *User, id int64) error {
+	status, err := s.Status(ctx, user)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(status.WebAuthnCredentials, func(c WebAuthnCredential) bool { return c.ID == id }) {
+		return ErrWebAuthnCredentialMissing
+	}
+	if status.Required && !status.TOTPEnabled && len(status.WebAuthnCredentials) == 1 {
+		return ErrTwoFactorRequired
+	}
+	return s.credentialRepo.Delete(ctx, user.ID, id)
+}
+
+// BeginWebAuthnLogin 开始安全密钥验证，返回 navigator.credentials.get 的参数
+func (s *TwoFactorService) BeginWebAuthnLogin(ctx context.Context, user *User) (*protocol.CredentialAssertion, error) {
+	if !s.WebAuthnEnabled() {
+		return nil, ErrWebAuthnDisabled
+	}
+	credentials, err := s.credentialRepo.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list webauthn credentials: %w", err)
+	}
+	if len(credentials) == 0 {
+		return nil, ErrWebAuthnCredentialMissing
+	}
+
+	assertion, session, err := s.webAuthn.BeginLogin(newWebAuthnUser(user, credentials))
+	if err != nil {
+		return nil, fmt.Errorf("begin webauthn login: %w", err)
+	}
+	if err := s.cache.SetWebAuthnSession(ctx, webAuthnSessionKey("login", user.ID), session, webAuthnSessionTTL); err != nil {
+		return nil, fmt.Errorf("save webauthn session: %w", err)
+	}
+	return assertion, nil
+}
+
+// FinishWebAuthnLogin 校验安全密钥签名
+func (s *TwoFactorService) FinishWebAuthnLogin(ctx context.Context, user *User, response []byte) error {
+	if !s.WebAuthnEnabled() {
+		return ErrWebAuthnDisabled
+	}
+	if err := s.checkAttempts(ctx, user.ID); err != nil {
+		return err
+	}
+	session, err := s.cache.TakeWebAuthnSession(ctx, webAuthnSessionKey("login", user.ID))
+	if err != nil || session == nil {
+		return ErrWebAuthnSessionExpired
+	}
+	credentials, err := s.credentialRepo.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("list webauthn credentials: %w", err)
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return ErrWebAuthnVerifyFailed.WithCause(err)
+	}
+	credential, err := s.webAuthn.ValidateLogin(newWebAuthnUser(user, credentials), *session, parsed)
+	if err != nil {
+		return ErrWebAuthnVerifyFailed.WithCause(err)
+	}
+	// 签名计数器回退，可能是被克隆的密钥
+	if credential.Authenticator.CloneWarning {
+		logger.FromContext(ctx).Warn("webauthn clone warning", "user_id", user.ID)
+		return ErrWebAuthnVerifyFailed
+	}
+
+	for _, c := range credentials {
+		if bytes.Equal(c.Credential.ID, credential.ID) {
+			if err := s.credentialRepo.UpdateCredential(ctx, c.ID, *credential, time.Now()); err != nil {
+				logger.FromContext(ctx).Warn("update webauthn credential failed", "credential_id", c.ID, logger.Err(err))
+			}
+			break
+		}
+	}
+	return nil
+}
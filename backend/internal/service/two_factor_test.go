@@ -0,0 +1,198 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/totp"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/require"
+)
+
+type totpRepoStub struct {
+	items map[int64]*UserTOTP
+}
+
+func (s *totpRepoStub) GetByUserID(ctx context.Context, userID int64) (*UserTOTP, error) {
+	if t, ok := s.items[userID]; ok {
+		cp := *t
+		cp.RecoveryCodes = slices.Clone(t.RecoveryCodes)
+		return &cp, nil
+	}
+	return nil, ErrTOTPNotFound
+}
+
+func (s *totpRepoStub) Upsert(ctx context.Context, t *UserTOTP) error {
+	cp := *t
+	s.items[t.UserID] = &cp
+	return nil
+}
+
+func (s *totpRepoStub) UpdateRecoveryCodes(ctx context.Context, userID int64, expected, codes []string) (bool, error) {
+	t, ok := s.items[userID]
+	if !ok || !slices.Equal(t.RecoveryCodes, expected) {
+		return false, nil
+	}
+	t.RecoveryCodes = codes
+	return true, nil
+}
+
+func (s *totpRepoStub) Delete(ctx context.Context, userID int64) error {
+	delete(s.items, userID)
+	return nil
+}
+
+type webAuthnCredentialRepoStub struct {
+	WebAuthnCredentialRepository
+}
+
+func (s *webAuthnCredentialRepoStub) ListByUserID(ctx context.Context, userID int64) ([]WebAuthnCredential, error) {
+	return nil, nil
+}
+
+type twoFactorCacheStub struct {
+	TwoFactorCache
+	used     map[int64]bool
+	attempts map[int64]int64
+}
+
+func (s *twoFactorCacheStub) MarkTOTPUsed(ctx context.Context, userID, counter int64, ttl time.Duration) (bool, error) {
+	if s.used[counter] {
+		return false, nil
+	}
+	s.used[counter] = true
+	return true, nil
+}
+
+func (s *twoFactorCacheStub) IncrementAttempts(ctx context.Context, userID int64, window time.Duration) (int64, error) {
+	s.attempts[userID]++
+	return s.attempts[userID], nil
+}
+
+func (s *twoFactorCacheStub) SetWebAuthnSession(ctx context.Context, key string, session *webauthn.SessionData, ttl time.Duration) error {
+	return nil
+}
+
+type twoFactorSettingRepoStub struct {
+	SettingRepository
+	values map[string]string
+}
+
+func (s *twoFactorSettingRepoStub) GetValue(ctx context.Context, key string) (string, error) {
+	if v, ok := s.values[key]; ok {
+		return v, nil
+	}
+	return "", ErrSettingNotFound
+}
+
+func newTestTwoFactorAuthService(t *testing.T) (*AuthService, *authUserRepoStub, *totpRepoStub) {
+	t.Helper()
+	svc, repo, _, _ := newTestAuthService(t)
+	totpRepo := &totpRepoStub{items: map[int64]*UserTOTP{}}
+	cache := &twoFactorCacheStub{used: map[int64]bool{}, attempts: map[int64]int64{}}
+	settings := NewSettingService(&twoFactorSettingRepoStub{values: map[string]string{}}, nil)
+	svc.twoFactorService = NewTwoFactorService(totpRepo, &webAuthnCredentialRepoStub{}, cache, settings, nil)
+	return svc, repo, totpRepo
+}
+
+func enableTestTOTP(t *testing.T, svc *AuthService, user *User) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+	setup, err := svc.twoFactorService.SetupTOTP(ctx, user)
+	require.NoError(t, err)
+	code, err := totp.CodeAt(setup.Secret, totp.Counter(time.Now())-1)
+	require.NoError(t, err)
+	recoveryCodes, err := svc.twoFactorService.EnableTOTP(ctx, user, code)
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, recoveryCodeCount)
+	return setup.Secret, recoveryCodes
+}
+
+func TestAuthService_LoginWithTOTP(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, totpRepo := newTestTwoFactorAuthService(t)
+	secret, recoveryCodes := enableTestTOTP(t, svc, repo.users[1])
+
+	result, err := svc.Login(ctx, "a@example.com", "old-password")
+	require.NoError(t, err)
+	require.True(t, result.RequiresTwoFactor())
+	require.Empty(t, result.Token)
+	require.False(t, result.EnrollmentRequired)
+	require.Equal(t, []string{TwoFactorMethodTOTP, TwoFactorMethodRecoveryCode}, result.TwoFactorMethods)
+
+	// 预认证 token 不能作为访问 token 使用
+	_, _, err = svc.ValidateToken(ctx, result.PreAuthToken)
+	require.ErrorIs(t, err, ErrInvalidToken)
+	_, err = svc.RefreshToken(ctx, result.PreAuthToken)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = svc.VerifyTwoFactor(ctx, result.PreAuthToken, "000000")
+	require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	code, err := totp.CodeAt(secret, totp.Counter(time.Now()))
+	require.NoError(t, err)
+	loggedIn, err := svc.VerifyTwoFactor(ctx, result.PreAuthToken, code)
+	require.NoError(t, err)
+	_, user, err := svc.ValidateToken(ctx, loggedIn.Token)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), user.ID)
+
+	// 同一验证码不能重复使用
+	_, err = svc.VerifyTwoFactor(ctx, result.PreAuthToken, code)
+	require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	// 恢复码只能使用一次
+	_, err = svc.VerifyTwoFactor(ctx, result.PreAuthToken, recoveryCodes[0])
+	require.NoError(t, err)
+	require.Len(t, totpRepo.items[1].RecoveryCodes, recoveryCodeCount-1)
+	_, err = svc.VerifyTwoFactor(ctx, result.PreAuthToken, recoveryCodes[0])
+	require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+}
+
+func TestAuthService_LoginWithoutTwoFactor(t *testing.T) {
+	svc, _, _ := newTestTwoFactorAuthService(t)
+
+	result, err := svc.Login(context.Background(), "a@example.com", "old-password")
+	require.NoError(t, err)
+	require.False(t, result.RequiresTwoFactor())
+	require.NotEmpty(t, result.Token)
+
+	// 普通访问 token 不能用于完成二次验证
+	_, err = svc.VerifyTwoFactor(context.Background(), result.Token, "123456")
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestAuthService_AdminMustEnrollTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _ := newTestTwoFactorAuthService(t)
+	repo.users[1].Role = RoleAdmin
+
+	result, err := svc.Login(ctx, "a@example.com", "old-password")
+	require.NoError(t, err)
+	require.True(t, result.RequiresTwoFactor())
+	require.True(t, result.EnrollmentRequired)
+	require.Empty(t, result.TwoFactorMethods)
+
+	setup, err := svc.BeginTwoFactorEnrollment(ctx, result.PreAuthToken)
+	require.NoError(t, err)
+	code, err := totp.CodeAt(setup.Secret, totp.Counter(time.Now()))
+	require.NoError(t, err)
+	loggedIn, err := svc.CompleteTwoFactorEnrollment(ctx, result.PreAuthToken, code)
+	require.NoError(t, err)
+	require.NotEmpty(t, loggedIn.Token)
+	require.Len(t, loggedIn.RecoveryCodes, recoveryCodeCount)
+
+	// 已绑定后不能再通过预认证 token 重新绑定
+	_, err = svc.BeginTwoFactorEnrollment(ctx, result.PreAuthToken)
+	require.ErrorIs(t, err, ErrTwoFactorAlreadyEnrolled)
+
+	// 必须启用二次验证时不能关闭唯一的验证方式
+	nextCode, err := totp.CodeAt(setup.Secret, totp.Counter(time.Now())+1)
+	require.NoError(t, err)
+	err = svc.twoFactorService.DisableTOTP(ctx, repo.users[1], "old-password", nextCode)
+	require.ErrorIs(t, err, ErrTwoFactorRequired)
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Wei-Shaw/sub2api/internal/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// doUpstreamAttempt 发送一次上游请求，并为该次尝试记录 span（耗时截至收到响应头）；
+// 请求开启抓取时同时记录上游请求与响应
+func doUpstreamAttempt(ctx context.Context, upstream HTTPUpstream, req *http.Request, proxyURL string, account *Account, attempt int) (*http.Response, error) {
+	_, span := tracing.Start(ctx, "upstream.attempt",
+		attribute.Int64("account.id", account.ID),
+		attribute.String("account.platform", account.Platform),
+		attribute.Int("attempt", attempt),
+		attribute.String("http.url", req.URL.Redacted()),
+	)
+	// 按 API Key / 账号开关抓取改写后的上游请求与原始响应
+	capture := captureFromContext(ctx)
+	if capture != nil && !capture.recordUpstreamRequest(req, account) {
+		capture = nil
+	}
+	resp, err := upstream.Do(req, proxyURL)
+	if resp != nil {
+		span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+		if capture != nil {
+			capture.recordUpstreamResponse(resp)
+		}
+	}
+	tracing.EndWithError(span, err)
+	return resp, err
+}
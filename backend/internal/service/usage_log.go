@@ -12,11 +12,13 @@ type UsageLog struct {
 	UserID    int64
 	ApiKeyID  int64
 	AccountID int64
-	RequestID string
+	RequestID string // 上游返回的请求 ID
+	TraceID   string // 启用追踪时的 trace id，便于从使用记录定位链路
 	Model     string
 
 	GroupID        *int64
 	SubscriptionID *int64
+	OrganizationID *int64 // 组织 Key 产生的用量
 
 	InputTokens         int
 	OutputTokens        int
@@ -36,6 +38,7 @@ type UsageLog struct {
 
 	BillingType  int8
 	Stream       bool
+	CacheHit     bool // 由响应缓存直接返回，未请求上游
 	DurationMs   *int
 	FirstTokenMs *int
 
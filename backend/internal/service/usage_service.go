@@ -52,15 +52,17 @@ type UsageStats struct {
 
 // UsageService 使用统计服务
 type UsageService struct {
-	usageRepo UsageLogRepository
-	userRepo  UserRepository
+	usageRepo     UsageLogRepository
+	userRepo      UserRepository
+	balanceTxRepo BalanceTransactionRepository
 }
 
 // NewUsageService 创建使用统计服务实例
-func NewUsageService(usageRepo UsageLogRepository, userRepo UserRepository) *UsageService {
+func NewUsageService(usageRepo UsageLogRepository, userRepo UserRepository, balanceTxRepo BalanceTransactionRepository) *UsageService {
 	return &UsageService{
-		usageRepo: usageRepo,
-		userRepo:  userRepo,
+		usageRepo:     usageRepo,
+		userRepo:      userRepo,
+		balanceTxRepo: balanceTxRepo,
 	}
 }
 
@@ -96,15 +98,13 @@ func (s *UsageService) Create(ctx context.Context, req CreateUsageLogRequest) (*
 		DurationMs:            req.DurationMs,
 	}
 
-	if err := s.usageRepo.Create(ctx, usageLog); err != nil {
-		return nil, fmt.Errorf("create usage log: %w", err)
-	}
-
-	// 扣除用户余额
+	// 创建使用日志并扣除用户余额（同事务写入余额流水）
+	var debit *BalanceTransaction
 	if req.ActualCost > 0 {
-		if err := s.userRepo.UpdateBalance(ctx, req.UserID, -req.ActualCost); err != nil {
-			return nil, fmt.Errorf("update user balance: %w", err)
-		}
+		debit = &BalanceTransaction{UserID: req.UserID, Type: BalanceTxTypeUsage, Amount: -req.ActualCost}
+	}
+	if err := s.balanceTxRepo.CreateUsageWithDebit(ctx, usageLog, debit); err != nil {
+		return nil, fmt.Errorf("create usage log: %w", err)
 	}
 
 	return usageLog, nil
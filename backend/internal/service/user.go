@@ -18,6 +18,7 @@ type User struct {
 	Concurrency   int
 	Status        string
 	AllowedGroups []int64
+	TokenVersion  int64 // 递增后旧的 JWT 全部失效
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 
@@ -25,10 +26,16 @@ type User struct {
 	Subscriptions []UserSubscription
 }
 
+// IsAdmin 是否为超级管理员
 func (u *User) IsAdmin() bool {
 	return u.Role == RoleAdmin
 }
 
+// HasAdminAccess 是否可以访问管理后台（任意管理角色）
+func (u *User) HasAdminAccess() bool {
+	return IsAdminRole(u.Role)
+}
+
 func (u *User) IsActive() bool {
 	return u.Status == StatusActive
 }
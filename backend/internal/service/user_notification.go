@@ -0,0 +1,142 @@
+package service
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+)
+
+// 用户通知类型
+const (
+	NotificationTypeBalanceLow           = "balance_low"
+	NotificationTypeSubscriptionExpiring = "subscription_expiring"
+	NotificationTypeQuotaReached         = "quota_reached"
+)
+
+// NotificationTypes 所有用户通知类型
+var NotificationTypes = []string{
+	NotificationTypeBalanceLow,
+	NotificationTypeSubscriptionExpiring,
+	NotificationTypeQuotaReached,
+}
+
+// DefaultNotifyBalanceThreshold 用户未设置时的低余额提醒阈值
+const DefaultNotifyBalanceThreshold = 1.0
+
+var ErrNotificationPrefsNotFound = infraerrors.NotFound("NOTIFICATION_PREFS_NOT_FOUND", "notification preferences not found")
+
+// UserNotificationPrefs 用户通知偏好，未保存时全部开启
+type UserNotificationPrefs struct {
+	UserID int64
+
+	BalanceLowEnabled         bool
+	BalanceThreshold          float64
+	SubscriptionExpiryEnabled bool
+	QuotaReachedEnabled       bool
+
+	UpdatedAt time.Time
+}
+
+// DefaultUserNotificationPrefs 默认通知偏好
+func DefaultUserNotificationPrefs(userID int64) *UserNotificationPrefs {
+	return &UserNotificationPrefs{
+		UserID:                    userID,
+		BalanceLowEnabled:         true,
+		BalanceThreshold:          DefaultNotifyBalanceThreshold,
+		SubscriptionExpiryEnabled: true,
+		QuotaReachedEnabled:       true,
+	}
+}
+
+// Enabled 是否接收该类型通知
+func (p *UserNotificationPrefs) Enabled(notificationType string) bool {
+	switch notificationType {
+	case NotificationTypeBalanceLow:
+		return p.BalanceLowEnabled
+	case NotificationTypeSubscriptionExpiring:
+		return p.SubscriptionExpiryEnabled
+	case NotificationTypeQuotaReached:
+		return p.QuotaReachedEnabled
+	}
+	return false
+}
+
+// NotificationTemplate 通知邮件模板，支持 {{site_name}} 等占位符
+type NotificationTemplate struct {
+	Type    string
+	Subject string
+	Body    string
+}
+
+// NotificationSettings 管理员配置的用户通知设置
+type NotificationSettings struct {
+	Enabled                bool
+	SubscriptionExpiryDays int
+	Templates              []NotificationTemplate
+}
+
+// Template 获取指定类型的模板
+func (s *NotificationSettings) Template(notificationType string) NotificationTemplate {
+	for _, t := range s.Templates {
+		if t.Type == notificationType {
+			return t
+		}
+	}
+	return DefaultNotificationTemplate(notificationType)
+}
+
+// NotificationPlaceholders 各类型模板可用的占位符
+var NotificationPlaceholders = map[string][]string{
+	NotificationTypeBalanceLow:           {"site_name", "username", "email", "balance", "threshold"},
+	NotificationTypeSubscriptionExpiring: {"site_name", "username", "email", "group_name", "expires_at", "days_remaining"},
+	NotificationTypeQuotaReached:         {"site_name", "username", "email", "group_name", "window", "limit_usd", "resets_at"},
+}
+
+var defaultNotificationTemplates = map[string]NotificationTemplate{
+	NotificationTypeBalanceLow: {
+		Subject: "[{{site_name}}] Your balance is running low",
+		Body: `<p>Hi {{username}},</p>
+<p>Your {{site_name}} balance has dropped to <strong>${{balance}}</strong>, below your alert threshold of ${{threshold}}.</p>
+<p>Please top up to avoid service interruption.</p>`,
+	},
+	NotificationTypeSubscriptionExpiring: {
+		Subject: "[{{site_name}}] Your {{group_name}} subscription expires in {{days_remaining}} day(s)",
+		Body: `<p>Hi {{username}},</p>
+<p>Your <strong>{{group_name}}</strong> subscription on {{site_name}} expires at {{expires_at}} ({{days_remaining}} day(s) remaining).</p>
+<p>Please renew it in time to keep using the service.</p>`,
+	},
+	NotificationTypeQuotaReached: {
+		Subject: "[{{site_name}}] {{window}} usage limit reached for {{group_name}}",
+		Body: `<p>Hi {{username}},</p>
+<p>Your <strong>{{group_name}}</strong> subscription has reached its {{window}} usage limit of ${{limit_usd}}.</p>
+<p>Requests will be available again at {{resets_at}}.</p>`,
+	},
+}
+
+// DefaultNotificationTemplate 内置默认模板
+func DefaultNotificationTemplate(notificationType string) NotificationTemplate {
+	t := defaultNotificationTemplates[notificationType]
+	t.Type = notificationType
+	return t
+}
+
+// IsValidNotificationType 检查通知类型是否受支持
+func IsValidNotificationType(notificationType string) bool {
+	_, ok := defaultNotificationTemplates[notificationType]
+	return ok
+}
+
+// renderNotification 替换模板占位符。正文为 HTML，变量值需转义。
+func renderNotification(tpl NotificationTemplate, vars map[string]string) (subject, body string) {
+	plain := make([]string, 0, len(vars)*2)
+	escaped := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		plain = append(plain, "{{"+k+"}}", v)
+		escaped = append(escaped, "{{"+k+"}}", html.EscapeString(v))
+	}
+	// 主题写入邮件头，去掉换行防止头注入
+	subject = strings.NewReplacer("\r", " ", "\n", " ").Replace(strings.NewReplacer(plain...).Replace(tpl.Subject))
+	return subject, strings.NewReplacer(escaped...).Replace(tpl.Body)
+}
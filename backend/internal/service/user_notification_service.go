@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/timezone"
+)
+
+const (
+	// userNotifySettingsCacheTTL 通知设置缓存时间，避免每次扣费都查询设置
+	userNotifySettingsCacheTTL = 30 * time.Second
+	// userNotifyPrefsCacheTTL 用户偏好缓存时间（本副本修改时立即失效）
+	userNotifyPrefsCacheTTL = time.Minute
+	// userNotifyExpiryScanInterval 订阅到期扫描间隔
+	userNotifyExpiryScanInterval = time.Hour
+	// userNotifyBalanceWindow 低余额提醒的去重窗口
+	userNotifyBalanceWindow = 24 * time.Hour
+	// userNotifyTimeLayout 邮件中的时间格式
+	userNotifyTimeLayout = "2006-01-02 15:04 MST"
+)
+
+// UserNotificationPrefsRepository 用户通知偏好存储
+type UserNotificationPrefsRepository interface {
+	// GetByUserID 未保存过偏好时返回 ErrNotificationPrefsNotFound
+	GetByUserID(ctx context.Context, userID int64) (*UserNotificationPrefs, error)
+	Upsert(ctx context.Context, prefs *UserNotificationPrefs) error
+}
+
+// NotificationDedupCache 通知去重标记
+type NotificationDedupCache interface {
+	// TryMark 写入去重标记，key 在 ttl 内已存在时返回 false
+	TryMark(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+type cachedNotificationPrefs struct {
+	prefs    *UserNotificationPrefs
+	loadedAt time.Time
+}
+
+// UserNotificationService 用户邮件通知：低余额、订阅即将到期、订阅限额用尽。
+// 邮件通过 EmailQueueService 的工作协程异步发送，每个窗口只发送一次。
+type UserNotificationService struct {
+	prefsRepo      UserNotificationPrefsRepository
+	dedupCache     NotificationDedupCache
+	userRepo       UserRepository
+	userSubRepo    UserSubscriptionRepository
+	settingService *SettingService
+	emailQueue     *EmailQueueService
+
+	mu               sync.Mutex
+	cachedSettings   *NotificationSettings
+	cachedSettingsAt time.Time
+	prefsCache       map[int64]cachedNotificationPrefs
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewUserNotificationService 创建用户通知服务
+func NewUserNotificationService(
+	prefsRepo UserNotificationPrefsRepository,
+	dedupCache NotificationDedupCache,
+	userRepo UserRepository,
+	userSubRepo UserSubscriptionRepository,
+	settingService *SettingService,
+	emailQueue *EmailQueueService,
+) *UserNotificationService {
+	return &UserNotificationService{
+		prefsRepo:      prefsRepo,
+		dedupCache:     dedupCache,
+		userRepo:       userRepo,
+		userSubRepo:    userSubRepo,
+		settingService: settingService,
+		emailQueue:     emailQueue,
+		prefsCache:     make(map[int64]cachedNotificationPrefs),
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// Start 启动订阅到期扫描
+func (s *UserNotificationService) Start() {
+	s.wg.Add(1)
+	go s.expiryLoop()
+	logger.Component("user_notify").Info("service started")
+}
+
+// Stop 停止后台任务
+func (s *UserNotificationService) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+	logger.Component("user_notify").Info("service stopped")
+}
+
+// GetPrefs 获取用户通知偏好，未保存时返回默认值
+func (s *UserNotificationService) GetPrefs(ctx context.Context, userID int64) (*UserNotificationPrefs, error) {
+	prefs, err := s.prefsRepo.GetByUserID(ctx, userID)
+	if errors.Is(err, ErrNotificationPrefsNotFound) {
+		return DefaultUserNotificationPrefs(userID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get notification prefs: %w", err)
+	}
+	return prefs, nil
+}
+
+// UpdatePrefs 保存用户通知偏好
+func (s *UserNotificationService) UpdatePrefs(ctx context.Context, prefs *UserNotificationPrefs) error {
+	if prefs.BalanceThreshold < 0 {
+		return infraerrors.BadRequest("INVALID_BALANCE_THRESHOLD", "balance threshold must not be negative")
+	}
+	if err := s.prefsRepo.Upsert(ctx, prefs); err != nil {
+		return fmt.Errorf("update notification prefs: %w", err)
+	}
+
+	s.mu.Lock()
+	delete(s.prefsCache, prefs.UserID)
+	s.mu.Unlock()
+	return nil
+}
+
+// settings 获取通知设置（带短期缓存），读取失败时返回 nil
+func (s *UserNotificationService) settings(ctx context.Context) *NotificationSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cachedSettings != nil && time.Since(s.cachedSettingsAt) < userNotifySettingsCacheTTL {
+		return s.cachedSettings
+	}
+	settings, err := s.settingService.GetNotificationSettings(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Warn("load notification settings failed", logger.Err(err))
+		return nil
+	}
+	s.cachedSettings, s.cachedSettingsAt = settings, time.Now()
+	return settings
+}
+
+// enabledSettings 返回已启用的通知设置，未启用时返回 nil
+func (s *UserNotificationService) enabledSettings(ctx context.Context) *NotificationSettings {
+	if s == nil {
+		return nil
+	}
+	settings := s.settings(ctx)
+	if settings == nil || !settings.Enabled {
+		return nil
+	}
+	return settings
+}
+
+// cachedPrefs 获取用户偏好（带短期缓存），读取失败时返回 nil
+func (s *UserNotificationService) cachedPrefs(ctx context.Context, userID int64) *UserNotificationPrefs {
+	s.mu.Lock()
+	if cached, ok := s.prefsCache[userID]; ok && time.Since(cached.loadedAt) < userNotifyPrefsCacheTTL {
+		s.mu.Unlock()
+		return cached.prefs
+	}
+	s.mu.Unlock()
+
+	prefs, err := s.GetPrefs(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("load notification prefs failed", "user_id", userID, logger.Err(err))
+		return nil
+	}
+
+	s.mu.Lock()
+	s.prefsCache[userID] = cachedNotificationPrefs{prefs: prefs, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return prefs
+}
+
+// NotifyBalanceDebited 扣费后余额首次低于用户阈值时提醒，每个窗口只提醒一次
+func (s *UserNotificationService) NotifyBalanceDebited(ctx context.Context, user *User, balanceAfter, amount float64) {
+	settings := s.enabledSettings(ctx)
+	if settings == nil {
+		return
+	}
+	prefs := s.cachedPrefs(ctx, user.ID)
+	if prefs == nil || !prefs.BalanceLowEnabled || prefs.BalanceThreshold <= 0 {
+		return
+	}
+	if balanceAfter >= prefs.BalanceThreshold || balanceAfter+amount < prefs.BalanceThreshold {
+		return
+	}
+
+	key := fmt.Sprintf("%s:%d", NotificationTypeBalanceLow, user.ID)
+	if !s.markOnce(ctx, key, userNotifyBalanceWindow) {
+		return
+	}
+	s.send(ctx, settings, user, NotificationTypeBalanceLow, map[string]string{
+		"balance":   strconv.FormatFloat(balanceAfter, 'f', 2, 64),
+		"threshold": strconv.FormatFloat(prefs.BalanceThreshold, 'f', 2, 64),
+	})
+}
+
+// NotifyQuotaReached 订阅的日/周/月限额用尽时提醒，每个限额窗口只提醒一次
+func (s *UserNotificationService) NotifyQuotaReached(ctx context.Context, sub *UserSubscription, group *Group, window string, limitUSD float64, resetsAt *time.Time) {
+	settings := s.enabledSettings(ctx)
+	if settings == nil {
+		return
+	}
+	prefs := s.cachedPrefs(ctx, sub.UserID)
+	if prefs == nil || !prefs.QuotaReachedEnabled {
+		return
+	}
+
+	ttl := 24 * time.Hour
+	var windowStart int64
+	if resetsAt != nil {
+		ttl = max(time.Until(*resetsAt), time.Minute)
+		windowStart = resetsAt.Unix()
+	}
+	key := fmt.Sprintf("%s:%d:%s:%d", NotificationTypeQuotaReached, sub.ID, window, windowStart)
+	if !s.markOnce(ctx, key, ttl) {
+		return
+	}
+
+	user := sub.User
+	if user == nil {
+		var err error
+		if user, err = s.userRepo.GetByID(ctx, sub.UserID); err != nil {
+			logger.FromContext(ctx).Warn("load user for quota notification failed", "user_id", sub.UserID, logger.Err(err))
+			return
+		}
+	}
+
+	resets := "the start of the next window"
+	if resetsAt != nil {
+		resets = resetsAt.In(timezone.Location()).Format(userNotifyTimeLayout)
+	}
+	s.send(ctx, settings, user, NotificationTypeQuotaReached, map[string]string{
+		"group_name": group.Name,
+		"window":     window,
+		"limit_usd":  strconv.FormatFloat(limitUSD, 'f', 2, 64),
+		"resets_at":  resets,
+	})
+}
+
+// markOnce 写入去重标记，返回是否为窗口内首次
+func (s *UserNotificationService) markOnce(ctx context.Context, key string, ttl time.Duration) bool {
+	ok, err := s.dedupCache.TryMark(ctx, key, ttl)
+	if err != nil {
+		logger.FromContext(ctx).Warn("mark notification sent failed", "key", key, logger.Err(err))
+		return false
+	}
+	return ok
+}
+
+// send 渲染模板并加入邮件队列
+func (s *UserNotificationService) send(ctx context.Context, settings *NotificationSettings, user *User, notificationType string, vars map[string]string) {
+	username := user.Username
+	if username == "" {
+		username = user.Email
+	}
+	vars["site_name"] = s.settingService.GetSiteName(ctx)
+	vars["username"] = username
+	vars["email"] = user.Email
+
+	subject, body := renderNotification(settings.Template(notificationType), vars)
+	if err := s.emailQueue.EnqueueNotification(user.Email, subject, body); err != nil {
+		logger.FromContext(ctx).Warn("enqueue notification failed", "type", notificationType, "user_id", user.ID, logger.Err(err))
+	}
+}
+
+func (s *UserNotificationService) expiryLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(userNotifyExpiryScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.scanExpiringSubscriptions()
+			s.evictPrefsCache()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// scanExpiringSubscriptions 提醒即将到期的订阅，每个订阅的每个到期时间只提醒一次
+func (s *UserNotificationService) scanExpiringSubscriptions() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	settings := s.enabledSettings(ctx)
+	if settings == nil || settings.SubscriptionExpiryDays <= 0 {
+		return
+	}
+
+	subs, err := s.userSubRepo.ListExpiringBefore(ctx, time.Now().AddDate(0, 0, settings.SubscriptionExpiryDays))
+	if err != nil {
+		logger.Component("user_notify").Error("list expiring subscriptions failed", logger.Err(err))
+		return
+	}
+	for i := range subs {
+		sub := &subs[i]
+		if sub.User == nil || sub.Group == nil {
+			continue
+		}
+		prefs := s.cachedPrefs(ctx, sub.UserID)
+		if prefs == nil || !prefs.SubscriptionExpiryEnabled {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d:%d", NotificationTypeSubscriptionExpiring, sub.ID, sub.ExpiresAt.Unix())
+		if !s.markOnce(ctx, key, time.Until(sub.ExpiresAt)+time.Hour) {
+			continue
+		}
+		s.send(ctx, settings, sub.User, NotificationTypeSubscriptionExpiring, map[string]string{
+			"group_name":     sub.Group.Name,
+			"expires_at":     sub.ExpiresAt.In(timezone.Location()).Format(userNotifyTimeLayout),
+			"days_remaining": strconv.Itoa(sub.DaysRemaining()),
+		})
+	}
+}
+
+// evictPrefsCache 清理过期的偏好缓存
+func (s *UserNotificationService) evictPrefsCache() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for userID, cached := range s.prefsCache {
+		if time.Since(cached.loadedAt) >= userNotifyPrefsCacheTTL {
+			delete(s.prefsCache, userID)
+		}
+	}
+}
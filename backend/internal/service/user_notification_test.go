@@ -0,0 +1,173 @@
+//go:build unit
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Wei-Shaw/sub2api/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type notifySettingRepoStub struct {
+	SettingRepository
+	values map[string]string
+}
+
+func (s *notifySettingRepoStub) GetValue(ctx context.Context, key string) (string, error) {
+	if v, ok := s.values[key]; ok {
+		return v, nil
+	}
+	return "", ErrSettingNotFound
+}
+
+func (s *notifySettingRepoStub) GetMultiple(ctx context.Context, keys []string) (map[string]string, error) {
+	out := make(map[string]string, len(keys))
+	for _, k := range keys {
+		if v, ok := s.values[k]; ok {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+type notifyPrefsRepoStub struct {
+	prefs map[int64]*UserNotificationPrefs
+}
+
+func (s *notifyPrefsRepoStub) GetByUserID(ctx context.Context, userID int64) (*UserNotificationPrefs, error) {
+	if p, ok := s.prefs[userID]; ok {
+		return p, nil
+	}
+	return nil, ErrNotificationPrefsNotFound
+}
+
+func (s *notifyPrefsRepoStub) Upsert(ctx context.Context, prefs *UserNotificationPrefs) error {
+	s.prefs[prefs.UserID] = prefs
+	return nil
+}
+
+type notifyDedupStub struct {
+	keys map[string]bool
+}
+
+func (s *notifyDedupStub) TryMark(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if s.keys[key] {
+		return false, nil
+	}
+	s.keys[key] = true
+	return true, nil
+}
+
+func newTestUserNotificationService(values map[string]string) (*UserNotificationService, *notifyPrefsRepoStub, chan EmailTask) {
+	settingService := NewSettingService(&notifySettingRepoStub{values: values}, &config.Config{})
+	prefsRepo := &notifyPrefsRepoStub{prefs: map[int64]*UserNotificationPrefs{}}
+	queue := &EmailQueueService{taskChan: make(chan EmailTask, 10)}
+	svc := NewUserNotificationService(prefsRepo, &notifyDedupStub{keys: map[string]bool{}}, nil, nil, settingService, queue)
+	return svc, prefsRepo, queue.taskChan
+}
+
+func TestRenderNotification_EscapesBodyAndStripsSubjectNewlines(t *testing.T) {
+	tpl := NotificationTemplate{Subject: "[{{site_name}}] {{group_name}}", Body: "<p>{{group_name}}</p>"}
+	subject, body := renderNotification(tpl, map[string]string{"site_name": "Sub2API", "group_name": "<b>x</b>\r\nBcc: a@b.c"})
+	require.Equal(t, "[Sub2API] <b>x</b>  Bcc: a@b.c", subject)
+	require.Equal(t, "<p>&lt;b&gt;x&lt;/b&gt;\r\nBcc: a@b.c</p>", body)
+}
+
+func TestUserNotificationService_BalanceLowOncePerWindow(t *testing.T) {
+	svc, _, tasks := newTestUserNotificationService(map[string]string{
+		SettingKeyUserNotifyEnabled: "true",
+		SettingKeySiteName:          "MySite",
+		SettingKeyUserNotifyTemplatePrefix + NotificationTypeBalanceLow + "_subject": "{{site_name}} low: {{balance}}",
+	})
+	user := &User{ID: 1, Email: "a@example.com"}
+
+	// 未跨越默认阈值 1.0
+	svc.NotifyBalanceDebited(context.Background(), user, 2, 0.5)
+	require.Len(t, tasks, 0)
+
+	svc.NotifyBalanceDebited(context.Background(), user, 0.8, 0.5)
+	require.Len(t, tasks, 1)
+	task := <-tasks
+	require.Equal(t, "notification", task.TaskType)
+	require.Equal(t, "a@example.com", task.Email)
+	require.Equal(t, "MySite low: 0.80", task.Subject)
+	require.Contains(t, task.Body, "a@example.com")
+
+	// 同一窗口内不重复发送
+	svc.NotifyBalanceDebited(context.Background(), user, 0.9, 0.5)
+	require.Len(t, tasks, 0)
+}
+
+func TestUserNotificationService_RespectsOptOutAndGlobalSwitch(t *testing.T) {
+	svc, prefsRepo, tasks := newTestUserNotificationService(map[string]string{SettingKeyUserNotifyEnabled: "true"})
+	prefs := DefaultUserNotificationPrefs(1)
+	prefs.BalanceLowEnabled = false
+	require.NoError(t, svc.UpdatePrefs(context.Background(), prefs))
+	require.False(t, prefsRepo.prefs[1].BalanceLowEnabled)
+
+	svc.NotifyBalanceDebited(context.Background(), &User{ID: 1, Email: "a@example.com"}, 0.5, 1)
+	require.Len(t, tasks, 0)
+
+	disabled, _, disabledTasks := newTestUserNotificationService(map[string]string{})
+	disabled.NotifyBalanceDebited(context.Background(), &User{ID: 2, Email: "b@example.com"}, 0.5, 1)
+	require.Len(t, disabledTasks, 0)
+
+	var nilSvc *UserNotificationService
+	nilSvc.NotifyBalanceDebited(context.Background(), &User{ID: 1}, 0, 1)
+}
+
+func TestUserNotificationService_QuotaReachedOncePerWindow(t *testing.T) {
+	svc, _, tasks := newTestUserNotificationService(map[string]string{SettingKeyUserNotifyEnabled: "true"})
+	limit := 10.0
+	group := &Group{Name: "Pro", DailyLimitUSD: &limit}
+	start := time.Now().Add(-time.Hour)
+	sub := &UserSubscription{ID: 5, UserID: 1, DailyWindowStart: &start, DailyUsageUSD: 12, User: &User{ID: 1, Email: "a@example.com", Username: "alice"}}
+
+	subSvc := NewSubscriptionService(nil, nil, nil, svc)
+	require.ErrorIs(t, subSvc.CheckUsageLimits(context.Background(), sub, group, 0), ErrDailyLimitExceeded)
+	require.ErrorIs(t, subSvc.CheckUsageLimits(context.Background(), sub, group, 0), ErrDailyLimitExceeded)
+
+	require.Len(t, tasks, 1)
+	task := <-tasks
+	require.Contains(t, task.Subject, "daily usage limit reached for Pro")
+	require.Contains(t, task.Body, "Hi alice")
+	require.Contains(t, task.Body, "$10.00")
+}
+
+func TestUserNotificationService_QuotaReachedOptOutKeepsWindow(t *testing.T) {
+	svc, _, tasks := newTestUserNotificationService(map[string]string{SettingKeyUserNotifyEnabled: "true"})
+	ctx := context.Background()
+	group := &Group{Name: "Pro"}
+	sub := &UserSubscription{ID: 5, UserID: 1, User: &User{ID: 1, Email: "a@example.com"}}
+	resetsAt := time.Now().Add(time.Hour)
+
+	// 关闭提醒期间触发限额，不应占用本窗口的去重标记
+	prefs := DefaultUserNotificationPrefs(1)
+	prefs.QuotaReachedEnabled = false
+	require.NoError(t, svc.UpdatePrefs(ctx, prefs))
+	svc.NotifyQuotaReached(ctx, sub, group, "daily", 10, &resetsAt)
+	require.Len(t, tasks, 0)
+
+	// 同一窗口内重新开启后仍会收到提醒
+	prefs.QuotaReachedEnabled = true
+	require.NoError(t, svc.UpdatePrefs(ctx, prefs))
+	svc.NotifyQuotaReached(ctx, sub, group, "daily", 10, &resetsAt)
+	require.Len(t, tasks, 1)
+}
+
+func TestNotificationSettings_TemplateFallsBackToDefault(t *testing.T) {
+	settingService := NewSettingService(&notifySettingRepoStub{values: map[string]string{
+		SettingKeyUserNotifyTemplatePrefix + NotificationTypeQuotaReached + "_body": "custom",
+	}}, &config.Config{})
+
+	settings, err := settingService.GetNotificationSettings(context.Background())
+	require.NoError(t, err)
+	require.False(t, settings.Enabled)
+	require.Equal(t, 3, settings.SubscriptionExpiryDays)
+	require.Len(t, settings.Templates, len(NotificationTypes))
+	require.Equal(t, "custom", settings.Template(NotificationTypeQuotaReached).Body)
+	require.Equal(t, DefaultNotificationTemplate(NotificationTypeQuotaReached).Subject, settings.Template(NotificationTypeQuotaReached).Subject)
+}
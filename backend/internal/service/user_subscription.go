@@ -6,6 +6,8 @@ type UserSubscription struct {
 	ID      int64
 	UserID  int64
 	GroupID int64
+	// OrganizationID 不为空时为组织共享订阅，UserID 为组织 owner
+	OrganizationID *int64
 
 	StartsAt  time.Time
 	ExpiresAt time.Time
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook 事件类型
+const (
+	WebhookEventAccountError         = "account.error"
+	WebhookEventAccountRateLimited   = "account.rate_limited"
+	WebhookEventAccountOverloaded    = "account.overloaded"
+	WebhookEventTokenRefreshFailed   = "account.token_refresh_failed"
+	WebhookEventProxyTestFailed      = "proxy.test_failed"
+	WebhookEventBalanceLow           = "user.balance_low"
+	WebhookEventSubscriptionExpiring = "subscription.expiring"
+	WebhookEventTest                 = "webhook.test"
+)
+
+// WebhookEventTypes 可订阅的事件类型（不含测试事件）
+var WebhookEventTypes = []string{
+	WebhookEventAccountError,
+	WebhookEventAccountRateLimited,
+	WebhookEventAccountOverloaded,
+	WebhookEventTokenRefreshFailed,
+	WebhookEventProxyTestFailed,
+	WebhookEventBalanceLow,
+	WebhookEventSubscriptionExpiring,
+}
+
+// Webhook 消息格式
+const (
+	WebhookFormatGeneric  = "generic"
+	WebhookFormatSlack    = "slack"
+	WebhookFormatDingTalk = "dingtalk"
+	WebhookFormatFeishu   = "feishu"
+)
+
+// Webhook 投递状态
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook 签名请求头
+const (
+	WebhookHeaderEvent     = "X-Sub2API-Event"
+	WebhookHeaderDelivery  = "X-Sub2API-Delivery"
+	WebhookHeaderTimestamp = "X-Sub2API-Timestamp"
+	WebhookHeaderSignature = "X-Sub2API-Signature"
+)
+
+// IsValidWebhookFormat 检查消息格式是否受支持
+func IsValidWebhookFormat(format string) bool {
+	switch format {
+	case WebhookFormatGeneric, WebhookFormatSlack, WebhookFormatDingTalk, WebhookFormatFeishu:
+		return true
+	}
+	return false
+}
+
+// WebhookConfig 管理后台配置的 Webhook
+type WebhookConfig struct {
+	Enabled bool
+	Url     string
+	Secret  string
+	Format  string
+	Events  []string // 为空表示订阅全部事件
+
+	// 余额低于该值时通知，<= 0 表示不通知
+	BalanceThreshold float64
+	// 订阅到期前多少天通知，<= 0 表示不通知
+	SubscriptionExpiryDays int
+
+	SiteName string
+}
+
+// Subscribed 是否订阅了该事件
+func (c *WebhookConfig) Subscribed(eventType string) bool {
+	if !c.Enabled || c.Url == "" {
+		return false
+	}
+	if eventType == WebhookEventTest || len(c.Events) == 0 {
+		return true
+	}
+	for _, e := range c.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent 一条运维通知，作为投递队列的负载持久化
+type WebhookEvent struct {
+	Type       string         `json:"type"`
+	Title      string         `json:"title"`
+	Message    string         `json:"message"`
+	Data       map[string]any `json:"data,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
+}
+
+// WebhookDelivery 持久化的投递任务，失败后按退避策略重试
+type WebhookDelivery struct {
+	ID        int64
+	EventType string
+	// DedupeKey 非空时同一 key 只投递一次，用于避免重复通知
+	DedupeKey string
+	Url       string
+	Format    string
+	Event     WebhookEvent
+
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    *time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// signWebhookPayload 计算通用 HMAC-SHA256 签名：hex(HMAC(secret, timestamp + "." + body))
+func signWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookChatSign 钉钉/飞书机器人签名：base64(HMAC-SHA256(key, msg))
+func webhookChatSign(key, msg string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(msg))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// webhookText 将事件渲染为聊天消息文本，附加字段按名称排序
+func webhookText(event *WebhookEvent, siteName string, markdown bool) (title, text string) {
+	title = fmt.Sprintf("[%s] %s", siteName, event.Title)
+
+	keys := make([]string, 0, len(event.Data))
+	for k := range event.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	if markdown {
+		b.WriteString("### " + title + "\n\n")
+	} else {
+		b.WriteString(title + "\n")
+	}
+	b.WriteString(event.Message + "\n")
+	for _, k := range keys {
+		if markdown {
+			b.WriteString("\n- ")
+		} else {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s: %v", k, event.Data[k])
+	}
+	fmt.Fprintf(&b, "\n\n%s", event.OccurredAt.Format(time.RFC3339))
+	return title, b.String()
+}
+
+// renderWebhookRequest 按格式生成请求 URL 与请求体。
+// 钉钉与飞书配置了 secret 时按其机器人规则附带签名。
+func renderWebhookRequest(delivery *WebhookDelivery, secret, siteName string, now time.Time) (string, []byte, error) {
+	event := &delivery.Event
+	target := delivery.Url
+
+	var payload any
+	switch delivery.Format {
+	case WebhookFormatSlack:
+		_, text := webhookText(event, siteName, false)
+		payload = map[string]any{"text": text}
+	case WebhookFormatDingTalk:
+		title, text := webhookText(event, siteName, true)
+		payload = map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]any{"title": title, "text": text},
+		}
+		if secret != "" {
+			timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+			sign := webhookChatSign(secret, timestamp+"\n"+secret)
+			u, err := url.Parse(target)
+			if err != nil {
+				return "", nil, fmt.Errorf("parse webhook url: %w", err)
+			}
+			q := u.Query()
+			q.Set("timestamp", timestamp)
+			q.Set("sign", sign)
+			u.RawQuery = q.Encode()
+			target = u.String()
+		}
+	case WebhookFormatFeishu:
+		_, text := webhookText(event, siteName, false)
+		body := map[string]any{
+			"msg_type": "text",
+			"content":  map[string]any{"text": text},
+		}
+		if secret != "" {
+			timestamp := strconv.FormatInt(now.Unix(), 10)
+			body["timestamp"] = timestamp
+			body["sign"] = webhookChatSign(timestamp+"\n"+secret, "")
+		}
+		payload = body
+	default:
+		payload = map[string]any{
+			"id":          delivery.ID,
+			"event":       event.Type,
+			"site":        siteName,
+			"title":       event.Title,
+			"message":     event.Message,
+			"data":        event.Data,
+			"occurred_at": event.OccurredAt,
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshal webhook payload: %w", err)
+	}
+	return target, body, nil
+}
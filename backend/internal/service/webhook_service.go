@@ -0,0 +1,474 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	infraerrors "github.com/Wei-Shaw/sub2api/internal/infrastructure/errors"
+	"github.com/Wei-Shaw/sub2api/internal/pkg/logger"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// webhookPollInterval 投递队列轮询间隔（新事件入队时会立即唤醒）
+	webhookPollInterval = 15 * time.Second
+	// webhookBatchSize 每次领取的投递任务数
+	webhookBatchSize = 20
+	// webhookDeliveryTimeout 单个任务的处理时间上限（发送请求并记录结果），每个任务使用独立的 context
+	webhookDeliveryTimeout = webhookRequestTimeout + 5*time.Second
+	// webhookClaimLease 领取后的租约时间，进程崩溃时任务在租约过期后被重新领取。
+	// 必须长于整批任务的最长处理时间，否则批次末尾的任务可能在投递中被其他实例重复领取
+	webhookClaimLease = webhookBatchSize*webhookDeliveryTimeout + time.Minute
+	// webhookMaxAttempts 最大投递次数，超过后标记为 failed
+	webhookMaxAttempts = 8
+	// webhookRetryBase / webhookRetryMax 指数退避的基数与上限
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = time.Hour
+	// webhookRequestTimeout 单次投递超时
+	webhookRequestTimeout = 10 * time.Second
+	// webhookConfigCacheTTL 配置缓存时间，避免每次记录使用量都查询设置
+	webhookConfigCacheTTL = 30 * time.Second
+	// webhookExpiryScanInterval 订阅到期扫描间隔
+	webhookExpiryScanInterval = time.Hour
+	// webhookRetention 已完成投递记录的保留时间。去重依赖投递记录上的唯一索引，
+	// 记录删除后同一去重键可以再次入队，因此去重只在该时间窗口内生效
+	webhookRetention = WebhookDedupeWindowDays * 24 * time.Hour
+)
+
+// WebhookDedupeWindowDays 去重键的有效天数，订阅到期提前通知的天数不能超过该值
+const WebhookDedupeWindowDays = 7
+
+// WebhookDeliveryRepository Webhook 投递队列
+type WebhookDeliveryRepository interface {
+	// Enqueue 写入待投递任务，DedupeKey 已存在时忽略并返回 false
+	Enqueue(ctx context.Context, delivery *WebhookDelivery) (bool, error)
+	// ClaimDue 领取到期的待投递任务：投递次数加一，并将下次尝试时间推迟 lease
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookDelivery, error)
+	MarkSucceeded(ctx context.Context, id int64, statusCode int) error
+	MarkRetry(ctx context.Context, id int64, statusCode int, lastErr string, nextAttemptAt time.Time) error
+	MarkFailed(ctx context.Context, id int64, statusCode int, lastErr string) error
+	// DeleteFinishedBefore 删除早于 before 的已完成（成功或最终失败）任务
+	DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// WebhookService 运维事件的 Webhook 通知：事件写入持久化队列，由后台任务签名投递并按退避策略重试
+type WebhookService struct {
+	deliveryRepo   WebhookDeliveryRepository
+	userSubRepo    UserSubscriptionRepository
+	settingService *SettingService
+	httpClient     *http.Client
+
+	mu          sync.Mutex
+	cachedCfg   *WebhookConfig
+	cachedCfgAt time.Time
+
+	wake   chan struct{}
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewWebhookService 创建 Webhook 通知服务
+func NewWebhookService(deliveryRepo WebhookDeliveryRepository, userSubRepo UserSubscriptionRepository, settingService *SettingService) *WebhookService {
+	return &WebhookService{
+		deliveryRepo:   deliveryRepo,
+		userSubRepo:    userSubRepo,
+		settingService: settingService,
+		httpClient:     &http.Client{Timeout: webhookRequestTimeout},
+		wake:           make(chan struct{}, 1),
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// Start 启动投递与订阅到期扫描
+func (s *WebhookService) Start() {
+	s.wg.Add(2)
+	go s.deliveryLoop()
+	go s.expiryLoop()
+	logger.Component("webhook").Info("service started")
+}
+
+// Stop 停止后台任务
+func (s *WebhookService) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+	logger.Component("webhook").Info("service stopped")
+}
+
+// config 获取 Webhook 配置（带短期缓存），读取失败时返回 nil
+func (s *WebhookService) config(ctx context.Context) *WebhookConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cachedCfg != nil && time.Since(s.cachedCfgAt) < webhookConfigCacheTTL {
+		return s.cachedCfg
+	}
+	cfg, err := s.settingService.GetWebhookConfig(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Warn("load webhook config failed", logger.Err(err))
+		return nil
+	}
+	s.cachedCfg, s.cachedCfgAt = cfg, time.Now()
+	return cfg
+}
+
+// Notify 将事件写入投递队列，未启用或未订阅该事件时忽略（nil 接收者同样忽略）。
+// dedupeKey 非空时同一 key 在 WebhookDedupeWindowDays 内只通知一次。
+func (s *WebhookService) Notify(ctx context.Context, event WebhookEvent, dedupeKey string) {
+	if s == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	cfg := s.config(ctx)
+	if cfg == nil || !cfg.Subscribed(event.Type) {
+		return
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	delivery := &WebhookDelivery{
+		EventType:     event.Type,
+		DedupeKey:     dedupeKey,
+		Url:           cfg.Url,
+		Format:        cfg.Format,
+		Event:         event,
+		Status:        WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+	created, err := s.deliveryRepo.Enqueue(ctx, delivery)
+	if err != nil {
+		logger.FromContext(ctx).Error("enqueue webhook failed", "event", event.Type, logger.Err(err))
+		return
+	}
+	if !created {
+		return
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// NotifyAccountError 账号因认证失败进入 error 状态
+func (s *WebhookService) NotifyAccountError(ctx context.Context, account *Account, reason string) {
+	s.Notify(ctx, WebhookEvent{
+		Type:    WebhookEventAccountError,
+		Title:   "Account disabled",
+		Message: fmt.Sprintf("Account %q was set to error status and removed from scheduling: %s", account.Name, reason),
+		Data:    webhookAccountData(account, map[string]any{"reason": reason}),
+	}, "")
+}
+
+// NotifyAccountRateLimited 账号被上游限流
+func (s *WebhookService) NotifyAccountRateLimited(ctx context.Context, account *Account, resetAt time.Time) {
+	s.Notify(ctx, WebhookEvent{
+		Type:    WebhookEventAccountRateLimited,
+		Title:   "Account rate limited",
+		Message: fmt.Sprintf("Account %q was rate limited by upstream until %s", account.Name, resetAt.Format(time.RFC3339)),
+		Data:    webhookAccountData(account, map[string]any{"reset_at": resetAt}),
+	}, fmt.Sprintf("%s:%d:%d", WebhookEventAccountRateLimited, account.ID, resetAt.Truncate(time.Minute).Unix()))
+}
+
+// NotifyAccountOverloaded 账号因上游过载进入冷却
+func (s *WebhookService) NotifyAccountOverloaded(ctx context.Context, account *Account, until time.Time) {
+	s.Notify(ctx, WebhookEvent{
+		Type:    WebhookEventAccountOverloaded,
+		Title:   "Account overloaded",
+		Message: fmt.Sprintf("Account %q is cooling down after upstream overload until %s", account.Name, until.Format(time.RFC3339)),
+		Data:    webhookAccountData(account, map[string]any{"until": until}),
+	}, fmt.Sprintf("%s:%d:%d", WebhookEventAccountOverloaded, account.ID, until.Truncate(time.Minute).Unix()))
+}
+
+// NotifyTokenRefreshFailed 账号 token 刷新重试耗尽
+func (s *WebhookService) NotifyTokenRefreshFailed(ctx context.Context, account *Account, attempts int, err error) {
+	s.Notify(ctx, WebhookEvent{
+		Type:    WebhookEventTokenRefreshFailed,
+		Title:   "Token refresh failed",
+		Message: fmt.Sprintf("Token refresh for account %q failed after %d attempts: %v", account.Name, attempts, err),
+		Data:    webhookAccountData(account, map[string]any{"attempts": attempts, "error": err.Error()}),
+	}, "")
+}
+
+// NotifyProxyTestFailed 代理连通性测试失败
+func (s *WebhookService) NotifyProxyTestFailed(ctx context.Context, proxy *Proxy, reason string) {
+	s.Notify(ctx, WebhookEvent{
+		Type:    WebhookEventProxyTestFailed,
+		Title:   "Proxy test failed",
+		Message: fmt.Sprintf("Proxy %q (%s:%d) failed the connectivity test: %s", proxy.Name, proxy.Host, proxy.Port, reason),
+		Data: map[string]any{
+			"proxy_id":   proxy.ID,
+			"proxy_name": proxy.Name,
+			"host":       proxy.Host,
+			"port":       proxy.Port,
+			"reason":     reason,
+		},
+	}, "")
+}
+
+// NotifyBalanceDebited 扣费后余额首次低于阈值时通知（扣费前不低于阈值）
+func (s *WebhookService) NotifyBalanceDebited(ctx context.Context, user *User, balanceAfter, amount float64) {
+	if s == nil {
+		return
+	}
+	cfg := s.config(ctx)
+	if cfg == nil || cfg.BalanceThreshold <= 0 || !cfg.Subscribed(WebhookEventBalanceLow) {
+		return
+	}
+	if balanceAfter >= cfg.BalanceThreshold || balanceAfter+amount < cfg.BalanceThreshold {
+		return
+	}
+	s.Notify(ctx, WebhookEvent{
+		Type:    WebhookEventBalanceLow,
+		Title:   "User balance low",
+		Message: fmt.Sprintf("Balance of user %s dropped to %.4f, below the threshold %.4f", user.Email, balanceAfter, cfg.BalanceThreshold),
+		Data: map[string]any{
+			"user_id":    user.ID,
+			"user_email": user.Email,
+			"balance":    balanceAfter,
+			"threshold":  cfg.BalanceThreshold,
+		},
+	}, "")
+}
+
+// SendTest 使用给定配置同步发送测试消息
+func (s *WebhookService) SendTest(ctx context.Context, cfg *WebhookConfig) error {
+	delivery := &WebhookDelivery{
+		EventType: WebhookEventTest,
+		Url:       cfg.Url,
+		Format:    cfg.Format,
+		Event: WebhookEvent{
+			Type:       WebhookEventTest,
+			Title:      "Webhook test",
+			Message:    "This is a test notification to verify your webhook settings are working correctly.",
+			OccurredAt: time.Now(),
+		},
+	}
+	if _, err := s.send(ctx, delivery, cfg.Secret, cfg.SiteName); err != nil {
+		return infraerrors.Newf(http.StatusBadGateway, "WEBHOOK_TEST_FAILED", "webhook test failed: %v", err)
+	}
+	return nil
+}
+
+func webhookAccountData(account *Account, extra map[string]any) map[string]any {
+	data := map[string]any{
+		"account_id":   account.ID,
+		"account_name": account.Name,
+		"platform":     account.Platform,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	return data
+}
+
+func (s *WebhookService) deliveryLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.processDue()
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// processDue 领取并投递到期任务
+func (s *WebhookService) processDue() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	deliveries, err := s.deliveryRepo.ClaimDue(ctx, time.Now(), webhookClaimLease, webhookBatchSize)
+	if err != nil {
+		logger.Component("webhook").Error("claim webhook deliveries failed", logger.Err(err))
+		return
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	cfg := s.config(ctx)
+	secret, siteName := "", "Sub2API"
+	if cfg != nil {
+		secret, siteName = cfg.Secret, cfg.SiteName
+	}
+	for i := range deliveries {
+		s.deliver(&deliveries[i], secret, siteName)
+	}
+}
+
+// deliver 投递一个任务并记录结果，每个任务使用独立的超时，慢速端点不会占用后续任务的时间
+func (s *WebhookService) deliver(delivery *WebhookDelivery, secret, siteName string) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookDeliveryTimeout)
+	defer cancel()
+
+	log := logger.Component("webhook").With("delivery_id", delivery.ID, "event", delivery.EventType, "attempt", delivery.Attempts)
+
+	statusCode, err := s.send(ctx, delivery, secret, siteName)
+	if err == nil {
+		if err := s.deliveryRepo.MarkSucceeded(ctx, delivery.ID, statusCode); err != nil {
+			log.Error("mark webhook delivered failed", logger.Err(err))
+		}
+		return
+	}
+
+	if delivery.Attempts >= webhookMaxAttempts {
+		log.Error("webhook delivery failed permanently", logger.Err(err))
+		if err := s.deliveryRepo.MarkFailed(ctx, delivery.ID, statusCode, err.Error()); err != nil {
+			log.Error("mark webhook failed failed", logger.Err(err))
+		}
+		return
+	}
+
+	next := time.Now().Add(webhookRetryDelay(delivery.Attempts))
+	log.Warn("webhook delivery failed, will retry", "next_attempt_at", next, logger.Err(err))
+	if err := s.deliveryRepo.MarkRetry(ctx, delivery.ID, statusCode, err.Error(), next); err != nil {
+		log.Error("mark webhook retry failed", logger.Err(err))
+	}
+}
+
+// webhookRetryDelay 第 attempts 次失败后的重试间隔：30s, 1m, 2m ... 最长 1h
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookRetryMax)
+}
+
+// send 渲染、签名并发送请求，返回上游状态码。
+// 钉钉/飞书即使签名错误也返回 200，需检查响应体中的错误码。
+func (s *WebhookService) send(ctx context.Context, delivery *WebhookDelivery, secret, siteName string) (int, error) {
+	now := time.Now()
+	target, body, err := renderWebhookRequest(delivery, secret, siteName, now)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("build webhook request: %w", err)
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	if secret != "" {
+		req.Header.Set(WebhookHeaderSignature, signWebhookPayload(secret, timestamp, body))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, respBody)
+	}
+	if path := webhookErrorCodePath(delivery.Format); path != "" {
+		if code := gjson.GetBytes(respBody, path); code.Exists() && code.Int() != 0 {
+			return resp.StatusCode, fmt.Errorf("webhook rejected: %s", respBody)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// webhookErrorCodePath 聊天机器人响应体中的错误码字段
+func webhookErrorCodePath(format string) string {
+	switch format {
+	case WebhookFormatDingTalk:
+		return "errcode"
+	case WebhookFormatFeishu:
+		return "code"
+	}
+	return ""
+}
+
+func (s *WebhookService) expiryLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(webhookExpiryScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.scanExpiringSubscriptions()
+			s.cleanupFinished()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// scanExpiringSubscriptions 通知即将到期的订阅，每个订阅的每个到期时间只通知一次
+func (s *WebhookService) scanExpiringSubscriptions() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	cfg := s.config(ctx)
+	if cfg == nil || cfg.SubscriptionExpiryDays <= 0 || !cfg.Subscribed(WebhookEventSubscriptionExpiring) {
+		return
+	}
+
+	// 提前天数不超过去重窗口，否则投递记录清理后同一订阅会被重复通知
+	days := min(cfg.SubscriptionExpiryDays, WebhookDedupeWindowDays)
+	subs, err := s.userSubRepo.ListExpiringBefore(ctx, time.Now().AddDate(0, 0, days))
+	if err != nil {
+		logger.Component("webhook").Error("list expiring subscriptions failed", logger.Err(err))
+		return
+	}
+	for i := range subs {
+		sub := &subs[i]
+		userEmail, groupName := "", ""
+		if sub.User != nil {
+			userEmail = sub.User.Email
+		}
+		if sub.Group != nil {
+			groupName = sub.Group.Name
+		}
+		s.Notify(ctx, WebhookEvent{
+			Type:    WebhookEventSubscriptionExpiring,
+			Title:   "Subscription expiring",
+			Message: fmt.Sprintf("Subscription of user %s to group %q expires at %s", userEmail, groupName, sub.ExpiresAt.Format(time.RFC3339)),
+			Data: map[string]any{
+				"subscription_id": sub.ID,
+				"user_id":         sub.UserID,
+				"user_email":      userEmail,
+				"group_id":        sub.GroupID,
+				"group_name":      groupName,
+				"expires_at":      sub.ExpiresAt,
+			},
+		}, fmt.Sprintf("%s:%d:%d", WebhookEventSubscriptionExpiring, sub.ID, sub.ExpiresAt.Unix()))
+	}
+}
+
+// cleanupFinished 清理过期的已完成投递记录
+func (s *WebhookService) cleanupFinished() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	deleted, err := s.deliveryRepo.DeleteFinishedBefore(ctx, time.Now().Add(-webhookRetention))
+	if err != nil {
+		logger.Component("webhook").Error("delete finished webhook deliveries failed", logger.Err(err))
+	} else if deleted > 0 {
+		logger.Component("webhook").Info("deleted finished webhook deliveries", "count", deleted)
+	}
+}
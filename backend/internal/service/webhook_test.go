@@ -0,0 +1,165 @@
+//go:build unit
+
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWebhookDelivery(format string) *WebhookDelivery {
+	return &WebhookDelivery{
+		ID:     42,
+		Url:    "https://hooks.example.com/robot/send?access_token=abc",
+		Format: format,
+		Event: WebhookEvent{
+			Type:       WebhookEventAccountError,
+			Title:      "Account disabled",
+			Message:    "Account \"a\" was set to error status",
+			Data:       map[string]any{"account_id": 7, "reason": "401"},
+			OccurredAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"a":1}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+
+	require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signWebhookPayload("secret", "1700000000", body))
+}
+
+func TestRenderWebhookRequest_Generic(t *testing.T) {
+	target, body, err := renderWebhookRequest(newTestWebhookDelivery(WebhookFormatGeneric), "s", "Sub2API", time.Now())
+	require.NoError(t, err)
+	require.Equal(t, "https://hooks.example.com/robot/send?access_token=abc", target)
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(body, &payload))
+	require.Equal(t, WebhookEventAccountError, payload["event"])
+	require.Equal(t, "Sub2API", payload["site"])
+	require.EqualValues(t, 42, payload["id"])
+	require.Equal(t, "401", payload["data"].(map[string]any)["reason"])
+}
+
+func TestRenderWebhookRequest_Slack(t *testing.T) {
+	_, body, err := renderWebhookRequest(newTestWebhookDelivery(WebhookFormatSlack), "", "Sub2API", time.Now())
+	require.NoError(t, err)
+
+	var payload map[string]string
+	require.NoError(t, json.Unmarshal(body, &payload))
+	require.Contains(t, payload["text"], "[Sub2API] Account disabled")
+	require.Contains(t, payload["text"], "account_id: 7")
+}
+
+func TestRenderWebhookRequest_DingTalkSignsQuery(t *testing.T) {
+	now := time.UnixMilli(1700000000123)
+	target, body, err := renderWebhookRequest(newTestWebhookDelivery(WebhookFormatDingTalk), "SEC123", "Sub2API", now)
+	require.NoError(t, err)
+
+	u, err := url.Parse(target)
+	require.NoError(t, err)
+	require.Equal(t, "abc", u.Query().Get("access_token"))
+	require.Equal(t, "1700000000123", u.Query().Get("timestamp"))
+	require.Equal(t, webhookChatSign("SEC123", "1700000000123\nSEC123"), u.Query().Get("sign"))
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(body, &payload))
+	require.Equal(t, "markdown", payload["msgtype"])
+}
+
+func TestRenderWebhookRequest_FeishuSignsBody(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	_, body, err := renderWebhookRequest(newTestWebhookDelivery(WebhookFormatFeishu), "SEC123", "Sub2API", now)
+	require.NoError(t, err)
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(body, &payload))
+	require.Equal(t, "text", payload["msg_type"])
+	require.Equal(t, "1700000000", payload["timestamp"])
+	require.Equal(t, webhookChatSign("1700000000\nSEC123", ""), payload["sign"])
+}
+
+func TestWebhookConfig_Subscribed(t *testing.T) {
+	cfg := &WebhookConfig{Enabled: true, Url: "https://example.com"}
+	require.True(t, cfg.Subscribed(WebhookEventProxyTestFailed))
+
+	cfg.Events = []string{WebhookEventAccountError}
+	require.True(t, cfg.Subscribed(WebhookEventAccountError))
+	require.False(t, cfg.Subscribed(WebhookEventProxyTestFailed))
+	require.True(t, cfg.Subscribed(WebhookEventTest))
+
+	cfg.Enabled = false
+	require.False(t, cfg.Subscribed(WebhookEventAccountError))
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	require.Equal(t, webhookRetryBase, webhookRetryDelay(1))
+	require.Equal(t, 2*webhookRetryBase, webhookRetryDelay(2))
+	require.Equal(t, webhookRetryMax, webhookRetryDelay(30))
+}
+
+func TestWebhookClaimLeaseCoversBatch(t *testing.T) {
+	// 租约必须长于整批任务的处理时间，否则任务可能在投递中被重复领取
+	require.Greater(t, webhookClaimLease, webhookBatchSize*webhookDeliveryTimeout)
+	require.Greater(t, webhookDeliveryTimeout, webhookRequestTimeout)
+}
+
+func TestWebhookService_NilIsNoop(t *testing.T) {
+	var svc *WebhookService
+	svc.NotifyAccountError(t.Context(), &Account{ID: 1}, "x")
+	svc.NotifyBalanceDebited(t.Context(), &User{ID: 1}, 1, 1)
+}
+
+func TestWebhookService_SendSignsGenericPayload(t *testing.T) {
+	var gotHeaders http.Header
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = r.Header.Clone()
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	svc := NewWebhookService(nil, nil, nil)
+	delivery := newTestWebhookDelivery(WebhookFormatGeneric)
+	delivery.Url = srv.URL
+	delivery.EventType = WebhookEventAccountError
+
+	status, err := svc.send(t.Context(), delivery, "secret", "Sub2API")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, status)
+	require.Equal(t, WebhookEventAccountError, gotHeaders.Get(WebhookHeaderEvent))
+	require.Equal(t, "42", gotHeaders.Get(WebhookHeaderDelivery))
+	require.Equal(t, signWebhookPayload("secret", gotHeaders.Get(WebhookHeaderTimestamp), gotBody), gotHeaders.Get(WebhookHeaderSignature))
+}
+
+func TestWebhookService_SendChatErrorCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+	}))
+	defer srv.Close()
+
+	svc := NewWebhookService(nil, nil, nil)
+	delivery := newTestWebhookDelivery(WebhookFormatDingTalk)
+	delivery.Url = srv.URL
+
+	_, err := svc.send(t.Context(), delivery, "", "Sub2API")
+	require.ErrorContains(t, err, "sign not match")
+
+	// 通用格式不解析响应体
+	delivery.Format = WebhookFormatGeneric
+	_, err = svc.send(t.Context(), delivery, "", "Sub2API")
+	require.NoError(t, err)
+}
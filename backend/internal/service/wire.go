@@ -38,8 +38,61 @@ func ProvideTokenRefreshService(
 	openaiOAuthService *OpenAIOAuthService,
 	geminiOAuthService *GeminiOAuthService,
 	cfg *config.Config,
+	webhookService *WebhookService,
 ) *TokenRefreshService {
-	svc := NewTokenRefreshService(accountRepo, oauthService, openaiOAuthService, geminiOAuthService, cfg)
+	svc := NewTokenRefreshService(accountRepo, oauthService, openaiOAuthService, geminiOAuthService, cfg, webhookService)
+	svc.Start()
+	return svc
+}
+
+// ProvideApiKeyExpiryService creates and starts ApiKeyExpiryService
+func ProvideApiKeyExpiryService(apiKeyRepo ApiKeyRepository) *ApiKeyExpiryService {
+	svc := NewApiKeyExpiryService(apiKeyRepo)
+	svc.Start()
+	return svc
+}
+
+// ProvideCaptureService creates CaptureService and starts expired capture cleanup
+func ProvideCaptureService(
+	captureRepo RequestCaptureRepository,
+	accountRepo AccountRepository,
+	apiKeyRepo ApiKeyRepository,
+	gatewayService *GatewayService,
+	cfg *config.Config,
+) *CaptureService {
+	svc := NewCaptureService(captureRepo, accountRepo, apiKeyRepo, gatewayService, cfg)
+	svc.Start()
+	return svc
+}
+
+// ProvideLiveEventService creates LiveEventService and starts cross-instance broadcasting
+func ProvideLiveEventService(pubsub LiveEventPubSub) *LiveEventService {
+	svc := NewLiveEventService(pubsub)
+	svc.Start()
+	return svc
+}
+
+// ProvideWebhookService creates WebhookService and starts the delivery worker
+func ProvideWebhookService(
+	deliveryRepo WebhookDeliveryRepository,
+	userSubRepo UserSubscriptionRepository,
+	settingService *SettingService,
+) *WebhookService {
+	svc := NewWebhookService(deliveryRepo, userSubRepo, settingService)
+	svc.Start()
+	return svc
+}
+
+// ProvideUserNotificationService creates UserNotificationService and starts the expiry scan
+func ProvideUserNotificationService(
+	prefsRepo UserNotificationPrefsRepository,
+	dedupCache NotificationDedupCache,
+	userRepo UserRepository,
+	userSubRepo UserSubscriptionRepository,
+	settingService *SettingService,
+	emailQueue *EmailQueueService,
+) *UserNotificationService {
+	svc := NewUserNotificationService(prefsRepo, dedupCache, userRepo, userSubRepo, settingService, emailQueue)
 	svc.Start()
 	return svc
 }
@@ -50,23 +103,29 @@ var ProviderSet = wire.NewSet(
 	NewAuthService,
 	NewUserService,
 	NewApiKeyService,
+	NewApiKeyLimitService,
 	NewGroupService,
 	NewAccountService,
 	NewProxyService,
 	NewRedeemService,
 	NewUsageService,
+	NewBalanceService,
+	NewAccountMetricsCollector,
 	NewDashboardService,
 	ProvidePricingService,
 	NewBillingService,
 	NewBillingCacheService,
 	NewAdminService,
 	NewGatewayService,
+	NewStickySessionService,
+	NewResponseCacheService,
 	NewOpenAIGatewayService,
 	NewOAuthService,
 	NewOpenAIOAuthService,
 	NewGeminiOAuthService,
 	NewGeminiTokenProvider,
 	NewGeminiMessagesCompatService,
+	NewOpenAIMessagesCompatService,
 	NewRateLimitService,
 	NewAccountUsageService,
 	NewAccountTestService,
@@ -74,10 +133,21 @@ var ProviderSet = wire.NewSet(
 	NewEmailService,
 	ProvideEmailQueueService,
 	NewTurnstileService,
+	NewTwoFactorService,
+	NewOIDCService,
+	NewAuditService,
+	NewAdminApiKeyService,
+	NewOrganizationService,
 	NewSubscriptionService,
 	NewConcurrencyService,
+	NewAccountScheduler,
 	NewIdentityService,
 	NewCRSSyncService,
 	ProvideUpdateService,
 	ProvideTokenRefreshService,
+	ProvideApiKeyExpiryService,
+	ProvideCaptureService,
+	ProvideLiveEventService,
+	ProvideWebhookService,
+	ProvideUserNotificationService,
 )
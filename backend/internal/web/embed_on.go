@@ -30,6 +30,7 @@ func ServeEmbeddedFrontend() gin.HandlerFunc {
 			strings.HasPrefix(path, "/v1beta/") ||
 			strings.HasPrefix(path, "/setup/") ||
 			path == "/health" ||
+			path == "/metrics" ||
 			path == "/responses" {
 			c.Next()
 			return
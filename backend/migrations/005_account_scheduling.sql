@@ -0,0 +1,7 @@
+-- 分组账号调度策略与账号权重
+
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS scheduling_strategy VARCHAR(30) NOT NULL DEFAULT 'priority';
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS weight INT NOT NULL DEFAULT 1;
+
+COMMENT ON COLUMN groups.scheduling_strategy IS '账号调度策略: priority/weighted/least_concurrency/round_robin';
+COMMENT ON COLUMN accounts.weight IS '加权调度权重（weighted 策略使用）';
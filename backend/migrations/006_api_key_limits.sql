@@ -0,0 +1,11 @@
+-- API Key 维度的限流与花费上限（0/NULL 表示不限制）
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS rate_limit_rpm INT NOT NULL DEFAULT 0;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS rate_limit_tpm INT NOT NULL DEFAULT 0;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS daily_limit_usd DECIMAL(20, 8);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS monthly_limit_usd DECIMAL(20, 8);
+
+COMMENT ON COLUMN api_keys.rate_limit_rpm IS '每分钟请求数上限，0 表示不限制';
+COMMENT ON COLUMN api_keys.rate_limit_tpm IS '每分钟 token 数上限，0 表示不限制';
+COMMENT ON COLUMN api_keys.daily_limit_usd IS '每日花费上限（USD），NULL 表示不限制';
+COMMENT ON COLUMN api_keys.monthly_limit_usd IS '每月花费上限（USD），NULL 表示不限制';
@@ -0,0 +1,7 @@
+-- API Key 模型与来源 IP 允许列表（NULL/空数组表示不限制）
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS allowed_models TEXT[];
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS allowed_ips TEXT[];
+
+COMMENT ON COLUMN api_keys.allowed_models IS '允许使用的模型列表，支持末尾 * 前缀匹配';
+COMMENT ON COLUMN api_keys.allowed_ips IS '允许的来源 IP 或 CIDR 列表';
@@ -0,0 +1,12 @@
+-- API Key 过期时间与轮换宽限期
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS previous_key VARCHAR(128);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS previous_key_expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_expires_at ON api_keys(expires_at);
+CREATE INDEX IF NOT EXISTS idx_api_keys_previous_key ON api_keys(previous_key);
+
+COMMENT ON COLUMN api_keys.expires_at IS '过期时间，NULL 表示永不过期';
+COMMENT ON COLUMN api_keys.previous_key IS '轮换前的旧 Key，宽限期内仍可使用';
+COMMENT ON COLUMN api_keys.previous_key_expires_at IS '旧 Key 宽限期结束时间';
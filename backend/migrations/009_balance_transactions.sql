@@ -0,0 +1,30 @@
+-- 余额流水表：记录每次余额变动（使用扣费、兑换充值、管理员调整、退款）
+
+CREATE TABLE IF NOT EXISTS balance_transactions (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type            VARCHAR(30) NOT NULL,
+    amount          DECIMAL(20, 10) NOT NULL,
+    balance_after   DECIMAL(20, 8) NOT NULL,
+    usage_log_id    BIGINT,
+    redeem_code_id  BIGINT,
+    operator_id     BIGINT,
+    notes           TEXT DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_transactions_user_id ON balance_transactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_balance_transactions_type ON balance_transactions(type);
+CREATE INDEX IF NOT EXISTS idx_balance_transactions_usage_log_id ON balance_transactions(usage_log_id);
+CREATE INDEX IF NOT EXISTS idx_balance_transactions_redeem_code_id ON balance_transactions(redeem_code_id);
+CREATE INDEX IF NOT EXISTS idx_balance_transactions_created_at ON balance_transactions(created_at);
+
+-- 同一使用记录只能退款一次
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balance_transactions_usage_refund
+    ON balance_transactions(usage_log_id) WHERE type = 'refund';
+
+COMMENT ON TABLE balance_transactions IS '余额流水';
+COMMENT ON COLUMN balance_transactions.type IS '类型: usage/redeem/admin_adjustment/refund';
+COMMENT ON COLUMN balance_transactions.amount IS '变动金额，正数入账，负数扣减';
+COMMENT ON COLUMN balance_transactions.balance_after IS '变动后的余额';
+COMMENT ON COLUMN balance_transactions.operator_id IS '操作管理员 ID';
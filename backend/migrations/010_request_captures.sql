@@ -0,0 +1,37 @@
+-- 请求抓取：按 API Key / 账号开启，记录脱敏后的客户端请求、改写后的上游请求与上游原始响应
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS capture_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS capture_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS request_captures (
+    id                  BIGSERIAL PRIMARY KEY,
+    user_id             BIGINT NOT NULL,
+    api_key_id          BIGINT NOT NULL,
+    account_id          BIGINT,
+    platform            VARCHAR(50) NOT NULL,
+    model               VARCHAR(100) DEFAULT '',
+    request_id          VARCHAR(64) DEFAULT '',
+    method              VARCHAR(10) NOT NULL,
+    path                VARCHAR(255) NOT NULL,
+    client_headers      JSONB DEFAULT '{}',
+    client_body         TEXT DEFAULT '',
+    upstream_url        TEXT DEFAULT '',
+    upstream_headers    JSONB DEFAULT '{}',
+    upstream_body       TEXT DEFAULT '',
+    response_status     INT NOT NULL DEFAULT 0,
+    response_headers    JSONB DEFAULT '{}',
+    response_body       TEXT DEFAULT '',
+    request_truncated   BOOLEAN NOT NULL DEFAULT FALSE,
+    response_truncated  BOOLEAN NOT NULL DEFAULT FALSE,
+    duration_ms         BIGINT NOT NULL DEFAULT 0,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_captures_user_id ON request_captures(user_id);
+CREATE INDEX IF NOT EXISTS idx_request_captures_api_key_id ON request_captures(api_key_id);
+CREATE INDEX IF NOT EXISTS idx_request_captures_account_id ON request_captures(account_id);
+CREATE INDEX IF NOT EXISTS idx_request_captures_created_at ON request_captures(created_at);
+
+COMMENT ON TABLE request_captures IS '请求抓取记录，超过 capture.retention_days 自动删除';
+COMMENT ON COLUMN request_captures.upstream_body IS '模型映射、系统提示词注入、user_id 改写之后的上游请求体';
+COMMENT ON COLUMN request_captures.response_body IS '上游原始响应，流式请求为 SSE 原文';
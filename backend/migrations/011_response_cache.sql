@@ -0,0 +1,12 @@
+-- 分组响应缓存：相同的非流式请求直接返回缓存的响应，不占用上游账号
+
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS response_cache_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS response_cache_ttl_seconds INT NOT NULL DEFAULT 0;
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS response_cache_multiplier DECIMAL(10, 4) NOT NULL DEFAULT 1.0;
+
+ALTER TABLE usage_logs ADD COLUMN IF NOT EXISTS cache_hit BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN groups.response_cache_enabled IS '是否开启响应缓存（分组内所有用户共享）';
+COMMENT ON COLUMN groups.response_cache_ttl_seconds IS '响应缓存有效期（秒），0 表示使用默认值 300';
+COMMENT ON COLUMN groups.response_cache_multiplier IS '命中响应缓存时的计费倍率，0 表示免费';
+COMMENT ON COLUMN usage_logs.cache_hit IS '是否由响应缓存返回';
@@ -0,0 +1,28 @@
+-- Webhook 投递队列：运维事件持久化后由后台任务签名投递，失败按指数退避重试
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id                  BIGSERIAL PRIMARY KEY,
+    event_type          VARCHAR(64) NOT NULL,
+    dedupe_key          VARCHAR(255),
+    url                 TEXT NOT NULL,
+    format              VARCHAR(20) NOT NULL,
+    payload             JSONB NOT NULL,
+    status              VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts            INT NOT NULL DEFAULT 0,
+    next_attempt_at     TIMESTAMPTZ NOT NULL,
+    last_status_code    INT NOT NULL DEFAULT 0,
+    last_error          TEXT DEFAULT '',
+    delivered_at        TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_type ON webhook_deliveries(event_type);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_dedupe_key ON webhook_deliveries(dedupe_key);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+
+COMMENT ON TABLE webhook_deliveries IS 'Webhook 投递队列';
+COMMENT ON COLUMN webhook_deliveries.dedupe_key IS '去重键，非空时同一事件只通知一次';
+COMMENT ON COLUMN webhook_deliveries.format IS '消息格式: generic/slack/dingtalk/feishu';
+COMMENT ON COLUMN webhook_deliveries.status IS '状态: pending/succeeded/failed';
+COMMENT ON COLUMN webhook_deliveries.attempts IS '已投递次数';
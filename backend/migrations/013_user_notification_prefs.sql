@@ -0,0 +1,13 @@
+-- 用户邮件通知偏好：未保存时默认全部开启
+
+CREATE TABLE IF NOT EXISTS user_notification_prefs (
+    user_id                     BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    balance_low_enabled         BOOLEAN NOT NULL DEFAULT TRUE,
+    balance_threshold           DECIMAL(20, 8) NOT NULL DEFAULT 1,
+    subscription_expiry_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    quota_reached_enabled       BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at                  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE user_notification_prefs IS '用户邮件通知偏好';
+COMMENT ON COLUMN user_notification_prefs.balance_threshold IS '余额低于该值时提醒（美元）';
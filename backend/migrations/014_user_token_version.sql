@@ -0,0 +1,5 @@
+-- 用户 Token 版本：重置密码或修改邮箱后递增，使旧的 JWT 失效
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version BIGINT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN users.token_version IS 'JWT 版本号，签发时写入，校验时不一致即视为已吊销';
@@ -0,0 +1,32 @@
+-- 二次验证：TOTP 与 WebAuthn 安全密钥
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id        BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret         VARCHAR(64) NOT NULL,
+    enabled        BOOLEAN NOT NULL DEFAULT FALSE,
+    recovery_codes TEXT[] NOT NULL DEFAULT '{}',
+    enabled_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE user_totp IS '用户 TOTP 二次验证配置';
+COMMENT ON COLUMN user_totp.enabled IS '是否已确认启用，未确认的密钥不参与登录校验';
+COMMENT ON COLUMN user_totp.recovery_codes IS '恢复码 SHA-256 摘要，使用后移除';
+
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name          VARCHAR(100) NOT NULL,
+    credential_id VARCHAR(1400) NOT NULL,
+    credential    JSONB NOT NULL,
+    sign_count    BIGINT NOT NULL DEFAULT 0,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at  TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webauthn_credentials_credential_id ON webauthn_credentials(credential_id);
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+
+COMMENT ON TABLE webauthn_credentials IS '用户注册的 WebAuthn 安全密钥 / Passkey';
+COMMENT ON COLUMN webauthn_credentials.credential_id IS 'base64url 编码的凭据 ID';
+COMMENT ON COLUMN webauthn_credentials.credential IS '完整凭据记录（公钥、标志位、AAGUID 等）';
@@ -0,0 +1,18 @@
+-- OIDC 单点登录：本地账号与外部身份的绑定
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer     VARCHAR(255) NOT NULL,
+    subject    VARCHAR(255) NOT NULL,
+    email      VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_login TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_issuer_subject ON user_identities(issuer, subject);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_user_issuer ON user_identities(user_id, issuer);
+
+COMMENT ON TABLE user_identities IS '本地账号绑定的外部身份（OIDC）';
+COMMENT ON COLUMN user_identities.subject IS 'ID Token 中的 sub';
+COMMENT ON COLUMN user_identities.email IS '绑定时 IdP 返回的邮箱，仅供参考';
@@ -0,0 +1,35 @@
+-- 管理操作审计日志：只追加，触发器禁止修改与删除
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id              BIGSERIAL PRIMARY KEY,
+    actor_id        BIGINT NOT NULL DEFAULT 0,
+    actor_role      VARCHAR(30) NOT NULL DEFAULT '',
+    auth_method     VARCHAR(30) NOT NULL DEFAULT '',
+    ip              VARCHAR(64) NOT NULL DEFAULT '',
+    action          VARCHAR(30) NOT NULL,
+    resource_type   VARCHAR(50) NOT NULL,
+    resource_id     VARCHAR(64) NOT NULL DEFAULT '',
+    changes         JSONB NOT NULL DEFAULT '{}',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs(actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_resource ON audit_logs(resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_logs_append_only ON audit_logs;
+CREATE TRIGGER trg_audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+COMMENT ON TABLE audit_logs IS '管理操作审计日志（只追加）';
+COMMENT ON COLUMN audit_logs.actor_role IS '操作时的管理角色: admin/viewer/billing_operator/account_operator';
+COMMENT ON COLUMN audit_logs.auth_method IS '认证方式: jwt/admin_api_key';
+COMMENT ON COLUMN audit_logs.changes IS '变更字段: {"字段": {"before": ..., "after": ...}}，敏感字段已脱敏';
+
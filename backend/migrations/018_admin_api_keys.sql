@@ -0,0 +1,28 @@
+-- 多个管理员 API Key：按名称区分，带权限范围、过期时间与吊销状态，只保存哈希
+-- 旧版保存在 settings.admin_api_key 中的 Key 在首次使用时自动迁移
+
+CREATE TABLE IF NOT EXISTS admin_api_keys (
+    id              BIGSERIAL PRIMARY KEY,
+    name            VARCHAR(100) NOT NULL,
+    key_hint        VARCHAR(32) NOT NULL DEFAULT '',
+    key_hash        VARCHAR(64) NOT NULL,
+    scope           VARCHAR(20) NOT NULL,
+    created_by      BIGINT NOT NULL DEFAULT 0,
+    expires_at      TIMESTAMPTZ,
+    last_used_at    TIMESTAMPTZ,
+    revoked_at      TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_admin_api_keys_key_hash ON admin_api_keys(key_hash);
+CREATE INDEX IF NOT EXISTS idx_admin_api_keys_name ON admin_api_keys(name);
+CREATE INDEX IF NOT EXISTS idx_admin_api_keys_expires_at ON admin_api_keys(expires_at);
+
+COMMENT ON TABLE admin_api_keys IS '管理员 API Key';
+COMMENT ON COLUMN admin_api_keys.key_hint IS '脱敏后的 Key，用于辨认';
+COMMENT ON COLUMN admin_api_keys.key_hash IS 'Key 的 SHA-256 哈希';
+COMMENT ON COLUMN admin_api_keys.scope IS '权限范围: read_only/accounts/billing/full';
+
+-- 审计日志记录使用的 Key
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS admin_api_key_id BIGINT;
+COMMENT ON COLUMN audit_logs.admin_api_key_id IS '通过 Admin API Key 操作时对应的 Key ID';
@@ -0,0 +1,80 @@
+-- 组织（团队）：成员共享余额与订阅，按成员设置每月花费上限
+-- 组织 Key 的用量从组织余额扣费，流水/使用记录/订阅通过 organization_id 关联组织
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id              BIGSERIAL PRIMARY KEY,
+    name            VARCHAR(100) NOT NULL,
+    balance         DECIMAL(20, 8) NOT NULL DEFAULT 0,
+    status          VARCHAR(20) NOT NULL DEFAULT 'active',
+    owner_id        BIGINT NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_organizations_owner_id ON organizations(owner_id);
+
+COMMENT ON TABLE organizations IS '组织（团队）';
+COMMENT ON COLUMN organizations.balance IS '组织共享余额';
+COMMENT ON COLUMN organizations.owner_id IS '创建者用户 ID';
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    id                  BIGSERIAL PRIMARY KEY,
+    organization_id     BIGINT NOT NULL,
+    user_id             BIGINT NOT NULL,
+    role                VARCHAR(20) NOT NULL DEFAULT 'member',
+    monthly_limit_usd   DECIMAL(20, 8),
+    monthly_usage_usd   DECIMAL(20, 10) NOT NULL DEFAULT 0,
+    usage_month         VARCHAR(7) NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_members_org_user ON organization_members(organization_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members(user_id);
+
+COMMENT ON TABLE organization_members IS '组织成员';
+COMMENT ON COLUMN organization_members.role IS '角色: owner/admin/member';
+COMMENT ON COLUMN organization_members.monthly_limit_usd IS '每月可使用组织余额的上限，NULL 表示不限制';
+COMMENT ON COLUMN organization_members.monthly_usage_usd IS 'usage_month 当月已使用的组织余额';
+COMMENT ON COLUMN organization_members.usage_month IS '花费累计月份（UTC），格式 YYYY-MM';
+
+CREATE TABLE IF NOT EXISTS organization_invitations (
+    id                  BIGSERIAL PRIMARY KEY,
+    organization_id     BIGINT NOT NULL,
+    email               VARCHAR(255) NOT NULL,
+    role                VARCHAR(20) NOT NULL DEFAULT 'member',
+    token_hash          VARCHAR(64) NOT NULL,
+    invited_by          BIGINT NOT NULL,
+    expires_at          TIMESTAMPTZ NOT NULL,
+    accepted_at         TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_invitations_token_hash ON organization_invitations(token_hash);
+CREATE INDEX IF NOT EXISTS idx_organization_invitations_organization_id ON organization_invitations(organization_id);
+CREATE INDEX IF NOT EXISTS idx_organization_invitations_accepted_at ON organization_invitations(accepted_at);
+
+COMMENT ON TABLE organization_invitations IS '组织成员邀请';
+COMMENT ON COLUMN organization_invitations.token_hash IS '邀请 Token 的 SHA-256 哈希';
+
+-- 组织归属：为空表示个人
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS organization_id BIGINT;
+ALTER TABLE usage_logs ADD COLUMN IF NOT EXISTS organization_id BIGINT;
+ALTER TABLE balance_transactions ADD COLUMN IF NOT EXISTS organization_id BIGINT;
+ALTER TABLE user_subscriptions ADD COLUMN IF NOT EXISTS organization_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_organization_id ON api_keys(organization_id);
+CREATE INDEX IF NOT EXISTS idx_usage_logs_organization_id ON usage_logs(organization_id);
+CREATE INDEX IF NOT EXISTS idx_balance_transactions_organization_id ON balance_transactions(organization_id);
+CREATE INDEX IF NOT EXISTS idx_user_subscriptions_organization_id ON user_subscriptions(organization_id);
+
+-- 组织共享订阅挂在 owner 的 user_id 下：原 (user_id, group_id) 唯一约束只对个人订阅生效，
+-- 组织订阅改为按 (organization_id, group_id) 唯一
+ALTER TABLE user_subscriptions DROP CONSTRAINT IF EXISTS user_subscriptions_user_id_group_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_subscriptions_user_group ON user_subscriptions(user_id, group_id) WHERE organization_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_subscriptions_org_group ON user_subscriptions(organization_id, group_id) WHERE organization_id IS NOT NULL;
+
+COMMENT ON COLUMN api_keys.organization_id IS '组织 Key 所属组织，用量从组织余额扣费';
+COMMENT ON COLUMN usage_logs.organization_id IS '组织 Key 产生的用量所属组织';
+COMMENT ON COLUMN balance_transactions.organization_id IS '组织余额流水所属组织';
+COMMENT ON COLUMN user_subscriptions.organization_id IS '组织共享订阅所属组织';
@@ -0,0 +1,7 @@
+-- 使用记录保存链路追踪 trace id，request_id 保持为上游返回的请求 ID
+
+ALTER TABLE usage_logs ADD COLUMN IF NOT EXISTS trace_id VARCHAR(32) DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_usage_logs_trace_id ON usage_logs(trace_id);
+
+COMMENT ON COLUMN usage_logs.trace_id IS '启用追踪时的 trace id，未启用时为空';
@@ -443,6 +443,7 @@ export interface UsageLog {
   api_key_id: number
   account_id: number | null
   request_id: string
+  trace_id?: string
   model: string
 
   group_id: number | null